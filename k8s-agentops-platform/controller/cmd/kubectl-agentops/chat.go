@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// defaultAgentPort is the agent's "http" port when the pod does not name one
+const defaultAgentPort = 8080
+
+// chatMessage is a message of an OpenAI-style chat completion request
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// runChat port-forwards to a ready agent pod and sends each line read from stdin as
+// a chat message, keeping the conversation history
+func runChat(ctx context.Context, args []string) error {
+	opts := &globalOptions{}
+	fs := newFlagSet("chat", opts)
+	path := fs.String("path", "/v1/chat/completions", "Path of the agent's chat endpoint")
+	system := fs.String("system", "", "System prompt sent at the start of the conversation")
+	positional, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	c, err := connect(opts)
+	if err != nil {
+		return err
+	}
+	ad, err := c.agentDeployment(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	pods, err := c.agentPods(ctx, ad)
+	if err != nil {
+		return err
+	}
+	pod := readyPod(pods)
+	if pod == nil {
+		return fmt.Errorf("agent %s has no ready pods", ad.Name)
+	}
+
+	localPort, stop, err := c.portForward(pod, agentPort(pod))
+	if err != nil {
+		return err
+	}
+	defer close(stop)
+	url := fmt.Sprintf("http://127.0.0.1:%d%s", localPort, *path)
+	fmt.Fprintf(os.Stderr, "Connected to %s/%s. Type a message, Ctrl-D to quit.\n", pod.Namespace, pod.Name)
+
+	var history []chatMessage
+	if *system != "" {
+		history = append(history, chatMessage{Role: "system", Content: *system})
+	}
+	input := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Fprint(os.Stderr, "> ")
+		if !input.Scan() {
+			fmt.Fprintln(os.Stderr)
+			return input.Err()
+		}
+		line := strings.TrimSpace(input.Text())
+		if line == "" {
+			continue
+		}
+		history = append(history, chatMessage{Role: "user", Content: line})
+		reply, err := sendChat(ctx, url, ad.Spec.Model, history)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			history = history[:len(history)-1]
+			continue
+		}
+		fmt.Println(reply)
+		history = append(history, chatMessage{Role: "assistant", Content: reply})
+	}
+}
+
+// sendChat posts the conversation and returns the reply text, or the raw response
+// body when it is not an OpenAI-style chat completion
+func sendChat(ctx context.Context, url, model string, messages []chatMessage) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": model, "messages": messages})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &completion); err == nil && len(completion.Choices) > 0 {
+		return completion.Choices[0].Message.Content, nil
+	}
+	return string(bytes.TrimSpace(data)), nil
+}
+
+// readyPod returns a ready pod, or nil
+func readyPod(pods []corev1.Pod) *corev1.Pod {
+	for i := range pods {
+		if pods[i].DeletionTimestamp != nil {
+			continue
+		}
+		for _, cond := range pods[i].Status.Conditions {
+			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+				return &pods[i]
+			}
+		}
+	}
+	return nil
+}
+
+// agentPort returns the "http" port of the agent container
+func agentPort(pod *corev1.Pod) int32 {
+	for _, container := range pod.Spec.Containers {
+		if container.Name != "agent" {
+			continue
+		}
+		for _, port := range container.Ports {
+			if port.Name == "http" {
+				return port.ContainerPort
+			}
+		}
+	}
+	return defaultAgentPort
+}
+
+// portForward forwards a free local port to a port of the pod and returns it, with
+// the channel that stops forwarding when closed
+func (c *cluster) portForward(pod *corev1.Pod, port int32) (uint16, chan struct{}, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(c.config)
+	if err != nil {
+		return 0, nil, err
+	}
+	url := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	stop, ready := make(chan struct{}), make(chan struct{})
+	forwarder, err := portforward.New(dialer, []string{fmt.Sprintf("0:%d", port)}, stop, ready, io.Discard, os.Stderr)
+	if err != nil {
+		return 0, nil, err
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- forwarder.ForwardPorts()
+	}()
+	select {
+	case <-ready:
+	case err := <-errs:
+		return 0, nil, err
+	}
+	ports, err := forwarder.GetPorts()
+	if err != nil {
+		close(stop)
+		return 0, nil, err
+	}
+	return ports[0].Local, stop, nil
+}
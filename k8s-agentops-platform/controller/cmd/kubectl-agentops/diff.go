@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	agentopsv1beta1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1beta1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/controllers"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/cosign"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/registry"
+)
+
+// errDiffFound makes the plugin exit with status 1 when there are differences, like
+// kubectl diff, without printing an error
+var errDiffFound = errors.New("differences found")
+
+// runDiff renders the child objects the controller would write for the
+// AgentDeployments of a manifest and diffs them against the cluster. Nothing is
+// changed: the manifest and the child objects are only sent as server-side dry runs.
+func runDiff(ctx context.Context, args []string) error {
+	opts := &globalOptions{}
+	fs := newFlagSet("diff", opts)
+	filename := fs.String("f", "", "Manifest with the AgentDeployments to diff, or - for stdin")
+	templatePatch := fs.String("pod-template-patch", "", "Namespace/name of the controller's --pod-template-patch ConfigMap")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if *filename == "" {
+		return fmt.Errorf("-f is required")
+	}
+	c, err := connect(opts)
+	if err != nil {
+		return err
+	}
+	manifests, err := readAgentDeployments(*filename, c.client.Scheme())
+	if err != nil {
+		return err
+	}
+
+	liveDir, err := os.MkdirTemp("", "agentops-live-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(liveDir)
+	mergedDir, err := os.MkdirTemp("", "agentops-merged-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(mergedDir)
+
+	images := registry.NewClient()
+	r := &controllers.AgentDeploymentReconciler{
+		Client:     c.client,
+		Scheme:     c.client.Scheme(),
+		Log:        logr.Discard(),
+		Signatures: cosign.NewVerifier(images),
+		Images:     images,
+	}
+	if *templatePatch != "" {
+		namespace, name, ok := strings.Cut(*templatePatch, "/")
+		if !ok {
+			return fmt.Errorf("invalid --pod-template-patch %q, expected namespace/name", *templatePatch)
+		}
+		r.PodTemplatePatch = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	for _, obj := range manifests {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(c.namespace)
+		}
+		live, ad, err := c.dryRunAgentDeployment(ctx, obj)
+		if err != nil {
+			return fmt.Errorf("agentdeployment/%s: %w", obj.GetName(), err)
+		}
+		if err := writeObject(c.client.Scheme(), liveDir, live); err != nil {
+			return err
+		}
+		if err := writeObject(c.client.Scheme(), mergedDir, obj); err != nil {
+			return err
+		}
+
+		changes, err := r.Render(ctx, ad)
+		if err != nil {
+			return fmt.Errorf("agentdeployment/%s: %w", obj.GetName(), err)
+		}
+		for _, change := range changes {
+			if err := writeObject(c.client.Scheme(), liveDir, change.Live); err != nil {
+				return err
+			}
+			if err := writeObject(c.client.Scheme(), mergedDir, change.Desired); err != nil {
+				return err
+			}
+		}
+	}
+	return externalDiff(liveDir, mergedDir)
+}
+
+// readAgentDeployments decodes the AgentDeployments of a multi-document manifest, in
+// either API version. Documents of other kinds are skipped.
+func readAgentDeployments(filename string, scheme *runtime.Scheme) ([]client.Object, error) {
+	var in io.Reader = os.Stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(in))
+	var objs []client.Object
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(string(doc)) == "" {
+			continue
+		}
+		obj, gvk, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			if runtime.IsNotRegisteredError(err) {
+				continue
+			}
+			return nil, err
+		}
+		if gvk.Kind != "AgentDeployment" {
+			continue
+		}
+		objs = append(objs, obj.(client.Object))
+	}
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("%s contains no AgentDeployment", filename)
+	}
+	return objs, nil
+}
+
+// dryRunAgentDeployment applies obj as a server-side dry run, so it picks up the CRD
+// defaults and is validated, and returns the live object (nil if it does not exist)
+// and the result as the version the controller reconciles
+func (c *cluster) dryRunAgentDeployment(ctx context.Context, obj client.Object) (client.Object, *agentopsv1alpha1.AgentDeployment, error) {
+	live := obj.DeepCopyObject().(client.Object)
+	err := c.client.Get(ctx, client.ObjectKeyFromObject(obj), live)
+	switch {
+	case apierrors.IsNotFound(err):
+		live = nil
+		err = c.client.Create(ctx, obj, client.DryRunAll)
+	case err == nil:
+		// The controller's finalizer is not part of manifests; apply keeps it
+		obj.SetResourceVersion(live.GetResourceVersion())
+		obj.SetFinalizers(live.GetFinalizers())
+		err = c.client.Update(ctx, obj, client.DryRunAll)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch in := obj.(type) {
+	case *agentopsv1alpha1.AgentDeployment:
+		return live, in.DeepCopy(), nil
+	case *agentopsv1beta1.AgentDeployment:
+		ad := &agentopsv1alpha1.AgentDeployment{}
+		if err := in.ConvertTo(ad); err != nil {
+			return nil, nil, err
+		}
+		return live, ad, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported type %T", obj)
+}
+
+// writeObject writes obj as YAML to dir, in a file named after its kind and name.
+// Server-managed metadata is dropped so it does not show up as a difference.
+func writeObject(scheme *runtime.Scheme, dir string, obj client.Object) error {
+	if obj == nil {
+		return nil
+	}
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return err
+	}
+	obj = obj.DeepCopyObject().(client.Object)
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+	obj.SetGeneration(0)
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s.%s.%s.%s", gvk.Group, gvk.Kind, obj.GetNamespace(), obj.GetName())
+	return os.WriteFile(filepath.Join(dir, strings.TrimPrefix(name, ".")), data, 0o644)
+}
+
+// externalDiff runs KUBECTL_EXTERNAL_DIFF, or "diff -u -N", on the two directories
+// and returns errDiffFound if they differ
+func externalDiff(liveDir, mergedDir string) error {
+	args := []string{"diff", "-u", "-N"}
+	if custom := strings.Fields(os.Getenv("KUBECTL_EXTERNAL_DIFF")); len(custom) > 0 {
+		args = custom
+	}
+	cmd := exec.Command(args[0], append(args[1:], liveDir, mergedDir)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return errDiffFound
+	}
+	return err
+}
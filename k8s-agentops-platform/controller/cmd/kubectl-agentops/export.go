@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// How export writes Secret values
+const (
+	secretsRedact  = "redact"
+	secretsEncrypt = "encrypt"
+	secretsInclude = "include"
+)
+
+const (
+	bundleAPIVersion = "agentops.io/v1alpha1"
+	bundleKind       = "AgentOpsBundle"
+
+	// exportedSecretAnnotation marks the Secrets of a bundle whose values were
+	// redacted or encrypted, with the value redacted or aes-256-gcm
+	exportedSecretAnnotation = "agentops.io/exported-secret"
+	secretRedacted           = "redacted"
+	secretEncrypted          = "aes-256-gcm"
+)
+
+// bundle is the portable snapshot written by export and applied by restore. Its
+// items are ordered so that what an agent references is restored before it.
+type bundle struct {
+	APIVersion string                   `json:"apiVersion"`
+	Kind       string                   `json:"kind"`
+	Namespace  string                   `json:"namespace"`
+	ExportedAt metav1.Time              `json:"exportedAt"`
+	Items      []map[string]interface{} `json:"items"`
+}
+
+// runExport writes AgentDeployments, and the Secrets and PromptTemplates they
+// reference, to a bundle
+func runExport(ctx context.Context, args []string) error {
+	opts := &globalOptions{}
+	fs := newFlagSet("export", opts)
+	output := fs.String("o", "", "File to write the bundle to; defaults to stdout")
+	secrets := fs.String("secrets", secretsRedact, "How Secret values are exported: redact, encrypt or include")
+	keyFile := fs.String("encryption-key-file", "", "File with a base64 AES-256 key, for --secrets encrypt")
+	names, err := parseArgs(fs, args, -1)
+	if err != nil {
+		return err
+	}
+	var aead cipher.AEAD
+	switch *secrets {
+	case secretsRedact, secretsInclude:
+	case secretsEncrypt:
+		if *keyFile == "" {
+			return fmt.Errorf("--secrets encrypt needs --encryption-key-file")
+		}
+		if aead, err = loadBundleKey(*keyFile); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid --secrets %q, expected redact, encrypt or include", *secrets)
+	}
+	c, err := connect(opts)
+	if err != nil {
+		return err
+	}
+
+	var ads []agentopsv1alpha1.AgentDeployment
+	if len(names) == 0 {
+		list := &agentopsv1alpha1.AgentDeploymentList{}
+		if err := c.client.List(ctx, list, client.InNamespace(c.namespace)); err != nil {
+			return err
+		}
+		ads = list.Items
+	}
+	for _, name := range names {
+		ad, err := c.agentDeployment(ctx, name)
+		if err != nil {
+			return err
+		}
+		ads = append(ads, *ad)
+	}
+	if len(ads) == 0 {
+		return fmt.Errorf("no AgentDeployments in namespace %s", c.namespace)
+	}
+
+	secretNames, templateNames := map[string]bool{}, map[string]bool{}
+	for i := range ads {
+		refs, err := secretRefs(&ads[i])
+		if err != nil {
+			return err
+		}
+		for _, name := range refs {
+			secretNames[name] = true
+		}
+		if ref := ads[i].Spec.PromptTemplateRef; ref != nil {
+			templateNames[ref.Name] = true
+		}
+	}
+
+	b := &bundle{APIVersion: bundleAPIVersion, Kind: bundleKind, Namespace: c.namespace, ExportedAt: metav1.Now()}
+	scheme := c.client.Scheme()
+	for _, name := range sortedKeys(secretNames) {
+		secret := &corev1.Secret{}
+		if err := c.client.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: name}, secret); apierrors.IsNotFound(err) {
+			fmt.Fprintf(os.Stderr, "warning: secret/%s is referenced but does not exist\n", name)
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := exportSecretValues(secret, *secrets, aead); err != nil {
+			return err
+		}
+		item, err := bundleItem(secret, scheme)
+		if err != nil {
+			return err
+		}
+		b.Items = append(b.Items, item)
+	}
+	for _, name := range sortedKeys(templateNames) {
+		template := &agentopsv1alpha1.PromptTemplate{}
+		if err := c.client.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: name}, template); apierrors.IsNotFound(err) {
+			fmt.Fprintf(os.Stderr, "warning: prompttemplate/%s is referenced but does not exist\n", name)
+			continue
+		} else if err != nil {
+			return err
+		}
+		item, err := bundleItem(template, scheme)
+		if err != nil {
+			return err
+		}
+		b.Items = append(b.Items, item)
+	}
+	for i := range ads {
+		item, err := bundleItem(&ads[i], scheme)
+		if err != nil {
+			return err
+		}
+		b.Items = append(b.Items, item)
+	}
+
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0o600); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d agents and %d objects they use to %s\n", len(ads), len(b.Items)-len(ads), *output)
+	return nil
+}
+
+// secretRefs returns the Secrets an agent reads: native spec.secrets and every
+// *SecretRef of the spec. Secrets of the vault, external and broker providers are
+// not stored in the namespace by hand and are left out.
+func secretRefs(ad *agentopsv1alpha1.AgentDeployment) ([]string, error) {
+	names := map[string]bool{}
+	for _, s := range ad.Spec.Secrets {
+		if s.Provider == "" || s.Provider == agentopsv1alpha1.SecretProviderNative {
+			names[s.Name] = true
+		}
+	}
+	data, err := json.Marshal(ad.Spec)
+	if err != nil {
+		return nil, err
+	}
+	var spec interface{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				if ref, ok := value.(map[string]interface{}); ok && strings.HasSuffix(key, "SecretRef") {
+					if name, _ := ref["name"].(string); name != "" {
+						names[name] = true
+					}
+				}
+				walk(value)
+			}
+		case []interface{}:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	walk(spec)
+	return sortedKeys(names), nil
+}
+
+// exportSecretValues redacts or encrypts the values of a Secret for a bundle
+func exportSecretValues(secret *corev1.Secret, mode string, aead cipher.AEAD) error {
+	if mode == secretsInclude {
+		return nil
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	if mode == secretsRedact {
+		secret.Annotations[exportedSecretAnnotation] = secretRedacted
+		for key := range secret.Data {
+			secret.Data[key] = []byte{}
+		}
+		return nil
+	}
+	secret.Annotations[exportedSecretAnnotation] = secretEncrypted
+	for key, value := range secret.Data {
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+		// The Secret and key names are authenticated so values cannot be swapped
+		secret.Data[key] = aead.Seal(nonce, nonce, value, []byte(secret.Name+"/"+key))
+	}
+	return nil
+}
+
+// loadBundleKey reads a base64 AES-256 key, e.g. from openssl rand -base64 32
+func loadBundleKey(path string) (cipher.AEAD, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not base64: %w", path, err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%s holds a %d-byte key, expected 32", path, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// bundleItem returns obj without status and the metadata tied to its cluster,
+// including the health annotation the controller writes
+func bundleItem(obj client.Object, scheme *runtime.Scheme) (map[string]interface{}, error) {
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return nil, err
+	}
+	item, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	item["apiVersion"], item["kind"] = gvk.GroupVersion().String(), gvk.Kind
+	delete(item, "status")
+
+	metadata := map[string]interface{}{"name": obj.GetName()}
+	if labels := obj.GetLabels(); len(labels) > 0 {
+		metadata["labels"] = labels
+	}
+	annotations := map[string]string{}
+	for k, v := range obj.GetAnnotations() {
+		if k != corev1.LastAppliedConfigAnnotation && k != agentopsv1alpha1.HealthAnnotation {
+			annotations[k] = v
+		}
+	}
+	if len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+	item["metadata"] = metadata
+	return item, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// runLogs prints the logs of every agent pod, each line prefixed with its pod
+func runLogs(ctx context.Context, args []string) error {
+	opts := &globalOptions{}
+	fs := newFlagSet("logs", opts)
+	container := fs.String("c", "agent", "Container to print the logs of")
+	follow := fs.Bool("f", false, "Stream new log lines")
+	tail := fs.Int64("tail", -1, "Lines of recent logs to print per pod; -1 prints all")
+	since := fs.Duration("since", 0, "Only print logs newer than this, e.g. 1h")
+	positional, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	c, err := connect(opts)
+	if err != nil {
+		return err
+	}
+	ad, err := c.agentDeployment(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	pods, err := c.agentPods(ctx, ad)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("agent %s has no pods", ad.Name)
+	}
+
+	logOpts := &corev1.PodLogOptions{Container: *container, Follow: *follow}
+	if *tail >= 0 {
+		logOpts.TailLines = tail
+	}
+	if *since > 0 {
+		seconds := int64(since.Seconds())
+		logOpts.SinceSeconds = &seconds
+	}
+
+	// Lines of different pods are interleaved, but never torn
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(chan error, len(pods))
+	for _, pod := range pods {
+		wg.Add(1)
+		go func(pod string) {
+			defer wg.Done()
+			stream, err := c.clientset.CoreV1().Pods(ad.Namespace).GetLogs(pod, logOpts).Stream(ctx)
+			if err != nil {
+				errs <- fmt.Errorf("%s: %w", pod, err)
+				return
+			}
+			defer stream.Close()
+			if err := copyPrefixed(&mu, os.Stdout, stream, "["+pod+"] "); err != nil && ctx.Err() == nil {
+				errs <- fmt.Errorf("%s: %w", pod, err)
+			}
+		}(pod.Name)
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// copyPrefixed copies lines from r to w, prefixing each one
+func copyPrefixed(mu *sync.Mutex, w io.Writer, r io.Reader, prefix string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		mu.Lock()
+		fmt.Fprintf(w, "%s%s\n", prefix, scanner.Text())
+		mu.Unlock()
+	}
+	return scanner.Err()
+}
+
+// agentPods returns the pods of the agent Deployment, oldest first
+func (c *cluster) agentPods(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) ([]corev1.Pod, error) {
+	dep := &appsv1.Deployment{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: ad.Namespace, Name: ad.Name}, dep); err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(dep.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	list := &corev1.PodList{}
+	if err := c.client.List(ctx, list, client.InNamespace(ad.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	pods := list.Items
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].CreationTimestamp.Before(&pods[j].CreationTimestamp)
+	})
+	return pods, nil
+}
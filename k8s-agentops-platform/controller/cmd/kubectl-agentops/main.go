@@ -0,0 +1,176 @@
+// kubectl-agentops is a kubectl plugin for operating AgentDeployments. Install it on
+// the PATH and run it as "kubectl agentops <command>".
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	agentopsv1beta1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1beta1"
+)
+
+const usage = `Operate AgentDeployments.
+
+Usage:
+  kubectl agentops <command> [flags]
+
+Commands:
+  status <name>                  Show an agent, its conditions, child resources and pods
+  logs <name>                    Print the logs of the agent pods
+  scale <name> --replicas N      Set the replica count of an agent
+  pause <name>                   Stop the controller from changing an agent
+  resume <name>                  Let the controller manage a paused agent again
+  promote <route> <name>         Send all traffic of an AgentRoute to one of its backends
+  chat <name>                    Port-forward to an agent pod and send it prompts
+  diff -f <file>                 Diff the objects the controller would generate against the cluster
+  export [name...]               Write agents and the Secrets and PromptTemplates they use to a bundle
+  restore -f <bundle>            Apply an exported bundle, e.g. in another cluster or namespace
+
+Global flags:
+  -n, --namespace   Namespace of the agent (defaults to the kubeconfig context)
+  --kubeconfig      Path to the kubeconfig file
+  --context         Kubeconfig context to use
+`
+
+// command runs a plugin subcommand
+type command func(ctx context.Context, args []string) error
+
+var commands = map[string]command{
+	"status":  runStatus,
+	"logs":    runLogs,
+	"scale":   runScale,
+	"pause":   runPause,
+	"resume":  runResume,
+	"promote": runPromote,
+	"chat":    runChat,
+	"diff":    runDiff,
+	"export":  runExport,
+	"restore": runRestore,
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	err := run(ctx, os.Args[2:])
+	if err == errDiffFound {
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// globalOptions select the cluster and namespace, like kubectl's own flags
+type globalOptions struct {
+	kubeconfig string
+	context    string
+	namespace  string
+}
+
+// newFlagSet returns the flags of a subcommand, including the global flags
+func newFlagSet(name string, opts *globalOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&opts.namespace, "namespace", "", "Namespace of the agent")
+	fs.StringVar(&opts.namespace, "n", "", "Namespace of the agent (shorthand)")
+	fs.StringVar(&opts.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	fs.StringVar(&opts.context, "context", "", "Kubeconfig context to use")
+	return fs
+}
+
+// parseArgs parses flags wherever they appear, as kubectl does, and returns the
+// positional arguments; a negative want accepts any number of them
+func parseArgs(fs *flag.FlagSet, args []string, want int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if want >= 0 && len(positional) != want {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", fs.Name(), want, len(positional))
+	}
+	return positional, nil
+}
+
+// cluster holds the clients of a subcommand
+type cluster struct {
+	client    client.Client
+	clientset kubernetes.Interface
+	config    *rest.Config
+	namespace string
+}
+
+// connect loads the kubeconfig the same way kubectl does
+func connect(opts *globalOptions) (*cluster, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = opts.kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: opts.context}
+	overrides.Context.Namespace = opts.namespace
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := agentopsv1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := agentopsv1beta1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &cluster{client: c, clientset: clientset, config: config, namespace: namespace}, nil
+}
+
+// agentDeployment returns the named AgentDeployment
+func (c *cluster) agentDeployment(ctx context.Context, name string) (*agentopsv1alpha1.AgentDeployment, error) {
+	ad := &agentopsv1alpha1.AgentDeployment{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: name}, ad); err != nil {
+		return nil, err
+	}
+	return ad, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// runPromote finishes a canary: every rule of the AgentRoute that has the agent as a
+// backend sends all of its traffic to it. The other backends are removed from those
+// rules, since a weight of zero is defaulted back to one.
+func runPromote(ctx context.Context, args []string) error {
+	opts := &globalOptions{}
+	fs := newFlagSet("promote", opts)
+	positional, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	routeName, agent := positional[0], positional[1]
+	c, err := connect(opts)
+	if err != nil {
+		return err
+	}
+
+	route := &agentopsv1alpha1.AgentRoute{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: routeName}, route); err != nil {
+		return err
+	}
+	promoted := 0
+	var removed []string
+	for i := range route.Spec.Rules {
+		rule := &route.Spec.Rules[i]
+		var kept []agentopsv1alpha1.AgentRouteBackend
+		var others []string
+		for _, b := range rule.Backends {
+			if b.Name == agent {
+				kept = append(kept, b)
+			} else {
+				others = append(others, b.Name)
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		rule.Backends = kept
+		removed = append(removed, others...)
+		promoted++
+	}
+	if promoted == 0 {
+		return fmt.Errorf("agentroute/%s has no rule with backend %s", route.Name, agent)
+	}
+	if err := c.client.Update(ctx, route); err != nil {
+		return err
+	}
+	fmt.Printf("agentroute/%s: %s receives all traffic of %d rule(s)\n", route.Name, agent, promoted)
+	if len(removed) > 0 {
+		fmt.Printf("removed backends: %s\n", strings.Join(removed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// runRestore applies the objects of an exported bundle, in the bundle's namespace
+// or the one given with -n
+func runRestore(ctx context.Context, args []string) error {
+	opts := &globalOptions{}
+	fs := newFlagSet("restore", opts)
+	filename := fs.String("f", "", "Bundle written by export, or - for stdin")
+	keyFile := fs.String("encryption-key-file", "", "File with the key the bundle's Secrets were encrypted with")
+	dryRun := fs.Bool("dry-run", false, "Send the objects as server-side dry runs only")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if *filename == "" {
+		return fmt.Errorf("-f is required")
+	}
+	b, err := readBundle(*filename)
+	if err != nil {
+		return err
+	}
+	var aead cipher.AEAD
+	if *keyFile != "" {
+		if aead, err = loadBundleKey(*keyFile); err != nil {
+			return err
+		}
+	}
+	namespace := opts.namespace
+	c, err := connect(opts)
+	if err != nil {
+		return err
+	}
+	if namespace == "" {
+		namespace = b.Namespace
+	}
+
+	applyOpts := []client.PatchOption{client.FieldOwner("kubectl-agentops"), client.ForceOwnership}
+	suffix := ""
+	if *dryRun {
+		applyOpts = append(applyOpts, client.DryRunAll)
+		suffix = " (server dry run)"
+	}
+	var pending []string
+	for _, item := range b.Items {
+		obj := &unstructured.Unstructured{Object: item}
+		obj.SetNamespace(namespace)
+		ref := fmt.Sprintf("%s/%s", strings.ToLower(obj.GetKind()), obj.GetName())
+
+		if obj.GetKind() == "Secret" {
+			switch obj.GetAnnotations()[exportedSecretAnnotation] {
+			case secretRedacted:
+				// Redacted values are never written over a live Secret
+				err := c.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: obj.GetName()}, &corev1.Secret{})
+				if apierrors.IsNotFound(err) {
+					pending = append(pending, obj.GetName())
+				} else if err != nil {
+					return err
+				}
+				fmt.Printf("%s skipped: its values are redacted in the bundle\n", ref)
+				continue
+			case secretEncrypted:
+				if aead == nil {
+					return fmt.Errorf("%s is encrypted: pass --encryption-key-file", ref)
+				}
+				if err := decryptSecretValues(obj, aead); err != nil {
+					return fmt.Errorf("%s: %w", ref, err)
+				}
+			}
+		}
+
+		if err := c.client.Patch(ctx, obj, client.Apply, applyOpts...); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", ref, err)
+		}
+		fmt.Printf("%s restored%s\n", ref, suffix)
+	}
+	if len(pending) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: create these Secrets in namespace %s before the agents can start: %s\n",
+			namespace, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// readBundle reads a bundle written by export
+func readBundle(filename string) (*bundle, error) {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b := &bundle{}
+	if err := yaml.Unmarshal(data, b); err != nil {
+		return nil, err
+	}
+	if b.APIVersion != bundleAPIVersion || b.Kind != bundleKind {
+		return nil, fmt.Errorf("%s is not a bundle written by kubectl agentops export", filename)
+	}
+	return b, nil
+}
+
+// decryptSecretValues replaces the encrypted values of a bundle Secret with the
+// plain ones and drops the export annotation
+func decryptSecretValues(obj *unstructured.Unstructured, aead cipher.AEAD) error {
+	data, _, err := unstructured.NestedStringMap(obj.Object, "data")
+	if err != nil {
+		return err
+	}
+	for key, value := range data {
+		sealed, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return err
+		}
+		if len(sealed) < aead.NonceSize() {
+			return fmt.Errorf("value of %s is too short", key)
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		plain, err := aead.Open(nil, nonce, ciphertext, []byte(obj.GetName()+"/"+key))
+		if err != nil {
+			return fmt.Errorf("cannot decrypt %s, wrong key?", key)
+		}
+		data[key] = base64.StdEncoding.EncodeToString(plain)
+	}
+	if err := unstructured.SetNestedStringMap(obj.Object, data, "data"); err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	delete(annotations, exportedSecretAnnotation)
+	obj.SetAnnotations(annotations)
+	return nil
+}
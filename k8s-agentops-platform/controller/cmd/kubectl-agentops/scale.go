@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// runScale sets spec.replicas of an agent
+func runScale(ctx context.Context, args []string) error {
+	opts := &globalOptions{}
+	fs := newFlagSet("scale", opts)
+	replicas := fs.Int("replicas", -1, "Desired number of replicas")
+	positional, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if *replicas < 0 {
+		return fmt.Errorf("--replicas is required")
+	}
+	c, err := connect(opts)
+	if err != nil {
+		return err
+	}
+	ad, err := c.agentDeployment(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	if err := c.mergePatch(ctx, ad, map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": *replicas},
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("agentdeployment/%s scaled to %d\n", ad.Name, *replicas)
+	if ad.Spec.Autoscaling != nil && ad.Spec.Autoscaling.Enabled {
+		fmt.Println("autoscaling is enabled: the replica count is clamped to its bounds and the HPA takes over from there")
+	}
+	return nil
+}
+
+// runPause stops the controller from changing an agent
+func runPause(ctx context.Context, args []string) error {
+	return setPaused(ctx, "pause", args, true)
+}
+
+// runResume lets the controller manage a paused agent again
+func runResume(ctx context.Context, args []string) error {
+	return setPaused(ctx, "resume", args, false)
+}
+
+// setPaused sets or removes the paused annotation of an agent
+func setPaused(ctx context.Context, name string, args []string, paused bool) error {
+	opts := &globalOptions{}
+	fs := newFlagSet(name, opts)
+	positional, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	c, err := connect(opts)
+	if err != nil {
+		return err
+	}
+	ad, err := c.agentDeployment(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	// A null value removes the annotation in a merge patch
+	var value interface{}
+	if paused {
+		value = "true"
+	}
+	if err := c.mergePatch(ctx, ad, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{agentopsv1alpha1.PausedAnnotation: value},
+		},
+	}); err != nil {
+		return err
+	}
+	if paused {
+		fmt.Printf("agentdeployment/%s paused\n", ad.Name)
+	} else {
+		fmt.Printf("agentdeployment/%s resumed\n", ad.Name)
+	}
+	return nil
+}
+
+// mergePatch applies a JSON merge patch to obj
+func (c *cluster) mergePatch(ctx context.Context, obj client.Object, patch map[string]interface{}) error {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	return c.client.Patch(ctx, obj, client.RawPatch(client.Merge.Type(), data))
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// managedBySelector matches every object the controller creates
+var managedBySelector = client.MatchingLabels{"app.kubernetes.io/managed-by": "agentops-controller"}
+
+// runStatus prints an agent, its conditions, the resources it owns and its pods
+func runStatus(ctx context.Context, args []string) error {
+	opts := &globalOptions{}
+	fs := newFlagSet("status", opts)
+	positional, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	c, err := connect(opts)
+	if err != nil {
+		return err
+	}
+	ad, err := c.agentDeployment(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	desired := "-"
+	if ad.Spec.Replicas != nil {
+		desired = fmt.Sprint(*ad.Spec.Replicas)
+	}
+	fmt.Fprintf(w, "Name:\t%s\n", ad.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", ad.Namespace)
+	fmt.Fprintf(w, "Model:\t%s\n", ad.Spec.Model)
+	fmt.Fprintf(w, "Phase:\t%s\n", ad.Status.Phase)
+	fmt.Fprintf(w, "Replicas:\t%d/%d ready (spec %s)\n", ad.Status.ReadyReplicas, ad.Status.Replicas, desired)
+	fmt.Fprintf(w, "Paused:\t%t\n", ad.Annotations[agentopsv1alpha1.PausedAnnotation] == "true")
+	if cost := ad.Status.Cost; cost != nil {
+		fmt.Fprintf(w, "Cost:\tinfra %s/h, tokens %s/day\n", usd(cost.HourlyInfra), usd(cost.DailyTokens))
+	}
+
+	fmt.Fprintln(w, "\nConditions:")
+	fmt.Fprintln(w, "TYPE\tSTATUS\tREASON\tAGE\tMESSAGE")
+	for _, cond := range ad.Status.Conditions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", cond.Type, cond.Status, cond.Reason, age(cond.LastTransitionTime), cond.Message)
+	}
+
+	fmt.Fprintln(w, "\nResources:")
+	fmt.Fprintln(w, "KIND\tNAME\tSTATUS")
+	hasDeployment, err := c.ownedResources(ctx, ad, w)
+	if err != nil || !hasDeployment {
+		return err
+	}
+
+	pods, err := c.agentPods(ctx, ad)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "\nPods:")
+	fmt.Fprintln(w, "NAME\tREADY\tSTATUS\tRESTARTS\tAGE\tNODE")
+	for _, pod := range pods {
+		ready, restarts := 0, int32(0)
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Ready {
+				ready++
+			}
+			restarts += cs.RestartCount
+		}
+		fmt.Fprintf(w, "%s\t%d/%d\t%s\t%d\t%s\t%s\n", pod.Name, ready, len(pod.Spec.Containers), podStatus(&pod),
+			restarts, age(pod.CreationTimestamp), pod.Spec.NodeName)
+	}
+	return nil
+}
+
+// ownedResources prints the child resources controlled by the agent and reports
+// whether its Deployment exists yet
+func (c *cluster) ownedResources(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, w *tabwriter.Writer) (bool, error) {
+	inNamespace := client.InNamespace(ad.Namespace)
+
+	hasDeployment := false
+	deployments := &appsv1.DeploymentList{}
+	if err := c.client.List(ctx, deployments, inNamespace, managedBySelector); err != nil {
+		return false, err
+	}
+	for i := range deployments.Items {
+		dep := &deployments.Items[i]
+		if !metav1.IsControlledBy(dep, ad) {
+			continue
+		}
+		fmt.Fprintf(w, "Deployment\t%s\t%d/%d ready, %d updated\n", dep.Name, dep.Status.ReadyReplicas, dep.Status.Replicas, dep.Status.UpdatedReplicas)
+		if dep.Name == ad.Name {
+			hasDeployment = true
+		}
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := c.client.List(ctx, statefulSets, inNamespace, managedBySelector); err != nil {
+		return false, err
+	}
+	for _, sts := range statefulSets.Items {
+		if metav1.IsControlledBy(&sts, ad) {
+			fmt.Fprintf(w, "StatefulSet\t%s\t%d/%d ready\n", sts.Name, sts.Status.ReadyReplicas, sts.Status.Replicas)
+		}
+	}
+
+	services := &corev1.ServiceList{}
+	if err := c.client.List(ctx, services, inNamespace, managedBySelector); err != nil {
+		return false, err
+	}
+	for _, svc := range services.Items {
+		if !metav1.IsControlledBy(&svc, ad) {
+			continue
+		}
+		status := fmt.Sprintf("%s %s", svc.Spec.Type, svc.Spec.ClusterIP)
+		if svc.Spec.Selector == nil {
+			status += ", routed to the activator"
+		}
+		fmt.Fprintf(w, "Service\t%s\t%s\n", svc.Name, status)
+	}
+
+	hpas := &autoscalingv2.HorizontalPodAutoscalerList{}
+	if err := c.client.List(ctx, hpas, inNamespace, managedBySelector); err != nil {
+		return false, err
+	}
+	for _, hpa := range hpas.Items {
+		if !metav1.IsControlledBy(&hpa, ad) {
+			continue
+		}
+		minReplicas := int32(1)
+		if hpa.Spec.MinReplicas != nil {
+			minReplicas = *hpa.Spec.MinReplicas
+		}
+		fmt.Fprintf(w, "HorizontalPodAutoscaler\t%s\t%d current, %d desired, %d-%d\n", hpa.Name,
+			hpa.Status.CurrentReplicas, hpa.Status.DesiredReplicas, minReplicas, hpa.Spec.MaxReplicas)
+	}
+
+	ingresses := &networkingv1.IngressList{}
+	if err := c.client.List(ctx, ingresses, inNamespace, managedBySelector); err != nil {
+		return false, err
+	}
+	for _, ing := range ingresses.Items {
+		if !metav1.IsControlledBy(&ing, ad) {
+			continue
+		}
+		var hosts []string
+		for _, rule := range ing.Spec.Rules {
+			hosts = append(hosts, rule.Host)
+		}
+		fmt.Fprintf(w, "Ingress\t%s\t%s\n", ing.Name, strings.Join(hosts, ","))
+	}
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := c.client.List(ctx, configMaps, inNamespace, managedBySelector); err != nil {
+		return false, err
+	}
+	for _, cm := range configMaps.Items {
+		if metav1.IsControlledBy(&cm, ad) {
+			fmt.Fprintf(w, "ConfigMap\t%s\t%d keys\n", cm.Name, len(cm.Data)+len(cm.BinaryData))
+		}
+	}
+	return hasDeployment, nil
+}
+
+// podStatus returns the status kubectl get pods shows: the waiting or terminated
+// reason of the first unhealthy container, or the pod phase
+func podStatus(pod *corev1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return "Terminating"
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+			return cs.State.Waiting.Reason
+		}
+		if cs.State.Terminated != nil && cs.State.Terminated.Reason != "" {
+			return cs.State.Terminated.Reason
+		}
+	}
+	return string(pod.Status.Phase)
+}
+
+// age formats the time since t like kubectl
+func age(t metav1.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(t.Time))
+}
+
+// usd formats an optional amount in USD
+func usd(q *resource.Quantity) string {
+	if q == nil {
+		return "-"
+	}
+	return fmt.Sprintf("$%.2f", q.AsApproximateFloat64())
+}
@@ -1,21 +1,52 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/activator"
 	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	agentopsv1beta1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1beta1"
 	"github.com/yourusername/k8s-agentops-platform/controller/pkg/controllers"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/cosign"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/cost"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/evaluation"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/feedback"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/fleet"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/hooks"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/keybroker"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/logging"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/metering"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/notify"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/observe"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/providerhealth"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/registry"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tasks"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/usage"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/warmup"
 )
 
 var (
@@ -26,50 +57,619 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(agentopsv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(agentopsv1beta1.AddToScheme(scheme))
 }
 
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var taskWorkers int
+	var prometheusURL string
+	var mode string
+	var dryRun bool
+	var dryRunReport string
+	var logConfig string
+	var activatorAddr string
+	var activatorService string
+	var podTemplatePatch string
+	var otlpEndpoint string
+	var debugAddr, debugTokenFile string
+	var resyncPeriod time.Duration
+	var maxConcurrentReconciles int
+	var rateLimiterBaseDelay, rateLimiterMaxDelay time.Duration
+	var rateLimiterQPS float64
+	var rateLimiterBurst int
+	var kubeAPIQPS float64
+	var kubeAPIBurst int
+	var watchNamespaces string
+	var watchSelector string
+	var leaderElectionID string
+	var enableWebhooks bool
+	var keyBrokerURL, keyBrokerType, keyBrokerTokenFile string
+	var notificationConfig string
+	var meteringInterval, reportPeriod, gcInterval time.Duration
+	var reportBucket, reportRegion, reportEndpoint, reportPrefix, reportFormat string
+	prices := cost.DefaultPrices
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.IntVar(&taskWorkers, "task-workers", 4, "Number of AgentTasks (slow external operations) executed concurrently.")
+	flag.StringVar(&prometheusURL, "prometheus-url", "http://prometheus-operated.monitoring.svc:9090",
+		"Prometheus server queried for agent token usage by TokenBudgets and status.cost, traffic for scale-to-zero, "+
+			"container usage for resource recommendations and GPU utilization for status.gpu.")
+	flag.Float64Var(&prices.CPUCoreHour, "price-cpu-core-hour", prices.CPUCoreHour,
+		"Hourly price in USD of a requested CPU core, used to estimate status.cost.hourlyInfra.")
+	flag.Float64Var(&prices.MemoryGiBHour, "price-memory-gib-hour", prices.MemoryGiBHour,
+		"Hourly price in USD of a requested GiB of memory, used to estimate status.cost.hourlyInfra.")
+	flag.Float64Var(&prices.GPUHour, "price-gpu-hour", prices.GPUHour,
+		"Hourly price in USD of a requested GPU, used to estimate status.cost.hourlyInfra.")
+	flag.StringVar(&activatorAddr, "activator-bind-address", ":8082",
+		"The address the activator serves requests for agents scaled to zero on.")
+	flag.StringVar(&activatorService, "activator-service", "agentops-system/agentops-activator",
+		"Namespace/name of the Service in front of the activator; agent Services point at its endpoints while scaled to zero.")
+	flag.StringVar(&podTemplatePatch, "pod-template-patch", "",
+		"Namespace/name of a ConfigMap whose patch.yaml, a Go template of a strategic merge patch, is applied to every agent pod template; disabled when empty.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"OTLP/HTTP endpoint (e.g. http://otel-collector.observability:4318) reconcile traces are exported to; tracing is off when empty.")
+	flag.StringVar(&debugAddr, "debug-bind-address", "",
+		"The address pprof profiles, /debug/reconciles and /debug/reconcile are served on; the debug server is off when empty.")
+	flag.StringVar(&debugTokenFile, "debug-token-file", "",
+		"File holding the bearer token required by the debug server; read on every request.")
+	flag.DurationVar(&resyncPeriod, "resync-period", 0,
+		"Reconcile every AgentDeployment at this interval in addition to watch events (e.g. 5m); 0 disables periodic resyncs.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"Number of objects each controller reconciles concurrently (AgentTasks use --task-workers).")
+	flag.DurationVar(&rateLimiterBaseDelay, "rate-limiter-base-delay", 5*time.Millisecond,
+		"Initial requeue delay of a failing object; doubled on every consecutive failure.")
+	flag.DurationVar(&rateLimiterMaxDelay, "rate-limiter-max-delay", 1000*time.Second,
+		"Maximum requeue delay of a failing object.")
+	flag.Float64Var(&rateLimiterQPS, "rate-limiter-qps", 10,
+		"Overall rate, per controller, at which requeued objects are let back into the workqueue.")
+	flag.IntVar(&rateLimiterBurst, "rate-limiter-burst", 100,
+		"Burst of the per-controller workqueue rate limiter.")
+	flag.Float64Var(&kubeAPIQPS, "kube-api-qps", 20, "Queries per second the controller may send to the Kubernetes API.")
+	flag.IntVar(&kubeAPIBurst, "kube-api-burst", 30, "Burst of queries the controller may send to the Kubernetes API.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"Comma-separated namespaces the controller watches; all namespaces when empty.")
+	flag.StringVar(&watchSelector, "watch-selector", "",
+		"Label selector (e.g. agentops.io/tenant-set=blue) limiting the AgentOps resources this instance reconciles.")
+	flag.StringVar(&leaderElectionID, "leader-election-id", "agentops.io",
+		"Leader election lock name; give every controller instance of a tenant set its own.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", true,
+		"Serve the AgentDeployment conversion webhook (v1alpha1 <-> v1beta1); needs serving certificates in the webhook cert dir.")
+	flag.StringVar(&keyBrokerURL, "key-broker-url", "",
+		"Base URL of the key broker issuing per-agent API keys for secrets with provider broker; the provider is disabled when empty.")
+	flag.StringVar(&keyBrokerType, "key-broker-type", keybroker.TypeGeneric,
+		"Protocol of the key broker: generic (POST /keys, DELETE /keys/<id>) or litellm (LiteLLM proxy virtual keys).")
+	flag.StringVar(&keyBrokerTokenFile, "key-broker-token-file", "",
+		"File holding the bearer token sent to the key broker (e.g. a LiteLLM master key); read on every call.")
+	flag.StringVar(&notificationConfig, "notification-config", "",
+		"File defining the Slack, webhook and PagerDuty channels AgentDeployments send notifications to; notifications are disabled when empty.")
+	flag.DurationVar(&gcInterval, "gc-interval", time.Minute,
+		"Interval at which expired AgentJobs and AgentWorkflows and inactive AgentDeployments are garbage collected.")
+	flag.DurationVar(&meteringInterval, "metering-interval", time.Minute,
+		"Interval at which agent token usage is read from Prometheus and exported as agentops_usage_* metrics; 0 disables metering.")
+	flag.StringVar(&reportBucket, "usage-report-bucket", "",
+		"S3 bucket usage reports are written to, with the credentials of the AWS_* environment variables; reports are off when empty.")
+	flag.StringVar(&reportRegion, "usage-report-region", "us-east-1", "Region of the usage report bucket.")
+	flag.StringVar(&reportEndpoint, "usage-report-endpoint", "",
+		"Endpoint of an S3-compatible object store (e.g. http://minio.storage:9000) instead of AWS.")
+	flag.StringVar(&reportPrefix, "usage-report-prefix", "agentops/usage/", "Prefix of the usage report object keys.")
+	flag.DurationVar(&reportPeriod, "usage-report-period", 24*time.Hour,
+		"Period each usage report covers; reports are written after every period, aligned in UTC.")
+	flag.StringVar(&reportFormat, "usage-report-format", metering.FormatCSV, "Format of the usage reports: csv or json.")
+	flag.StringVar(&mode, "mode", observe.ModeEnforce,
+		"Controller mode: enforce applies changes, observe only computes desired state and reports drift "+
+			"(all writes are sent as server-side dry runs).")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Shorthand for --mode=observe: compute and log every change without applying it, e.g. when upgrading the controller.")
+	flag.StringVar(&dryRunReport, "dry-run-report", "",
+		"Namespace/name of a ConfigMap the writes skipped in observe mode are reported to every minute; off when empty.")
+	flag.StringVar(&logConfig, "log-config", "",
+		"Namespace/name of a ConfigMap whose level key (debug, info, error or a V-level) overrides --zap-log-level at runtime; off when empty.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 
-	opts := zap.Options{
-		Development: true,
-	}
+	// JSON lines at info level; --zap-devel switches to console output at debug
+	opts := zap.Options{}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	ctrl.SetLogger(logging.New(&opts))
+
+	if dryRun {
+		mode = observe.ModeObserve
+	}
+	if mode != observe.ModeEnforce && mode != observe.ModeObserve {
+		setupLog.Error(nil, "invalid --mode, expected enforce or observe", "mode", mode)
+		os.Exit(1)
+	}
+	if keyBrokerType != keybroker.TypeGeneric && keyBrokerType != keybroker.TypeLiteLLM {
+		setupLog.Error(nil, "invalid --key-broker-type, expected generic or litellm", "type", keyBrokerType)
+		os.Exit(1)
+	}
+	if reportFormat != metering.FormatCSV && reportFormat != metering.FormatJSON {
+		setupLog.Error(nil, "invalid --usage-report-format, expected csv or json", "format", reportFormat)
+		os.Exit(1)
+	}
+	if gcInterval <= 0 {
+		setupLog.Error(nil, "invalid --gc-interval, expected a positive duration", "interval", gcInterval)
+		os.Exit(1)
+	}
+	if reportBucket != "" && reportPeriod <= 0 {
+		setupLog.Error(nil, "invalid --usage-report-period, expected a positive duration", "period", reportPeriod)
+		os.Exit(1)
+	}
+	if debugAddr != "" && debugTokenFile == "" {
+		setupLog.Error(nil, "--debug-bind-address requires --debug-token-file")
+		os.Exit(1)
+	}
+	observing := mode == observe.ModeObserve
+	if dryRunReport != "" && !observing {
+		setupLog.Error(nil, "--dry-run-report requires --dry-run or --mode=observe")
+		os.Exit(1)
+	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	ctx := ctrl.SetupSignalHandler()
+	shutdownTracing, err := tracing.Setup(ctx, otlpEndpoint, "agentops-controller")
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
+	activatorNamespace, activatorName, ok := strings.Cut(activatorService, "/")
+	if !ok {
+		setupLog.Error(nil, "invalid --activator-service, expected namespace/name", "activator-service", activatorService)
+		os.Exit(1)
+	}
+
+	var templatePatch types.NamespacedName
+	if podTemplatePatch != "" {
+		patchNamespace, patchName, ok := strings.Cut(podTemplatePatch, "/")
+		if !ok {
+			setupLog.Error(nil, "invalid --pod-template-patch, expected namespace/name", "pod-template-patch", podTemplatePatch)
+			os.Exit(1)
+		}
+		templatePatch = types.NamespacedName{Namespace: patchNamespace, Name: patchName}
+	}
+
+	// One instance per tenant set: restrict the cache to the watched namespaces and
+	// the AgentOps resources matching the selector
+	selector, err := labels.Parse(watchSelector)
+	if err != nil {
+		setupLog.Error(err, "invalid --watch-selector", "watch-selector", watchSelector)
+		os.Exit(1)
+	}
+	var namespaces []string
+	if watchNamespaces != "" {
+		// The activator's Endpoints are read from its own namespace
+		namespaces = append(strings.Split(watchNamespaces, ","), activatorNamespace)
+		// So is the pod template patch
+		if templatePatch.Namespace != "" {
+			namespaces = append(namespaces, templatePatch.Namespace)
+		}
+	}
+
+	restConfig := ctrl.GetConfigOrDie()
+	restConfig.QPS = float32(kubeAPIQPS)
+	restConfig.Burst = kubeAPIBurst
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
 			BindAddress: metricsAddr,
 		},
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "agentops.io",
+		LeaderElectionID:       leaderElectionID,
+		Cache:                  cacheOptions(namespaces, selector),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	// In observe mode every reconciler gets a client that dry-runs its writes, and
+	// side effects outside the cluster (hooks, AgentTasks, evaluations, workflow
+	// steps, events, notifications) are disabled
+	kubeClient := mgr.GetClient()
+	var hookClient *hooks.Client
+	var warmer controllers.Warmer
+	var evaluator controllers.Evaluator
+	var recorder record.EventRecorder
+	var gcRecorder record.EventRecorder
+	var broker controllers.KeyBroker
+	var notifier controllers.Notifier
+	// Writes to member clusters are dry-run like those to the hub
+	var wrapMember func(client.Client) client.Client
+	if observing {
+		setupLog.Info("running in observe mode: no changes will be persisted")
+		kubeClient = observe.NewClient(kubeClient, ctrl.Log.WithName("observe"))
+		wrapMember = func(c client.Client) client.Client { return observe.NewClient(c, ctrl.Log.WithName("observe")) }
+		if dryRunReport != "" {
+			reportNamespace, reportName, ok := strings.Cut(dryRunReport, "/")
+			if !ok {
+				setupLog.Error(nil, "invalid --dry-run-report, expected namespace/name", "dry-run-report", dryRunReport)
+				os.Exit(1)
+			}
+			// The report is the one write persisted in observe mode
+			if err := mgr.Add(&observe.ReportWriter{
+				Client:    mgr.GetClient(),
+				Log:       ctrl.Log.WithName("observe"),
+				Namespace: reportNamespace,
+				Name:      reportName,
+				Interval:  time.Minute,
+			}); err != nil {
+				setupLog.Error(err, "unable to set up dry-run report")
+				os.Exit(1)
+			}
+		}
+	} else {
+		hookClient = hooks.NewClient()
+		warmupClient, err := warmup.NewClient(restConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to create warmup client")
+			os.Exit(1)
+		}
+		warmer = warmupClient
+		evaluator = evaluation.NewClient()
+		recorder = mgr.GetEventRecorderFor("agentdeployment-controller")
+		gcRecorder = mgr.GetEventRecorderFor("agentops-gc")
+		if keyBrokerURL != "" {
+			broker = keybroker.NewClient(keyBrokerURL, keyBrokerType, keyBrokerTokenFile)
+		}
+		if notificationConfig != "" {
+			cfg, err := notify.LoadConfig(notificationConfig)
+			if err != nil {
+				setupLog.Error(err, "unable to load notification config")
+				os.Exit(1)
+			}
+			notifier = notify.NewClient(cfg)
+		}
+	}
+	// Kubernetes API calls show up as child spans of the reconcile that made them
+	kubeClient = tracing.NewClient(kubeClient)
+
+	// Every controller gets its own workqueue rate limiter: per-object exponential
+	// backoff, capped by an overall token bucket
+	controllerOptions := func() controller.Options {
+		return controller.Options{
+			MaxConcurrentReconciles: maxConcurrentReconciles,
+			RateLimiter: workqueue.NewMaxOfRateLimiter(
+				workqueue.NewItemExponentialFailureRateLimiter(rateLimiterBaseDelay, rateLimiterMaxDelay),
+				&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(rateLimiterQPS), rateLimiterBurst)},
+			),
+		}
+	}
+
+	// Token usage for TokenBudgets, request rates for scale-to-zero and hibernation,
+	// container usage for resource recommendations and GPU utilization
+	metrics := usage.NewPrometheus(prometheusURL)
+
+	// Reconciles queued on demand by the debug server
+	var triggers chan event.GenericEvent
+	if debugAddr != "" {
+		triggers = make(chan event.GenericEvent, 16)
+	}
+
+	// Image digests for spec.imageUpdatePolicy and cosign verification
+	images := registry.NewClient()
+
 	if err = (&controllers.AgentDeploymentReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Log:    ctrl.Log.WithName("controllers").WithName("AgentDeployment"),
+		Client:            kubeClient,
+		Scheme:            mgr.GetScheme(),
+		Log:               ctrl.Log.WithName("controllers").WithName("AgentDeployment"),
+		Hooks:             hookClient,
+		Recorder:          recorder,
+		Notifier:          notifier,
+		Warmer:            warmer,
+		Signatures:        cosign.NewVerifier(images),
+		Images:            images,
+		Pulls:             images,
+		PrometheusAddress: prometheusURL,
+		Activity:          metrics,
+		Usage:             metrics,
+		Tokens:            metrics,
+		GPUs:              metrics,
+		Providers:         providerhealth.NewProber(providerhealth.DefaultTTL),
+		Prices:            prices,
+		ActivatorService:  types.NamespacedName{Namespace: activatorNamespace, Name: activatorName},
+		PodTemplatePatch:  templatePatch,
+		ResyncPeriod:      resyncPeriod,
+		Triggers:          triggers,
+		Options:           controllerOptions(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AgentDeployment")
 		os.Exit(1)
 	}
 
+	if err := mgr.Add(&controllers.GarbageCollector{
+		Client:   kubeClient,
+		Log:      ctrl.Log.WithName("gc"),
+		Recorder: gcRecorder,
+		Activity: metrics,
+		Interval: gcInterval,
+	}); err != nil {
+		setupLog.Error(err, "unable to set up garbage collector")
+		os.Exit(1)
+	}
+
+	if err := mgr.Add(&activator.Activator{
+		Client:  kubeClient,
+		Log:     ctrl.Log.WithName("activator"),
+		Addr:    activatorAddr,
+		Timeout: 5 * time.Minute,
+	}); err != nil {
+		setupLog.Error(err, "unable to set up activator")
+		os.Exit(1)
+	}
+
+	if logConfig != "" {
+		logNamespace, logName, ok := strings.Cut(logConfig, "/")
+		if !ok {
+			setupLog.Error(nil, "invalid --log-config, expected namespace/name", "log-config", logConfig)
+			os.Exit(1)
+		}
+		if err := mgr.Add(&logging.ConfigWatcher{
+			Reader:   mgr.GetAPIReader(),
+			Log:      ctrl.Log.WithName("logging"),
+			Key:      types.NamespacedName{Namespace: logNamespace, Name: logName},
+			Interval: 30 * time.Second,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up log config")
+			os.Exit(1)
+		}
+	}
+
+	if debugAddr != "" {
+		if err := mgr.Add(&debug.Server{
+			Client:    kubeClient,
+			Log:       ctrl.Log.WithName("debug"),
+			Addr:      debugAddr,
+			TokenFile: debugTokenFile,
+			Trigger:   triggers,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up debug server")
+			os.Exit(1)
+		}
+	}
+
+	if meteringInterval > 0 {
+		meter := &metering.Meter{
+			Source:       metrics,
+			Log:          ctrl.Log.WithName("metering"),
+			Interval:     meteringInterval,
+			ReportPeriod: reportPeriod,
+			Prefix:       reportPrefix,
+			Format:       reportFormat,
+		}
+		// Reports are written outside the cluster, so not in observe mode
+		if reportBucket != "" && !observing {
+			meter.Store = metering.NewS3Store(reportBucket, reportRegion, reportEndpoint)
+		}
+		if err := mgr.Add(meter); err != nil {
+			setupLog.Error(err, "unable to set up metering")
+			os.Exit(1)
+		}
+	}
+
+	if err = (&controllers.AgentPoolReconciler{
+		Client:  kubeClient,
+		Scheme:  mgr.GetScheme(),
+		Log:     ctrl.Log.WithName("controllers").WithName("AgentPool"),
+		Options: controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AgentPool")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.PromptTemplateReconciler{
+		Client:  kubeClient,
+		Scheme:  mgr.GetScheme(),
+		Log:     ctrl.Log.WithName("controllers").WithName("PromptTemplate"),
+		Options: controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PromptTemplate")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.AgentRouteReconciler{
+		Client:  kubeClient,
+		Scheme:  mgr.GetScheme(),
+		Log:     ctrl.Log.WithName("controllers").WithName("AgentRoute"),
+		Options: controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AgentRoute")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.TokenBudgetReconciler{
+		Client:  kubeClient,
+		Scheme:  mgr.GetScheme(),
+		Log:     ctrl.Log.WithName("controllers").WithName("TokenBudget"),
+		Usage:   metrics,
+		Options: controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TokenBudget")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.RateLimitPolicyReconciler{
+		Client:  kubeClient,
+		Scheme:  mgr.GetScheme(),
+		Log:     ctrl.Log.WithName("controllers").WithName("RateLimitPolicy"),
+		Options: controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RateLimitPolicy")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.TenantQuotaReconciler{
+		Client:  kubeClient,
+		Scheme:  mgr.GetScheme(),
+		Log:     ctrl.Log.WithName("controllers").WithName("TenantQuota"),
+		Options: controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TenantQuota")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.AgentJobReconciler{
+		Client:  kubeClient,
+		Scheme:  mgr.GetScheme(),
+		Log:     ctrl.Log.WithName("controllers").WithName("AgentJob"),
+		Options: controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AgentJob")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.EvaluationRunReconciler{
+		Client:    kubeClient,
+		Scheme:    mgr.GetScheme(),
+		Log:       ctrl.Log.WithName("controllers").WithName("EvaluationRun"),
+		Evaluator: evaluator,
+		Options:   controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "EvaluationRun")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.AgentExperimentReconciler{
+		Client:   kubeClient,
+		Scheme:   mgr.GetScheme(),
+		Log:      ctrl.Log.WithName("controllers").WithName("AgentExperiment"),
+		Metrics:  metrics,
+		Feedback: feedback.NewClient(),
+		Options:  controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AgentExperiment")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.APIKeyReconciler{
+		Client:  kubeClient,
+		Scheme:  mgr.GetScheme(),
+		Log:     ctrl.Log.WithName("controllers").WithName("APIKey"),
+		Broker:  broker,
+		Options: controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "APIKey")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.AgentFleetReconciler{
+		Client:   kubeClient,
+		Scheme:   mgr.GetScheme(),
+		Log:      ctrl.Log.WithName("controllers").WithName("AgentFleet"),
+		Clusters: fleet.NewClients(mgr.GetScheme(), wrapMember),
+		Options:  controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AgentFleet")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.AgentScheduleReconciler{
+		Client:  kubeClient,
+		Scheme:  mgr.GetScheme(),
+		Log:     ctrl.Log.WithName("controllers").WithName("AgentSchedule"),
+		Options: controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AgentSchedule")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.AgentPreviewReconciler{
+		Client:   kubeClient,
+		Scheme:   mgr.GetScheme(),
+		Log:      ctrl.Log.WithName("controllers").WithName("AgentPreview"),
+		Activity: metrics,
+		Options:  controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AgentPreview")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.ModelCacheReconciler{
+		Client:  kubeClient,
+		Scheme:  mgr.GetScheme(),
+		Log:     ctrl.Log.WithName("controllers").WithName("ModelCache"),
+		Options: controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ModelCache")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.RAGPipelineReconciler{
+		Client:  kubeClient,
+		Scheme:  mgr.GetScheme(),
+		Log:     ctrl.Log.WithName("controllers").WithName("RAGPipeline"),
+		Options: controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RAGPipeline")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.ToolServerReconciler{
+		Client:  kubeClient,
+		Scheme:  mgr.GetScheme(),
+		Log:     ctrl.Log.WithName("controllers").WithName("ToolServer"),
+		Options: controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ToolServer")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.AgentWorkflowReconciler{
+		Client:  kubeClient,
+		Scheme:  mgr.GetScheme(),
+		Log:     ctrl.Log.WithName("controllers").WithName("AgentWorkflow"),
+		Runner:  evaluator,
+		Options: controllerOptions(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AgentWorkflow")
+		os.Exit(1)
+	}
+
+	if !observing {
+		taskHandlers := tasks.NewRegistry()
+		taskHandlers.Register(controllers.WeightSwapTaskType, controllers.NewWeightSwapHandler(kubeClient, nil))
+		if err = (&controllers.AgentTaskReconciler{
+			Client:   kubeClient,
+			Scheme:   mgr.GetScheme(),
+			Log:      ctrl.Log.WithName("controllers").WithName("AgentTask"),
+			Handlers: taskHandlers,
+			Workers:  taskWorkers,
+			Options:  controllerOptions(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "AgentTask")
+			os.Exit(1)
+		}
+	}
+
+	if enableWebhooks {
+		if err = (&agentopsv1alpha1.AgentDeployment{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AgentDeployment")
+			os.Exit(1)
+		}
+		// Observing must not turn away changes users make
+		if !observing {
+			validator := &controllers.AgentDeploymentValidator{Reader: mgr.GetClient()}
+			if err = validator.SetupWebhookWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create webhook", "webhook", "AgentDeployment validation")
+				os.Exit(1)
+			}
+			defaulter := &controllers.AgentDeploymentDefaulter{Reader: mgr.GetClient()}
+			if err = defaulter.SetupWebhookWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create webhook", "webhook", "AgentDeployment defaulting")
+				os.Exit(1)
+			}
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -80,8 +680,50 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// cacheOptions scopes the manager cache to namespaces (all when empty) and applies
+// selector to the AgentOps resources users create. AgentTasks are created by the
+// controller and stay unfiltered; child objects are found through their owners.
+func cacheOptions(namespaces []string, selector labels.Selector) cache.Options {
+	opts := cache.Options{}
+	if len(namespaces) > 0 {
+		opts.DefaultNamespaces = map[string]cache.Config{}
+		for _, ns := range namespaces {
+			if ns = strings.TrimSpace(ns); ns != "" {
+				opts.DefaultNamespaces[ns] = cache.Config{}
+			}
+		}
+	}
+	if selector.Empty() {
+		return opts
+	}
+	opts.ByObject = map[client.Object]cache.ByObject{}
+	for _, obj := range []client.Object{
+		&agentopsv1alpha1.AgentDeployment{},
+		&agentopsv1alpha1.AgentPool{},
+		&agentopsv1alpha1.AgentRoute{},
+		&agentopsv1alpha1.AgentJob{},
+		&agentopsv1alpha1.AgentSchedule{},
+		&agentopsv1alpha1.AgentPreview{},
+		&agentopsv1alpha1.PromptTemplate{},
+		&agentopsv1alpha1.TokenBudget{},
+		&agentopsv1alpha1.RateLimitPolicy{},
+		&agentopsv1alpha1.ModelCache{},
+		&agentopsv1alpha1.TenantQuota{},
+		&agentopsv1alpha1.ModelPolicy{},
+		&agentopsv1alpha1.ToolServer{},
+		&agentopsv1alpha1.AgentWorkflow{},
+		&agentopsv1alpha1.AgentFleet{},
+		&agentopsv1alpha1.AgentExperiment{},
+		&agentopsv1alpha1.RAGPipeline{},
+		&agentopsv1alpha1.EvaluationRun{},
+	} {
+		opts.ByObject[obj] = cache.ByObject{Label: selector}
+	}
+	return opts
+}
@@ -0,0 +1,197 @@
+package activator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// ActivatedAtAnnotation records on an AgentDeployment when the activator last
+// received a request for it; the AgentDeployment controller scales the agent back
+// up while the annotation is younger than the idle timeout
+const ActivatedAtAnnotation = "agentops.io/activated-at"
+
+const (
+	pollInterval = 500 * time.Millisecond
+	// annotateInterval throttles annotation patches under a burst of held requests
+	annotateInterval = 10 * time.Second
+)
+
+var errNoAgent = errors.New("no scaled-to-zero AgentDeployment matches the request host")
+
+// Activator holds requests for AgentDeployments scaled to zero, triggers their
+// scale-up and forwards the requests once a pod is ready. Agent Services point at
+// the activator while they have no ready pods.
+type Activator struct {
+	// Client reads AgentDeployments and pods from the cache and patches activations
+	Client client.Client
+
+	Log logr.Logger
+
+	// Addr is the address the activator listens on
+	Addr string
+
+	// Timeout bounds how long a request is held waiting for a cold start
+	Timeout time.Duration
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+// Start serves until ctx is cancelled; it implements manager.Runnable
+func (a *Activator) Start(ctx context.Context) error {
+	srv := &http.Server{Addr: a.Addr, Handler: a}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	a.Log.Info("Starting activator", "Addr", a.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection is false: every controller replica serves held requests
+func (a *Activator) NeedLeaderElection() bool {
+	return false
+}
+
+// ServeHTTP holds the request until the target agent has a ready pod and proxies it there
+func (a *Activator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), a.Timeout)
+	defer cancel()
+
+	ad, err := a.agentForHost(ctx, req.Host)
+	if err != nil {
+		a.Log.Info("Rejecting request", "Host", req.Host, "Reason", err.Error())
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if err := a.activate(ctx, ad); err != nil {
+		a.Log.Error(err, "Failed to activate AgentDeployment", "AgentDeployment", ad.Name, "Namespace", ad.Namespace)
+	}
+
+	target, err := a.waitForPod(ctx, ad)
+	if err != nil {
+		a.Log.Info("Cold start timed out", "AgentDeployment", ad.Name, "Namespace", ad.Namespace)
+		http.Error(w, fmt.Sprintf("agent %s/%s did not become ready in time", ad.Namespace, ad.Name), http.StatusServiceUnavailable)
+		return
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	// Stream tokens as the agent produces them
+	proxy.FlushInterval = -1
+	proxy.ServeHTTP(w, req)
+}
+
+// agentForHost resolves the AgentDeployment a request was sent to from its Host
+// header: <service>.<namespace>[.svc[.cluster.local]] or a bare service name when it
+// is unique across namespaces
+func (a *Activator) agentForHost(ctx context.Context, host string) (*agentopsv1alpha1.AgentDeployment, error) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	parts := strings.Split(host, ".")
+	name := parts[0]
+
+	list := &agentopsv1alpha1.AgentDeploymentList{}
+	var opts []client.ListOption
+	if len(parts) > 1 {
+		opts = append(opts, client.InNamespace(parts[1]))
+	}
+	if err := a.Client.List(ctx, list, opts...); err != nil {
+		return nil, err
+	}
+	var match *agentopsv1alpha1.AgentDeployment
+	for i := range list.Items {
+		ad := &list.Items[i]
+		if ad.Name != name || ad.Spec.ScaleToZero == nil || !ad.Spec.ScaleToZero.Enabled {
+			continue
+		}
+		if match != nil {
+			return nil, fmt.Errorf("host %q is ambiguous, use <name>.<namespace>", host)
+		}
+		match = ad
+	}
+	if match == nil {
+		return nil, errNoAgent
+	}
+	return match, nil
+}
+
+// activate records the request on the AgentDeployment so the controller scales it up
+func (a *Activator) activate(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	if last, err := time.Parse(time.RFC3339, ad.Annotations[ActivatedAtAnnotation]); err == nil && time.Since(last) < annotateInterval {
+		return nil
+	}
+	patch := client.MergeFrom(ad.DeepCopy())
+	if ad.Annotations == nil {
+		ad.Annotations = map[string]string{}
+	}
+	ad.Annotations[ActivatedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	return a.Client.Patch(ctx, ad, patch)
+}
+
+// waitForPod polls until a pod of the agent is ready and returns its URL
+func (a *Activator) waitForPod(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (*url.URL, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		pods := &corev1.PodList{}
+		if err := a.Client.List(ctx, pods, client.InNamespace(ad.Namespace), client.MatchingLabels{
+			"app.kubernetes.io/name":     "agent",
+			"app.kubernetes.io/instance": ad.Name,
+		}); err != nil {
+			return nil, err
+		}
+		for i := range pods.Items {
+			if target := readyPodURL(&pods.Items[i]); target != nil {
+				return target, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// readyPodURL returns the URL of the pod's "http" port, or nil when the pod is not ready
+func readyPodURL(pod *corev1.Pod) *url.URL {
+	if pod.DeletionTimestamp != nil || pod.Status.PodIP == "" {
+		return nil
+	}
+	ready := false
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
+			ready = true
+		}
+	}
+	if !ready {
+		return nil
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == "http" {
+				return &url.URL{Scheme: "http", Host: net.JoinHostPort(pod.Status.PodIP, fmt.Sprint(p.ContainerPort))}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+// Package conditions defines the status condition types and reasons that
+// AgentDeployment and related resources publish. The string values are a public,
+// versioned contract: dashboards, GitOps health checks and alerting rules match on
+// them, so existing values must never be renamed or removed within a contract
+// version. New types and reasons may be added.
+package conditions
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ContractVersion is the version of the condition contract. It is bumped only when
+// an existing type or reason changes meaning or is removed.
+const ContractVersion = "v1"
+
+// Condition types
+const (
+	// Ready is True when all desired replicas are ready to serve traffic
+	Ready = "Ready"
+
+	// Available is True when at least one replica is available
+	Available = "Available"
+
+	// Progressing is True while a rollout or scale operation is in flight
+	Progressing = "Progressing"
+
+	// Degraded is True when pods are failing (crash loops, image pull errors, ...)
+	Degraded = "Degraded"
+
+	// BudgetExceeded is True when a TokenBudget covering the agent is blown
+	BudgetExceeded = "BudgetExceeded"
+
+	// Complete is True when a one-shot run succeeded, False while it runs or after it failed
+	Complete = "Complete"
+
+	// Hibernated is True while an idle agent is scaled down by spec.hibernation
+	Hibernated = "Hibernated"
+
+	// Rejected is True when a ModelPolicy of the namespace forbids the agent's model
+	// or provider
+	Rejected = "Rejected"
+
+	// Evaluated is True when the pending pod template passed spec.evaluation, False
+	// while it is evaluated or after it failed
+	Evaluated = "Evaluated"
+
+	// Reconciling is True while the controller still works towards the latest spec.
+	// With Stalled it follows the kstatus convention read by Flux and cli-utils.
+	Reconciling = "Reconciling"
+
+	// Stalled is True when the controller cannot make progress without a change to
+	// the spec, its policies or the cluster
+	Stalled = "Stalled"
+
+	// CapacityPending is True when the free GPUs of the cluster cannot hold every
+	// desired replica of a GPU-backed agent
+	CapacityPending = "CapacityPending"
+
+	// SessionStoreReady is True when the session store of spec.sessionStore is up
+	SessionStoreReady = "SessionStoreReady"
+
+	// QueueReady is True when the broker of spec.queue is up
+	QueueReady = "QueueReady"
+
+	// ResponseCacheReady is True when the redis of spec.responseCache is up
+	ResponseCacheReady = "ResponseCacheReady"
+
+	// ToolsReady is True when every ToolServer of spec.tools is attached to the agent
+	ToolsReady = "ToolsReady"
+
+	// DependenciesReady is True when every dependency of spec.dependencies is reachable
+	DependenciesReady = "DependenciesReady"
+
+	// RolloutFailed is True when the latest rollout of the agent failed and, with
+	// spec.upgrade.autoRollback, the previous revision was restored
+	RolloutFailed = "RolloutFailed"
+
+	// SecretsFound is True when every Secret and key the agent pods reference exists
+	SecretsFound = "SecretsFound"
+
+	// ImagePullable is True when every image of the agent pods can be pulled with
+	// their pull secrets
+	ImagePullable = "ImagePullable"
+
+	// GPUsAvailable is True when the cluster has nodes with the GPUs the agent pods request
+	GPUsAvailable = "GPUsAvailable"
+
+	// ProviderAuthenticated is True when the hosted provider accepts the agent's API
+	// key and serves its model, per spec.preflight.providerAuth
+	ProviderAuthenticated = "ProviderAuthenticated"
+
+	// CloudIdentityReady is True when the agent pods receive the cloud identity of
+	// spec.cloudIdentity
+	CloudIdentityReady = "CloudIdentityReady"
+)
+
+// Condition reasons
+const (
+	// ReasonReplicasReady: all desired replicas are ready
+	ReasonReplicasReady = "ReplicasReady"
+
+	// ReasonReplicasUnavailable: fewer replicas are ready than desired
+	ReasonReplicasUnavailable = "ReplicasUnavailable"
+
+	// ReasonRolloutInProgress: the Deployment is rolling out a new pod template
+	ReasonRolloutInProgress = "RolloutInProgress"
+
+	// ReasonRolloutComplete: the latest pod template is fully rolled out
+	ReasonRolloutComplete = "RolloutComplete"
+
+	// ReasonScaledToZero: the agent has no desired replicas
+	ReasonScaledToZero = "ScaledToZero"
+
+	// ReasonReconcileError: the controller failed to reconcile child resources
+	ReasonReconcileError = "ReconcileError"
+
+	// ReasonInvalidSpec: the resource spec is inconsistent and cannot be applied
+	ReasonInvalidSpec = "InvalidSpec"
+
+	// ReasonNoBackends: a route matches no AgentDeployment
+	ReasonNoBackends = "NoBackends"
+
+	// ReasonWithinBudget: usage is below every limit of the budget
+	ReasonWithinBudget = "WithinBudget"
+
+	// ReasonTokenLimitExceeded: the token limit of the budget is exceeded
+	ReasonTokenLimitExceeded = "TokenLimitExceeded"
+
+	// ReasonCostLimitExceeded: the cost limit of the budget is exceeded
+	ReasonCostLimitExceeded = "CostLimitExceeded"
+
+	// ReasonUsageUnavailable: usage metrics could not be read
+	ReasonUsageUnavailable = "UsageUnavailable"
+
+	// ReasonHookRejected: a preApply hook rejected or failed the pending change
+	ReasonHookRejected = "HookRejected"
+
+	// ReasonJobRunning: the run has not finished yet
+	ReasonJobRunning = "JobRunning"
+
+	// ReasonJobSucceeded: the run finished successfully
+	ReasonJobSucceeded = "JobSucceeded"
+
+	// ReasonJobFailed: the run failed after exhausting its retries or deadline
+	ReasonJobFailed = "JobFailed"
+
+	// ReasonSuspended: the schedule is suspended and starts no runs
+	ReasonSuspended = "Suspended"
+
+	// ReasonRunSkipped: a due run was skipped because the previous one is still active
+	ReasonRunSkipped = "RunSkipped"
+
+	// ReasonDownloading: model artifacts are being downloaded into the cache
+	ReasonDownloading = "Downloading"
+
+	// ReasonDownloadFailed: downloading model artifacts failed after retries
+	ReasonDownloadFailed = "DownloadFailed"
+
+	// ReasonCached: model artifacts are cached and ready to mount
+	ReasonCached = "Cached"
+
+	// ReasonImagePullError: pods cannot pull their container image
+	ReasonImagePullError = "ImagePullError"
+
+	// ReasonUnschedulable: pods cannot be scheduled on any node
+	ReasonUnschedulable = "Unschedulable"
+
+	// ReasonCrashLoop: containers keep exiting and are restarted with back-off
+	ReasonCrashLoop = "CrashLoop"
+
+	// ReasonOOMKilled: containers are killed for exceeding their memory limit
+	ReasonOOMKilled = "OOMKilled"
+
+	// ReasonContainerConfigError: containers cannot be created, e.g. a referenced Secret is missing
+	ReasonContainerConfigError = "ContainerConfigError"
+
+	// ReasonIdle: the agent received no requests for its idle period
+	ReasonIdle = "Idle"
+
+	// ReasonReceivingTraffic: the agent received requests within its idle period, or
+	// was activated
+	ReasonReceivingTraffic = "ReceivingTraffic"
+
+	// ReasonProviderNotAllowed: the AgentOpsConfig does not allow the agent's model provider
+	ReasonProviderNotAllowed = "ProviderNotAllowed"
+
+	// ReasonModelNotAllowed: a ModelPolicy of the namespace does not allow the agent's model
+	ReasonModelNotAllowed = "ModelNotAllowed"
+
+	// ReasonSignatureInvalid: the agent image is not signed or attested with the keys
+	// of the AgentOpsConfig
+	ReasonSignatureInvalid = "SignatureInvalid"
+
+	// ReasonQuotaExceeded: the agent does not fit a TenantQuota of its namespace
+	ReasonQuotaExceeded = "QuotaExceeded"
+
+	// ReasonEvaluationRunning: the EvaluationRun has not finished yet
+	ReasonEvaluationRunning = "EvaluationRunning"
+
+	// ReasonEvaluationPassed: the EvaluationRun reached its score threshold
+	ReasonEvaluationPassed = "EvaluationPassed"
+
+	// ReasonEvaluationFailed: the EvaluationRun scored below its threshold
+	ReasonEvaluationFailed = "EvaluationFailed"
+
+	// ReasonWorkflowRunning: the AgentWorkflow has steps left to run
+	ReasonWorkflowRunning = "WorkflowRunning"
+
+	// ReasonWorkflowSucceeded: every step of the AgentWorkflow succeeded
+	ReasonWorkflowSucceeded = "WorkflowSucceeded"
+
+	// ReasonWorkflowFailed: a step of the AgentWorkflow failed after its retries
+	ReasonWorkflowFailed = "WorkflowFailed"
+
+	// ReasonExperimentRunning: the experiment is still collecting metrics
+	ReasonExperimentRunning = "ExperimentRunning"
+
+	// ReasonWinnerFound: the experiment named the variant that did best on its objective
+	ReasonWinnerFound = "WinnerFound"
+
+	// ReasonInconclusive: the experiment ended without enough data to name a winner
+	ReasonInconclusive = "Inconclusive"
+
+	// ReasonClustersReady: the AgentDeployment of every member cluster is synced and ready
+	ReasonClustersReady = "ClustersReady"
+
+	// ReasonClustersUnavailable: member clusters cannot be reached or their
+	// AgentDeployment is not ready
+	ReasonClustersUnavailable = "ClustersUnavailable"
+
+	// ReasonInsufficientGPUs: no schedulable node has the GPUs of more agent pods free
+	ReasonInsufficientGPUs = "InsufficientGPUs"
+
+	// ReasonSessionStoreUnavailable: the session store is not running, or its Secret
+	// or Service is missing
+	ReasonSessionStoreUnavailable = "SessionStoreUnavailable"
+
+	// ReasonQueueUnavailable: the broker of the request queue is not running
+	ReasonQueueUnavailable = "QueueUnavailable"
+
+	// ReasonResponseCacheUnavailable: the redis of the response cache is not running
+	ReasonResponseCacheUnavailable = "ResponseCacheUnavailable"
+
+	// ReasonToolServerNotFound: a ToolServer of spec.tools does not exist
+	ReasonToolServerNotFound = "ToolServerNotFound"
+
+	// ReasonBaseNotFound: the AgentDeployment of an AgentPreview's baseRef does not exist
+	ReasonBaseNotFound = "BaseNotFound"
+
+	// ReasonDependenciesUnavailable: a dependency of spec.dependencies is not reachable
+	ReasonDependenciesUnavailable = "DependenciesUnavailable"
+
+	// ReasonProgressDeadlineExceeded: the new revision made no progress within its deadline
+	ReasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+
+	// ReasonAnalysisFailed: the canary analysis of spec.progressiveDelivery aborted the rollout
+	ReasonAnalysisFailed = "AnalysisFailed"
+
+	// ReasonPreflightFailed: a preflight check failed and the agent's workload was not created
+	ReasonPreflightFailed = "PreflightFailed"
+
+	// ReasonSecretNotFound: a Secret, or a key of it, referenced by the agent pods is missing
+	ReasonSecretNotFound = "SecretNotFound"
+
+	// ReasonNoGPUNodes: no node of the cluster has the GPUs the agent pods request
+	ReasonNoGPUNodes = "NoGPUNodes"
+
+	// ReasonAuthenticationFailed: the provider rejected the agent's API key
+	ReasonAuthenticationFailed = "AuthenticationFailed"
+
+	// ReasonModelUnavailable: the provider does not serve the model to the agent's API key
+	ReasonModelUnavailable = "ModelUnavailable"
+
+	// ReasonProviderUnreachable: the provider could not be asked whether it accepts the key
+	ReasonProviderUnreachable = "ProviderUnreachable"
+
+	// ReasonCloudIdentityInvalid: spec.cloudIdentity does not fit the agent's provider or credentials
+	ReasonCloudIdentityInvalid = "CloudIdentityInvalid"
+
+	// ReasonIdentityNotInjected: the cluster does not hand the agent pods their cloud identity
+	ReasonIdentityNotInjected = "IdentityNotInjected"
+
+	// ReasonAsExpected: the condition is in its healthy state
+	ReasonAsExpected = "AsExpected"
+)
+
+// Set adds or updates a condition, keeping the transition time when the status is unchanged
+func Set(conditions *[]metav1.Condition, conditionType string, status metav1.ConditionStatus, reason, message string, generation int64) {
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
+}
+
+// Get returns the condition of the given type, or nil
+func Get(conditions []metav1.Condition, conditionType string) *metav1.Condition {
+	return meta.FindStatusCondition(conditions, conditionType)
+}
+
+// IsTrue reports whether the condition of the given type is True
+func IsTrue(conditions []metav1.Condition, conditionType string) bool {
+	return meta.IsStatusConditionTrue(conditions, conditionType)
+}
+
+// IsFalse reports whether the condition of the given type is False
+func IsFalse(conditions []metav1.Condition, conditionType string) bool {
+	return meta.IsStatusConditionFalse(conditions, conditionType)
+}
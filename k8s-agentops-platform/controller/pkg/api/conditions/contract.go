@@ -0,0 +1,103 @@
+package conditions
+
+import "sort"
+
+// published is the frozen set of values in contract v1. It is written out as
+// literals on purpose: renaming a constant above without also editing this table
+// fails the contract tests, which turns an accidental rename into a test failure
+// instead of a silent break for external tooling.
+var published = map[string]string{
+	"Ready":                    Ready,
+	"Available":                Available,
+	"Progressing":              Progressing,
+	"Degraded":                 Degraded,
+	"BudgetExceeded":           BudgetExceeded,
+	"Complete":                 Complete,
+	"Hibernated":               Hibernated,
+	"Rejected":                 Rejected,
+	"ReplicasReady":            ReasonReplicasReady,
+	"ReplicasUnavailable":      ReasonReplicasUnavailable,
+	"RolloutInProgress":        ReasonRolloutInProgress,
+	"RolloutComplete":          ReasonRolloutComplete,
+	"ScaledToZero":             ReasonScaledToZero,
+	"ReconcileError":           ReasonReconcileError,
+	"InvalidSpec":              ReasonInvalidSpec,
+	"NoBackends":               ReasonNoBackends,
+	"WithinBudget":             ReasonWithinBudget,
+	"TokenLimitExceeded":       ReasonTokenLimitExceeded,
+	"CostLimitExceeded":        ReasonCostLimitExceeded,
+	"UsageUnavailable":         ReasonUsageUnavailable,
+	"HookRejected":             ReasonHookRejected,
+	"JobRunning":               ReasonJobRunning,
+	"JobSucceeded":             ReasonJobSucceeded,
+	"JobFailed":                ReasonJobFailed,
+	"Suspended":                ReasonSuspended,
+	"RunSkipped":               ReasonRunSkipped,
+	"Downloading":              ReasonDownloading,
+	"DownloadFailed":           ReasonDownloadFailed,
+	"Cached":                   ReasonCached,
+	"ImagePullError":           ReasonImagePullError,
+	"Unschedulable":            ReasonUnschedulable,
+	"CrashLoop":                ReasonCrashLoop,
+	"OOMKilled":                ReasonOOMKilled,
+	"ContainerConfigError":     ReasonContainerConfigError,
+	"Idle":                     ReasonIdle,
+	"ReceivingTraffic":         ReasonReceivingTraffic,
+	"AsExpected":               ReasonAsExpected,
+	"ProviderNotAllowed":       ReasonProviderNotAllowed,
+	"QuotaExceeded":            ReasonQuotaExceeded,
+	"ModelNotAllowed":          ReasonModelNotAllowed,
+	"SignatureInvalid":         ReasonSignatureInvalid,
+	"Evaluated":                Evaluated,
+	"EvaluationRunning":        ReasonEvaluationRunning,
+	"EvaluationPassed":         ReasonEvaluationPassed,
+	"EvaluationFailed":         ReasonEvaluationFailed,
+	"ExperimentRunning":        ReasonExperimentRunning,
+	"WinnerFound":              ReasonWinnerFound,
+	"Inconclusive":             ReasonInconclusive,
+	"ClustersReady":            ReasonClustersReady,
+	"ClustersUnavailable":      ReasonClustersUnavailable,
+	"Reconciling":              Reconciling,
+	"Stalled":                  Stalled,
+	"CapacityPending":          CapacityPending,
+	"InsufficientGPUs":         ReasonInsufficientGPUs,
+	"SessionStoreReady":        SessionStoreReady,
+	"SessionStoreUnavailable":  ReasonSessionStoreUnavailable,
+	"ToolsReady":               ToolsReady,
+	"QueueReady":               QueueReady,
+	"QueueUnavailable":         ReasonQueueUnavailable,
+	"ResponseCacheReady":       ResponseCacheReady,
+	"ResponseCacheUnavailable": ReasonResponseCacheUnavailable,
+	"ToolServerNotFound":       ReasonToolServerNotFound,
+	"WorkflowRunning":          ReasonWorkflowRunning,
+	"WorkflowSucceeded":        ReasonWorkflowSucceeded,
+	"WorkflowFailed":           ReasonWorkflowFailed,
+	"RolloutFailed":            RolloutFailed,
+	"DependenciesReady":        DependenciesReady,
+	"DependenciesUnavailable":  ReasonDependenciesUnavailable,
+	"ProgressDeadlineExceeded": ReasonProgressDeadlineExceeded,
+	"AnalysisFailed":           ReasonAnalysisFailed,
+	"SecretsFound":             SecretsFound,
+	"ImagePullable":            ImagePullable,
+	"GPUsAvailable":            GPUsAvailable,
+	"ProviderAuthenticated":    ProviderAuthenticated,
+	"PreflightFailed":          ReasonPreflightFailed,
+	"SecretNotFound":           ReasonSecretNotFound,
+	"NoGPUNodes":               ReasonNoGPUNodes,
+	"AuthenticationFailed":     ReasonAuthenticationFailed,
+	"ModelUnavailable":         ReasonModelUnavailable,
+	"ProviderUnreachable":      ReasonProviderUnreachable,
+	"CloudIdentityReady":       CloudIdentityReady,
+	"CloudIdentityInvalid":     ReasonCloudIdentityInvalid,
+	"IdentityNotInjected":      ReasonIdentityNotInjected,
+}
+
+// Published returns the condition types and reasons in the current contract
+func Published() []string {
+	values := make([]string, 0, len(published))
+	for wire := range published {
+		values = append(values, wire)
+	}
+	sort.Strings(values)
+	return values
+}
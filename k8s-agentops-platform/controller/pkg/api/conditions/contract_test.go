@@ -0,0 +1,37 @@
+package conditions
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestPublishedValuesUnchanged(t *testing.T) {
+	wires := make([]string, 0, len(published))
+	for wire := range published {
+		wires = append(wires, wire)
+	}
+	sort.Strings(wires)
+
+	for _, wire := range wires {
+		t.Run(wire, func(t *testing.T) {
+			if value := published[wire]; value != wire {
+				t.Errorf("condition contract %s: published value %q is now %q", ContractVersion, wire, value)
+			}
+		})
+	}
+}
+
+func TestPublished(t *testing.T) {
+	values := Published()
+	if len(values) != len(published) {
+		t.Fatalf("Published() returned %d values, want %d", len(values), len(published))
+	}
+	if !sort.StringsAreSorted(values) {
+		t.Errorf("Published() is not sorted: %v", values)
+	}
+	for _, value := range values {
+		if _, ok := published[value]; !ok {
+			t.Errorf("Published() returned %q, which is not in the contract", value)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package v1alpha1
+
+import (
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Hub marks v1alpha1 as the version AgentDeployments are stored in and converted
+// through; the controllers work on this version
+func (*AgentDeployment) Hub() {}
+
+// SetupWebhookWithManager serves the AgentDeployment conversion webhook
+func (r *AgentDeployment) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
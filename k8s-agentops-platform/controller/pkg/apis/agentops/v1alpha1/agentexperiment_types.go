@@ -0,0 +1,220 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentExperimentSpec defines an A/B comparison of two agents on live traffic
+type AgentExperimentSpec struct {
+	// Variants are the two agents compared
+	// +kubebuilder:validation:MinItems=2
+	// +kubebuilder:validation:MaxItems=2
+	Variants []ExperimentVariant `json:"variants"`
+
+	// Route is where clients send the traffic split between the variants
+	// +kubebuilder:validation:Required
+	Route ExperimentRoute `json:"route"`
+
+	// Duration the experiment collects metrics for, e.g. 72h
+	// +kubebuilder:validation:Required
+	Duration metav1.Duration `json:"duration"`
+
+	// Objective decides the winner: Latency (lower p95 request latency), Cost (lower
+	// LLM API spend per request) or Feedback (higher user feedback score)
+	// +optional
+	// +kubebuilder:default=Latency
+	// +kubebuilder:validation:Enum=Latency;Cost;Feedback
+	Objective string `json:"objective,omitempty"`
+
+	// Feedback reads the user feedback scores of the variants from a webhook
+	// +optional
+	Feedback *ExperimentFeedback `json:"feedback,omitempty"`
+
+	// MinRequests is the number of requests each variant must serve for the
+	// experiment to name a winner
+	// +optional
+	// +kubebuilder:default=100
+	// +kubebuilder:validation:Minimum=0
+	MinRequests *int64 `json:"minRequests,omitempty"`
+
+	// PromoteWinner sends all traffic of the route to the winner once the experiment
+	// completed
+	// +optional
+	PromoteWinner bool `json:"promoteWinner,omitempty"`
+}
+
+// ExperimentVariant is an agent compared in an AgentExperiment
+type ExperimentVariant struct {
+	// Name identifies the variant, e.g. control or treatment
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=20
+	Name string `json:"name"`
+
+	// AgentDeployment serving the variant, in the experiment's namespace
+	// +kubebuilder:validation:Required
+	AgentDeployment string `json:"agentDeployment"`
+
+	// Revision runs this AgentRevision of the AgentDeployment as an AgentDeployment of
+	// its own, named <experiment>-<variant>, instead of the AgentDeployment as it is
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	Revision int64 `json:"revision,omitempty"`
+
+	// Weight is the relative share of traffic
+	// +optional
+	// +kubebuilder:default=50
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1000
+	Weight int32 `json:"weight,omitempty"`
+}
+
+// ExperimentRoute is the AgentRoute generated for an AgentExperiment
+type ExperimentRoute struct {
+	// Mode selects the routing layer, like spec.mode of an AgentRoute
+	// +optional
+	// +kubebuilder:default=GatewayAPI
+	// +kubebuilder:validation:Enum=GatewayAPI;Bundled
+	Mode string `json:"mode,omitempty"`
+
+	// ParentRefs are the Gateways the route attaches to (GatewayAPI mode)
+	// +optional
+	ParentRefs []GatewayParentReference `json:"parentRefs,omitempty"`
+
+	// Hostnames the route answers for
+	// +optional
+	Hostnames []string `json:"hostnames,omitempty"`
+
+	// PathPrefix matches the request path
+	// +optional
+	PathPrefix string `json:"pathPrefix,omitempty"`
+}
+
+// ExperimentFeedback configures the webhook user feedback is read from
+type ExperimentFeedback struct {
+	// URL is called with a POST of the experiment, namespace, variant, AgentDeployment
+	// and start time, and answers the variant's mean score and number of ratings as
+	// {"score": 0.82, "count": 140}
+	// +kubebuilder:validation:Required
+	URL string `json:"url"`
+
+	// Timeout of a call
+	// +optional
+	// +kubebuilder:default="10s"
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// AgentExperiment objectives
+const (
+	ExperimentObjectiveLatency  = "Latency"
+	ExperimentObjectiveCost     = "Cost"
+	ExperimentObjectiveFeedback = "Feedback"
+)
+
+// AgentExperiment phases
+const (
+	AgentExperimentPending   = "Pending"
+	AgentExperimentRunning   = "Running"
+	AgentExperimentCompleted = "Completed"
+)
+
+// ExperimentVariantStatus holds the metrics of a variant, over the experiment so far
+type ExperimentVariantStatus struct {
+	// Name of the variant
+	Name string `json:"name"`
+
+	// AgentDeployment serving the variant
+	AgentDeployment string `json:"agentDeployment"`
+
+	// Requests is the number of requests the variant served
+	// +optional
+	Requests int64 `json:"requests,omitempty"`
+
+	// LatencyP95 is the 95th percentile request latency
+	// +optional
+	LatencyP95 *metav1.Duration `json:"latencyP95,omitempty"`
+
+	// CostPer1kRequests is the LLM API spend per 1000 requests, in USD
+	// +optional
+	CostPer1kRequests *resource.Quantity `json:"costPer1kRequests,omitempty"`
+
+	// FeedbackScore is the mean user feedback score
+	// +optional
+	FeedbackScore string `json:"feedbackScore,omitempty"`
+
+	// FeedbackCount is the number of ratings the score is based on
+	// +optional
+	FeedbackCount int64 `json:"feedbackCount,omitempty"`
+}
+
+// AgentExperimentStatus defines the observed state of AgentExperiment
+type AgentExperimentStatus struct {
+	// Conditions represent the latest available observations of the experiment's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Phase is Pending, Running or Completed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// StartTime is when traffic started to be split
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the experiment ended
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Variants are the metrics of each variant
+	// +optional
+	Variants []ExperimentVariantStatus `json:"variants,omitempty"`
+
+	// Winner is the variant that did best on the objective; empty while running and
+	// when the experiment was inconclusive
+	// +optional
+	Winner string `json:"winner,omitempty"`
+
+	// LastUpdateTime is when the metrics were last read
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+
+	// Message is a human readable description of the last transition
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed AgentExperiment
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=aexp
+// +kubebuilder:printcolumn:name="Objective",type=string,JSONPath=`.spec.objective`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Winner",type=string,JSONPath=`.status.winner`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentExperiment is the Schema for the agentexperiments API
+type AgentExperiment struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable once the experiment is created"
+	Spec   AgentExperimentSpec   `json:"spec,omitempty"`
+	Status AgentExperimentStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AgentExperimentList contains a list of AgentExperiment
+type AgentExperimentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentExperiment `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentExperiment{}, &AgentExperimentList{})
+}
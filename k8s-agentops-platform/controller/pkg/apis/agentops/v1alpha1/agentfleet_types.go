@@ -0,0 +1,289 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// AgentFleetSpec defines an AgentDeployment run in several member clusters from a
+// hub cluster
+type AgentFleetSpec struct {
+	// Template is the AgentDeployment created in every member cluster, named after
+	// the fleet
+	// +kubebuilder:validation:Required
+	Template AgentFleetTemplate `json:"template"`
+
+	// Clusters are the member clusters the AgentDeployment runs in
+	// +kubebuilder:validation:MinItems=1
+	Clusters []FleetCluster `json:"clusters"`
+
+	// Failover moves traffic and capacity away from clusters whose agents are degraded
+	// +optional
+	Failover *FleetFailoverSpec `json:"failover,omitempty"`
+}
+
+// FleetFailoverSpec configures health-based failover between the member clusters
+type FleetFailoverSpec struct {
+	// After is how long a cluster stays degraded or unreachable before it is failed
+	// over, and healthy before it gets its traffic back
+	// +optional
+	// +kubebuilder:default="2m"
+	After metav1.Duration `json:"after,omitempty"`
+
+	// ScaleUp adds the replicas of failed-over clusters to the healthy clusters, by
+	// weight
+	// +optional
+	// +kubebuilder:default=true
+	ScaleUp bool `json:"scaleUp,omitempty"`
+
+	// DNS publishes a weighted record per cluster endpoint through external-dns
+	// +optional
+	DNS *FleetDNSSpec `json:"dns,omitempty"`
+
+	// Gateway renders an HTTPRoute in the hub cluster sending traffic to the cluster
+	// endpoints by weight
+	// +optional
+	Gateway *FleetGatewaySpec `json:"gateway,omitempty"`
+}
+
+// FleetDNSSpec configures the weighted DNS records of a fleet
+type FleetDNSSpec struct {
+	// Hostname clients resolve, e.g. support.agents.example.com
+	// +kubebuilder:validation:Required
+	Hostname string `json:"hostname"`
+
+	// TTL of the records in seconds; it bounds how fast clients follow a failover
+	// +optional
+	// +kubebuilder:default=60
+	// +kubebuilder:validation:Minimum=1
+	TTL int64 `json:"ttl,omitempty"`
+}
+
+// FleetGatewaySpec configures the hub HTTPRoute of a fleet
+type FleetGatewaySpec struct {
+	// ParentRefs are the Gateways of the hub cluster the HTTPRoute attaches to
+	// +kubebuilder:validation:MinItems=1
+	ParentRefs []GatewayParentReference `json:"parentRefs"`
+
+	// Hostnames the route answers for
+	// +optional
+	Hostnames []string `json:"hostnames,omitempty"`
+
+	// Port of the cluster endpoints
+	// +optional
+	// +kubebuilder:default=80
+	Port int32 `json:"port,omitempty"`
+}
+
+// AgentFleetTemplate is the AgentDeployment of a fleet
+type AgentFleetTemplate struct {
+	// Metadata holds the labels and annotations of the AgentDeployments
+	// +optional
+	Metadata FleetObjectMeta `json:"metadata,omitempty"`
+
+	// Spec of the AgentDeployments
+	// +kubebuilder:validation:Required
+	Spec AgentDeploymentSpec `json:"spec"`
+}
+
+// FleetObjectMeta is the metadata set on the AgentDeployments of a fleet
+type FleetObjectMeta struct {
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// FleetCluster is a member cluster of an AgentFleet
+type FleetCluster struct {
+	// Name identifies the cluster in the fleet status, e.g. eu-west-1
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// KubeconfigSecretRef is the Secret, in the fleet's namespace, holding a
+	// kubeconfig of the member cluster. Cluster API writes one per cluster as
+	// <cluster>-kubeconfig; the Open Cluster Management managed service account
+	// addon can provide one too.
+	// +kubebuilder:validation:Required
+	KubeconfigSecretRef KubeconfigSecretReference `json:"kubeconfigSecretRef"`
+
+	// Namespace the AgentDeployment is created in; defaults to the fleet's namespace
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Overrides is a strategic merge patch of the template spec for this cluster,
+	// e.g. {"replicas": 5} or a region's provider endpoint
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Overrides *runtime.RawExtension `json:"overrides,omitempty"`
+
+	// Endpoint is the address the agents of the cluster are reached at, e.g. the
+	// hostname or IP of its ingress load balancer; failover DNS records and the
+	// gateway route point to it
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Weight is the cluster's share of the fleet's traffic
+	// +optional
+	// +kubebuilder:default=100
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1000
+	Weight int32 `json:"weight,omitempty"`
+}
+
+// KubeconfigSecretReference selects a kubeconfig in a Secret
+type KubeconfigSecretReference struct {
+	// Name of the Secret
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Key holding the kubeconfig
+	// +optional
+	// +kubebuilder:default=value
+	Key string `json:"key,omitempty"`
+}
+
+// FleetClusterStatus is the state of the AgentDeployment of a member cluster
+type FleetClusterStatus struct {
+	// Name of the cluster
+	Name string `json:"name"`
+
+	// Namespace of the AgentDeployment in the cluster
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// KubeconfigSecretRef is kept to remove the AgentDeployment once the cluster
+	// leaves the spec
+	// +optional
+	KubeconfigSecretRef *KubeconfigSecretReference `json:"kubeconfigSecretRef,omitempty"`
+
+	// Synced is true when the AgentDeployment of the cluster matches the fleet
+	Synced bool `json:"synced"`
+
+	// Phase of the AgentDeployment in the cluster
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Replicas is the number of agent pods in the cluster
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of ready agent pods in the cluster
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// Message describes why the cluster is not synced or not ready
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Degraded is true when the AgentDeployment of the cluster reports failing pods
+	// +optional
+	Degraded bool `json:"degraded,omitempty"`
+
+	// HealthChangedTime is when the cluster last became healthy, or degraded or
+	// unreachable
+	// +optional
+	HealthChangedTime *metav1.Time `json:"healthChangedTime,omitempty"`
+
+	// FailedOver is true while the traffic and capacity of the cluster are moved to
+	// the healthy clusters
+	// +optional
+	FailedOver bool `json:"failedOver,omitempty"`
+
+	// Weight is the cluster's current share of the traffic; 0 while failed over
+	// +optional
+	Weight int32 `json:"weight,omitempty"`
+
+	// ExtraReplicas were added to the cluster to take over failed-over clusters
+	// +optional
+	ExtraReplicas int32 `json:"extraReplicas,omitempty"`
+
+	// LastSyncTime is when the cluster was last reached
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// FleetFailoverEvent records a cluster failing over or getting its traffic back
+type FleetFailoverEvent struct {
+	// Time of the event
+	Time metav1.Time `json:"time"`
+
+	// Cluster that failed over or back
+	Cluster string `json:"cluster"`
+
+	// Type is FailedOver or FailedBack
+	Type string `json:"type"`
+
+	// Message describes the cause
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// Fleet failover event types
+const (
+	FleetFailedOver = "FailedOver"
+	FleetFailedBack = "FailedBack"
+)
+
+// AgentFleetStatus defines the observed state of AgentFleet
+type AgentFleetStatus struct {
+	// Conditions represent the latest available observations of the fleet's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Clusters is the state of every member cluster, including clusters removed from
+	// the spec whose AgentDeployment could not be deleted yet
+	// +optional
+	Clusters []FleetClusterStatus `json:"clusters,omitempty"`
+
+	// ReadyClusters is the number of clusters whose AgentDeployment is synced and ready
+	// +optional
+	ReadyClusters int32 `json:"readyClusters,omitempty"`
+
+	// Replicas is the number of agent pods across the clusters
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of ready agent pods across the clusters
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// FailoverEvents are the latest failovers and failbacks, oldest first
+	// +optional
+	FailoverEvents []FleetFailoverEvent `json:"failoverEvents,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed AgentFleet
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=afleet
+// +kubebuilder:printcolumn:name="Model",type=string,JSONPath=`.spec.template.spec.model`
+// +kubebuilder:printcolumn:name="Clusters",type=integer,JSONPath=`.status.readyClusters`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentFleet is the Schema for the agentfleets API
+type AgentFleet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AgentFleetSpec   `json:"spec,omitempty"`
+	Status AgentFleetStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AgentFleetList contains a list of AgentFleet
+type AgentFleetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentFleet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentFleet{}, &AgentFleetList{})
+}
@@ -0,0 +1,164 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentJobSpec defines a one-shot agent run
+type AgentJobSpec struct {
+	// Model is the LLM model the agent runs with
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=claude-3-opus;claude-3-sonnet;claude-3-haiku;gpt-4;gpt-4-turbo;gpt-3.5-turbo;llama-2-70b;mixtral-8x7b
+	Model string `json:"model"`
+
+	// Image overrides the agent image derived from the model
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// PromptTemplateRef selects the PromptTemplate revision mounted into the agent
+	// +optional
+	PromptTemplateRef *PromptTemplateReference `json:"promptTemplateRef,omitempty"`
+
+	// Input is the task given to the agent
+	// +optional
+	Input *AgentJobInput `json:"input,omitempty"`
+
+	// Output configures where the agent writes its result
+	// +optional
+	Output *AgentJobOutput `json:"output,omitempty"`
+
+	// Secrets to inject as environment variables
+	// +optional
+	Secrets []SecretReference `json:"secrets,omitempty"`
+
+	// Resources for the agent container
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Retry configures retries and the deadline of the run
+	// +optional
+	Retry *AgentJobRetry `json:"retry,omitempty"`
+
+	// TTLSecondsAfterFinished deletes the AgentJob, and its Job and pods, this long
+	// after it succeeded or failed
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// AgentJobInput is the task given to the agent; set exactly one field
+type AgentJobInput struct {
+	// Text is passed inline
+	// +optional
+	Text string `json:"text,omitempty"`
+
+	// ConfigMapRef selects a ConfigMap key mounted as the input file
+	// +optional
+	ConfigMapRef *corev1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
+}
+
+// AgentJobOutput configures where the result is written
+type AgentJobOutput struct {
+	// Location is an object storage URI (s3://, gs://, az://) the agent uploads its
+	// result to; when empty the result is only reported through the pod's
+	// termination message
+	// +optional
+	Location string `json:"location,omitempty"`
+}
+
+// AgentJobRetry configures retries of an AgentJob
+type AgentJobRetry struct {
+	// BackoffLimit is the number of retries before the job is marked failed
+	// +optional
+	// +kubebuilder:default=2
+	// +kubebuilder:validation:Minimum=0
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+
+	// ActiveDeadlineSeconds bounds the whole run, including retries
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+}
+
+// AgentJob phases
+const (
+	AgentJobPending   = "Pending"
+	AgentJobRunning   = "Running"
+	AgentJobSucceeded = "Succeeded"
+	AgentJobFailed    = "Failed"
+)
+
+// AgentJobStatus defines the observed state of AgentJob
+type AgentJobStatus struct {
+	// Conditions represent the latest available observations of the job's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Phase is Pending, Running, Succeeded or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// StartTime is when the Job started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the run succeeded or finally failed
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Attempts is the number of pods started for the run
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// OutputLocation is where the agent wrote its result
+	// +optional
+	OutputLocation string `json:"outputLocation,omitempty"`
+
+	// TokensUsed is the number of tokens the run consumed, as reported by the agent
+	// +optional
+	TokensUsed int64 `json:"tokensUsed,omitempty"`
+
+	// Message is a human readable description of the last transition
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed AgentJob
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// Finished reports whether the run succeeded or failed
+func (j *AgentJob) Finished() bool {
+	return j.Status.Phase == AgentJobSucceeded || j.Status.Phase == AgentJobFailed
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Model",type=string,JSONPath=`.spec.model`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Tokens",type=integer,JSONPath=`.status.tokensUsed`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentJob is the Schema for the agentjobs API
+type AgentJob struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable once the job is created"
+	Spec   AgentJobSpec   `json:"spec,omitempty"`
+	Status AgentJobStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AgentJobList contains a list of AgentJob
+type AgentJobList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentJob `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentJob{}, &AgentJobList{})
+}
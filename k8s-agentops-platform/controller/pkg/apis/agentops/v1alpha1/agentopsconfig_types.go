@@ -0,0 +1,277 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentOpsConfigName is the name of the AgentOpsConfig the controller reads; other
+// AgentOpsConfigs are ignored
+const AgentOpsConfigName = "default"
+
+// AgentOpsConfigSpec holds organization-wide defaults for AgentDeployments. Settings
+// of an AgentDeployment always take precedence over them.
+type AgentOpsConfigSpec struct {
+	// ImageRegistry is prepended to agent pod images that do not name a registry,
+	// e.g. registry.corp.example.com/mirror
+	// +optional
+	ImageRegistry string `json:"imageRegistry,omitempty"`
+
+	// ResourceProfiles set the default agent container resources by model size
+	// +optional
+	ResourceProfiles []ResourceProfile `json:"resourceProfiles,omitempty"`
+
+	// SizeClasses replace the built-in default agent container resources of model
+	// size classes, used for agents that set no resources and match no profile
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	SizeClasses []SizeClassResources `json:"sizeClasses,omitempty"`
+
+	// ResourcePolicy makes agent container resources mandatory, so that no agent
+	// runs unbounded on shared nodes; enforced by the validating webhook
+	// +optional
+	ResourcePolicy *ResourcePolicy `json:"resourcePolicy,omitempty"`
+
+	// AdmissionPolicy holds organization-specific rules the validating webhook
+	// checks AgentDeployments against on create and update
+	// +optional
+	AdmissionPolicy *AdmissionPolicy `json:"admissionPolicy,omitempty"`
+
+	// Labels are added to every agent pod
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to every agent pod
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// SecurityContext is the default security context of agent containers; fields
+	// set in an AgentDeployment's spec.securityContext win
+	// +optional
+	SecurityContext *SecurityContextSpec `json:"securityContext,omitempty"`
+
+	// AllowedProviders restricts the model providers agents may use; empty allows all
+	// +optional
+	AllowedProviders []string `json:"allowedProviders,omitempty"`
+
+	// CertificateIssuerRef is the default cert-manager issuer of agents exposed with TLS
+	// +optional
+	CertificateIssuerRef *CertificateIssuerReference `json:"certificateIssuerRef,omitempty"`
+
+	// PreviewDomain is the domain the ingress hosts of AgentPreviews are generated
+	// under, e.g. previews.example.com
+	// +optional
+	PreviewDomain string `json:"previewDomain,omitempty"`
+
+	// ImageVerification requires agent images to be signed with cosign before they
+	// are deployed
+	// +optional
+	ImageVerification *ImageVerificationSpec `json:"imageVerification,omitempty"`
+
+	// Audit makes auditing mandatory for the agents of regulated namespaces
+	// +optional
+	Audit *AuditPolicy `json:"audit,omitempty"`
+
+	// EgressProxy forces the agents of the listed namespaces to reach LLM providers
+	// through an egress proxy run by the operator
+	// +optional
+	EgressProxy *EgressProxySpec `json:"egressProxy,omitempty"`
+}
+
+// Actions of a ResourcePolicy
+const (
+	ResourcePolicyReject  = "Reject"
+	ResourcePolicyDefault = "Default"
+)
+
+// ResourcePolicy requires requests and limits of the listed resources on the agent
+// container
+type ResourcePolicy struct {
+	// Action taken on AgentDeployments missing a request or limit: Reject denies
+	// them; Default fills in what is missing from the agent's resource profile or
+	// size class on admission and denies agents whose model has neither
+	// +optional
+	// +kubebuilder:default=Reject
+	// +kubebuilder:validation:Enum=Reject;Default
+	Action string `json:"action,omitempty"`
+
+	// Namespaces the policy applies to; empty applies it to all
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Requests that must be set; defaults to cpu and memory
+	// +optional
+	Requests []corev1.ResourceName `json:"requests,omitempty"`
+
+	// Limits that must be set; defaults to memory, since CPU limits throttle
+	// agents without protecting their neighbors
+	// +optional
+	Limits []corev1.ResourceName `json:"limits,omitempty"`
+}
+
+// AdmissionPolicy admits an AgentDeployment only if all rules and the external
+// policy do
+type AdmissionPolicy struct {
+	// Rules are CEL expressions over object, oldObject (null on create) and
+	// namespaceObject (name, labels, annotations), e.g. object.spec.model != 'gpt-4'
+	// || ('tier' in namespaceObject.labels && namespaceObject.labels.tier == 'prod')
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Rules []AdmissionRule `json:"rules,omitempty"`
+
+	// External is an Open Policy Agent decision consulted after the rules
+	// +optional
+	External *ExternalPolicy `json:"external,omitempty"`
+}
+
+// AdmissionRule is a CEL expression an AgentDeployment must satisfy
+type AdmissionRule struct {
+	// Name identifies the rule in denials
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Expression must evaluate to true for the AgentDeployment to be admitted
+	// +kubebuilder:validation:MinLength=1
+	Expression string `json:"expression"`
+
+	// Message is returned to the user when the rule denies a request
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// Failure policies of an ExternalPolicy
+const (
+	ExternalPolicyFail   = "Fail"
+	ExternalPolicyIgnore = "Ignore"
+)
+
+// ExternalPolicy is a decision of an Open Policy Agent data API
+type ExternalPolicy struct {
+	// URL of the decision, e.g. http://opa.opa:8181/v1/data/agentops/admission.
+	// The webhook posts {"input": {operation, object, oldObject, namespace}} and
+	// expects {"result": bool} or {"result": {"allowed": bool, "reasons": [string]}}.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// TimeoutSeconds bounds each query
+	// +optional
+	// +kubebuilder:default=5
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=25
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// FailurePolicy decides requests the policy cannot be queried for: Fail denies
+	// them, Ignore admits them
+	// +optional
+	// +kubebuilder:default=Fail
+	// +kubebuilder:validation:Enum=Fail;Ignore
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+}
+
+// AuditPolicy audits every agent of the listed namespaces. Agents without
+// spec.audit get this configuration; agents with their own still get its
+// redactions, and PII redaction when it asks for it.
+type AuditPolicy struct {
+	// Namespaces whose agents must be audited
+	// +kubebuilder:validation:MinItems=1
+	Namespaces []string `json:"namespaces"`
+
+	AuditSpec `json:",inline"`
+}
+
+// EgressProxySpec configures the egress proxy. The operator runs one in every listed
+// namespace; a NetworkPolicy keeps the agent pods from reaching anything outside the
+// cluster but the proxy, and HTTP_PROXY/HTTPS_PROXY point their clients at it.
+type EgressProxySpec struct {
+	// Namespaces whose agents only reach LLM providers through the proxy
+	// +kubebuilder:validation:MinItems=1
+	Namespaces []string `json:"namespaces"`
+
+	// AllowedHosts are the hosts the proxy forwards to; a leading "*." matches any
+	// subdomain. Defaults to the APIs of the hosted model providers.
+	// +optional
+	AllowedHosts []string `json:"allowedHosts,omitempty"`
+
+	// RequestsPerMinute limits the requests each namespace's proxy forwards; a
+	// tunnelled HTTPS connection counts as one request. Zero disables the limit.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	RequestsPerMinute int32 `json:"requestsPerMinute,omitempty"`
+
+	// Replicas of the proxy in each namespace
+	// +optional
+	// +kubebuilder:default=2
+	// +kubebuilder:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Image overrides the Envoy image of the proxy
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// ImageVerificationSpec defines the cosign keys agent images must be signed with
+type ImageVerificationSpec struct {
+	// PublicKeys are PEM-encoded cosign public keys; a signature by any of them is
+	// accepted
+	// +kubebuilder:validation:MinItems=1
+	PublicKeys []string `json:"publicKeys"`
+
+	// Attestations are in-toto predicate types, e.g. https://slsa.dev/provenance/v0.2,
+	// that must be attested for the image with the same keys
+	// +optional
+	Attestations []string `json:"attestations,omitempty"`
+}
+
+// ResourceProfile is the default agent container resources of a model size
+type ResourceProfile struct {
+	// Name of the size, e.g. small or large
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Models the profile applies to
+	// +kubebuilder:validation:MinItems=1
+	Models []string `json:"models"`
+
+	// Resources are used for every resource the AgentDeployment sets neither a
+	// request nor a limit for
+	Resources corev1.ResourceRequirements `json:"resources"`
+}
+
+// SizeClassResources are the default agent container resources of a model size
+// class of the catalog
+type SizeClassResources struct {
+	// Name of the size class
+	// +kubebuilder:validation:Enum=small;medium;large;xl
+	Name string `json:"name"`
+
+	// Resources of the agent container
+	Resources corev1.ResourceRequirements `json:"resources"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Registry",type=string,JSONPath=`.spec.imageRegistry`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentOpsConfig is the Schema for the agentopsconfigs API
+type AgentOpsConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AgentOpsConfigSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AgentOpsConfigList contains a list of AgentOpsConfig
+type AgentOpsConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentOpsConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentOpsConfig{}, &AgentOpsConfigList{})
+}
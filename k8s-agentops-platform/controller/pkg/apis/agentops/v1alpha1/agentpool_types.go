@@ -0,0 +1,76 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentPoolSpec defines the desired state of AgentPool
+type AgentPoolSpec struct {
+	// Size is the number of warm, unclaimed pods kept ready
+	// +optional
+	// +kubebuilder:default=2
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Size *int32 `json:"size,omitempty"`
+
+	// Image is the generic agent runtime image run by warm pods
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Models restricts which models may claim pods from the pool; empty allows all
+	// +optional
+	Models []string `json:"models,omitempty"`
+
+	// Resources defines the resource requirements of each warm pod
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// AgentPoolStatus defines the observed state of AgentPool
+type AgentPoolStatus struct {
+	// Conditions represent the latest available observations of the pool's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// WarmReplicas is the number of ready pods available for claiming
+	// +optional
+	WarmReplicas int32 `json:"warmReplicas,omitempty"`
+
+	// ClaimedReplicas is the number of pods currently claimed by AgentDeployments
+	// +optional
+	ClaimedReplicas int32 `json:"claimedReplicas,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed AgentPool
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Size",type=integer,JSONPath=`.spec.size`
+// +kubebuilder:printcolumn:name="Warm",type=integer,JSONPath=`.status.warmReplicas`
+// +kubebuilder:printcolumn:name="Claimed",type=integer,JSONPath=`.status.claimedReplicas`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentPool is the Schema for the agentpools API
+type AgentPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AgentPoolSpec   `json:"spec,omitempty"`
+	Status AgentPoolStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AgentPoolList contains a list of AgentPool
+type AgentPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentPool{}, &AgentPoolList{})
+}
@@ -0,0 +1,130 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentPreviewSpec defines a short-lived AgentDeployment running the agent image of
+// a change under review, typically created by CI for a pull request
+// +kubebuilder:validation:XValidation:rule="has(self.baseRef) != has(self.template)",message="exactly one of baseRef and template is required"
+type AgentPreviewSpec struct {
+	// Image is the agent image under review, e.g. ghcr.io/myorg/llm-agent:pr-123
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// BaseRef names the AgentDeployment in the namespace whose spec the preview copies
+	// +optional
+	BaseRef string `json:"baseRef,omitempty"`
+
+	// Template is the AgentDeployment spec of the preview when there is no baseRef
+	// +optional
+	Template *AgentDeploymentSpec `json:"template,omitempty"`
+
+	// PullRequest identifies the change under review
+	// +optional
+	PullRequest *PullRequestReference `json:"pullRequest,omitempty"`
+
+	// Domain the ingress host of the preview, <name>.<domain>, is generated under;
+	// defaults to previewDomain of the AgentOpsConfig. Without a domain the preview
+	// is only reachable inside the cluster.
+	// +optional
+	Domain string `json:"domain,omitempty"`
+
+	// TTL is how long after its creation the preview is deleted
+	// +optional
+	// +kubebuilder:default="72h"
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// InactivityTimeout deletes the preview once its agent received no requests for
+	// this long; a new image restarts the clock
+	// +optional
+	// +kubebuilder:default="24h"
+	InactivityTimeout *metav1.Duration `json:"inactivityTimeout,omitempty"`
+}
+
+// PullRequestReference identifies a pull request
+type PullRequestReference struct {
+	// Repository, e.g. myorg/llm-agent
+	// +optional
+	Repository string `json:"repository,omitempty"`
+
+	// Number of the pull request
+	// +kubebuilder:validation:Minimum=1
+	Number int32 `json:"number"`
+
+	// Commit the image was built from
+	// +optional
+	Commit string `json:"commit,omitempty"`
+}
+
+// AgentPreview phases
+const (
+	AgentPreviewPending = "Pending"
+	AgentPreviewReady   = "Ready"
+)
+
+// AgentPreviewStatus defines the observed state of AgentPreview
+type AgentPreviewStatus struct {
+	// Conditions represent the latest available observations of the preview's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Phase is Pending until the AgentDeployment of the preview is ready
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// AgentDeployment is the name of the AgentDeployment running the preview
+	// +optional
+	AgentDeployment string `json:"agentDeployment,omitempty"`
+
+	// URL the preview is served at, when it has an ingress host
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Image is the agent image the preview runs
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// ImageUpdateTime is when the preview last changed its image
+	// +optional
+	ImageUpdateTime *metav1.Time `json:"imageUpdateTime,omitempty"`
+
+	// ExpirationTime is when the TTL of the preview runs out
+	// +optional
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed AgentPreview
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.status.url`
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.status.expirationTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentPreview is the Schema for the agentpreviews API
+type AgentPreview struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AgentPreviewSpec   `json:"spec,omitempty"`
+	Status AgentPreviewStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AgentPreviewList contains a list of AgentPreview
+type AgentPreviewList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentPreview `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentPreview{}, &AgentPreviewList{})
+}
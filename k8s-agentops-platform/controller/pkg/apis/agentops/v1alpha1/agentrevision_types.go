@@ -0,0 +1,70 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// AgentRevisionSpec is a rollout of an AgentDeployment. Revisions are created by
+// the controller and are immutable apart from their number, which moves to the
+// newest when an earlier revision is rolled out again.
+type AgentRevisionSpec struct {
+	// Revision is the number of the revision, increasing per AgentDeployment
+	// +kubebuilder:validation:Minimum=1
+	Revision int64 `json:"revision"`
+
+	// Model is the model the agent called
+	Model string `json:"model"`
+
+	// Image is the agent image
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// ImageDigest is the manifest digest the agent image was pinned to
+	// +optional
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// PromptRevision is the mounted PromptTemplate revision
+	// +optional
+	PromptRevision int32 `json:"promptRevision,omitempty"`
+
+	// ConfigHash identifies Template
+	ConfigHash string `json:"configHash"`
+
+	// Template is the AgentDeployment spec of the revision, without replicas
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Template runtime.RawExtension `json:"template"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=arev
+// +kubebuilder:printcolumn:name="Revision",type=integer,JSONPath=`.spec.revision`
+// +kubebuilder:printcolumn:name="Model",type=string,JSONPath=`.spec.model`
+// +kubebuilder:printcolumn:name="Digest",type=string,JSONPath=`.spec.imageDigest`
+// +kubebuilder:printcolumn:name="Prompt",type=integer,JSONPath=`.spec.promptRevision`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentRevision is the Schema for the agentrevisions API
+type AgentRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AgentRevisionSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AgentRevisionList contains a list of AgentRevision
+type AgentRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentRevision `json:"items"`
+}
+
+// RollbackToAnnotation on an AgentDeployment rolls it back to the AgentRevision
+// with the given number. The controller removes it once handled.
+const RollbackToAnnotation = "agentops.io/rollback-to"
+
+func init() {
+	SchemeBuilder.Register(&AgentRevision{}, &AgentRevisionList{})
+}
@@ -0,0 +1,135 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentRouteSpec defines how traffic is distributed across AgentDeployments
+type AgentRouteSpec struct {
+	// Mode selects the routing layer: GatewayAPI renders an HTTPRoute attached to an
+	// existing Gateway; Bundled deploys a dedicated Envoy gateway for the route
+	// +optional
+	// +kubebuilder:default=GatewayAPI
+	// +kubebuilder:validation:Enum=GatewayAPI;Bundled
+	Mode string `json:"mode,omitempty"`
+
+	// ParentRefs are the Gateways the HTTPRoute attaches to (GatewayAPI mode)
+	// +optional
+	ParentRefs []GatewayParentReference `json:"parentRefs,omitempty"`
+
+	// Hostnames the route answers for
+	// +optional
+	Hostnames []string `json:"hostnames,omitempty"`
+
+	// Rules are evaluated in order; the first match wins
+	// +kubebuilder:validation:MinItems=1
+	Rules []AgentRouteRule `json:"rules"`
+}
+
+// GatewayParentReference identifies a Gateway API Gateway
+type GatewayParentReference struct {
+	// Name of the Gateway
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Namespace of the Gateway; defaults to the route's namespace
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// SectionName selects a listener of the Gateway
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// AgentRouteRule matches requests and sends them to weighted backends
+type AgentRouteRule struct {
+	// PathPrefix matches the request path
+	// +optional
+	PathPrefix string `json:"pathPrefix,omitempty"`
+
+	// Headers that must match exactly, e.g. x-agent-variant: b
+	// +optional
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Backends are AgentDeployments receiving the traffic by weight
+	// +optional
+	Backends []AgentRouteBackend `json:"backends,omitempty"`
+
+	// Capability routes to every AgentDeployment in the namespace whose model has
+	// this catalog capability (tool-use, vision, long-context, fast), with equal weights.
+	// Used when Backends is empty.
+	// +optional
+	Capability string `json:"capability,omitempty"`
+}
+
+// AgentRouteBackend is an AgentDeployment receiving a share of the traffic
+type AgentRouteBackend struct {
+	// Name of the AgentDeployment in the route's namespace
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Weight is the relative share of traffic
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1000
+	Weight int32 `json:"weight,omitempty"`
+
+	// Adapter serves the backend's share of the traffic with this LoRA adapter
+	// of the AgentDeployment's spec.serving.adapters instead of the base model
+	// +optional
+	Adapter string `json:"adapter,omitempty"`
+}
+
+// AgentRoute modes
+const (
+	RouteModeGatewayAPI = "GatewayAPI"
+	RouteModeBundled    = "Bundled"
+)
+
+// AgentRouteStatus defines the observed state of AgentRoute
+type AgentRouteStatus struct {
+	// Conditions represent the latest available observations of the route's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Backends lists the AgentDeployments the route currently sends traffic to
+	// +optional
+	Backends []AgentRouteBackend `json:"backends,omitempty"`
+
+	// Address is the in-cluster address of the bundled gateway
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed AgentRoute
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Mode",type=string,JSONPath=`.spec.mode`
+// +kubebuilder:printcolumn:name="Address",type=string,JSONPath=`.status.address`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentRoute is the Schema for the agentroutes API
+type AgentRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AgentRouteSpec   `json:"spec,omitempty"`
+	Status AgentRouteStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AgentRouteList contains a list of AgentRoute
+type AgentRouteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentRoute `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentRoute{}, &AgentRouteList{})
+}
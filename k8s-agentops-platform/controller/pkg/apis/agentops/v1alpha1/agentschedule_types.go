@@ -0,0 +1,133 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentScheduleSpec defines AgentJobs run on a cron schedule
+type AgentScheduleSpec struct {
+	// Schedule in cron format, e.g. "0 2 * * *"
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// TimeZone the schedule is interpreted in, e.g. "Europe/Berlin"; defaults to UTC
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// ConcurrencyPolicy decides what happens when a run is due while the previous
+	// one is still active: Allow starts it anyway, Forbid skips it, Replace stops the
+	// previous run first
+	// +optional
+	// +kubebuilder:default=Forbid
+	// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+	ConcurrencyPolicy string `json:"concurrencyPolicy,omitempty"`
+
+	// Suspend stops new runs without affecting active ones
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// StartingDeadlineSeconds is how late a missed run may still start
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+
+	// SuccessfulRunsHistoryLimit is the number of succeeded AgentJobs kept
+	// +optional
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=0
+	SuccessfulRunsHistoryLimit *int32 `json:"successfulRunsHistoryLimit,omitempty"`
+
+	// FailedRunsHistoryLimit is the number of failed AgentJobs kept
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	FailedRunsHistoryLimit *int32 `json:"failedRunsHistoryLimit,omitempty"`
+
+	// JobTemplate is the AgentJob created for each run
+	// +kubebuilder:validation:Required
+	JobTemplate AgentJobTemplateSpec `json:"jobTemplate"`
+}
+
+// AgentJobTemplateSpec describes the AgentJob created by a schedule
+type AgentJobTemplateSpec struct {
+	// Labels added to each AgentJob
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Spec of each AgentJob
+	// +kubebuilder:validation:Required
+	Spec AgentJobSpec `json:"spec"`
+}
+
+// Schedule concurrency policies
+const (
+	ConcurrencyPolicyAllow   = "Allow"
+	ConcurrencyPolicyForbid  = "Forbid"
+	ConcurrencyPolicyReplace = "Replace"
+)
+
+// AgentScheduleStatus defines the observed state of AgentSchedule
+type AgentScheduleStatus struct {
+	// Conditions represent the latest available observations of the schedule's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Active lists the AgentJobs currently running
+	// +optional
+	Active []string `json:"active,omitempty"`
+
+	// LastScheduleTime is when a run was last started
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// LastSuccessfulTime is when a run last succeeded
+	// +optional
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+
+	// LastRun is the name of the most recently started AgentJob
+	// +optional
+	LastRun string `json:"lastRun,omitempty"`
+
+	// LastRunPhase is the phase of the most recently started AgentJob
+	// +optional
+	LastRunPhase string `json:"lastRunPhase,omitempty"`
+
+	// NextScheduleTime is when the next run is due
+	// +optional
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed AgentSchedule
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+// +kubebuilder:printcolumn:name="Suspend",type=boolean,JSONPath=`.spec.suspend`
+// +kubebuilder:printcolumn:name="Last Run",type=string,JSONPath=`.status.lastRunPhase`
+// +kubebuilder:printcolumn:name="Last Schedule",type=date,JSONPath=`.status.lastScheduleTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentSchedule is the Schema for the agentschedules API
+type AgentSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AgentScheduleSpec   `json:"spec,omitempty"`
+	Status AgentScheduleStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AgentScheduleList contains a list of AgentSchedule
+type AgentScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentSchedule{}, &AgentScheduleList{})
+}
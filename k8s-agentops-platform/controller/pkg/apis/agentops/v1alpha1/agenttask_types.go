@@ -0,0 +1,102 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentTaskSpec defines a slow external operation executed outside the reconcile loop
+type AgentTaskSpec struct {
+	// Type selects the registered task handler (e.g. weight-swap, resolve-digest)
+	// +kubebuilder:validation:Required
+	Type string `json:"type"`
+
+	// Params are passed to the task handler
+	// +optional
+	Params map[string]string `json:"params,omitempty"`
+
+	// MaxAttempts is the number of times the task is tried before it fails
+	// +optional
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+
+	// TimeoutSeconds bounds a single attempt
+	// +optional
+	// +kubebuilder:default=600
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// AgentTask phases
+const (
+	TaskPhasePending   = "Pending"
+	TaskPhaseRunning   = "Running"
+	TaskPhaseSucceeded = "Succeeded"
+	TaskPhaseFailed    = "Failed"
+)
+
+// AgentTaskStatus defines the observed state of AgentTask
+type AgentTaskStatus struct {
+	// Phase is the lifecycle phase of the task
+	// +optional
+	// +kubebuilder:validation:Enum=Pending;Running;Succeeded;Failed
+	Phase string `json:"phase,omitempty"`
+
+	// Attempts is the number of attempts started so far
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// StartTime is when the latest attempt started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the task succeeded or finally failed
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Result holds the handler output
+	// +optional
+	Result map[string]string `json:"result,omitempty"`
+
+	// Message describes the last error
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed AgentTask
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Attempts",type=integer,JSONPath=`.status.attempts`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentTask is the Schema for the agenttasks API
+type AgentTask struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AgentTaskSpec   `json:"spec,omitempty"`
+	Status AgentTaskStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AgentTaskList contains a list of AgentTask
+type AgentTaskList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentTask `json:"items"`
+}
+
+// Done reports whether the task reached a terminal phase
+func (t *AgentTask) Done() bool {
+	return t.Status.Phase == TaskPhaseSucceeded || t.Status.Phase == TaskPhaseFailed
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentTask{}, &AgentTaskList{})
+}
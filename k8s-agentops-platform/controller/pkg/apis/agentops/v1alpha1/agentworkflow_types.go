@@ -0,0 +1,180 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentWorkflowSpec defines a run of a multi-agent pipeline: a DAG of steps, each
+// handled by an AgentDeployment
+type AgentWorkflowSpec struct {
+	// Input is available to the prompts of the steps and to Output as .Input
+	// +optional
+	Input map[string]string `json:"input,omitempty"`
+
+	// Steps of the workflow. A step runs once all the steps it depends on succeeded;
+	// steps whose dependencies are met run concurrently.
+	// +kubebuilder:validation:MinItems=1
+	// +listType=map
+	// +listMapKey=name
+	Steps []WorkflowStep `json:"steps"`
+
+	// Output is a Go template rendered into status.output once every step succeeded,
+	// with .Input and .Steps, the outputs of the steps by name; it defaults to the
+	// output of the last step
+	// +optional
+	Output string `json:"output,omitempty"`
+
+	// TTLSecondsAfterFinished deletes the AgentWorkflow this long after it succeeded
+	// or failed
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// WorkflowStep is a prompt sent to an agent
+type WorkflowStep struct {
+	// Name identifies the step in dependencies, templates and the status
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// AgentDeployment handling the step, in the namespace of the workflow
+	// +kubebuilder:validation:Required
+	AgentDeployment string `json:"agentDeployment"`
+
+	// DependsOn names the steps that must succeed before this one runs
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// System is sent as the system message before the prompt
+	// +optional
+	System string `json:"system,omitempty"`
+
+	// Prompt is a Go template rendered with .Input and .Steps, the outputs of the
+	// steps it depends on by name, e.g. {{ index .Steps "triage" }}, and sent as the
+	// user message
+	// +kubebuilder:validation:Required
+	Prompt string `json:"prompt"`
+
+	// Path of the agent's OpenAI-style chat endpoint
+	// +optional
+	// +kubebuilder:default="/v1/chat/completions"
+	Path string `json:"path,omitempty"`
+
+	// Retries is the number of times a failed attempt is retried before the step,
+	// and the workflow, fail
+	// +optional
+	// +kubebuilder:default=0
+	// +kubebuilder:validation:Minimum=0
+	Retries int32 `json:"retries,omitempty"`
+
+	// Timeout bounds a single attempt
+	// +optional
+	// +kubebuilder:default="5m"
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// AgentWorkflow and workflow step phases
+const (
+	WorkflowPending   = "Pending"
+	WorkflowRunning   = "Running"
+	WorkflowSucceeded = "Succeeded"
+	WorkflowFailed    = "Failed"
+	// WorkflowSkipped steps did not run because the workflow failed first
+	WorkflowSkipped = "Skipped"
+)
+
+// WorkflowStepStatus is the progress of a step
+type WorkflowStepStatus struct {
+	// Name of the step
+	Name string `json:"name"`
+
+	// Phase is Pending, Running, Succeeded, Failed or Skipped
+	Phase string `json:"phase"`
+
+	// Attempts is the number of attempts started so far
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// StartTime is when the latest attempt started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the latest attempt finished
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Output is the agent's answer, truncated to 16KiB
+	// +optional
+	Output string `json:"output,omitempty"`
+
+	// Message describes why the step waits or the error of the latest attempt
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// AgentWorkflowStatus defines the observed state of AgentWorkflow
+type AgentWorkflowStatus struct {
+	// Conditions represent the latest available observations of the workflow's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Phase is Pending, Running, Succeeded or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Steps is the progress of the steps, in the order of spec.steps
+	// +optional
+	Steps []WorkflowStepStatus `json:"steps,omitempty"`
+
+	// Output is the rendered spec.output of a succeeded workflow
+	// +optional
+	Output string `json:"output,omitempty"`
+
+	// StartTime is when the first step started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the workflow succeeded or failed
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Message is a human readable description of the last transition
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// Finished reports whether the workflow succeeded or failed
+func (w *AgentWorkflow) Finished() bool {
+	return w.Status.Phase == WorkflowSucceeded || w.Status.Phase == WorkflowFailed
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=awf
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Message",type=string,JSONPath=`.status.message`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentWorkflow is the Schema for the agentworkflows API
+type AgentWorkflow struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable once the workflow is created"
+	Spec   AgentWorkflowSpec   `json:"spec,omitempty"`
+	Status AgentWorkflowStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AgentWorkflowList contains a list of AgentWorkflow
+type AgentWorkflowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentWorkflow `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentWorkflow{}, &AgentWorkflowList{})
+}
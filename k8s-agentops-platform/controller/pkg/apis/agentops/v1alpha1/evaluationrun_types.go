@@ -0,0 +1,187 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EvaluationSuite is a set of prompts sent to an agent and the assertions its answers
+// must pass
+type EvaluationSuite struct {
+	// Cases are sent to the agent one after the other
+	// +kubebuilder:validation:MinItems=1
+	Cases []EvaluationCase `json:"cases"`
+
+	// Threshold is the lowest score, in percent of the case weights that passed, the
+	// agent needs to pass the evaluation
+	// +optional
+	// +kubebuilder:default=100
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Threshold *int32 `json:"threshold,omitempty"`
+
+	// Path of the agent's OpenAI-style chat endpoint
+	// +optional
+	// +kubebuilder:default="/v1/chat/completions"
+	Path string `json:"path,omitempty"`
+
+	// Timeout bounds the answer to a single case
+	// +optional
+	// +kubebuilder:default="1m"
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// EvaluationCase is a prompt and the assertions on the agent's answer
+type EvaluationCase struct {
+	// Name identifies the case in the results
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// System is sent as the system message before the prompt
+	// +optional
+	System string `json:"system,omitempty"`
+
+	// Prompt is sent as the user message
+	// +kubebuilder:validation:Required
+	Prompt string `json:"prompt"`
+
+	// Assertions must all hold for the case to pass; without assertions any answer passes
+	// +optional
+	Assertions []EvaluationAssertion `json:"assertions,omitempty"`
+
+	// Weight of the case in the score
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+// EvaluationAssertion types
+const (
+	AssertionContains    = "Contains"
+	AssertionNotContains = "NotContains"
+	AssertionMatches     = "Matches"
+	AssertionMaxLatency  = "MaxLatency"
+)
+
+// EvaluationAssertion is a check of the agent's answer
+type EvaluationAssertion struct {
+	// Type is Contains or NotContains (the answer contains Value, ignoring case),
+	// Matches (the answer matches the regular expression Value) or MaxLatency (the
+	// answer took at most the duration Value)
+	// +kubebuilder:validation:Enum=Contains;NotContains;Matches;MaxLatency
+	Type string `json:"type"`
+
+	// Value is the text, regular expression or duration checked
+	// +kubebuilder:validation:Required
+	Value string `json:"value"`
+}
+
+// EvaluationRunSpec defines an evaluation of an agent
+type EvaluationRunSpec struct {
+	// AgentDeployment is the evaluated agent, in the namespace of the EvaluationRun
+	// +kubebuilder:validation:Required
+	AgentDeployment string `json:"agentDeployment"`
+
+	// Candidate evaluates the candidate pods running the pod template the
+	// AgentDeployment holds back for spec.evaluation instead of its serving pods
+	// +optional
+	Candidate bool `json:"candidate,omitempty"`
+
+	EvaluationSuite `json:",inline"`
+}
+
+// EvaluationRun phases
+const (
+	EvaluationRunPending = "Pending"
+	EvaluationRunRunning = "Running"
+	EvaluationRunPassed  = "Passed"
+	EvaluationRunFailed  = "Failed"
+)
+
+// EvaluationCaseResult is the outcome of a case
+type EvaluationCaseResult struct {
+	// Name of the case
+	Name string `json:"name"`
+
+	// Passed reports whether the agent answered and every assertion held
+	Passed bool `json:"passed"`
+
+	// Latency is how long the agent took to answer
+	// +optional
+	Latency *metav1.Duration `json:"latency,omitempty"`
+
+	// Message is the failed assertion or the error of the request
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// EvaluationRunStatus defines the observed state of EvaluationRun
+type EvaluationRunStatus struct {
+	// Conditions represent the latest available observations of the run's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Phase is Pending, Running, Passed or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Score is the share of the case weights that passed, in percent
+	// +optional
+	Score *int32 `json:"score,omitempty"`
+
+	// Results of the cases run so far, in the order of spec.cases
+	// +optional
+	Results []EvaluationCaseResult `json:"results,omitempty"`
+
+	// Pod is the agent pod the cases were sent to
+	// +optional
+	Pod string `json:"pod,omitempty"`
+
+	// StartTime is when the first case was sent
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the last case finished
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Message is a human readable description of the last transition
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// Finished reports whether the run passed or failed
+func (e *EvaluationRun) Finished() bool {
+	return e.Status.Phase == EvaluationRunPassed || e.Status.Phase == EvaluationRunFailed
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=evalrun
+// +kubebuilder:printcolumn:name="Agent",type=string,JSONPath=`.spec.agentDeployment`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Score",type=integer,JSONPath=`.status.score`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// EvaluationRun is the Schema for the evaluationruns API
+type EvaluationRun struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable once the run is created"
+	Spec   EvaluationRunSpec   `json:"spec,omitempty"`
+	Status EvaluationRunStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EvaluationRunList contains a list of EvaluationRun
+type EvaluationRunList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EvaluationRun `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EvaluationRun{}, &EvaluationRunList{})
+}
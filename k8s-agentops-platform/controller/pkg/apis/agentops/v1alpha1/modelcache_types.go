@@ -0,0 +1,165 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ModelCacheSpec defines model artifacts downloaded ahead of time
+type ModelCacheSpec struct {
+	// Source is where the artifacts are downloaded from; set exactly one field
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="source is immutable; create a new ModelCache instead"
+	Source ModelSource `json:"source"`
+
+	// Storage is where the artifacts are cached
+	// +kubebuilder:validation:Required
+	Storage ModelCacheStorage `json:"storage"`
+}
+
+// ModelSource is the origin of model artifacts
+type ModelSource struct {
+	// HuggingFace downloads a repository from the Hugging Face hub
+	// +optional
+	HuggingFace *HuggingFaceSource `json:"huggingFace,omitempty"`
+
+	// S3 syncs a prefix of an S3 bucket
+	// +optional
+	S3 *S3Source `json:"s3,omitempty"`
+
+	// OCI pulls an OCI artifact, e.g. weights pushed with oras
+	// +optional
+	OCI *OCISource `json:"oci,omitempty"`
+}
+
+// HuggingFaceSource is a Hugging Face hub repository
+type HuggingFaceSource struct {
+	// Repo is the repository ID, e.g. mistralai/Mixtral-8x7B-Instruct-v0.1
+	// +kubebuilder:validation:Required
+	Repo string `json:"repo"`
+
+	// Revision is a branch, tag or commit
+	// +optional
+	// +kubebuilder:default=main
+	Revision string `json:"revision,omitempty"`
+
+	// TokenSecretRef selects the key holding a hub token for gated repositories
+	// +optional
+	TokenSecretRef *corev1.SecretKeySelector `json:"tokenSecretRef,omitempty"`
+}
+
+// S3Source is a prefix of an S3 bucket
+type S3Source struct {
+	// URI of the model directory, s3://bucket/prefix
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^s3://`
+	URI string `json:"uri"`
+
+	// CredentialsSecretRef names a Secret exposed to the download as environment
+	// variables (AWS_ACCESS_KEY_ID, ...); omit to use workload identity
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// OCISource is an OCI artifact
+type OCISource struct {
+	// Reference of the artifact, e.g. ghcr.io/myorg/models/llama-2-70b:v1
+	// +kubebuilder:validation:Required
+	Reference string `json:"reference"`
+
+	// PullSecretRef names a kubernetes.io/dockerconfigjson Secret for the registry
+	// +optional
+	PullSecretRef *corev1.LocalObjectReference `json:"pullSecretRef,omitempty"`
+}
+
+// ModelCache storage modes
+const (
+	ModelCachePVC       = "PVC"
+	ModelCacheNodeLocal = "NodeLocal"
+)
+
+// ModelCacheStorage defines where artifacts are cached
+type ModelCacheStorage struct {
+	// Mode is PVC, a volume populated once and mounted ReadOnlyMany by model
+	// servers, or NodeLocal, a host directory populated on every selected node
+	// +optional
+	// +kubebuilder:default=PVC
+	// +kubebuilder:validation:Enum=PVC;NodeLocal
+	Mode string `json:"mode,omitempty"`
+
+	// Size of the cache volume
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// StorageClassName of the cache volume; it must support ReadOnlyMany
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// NodeSelector restricts the nodes a NodeLocal cache is populated on
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+}
+
+// ModelCache phases
+const (
+	ModelCachePending     = "Pending"
+	ModelCacheDownloading = "Downloading"
+	ModelCacheReady       = "Ready"
+	ModelCacheFailed      = "Failed"
+)
+
+// ModelCacheStatus defines the observed state of ModelCache
+type ModelCacheStatus struct {
+	// Conditions represent the latest available observations of the cache's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Phase is Pending, Downloading, Ready or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// ClaimName is the PersistentVolumeClaim holding a PVC cache
+	// +optional
+	ClaimName string `json:"claimName,omitempty"`
+
+	// HostPath is the node directory holding a NodeLocal cache
+	// +optional
+	HostPath string `json:"hostPath,omitempty"`
+
+	// NodesReady is the number of nodes a NodeLocal cache is populated on
+	// +optional
+	NodesReady int32 `json:"nodesReady,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed ModelCache
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Mode",type=string,JSONPath=`.spec.storage.mode`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ModelCache is the Schema for the modelcaches API
+type ModelCache struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ModelCacheSpec   `json:"spec,omitempty"`
+	Status ModelCacheStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ModelCacheList contains a list of ModelCache
+type ModelCacheList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ModelCache `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ModelCache{}, &ModelCacheList{})
+}
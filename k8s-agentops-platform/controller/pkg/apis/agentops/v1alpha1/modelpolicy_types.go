@@ -0,0 +1,52 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ModelPolicySpec restricts the models and providers the AgentDeployments of a
+// namespace may use. Deny lists take precedence over allow lists.
+type ModelPolicySpec struct {
+	// AllowedModels lists the models agents may use; empty allows all
+	// +optional
+	AllowedModels []string `json:"allowedModels,omitempty"`
+
+	// DeniedModels lists models agents may not use
+	// +optional
+	DeniedModels []string `json:"deniedModels,omitempty"`
+
+	// AllowedProviders lists the model providers agents may use; empty allows all
+	// +optional
+	AllowedProviders []string `json:"allowedProviders,omitempty"`
+
+	// DeniedProviders lists model providers agents may not use
+	// +optional
+	DeniedProviders []string `json:"deniedProviders,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=mp
+// +kubebuilder:printcolumn:name="Allowed Models",type=string,JSONPath=`.spec.allowedModels`
+// +kubebuilder:printcolumn:name="Allowed Providers",type=string,JSONPath=`.spec.allowedProviders`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ModelPolicy is the Schema for the modelpolicies API
+type ModelPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ModelPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ModelPolicyList contains a list of ModelPolicy
+type ModelPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ModelPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ModelPolicy{}, &ModelPolicyList{})
+}
@@ -0,0 +1,107 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PromptTemplateSpec defines the desired state of PromptTemplate
+type PromptTemplateSpec struct {
+	// Revisions holds every published revision of the prompt; revisions are
+	// append-only so agents can roll back to any earlier one
+	// +kubebuilder:validation:MinItems=1
+	Revisions []PromptRevision `json:"revisions"`
+}
+
+// PromptRevision is an immutable revision of a prompt template
+type PromptRevision struct {
+	// Revision is the revision number, unique within the template
+	// +kubebuilder:validation:Minimum=1
+	Revision int32 `json:"revision"`
+
+	// SystemPrompt is the system prompt given to the model
+	// +optional
+	SystemPrompt string `json:"systemPrompt,omitempty"`
+
+	// Template is the user prompt template rendered by the agent
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// Description summarizes the change in this revision
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+// PromptTemplateStatus defines the observed state of PromptTemplate
+type PromptTemplateStatus struct {
+	// Conditions represent the latest available observations of the template's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// LatestRevision is the highest revision number in the template
+	// +optional
+	LatestRevision int32 `json:"latestRevision,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed PromptTemplate
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Latest",type=integer,JSONPath=`.status.latestRevision`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PromptTemplate is the Schema for the prompttemplates API
+type PromptTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PromptTemplateSpec   `json:"spec,omitempty"`
+	Status PromptTemplateStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PromptTemplateList contains a list of PromptTemplate
+type PromptTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PromptTemplate `json:"items"`
+}
+
+// PromptTemplateReference selects a revision of a PromptTemplate
+type PromptTemplateReference struct {
+	// Name of the PromptTemplate in the same namespace
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Revision to mount; defaults to the latest revision
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	Revision *int32 `json:"revision,omitempty"`
+}
+
+// RevisionByNumber returns the revision with the given number, or nil
+func (t *PromptTemplate) RevisionByNumber(revision int32) *PromptRevision {
+	for i := range t.Spec.Revisions {
+		if t.Spec.Revisions[i].Revision == revision {
+			return &t.Spec.Revisions[i]
+		}
+	}
+	return nil
+}
+
+// Latest returns the revision with the highest number, or nil if there are none
+func (t *PromptTemplate) Latest() *PromptRevision {
+	var latest *PromptRevision
+	for i := range t.Spec.Revisions {
+		if latest == nil || t.Spec.Revisions[i].Revision > latest.Revision {
+			latest = &t.Spec.Revisions[i]
+		}
+	}
+	return latest
+}
+
+func init() {
+	SchemeBuilder.Register(&PromptTemplate{}, &PromptTemplateList{})
+}
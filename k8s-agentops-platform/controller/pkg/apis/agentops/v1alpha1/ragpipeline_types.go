@@ -0,0 +1,329 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RAGPipelineSpec defines a retrieval pipeline agents query for context: documents
+// are embedded into a vector database by an ingestion Job, and a retriever embeds
+// queries with the same model to look them up
+type RAGPipelineSpec struct {
+	// VectorStore is the vector database holding the embedded documents
+	// +kubebuilder:validation:Required
+	VectorStore VectorStoreSpec `json:"vectorStore"`
+
+	// Embedder is the embedding model served for ingestion and retrieval
+	// +kubebuilder:validation:Required
+	Embedder EmbedderSpec `json:"embedder"`
+
+	// Retriever serves retrieval queries to agents
+	// +optional
+	Retriever RetrieverSpec `json:"retriever,omitempty"`
+
+	// Sources are the documents ingested into the vector store. Each source is
+	// ingested by its own Job; changing a source ingests it again.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Sources []DocumentSource `json:"sources,omitempty"`
+
+	// Chunking splits documents before they are embedded; sources may override it
+	// +optional
+	Chunking *ChunkingSpec `json:"chunking,omitempty"`
+
+	// Ingestion schedules re-ingestion of the sources and tunes it
+	// +optional
+	Ingestion *IngestionSpec `json:"ingestion,omitempty"`
+}
+
+// IngestionSpec defines when and how sources are ingested again
+type IngestionSpec struct {
+	// Schedule in cron format re-ingests every source without a schedule of its
+	// own, e.g. "0 3 * * *"; without it sources are only ingested when they change
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// TimeZone the schedules are interpreted in, e.g. "Europe/Berlin"; defaults to UTC
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Suspend stops scheduled re-ingestion; changed sources are still ingested
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// Deduplication is content, which skips chunks whose content is already stored
+	// and removes the chunks a source no longer yields, or none, which replaces all
+	// chunks of the source on every run
+	// +optional
+	// +kubebuilder:default=content
+	// +kubebuilder:validation:Enum=content;none
+	Deduplication string `json:"deduplication,omitempty"`
+}
+
+// Deduplication modes
+const (
+	DeduplicationContent = "content"
+	DeduplicationNone    = "none"
+)
+
+// Vector store types
+const (
+	VectorStorePgvector = "pgvector"
+	VectorStoreQdrant   = "qdrant"
+	VectorStoreWeaviate = "weaviate"
+)
+
+// VectorStoreSpec references an existing vector database
+type VectorStoreSpec struct {
+	// Type is pgvector, qdrant or weaviate
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=pgvector;qdrant;weaviate
+	Type string `json:"type"`
+
+	// URL of the database, e.g. http://qdrant.vector:6333 or a postgres:// DSN
+	// without credentials
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// CredentialsSecretRef names a Secret of the namespace whose url key replaces
+	// URL, e.g. a DSN with credentials, and whose api-key key authenticates to
+	// Qdrant or Weaviate
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+
+	// Collection is the Qdrant collection, Weaviate class or pgvector table;
+	// defaults to the pipeline name
+	// +optional
+	Collection string `json:"collection,omitempty"`
+}
+
+// EmbedderSpec defines the embedding model Deployment
+type EmbedderSpec struct {
+	// Model is the Hugging Face ID of the embedding model, e.g. BAAI/bge-small-en-v1.5
+	// +kubebuilder:validation:Required
+	Model string `json:"model"`
+
+	// Image of the embedding server; it is started with --model-id and --port like
+	// text-embeddings-inference
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Replicas of the embedding server
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Resources of the embedding server
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// TokenSecretRef selects the key holding a Hugging Face hub token for gated models
+	// +optional
+	TokenSecretRef *corev1.SecretKeySelector `json:"tokenSecretRef,omitempty"`
+}
+
+// RetrieverSpec defines the retriever Deployment
+type RetrieverSpec struct {
+	// Image of the retriever
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Replicas of the retriever
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// TopK is the number of chunks returned per query unless the query asks otherwise
+	// +optional
+	// +kubebuilder:default=5
+	// +kubebuilder:validation:Minimum=1
+	TopK int32 `json:"topK,omitempty"`
+}
+
+// DocumentSource is a location documents are ingested from; set URI or Connector
+// +kubebuilder:validation:XValidation:rule="has(self.uri) != has(self.connector)",message="set exactly one of uri and connector"
+type DocumentSource struct {
+	// Name identifies the source in the vector store metadata
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=40
+	Name string `json:"name"`
+
+	// URI of the documents: s3://bucket/prefix, gs://bucket/prefix, an https://
+	// URL, or a git repository as git+https://host/repo.git
+	// +optional
+	// +kubebuilder:validation:Pattern=`^(s3|gs|https|git\+https)://`
+	URI string `json:"uri,omitempty"`
+
+	// Connector fetches the documents with a connector image, e.g. for Confluence
+	// +optional
+	Connector *ConnectorSpec `json:"connector,omitempty"`
+
+	// CredentialsSecretRef names a Secret exposed to the ingestion and the connector
+	// as environment variables, e.g. AWS_ACCESS_KEY_ID, GIT_TOKEN or CONFLUENCE_TOKEN
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+
+	// Schedule in cron format re-ingests this source, overriding ingestion.schedule
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// Chunking overrides the chunking of the pipeline for this source
+	// +optional
+	Chunking *ChunkingSpec `json:"chunking,omitempty"`
+}
+
+// ConnectorSpec runs an image that writes the documents of a source into the
+// directory named by $DOCUMENTS_DIR before they are chunked and embedded
+type ConnectorSpec struct {
+	// Image of the connector
+	// +kubebuilder:validation:Required
+	Image string `json:"image"`
+
+	// Args of the connector
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// Env of the connector, e.g. the Confluence base URL and space key
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+}
+
+// ChunkingSpec defines how documents are split before embedding
+type ChunkingSpec struct {
+	// Size of a chunk in tokens
+	// +optional
+	// +kubebuilder:default=512
+	// +kubebuilder:validation:Minimum=1
+	Size int32 `json:"size,omitempty"`
+
+	// Overlap between consecutive chunks in tokens
+	// +optional
+	// +kubebuilder:default=64
+	// +kubebuilder:validation:Minimum=0
+	Overlap int32 `json:"overlap,omitempty"`
+}
+
+// RAGPipeline phases
+const (
+	RAGPipelinePending   = "Pending"
+	RAGPipelineIngesting = "Ingesting"
+	RAGPipelineReady     = "Ready"
+	RAGPipelineFailed    = "Failed"
+)
+
+// RAGPipelineStatus defines the observed state of RAGPipeline
+type RAGPipelineStatus struct {
+	// Conditions represent the latest available observations of the pipeline's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Phase is Pending, Ingesting, Ready or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Endpoint is the retrieval URL injected into agents referencing the pipeline
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// IngestedRevision is the revision of the pipeline once every source of it
+	// is ingested
+	// +optional
+	IngestedRevision string `json:"ingestedRevision,omitempty"`
+
+	// LastIngestionTime is when the last successful ingestion of any source finished
+	// +optional
+	LastIngestionTime *metav1.Time `json:"lastIngestionTime,omitempty"`
+
+	// Sources reports the ingestion of each source
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Sources []SourceStatus `json:"sources,omitempty"`
+
+	// EmbedderReadyReplicas is the number of ready embedding server pods
+	// +optional
+	EmbedderReadyReplicas int32 `json:"embedderReadyReplicas,omitempty"`
+
+	// RetrieverReadyReplicas is the number of ready retriever pods
+	// +optional
+	RetrieverReadyReplicas int32 `json:"retrieverReadyReplicas,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed RAGPipeline
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// Source ingestion phases
+const (
+	SourcePending   = "Pending"
+	SourceIngesting = "Ingesting"
+	SourceIngested  = "Ingested"
+	SourceFailed    = "Failed"
+)
+
+// SourceStatus is the observed ingestion state of a source
+type SourceStatus struct {
+	// Name of the source
+	Name string `json:"name"`
+
+	// Phase is Pending, Ingesting, Ingested or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Revision of the source the last successful ingestion embedded
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
+	// LastJob is the latest ingestion Job of the source
+	// +optional
+	LastJob string `json:"lastJob,omitempty"`
+
+	// LastIngestionTime is when the last successful ingestion of the source finished
+	// +optional
+	LastIngestionTime *metav1.Time `json:"lastIngestionTime,omitempty"`
+
+	// LastScheduleTime is when the source was last re-ingested on schedule
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// NextScheduleTime is when the source is re-ingested next
+	// +optional
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+
+	// Message explains a failed ingestion
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=rag
+// +kubebuilder:printcolumn:name="Store",type=string,JSONPath=`.spec.vectorStore.type`
+// +kubebuilder:printcolumn:name="Embedder",type=string,JSONPath=`.spec.embedder.model`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// RAGPipeline is the Schema for the ragpipelines API
+type RAGPipeline struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RAGPipelineSpec   `json:"spec,omitempty"`
+	Status RAGPipelineStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RAGPipelineList contains a list of RAGPipeline
+type RAGPipelineList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RAGPipeline `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RAGPipeline{}, &RAGPipelineList{})
+}
@@ -0,0 +1,95 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RateLimitPolicySpec defines request and token limits enforced in front of agents
+type RateLimitPolicySpec struct {
+	// Selector selects the AgentDeployments in the namespace the policy applies to;
+	// empty selects all of them
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// RequestsPerMinute limits requests per agent pod
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	RequestsPerMinute *int32 `json:"requestsPerMinute,omitempty"`
+
+	// TokensPerMinute limits LLM tokens per agent pod. Tokens are only known to the
+	// agent runtime, so this limit is passed to it rather than to the proxy.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TokensPerMinute *int32 `json:"tokensPerMinute,omitempty"`
+
+	// PerClient sets separate request limits for identified clients
+	// +optional
+	PerClient *ClientRateLimits `json:"perClient,omitempty"`
+}
+
+// ClientRateLimits defines per-client request limits
+type ClientRateLimits struct {
+	// Header carries the client identity
+	// +optional
+	// +kubebuilder:default=x-client-id
+	Header string `json:"header,omitempty"`
+
+	// Clients lists the identified clients and their limits; other clients share
+	// the policy-wide limit
+	// +kubebuilder:validation:MinItems=1
+	Clients []ClientRateLimit `json:"clients"`
+}
+
+// ClientRateLimit is the request limit of a single client
+type ClientRateLimit struct {
+	// Name is the value of the client header
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// RequestsPerMinute limits the client's requests per agent pod
+	// +kubebuilder:validation:Minimum=1
+	RequestsPerMinute int32 `json:"requestsPerMinute"`
+}
+
+// RateLimitPolicyStatus defines the observed state of RateLimitPolicy
+type RateLimitPolicyStatus struct {
+	// Conditions represent the latest available observations of the policy's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// AgentDeployments lists the agents the policy applies to
+	// +optional
+	AgentDeployments []string `json:"agentDeployments,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed RateLimitPolicy
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="RPM",type=integer,JSONPath=`.spec.requestsPerMinute`
+// +kubebuilder:printcolumn:name="TPM",type=integer,JSONPath=`.spec.tokensPerMinute`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// RateLimitPolicy is the Schema for the ratelimitpolicies API
+type RateLimitPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RateLimitPolicySpec   `json:"spec,omitempty"`
+	Status RateLimitPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RateLimitPolicyList contains a list of RateLimitPolicy
+type RateLimitPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RateLimitPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RateLimitPolicy{}, &RateLimitPolicyList{})
+}
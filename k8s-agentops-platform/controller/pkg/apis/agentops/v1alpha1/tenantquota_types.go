@@ -0,0 +1,92 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TenantQuotaSpec limits what the AgentDeployments of a namespace may consume
+type TenantQuotaSpec struct {
+	// MaxAgents limits the number of AgentDeployments
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxAgents *int32 `json:"maxAgents,omitempty"`
+
+	// MaxReplicas limits the agent pods of all AgentDeployments together. Autoscaled
+	// agents count with their maximum replicas.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+
+	// MaxGPUs limits the GPUs requested by agent and model server pods together, at
+	// their maximum replicas
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxGPUs *int32 `json:"maxGPUs,omitempty"`
+
+	// AllowedModels restricts the models agents may use; empty allows all
+	// +optional
+	AllowedModels []string `json:"allowedModels,omitempty"`
+}
+
+// TenantQuotaUsage is the consumption counted against a TenantQuota
+type TenantQuotaUsage struct {
+	// Agents is the number of AgentDeployments
+	Agents int32 `json:"agents"`
+
+	// Replicas is the number of agent pods at maximum scale
+	Replicas int32 `json:"replicas"`
+
+	// GPUs is the number of GPUs at maximum scale
+	GPUs int32 `json:"gpus"`
+}
+
+// TenantQuotaStatus defines the observed state of TenantQuota
+type TenantQuotaStatus struct {
+	// Used is the current consumption of the namespace
+	// +optional
+	Used TenantQuotaUsage `json:"used,omitempty"`
+
+	// Rejected lists the AgentDeployments that are not rolled out because they
+	// exceed the quota
+	// +optional
+	Rejected []string `json:"rejected,omitempty"`
+
+	// Conditions represent the latest available observations of the quota's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed TenantQuota
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=tq
+// +kubebuilder:printcolumn:name="Agents",type=integer,JSONPath=`.status.used.agents`
+// +kubebuilder:printcolumn:name="Max Agents",type=integer,JSONPath=`.spec.maxAgents`
+// +kubebuilder:printcolumn:name="GPUs",type=integer,JSONPath=`.status.used.gpus`
+// +kubebuilder:printcolumn:name="Max GPUs",type=integer,JSONPath=`.spec.maxGPUs`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// TenantQuota is the Schema for the tenantquotas API
+type TenantQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TenantQuotaSpec   `json:"spec,omitempty"`
+	Status TenantQuotaStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TenantQuotaList contains a list of TenantQuota
+type TenantQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TenantQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TenantQuota{}, &TenantQuotaList{})
+}
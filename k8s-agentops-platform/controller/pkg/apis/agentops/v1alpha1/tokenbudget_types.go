@@ -0,0 +1,109 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TokenBudgetSpec defines token and cost limits for a team's agents
+type TokenBudgetSpec struct {
+	// Period is the accounting window the limits apply to; it resets at 00:00 UTC
+	// (Daily) or on the first of the month (Monthly)
+	// +optional
+	// +kubebuilder:default=Monthly
+	// +kubebuilder:validation:Enum=Daily;Monthly
+	Period string `json:"period,omitempty"`
+
+	// MaxTokens is the number of tokens the selected agents may consume per period
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxTokens *int64 `json:"maxTokens,omitempty"`
+
+	// MaxCost is the spend in USD the selected agents may incur per period, priced
+	// from the model catalog
+	// +optional
+	MaxCost *resource.Quantity `json:"maxCost,omitempty"`
+
+	// Selector selects the AgentDeployments in the namespace covered by the budget;
+	// empty selects all of them
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Enforcement is what happens when the budget is exceeded: Alert only sets the
+	// BudgetExceeded condition, ScaleToZero also scales the agents down until the
+	// next period
+	// +optional
+	// +kubebuilder:default=Alert
+	// +kubebuilder:validation:Enum=Alert;ScaleToZero
+	Enforcement string `json:"enforcement,omitempty"`
+}
+
+// TokenBudget periods and enforcement modes
+const (
+	BudgetPeriodDaily   = "Daily"
+	BudgetPeriodMonthly = "Monthly"
+
+	BudgetEnforcementAlert       = "Alert"
+	BudgetEnforcementScaleToZero = "ScaleToZero"
+)
+
+// TokenBudgetStatus defines the observed state of TokenBudget
+type TokenBudgetStatus struct {
+	// Conditions represent the latest available observations of the budget's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// PeriodStart is the start of the current accounting period
+	// +optional
+	PeriodStart *metav1.Time `json:"periodStart,omitempty"`
+
+	// TokensUsed is the number of tokens consumed in the current period
+	// +optional
+	TokensUsed int64 `json:"tokensUsed,omitempty"`
+
+	// Cost is the spend in USD in the current period
+	// +optional
+	Cost *resource.Quantity `json:"cost,omitempty"`
+
+	// Exceeded is true when a limit is blown for the current period
+	// +optional
+	Exceeded bool `json:"exceeded,omitempty"`
+
+	// AgentDeployments lists the agents covered by the budget
+	// +optional
+	AgentDeployments []string `json:"agentDeployments,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed TokenBudget
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Period",type=string,JSONPath=`.spec.period`
+// +kubebuilder:printcolumn:name="Tokens",type=integer,JSONPath=`.status.tokensUsed`
+// +kubebuilder:printcolumn:name="Cost",type=string,JSONPath=`.status.cost`
+// +kubebuilder:printcolumn:name="Exceeded",type=boolean,JSONPath=`.status.exceeded`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// TokenBudget is the Schema for the tokenbudgets API
+type TokenBudget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TokenBudgetSpec   `json:"spec,omitempty"`
+	Status TokenBudgetStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TokenBudgetList contains a list of TokenBudget
+type TokenBudgetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TokenBudget `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TokenBudget{}, &TokenBudgetList{})
+}
@@ -0,0 +1,148 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ToolServerSpec describes a tool-calling server, such as an MCP server, agents
+// reference in spec.tools
+type ToolServerSpec struct {
+	// Image of the tool server
+	// +kubebuilder:validation:Required
+	Image string `json:"image"`
+
+	// Args of the tool server
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// Env of the tool server
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Port the tool server listens on; it also gets it as $PORT
+	// +optional
+	// +kubebuilder:default=3000
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port,omitempty"`
+
+	// Path of the tool endpoint
+	// +optional
+	// +kubebuilder:default=/mcp
+	Path string `json:"path,omitempty"`
+
+	// Transport is streamable-http or sse
+	// +optional
+	// +kubebuilder:default=streamable-http
+	// +kubebuilder:validation:Enum=streamable-http;sse
+	Transport string `json:"transport,omitempty"`
+
+	// Capabilities are the tools the server offers, e.g. kubectl-get or jira-search;
+	// they are advertised to the agents referencing it
+	// +optional
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// Auth is the token the agents present to the tool server
+	// +optional
+	Auth *ToolAuthSpec `json:"auth,omitempty"`
+
+	// Attach is service, which runs the tool server in its own Deployment behind a
+	// Service, or sidecar, which runs it in every agent pod referencing it. Agents
+	// may override it per reference.
+	// +optional
+	// +kubebuilder:default=service
+	// +kubebuilder:validation:Enum=service;sidecar
+	Attach string `json:"attach,omitempty"`
+
+	// Replicas of the tool server Deployment in service mode
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Resources of the tool server container
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Rules are the Kubernetes API permissions the tool server gets in its namespace,
+	// through a ServiceAccount and Role of its own in service mode, or of the agent
+	// in sidecar mode
+	// +optional
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+}
+
+// Tool server attach modes
+const (
+	ToolAttachService = "service"
+	ToolAttachSidecar = "sidecar"
+)
+
+// ToolReference attaches a ToolServer to an agent
+type ToolReference struct {
+	// Name of the ToolServer in the same namespace
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Attach overrides the attach mode of the ToolServer for this agent
+	// +optional
+	// +kubebuilder:validation:Enum=service;sidecar
+	Attach string `json:"attach,omitempty"`
+}
+
+// ToolAuthSpec defines the bearer token shared by a tool server and its agents
+type ToolAuthSpec struct {
+	// TokenSecretRef selects the token; the tool server gets it as $AUTH_TOKEN
+	// +kubebuilder:validation:Required
+	TokenSecretRef corev1.SecretKeySelector `json:"tokenSecretRef"`
+}
+
+// ToolServerStatus defines the observed state of ToolServer
+type ToolServerStatus struct {
+	// Conditions represent the latest available observations of the tool server's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Endpoint is the URL of the tool server in service mode
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// ReadyReplicas is the number of ready tool server pods in service mode
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed ToolServer
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=tool
+// +kubebuilder:printcolumn:name="Attach",type=string,JSONPath=`.spec.attach`
+// +kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.endpoint`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ToolServer is the Schema for the toolservers API
+type ToolServer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ToolServerSpec   `json:"spec,omitempty"`
+	Status ToolServerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ToolServerList contains a list of ToolServer
+type ToolServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ToolServer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ToolServer{}, &ToolServerList{})
+}
@@ -2,16 +2,37 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // AgentDeploymentSpec defines the desired state of AgentDeployment
+// +kubebuilder:validation:XValidation:rule="!has(self.secrets) || !self.secrets.exists(s, has(s.provider) && s.provider == 'external') || has(self.secretStoreRef)",message="secrets with provider external require secretStoreRef"
 type AgentDeploymentSpec struct {
 	// Model is the LLM model to deploy
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:Enum=claude-3-opus;claude-3-sonnet;claude-3-haiku;gpt-4;gpt-4-turbo;gpt-3.5-turbo;llama-2-70b;mixtral-8x7b
 	Model string `json:"model"`
 
+	// Image replaces the agent container image, e.g. with the build of a pull request;
+	// ignored when the agent pod serves the model itself
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Provider is the backend serving the model; defaults to the model's usual
+	// provider (anthropic for Claude, openai for GPT, vllm for open-weight models)
+	// +optional
+	// +kubebuilder:validation:Enum=anthropic;openai;azure-openai;bedrock;vertex;vllm;ollama;tgi
+	Provider string `json:"provider,omitempty"`
+
+	// ProviderConfig holds provider-specific settings
+	// +optional
+	ProviderConfig *ProviderConfig `json:"providerConfig,omitempty"`
+
+	// Serving configures where the model is served for self-hosted providers
+	// +optional
+	Serving *ServingSpec `json:"serving,omitempty"`
+
 	// Replicas is the number of desired pods
 	// +optional
 	// +kubebuilder:default=2
@@ -27,6 +48,75 @@ type AgentDeploymentSpec struct {
 	// +optional
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 
+	// ResourcesByComponent sets the requests and limits of each container the
+	// controller manages. Its agent and server entries take precedence over resources
+	// and serving.selfHosted.resources.
+	// +optional
+	ResourcesByComponent *ComponentResources `json:"resourcesByComponent,omitempty"`
+
+	// AutoRightSize applies status.recommendations to the agent container the next
+	// time its pods roll out; it never triggers a rollout on its own
+	// +optional
+	AutoRightSize bool `json:"autoRightSize,omitempty"`
+
+	// PriorityClassName is the PriorityClass of the agent pods
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// PriorityTier selects a PriorityClass managed by the controller
+	// (agentops-critical, agentops-standard or agentops-batch), which is created on
+	// first use; ignored when priorityClassName is set
+	// +optional
+	// +kubebuilder:validation:Enum=critical;standard;batch
+	PriorityTier string `json:"priorityTier,omitempty"`
+
+	// SpreadPolicy spreads the agent pods across zones, nodes or GPU node pools
+	// +optional
+	SpreadPolicy *SpreadPolicySpec `json:"spreadPolicy,omitempty"`
+
+	// CapacityPolicy decides what happens to replicas of a GPU-backed agent that the
+	// free GPUs of the cluster cannot hold: report creates them and only sets the
+	// CapacityPending condition, queue holds them back until GPUs free up, reject
+	// adds none until all fit. Unset is report.
+	// +optional
+	// +kubebuilder:validation:Enum=report;queue;reject
+	CapacityPolicy string `json:"capacityPolicy,omitempty"`
+
+	// Placement puts the agent pods on spot or preemptible nodes
+	// +optional
+	Placement *PlacementSpec `json:"placement,omitempty"`
+
+	// SessionStore keeps the agent's conversations in Redis or Postgres, so they
+	// survive restarts and any replica can continue a session
+	// +optional
+	SessionStore *SessionStoreSpec `json:"sessionStore,omitempty"`
+
+	// Queue puts a durable request queue in front of the agent, which buffers
+	// requests during rollouts and provider outages and retries them with backoff
+	// +optional
+	Queue *QueueSpec `json:"queue,omitempty"`
+
+	// Resilience routes the agent's provider calls through a sidecar that bounds
+	// them with timeouts, retries them and opens a circuit on a failing provider,
+	// failing over to a secondary provider or to cached responses
+	// +optional
+	Resilience *ResilienceSpec `json:"resilience,omitempty"`
+
+	// ResponseCache answers repeated prompts from a cache deployed for the agent
+	// instead of calling the model
+	// +optional
+	ResponseCache *ResponseCacheSpec `json:"responseCache,omitempty"`
+
+	// TrafficPolicy classes the agent's requests as interactive or batch and caps
+	// the requests admitted at once, overall and per client, in the gateway sidecar
+	// +optional
+	TrafficPolicy *TrafficPolicySpec `json:"trafficPolicy,omitempty"`
+
+	// EphemeralStorage sizes local disk for temporary tensors and caches; when
+	// omitted it is derived from the model's cache footprint in the catalog
+	// +optional
+	EphemeralStorage *EphemeralStorageSpec `json:"ephemeralStorage,omitempty"`
+
 	// SecurityContext defines security settings
 	// +optional
 	SecurityContext *SecurityContextSpec `json:"securityContext,omitempty"`
@@ -35,134 +125,2502 @@ type AgentDeploymentSpec struct {
 	// +optional
 	Secrets []SecretReference `json:"secrets,omitempty"`
 
+	// SecretStoreRef is the External Secrets Operator store used by secrets with the external provider
+	// +optional
+	SecretStoreRef *SecretStoreReference `json:"secretStoreRef,omitempty"`
+
 	// Monitoring configuration
 	// +optional
 	Monitoring *MonitoringSpec `json:"monitoring,omitempty"`
 
+	// Telemetry configures OpenTelemetry trace export from the agent pods
+	// +optional
+	Telemetry *TelemetrySpec `json:"telemetry,omitempty"`
+
+	// Audit records the agent's prompts and responses, with PII redacted, in an
+	// audit sidecar that ships them to a sink
+	// +optional
+	Audit *AuditSpec `json:"audit,omitempty"`
+
+	// Ports served by the agent container in addition to the main "http" port, e.g.
+	// a WebSocket or gRPC inference port; listing a port named "http" replaces the
+	// default one
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Ports []AgentPort `json:"ports,omitempty"`
+
 	// Ingress configuration
 	// +optional
 	Ingress *IngressSpec `json:"ingress,omitempty"`
+
+	// Health configures how agent liveness and readiness are checked
+	// +optional
+	Health *HealthSpec `json:"health,omitempty"`
+
+	// Probes overrides the timing and paths of the agent container probes, adds a
+	// startup probe, or disables probes for runtimes that cannot be checked
+	// +optional
+	Probes *ProbesSpec `json:"probes,omitempty"`
+
+	// Warmup is run against every new agent pod once its containers are ready, e.g.
+	// a first inference to fill caches; the pod only receives traffic afterwards
+	// +optional
+	Warmup *WarmupSpec `json:"warmup,omitempty"`
+
+	// Lifecycle controls how agent pods are taken out of service, so streaming
+	// responses survive rollouts and scale-down
+	// +optional
+	Lifecycle *LifecycleSpec `json:"lifecycle,omitempty"`
+
+	// Upgrade configures how new revisions and model weight updates are rolled out
+	// +optional
+	Upgrade *UpgradeSpec `json:"upgrade,omitempty"`
+
+	// PoolRef references an AgentPool in the same namespace whose warm pods are
+	// claimed to cover cold starts
+	// +optional
+	PoolRef *corev1.LocalObjectReference `json:"poolRef,omitempty"`
+
+	// PromptTemplateRef selects the PromptTemplate revision mounted into the agent
+	// +optional
+	PromptTemplateRef *PromptTemplateReference `json:"promptTemplateRef,omitempty"`
+
+	// RAGRef names a RAGPipeline of the namespace whose retrieval endpoint is
+	// injected into the agent
+	// +optional
+	RAGRef *corev1.LocalObjectReference `json:"ragRef,omitempty"`
+
+	// Tools are the ToolServers of the namespace the agent calls, attached as
+	// sidecars or reached through their Service
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Tools []ToolReference `json:"tools,omitempty"`
+
+	// Peers are the other AgentDeployments this agent may call. The agent gets their
+	// endpoints, and agents of a peering only accept calls from the agents declaring them.
+	// +optional
+	Peers []PeerReference `json:"peers,omitempty"`
+
+	// PeerAuth is how peer agents calling this one authenticate: mtls through the
+	// Istio mesh of spec.mesh, or token, a signed token verified by the agent's gateway
+	// sidecar
+	// +optional
+	// +kubebuilder:validation:Enum=mtls;token
+	PeerAuth string `json:"peerAuth,omitempty"`
+
+	// Dependencies are checked by the controller before new agent pods receive
+	// traffic: the pods stay out of the agent's Service, and the agent is not Ready,
+	// until every dependency is reachable
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Dependencies []DependencySpec `json:"dependencies,omitempty"`
+
+	// Hooks are webhooks called around changes to the agent's Deployment
+	// +optional
+	Hooks *HooksSpec `json:"hooks,omitempty"`
+
+	// ScaleToZero scales idle agents down to zero replicas; requests arriving while
+	// scaled down are held by the activator until a pod is ready
+	// +optional
+	ScaleToZero *ScaleToZeroSpec `json:"scaleToZero,omitempty"`
+
+	// Hibernation scales agents that receive no traffic down to a few replicas, or to
+	// none, and reports it in the Hibernated condition
+	// +optional
+	Hibernation *HibernationSpec `json:"hibernation,omitempty"`
+
+	// TTLSinceLastActivity deletes the AgentDeployment once it received no requests
+	// for this long, e.g. to clean up experiments in shared clusters
+	// +optional
+	TTLSinceLastActivity *metav1.Duration `json:"ttlSinceLastActivity,omitempty"`
+
+	// Termination configures the cleanup performed when the AgentDeployment is deleted
+	// +optional
+	Termination *TerminationSpec `json:"termination,omitempty"`
+
+	// Mesh joins the agent to an Istio or Linkerd service mesh
+	// +optional
+	Mesh *MeshSpec `json:"mesh,omitempty"`
+
+	// ImageUpdatePolicy pins the agent image to a digest: pinned resolves the tag
+	// once, when the image changes; track-tag follows the tag and rolls out when it
+	// moves. Unset deploys the tag as is.
+	// +optional
+	// +kubebuilder:validation:Enum=pinned;track-tag
+	ImageUpdatePolicy string `json:"imageUpdatePolicy,omitempty"`
+
+	// ImagePullSecrets are the Secrets the agent pods pull their images with
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Preflight configures the checks run before the agent's workload is created
+	// +optional
+	Preflight *PreflightSpec `json:"preflight,omitempty"`
+
+	// CloudIdentity binds the agent pods to a cloud IAM identity through workload
+	// identity, so Bedrock, Vertex AI and Azure OpenAI are called without static keys
+	// +optional
+	CloudIdentity *CloudIdentitySpec `json:"cloudIdentity,omitempty"`
+
+	// RevisionHistoryLimit is the number of AgentRevisions kept for rollback
+	// +optional
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// ProgressiveDelivery renders the agent as an Argo Rollouts Rollout instead of a
+	// Deployment and delivers new versions progressively
+	// +optional
+	ProgressiveDelivery *ProgressiveDeliverySpec `json:"progressiveDelivery,omitempty"`
+
+	// Evaluation holds back changes of the pod template until a candidate pod running
+	// the new template passed the suite in an EvaluationRun
+	// +optional
+	Evaluation *EvaluationSuite `json:"evaluation,omitempty"`
+
+	// Shadow mirrors the agent's requests to a candidate AgentDeployment, whose
+	// responses are discarded, to try a new model under production load
+	// +optional
+	Shadow *ShadowSpec `json:"shadow,omitempty"`
 }
 
-// AutoscalingSpec defines autoscaling configuration
-type AutoscalingSpec struct {
-	// Enabled determines if autoscaling is enabled
+// ProviderConfig holds the settings of the selected provider; only the block
+// matching spec.provider is used
+type ProviderConfig struct {
+	// CredentialsSecretRef names a Secret with the provider credentials: "api-key"
+	// for anthropic, openai and azure-openai, "aws-access-key-id" and
+	// "aws-secret-access-key" for bedrock, "credentials.json" for vertex and
+	// "hf-token" for vllm and tgi. Bedrock and Vertex fall back to workload identity
+	// when it is omitted.
 	// +optional
-	// +kubebuilder:default=true
-	Enabled bool `json:"enabled,omitempty"`
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
 
-	// MinReplicas is the minimum number of replicas
 	// +optional
-	// +kubebuilder:default=2
+	Anthropic *AnthropicConfig `json:"anthropic,omitempty"`
+
+	// +optional
+	OpenAI *OpenAIConfig `json:"openai,omitempty"`
+
+	// +optional
+	AzureOpenAI *AzureOpenAIConfig `json:"azureOpenAI,omitempty"`
+
+	// +optional
+	Bedrock *BedrockConfig `json:"bedrock,omitempty"`
+
+	// +optional
+	Vertex *VertexConfig `json:"vertex,omitempty"`
+
+	// SelfHosted configures the vllm, ollama and tgi runtimes
+	// +optional
+	SelfHosted *SelfHostedConfig `json:"selfHosted,omitempty"`
+}
+
+// ServingSpec defines how the model behind the agent is served
+type ServingSpec struct {
+	// SelfHosted runs the vllm or tgi runtime selected by spec.provider as a separate
+	// StatefulSet and points the agent at it, instead of serving from the agent pod
+	// +optional
+	SelfHosted *SelfHostedServingSpec `json:"selfHosted,omitempty"`
+
+	// Batching tunes how the vllm or tgi runtime batches requests, in the model
+	// server or the agent pod
+	// +optional
+	Batching *BatchingSpec `json:"batching,omitempty"`
+
+	// Quantization serves quantized weights: awq or gptq load the pre-quantized
+	// weights of the model, fp8 quantizes 16-bit weights on load. Fewer GPUs are
+	// requested by default.
+	// +optional
+	// +kubebuilder:validation:Enum=awq;gptq;fp8
+	Quantization string `json:"quantization,omitempty"`
+
+	// MaxContextTokens caps the context window the runtime serves, below the
+	// model's; GPUs are sized by default to hold one sequence of that length
+	// +optional
+	// +kubebuilder:validation:Minimum=256
+	MaxContextTokens *int32 `json:"maxContextTokens,omitempty"`
+
+	// TensorParallel is the number of GPUs each server pod, or the agent pod,
+	// shards the model across; it takes precedence over selfHosted.gpu.count
+	// +optional
 	// +kubebuilder:validation:Minimum=1
-	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	TensorParallel *int32 `json:"tensorParallel,omitempty"`
 
-	// MaxReplicas is the maximum number of replicas
+	// PipelineParallel splits the model's layers across this many model server
+	// pods, on as many nodes, serving as one replica. Above 1 the server runs as a
+	// LeaderWorkerSet; requires serving.selfHosted and provider vllm.
 	// +optional
-	// +kubebuilder:default=10
 	// +kubebuilder:validation:Minimum=1
-	// +kubebuilder:validation:Maximum=100
-	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+	PipelineParallel *int32 `json:"pipelineParallel,omitempty"`
 
-	// Metrics contains the specifications for which to use to calculate the desired replica count
+	// Adapters are LoRA adapters of the base model served next to it by the model
+	// server (vllm only). Requests select one by its name as the model. Adapters
+	// are loaded and unloaded while the server runs; only adding the first or
+	// removing the last restarts it.
 	// +optional
-	Metrics []interface{} `json:"metrics,omitempty"`
+	// +listType=map
+	// +listMapKey=name
+	Adapters []LoRAAdapterSpec `json:"adapters,omitempty"`
+
+	// MaxLoadedAdapters is the number of adapters held on the GPUs at once and
+	// served in the same batch; others wait in host memory until swapped in
+	// +optional
+	// +kubebuilder:default=4
+	// +kubebuilder:validation:Minimum=1
+	MaxLoadedAdapters *int32 `json:"maxLoadedAdapters,omitempty"`
+
+	// MaxAdapterRank is the largest LoRA rank among the adapters
+	// +optional
+	// +kubebuilder:validation:Enum=8;16;32;64;128;256
+	MaxAdapterRank *int32 `json:"maxAdapterRank,omitempty"`
 }
 
-// SecurityContextSpec defines security context
-type SecurityContextSpec struct {
-	// RunAsNonRoot ensures the container runs as a non-root user
+// LoRAAdapterSpec is a LoRA adapter served on the base model
+type LoRAAdapterSpec struct {
+	// Name the adapter is requested by, as the model of a request or the
+	// x-agentops-adapter header of the agent
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9_.]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Source of the adapter weights: a Hugging Face repository (org/name), read
+	// with the token of providerConfig.credentialsSecretRef, or an object store
+	// directory (s3://bucket/prefix or gs://bucket/prefix), read with the model
+	// server's workload identity
+	// +kubebuilder:validation:Required
+	Source string `json:"source"`
+}
+
+// Quantization methods
+const (
+	QuantizationAWQ  = "awq"
+	QuantizationGPTQ = "gptq"
+	QuantizationFP8  = "fp8"
+)
+
+// BatchingSpec configures the continuous batching of a self-hosted runtime. Both
+// vllm and tgi batch continuously, adding requests to the running batch at every
+// decoding step; not every setting applies to both.
+type BatchingSpec struct {
+	// MaxBatchSize is the number of sequences decoded together (vllm
+	// --max-num-seqs, tgi --max-batch-size)
 	// +optional
-	// +kubebuilder:default=true
-	RunAsNonRoot *bool `json:"runAsNonRoot,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	MaxBatchSize *int32 `json:"maxBatchSize,omitempty"`
 
-	// ReadOnlyRootFilesystem mounts the container's root filesystem as read-only
+	// MaxBatchTokens caps the tokens of a batch (vllm --max-num-batched-tokens, tgi
+	// --max-batch-total-tokens)
 	// +optional
-	// +kubebuilder:default=true
-	ReadOnlyRootFilesystem *bool `json:"readOnlyRootFilesystem,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	MaxBatchTokens *int32 `json:"maxBatchTokens,omitempty"`
 
-	// RunAsUser is the UID to run the entrypoint of the container process
+	// MaxPrefillTokens caps the prompt tokens prefilled in one step (tgi
+	// --max-batch-prefill-tokens)
 	// +optional
-	// +kubebuilder:default=1000
-	RunAsUser *int64 `json:"runAsUser,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	MaxPrefillTokens *int32 `json:"maxPrefillTokens,omitempty"`
+
+	// MaxWaitingTokens is the number of tokens decoded before waiting requests are
+	// forced into the batch, the latency the batch may add to them (tgi
+	// --max-waiting-tokens)
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxWaitingTokens *int32 `json:"maxWaitingTokens,omitempty"`
+
+	// ChunkedPrefill splits long prompts across steps so they do not stall the
+	// decoding of the batch (vllm --enable-chunked-prefill)
+	// +optional
+	ChunkedPrefill *bool `json:"chunkedPrefill,omitempty"`
+
+	// PrefixCaching reuses the KV cache of prompt prefixes shared across requests
+	// (vllm --enable-prefix-caching)
+	// +optional
+	PrefixCaching *bool `json:"prefixCaching,omitempty"`
 }
 
-// SecretReference references a secret and key
-type SecretReference struct {
-	// Name of the secret
+// SelfHostedServingSpec defines a model server StatefulSet
+type SelfHostedServingSpec struct {
+	// Replicas is the number of model server pods
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Image overrides the runtime image of the provider
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// ModelID overrides the Hugging Face repository derived from spec.model; ignored
+	// when weights are loaded from a volume
+	// +optional
+	ModelID string `json:"modelId,omitempty"`
+
+	// Weights is where the model weights are loaded from; when omitted the runtime
+	// downloads them from the Hugging Face hub on start
+	// +optional
+	Weights *WeightsSource `json:"weights,omitempty"`
+
+	// GPU sizes the accelerators of each server pod; the model is sharded across them
+	// +optional
+	GPU *GPUSpec `json:"gpu,omitempty"`
+
+	// Resources of the server container, in addition to the GPUs
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// SharedMemory sizes the memory-backed /dev/shm used by tensor parallel workers
+	// +optional
+	// +kubebuilder:default="16Gi"
+	SharedMemory *resource.Quantity `json:"sharedMemory,omitempty"`
+
+	// Args are appended to the runtime arguments
+	// +optional
+	Args []string `json:"args,omitempty"`
+}
+
+// WeightsSource defines where model weights are read from; exactly one is set
+type WeightsSource struct {
+	// PersistentVolumeClaim holding the weights, mounted read-only
+	// +optional
+	PersistentVolumeClaim *PVCWeightsSource `json:"persistentVolumeClaim,omitempty"`
+
+	// ObjectStore is synced into a per-pod volume by an init container
+	// +optional
+	ObjectStore *ObjectStoreWeightsSource `json:"objectStore,omitempty"`
+
+	// ModelCache mounts weights pre-fetched by a ModelCache; the server is not
+	// deployed until the cache is ready
+	// +optional
+	ModelCache *ModelCacheWeightsSource `json:"modelCache,omitempty"`
+}
+
+// ModelCacheWeightsSource references a ModelCache in the same namespace
+type ModelCacheWeightsSource struct {
+	// Name of the ModelCache
 	// +kubebuilder:validation:Required
 	Name string `json:"name"`
 
-	// Key in the secret
+	// SubPath is the model directory inside the cache
+	// +optional
+	SubPath string `json:"subPath,omitempty"`
+}
+
+// PVCWeightsSource references weights on an existing claim
+type PVCWeightsSource struct {
+	// ClaimName is a PersistentVolumeClaim in the AgentDeployment's namespace
 	// +kubebuilder:validation:Required
-	Key string `json:"key"`
+	ClaimName string `json:"claimName"`
+
+	// SubPath is the model directory inside the volume
+	// +optional
+	SubPath string `json:"subPath,omitempty"`
 }
 
-// MonitoringSpec defines monitoring configuration
-type MonitoringSpec struct {
-	// Enabled determines if monitoring is enabled
+// ObjectStoreWeightsSource references weights in S3 or GCS
+type ObjectStoreWeightsSource struct {
+	// URI of the model directory, s3://bucket/prefix or gs://bucket/prefix
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^(s3|gs)://`
+	URI string `json:"uri"`
+
+	// CredentialsSecretRef names a Secret exposed to the sync container as environment
+	// variables (e.g. AWS_ACCESS_KEY_ID); omit to use workload identity
 	// +optional
-	// +kubebuilder:default=true
-	Enabled bool `json:"enabled,omitempty"`
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
 
-	// ScrapeInterval is the Prometheus scrape interval
+	// Size of the per-pod weights volume
+	// +kubebuilder:validation:Required
+	Size resource.Quantity `json:"size"`
+
+	// StorageClassName of the per-pod weights volume
 	// +optional
-	// +kubebuilder:default="30s"
-	ScrapeInterval string `json:"scrapeInterval,omitempty"`
+	StorageClassName *string `json:"storageClassName,omitempty"`
 }
 
-// IngressSpec defines ingress configuration
-type IngressSpec struct {
-	// Enabled determines if ingress is enabled
+// GPUSpec defines the accelerators of a model server pod
+type GPUSpec struct {
+	// Count is the number of GPUs per pod; by default as many as the weights and
+	// the attention cache of the model need, or one when the catalog does not know
+	// the model
 	// +optional
-	// +kubebuilder:default=false
-	Enabled bool `json:"enabled,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	Count int32 `json:"count,omitempty"`
 
-	// Host is the hostname for the ingress
+	// Memory of each GPU, used to size the default Count
 	// +optional
-	Host string `json:"host,omitempty"`
+	// +kubebuilder:default="80Gi"
+	Memory *resource.Quantity `json:"memory,omitempty"`
 
-	// TLS enables TLS for the ingress
+	// ResourceName is the extended resource of the GPU device plugin
 	// +optional
-	// +kubebuilder:default=true
-	TLS bool `json:"tls,omitempty"`
+	// +kubebuilder:default="nvidia.com/gpu"
+	ResourceName corev1.ResourceName `json:"resourceName,omitempty"`
+
+	// NodeSelector pins server pods to GPU nodes, e.g. by accelerator product
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 }
 
-// AgentDeploymentStatus defines the observed state of AgentDeployment
-type AgentDeploymentStatus struct {
-	// Conditions represent the latest available observations of an object's state
+// AnthropicConfig configures the Anthropic API
+type AnthropicConfig struct {
+	// Endpoint overrides the API base URL
 	// +optional
-	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
 
-	// Replicas is the most recently observed number of replicas
+// OpenAIConfig configures the OpenAI API or an OpenAI-compatible gateway
+type OpenAIConfig struct {
+	// Endpoint overrides the API base URL
 	// +optional
-	Replicas int32 `json:"replicas,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
 
-	// ReadyReplicas is the number of pods with a Ready condition
+	// Organization is sent as the OpenAI organization ID
 	// +optional
-	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	Organization string `json:"organization,omitempty"`
+}
 
-	// AvailableReplicas is the number of available replicas
+// AzureOpenAIConfig configures an Azure OpenAI resource
+type AzureOpenAIConfig struct {
+	// Endpoint is the resource endpoint, e.g. https://my-resource.openai.azure.com
+	// +kubebuilder:validation:Required
+	Endpoint string `json:"endpoint"`
+
+	// DeploymentName is the Azure deployment serving the model
+	// +kubebuilder:validation:Required
+	DeploymentName string `json:"deploymentName"`
+
+	// APIVersion is the Azure OpenAI API version
 	// +optional
-	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+	// +kubebuilder:default="2024-02-01"
+	APIVersion string `json:"apiVersion,omitempty"`
+}
 
-	// Phase represents the current phase of the agent deployment
+// BedrockConfig configures Amazon Bedrock
+type BedrockConfig struct {
+	// Region is the AWS region hosting the model
+	// +kubebuilder:validation:Required
+	Region string `json:"region"`
+
+	// ModelID overrides the Bedrock model ID derived from spec.model
 	// +optional
-	// +kubebuilder:validation:Enum=Pending;Running;Failed;Scaling
-	Phase string `json:"phase,omitempty"`
+	ModelID string `json:"modelId,omitempty"`
+}
 
-	// ObservedGeneration reflects the generation of the most recently observed AgentDeployment
+// VertexConfig configures Google Vertex AI
+type VertexConfig struct {
+	// Project is the Google Cloud project ID
+	// +kubebuilder:validation:Required
+	Project string `json:"project"`
+
+	// Region is the Vertex AI region, e.g. us-east5
+	// +kubebuilder:validation:Required
+	Region string `json:"region"`
+}
+
+// SelfHostedConfig configures a model runtime served from the agent pod
+type SelfHostedConfig struct {
+	// Image overrides the runtime image of the provider
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// ModelID overrides the model the runtime loads (Hugging Face repository or
+	// Ollama tag) derived from spec.model
+	// +optional
+	ModelID string `json:"modelId,omitempty"`
+
+	// Args are appended to the runtime arguments
+	// +optional
+	Args []string `json:"args,omitempty"`
+}
+
+const (
+	// PriorityTierCritical is for production agents that preempt every other tier
+	PriorityTierCritical = "critical"
+
+	// PriorityTierStandard is for regular agents that preempt batch agents
+	PriorityTierStandard = "standard"
+
+	// PriorityTierBatch is for experimental and batch agents that never preempt
+	PriorityTierBatch = "batch"
+)
+
+// SpreadPolicySpec renders topology spread constraints for the agent pods
+type SpreadPolicySpec struct {
+	// Across lists the topologies the pods are spread over: zone, node and gpu-pool
+	// +kubebuilder:validation:MinItems=1
+	Across []SpreadTopology `json:"across"`
+
+	// MaxSkew is the largest allowed difference in pods between two domains
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	MaxSkew int32 `json:"maxSkew,omitempty"`
+
+	// WhenUnsatisfiable is ScheduleAnyway to prefer an even spread, or DoNotSchedule
+	// to keep pods pending rather than break it
+	// +optional
+	// +kubebuilder:default=ScheduleAnyway
+	// +kubebuilder:validation:Enum=ScheduleAnyway;DoNotSchedule
+	WhenUnsatisfiable string `json:"whenUnsatisfiable,omitempty"`
+
+	// GPUPoolLabel is the node label naming the node pool of a node, for gpu-pool
+	// +optional
+	// +kubebuilder:default="eks.amazonaws.com/nodegroup"
+	GPUPoolLabel string `json:"gpuPoolLabel,omitempty"`
+}
+
+// SpreadTopology is a topology agent pods are spread over
+// +kubebuilder:validation:Enum=zone;node;gpu-pool
+type SpreadTopology string
+
+const (
+	// SpreadZone spreads pods across topology.kubernetes.io/zone
+	SpreadZone SpreadTopology = "zone"
+
+	// SpreadNode spreads pods across kubernetes.io/hostname
+	SpreadNode SpreadTopology = "node"
+
+	// SpreadGPUPool spreads pods across the node pools named by gpuPoolLabel
+	SpreadGPUPool SpreadTopology = "gpu-pool"
+)
+
+// PlacementSpec selects the kind of nodes the agent pods run on
+type PlacementSpec struct {
+	// Spot runs the agent pods on spot or preemptible nodes
+	// +optional
+	Spot *SpotPlacementSpec `json:"spot,omitempty"`
+}
+
+// SpotPlacementSpec renders the node affinity and tolerations of spot nodes. In
+// fallback-to-on-demand mode the replicas are split: SpotPercent of them prefer
+// spot nodes, and the rest run in a <name>-on-demand Deployment that never lands
+// on spot nodes.
+type SpotPlacementSpec struct {
+	// Mode is prefer (spot nodes when there is room), require (spot nodes only) or
+	// fallback-to-on-demand
+	// +optional
+	// +kubebuilder:default=prefer
+	// +kubebuilder:validation:Enum=prefer;require;fallback-to-on-demand
+	Mode string `json:"mode,omitempty"`
+
+	// SpotPercent is the share of replicas preferring spot nodes in
+	// fallback-to-on-demand mode; the on-demand share is rounded up
+	// +optional
+	// +kubebuilder:default=50
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	SpotPercent *int32 `json:"spotPercent,omitempty"`
+
+	// NodeLabel marks the capacity type of a node, e.g. karpenter.sh/capacity-type
+	// or cloud.google.com/gke-spot
+	// +optional
+	// +kubebuilder:default="eks.amazonaws.com/capacityType"
+	NodeLabel string `json:"nodeLabel,omitempty"`
+
+	// SpotValue is the value of NodeLabel on spot nodes, e.g. spot or "true"
+	// +optional
+	// +kubebuilder:default=SPOT
+	SpotValue string `json:"spotValue,omitempty"`
+
+	// Tolerations of the taints of spot nodes; defaults to tolerating any taint
+	// keyed by NodeLabel
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// Spot placement modes
+const (
+	SpotModePrefer   = "prefer"
+	SpotModeRequire  = "require"
+	SpotModeFallback = "fallback-to-on-demand"
+)
+
+const (
+	// CapacityPolicyReport creates every replica and reports missing GPUs
+	CapacityPolicyReport = "report"
+
+	// CapacityPolicyQueue creates the replicas the free GPUs hold and the rest as
+	// GPUs free up
+	CapacityPolicyQueue = "queue"
+
+	// CapacityPolicyReject adds no replicas until the free GPUs hold all of them
+	CapacityPolicyReject = "reject"
+)
+
+// SessionStoreSpec binds the agent to a conversation store. The store is an
+// existing one named by SecretRef or ServiceRef; without either, a redis store is
+// deployed for the agent.
+type SessionStoreSpec struct {
+	// Type is redis, postgres or none
+	// +optional
+	// +kubebuilder:default=none
+	// +kubebuilder:validation:Enum=redis;postgres;none
+	Type string `json:"type,omitempty"`
+
+	// SecretRef names a Secret of the namespace whose url key holds the connection
+	// URL, credentials included
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// ServiceRef names a Service of the namespace in front of the store, exposing
+	// it on port 6379 for redis or 5432 for postgres. The connection URL is built
+	// from it unless SecretRef is set.
+	// +optional
+	ServiceRef *corev1.LocalObjectReference `json:"serviceRef,omitempty"`
+
+	// Image of the deployed redis store
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Storage persists the deployed redis store on a volume of this size; without
+	// it sessions are lost when the store restarts
+	// +optional
+	Storage *resource.Quantity `json:"storage,omitempty"`
+
+	// StorageClassName of the volume of the deployed redis store
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+// Session store types
+const (
+	SessionStoreRedis    = "redis"
+	SessionStorePostgres = "postgres"
+	SessionStoreNone     = "none"
+)
+
+// QueueSpec configures the request queue of an agent. A queue proxy in every agent
+// pod accepts requests into a stream of a broker deployed for the agent and
+// delivers them to the agent, retrying failed ones with exponential backoff until
+// they go to the dead-letter stream.
+type QueueSpec struct {
+	// Enabled turns on the queue
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Backend is the broker holding the streams, redis or nats (JetStream)
+	// +optional
+	// +kubebuilder:default=redis
+	// +kubebuilder:validation:Enum=redis;nats
+	Backend string `json:"backend,omitempty"`
+
+	// Image of the deployed broker
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Storage persists the broker on a volume of this size; without it queued
+	// requests are lost when the broker restarts
+	// +optional
+	Storage *resource.Quantity `json:"storage,omitempty"`
+
+	// StorageClassName of the broker volume
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// MaxRetries is the number of times a failed request is retried before it goes
+	// to the dead-letter stream
+	// +optional
+	// +kubebuilder:default=5
+	// +kubebuilder:validation:Minimum=0
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+
+	// Backoff is the delay before the first retry; it doubles with every retry
+	// +optional
+	// +kubebuilder:default="2s"
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+
+	// MaxBackoff caps the delay between retries
+	// +optional
+	// +kubebuilder:default="5m"
+	MaxBackoff *metav1.Duration `json:"maxBackoff,omitempty"`
+
+	// TargetDepth adds the queue depth to the metrics of spec.autoscaling, scaling
+	// to one replica per TargetDepth queued requests
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TargetDepth *int32 `json:"targetDepth,omitempty"`
+}
+
+// Queue backends
+const (
+	QueueBackendRedis = "redis"
+	QueueBackendNATS  = "nats"
+)
+
+// ResponseCacheSpec configures the response cache of an agent. A cache proxy in
+// every agent pod answers requests matching a cached one from a redis deployed for
+// the agent, and caches the successful non-streamed answers of the others.
+type ResponseCacheSpec struct {
+	// Enabled turns on the cache
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Mode is exact, matching requests with the same model, parameters and
+	// messages, or semantic, also matching requests whose last message embeds
+	// within SimilarityThreshold of a cached one
+	// +optional
+	// +kubebuilder:default=exact
+	// +kubebuilder:validation:Enum=exact;semantic
+	Mode string `json:"mode,omitempty"`
+
+	// TTL is how long an answer stays cached
+	// +optional
+	// +kubebuilder:default="1h"
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// SimilarityThreshold is the cosine similarity, between 0 and 1, above which
+	// semantic mode treats two prompts as the same
+	// +optional
+	// +kubebuilder:default="0.95"
+	// +kubebuilder:validation:Pattern=`^(0(\.[0-9]+)?|1(\.0+)?)$`
+	SimilarityThreshold string `json:"similarityThreshold,omitempty"`
+
+	// EmbedderURL is the text-embeddings endpoint of semantic mode; it defaults to
+	// the embedder of the RAGPipeline of spec.ragRef
+	// +optional
+	EmbedderURL string `json:"embedderURL,omitempty"`
+
+	// Image of the deployed redis; semantic mode needs the vector search of Redis
+	// Stack
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Storage persists the cache on a volume of this size; without it the cache is
+	// emptied when redis restarts
+	// +optional
+	Storage *resource.Quantity `json:"storage,omitempty"`
+
+	// StorageClassName of the cache volume
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+// Response cache modes
+const (
+	ResponseCacheExact    = "exact"
+	ResponseCacheSemantic = "semantic"
+)
+
+// TrafficPolicySpec defines the admission control of the gateway sidecar. Batch
+// requests may only take a share of the agent's concurrency, so interactive
+// requests are not starved by batch jobs.
+type TrafficPolicySpec struct {
+	// Priority is the class of requests that neither set PriorityHeader nor come
+	// from one of Clients
+	// +optional
+	// +kubebuilder:default=interactive
+	// +kubebuilder:validation:Enum=interactive;batch
+	Priority string `json:"priority,omitempty"`
+
+	// PriorityHeader lets callers class a request as interactive or batch
+	// +optional
+	// +kubebuilder:default=x-agentops-priority
+	PriorityHeader string `json:"priorityHeader,omitempty"`
+
+	// MaxConcurrentRequests is the number of interactive requests the agent serves
+	// at once; as many more wait for a free slot and the rest are answered with 503
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrentRequests *int32 `json:"maxConcurrentRequests,omitempty"`
+
+	// BatchShare is the percentage of MaxConcurrentRequests batch requests may take
+	// +optional
+	// +kubebuilder:default=50
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	BatchShare *int32 `json:"batchShare,omitempty"`
+
+	// ClientHeader identifies the client of a request for Clients
+	// +optional
+	// +kubebuilder:default=x-agentops-client
+	ClientHeader string `json:"clientHeader,omitempty"`
+
+	// Clients have a class and a concurrency limit of their own
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Clients []ClientTrafficPolicy `json:"clients,omitempty"`
+}
+
+// ClientTrafficPolicy is the traffic policy of a client of the agent
+type ClientTrafficPolicy struct {
+	// Name is the value of the client header
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Priority is the class of the client's requests; defaults to the agent's
+	// +optional
+	// +kubebuilder:validation:Enum=interactive;batch
+	Priority string `json:"priority,omitempty"`
+
+	// MaxConcurrentRequests is the number of the client's requests served at once
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrentRequests int32 `json:"maxConcurrentRequests"`
+}
+
+// Traffic priorities
+const (
+	TrafficPriorityInteractive = "interactive"
+	TrafficPriorityBatch       = "batch"
+)
+
+// ResilienceSpec configures the provider proxy sidecar of an agent. The agent calls
+// its provider through the sidecar, which retries failed calls and ejects the
+// provider after consecutive failures; while it is ejected calls are answered with
+// 503 and the agent runtime uses the fallback instead.
+type ResilienceSpec struct {
+	// Timeout bounds a provider call including its retries; 0s disables it, as
+	// streamed responses may take minutes
+	// +optional
+	// +kubebuilder:default="10m"
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// +optional
+	Retries *RetryPolicySpec `json:"retries,omitempty"`
+
+	// +optional
+	CircuitBreaker *CircuitBreakerSpec `json:"circuitBreaker,omitempty"`
+
+	// Fallback serves the agent's calls while the circuit of the provider is open
+	// +optional
+	Fallback *FallbackSpec `json:"fallback,omitempty"`
+}
+
+// RetryPolicySpec defines which failed provider calls are retried
+type RetryPolicySpec struct {
+	// Attempts is the number of retries of a failed call
+	// +optional
+	// +kubebuilder:default=2
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	Attempts *int32 `json:"attempts,omitempty"`
+
+	// PerTryTimeout bounds each attempt; by default an attempt may take the whole
+	// timeout
+	// +optional
+	PerTryTimeout *metav1.Duration `json:"perTryTimeout,omitempty"`
+
+	// RetryOn are the Envoy retry conditions, e.g. 5xx, reset, connect-failure
+	// +optional
+	// +kubebuilder:default={"5xx","reset","connect-failure"}
+	RetryOn []string `json:"retryOn,omitempty"`
+
+	// StatusCodes are retried in addition to RetryOn; the defaults are the rate
+	// limited and overloaded answers of the hosted providers
+	// +optional
+	// +kubebuilder:default={429,529}
+	StatusCodes []int32 `json:"statusCodes,omitempty"`
+}
+
+// CircuitBreakerSpec defines when the provider is considered down
+type CircuitBreakerSpec struct {
+	// ConsecutiveFailures is the number of 5xx answers in a row opening the circuit
+	// +optional
+	// +kubebuilder:default=5
+	// +kubebuilder:validation:Minimum=1
+	ConsecutiveFailures *int32 `json:"consecutiveFailures,omitempty"`
+
+	// Interval between the checks of the failure counts
+	// +optional
+	// +kubebuilder:default="10s"
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// OpenDuration is how long the circuit stays open before calls are tried again;
+	// it grows with every consecutive opening
+	// +optional
+	// +kubebuilder:default="30s"
+	OpenDuration *metav1.Duration `json:"openDuration,omitempty"`
+
+	// MaxPendingRequests caps the calls waiting for a connection; calls beyond it
+	// fail fast
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxPendingRequests *int32 `json:"maxPendingRequests,omitempty"`
+
+	// MaxRequests caps the calls in flight to the provider
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxRequests *int32 `json:"maxRequests,omitempty"`
+}
+
+// FallbackSpec defines what serves the agent while its provider is down
+type FallbackSpec struct {
+	// Mode is provider, calling the secondary provider below through the sidecar,
+	// or cached, answering from the runtime's cache of recent responses (kept in
+	// the session store when spec.sessionStore is set)
+	// +optional
+	// +kubebuilder:default=provider
+	// +kubebuilder:validation:Enum=provider;cached
+	Mode string `json:"mode,omitempty"`
+
+	// Provider is the secondary provider: anthropic, openai or azure-openai
+	// +optional
+	// +kubebuilder:validation:Enum=anthropic;openai;azure-openai
+	Provider string `json:"provider,omitempty"`
+
+	// Model is the model ID at the secondary provider; for azure-openai the
+	// deployment name. Defaults to the agent's model at that provider.
+	// +optional
+	Model string `json:"model,omitempty"`
+
+	// Endpoint overrides the API base URL of the secondary provider; required for
+	// azure-openai
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// CredentialsSecretRef names a Secret with the "api-key" of the secondary
+	// provider; defaults to providerConfig.credentialsSecretRef
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// Fallback modes
+const (
+	FallbackModeProvider = "provider"
+	FallbackModeCached   = "cached"
+)
+
+// ScaleToZeroSpec defines idle scale-down
+type ScaleToZeroSpec struct {
+	// Enabled turns on scale-to-zero
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// IdleTimeout is how long the agent must receive no requests before it is scaled
+	// to zero, e.g. "15m"
+	// +optional
+	// +kubebuilder:default="15m"
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+}
+
+// HibernationSpec defines idle scale-down without the activator
+type HibernationSpec struct {
+	// IdleAfter is how long the agent must receive no requests before it hibernates,
+	// e.g. "30m"
+	IdleAfter metav1.Duration `json:"idleAfter"`
+
+	// Replicas is the replica count while hibernated. With at least one replica the
+	// agent wakes up on its own when traffic resumes; with zero it stays down until
+	// it is activated through the agentops.io/activated-at annotation.
+	// +optional
+	// +kubebuilder:default=0
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+}
+
+// TerminationSpec defines how a deleted agent is taken out of service
+type TerminationSpec struct {
+	// DrainRequests holds deletion, after the agent is removed from its routes, until
+	// it has served no requests for a minute
+	// +optional
+	DrainRequests bool `json:"drainRequests,omitempty"`
+
+	// GracePeriod bounds how long deletion waits for routes, draining and load
+	// balancer cleanup before the AgentDeployment is removed anyway, e.g. "5m"
+	// +optional
+	// +kubebuilder:default="5m"
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+}
+
+// ProgressiveDeliverySpec defines the progressive delivery of new agent versions by
+// Argo Rollouts or Flagger
+type ProgressiveDeliverySpec struct {
+	// Engine is ArgoRollouts (a Rollout replaces the Deployment; steps shift the
+	// share of replicas running the new version) or Flagger (a Flagger Canary drives
+	// the Deployment; steps shift mesh traffic)
+	// +optional
+	// +kubebuilder:default=ArgoRollouts
+	// +kubebuilder:validation:Enum=ArgoRollouts;Flagger
+	Engine string `json:"engine,omitempty"`
+
+	// Strategy is Canary (move replicas over step by step) or BlueGreen (bring up
+	// the new version next to the old one and switch the Service at once)
+	// +optional
+	// +kubebuilder:default=Canary
+	// +kubebuilder:validation:Enum=Canary;BlueGreen
+	Strategy string `json:"strategy,omitempty"`
+
+	// Steps of a canary rollout; defaults to 20% and 50% of the replicas for 5
+	// minutes each before the rest
+	// +optional
+	Steps []CanaryStep `json:"steps,omitempty"`
+
+	// AutoPromotionSeconds promotes a blue-green rollout this long after the new
+	// version is ready; unset waits for kubectl argo rollouts promote
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	AutoPromotionSeconds *int32 `json:"autoPromotionSeconds,omitempty"`
+
+	// Analysis aborts the rollout when the new version misses its latency or
+	// error-rate objectives
+	// +optional
+	Analysis *DeliveryAnalysisSpec `json:"analysis,omitempty"`
+
+	// Webhooks are called by Flagger during the rollout, e.g. an evaluation service
+	// scoring the new version for hallucinations; a non-2xx answer fails the check
+	// +optional
+	Webhooks []DeliveryWebhook `json:"webhooks,omitempty"`
+}
+
+// DeliveryWebhook is a Flagger webhook
+type DeliveryWebhook struct {
+	// Name identifies the webhook in Flagger's events
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Type is when Flagger calls the webhook: before the rollout, at every analysis
+	// interval, before promotion or after the rollout
+	// +optional
+	// +kubebuilder:default=rollout
+	// +kubebuilder:validation:Enum=pre-rollout;rollout;confirm-promotion;post-rollout
+	Type string `json:"type,omitempty"`
+
+	// URL is called with a POST of the canary name, namespace and metadata
+	// +kubebuilder:validation:Required
+	URL string `json:"url"`
+
+	// Timeout of a call
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Metadata is passed to the webhook
+	// +optional
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// CanaryStep is a step of a canary rollout
+type CanaryStep struct {
+	// Weight is the percentage of replicas running the new version
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight"`
+
+	// Pause is how long Argo Rollouts waits before the next step; unset waits for
+	// kubectl argo rollouts promote. Flagger moves on every analysis interval.
+	// +optional
+	Pause *metav1.Duration `json:"pause,omitempty"`
+}
+
+// DeliveryAnalysisSpec defines the objectives of a new agent version, measured in
+// Prometheus while the rollout runs
+type DeliveryAnalysisSpec struct {
+	// MaxLatency is the highest p95 request latency of the new version, e.g. 2s
+	// +optional
+	MaxLatency *metav1.Duration `json:"maxLatency,omitempty"`
+
+	// MaxTokenLatency is the highest request time per generated token of the new
+	// version, e.g. 50ms
+	// +optional
+	MaxTokenLatency *metav1.Duration `json:"maxTokenLatency,omitempty"`
+
+	// MaxErrorPercent is the highest share of 5xx responses of the new version, in percent
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=5
+	MaxErrorPercent int32 `json:"maxErrorPercent,omitempty"`
+
+	// Interval between measurements
+	// +optional
+	// +kubebuilder:default="1m"
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// FailureLimit is the number of missed measurements that aborts the rollout
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=2
+	FailureLimit *int32 `json:"failureLimit,omitempty"`
+}
+
+// PeerReference names an AgentDeployment an agent may call
+type PeerReference struct {
+	// Name of the AgentDeployment
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Namespace of the AgentDeployment; defaults to the agent's
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// DependencySpec is a downstream dependency of an agent; exactly one of service,
+// url, agentDeployment and ragPipeline is set
+// +kubebuilder:validation:XValidation:rule="[has(self.service), has(self.url), has(self.agentDeployment), has(self.ragPipeline)].filter(x, x).size() == 1",message="exactly one of service, url, agentDeployment and ragPipeline is required"
+type DependencySpec struct {
+	// Name identifies the dependency in conditions and events
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Service is reachable once it has ready endpoints, or when it is an ExternalName
+	// +optional
+	Service *DependencyServiceReference `json:"service,omitempty"`
+
+	// URL is reachable once a GET from the controller answers with a 2xx status
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// AgentDeployment names an AgentDeployment of the namespace that must be Ready
+	// +optional
+	AgentDeployment string `json:"agentDeployment,omitempty"`
+
+	// RAGPipeline names a RAGPipeline of the namespace, e.g. the vector store the
+	// agent retrieves from, that must be Ready
+	// +optional
+	RAGPipeline string `json:"ragPipeline,omitempty"`
+
+	// Timeout of a URL check
+	// +optional
+	// +kubebuilder:default="5s"
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// DependencyServiceReference names a Service
+type DependencyServiceReference struct {
+	// Name of the Service
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Namespace of the Service; defaults to the agent's
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// CloudIdentitySpec selects the cloud identity of the agent pods. The controller
+// generates a ServiceAccount named after the agent, annotated for the cloud's
+// workload identity, and runs the pods under it.
+// +kubebuilder:validation:XValidation:rule="[has(self.aws), has(self.gcp), has(self.azure)].filter(x, x).size() == 1",message="exactly one of aws, gcp and azure is required"
+type CloudIdentitySpec struct {
+	// AWS assumes an IAM role through IAM Roles for Service Accounts (IRSA)
+	// +optional
+	AWS *AWSIdentity `json:"aws,omitempty"`
+
+	// GCP impersonates a Google service account through GKE Workload Identity
+	// +optional
+	GCP *GCPIdentity `json:"gcp,omitempty"`
+
+	// Azure federates with a Microsoft Entra application or managed identity through
+	// Azure Workload Identity
+	// +optional
+	Azure *AzureIdentity `json:"azure,omitempty"`
+}
+
+// AWSIdentity is an IAM role assumed with IRSA
+type AWSIdentity struct {
+	// RoleARN is the IAM role, e.g. arn:aws:iam::123456789012:role/research-agent;
+	// its trust policy must allow the agent's ServiceAccount
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^arn:aws[a-z-]*:iam::[0-9]{12}:role/.+`
+	RoleARN string `json:"roleARN"`
+}
+
+// GCPIdentity is a Google service account impersonated with GKE Workload Identity
+type GCPIdentity struct {
+	// ServiceAccount is the email of the Google service account, which must grant
+	// roles/iam.workloadIdentityUser to the agent's ServiceAccount
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[^@]+@[^@]+\.iam\.gserviceaccount\.com$`
+	ServiceAccount string `json:"serviceAccount"`
+}
+
+// AzureIdentity is an Entra application or user-assigned managed identity with a
+// federated credential for the agent's ServiceAccount
+type AzureIdentity struct {
+	// ClientID of the application or managed identity
+	// +kubebuilder:validation:Required
+	ClientID string `json:"clientID"`
+
+	// TenantID overrides the tenant configured in the Azure Workload Identity webhook
+	// +optional
+	TenantID string `json:"tenantID,omitempty"`
+}
+
+// PreflightSpec configures the checks run before the agent's workload is created.
+// Referenced Secrets, the pullability of the images and GPU nodes are always
+// checked.
+type PreflightSpec struct {
+	// ProviderAuth also calls the hosted provider with the agent's API key, and
+	// checks that the key is accepted and the model is served to it
+	// +optional
+	ProviderAuth bool `json:"providerAuth,omitempty"`
+}
+
+// Peer authentication modes
+const (
+	PeerAuthMTLS  = "mtls"
+	PeerAuthToken = "token"
+)
+
+// MeshSpec defines how the agent joins a service mesh. Traffic policies, canary
+// splitting and mTLS enforcement are rendered as Istio resources; Linkerd only gets
+// sidecar injection since it encrypts traffic between meshed pods by default.
+type MeshSpec struct {
+	// Provider is the service mesh: istio or linkerd
+	// +kubebuilder:validation:Enum=istio;linkerd
+	Provider string `json:"provider"`
+
+	// InjectSidecar annotates agent pods for sidecar injection
+	// +optional
+	// +kubebuilder:default=true
+	InjectSidecar *bool `json:"injectSidecar,omitempty"`
+
+	// MTLS is the mode of a PeerAuthentication for the agent pods: STRICT accepts
+	// only mutual TLS, PERMISSIVE also plain text (istio)
+	// +optional
+	// +kubebuilder:validation:Enum=STRICT;PERMISSIVE
+	MTLS string `json:"mtls,omitempty"`
+
+	// Timeout bounds each request to the agent through the mesh, e.g. "60s" (istio)
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Retries retries failed requests to the agent (istio)
+	// +optional
+	Retries *MeshRetrySpec `json:"retries,omitempty"`
+
+	// Canary sends a share of the agent's in-mesh traffic to another AgentDeployment (istio)
+	// +optional
+	Canary *MeshCanarySpec `json:"canary,omitempty"`
+}
+
+// MeshRetrySpec defines retries of failed requests
+type MeshRetrySpec struct {
+	// Attempts is the number of retries per request
+	// +kubebuilder:validation:Minimum=1
+	Attempts int32 `json:"attempts"`
+
+	// PerTryTimeout bounds each attempt, e.g. "20s"
+	// +optional
+	PerTryTimeout *metav1.Duration `json:"perTryTimeout,omitempty"`
+
+	// RetryOn lists the Envoy retry conditions
+	// +optional
+	// +kubebuilder:default="5xx,reset,connect-failure"
+	RetryOn string `json:"retryOn,omitempty"`
+}
+
+// MeshCanarySpec splits traffic between the agent and a canary AgentDeployment
+type MeshCanarySpec struct {
+	// AgentDeployment is the canary in the same namespace
+	// +kubebuilder:validation:Required
+	AgentDeployment string `json:"agentDeployment"`
+
+	// Weight is the percentage of requests sent to the canary
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight"`
+}
+
+// ShadowSpec mirrors requests to a candidate AgentDeployment. Clients only ever get
+// the agent's own responses.
+type ShadowSpec struct {
+	// AgentDeployment is the candidate in the same namespace
+	// +kubebuilder:validation:Required
+	AgentDeployment string `json:"agentDeployment"`
+
+	// Percent of the requests mirrored (gateway and mesh; Ingresses mirror all)
+	// +optional
+	// +kubebuilder:default=100
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Percent *int32 `json:"percent,omitempty"`
+}
+
+const (
+	// MeshProviderIstio joins the agent to an Istio mesh
+	MeshProviderIstio = "istio"
+
+	// MeshProviderLinkerd joins the agent to a Linkerd mesh
+	MeshProviderLinkerd = "linkerd"
+)
+
+const (
+	// IngressClassIngress exposes the agent through Ingresses
+	IngressClassIngress = "ingress"
+
+	// IngressClassGateway exposes the agent through a Gateway API HTTPRoute
+	IngressClassGateway = "gateway"
+)
+
+const (
+	// ImageUpdatePolicyPinned resolves the image tag once, when the image changes
+	ImageUpdatePolicyPinned = "pinned"
+
+	// ImageUpdatePolicyTrackTag resolves the image tag on every reconcile
+	ImageUpdatePolicyTrackTag = "track-tag"
+)
+
+const (
+	// DeliveryEngineArgoRollouts replaces the Deployment with an Argo Rollouts Rollout
+	DeliveryEngineArgoRollouts = "ArgoRollouts"
+
+	// DeliveryEngineFlagger drives the Deployment with a Flagger Canary
+	DeliveryEngineFlagger = "Flagger"
+)
+
+const (
+	// DeliveryStrategyCanary moves replicas to the new version step by step
+	DeliveryStrategyCanary = "Canary"
+
+	// DeliveryStrategyBlueGreen switches to the new version at once
+	DeliveryStrategyBlueGreen = "BlueGreen"
+)
+
+// AutoscalingSpec defines autoscaling configuration
+// +kubebuilder:validation:XValidation:rule="!has(self.minReplicas) || !has(self.maxReplicas) || self.minReplicas <= self.maxReplicas",message="minReplicas must not exceed maxReplicas"
+type AutoscalingSpec struct {
+	// Enabled determines if autoscaling is enabled
+	// +optional
+	// +kubebuilder:default=true
+	Enabled bool `json:"enabled,omitempty"`
+
+	// MinReplicas is the minimum number of replicas
+	// +optional
+	// +kubebuilder:default=2
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the maximum number of replicas
+	// +optional
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+
+	// Metrics contains the specifications for which to use to calculate the desired replica count
+	// +optional
+	Metrics []interface{} `json:"metrics,omitempty"`
+
+	// Vertical right-sizes the agent container's requests with a VerticalPodAutoscaler
+	// +optional
+	Vertical *VerticalAutoscalingSpec `json:"vertical,omitempty"`
+
+	// GPU scales agent pods serving the model on GPUs on the DCGM exporter metrics of
+	// their GPUs instead of CPU
+	// +optional
+	GPU *GPUAutoscalingSpec `json:"gpu,omitempty"`
+}
+
+// GPUAutoscalingSpec defines GPU utilization targets, averaged across the GPUs of the
+// agent pods; the HPA scales on whichever needs the most replicas
+type GPUAutoscalingSpec struct {
+	// TargetUtilization is the percent of time the GPUs should be busy
+	// (DCGM_FI_DEV_GPU_UTIL)
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	TargetUtilization *int32 `json:"targetUtilization,omitempty"`
+
+	// TargetMemoryUtilization is the percent of GPU memory that should be in use
+	// (DCGM_FI_DEV_FB_USED of DCGM_FI_DEV_FB_USED plus DCGM_FI_DEV_FB_FREE)
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	TargetMemoryUtilization *int32 `json:"targetMemoryUtilization,omitempty"`
+}
+
+// VerticalAutoscalingSpec defines right-sizing of the agent container
+type VerticalAutoscalingSpec struct {
+	// Enabled creates a VerticalPodAutoscaler for the agent
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Mode is Off to only publish recommendations in status.recommendedResources,
+	// Initial to apply them when pods are created, or Auto to also evict running pods
+	// +optional
+	// +kubebuilder:default=Off
+	// +kubebuilder:validation:Enum=Off;Initial;Auto
+	Mode string `json:"mode,omitempty"`
+
+	// ControlledResources are the resources right-sized; defaults to memory while
+	// horizontal autoscaling is enabled, so that both do not act on CPU, and to cpu
+	// and memory otherwise
+	// +optional
+	ControlledResources []corev1.ResourceName `json:"controlledResources,omitempty"`
+
+	// MinAllowed is the lower bound of the recommended requests
+	// +optional
+	MinAllowed corev1.ResourceList `json:"minAllowed,omitempty"`
+
+	// MaxAllowed is the upper bound of the recommended requests
+	// +optional
+	MaxAllowed corev1.ResourceList `json:"maxAllowed,omitempty"`
+}
+
+const (
+	// VerticalModeOff only records recommendations
+	VerticalModeOff = "Off"
+
+	// VerticalModeInitial applies recommendations to newly created pods
+	VerticalModeInitial = "Initial"
+
+	// VerticalModeAuto applies recommendations by evicting running pods
+	VerticalModeAuto = "Auto"
+)
+
+// ComponentResources defines the resources of the agent, its model server and
+// their sidecars
+type ComponentResources struct {
+	// Agent is the agent container
+	// +optional
+	Agent *corev1.ResourceRequirements `json:"agent,omitempty"`
+
+	// Server is the model server container of serving.selfHosted; GPUs are still
+	// added from serving.selfHosted.gpu
+	// +optional
+	Server *corev1.ResourceRequirements `json:"server,omitempty"`
+
+	// Sidecars replace the default resources of sidecars by container name: audit,
+	// otel-collector, ratelimit, cache, queue, provider-proxy and tool-<ToolServer> in
+	// the agent pods, adapter-sync in the model server pods
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Sidecars []SidecarResources `json:"sidecars,omitempty"`
+}
+
+// SidecarResources defines the resources of one sidecar
+type SidecarResources struct {
+	// Name of the sidecar container
+	Name string `json:"name"`
+
+	// Resources of the container
+	Resources corev1.ResourceRequirements `json:"resources"`
+}
+
+// EphemeralStorageSpec defines local disk sizing for the agent container
+type EphemeralStorageSpec struct {
+	// Request is the ephemeral-storage request
+	// +optional
+	Request *resource.Quantity `json:"request,omitempty"`
+
+	// Limit is the ephemeral-storage limit
+	// +optional
+	Limit *resource.Quantity `json:"limit,omitempty"`
+
+	// ScratchPath is where the size-limited scratch volume is mounted
+	// +optional
+	// +kubebuilder:default="/tmp"
+	ScratchPath string `json:"scratchPath,omitempty"`
+
+	// ProtectFromEviction marks pods as not safe to evict by the cluster autoscaler,
+	// so large local caches are not thrown away during node scale-down
+	// +optional
+	ProtectFromEviction bool `json:"protectFromEviction,omitempty"`
+}
+
+// SecurityContextSpec defines security context
+type SecurityContextSpec struct {
+	// RunAsNonRoot ensures the container runs as a non-root user
+	// +optional
+	// +kubebuilder:default=true
+	RunAsNonRoot *bool `json:"runAsNonRoot,omitempty"`
+
+	// ReadOnlyRootFilesystem mounts the container's root filesystem as read-only
+	// +optional
+	// +kubebuilder:default=true
+	ReadOnlyRootFilesystem *bool `json:"readOnlyRootFilesystem,omitempty"`
+
+	// RunAsUser is the UID to run the entrypoint of the container process
+	// +optional
+	// +kubebuilder:default=1000
+	RunAsUser *int64 `json:"runAsUser,omitempty"`
+}
+
+// SecretReference references a secret and key
+// +kubebuilder:validation:XValidation:rule="!has(self.mountPath) || (has(self.as) && self.as == 'file')",message="mountPath requires as: file"
+// +kubebuilder:validation:XValidation:rule="!has(self.provider) || self.provider != 'vault' || has(self.vault)",message="provider vault requires vault"
+// +kubebuilder:validation:XValidation:rule="!has(self.provider) || self.provider != 'external' || has(self.remoteRef)",message="provider external requires remoteRef"
+type SecretReference struct {
+	// Name of the secret
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Key in the secret
+	// +kubebuilder:validation:Required
+	Key string `json:"key"`
+
+	// As delivers the value in the environment variable named after the key (env) or
+	// as a read-only file (file), whose path is in <KEY>_FILE; Vault secrets are
+	// always files
+	// +optional
+	// +kubebuilder:default=env
+	// +kubebuilder:validation:Enum=env;file
+	As string `json:"as,omitempty"`
+
+	// MountPath is the absolute path of the file when as is file; defaults to
+	// /var/run/secrets/agentops/secrets/<name>/<key>. The file is mounted on its own,
+	// leaving the rest of its directory in place, and is not updated when the Secret
+	// changes.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^/.*[^/]$`
+	MountPath string `json:"mountPath,omitempty"`
+
+	// Provider selects where the secret value comes from
+	// +optional
+	// +kubebuilder:default=native
+	// +kubebuilder:validation:Enum=native;vault;external;broker
+	Provider string `json:"provider,omitempty"`
+
+	// Vault configures the Vault Agent injector when provider is vault
+	// +optional
+	Vault *VaultSecretSource `json:"vault,omitempty"`
+
+	// RemoteRef locates the value in the external secret store when provider is external
+	// +optional
+	RemoteRef *ExternalSecretRemoteRef `json:"remoteRef,omitempty"`
+
+	// RotationPeriod is how often a key issued by the key broker is replaced, e.g.
+	// 720h; without it the key is kept until the secret is removed (provider broker)
+	// +optional
+	RotationPeriod *metav1.Duration `json:"rotationPeriod,omitempty"`
+}
+
+// VaultSecretSource defines where a secret lives in HashiCorp Vault
+type VaultSecretSource struct {
+	// Path is the Vault secret path (e.g. secret/data/llm/anthropic)
+	// +kubebuilder:validation:Required
+	Path string `json:"path"`
+
+	// Field is the field within the Vault secret to render
+	// +optional
+	// +kubebuilder:default=value
+	Field string `json:"field,omitempty"`
+
+	// Role is the Vault Kubernetes auth role used by the injector
+	// +kubebuilder:validation:Required
+	Role string `json:"role"`
+}
+
+const (
+	// SecretProviderNative reads the value from a Kubernetes Secret
+	SecretProviderNative = "native"
+
+	// SecretProviderVault renders the value with the Vault Agent injector
+	SecretProviderVault = "vault"
+
+	// SecretProviderExternal syncs the value with the External Secrets Operator
+	SecretProviderExternal = "external"
+
+	// SecretProviderBroker issues a virtual API key for the agent from the key broker
+	SecretProviderBroker = "broker"
+)
+
+const (
+	// SecretAsEnv delivers the secret value in an environment variable
+	SecretAsEnv = "env"
+
+	// SecretAsFile mounts the secret value as a read-only file
+	SecretAsFile = "file"
+)
+
+// SecretStoreReference references an External Secrets Operator SecretStore
+type SecretStoreReference struct {
+	// Name of the SecretStore or ClusterSecretStore
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Kind of the store
+	// +optional
+	// +kubebuilder:default=SecretStore
+	// +kubebuilder:validation:Enum=SecretStore;ClusterSecretStore
+	Kind string `json:"kind,omitempty"`
+
+	// RefreshInterval is how often the synced Secret is refreshed
+	// +optional
+	// +kubebuilder:default="1h"
+	RefreshInterval string `json:"refreshInterval,omitempty"`
+}
+
+// ExternalSecretRemoteRef locates a value in an external secret manager
+type ExternalSecretRemoteRef struct {
+	// Key is the name of the secret in the provider (e.g. AWS Secrets Manager secret name)
+	// +kubebuilder:validation:Required
+	Key string `json:"key"`
+
+	// Property selects a JSON property of the remote secret
+	// +optional
+	Property string `json:"property,omitempty"`
+}
+
+// MonitoringSpec defines monitoring configuration
+type MonitoringSpec struct {
+	// Enabled determines if monitoring is enabled
+	// +optional
+	// +kubebuilder:default=true
+	Enabled bool `json:"enabled,omitempty"`
+
+	// ScrapeInterval is the Prometheus scrape interval
+	// +optional
+	// +kubebuilder:default="30s"
+	ScrapeInterval string `json:"scrapeInterval,omitempty"`
+
+	// Alerts are rendered into a PrometheusRule owned by the AgentDeployment
+	// +optional
+	Alerts *AlertsSpec `json:"alerts,omitempty"`
+}
+
+// AlertsSpec defines the alerting rules generated for an agent
+type AlertsSpec struct {
+	// Latency fires when a request latency percentile exceeds its objective
+	// +optional
+	Latency *LatencyAlert `json:"latency,omitempty"`
+
+	// ErrorRate fires when the share of 5xx responses exceeds a threshold
+	// +optional
+	ErrorRate *ErrorRateAlert `json:"errorRate,omitempty"`
+
+	// CrashLoop fires when agent containers restart repeatedly
+	// +optional
+	CrashLoop *CrashLoopAlert `json:"crashLoop,omitempty"`
+
+	// Severity is the severity label of the generated alerts
+	// +optional
+	// +kubebuilder:validation:Enum=info;warning;critical
+	// +kubebuilder:default=warning
+	Severity string `json:"severity,omitempty"`
+
+	// Labels are added to every generated alert, e.g. to route them in Alertmanager
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// LatencyAlert defines a latency SLO
+type LatencyAlert struct {
+	// Percentile of the request latency distribution the objective applies to
+	// +optional
+	// +kubebuilder:validation:Enum=50;90;95;99
+	// +kubebuilder:default=95
+	Percentile int32 `json:"percentile,omitempty"`
+
+	// Threshold is the latency objective, e.g. 2s
+	// +kubebuilder:validation:Required
+	Threshold metav1.Duration `json:"threshold"`
+
+	// For is how long the objective must be missed before the alert fires
+	// +optional
+	// +kubebuilder:default="5m"
+	For *metav1.Duration `json:"for,omitempty"`
+}
+
+// ErrorRateAlert defines an error-rate threshold
+type ErrorRateAlert struct {
+	// ThresholdPercent is the share of 5xx responses, in percent, that fires the alert
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=5
+	ThresholdPercent int32 `json:"thresholdPercent,omitempty"`
+
+	// For is how long the threshold must be exceeded before the alert fires
+	// +optional
+	// +kubebuilder:default="5m"
+	For *metav1.Duration `json:"for,omitempty"`
+}
+
+// CrashLoopAlert defines crash-loop detection
+type CrashLoopAlert struct {
+	// Enabled turns crash-loop detection on
+	Enabled bool `json:"enabled"`
+
+	// Restarts within Window that fire the alert
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	Restarts int32 `json:"restarts,omitempty"`
+
+	// Window restarts are counted over
+	// +optional
+	// +kubebuilder:default="10m"
+	Window *metav1.Duration `json:"window,omitempty"`
+}
+
+// TelemetrySpec defines OpenTelemetry export from agent pods
+type TelemetrySpec struct {
+	// Endpoint is the OTLP endpoint traces are sent to, e.g.
+	// http://otel-collector.observability:4317
+	// +kubebuilder:validation:Required
+	Endpoint string `json:"endpoint"`
+
+	// Protocol is the OTLP transport to the endpoint
+	// +optional
+	// +kubebuilder:default=grpc
+	// +kubebuilder:validation:Enum=grpc;http/protobuf
+	Protocol string `json:"protocol,omitempty"`
+
+	// SamplingPercent is the share of new traces recorded; traces started upstream
+	// follow the caller's sampling decision
+	// +optional
+	// +kubebuilder:default=100
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	SamplingPercent *int32 `json:"samplingPercent,omitempty"`
+
+	// ResourceAttributes are added to every span of the agent
+	// +optional
+	ResourceAttributes map[string]string `json:"resourceAttributes,omitempty"`
+
+	// CollectorSidecar runs an OpenTelemetry Collector next to the agent, which
+	// batches spans and forwards them to Endpoint
+	// +optional
+	CollectorSidecar *CollectorSidecarSpec `json:"collectorSidecar,omitempty"`
+}
+
+// CollectorSidecarSpec defines the collector sidecar
+type CollectorSidecarSpec struct {
+	// Enabled injects the sidecar
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Image overrides the collector image
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// AuditSpec defines the audit sidecar. The agent runtime posts a JSON record of
+// every exchange to it at AGENTOPS_AUDIT_ENDPOINT.
+type AuditSpec struct {
+	// Sink receives the audit records
+	// +kubebuilder:validation:Required
+	Sink AuditSink `json:"sink"`
+
+	// RedactPII masks email addresses, phone numbers and credit card and US social
+	// security numbers before records leave the pod
+	// +optional
+	// +kubebuilder:default=true
+	RedactPII *bool `json:"redactPII,omitempty"`
+
+	// Redactions are further patterns masked before records leave the pod
+	// +optional
+	Redactions []AuditRedaction `json:"redactions,omitempty"`
+
+	// Image overrides the audit sidecar (Vector) image
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// AuditRedaction masks the matches of a regular expression in audit records
+type AuditRedaction struct {
+	// Name identifies the rule
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Pattern is the regular expression masked, e.g. `ACC-\d{8}`
+	// +kubebuilder:validation:Required
+	Pattern string `json:"pattern"`
+
+	// Replacement is written in place of each match
+	// +optional
+	// +kubebuilder:default="[REDACTED]"
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// AuditSink is where audit records are shipped to
+// +kubebuilder:validation:XValidation:rule="self.type != 'S3' || has(self.s3)",message="s3 is required for the S3 sink"
+// +kubebuilder:validation:XValidation:rule="self.type != 'Loki' || has(self.loki)",message="loki is required for the Loki sink"
+// +kubebuilder:validation:XValidation:rule="self.type != 'Kafka' || has(self.kafka)",message="kafka is required for the Kafka sink"
+type AuditSink struct {
+	// Type of the sink
+	// +kubebuilder:validation:Enum=S3;Loki;Kafka
+	Type string `json:"type"`
+
+	// S3 writes gzipped JSON lines to a bucket
+	// +optional
+	S3 *AuditS3Sink `json:"s3,omitempty"`
+
+	// Loki pushes records as log lines
+	// +optional
+	Loki *AuditLokiSink `json:"loki,omitempty"`
+
+	// Kafka produces records to a topic
+	// +optional
+	Kafka *AuditKafkaSink `json:"kafka,omitempty"`
+
+	// CredentialsSecretRef names a Secret whose keys become the sidecar's environment,
+	// e.g. AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY for S3
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// AuditS3Sink defines an S3 bucket receiving audit records
+type AuditS3Sink struct {
+	// Bucket name
+	// +kubebuilder:validation:Required
+	Bucket string `json:"bucket"`
+
+	// Region of the bucket
+	// +kubebuilder:validation:Required
+	Region string `json:"region"`
+
+	// Prefix of the object keys; records land under <prefix><namespace>/<agent>/date=<day>/
+	// +optional
+	// +kubebuilder:default="audit/"
+	Prefix string `json:"prefix,omitempty"`
+
+	// Endpoint overrides the S3 endpoint, e.g. for MinIO
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// AuditLokiSink defines a Loki instance receiving audit records
+type AuditLokiSink struct {
+	// URL of Loki, e.g. http://loki.monitoring:3100
+	// +kubebuilder:validation:Required
+	URL string `json:"url"`
+
+	// TenantID is sent as X-Scope-OrgID
+	// +optional
+	TenantID string `json:"tenantID,omitempty"`
+
+	// Labels are added to the agent, namespace and model labels of the stream
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// AuditKafkaSink defines a Kafka topic receiving audit records
+type AuditKafkaSink struct {
+	// Brokers to bootstrap from, as host:port
+	// +kubebuilder:validation:MinItems=1
+	Brokers []string `json:"brokers"`
+
+	// Topic the records are produced to, keyed by agent
+	// +kubebuilder:validation:Required
+	Topic string `json:"topic"`
+}
+
+const (
+	// AuditSinkS3 ships audit records to an S3 bucket
+	AuditSinkS3 = "S3"
+
+	// AuditSinkLoki ships audit records to Loki
+	AuditSinkLoki = "Loki"
+
+	// AuditSinkKafka ships audit records to a Kafka topic
+	AuditSinkKafka = "Kafka"
+)
+
+// AgentPort is a port served by the agent container
+type AgentPort struct {
+	// Name identifies the port in the pod and the Service
+	// +kubebuilder:validation:MaxLength=15
+	Name string `json:"name"`
+
+	// Protocol is the application protocol spoken on the port; http also covers
+	// server-sent events
+	// +optional
+	// +kubebuilder:default=http
+	// +kubebuilder:validation:Enum=http;websocket;grpc
+	Protocol string `json:"protocol,omitempty"`
+
+	// ContainerPort is the port the agent listens on; defaults to 8081 for grpc
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	ContainerPort int32 `json:"containerPort,omitempty"`
+}
+
+const (
+	// AgentPortProtocolHTTP is plain HTTP/1.1, including server-sent events
+	AgentPortProtocolHTTP = "http"
+
+	// AgentPortProtocolWebSocket is HTTP upgraded to WebSocket
+	AgentPortProtocolWebSocket = "websocket"
+
+	// AgentPortProtocolGRPC is gRPC over cleartext HTTP/2
+	AgentPortProtocolGRPC = "grpc"
+)
+
+// IngressSpec defines ingress configuration
+// +kubebuilder:validation:XValidation:rule="!has(self.enabled) || !self.enabled || (has(self.host) && size(self.host) > 0)",message="host is required when ingress is enabled"
+// +kubebuilder:validation:XValidation:rule="!has(self.grpcHost) || !has(self.host) || self.grpcHost != self.host",message="grpcHost must differ from host"
+type IngressSpec struct {
+	// Enabled determines if ingress is enabled
+	// +optional
+	// +kubebuilder:default=false
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Host is the hostname for the ingress
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// TLS enables TLS for the ingress
+	// +optional
+	// +kubebuilder:default=true
+	TLS bool `json:"tls,omitempty"`
+
+	// GRPCHost is the hostname of a second Ingress serving the first grpc port;
+	// gRPC needs its own Ingress because the backend protocol is set per Ingress
+	// +optional
+	GRPCHost string `json:"grpcHost,omitempty"`
+
+	// ClassName is the IngressClass of the generated Ingresses
+	// +optional
+	ClassName *string `json:"className,omitempty"`
+
+	// Class selects what exposes the agent: ingress renders Ingresses, gateway a
+	// Gateway API HTTPRoute configured by Gateway
+	// +optional
+	// +kubebuilder:default=ingress
+	// +kubebuilder:validation:Enum=ingress;gateway
+	Class string `json:"class,omitempty"`
+
+	// Gateway configures the HTTPRoute of the gateway class
+	// +optional
+	Gateway *IngressGatewaySpec `json:"gateway,omitempty"`
+
+	// IssuerRef is the cert-manager issuer of the TLS certificates; defaults to the
+	// issuer of the AgentOpsConfig. Without an issuer the certificate Secrets are
+	// provided by hand.
+	// +optional
+	IssuerRef *CertificateIssuerReference `json:"issuerRef,omitempty"`
+}
+
+// CertificateIssuerReference identifies a cert-manager Issuer or ClusterIssuer
+type CertificateIssuerReference struct {
+	// Name of the issuer
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Kind is Issuer, in the agent's namespace, or ClusterIssuer
+	// +optional
+	// +kubebuilder:default=Issuer
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	Kind string `json:"kind,omitempty"`
+}
+
+// IngressGatewaySpec defines how the agent is exposed through the Gateway API. The
+// HTTPRoute attaches to ParentRefs and, with GatewayClassName, to a Gateway created
+// for the agent.
+type IngressGatewaySpec struct {
+	// ParentRefs are existing Gateways the HTTPRoute attaches to
+	// +optional
+	ParentRefs []GatewayParentReference `json:"parentRefs,omitempty"`
+
+	// GatewayClassName creates a Gateway of this class for the agent, listening on
+	// spec.ingress.host
+	// +optional
+	GatewayClassName string `json:"gatewayClassName,omitempty"`
+
+	// Rules are evaluated in order; the first match wins. Without rules all requests
+	// go to the agent.
+	// +optional
+	Rules []IngressRouteRule `json:"rules,omitempty"`
+}
+
+// IngressRouteRule matches requests and sends them to the agent or other
+// AgentDeployments
+type IngressRouteRule struct {
+	// PathPrefix matches the request path, e.g. /claude-3-haiku
+	// +optional
+	PathPrefix string `json:"pathPrefix,omitempty"`
+
+	// Headers that must match exactly, e.g. x-agent-variant: b
+	// +optional
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Backends are AgentDeployments in the namespace receiving the traffic by weight
+	// +optional
+	Backends []AgentRouteBackend `json:"backends,omitempty"`
+
+	// Model routes to every AgentDeployment in the namespace serving this model, with
+	// equal weights. Used when Backends is empty; without either the rule routes to
+	// the agent itself.
+	// +optional
+	Model string `json:"model,omitempty"`
+}
+
+// HealthSpec defines how the agent's health is checked
+type HealthSpec struct {
+	// Profile selects the runtime health profile providing default checks
+	// +optional
+	// +kubebuilder:default=default
+	// +kubebuilder:validation:Enum=default;vllm;tgi;ollama;grpc;tcp
+	Profile string `json:"profile,omitempty"`
+
+	// Checker overrides the health check protocol of the profile
+	// +optional
+	// +kubebuilder:validation:Enum=http;grpc;tcp;exec
+	Checker string `json:"checker,omitempty"`
+
+	// Port overrides the probed container port
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port *int32 `json:"port,omitempty"`
+
+	// LivenessPath overrides the liveness endpoint for the http checker
+	// +optional
+	LivenessPath string `json:"livenessPath,omitempty"`
+
+	// ReadinessPath overrides the readiness endpoint for the http checker
+	// +optional
+	ReadinessPath string `json:"readinessPath,omitempty"`
+
+	// GRPCService is the service name reported by the gRPC health protocol
+	// +optional
+	GRPCService string `json:"grpcService,omitempty"`
+
+	// Command is run inside the container by the exec checker
+	// +optional
+	Command []string `json:"command,omitempty"`
+}
+
+// ProbesSpec overrides the probes of the agent container
+type ProbesSpec struct {
+	// +optional
+	Liveness *ProbeSpec `json:"liveness,omitempty"`
+
+	// +optional
+	Readiness *ProbeSpec `json:"readiness,omitempty"`
+
+	// Startup adds a startup probe on the liveness endpoint; liveness and readiness
+	// checks only start once it succeeded, which protects slow model loads. Agents
+	// serving a self-hosted model in the pod get one by default, allowing 30 minutes.
+	// +optional
+	Startup *ProbeSpec `json:"startup,omitempty"`
+}
+
+// ProbeSpec overrides a single probe; unset fields keep the defaults
+type ProbeSpec struct {
+	// Disabled removes the probe from the container
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Path overrides the endpoint probed by the http checker
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	InitialDelaySeconds *int32 `json:"initialDelaySeconds,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	PeriodSeconds *int32 `json:"periodSeconds,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+}
+
+// WarmupSpec defines the warmup hook of agent pods; exactly one of HTTP and Exec is set
+type WarmupSpec struct {
+	// HTTP calls an endpoint of the agent container
+	// +optional
+	HTTP *HTTPWarmup `json:"http,omitempty"`
+
+	// Exec runs a command in the agent container
+	// +optional
+	Exec *ExecWarmup `json:"exec,omitempty"`
+
+	// Timeout bounds a single warmup attempt; failed attempts are retried
+	// +optional
+	// +kubebuilder:default="5m"
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// HTTPWarmup is a request sent to the agent's "http" port
+type HTTPWarmup struct {
+	// Path of the request, e.g. "/v1/completions"
+	Path string `json:"path"`
+
+	// +optional
+	// +kubebuilder:default=POST
+	// +kubebuilder:validation:Enum=GET;POST
+	Method string `json:"method,omitempty"`
+
+	// Body is sent as JSON with POST requests
+	// +optional
+	Body string `json:"body,omitempty"`
+}
+
+// ExecWarmup is a command run in the agent container; a zero exit status succeeds
+type ExecWarmup struct {
+	// +kubebuilder:validation:MinItems=1
+	Command []string `json:"command"`
+}
+
+// LifecycleSpec defines pod termination and readiness timing of the agent
+type LifecycleSpec struct {
+	// TerminationGracePeriodSeconds is how long a terminating pod may finish
+	// in-flight generations before it is killed
+	// +optional
+	// +kubebuilder:default=300
+	// +kubebuilder:validation:Minimum=0
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+
+	// PreStopDrainPath is an HTTP endpoint on the agent port called before the
+	// container is stopped; it should stop accepting requests and return once
+	// in-flight streams have finished, e.g. "/admin/drain"
+	// +optional
+	PreStopDrainPath string `json:"preStopDrainPath,omitempty"`
+
+	// MinReadySeconds is how long a new pod must be ready before it counts as
+	// available, slowing rollouts so old pods are not drained too early
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+}
+
+// UpgradeSpec defines how new revisions, model weights and adapter updates are
+// rolled out
+type UpgradeSpec struct {
+	// Strategy is RollingUpdate (replace pods) or DualSlot (load the new weights
+	// next to the old ones and switch atomically, falling back to RollingUpdate
+	// when the runtime does not support it or GPU memory is insufficient)
+	// +optional
+	// +kubebuilder:default=RollingUpdate
+	// +kubebuilder:validation:Enum=RollingUpdate;DualSlot
+	Strategy string `json:"strategy,omitempty"`
+
+	// WeightsVersion identifies the model weights or adapter revision to serve
+	// +optional
+	WeightsVersion string `json:"weightsVersion,omitempty"`
+
+	// ProgressDeadlineSeconds is how long a new revision may take to make progress
+	// before its rollout counts as failed; it sets the progress deadline of the
+	// Deployment or Rollout, which defaults to 600
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// AutoRollback restores the previous AgentRevision when a rollout fails: the new
+	// revision exceeds its progress deadline, or the analysis of
+	// spec.progressiveDelivery aborts the canary
+	// +optional
+	AutoRollback bool `json:"autoRollback,omitempty"`
+}
+
+const (
+	// UpgradeStrategyRollingUpdate replaces pods to apply new weights
+	UpgradeStrategyRollingUpdate = "RollingUpdate"
+
+	// UpgradeStrategyDualSlot swaps weights in place on running pods
+	UpgradeStrategyDualSlot = "DualSlot"
+)
+
+// HooksSpec defines webhooks called around child-object changes
+type HooksSpec struct {
+	// PreApply hooks are called before a change is applied and can reject it,
+	// e.g. to gate rollouts on a change-approval system
+	// +optional
+	PreApply []WebhookSpec `json:"preApply,omitempty"`
+
+	// PostApply hooks are called after a change is applied, e.g. to update a CMDB;
+	// their failures are logged and never block reconciliation
+	// +optional
+	PostApply []WebhookSpec `json:"postApply,omitempty"`
+}
+
+// WebhookSpec defines an HTTP webhook
+type WebhookSpec struct {
+	// Name identifies the hook in conditions and logs
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// URL receives a JSON POST describing the change
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// TokenSecretRef references a Secret key whose value is sent as a bearer token
+	// +optional
+	TokenSecretRef *corev1.SecretKeySelector `json:"tokenSecretRef,omitempty"`
+
+	// TimeoutSeconds bounds each call
+	// +optional
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=30
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// FailurePolicy decides whether an unreachable or failing preApply hook blocks
+	// the change (Fail) or is skipped (Ignore)
+	// +optional
+	// +kubebuilder:default=Fail
+	// +kubebuilder:validation:Enum=Fail;Ignore
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+}
+
+// Hook failure policies
+const (
+	HookFailurePolicyFail   = "Fail"
+	HookFailurePolicyIgnore = "Ignore"
+)
+
+// PausedAnnotation stops the controller from changing an AgentDeployment and its
+// child resources while set to "true"; deletion is still handled
+const PausedAnnotation = "agentops.io/paused"
+
+// LogLevelAnnotation raises the verbosity of the controller's logs about one
+// AgentDeployment: debug, info, error or a V-level, e.g. "debug" or "2"
+const LogLevelAnnotation = "agentops.io/log-level"
+
+// LogLevelUntilAnnotation is an RFC 3339 time after which LogLevelAnnotation is
+// ignored, so that a verbosity bump for an incident does not outlive it
+const LogLevelUntilAnnotation = "agentops.io/log-level-until"
+
+// HealthAnnotation carries the health the controller derives from the
+// AgentDeployment's conditions, for GitOps tools and scripts that read annotations
+const HealthAnnotation = "agentops.io/health"
+
+// NotifyAnnotation lists, comma-separated, the notification channels configured on
+// the controller that the AgentDeployment's transitions are sent to
+const NotifyAnnotation = "agentops.io/notify"
+
+// NotifyEventsAnnotation restricts notifications to the listed, comma-separated
+// events; all events are sent when it is not set
+const NotifyEventsAnnotation = "agentops.io/notify-events"
+
+// Values of the health annotation
+const (
+	// HealthHealthy: the latest spec is fully rolled out and every desired replica is ready
+	HealthHealthy = "Healthy"
+	// HealthProgressing: a rollout or scale operation is in flight
+	HealthProgressing = "Progressing"
+	// HealthDegraded: the agent cannot converge without a change, see the Stalled condition
+	HealthDegraded = "Degraded"
+	// HealthSuspended: the agent is paused or hibernated
+	HealthSuspended = "Suspended"
+)
+
+// ResourceRecommendations are requests and limits for the agent container sized
+// from its observed usage
+type ResourceRecommendations struct {
+	// Requests are the recommended requests
+	// +optional
+	Requests corev1.ResourceList `json:"requests,omitempty"`
+
+	// Limits are the recommended limits
+	// +optional
+	Limits corev1.ResourceList `json:"limits,omitempty"`
+
+	// Window is the usage period the recommendations are based on
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+
+	// LastUpdateTime is when the recommendations were last computed
+	// +optional
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// CostStatus estimates the infrastructure and LLM API spend of an agent
+type CostStatus struct {
+	// HourlyInfra is the hourly price in USD of the resources requested by the agent
+	// and model server pods
+	// +optional
+	HourlyInfra *resource.Quantity `json:"hourlyInfra,omitempty"`
+
+	// DailyTokens is the daily LLM API spend in USD projected from the last hour of
+	// token usage
+	// +optional
+	DailyTokens *resource.Quantity `json:"dailyTokens,omitempty"`
+
+	// LastUpdateTime is when the token spend was last projected
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// GPUStatus is the current utilization of the GPUs serving the agent's model, in
+// the agent pods or the model server
+type GPUStatus struct {
+	// Utilization is the average percent of time the GPUs were busy
+	// +optional
+	Utilization *int32 `json:"utilization,omitempty"`
+
+	// MemoryUtilization is the average percent of GPU memory in use
+	// +optional
+	MemoryUtilization *int32 `json:"memoryUtilization,omitempty"`
+
+	// LastUpdateTime is when the utilization was last read
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// ProviderStatus is the last probe of the hosted provider API serving the agent's
+// model
+type ProviderStatus struct {
+	// Name of the provider
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Model is the model ID, or Azure deployment, that was probed
+	// +optional
+	Model string `json:"model,omitempty"`
+
+	// Auth is Valid when the provider accepted the API key, Invalid when it rejected
+	// it and Unknown when the probe did not tell
+	// +optional
+	Auth string `json:"auth,omitempty"`
+
+	// ModelAvailable reports whether the provider serves the model to the API key
+	// +optional
+	ModelAvailable *bool `json:"modelAvailable,omitempty"`
+
+	// RequestsLimit and RequestsRemaining are the request rate limit of the API key
+	// and what is left of it in the current window
+	// +optional
+	RequestsLimit *int64 `json:"requestsLimit,omitempty"`
+	// +optional
+	RequestsRemaining *int64 `json:"requestsRemaining,omitempty"`
+
+	// TokensLimit and TokensRemaining are the token rate limit of the API key and
+	// what is left of it in the current window
+	// +optional
+	TokensLimit *int64 `json:"tokensLimit,omitempty"`
+	// +optional
+	TokensRemaining *int64 `json:"tokensRemaining,omitempty"`
+
+	// Message explains a failed probe
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastProbeTime is when the provider was last called
+	// +optional
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+}
+
+// AgentDeploymentStatus defines the observed state of AgentDeployment
+type AgentDeploymentStatus struct {
+	// Conditions represent the latest available observations of an object's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Replicas is the most recently observed number of replicas
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of pods with a Ready condition
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// AvailableReplicas is the number of available replicas
+	// +optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+
+	// Selector is the label selector of the agent pods, used by the scale subresource
+	// so that HorizontalPodAutoscalers can target the AgentDeployment
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// Phase represents the current phase of the agent deployment
+	// +optional
+	// +kubebuilder:validation:Enum=Pending;Running;Failed;Scaling
+	Phase string `json:"phase,omitempty"`
+
+	// ObservedGeneration reflects the generation of the most recently observed AgentDeployment
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// WeightsVersion is the model weights version active on the agent pods
+	// +optional
+	WeightsVersion string `json:"weightsVersion,omitempty"`
+
+	// PoolClaims is the number of AgentPool pods currently claimed
+	// +optional
+	PoolClaims int32 `json:"poolClaims,omitempty"`
+
+	// RecommendedResources are the requests recommended for the agent container by
+	// its VerticalPodAutoscaler
+	// +optional
+	RecommendedResources corev1.ResourceList `json:"recommendedResources,omitempty"`
+
+	// Recommendations are agent container requests and limits derived from its
+	// observed resource usage
+	// +optional
+	Recommendations *ResourceRecommendations `json:"recommendations,omitempty"`
+
+	// Cost estimates what the agent costs to run
+	// +optional
+	Cost *CostStatus `json:"cost,omitempty"`
+
+	// GPU reports the utilization of the GPUs serving the model
+	// +optional
+	GPU *GPUStatus `json:"gpu,omitempty"`
+
+	// PromptRevision is the PromptTemplate revision mounted into the agent
+	// +optional
+	PromptRevision int32 `json:"promptRevision,omitempty"`
+
+	// ModelServerReadyReplicas is the number of ready self-hosted model server pods
+	// +optional
+	ModelServerReadyReplicas int32 `json:"modelServerReadyReplicas,omitempty"`
+
+	// Image is the agent image resolved for spec.imageUpdatePolicy
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// ImageDigest is the manifest digest the agent image is pinned to
+	// +optional
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// Revision is the number of the AgentRevision currently rolled out
+	// +optional
+	Revision int64 `json:"revision,omitempty"`
+
+	// Topology reports how the ready agent pods are spread across zones and nodes
+	// +optional
+	Topology *TopologyStatus `json:"topology,omitempty"`
+
+	// Provider reports whether the hosted provider API accepts the agent's key and
+	// serves its model, and the rate limit headroom left
+	// +optional
+	Provider *ProviderStatus `json:"provider,omitempty"`
+}
+
+// TopologyStatus is the spread of the ready agent pods
+type TopologyStatus struct {
+	// Zones running ready agent pods
+	// +optional
+	Zones []TopologyDomain `json:"zones,omitempty"`
+
+	// GPUPools running ready agent pods, when spreadPolicy spreads across gpu-pool
+	// +optional
+	GPUPools []TopologyDomain `json:"gpuPools,omitempty"`
+
+	// Nodes is the number of nodes running ready agent pods
+	// +optional
+	Nodes int32 `json:"nodes,omitempty"`
+
+	// MaxPerNode is the largest number of ready agent pods on one node
+	// +optional
+	MaxPerNode int32 `json:"maxPerNode,omitempty"`
+
+	// Spot is the number of ready agent pods on spot nodes, with spec.placement.spot
+	// +optional
+	Spot int32 `json:"spot,omitempty"`
+
+	// OnDemand is the number of ready agent pods on other nodes, with
+	// spec.placement.spot
+	// +optional
+	OnDemand int32 `json:"onDemand,omitempty"`
+}
+
+// TopologyDomain is a zone or node pool and the ready agent pods it runs
+type TopologyDomain struct {
+	// Name of the zone or node pool
+	Name string `json:"name"`
+
+	// Replicas is the number of ready agent pods in the domain
+	Replicas int32 `json:"replicas"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:storageversion
 // +kubebuilder:subresource:status
-// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
 // +kubebuilder:printcolumn:name="Model",type=string,JSONPath=`.spec.model`
 // +kubebuilder:printcolumn:name="Replicas",type=integer,JSONPath=`.status.replicas`
 // +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
 // +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Infra/h",type=string,JSONPath=`.status.cost.hourlyInfra`
+// +kubebuilder:printcolumn:name="Tokens/day",type=string,JSONPath=`.status.cost.dailyTokens`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // AgentDeployment is the Schema for the agentdeployments API
@@ -0,0 +1,157 @@
+package v1beta1
+
+import (
+	"encoding/json"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// fieldsChangedInV1beta1 are the spec fields whose shape differs between the
+// versions; everything else has the same JSON form and is copied as is
+var fieldsChangedInV1beta1 = []string{"provider", "providerConfig", "autoscaling", "upgrade", "rollout"}
+
+// ConvertTo converts this AgentDeployment to the hub version (v1alpha1)
+func (src *AgentDeployment) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.AgentDeployment)
+	dst.ObjectMeta = src.ObjectMeta
+	if err := convertUnchanged(&src.Spec, &dst.Spec); err != nil {
+		return err
+	}
+	if err := convertJSON(&src.Status, &dst.Status); err != nil {
+		return err
+	}
+
+	if p := src.Spec.Provider; p != nil {
+		dst.Spec.Provider = p.Name
+		config := &v1alpha1.ProviderConfig{}
+		if err := convertJSON(p, config); err != nil {
+			return err
+		}
+		if *config != (v1alpha1.ProviderConfig{}) {
+			dst.Spec.ProviderConfig = config
+		}
+	}
+
+	if a := src.Spec.Autoscaling; a != nil {
+		dst.Spec.Autoscaling = &v1alpha1.AutoscalingSpec{
+			Enabled:     a.Enabled,
+			MinReplicas: a.MinReplicas,
+			MaxReplicas: a.MaxReplicas,
+		}
+		if a.Vertical != nil {
+			dst.Spec.Autoscaling.Vertical = &v1alpha1.VerticalAutoscalingSpec{}
+			if err := convertJSON(a.Vertical, dst.Spec.Autoscaling.Vertical); err != nil {
+				return err
+			}
+		}
+		if a.GPU != nil {
+			dst.Spec.Autoscaling.GPU = &v1alpha1.GPUAutoscalingSpec{}
+			if err := convertJSON(a.GPU, dst.Spec.Autoscaling.GPU); err != nil {
+				return err
+			}
+		}
+		if len(a.Metrics) > 0 {
+			if err := convertJSON(a.Metrics, &dst.Spec.Autoscaling.Metrics); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r := src.Spec.Rollout; r != nil {
+		dst.Spec.Upgrade = &v1alpha1.UpgradeSpec{
+			Strategy:                r.Strategy,
+			WeightsVersion:          r.WeightsVersion,
+			ProgressDeadlineSeconds: r.ProgressDeadlineSeconds,
+			AutoRollback:            r.AutoRollback,
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts from the hub version (v1alpha1) to this version
+func (dst *AgentDeployment) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.AgentDeployment)
+	dst.ObjectMeta = src.ObjectMeta
+	if err := convertUnchanged(&src.Spec, &dst.Spec); err != nil {
+		return err
+	}
+	if err := convertJSON(&src.Status, &dst.Status); err != nil {
+		return err
+	}
+
+	if src.Spec.Provider != "" || src.Spec.ProviderConfig != nil {
+		dst.Spec.Provider = &ProviderSpec{}
+		if src.Spec.ProviderConfig != nil {
+			if err := convertJSON(src.Spec.ProviderConfig, dst.Spec.Provider); err != nil {
+				return err
+			}
+		}
+		dst.Spec.Provider.Name = src.Spec.Provider
+	}
+
+	if a := src.Spec.Autoscaling; a != nil {
+		dst.Spec.Autoscaling = &AutoscalingSpec{
+			Enabled:     a.Enabled,
+			MinReplicas: a.MinReplicas,
+			MaxReplicas: a.MaxReplicas,
+		}
+		if a.Vertical != nil {
+			dst.Spec.Autoscaling.Vertical = &VerticalAutoscalingSpec{}
+			if err := convertJSON(a.Vertical, dst.Spec.Autoscaling.Vertical); err != nil {
+				return err
+			}
+		}
+		if a.GPU != nil {
+			dst.Spec.Autoscaling.GPU = &GPUAutoscalingSpec{}
+			if err := convertJSON(a.GPU, dst.Spec.Autoscaling.GPU); err != nil {
+				return err
+			}
+		}
+		// v1alpha1 metrics are untyped HorizontalPodAutoscaler metric specs
+		if len(a.Metrics) > 0 {
+			if err := convertJSON(a.Metrics, &dst.Spec.Autoscaling.Metrics); err != nil {
+				return err
+			}
+		}
+	}
+
+	if u := src.Spec.Upgrade; u != nil {
+		dst.Spec.Rollout = &RolloutSpec{
+			Strategy:                u.Strategy,
+			WeightsVersion:          u.WeightsVersion,
+			ProgressDeadlineSeconds: u.ProgressDeadlineSeconds,
+			AutoRollback:            u.AutoRollback,
+		}
+	}
+	return nil
+}
+
+// convertUnchanged copies the spec fields that are the same in both versions
+func convertUnchanged(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for _, name := range fieldsChangedInV1beta1 {
+		delete(fields, name)
+	}
+	if data, err = json.Marshal(fields); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// convertJSON copies in to out through their JSON form
+func convertJSON(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
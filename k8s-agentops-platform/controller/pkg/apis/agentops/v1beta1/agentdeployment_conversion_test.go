@@ -0,0 +1,111 @@
+package v1beta1
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+func int32Ptr(v int32) *int32 { return &v }
+
+func TestAutoscalingRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		autoscaling *AutoscalingSpec
+	}{
+		{
+			name:        "nil",
+			autoscaling: nil,
+		},
+		{
+			name: "replicas only",
+			autoscaling: &AutoscalingSpec{
+				Enabled:     true,
+				MinReplicas: int32Ptr(1),
+				MaxReplicas: int32Ptr(5),
+			},
+		},
+		{
+			name: "gpu",
+			autoscaling: &AutoscalingSpec{
+				Enabled:     true,
+				MinReplicas: int32Ptr(1),
+				MaxReplicas: int32Ptr(4),
+				GPU: &GPUAutoscalingSpec{
+					TargetUtilization:       int32Ptr(70),
+					TargetMemoryUtilization: int32Ptr(80),
+				},
+			},
+		},
+		{
+			name: "vertical and gpu",
+			autoscaling: &AutoscalingSpec{
+				Enabled:     true,
+				MaxReplicas: int32Ptr(2),
+				Vertical:    &VerticalAutoscalingSpec{Enabled: true},
+				GPU:         &GPUAutoscalingSpec{TargetUtilization: int32Ptr(50)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &AgentDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+				Spec:       AgentDeploymentSpec{Autoscaling: tt.autoscaling},
+			}
+
+			hub := &v1alpha1.AgentDeployment{}
+			if err := src.ConvertTo(hub); err != nil {
+				t.Fatalf("ConvertTo: %v", err)
+			}
+			if tt.autoscaling != nil && tt.autoscaling.GPU != nil {
+				if hub.Spec.Autoscaling == nil || hub.Spec.Autoscaling.GPU == nil {
+					t.Fatalf("ConvertTo dropped spec.autoscaling.gpu")
+				}
+			}
+
+			dst := &AgentDeployment{}
+			if err := dst.ConvertFrom(hub); err != nil {
+				t.Fatalf("ConvertFrom: %v", err)
+			}
+			if !reflect.DeepEqual(dst.Spec.Autoscaling, tt.autoscaling) {
+				t.Errorf("spec.autoscaling after round trip = %+v, want %+v", dst.Spec.Autoscaling, tt.autoscaling)
+			}
+		})
+	}
+}
+
+func TestIngressBackendAdapterRoundTrip(t *testing.T) {
+	ingress := &IngressSpec{
+		Enabled: true,
+		Gateway: &IngressGatewaySpec{
+			Rules: []IngressRouteRule{{
+				PathPrefix: "/",
+				Backends: []AgentRouteBackend{
+					{Name: "llama-served", Weight: 80},
+					{Name: "llama-served", Weight: 20, Adapter: "support-tone"},
+				},
+			}},
+		},
+	}
+	src := &AgentDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+		Spec:       AgentDeploymentSpec{Ingress: ingress},
+	}
+
+	hub := &v1alpha1.AgentDeployment{}
+	if err := src.ConvertTo(hub); err != nil {
+		t.Fatalf("ConvertTo: %v", err)
+	}
+	dst := &AgentDeployment{}
+	if err := dst.ConvertFrom(hub); err != nil {
+		t.Fatalf("ConvertFrom: %v", err)
+	}
+	if !reflect.DeepEqual(dst.Spec.Ingress, ingress) {
+		t.Errorf("spec.ingress after round trip = %+v, want %+v", dst.Spec.Ingress, ingress)
+	}
+}
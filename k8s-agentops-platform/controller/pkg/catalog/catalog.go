@@ -0,0 +1,243 @@
+package catalog
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/providers"
+)
+
+// Model describes the deployment characteristics of a supported model
+type Model struct {
+	// Name is the model identifier used in AgentDeployment specs
+	Name string
+
+	// SelfHosted is true when the weights are served inside the cluster
+	SelfHosted bool
+
+	// SizeClass groups the model with others needing similar agent resources
+	SizeClass string
+
+	// CacheFootprint is the local disk used by the agent for this model: downloaded
+	// weights and tensor scratch for self-hosted models, tokenizer and response
+	// caches for hosted APIs
+	CacheFootprint resource.Quantity
+
+	// Capabilities lists features routes can select on (tool-use, vision, long-context, fast)
+	Capabilities []string
+
+	// USDPerMillionTokens is the blended list price used for budget accounting;
+	// zero for self-hosted models
+	USDPerMillionTokens float64
+
+	// DefaultProvider serves the model when the spec does not select a provider
+	DefaultProvider string
+
+	// ProviderModelIDs names the model as each provider knows it, where that differs
+	// from Name (Bedrock model IDs, Hugging Face repositories, Ollama tags)
+	ProviderModelIDs map[string]string
+
+	// QuantizedModelIDs are the Hugging Face repositories of pre-quantized weights,
+	// by quantization method (awq, gptq)
+	QuantizedModelIDs map[string]string
+
+	// ContextTokens is the context window of the model
+	ContextTokens int32
+
+	// KVCacheBytesPerToken is the GPU memory the attention cache of a self-hosted
+	// model takes per token of context, at 16-bit precision
+	KVCacheBytesPerToken int64
+}
+
+// Model capabilities
+const (
+	CapabilityToolUse     = "tool-use"
+	CapabilityVision      = "vision"
+	CapabilityLongContext = "long-context"
+	CapabilityFast        = "fast"
+)
+
+// Size classes, from hosted models answered by a light client to self-hosted ones
+// needing several GPUs
+const (
+	SizeSmall  = "small"
+	SizeMedium = "medium"
+	SizeLarge  = "large"
+	SizeXL     = "xl"
+)
+
+// sizeClassResources are the built-in agent container resources of each size class.
+// The GPUs of large and xl are only needed by agent pods serving the model.
+var sizeClassResources = map[string]corev1.ResourceRequirements{
+	SizeSmall: {
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m"), corev1.ResourceMemory: resource.MustParse("512Mi")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+	},
+	SizeMedium: {
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m"), corev1.ResourceMemory: resource.MustParse("1Gi")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+	},
+	SizeLarge: {
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), corev1.ResourceMemory: resource.MustParse("32Gi"), "nvidia.com/gpu": resource.MustParse("1")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("48Gi"), "nvidia.com/gpu": resource.MustParse("1")},
+	},
+	SizeXL: {
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8"), corev1.ResourceMemory: resource.MustParse("64Gi"), "nvidia.com/gpu": resource.MustParse("2")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("96Gi"), "nvidia.com/gpu": resource.MustParse("2")},
+	},
+}
+
+var models = map[string]Model{
+	"claude-3-opus": {
+		Name:                "claude-3-opus",
+		SizeClass:           SizeMedium,
+		CacheFootprint:      resource.MustParse("1Gi"),
+		Capabilities:        []string{CapabilityToolUse, CapabilityVision, CapabilityLongContext},
+		USDPerMillionTokens: 30,
+		ContextTokens:       200000,
+		DefaultProvider:     providers.Anthropic,
+		ProviderModelIDs: map[string]string{
+			providers.Anthropic: "claude-3-opus-20240229",
+			providers.Bedrock:   "anthropic.claude-3-opus-20240229-v1:0",
+			providers.Vertex:    "claude-3-opus@20240229",
+		},
+	},
+	"claude-3-sonnet": {
+		Name:                "claude-3-sonnet",
+		SizeClass:           SizeMedium,
+		CacheFootprint:      resource.MustParse("1Gi"),
+		Capabilities:        []string{CapabilityToolUse, CapabilityVision, CapabilityLongContext},
+		USDPerMillionTokens: 6,
+		ContextTokens:       200000,
+		DefaultProvider:     providers.Anthropic,
+		ProviderModelIDs: map[string]string{
+			providers.Anthropic: "claude-3-sonnet-20240229",
+			providers.Bedrock:   "anthropic.claude-3-sonnet-20240229-v1:0",
+			providers.Vertex:    "claude-3-sonnet@20240229",
+		},
+	},
+	"claude-3-haiku": {
+		Name:                "claude-3-haiku",
+		SizeClass:           SizeSmall,
+		CacheFootprint:      resource.MustParse("512Mi"),
+		Capabilities:        []string{CapabilityToolUse, CapabilityVision, CapabilityLongContext, CapabilityFast},
+		USDPerMillionTokens: 0.5,
+		ContextTokens:       200000,
+		DefaultProvider:     providers.Anthropic,
+		ProviderModelIDs: map[string]string{
+			providers.Anthropic: "claude-3-haiku-20240307",
+			providers.Bedrock:   "anthropic.claude-3-haiku-20240307-v1:0",
+			providers.Vertex:    "claude-3-haiku@20240307",
+		},
+	},
+	"gpt-4": {
+		Name:                "gpt-4",
+		SizeClass:           SizeMedium,
+		CacheFootprint:      resource.MustParse("1Gi"),
+		Capabilities:        []string{CapabilityToolUse},
+		USDPerMillionTokens: 45,
+		ContextTokens:       8192,
+		DefaultProvider:     providers.OpenAI,
+	},
+	"gpt-4-turbo": {
+		Name:                "gpt-4-turbo",
+		SizeClass:           SizeMedium,
+		CacheFootprint:      resource.MustParse("1Gi"),
+		Capabilities:        []string{CapabilityToolUse, CapabilityVision, CapabilityLongContext},
+		USDPerMillionTokens: 15,
+		ContextTokens:       128000,
+		DefaultProvider:     providers.OpenAI,
+	},
+	"gpt-3.5-turbo": {
+		Name:                "gpt-3.5-turbo",
+		SizeClass:           SizeSmall,
+		CacheFootprint:      resource.MustParse("512Mi"),
+		Capabilities:        []string{CapabilityToolUse, CapabilityFast},
+		USDPerMillionTokens: 1,
+		ContextTokens:       16385,
+		DefaultProvider:     providers.OpenAI,
+	},
+	"llama-2-70b": {
+		Name:            "llama-2-70b",
+		SizeClass:       SizeXL,
+		SelfHosted:      true,
+		CacheFootprint:  resource.MustParse("140Gi"),
+		DefaultProvider: providers.VLLM,
+		ProviderModelIDs: map[string]string{
+			providers.VLLM:    "meta-llama/Llama-2-70b-chat-hf",
+			providers.TGI:     "meta-llama/Llama-2-70b-chat-hf",
+			providers.Ollama:  "llama2:70b",
+			providers.Bedrock: "meta.llama2-70b-chat-v1",
+		},
+		QuantizedModelIDs: map[string]string{
+			"awq":  "TheBloke/Llama-2-70B-Chat-AWQ",
+			"gptq": "TheBloke/Llama-2-70B-Chat-GPTQ",
+		},
+		ContextTokens: 4096,
+		// 80 layers, 8 KV heads of 128 dimensions, keys and values
+		KVCacheBytesPerToken: 2 * 80 * 8 * 128 * 2,
+	},
+	"mixtral-8x7b": {
+		Name:            "mixtral-8x7b",
+		SizeClass:       SizeLarge,
+		SelfHosted:      true,
+		CacheFootprint:  resource.MustParse("96Gi"),
+		Capabilities:    []string{CapabilityToolUse, CapabilityFast},
+		DefaultProvider: providers.VLLM,
+		ProviderModelIDs: map[string]string{
+			providers.VLLM:    "mistralai/Mixtral-8x7B-Instruct-v0.1",
+			providers.TGI:     "mistralai/Mixtral-8x7B-Instruct-v0.1",
+			providers.Ollama:  "mixtral:8x7b",
+			providers.Bedrock: "mistral.mixtral-8x7b-instruct-v0:1",
+		},
+		QuantizedModelIDs: map[string]string{
+			"awq":  "TheBloke/Mixtral-8x7B-Instruct-v0.1-AWQ",
+			"gptq": "TheBloke/Mixtral-8x7B-Instruct-v0.1-GPTQ",
+		},
+		ContextTokens: 32768,
+		// 32 layers, 8 KV heads of 128 dimensions, keys and values
+		KVCacheBytesPerToken: 2 * 32 * 8 * 128 * 2,
+	},
+}
+
+// HasCapability reports whether the model advertises the capability
+func (m Model) HasCapability(capability string) bool {
+	for _, c := range m.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// SizeClassResources returns the built-in agent container resources of a size class
+func SizeClassResources(class string) (corev1.ResourceRequirements, bool) {
+	res, ok := sizeClassResources[class]
+	return *res.DeepCopy(), ok
+}
+
+// Lookup returns the catalog entry for a model
+func Lookup(name string) (Model, bool) {
+	m, ok := models[name]
+	return m, ok
+}
+
+// ProviderModelID returns the identifier of the model at the given provider
+func ProviderModelID(name, provider string) string {
+	if id, ok := models[name].ProviderModelIDs[provider]; ok {
+		return id
+	}
+	return name
+}
+
+// QuantizedModelID returns the repository of the model's weights pre-quantized with
+// method, if the catalog knows one
+func QuantizedModelID(name, method string) (string, bool) {
+	id, ok := models[name].QuantizedModelIDs[method]
+	return id, ok
+}
+
+// Cost returns the list price in USD of the given number of tokens of a model
+func Cost(model string, tokens float64) float64 {
+	return tokens / 1e6 * models[model].USDPerMillionTokens
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/providers"
+)
+
+const (
+	adapterSyncImage         = "ghcr.io/myorg/agentops-adapter-sync:latest"
+	adaptersVolumeName       = "adapters"
+	adaptersDir              = "/adapters"
+	adaptersConfigVolumeName = "adapters-config"
+	adaptersConfigDir        = "/etc/agentops/adapters"
+	adaptersConfigKey        = "adapters.json"
+	defaultMaxLoadedAdapters = int32(4)
+
+	// adapterHeader names the adapter an agent request is served with; the agent
+	// passes it to the model server as the model of its calls
+	adapterHeader    = "x-agentops-adapter"
+	adapterHeaderEnv = "AGENTOPS_ADAPTER_HEADER"
+)
+
+// adaptersEnabled reports whether the model server serves LoRA adapters
+func adaptersEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return modelServerEnabled(ad) && len(ad.Spec.Serving.Adapters) > 0
+}
+
+// hasAdapter reports whether the agent's model server serves the named adapter
+func hasAdapter(ad *agentopsv1alpha1.AgentDeployment, name string) bool {
+	if !adaptersEnabled(ad) {
+		return false
+	}
+	for _, a := range ad.Spec.Serving.Adapters {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// adaptersConfigMapName returns the name of the ConfigMap listing the adapters
+func adaptersConfigMapName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-adapters"
+}
+
+// validateAdapters checks that spec.serving.adapters can be served by the runtime
+func validateAdapters(ad *agentopsv1alpha1.AgentDeployment, p providers.Provider, modelID string) error {
+	if !adaptersEnabled(ad) {
+		return nil
+	}
+	// tgi reads its adapters once on start and cannot change them while serving
+	if p.Name != providers.VLLM {
+		return fmt.Errorf("serving.adapters require provider vllm, got %s", p.Name)
+	}
+	seen := map[string]bool{}
+	for _, a := range ad.Spec.Serving.Adapters {
+		if seen[a.Name] || a.Name == modelID {
+			return fmt.Errorf("serving.adapters name %q is not unique", a.Name)
+		}
+		seen[a.Name] = true
+	}
+	return nil
+}
+
+// adapterArgs enables LoRA serving on the vllm runtime. The arguments do not
+// depend on the adapters listed, so changing them does not restart the server.
+func adapterArgs(ad *agentopsv1alpha1.AgentDeployment) []string {
+	loaded := defaultMaxLoadedAdapters
+	if ad.Spec.Serving.MaxLoadedAdapters != nil {
+		loaded = *ad.Spec.Serving.MaxLoadedAdapters
+	}
+	args := []string{"--enable-lora", "--max-loras", strconv.Itoa(int(loaded))}
+	if rank := ad.Spec.Serving.MaxAdapterRank; rank != nil {
+		args = append(args, "--max-lora-rank", strconv.Itoa(int(*rank)))
+	}
+	return args
+}
+
+// reconcileAdapters publishes the adapters the model server should serve, or
+// removes the list when there are none. The sync sidecar of the server watches the
+// mounted ConfigMap and loads and unloads adapters to match it.
+func (r *AgentDeploymentReconciler) reconcileAdapters(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: adaptersConfigMapName(ad), Namespace: ad.Namespace},
+	}
+	if !adaptersEnabled(ad) {
+		if ad.Spec.Serving != nil && len(ad.Spec.Serving.Adapters) > 0 {
+			return fmt.Errorf("serving.adapters require serving.selfHosted")
+		}
+		return deleteOwned(ctx, r.Client, ad, cm)
+	}
+	data, err := json.Marshal(ad.Spec.Serving.Adapters)
+	if err != nil {
+		return err
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Labels = labelsForModelServer(ad.Name)
+		cm.Data = map[string]string{adaptersConfigKey: string(data)}
+		return controllerutil.SetControllerReference(ad, cm, r.Scheme)
+	})
+	return err
+}
+
+// adapterSyncContainer downloads the listed adapters into the adapters volume and
+// loads them into the server at port through its runtime LoRA API, unloading the
+// adapters no longer listed. It reads Hugging Face repositories with the token of
+// the provider credentials and object stores with the pod's workload identity.
+func adapterSyncContainer(ad *agentopsv1alpha1.AgentDeployment, port int32) corev1.Container {
+	env := []corev1.EnvVar{
+		{Name: "ADAPTERS_CONFIG", Value: path.Join(adaptersConfigDir, adaptersConfigKey)},
+		{Name: "ADAPTERS_DIR", Value: adaptersDir},
+		{Name: "ADAPTERS_SERVER_URL", Value: fmt.Sprintf("http://127.0.0.1:%d", port)},
+	}
+	if cfg := ad.Spec.ProviderConfig; cfg != nil && cfg.CredentialsSecretRef != nil {
+		env = append(env, credentialEnv("HF_TOKEN", cfg.CredentialsSecretRef.Name, hfTokenSecretKey, true))
+	}
+	return corev1.Container{
+		Name:  "adapter-sync",
+		Image: adapterSyncImage,
+		Env:   env,
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: adaptersVolumeName, MountPath: adaptersDir},
+			{Name: adaptersConfigVolumeName, MountPath: adaptersConfigDir, ReadOnly: true},
+		},
+	}
+}
+
+// adapterVolumes returns the volumes shared by the server and the sync sidecar. The
+// ConfigMap is mounted as a volume, not projected into env, so that kubelet
+// refreshes it in the running pods.
+func adapterVolumes(ad *agentopsv1alpha1.AgentDeployment) []corev1.Volume {
+	return []corev1.Volume{
+		{
+			Name:         adaptersVolumeName,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		},
+		{
+			Name: adaptersConfigVolumeName,
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: adaptersConfigMapName(ad)},
+			}},
+		},
+	}
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/policy"
+)
+
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// validateAdmissionPolicy evaluates the agent against the compiled-in policies of
+// the validator, then the rules and the external policy of the AgentOpsConfig
+func (v *AgentDeploymentValidator) validateAdmissionPolicy(ctx context.Context, ad, old *agentopsv1alpha1.AgentDeployment) error {
+	cfg, err := getAgentOpsConfig(ctx, v.Reader)
+	if err != nil {
+		return err
+	}
+	evaluators := append([]policy.Evaluator(nil), v.Policies...)
+	if cfg != nil && cfg.Spec.AdmissionPolicy != nil {
+		ap := cfg.Spec.AdmissionPolicy
+		if len(ap.Rules) > 0 {
+			evaluators = append(evaluators, &policy.CEL{Rules: ap.Rules})
+		}
+		if ap.External != nil {
+			evaluators = append(evaluators, &policy.OPA{Spec: *ap.External, HTTP: http.DefaultClient})
+		}
+	}
+	if len(evaluators) == 0 {
+		return nil
+	}
+
+	in, err := policyInput(ctx, v.Reader, ad, old)
+	if err != nil {
+		return err
+	}
+	return policy.Evaluate(ctx, in, evaluators...)
+}
+
+// policyInput renders an admission request for policies
+func policyInput(ctx context.Context, c client.Reader, ad, old *agentopsv1alpha1.AgentDeployment) (policy.Input, error) {
+	in := policy.Input{Operation: "CREATE"}
+	var err error
+	if in.Object, err = runtime.DefaultUnstructuredConverter.ToUnstructured(ad); err != nil {
+		return in, err
+	}
+	if old != nil {
+		in.Operation = "UPDATE"
+		if in.OldObject, err = runtime.DefaultUnstructuredConverter.ToUnstructured(old); err != nil {
+			return in, err
+		}
+	}
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: ad.Namespace}, ns); err != nil {
+		return in, err
+	}
+	in.Namespace = policy.Namespace{Name: ns.Name, Labels: ns.Labels, Annotations: ns.Annotations}
+	return in, nil
+}
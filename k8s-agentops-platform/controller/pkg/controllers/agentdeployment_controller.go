@@ -3,25 +3,47 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
 	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/cosign"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/cost"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/hooks"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/logging"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/podpatch"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
 )
 
 const (
 	agentDeploymentFinalizer = "agentops.io/finalizer"
 	defaultImage             = "ghcr.io/myorg/llm-agent"
+	safeToEvictAnnotation    = "cluster-autoscaler.kubernetes.io/safe-to-evict"
 )
 
 // AgentDeploymentReconciler reconciles an AgentDeployment object
@@ -29,14 +51,124 @@ type AgentDeploymentReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Log    logr.Logger
+	Hooks  *hooks.Client
+
+	// Recorder emits events on AgentDeployments; nil disables events
+	Recorder record.EventRecorder
+
+	// Notifier sends phase and condition transitions to the channels agents opt in
+	// to; nil disables notifications
+	Notifier Notifier
+
+	// Warmer runs spec.warmup hooks against new agent pods and checks the URLs of
+	// spec.dependencies; nil leaves them unwarmed and the URLs unchecked
+	Warmer Warmer
+
+	// Signatures verifies agent images for the AgentOpsConfig's image verification;
+	// nil skips verification
+	Signatures SignatureVerifier
+
+	// Images resolves agent image tags for spec.imageUpdatePolicy; nil deploys tags
+	// as they are
+	Images ImageResolver
+
+	// Pulls checks that the images of a new agent Deployment can be pulled; nil
+	// skips the check
+	Pulls ImagePullChecker
+
+	// PrometheusAddress is queried by the AnalysisTemplates of spec.progressiveDelivery;
+	// empty rolls out without analysis
+	PrometheusAddress string
+
+	// Activity reads agent traffic for scale-to-zero; nil disables idle scale-down
+	Activity ActivitySource
+
+	// Usage reads agent container resource usage for status.recommendations; nil
+	// disables recommendations
+	Usage ResourceUsageSource
+
+	// Tokens reads agent token usage to project LLM API spend in status.cost; nil
+	// leaves it out
+	Tokens UsageSource
+
+	// GPUs reads the utilization of the GPUs serving the model for status.gpu; nil
+	// leaves it out
+	GPUs GPUUsageSource
+
+	// Providers probes the key, model and rate limit headroom of hosted providers for
+	// status.provider; nil leaves it out
+	Providers ProviderProber
+
+	// Prices price the resources requested by agent pods in status.cost
+	Prices cost.Prices
+
+	// ActivatorService is the Service of the activator that holds requests for agents
+	// scaled to zero
+	ActivatorService types.NamespacedName
+
+	// PodTemplatePatch is the ConfigMap whose patch.yaml is merged into every agent pod
+	// template; an empty name disables it
+	PodTemplatePatch types.NamespacedName
+
+	// ResyncPeriod forces a reconcile of every AgentDeployment at this interval in
+	// addition to events; zero reconciles on events only
+	ResyncPeriod time.Duration
+
+	// Triggers queues reconciles of the AgentDeployments sent on it, e.g. by the
+	// debug server; nil disables it
+	Triggers <-chan event.GenericEvent
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// WeightSwapper swaps model weights in place on a running agent pod
+type WeightSwapper interface {
+	Swap(ctx context.Context, baseURL, version string) error
 }
 
 // +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=endpoints,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=pods/exec,verbs=create
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=agentpools,verbs=get;list;watch
+// +kubebuilder:rbac:groups=agentops.io,resources=prompttemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=agentops.io,resources=agenttasks,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=agentops.io,resources=tokenbudgets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=agentops.io,resources=ratelimitpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=agentops.io,resources=modelcaches,verbs=get;list;watch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentopsconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=agentops.io,resources=tenantquotas,verbs=get;list;watch
+// +kubebuilder:rbac:groups=agentops.io,resources=modelpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=agentops.io,resources=toolservers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update;patch;delete;escalate;bind
+// +kubebuilder:rbac:groups=agentops.io,resources=agentrevisions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=evaluationruns,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes;gateways,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=flagger.app,resources=canaries;metrictemplates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=leaderworkerset.x-k8s.io,resources=leaderworkersets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=argoproj.io,resources=rollouts;analysistemplates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.istio.io,resources=virtualservices;destinationrules,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=security.istio.io,resources=peerauthentications,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=scheduling.k8s.io,resources=priorityclasses,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
 // +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=autoscaling.k8s.io,resources=verticalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=external-secrets.io,resources=externalsecrets,verbs=get;list;watch;create;update;patch;delete;deletecollection
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=prometheusrules,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop
 func (r *AgentDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -53,14 +185,21 @@ func (r *AgentDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		log.Error(err, "Failed to get AgentDeployment")
 		return ctrl.Result{}, err
 	}
+	log = logging.ForObject(log, agentDep)
+	log.V(1).Info("Reconciling", "Generation", agentDep.Generation, "ResourceVersion", agentDep.ResourceVersion)
 
 	// Handle deletion
 	if !agentDep.ObjectMeta.DeletionTimestamp.IsZero() {
 		if controllerutil.ContainsFinalizer(agentDep, agentDeploymentFinalizer) {
 			// Run finalization logic
-			if err := r.finalizeAgentDeployment(ctx, agentDep); err != nil {
+			done, err := r.finalizeAgentDeployment(ctx, agentDep)
+			if err != nil {
+				log.Error(err, "Failed to finalize AgentDeployment")
 				return ctrl.Result{}, err
 			}
+			if !done {
+				return ctrl.Result{RequeueAfter: finalizeRetryInterval}, nil
+			}
 
 			// Remove finalizer
 			controllerutil.RemoveFinalizer(agentDep, agentDeploymentFinalizer)
@@ -71,6 +210,12 @@ func (r *AgentDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, nil
 	}
 
+	// Operators pause reconciliation to make manual changes during an incident
+	if agentDep.Annotations[agentopsv1alpha1.PausedAnnotation] == "true" {
+		log.Info("AgentDeployment is paused; skipping reconcile")
+		return ctrl.Result{}, r.setHealthAnnotation(ctx, agentDep)
+	}
+
 	// Add finalizer if not present
 	if !controllerutil.ContainsFinalizer(agentDep, agentDeploymentFinalizer) {
 		controllerutil.AddFinalizer(agentDep, agentDeploymentFinalizer)
@@ -79,139 +224,873 @@ func (r *AgentDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		}
 	}
 
-	// Reconcile Deployment
+	// Roll back to the AgentRevision named by the rollback-to annotation
+	if rolledBack, err := r.rollback(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to roll back AgentDeployment")
+		return ctrl.Result{}, err
+	} else if rolledBack {
+		return ctrl.Result{}, nil
+	}
+	// Roll back to the previous AgentRevision when the rollout of the current one failed
+	if rolledBack, err := r.autoRollback(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to roll back failed rollout")
+		return ctrl.Result{}, err
+	} else if rolledBack {
+		return ctrl.Result{}, nil
+	}
+	// Revisions record the spec as written, without the AgentOpsConfig defaults
+	template := revisionTemplate(agentDep)
+
+	// Org-wide defaults of the AgentOpsConfig apply under the agent's own settings
+	config, err := r.agentOpsConfig(ctx)
+	if err != nil {
+		log.Error(err, "Failed to get AgentOpsConfig")
+		return ctrl.Result{}, err
+	}
+	applyConfigDefaults(agentDep, config)
+	// The AgentOpsConfig is watched, so a rejected provider needs no retry
+	if err := providerAllowed(agentDep, config); err != nil {
+		return ctrl.Result{}, r.rolloutRejected(ctx, agentDep, conditions.ReasonProviderNotAllowed, err.Error())
+	}
+
+	// ModelPolicies are watched, so a rejected agent needs no retry
+	rejected, err := r.reconcileModelPolicies(ctx, agentDep)
+	if err != nil {
+		log.Error(err, "Failed to check ModelPolicies")
+		return ctrl.Result{}, err
+	}
+	if rejected {
+		return ctrl.Result{}, nil
+	}
+
+	// Agents that do not fit the TenantQuotas of the namespace wait for room
+	rejection, err := r.quotaRejection(ctx, agentDep)
+	if err != nil {
+		log.Error(err, "Failed to check TenantQuotas")
+		return ctrl.Result{}, err
+	}
+	if rejection != "" {
+		return ctrl.Result{RequeueAfter: quotaRetryInterval}, r.rolloutRejected(ctx, agentDep, conditions.ReasonQuotaExceeded, rejection)
+	}
+
+	// Reconcile ExternalSecrets before the pods that consume them
+	if err := r.reconcileExternalSecrets(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile ExternalSecrets")
+		return ctrl.Result{}, err
+	}
+
+	// Publish the desired weights version before any pod reads it
+	if err := r.reconcileWeightsConfigMap(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile weights ConfigMap")
+		return ctrl.Result{}, err
+	}
+
+	// Mount the selected prompt revision
+	if err := r.reconcilePromptConfigMap(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile prompt ConfigMap")
+		return ctrl.Result{}, err
+	}
+
+	// Publish the collector sidecar configuration before pods mount it
+	if err := r.reconcileCollectorConfigMap(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile collector ConfigMap")
+		return ctrl.Result{}, err
+	}
+
+	// Publish the audit sidecar configuration before pods mount it
+	if err := r.reconcileAuditConfigMap(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile audit ConfigMap")
+		return ctrl.Result{}, err
+	}
+
+	// Ship the agent's Grafana dashboard
+	if err := r.reconcileDashboard(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile dashboard")
+		return ctrl.Result{}, err
+	}
+
+	// Render the agent's alerting rules
+	if err := r.reconcilePrometheusRule(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile PrometheusRule")
+		return ctrl.Result{}, err
+	}
+
+	// Pods of a priority tier need its PriorityClass before they are created
+	if err := r.reconcilePriorityClass(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile PriorityClass")
+		return ctrl.Result{}, err
+	}
+
+	// Reconcile Service
+	if err := r.reconcileService(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile Service")
+		return ctrl.Result{}, err
+	}
+
+	// Request TLS certificates for the hosts the Ingress or Gateway serves
+	if err := r.reconcileCertificates(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile Certificates")
+		return ctrl.Result{}, err
+	}
+
+	// Expose the agent outside the cluster
+	if err := r.reconcileIngress(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile Ingress")
+		return ctrl.Result{}, err
+	}
+
+	// Apply mesh traffic policies and mTLS
+	if err := r.reconcileMesh(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile service mesh resources")
+		return ctrl.Result{}, err
+	}
+
+	// Force the agent's calls out of the cluster through the namespace's egress proxy
+	if err := r.reconcileEgress(ctx, agentDep, config); err != nil {
+		log.Error(err, "Failed to reconcile egress proxy")
+		return ctrl.Result{}, err
+	}
+
+	// Publish the LoRA adapters before the model server pods mount their list
+	if err := r.reconcileAdapters(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile LoRA adapters")
+		return ctrl.Result{}, err
+	}
+
+	// Bring up the self-hosted model server the agent calls
+	if err := r.reconcileModelServer(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile model server")
+		return ctrl.Result{}, err
+	}
+
+	// Deploy or check the store keeping the agent's sessions
+	if err := r.reconcileSessionStore(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile session store")
+		return ctrl.Result{}, err
+	}
+
+	// Deploy the broker of the request queue before the queue proxies connect to it
+	if err := r.reconcileQueue(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile queue broker")
+		return ctrl.Result{}, err
+	}
+
+	// Deploy the redis of the response cache before the cache proxies connect to it
+	if err := r.reconcileResponseCache(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile response cache")
+		return ctrl.Result{}, err
+	}
+
+	// Annotate the ServiceAccount of the agent's cloud identity before pods use it
+	if err := r.reconcileCloudIdentity(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to reconcile cloud identity")
+		return ctrl.Result{}, err
+	}
+
+	// Look up the tool servers the agent calls, and grant its sidecar tools their
+	// Kubernetes API permissions before pods use them
+	tools, err := r.resolveTools(ctx, agentDep)
+	if err != nil {
+		log.Error(err, "Failed to get ToolServers")
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileToolsRBAC(ctx, agentDep, tools); err != nil {
+		log.Error(err, "Failed to reconcile tool RBAC")
+		return ctrl.Result{}, err
+	}
+
+	// Admit only declared peers and hand the agent the tokens its peers expect
+	peerTokensHash, err := r.reconcilePeers(ctx, agentDep)
+	if err != nil {
+		log.Error(err, "Failed to reconcile peers")
+		return ctrl.Result{}, err
+	}
+
+	// Exceeded TokenBudgets may take the agent down until the next period
+	budget, err := r.exceededBudget(ctx, agentDep)
+	if err != nil {
+		log.Error(err, "Failed to check TokenBudgets")
+		return ctrl.Result{}, err
+	}
+
+	// Put the rate limit proxy configuration in place before pods mount it
+	rateLimit, err := r.rateLimitForAgentDeployment(ctx, agentDep)
+	if err != nil {
+		log.Error(err, "Failed to resolve RateLimitPolicies")
+		return ctrl.Result{}, err
+	}
+	rateLimitHash, err := r.reconcileRateLimitConfigMap(ctx, agentDep, rateLimit)
+	if err != nil {
+		log.Error(err, "Failed to reconcile rate limit ConfigMap")
+		return ctrl.Result{}, err
+	}
+	// Put the provider proxy configuration of spec.resilience in place as well
+	resilienceHash, err := r.reconcileResilience(ctx, agentDep, config)
+	if err != nil {
+		log.Error(err, "Failed to reconcile provider proxy ConfigMap")
+		return ctrl.Result{}, err
+	}
+	// An HPA on the AgentDeployment itself takes precedence over spec.autoscaling
+	managedAutoscaler, err := r.managedAutoscaler(ctx, agentDep)
+	if err != nil {
+		log.Error(err, "Failed to list HorizontalPodAutoscalers")
+		return ctrl.Result{}, err
+	}
+	// Org-wide additions to every agent pod template
+	templatePatch, err := r.podTemplatePatch(ctx, agentDep)
+	if err != nil {
+		log.Error(err, "Failed to render pod template patch")
+		return ctrl.Result{}, err
+	}
+	// Pin the agent image to a digest, and only roll out images signed with the keys
+	// of the AgentOpsConfig
+	image, err := r.agentImageFor(agentDep, config, templatePatch)
+	if err != nil {
+		log.Error(err, "Failed to build Deployment")
+		return ctrl.Result{}, err
+	}
+	digest, err := r.resolveAgentImage(ctx, agentDep, image)
+	if err != nil {
+		log.Error(err, "Failed to resolve agent image digest", "Image", image)
+		return ctrl.Result{}, err
+	}
+	digest, err = r.verifyAgentImage(ctx, config, image, digest)
+	if cosign.IsVerificationError(err) {
+		return ctrl.Result{RequeueAfter: signatureRetryInterval}, r.rolloutRejected(ctx, agentDep, conditions.ReasonSignatureInvalid, err.Error())
+	} else if err != nil {
+		log.Error(err, "Failed to verify agent image signature")
+		return ctrl.Result{}, err
+	}
+	// Pods roll when the key broker rotates one of their keys
+	apiKeys, err := r.apiKeysChecksum(ctx, agentDep)
+	if err != nil {
+		log.Error(err, "Failed to read API key Secrets")
+		return ctrl.Result{}, err
+	}
+	// Idle agents with spec.hibernation are scaled down to their hibernation replicas
+	hibernated := r.reconcileHibernation(ctx, agentDep)
+	// GPU-backed agents only get the replicas the free GPUs hold, per spec.capacityPolicy
+	capacity, err := r.gpuCapacity(ctx, agentDep)
+	if err != nil {
+		log.Error(err, "Failed to read GPU capacity")
+		return ctrl.Result{}, err
+	}
+	// With spec.placement.spot in fallback-to-on-demand mode part of the replicas
+	// run in a separate on-demand Deployment
+	split := &spotSplit{}
+	overlays := []deploymentOverlay{
+		configOverlay(config),
+		scaleToZeroOverlay(r.agentIdle(ctx, agentDep)),
+		hibernationOverlay(agentDep, hibernated),
+		budgetOverlay(budget),
+		rateLimitOverlay(agentDep, rateLimit, rateLimitHash),
+		responseCacheOverlay(agentDep, needsGatewaySidecar(agentDep, rateLimit)),
+		queueOverlay(agentDep, needsGatewaySidecar(agentDep, rateLimit) || responseCacheEnabled(agentDep)),
+		resilienceOverlay(agentDep, resilienceHash),
+		imageDigestOverlay(image, digest),
+		apiKeysOverlay(apiKeys),
+		egressOverlay(agentDep.Namespace, config),
+		toolsOverlay(agentDep, tools),
+		peerTokensOverlay(peerTokensHash),
+		spotOverlay(agentDep, split),
+		capacityOverlay(agentDep, capacity),
+		componentResourcesOverlay(agentDep),
+	}
+
+	// Reconcile Deployment, or the Argo Rollouts Rollout replacing it with
+	// spec.progressiveDelivery
 	deployment := &appsv1.Deployment{}
-	err = r.Get(ctx, types.NamespacedName{Name: agentDep.Name, Namespace: agentDep.Namespace}, deployment)
+	workload := "Deployment"
+	if rolloutEnabled(agentDep) {
+		workload = "Rollout"
+		// The Rollout references its AnalysisTemplate by name
+		if err := r.reconcileAnalysisTemplate(ctx, agentDep); err != nil {
+			log.Error(err, "Failed to reconcile AnalysisTemplate")
+			return ctrl.Result{}, err
+		}
+		deployment, err = r.getRollout(ctx, agentDep)
+	} else {
+		err = r.Get(ctx, types.NamespacedName{Name: agentDep.Name, Namespace: agentDep.Namespace}, deployment)
+	}
 	if err != nil && errors.IsNotFound(err) {
 		// Create new Deployment
-		dep := r.deploymentForAgentDeployment(agentDep)
-		log.Info("Creating a new Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
-		err = r.Create(ctx, dep)
+		dep, err := r.deploymentForAgentDeployment(agentDep, templatePatch)
 		if err != nil {
-			log.Error(err, "Failed to create new Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
+			log.Error(err, "Failed to build Deployment")
+			return ctrl.Result{}, err
+		}
+		replicas := replicasForDeployment(agentDep, nil, managedAutoscaler)
+		dep.Spec.Replicas = &replicas
+		for _, overlay := range overlays {
+			overlay(dep)
+		}
+		// Secrets, images and GPUs the pods need are checked before they are created
+		if ok, err := r.preflight(ctx, agentDep, dep); err != nil {
+			log.Error(err, "Failed to run preflight checks")
 			return ctrl.Result{}, err
+		} else if !ok {
+			return ctrl.Result{RequeueAfter: preflightRetryInterval}, nil
+		}
+		var obj client.Object = dep
+		if rolloutEnabled(agentDep) {
+			rollout, err := r.rolloutForDeployment(agentDep, dep)
+			if err != nil {
+				log.Error(err, "Failed to build Rollout")
+				return ctrl.Result{}, err
+			}
+			obj = rollout
+		}
+		if err := r.runHooks(ctx, agentDep, hooks.PreApply, "create", obj); err != nil {
+			return r.hookRejected(ctx, agentDep, err)
 		}
+		log.Info("Creating a new "+workload, workload+".Namespace", dep.Namespace, workload+".Name", dep.Name)
+		err = r.Create(ctx, obj)
+		if err != nil {
+			log.Error(err, "Failed to create new "+workload, workload+".Namespace", dep.Namespace, workload+".Name", dep.Name)
+			return ctrl.Result{}, err
+		}
+		r.runHooks(ctx, agentDep, hooks.PostApply, "create", obj)
 		return ctrl.Result{Requeue: true}, nil
 	} else if err != nil {
-		log.Error(err, "Failed to get Deployment")
+		log.Error(err, "Failed to get "+workload)
+		return ctrl.Result{}, err
+	}
+
+	// Size the agent container from its observed usage
+	r.reconcileRecommendations(ctx, agentDep)
+	r.reconcileGPUStatus(ctx, agentDep)
+	r.reconcileProviderStatus(ctx, agentDep)
+
+	// Update the Deployment if the desired state drifted
+	update := r.updateDeployment
+	if rolloutEnabled(agentDep) {
+		update = r.updateRollout
+	}
+	if err := update(ctx, agentDep, deployment, managedAutoscaler, templatePatch, overlays); hooks.IsRejected(err) {
+		return r.hookRejected(ctx, agentDep, err)
+	} else if err != nil {
+		log.Error(err, "Failed to update "+workload, workload+".Namespace", deployment.Namespace, workload+".Name", deployment.Name)
 		return ctrl.Result{}, err
 	}
+	setCapacityCondition(agentDep, capacity)
 
-	// Update the AgentDeployment status
-	if err := r.updateStatus(ctx, agentDep, deployment); err != nil {
+	// Run the on-demand share of spot agents off spot nodes
+	if err := r.reconcileOnDemand(ctx, agentDep, deployment, split); err != nil {
+		log.Error(err, "Failed to reconcile on-demand Deployment")
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	// Remove the Deployment or Rollout the agent switched away from once the other runs it
+	if err := r.handOverWorkload(ctx, agentDep, deployment); err != nil {
+		log.Error(err, "Failed to hand over between Deployment and Rollout")
+		return ctrl.Result{}, err
+	}
+
+	// Let Flagger roll out new versions of the Deployment
+	if err := r.reconcileFlagger(ctx, agentDep, managedAutoscaler); err != nil {
+		log.Error(err, "Failed to reconcile Flagger Canary")
+		return ctrl.Result{}, err
+	}
+
+	// Record the rollout for rollbacks
+	if err := r.reconcileRevisions(ctx, agentDep, template, image, digest); err != nil {
+		log.Error(err, "Failed to reconcile AgentRevisions")
+		return ctrl.Result{}, err
+	}
+
+	// Let the HPA scale the Deployment between the autoscaling bounds
+	if err := r.reconcileHPA(ctx, agentDep, managedAutoscaler, hibernated); err != nil {
+		log.Error(err, "Failed to reconcile HorizontalPodAutoscaler")
+		return ctrl.Result{}, err
+	}
+
+	// Right-size the agent container, or only record recommendations, with a VPA
+	if err := r.reconcileVPA(ctx, agentDep, managedAutoscaler); err != nil {
+		log.Error(err, "Failed to reconcile VerticalPodAutoscaler")
+		return ctrl.Result{}, err
+	}
+
+	// Cover cold starts with warm pods from the referenced AgentPool
+	if err := r.reconcilePoolClaims(ctx, agentDep, deployment); err != nil {
+		log.Error(err, "Failed to reconcile AgentPool claims")
+		return ctrl.Result{}, err
+	}
+
+	// Let new pods take traffic once their warmup hook succeeded
+	retryWarmup, err := r.reconcileWarmup(ctx, agentDep)
+	if err != nil {
+		log.Error(err, "Failed to warm up agent pods")
+		return ctrl.Result{}, err
+	}
+
+	// Let new pods take traffic once the dependencies of spec.dependencies are reachable
+	if err := r.reconcileDependencies(ctx, agentDep); err != nil {
+		log.Error(err, "Failed to check dependencies")
+		return ctrl.Result{}, err
+	}
+
+	// Update the AgentDeployment status from the Deployment serving the agent
+	serving, err := r.flaggerPrimary(ctx, agentDep, deployment)
+	if err != nil {
+		log.Error(err, "Failed to get Flagger primary Deployment")
+		return ctrl.Result{}, err
+	}
+	if err := r.updateStatus(ctx, agentDep, serving, budget); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	after := r.requeueAfter(agentDep)
+	if retryWarmup && (after == 0 || warmupRetryInterval < after) {
+		after = warmupRetryInterval
+	}
+	if conditions.IsTrue(agentDep.Status.Conditions, conditions.CapacityPending) && (after == 0 || capacityRetryInterval < after) {
+		after = capacityRetryInterval
+	}
+	if conditions.IsFalse(agentDep.Status.Conditions, conditions.SessionStoreReady) && (after == 0 || sessionStoreRetryInterval < after) {
+		after = sessionStoreRetryInterval
+	}
+	if conditions.IsFalse(agentDep.Status.Conditions, conditions.DependenciesReady) && (after == 0 || dependencyRetryInterval < after) {
+		after = dependencyRetryInterval
+	}
+	log.V(1).Info("Reconciled", "Phase", agentDep.Status.Phase, "RequeueAfter", after)
+	return ctrl.Result{RequeueAfter: after}, nil
 }
 
-// deploymentForAgentDeployment returns a Deployment object
-func (r *AgentDeploymentReconciler) deploymentForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) *appsv1.Deployment {
+// requeueAfter returns when the AgentDeployment must be reconciled again without an
+// event. Child objects are watched, so only work driven by the clock is scheduled:
+// idle detection, recommendations, token spend and GPU utilization poll Prometheus, and ResyncPeriod adds optional periodic checks.
+func (r *AgentDeploymentReconciler) requeueAfter(ad *agentopsv1alpha1.AgentDeployment) time.Duration {
+	var after time.Duration
+	if (scaleToZeroEnabled(ad) || hibernationEnabled(ad)) && r.Activity != nil {
+		after = idleCheckInterval
+	}
+	if r.Usage != nil && (after == 0 || recommendationInterval < after) {
+		after = recommendationInterval
+	}
+	if r.Tokens != nil && (after == 0 || costRefreshInterval < after) {
+		after = costRefreshInterval
+	}
+	if r.GPUs != nil && gpuPodPattern(ad) != "" && (after == 0 || gpuStatusInterval < after) {
+		after = gpuStatusInterval
+	}
+	if r.Providers != nil && providerProbeEnabled(ad) && (after == 0 || providerProbeInterval < after) {
+		after = providerProbeInterval
+	}
+	if ad.Spec.ImageUpdatePolicy == agentopsv1alpha1.ImageUpdatePolicyTrackTag && r.Images != nil &&
+		(after == 0 || trackTagInterval < after) {
+		after = trackTagInterval
+	}
+	if r.ResyncPeriod > 0 && (after == 0 || r.ResyncPeriod < after) {
+		after = r.ResyncPeriod
+	}
+	return after
+}
+
+// deploymentForAgentDeployment returns a Deployment object. templatePatch is the
+// rendered cluster-wide pod template patch, applied last.
+func (r *AgentDeploymentReconciler) deploymentForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment, templatePatch []byte) (*appsv1.Deployment, error) {
 	labels := labelsForAgentDeployment(ad.Name)
-	replicas := ad.Spec.Replicas
-	if replicas == nil {
-		defaultReplicas := int32(2)
-		replicas = &defaultReplicas
+	liveness, readiness, startup, err := probesForAgentDeployment(ad)
+	if err != nil {
+		return nil, fmt.Errorf("invalid health configuration: %w", err)
 	}
+	replicas := specReplicas(ad)
 
-	// Determine image based on model
-	image := fmt.Sprintf("%s:%s", defaultImage, ad.Spec.Model)
+	volumes, volumeMounts := promptVolumeForAgentDeployment(ad)
+	scratchVolume, scratchMount := scratchVolumeForAgentDeployment(ad)
+	volumes = append(volumes, scratchVolume)
+	volumeMounts = append(volumeMounts, scratchMount)
+
+	// Image, arguments and credentials depend on the provider serving the model
+	agentRT, err := runtimeForAgentDeployment(ad, scratchMount.MountPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provider configuration: %w", err)
+	}
+	volumes = append(volumes, agentRT.Volumes...)
+	volumeMounts = append(volumeMounts, agentRT.Mounts...)
+	secretVolumes, secretMounts, err := secretFileVolumes(ad.Spec.Secrets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets: %w", err)
+	}
+	volumes = append(volumes, secretVolumes...)
+	volumeMounts = append(volumeMounts, secretMounts...)
+	podAnnotations, err := podAnnotationsForAgentDeployment(ad)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets: %w", err)
+	}
+	env := append(secretEnv(ad.Spec.Secrets), weightsEnvForAgentDeployment(ad)...)
+	env = append(env, agentRT.Env...)
+	env = append(env, telemetryEnvForAgentDeployment(ad)...)
+	env = append(env, auditEnvForAgentDeployment(ad)...)
+	env = append(env, sessionStoreEnv(ad)...)
+	env = append(env, ragEnvForAgentDeployment(ad)...)
+	env = append(env, peerEnvForAgentDeployment(ad)...)
 
 	dep := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      ad.Name,
 			Namespace: ad.Namespace,
 			Labels:    labels,
+			Annotations: map[string]string{
+				appliedReplicasAnnotation: strconv.Itoa(int(replicas)),
+			},
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: replicas,
+			Replicas: &replicas,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: labels,
+					Labels:      labels,
+					Annotations: podAnnotations,
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{{
-						Image: image,
-						Name:  "agent",
-						Ports: []corev1.ContainerPort{{
-							ContainerPort: 8080,
-							Name:          "http",
-						}},
-						Resources: ad.Spec.Resources,
-						LivenessProbe: &corev1.Probe{
-							ProbeHandler: corev1.ProbeHandler{
-								HTTPGet: &corev1.HTTPGetAction{
-									Path: "/health",
-									Port: intstr.FromInt(8080),
-								},
-							},
-							InitialDelaySeconds: 30,
-							PeriodSeconds:       10,
-						},
-						ReadinessProbe: &corev1.Probe{
-							ProbeHandler: corev1.ProbeHandler{
-								HTTPGet: &corev1.HTTPGetAction{
-									Path: "/ready",
-									Port: intstr.FromInt(8080),
-								},
-							},
-							InitialDelaySeconds: 10,
-							PeriodSeconds:       5,
-						},
+						Image:           agentRT.Image,
+						Name:            "agent",
+						Args:            agentRT.Args,
+						Ports:           containerPortsForAgentDeployment(ad),
+						Env:             env,
+						Resources:       resourcesForAgentDeployment(ad),
+						LivenessProbe:   liveness,
+						ReadinessProbe:  readiness,
+						StartupProbe:    startup,
+						SecurityContext: securityContextForAgentDeployment(ad),
+						VolumeMounts:    volumeMounts,
+						Lifecycle:       agentRT.Lifecycle,
 					}},
+					Volumes:          volumes,
+					ImagePullSecrets: ad.Spec.ImagePullSecrets,
 				},
 			},
 		},
 	}
 
+	applyLifecycle(ad, dep)
+	applyProgressDeadline(ad, dep)
+	applyWarmupGate(ad, &dep.Spec.Template.Spec)
+	applyDependencyGate(ad, &dep.Spec.Template.Spec)
+	applyCloudIdentity(ad, &dep.Spec.Template)
+	dep.Spec.Template.Spec.PriorityClassName = priorityClassNameForAgentDeployment(ad)
+	dep.Spec.Template.Spec.TopologySpreadConstraints = topologySpreadConstraintsForAgentDeployment(ad)
+	applySpotPlacement(ad, &dep.Spec.Template.Spec)
+
+	if collectorSidecarEnabled(ad) {
+		sidecar, volume, hash, err := collectorSidecar(ad)
+		if err != nil {
+			return nil, fmt.Errorf("invalid telemetry configuration: %w", err)
+		}
+		pod := &dep.Spec.Template
+		pod.Spec.Containers = append(pod.Spec.Containers, sidecar)
+		pod.Spec.Volumes = append(pod.Spec.Volumes, volume)
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[collectorConfigAnnotation] = hash
+	}
+
+	if auditEnabled(ad) {
+		sidecar, volumes, hash, err := auditSidecar(ad)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit configuration: %w", err)
+		}
+		pod := &dep.Spec.Template
+		pod.Spec.Containers = append(pod.Spec.Containers, sidecar)
+		pod.Spec.Volumes = append(pod.Spec.Volumes, volumes...)
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[auditConfigAnnotation] = hash
+	}
+
+	if err := podpatch.Apply(templatePatch, &dep.Spec.Template); err != nil {
+		return nil, fmt.Errorf("invalid pod template patch: %w", err)
+	}
+
 	// Set AgentDeployment instance as the owner
 	controllerutil.SetControllerReference(ad, dep, r.Scheme)
-	return dep
+	return dep, nil
+}
+
+// deploymentOverlay adjusts a built Deployment for state outside the AgentDeployment
+// spec, such as budgets and rate limit policies
+type deploymentOverlay func(*appsv1.Deployment)
+
+// podAnnotationsForAgentDeployment returns the annotations of the agent pod template
+func podAnnotationsForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) (map[string]string, error) {
+	vault, err := vaultAnnotations(ad.Spec.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	annotations := map[string]string{}
+	for k, v := range vault {
+		annotations[k] = v
+	}
+	if version := desiredWeightsVersion(ad); version != "" {
+		annotations[weightsVersionAnnotation] = version
+	}
+	if ad.Status.PromptRevision != 0 {
+		annotations[promptRevisionAnnotation] = strconv.Itoa(int(ad.Status.PromptRevision))
+	}
+	if ad.Spec.EphemeralStorage != nil && ad.Spec.EphemeralStorage.ProtectFromEviction {
+		annotations[safeToEvictAnnotation] = "false"
+	}
+	for k, v := range meshAnnotations(ad) {
+		annotations[k] = v
+	}
+	if len(annotations) == 0 {
+		return nil, nil
+	}
+	return annotations, nil
+}
+
+// updateDeployment brings an existing Deployment in line with the AgentDeployment spec
+func (r *AgentDeploymentReconciler) updateDeployment(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment, managedAutoscaler bool, templatePatch []byte, overlays []deploymentOverlay) error {
+	changed, err := r.syncDeployment(ctx, ad, dep, managedAutoscaler, templatePatch, overlays)
+	if err != nil || !changed {
+		return err
+	}
+
+	r.Log.Info("Updating Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
+	if err := r.runHooks(ctx, ad, hooks.PreApply, "update", dep); err != nil {
+		return err
+	}
+	if err := r.Update(ctx, dep); err != nil {
+		return err
+	}
+	r.runHooks(ctx, ad, hooks.PostApply, "update", dep)
+	return nil
+}
+
+// syncDeployment writes the desired replicas and pod template of the agent into dep
+// and reports whether anything changed
+func (r *AgentDeploymentReconciler) syncDeployment(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment, managedAutoscaler bool, templatePatch []byte, overlays []deploymentOverlay) (bool, error) {
+	desired, err := r.deploymentForAgentDeployment(ad, templatePatch)
+	if err != nil {
+		return false, err
+	}
+	replicas := replicasForDeployment(ad, dep, managedAutoscaler)
+	desired.Spec.Replicas = &replicas
+	for _, overlay := range overlays {
+		overlay(desired)
+	}
+	version, err := r.weightsRolloutVersion(ctx, ad, dep)
+	if err != nil {
+		return false, err
+	}
+	if version != "" {
+		desired.Spec.Template.Annotations[weightsVersionAnnotation] = version
+	}
+	// Recommendations ride along with rollouts: until the template changes for another
+	// reason, the live agent container resources are the desired ones
+	rightSize := autoRightSizeEnabled(ad)
+	if rightSize {
+		keepLiveResources(&desired.Spec.Template.Spec, &dep.Spec.Template.Spec)
+	}
+	// With spec.evaluation a new pod template waits for its EvaluationRun to pass
+	promote, err := r.evaluationGate(ctx, ad, dep, &desired.Spec.Template)
+	if err != nil {
+		return false, err
+	}
+	if !promote {
+		desired.Spec.Template = *dep.Spec.Template.DeepCopy()
+	}
+
+	if equality.Semantic.DeepDerivative(desired.Spec.Template, dep.Spec.Template) &&
+		equality.Semantic.DeepEqual(desired.Spec.Replicas, dep.Spec.Replicas) &&
+		desired.Spec.MinReadySeconds == dep.Spec.MinReadySeconds &&
+		(desired.Spec.ProgressDeadlineSeconds == nil || equality.Semantic.DeepEqual(desired.Spec.ProgressDeadlineSeconds, dep.Spec.ProgressDeadlineSeconds)) &&
+		!probesRemoved(desired, dep) &&
+		!spreadRemoved(desired, dep) &&
+		!placementRemoved(desired, dep) &&
+		!sidecarsRemoved(desired, dep) &&
+		!readinessGatesRemoved(desired, dep) &&
+		dep.Annotations[appliedReplicasAnnotation] == desired.Annotations[appliedReplicasAnnotation] {
+		return false, nil
+	}
+
+	if rightSize && !equality.Semantic.DeepDerivative(desired.Spec.Template, dep.Spec.Template) {
+		applyRecommendations(&desired.Spec.Template.Spec, ad.Status.Recommendations)
+	}
+
+	if dep.Annotations == nil {
+		dep.Annotations = map[string]string{}
+	}
+	dep.Annotations[appliedReplicasAnnotation] = desired.Annotations[appliedReplicasAnnotation]
+	dep.Spec.Replicas = desired.Spec.Replicas
+	dep.Spec.MinReadySeconds = desired.Spec.MinReadySeconds
+	if desired.Spec.ProgressDeadlineSeconds != nil {
+		dep.Spec.ProgressDeadlineSeconds = desired.Spec.ProgressDeadlineSeconds
+	}
+	dep.Spec.Template = desired.Spec.Template
+	return true, nil
 }
 
 // updateStatus updates the AgentDeployment status
-func (r *AgentDeploymentReconciler) updateStatus(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment) error {
+func (r *AgentDeploymentReconciler) updateStatus(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment, budget *agentopsv1alpha1.TokenBudget) error {
+	previous := ad.Status.DeepCopy()
 	ad.Status.Replicas = dep.Status.Replicas
 	ad.Status.ReadyReplicas = dep.Status.ReadyReplicas
 	ad.Status.AvailableReplicas = dep.Status.AvailableReplicas
+	ad.Status.Selector = labels.SelectorFromSet(labelsForAgentDeployment(ad.Name)).String()
+
+	degraded, err := r.setDegradedCondition(ctx, ad)
+	if err != nil {
+		return err
+	}
+	if err := r.setTopologyStatus(ctx, ad); err != nil {
+		return err
+	}
 
 	// Update phase
 	if dep.Status.ReadyReplicas == *dep.Spec.Replicas {
 		ad.Status.Phase = "Running"
 	} else if dep.Status.ReadyReplicas > 0 {
 		ad.Status.Phase = "Scaling"
+	} else if degraded {
+		ad.Status.Phase = "Failed"
 	} else {
 		ad.Status.Phase = "Pending"
 	}
+	setReplicaConditions(ad, dep)
+	resolveRolloutFailed(ad, dep)
+	setBudgetCondition(ad, budget)
+
+	// Weights rolled out through the pod template are active once the template carries them
+	if version := desiredWeightsVersion(ad); version != "" && dep.Spec.Template.Annotations[weightsVersionAnnotation] == version {
+		ad.Status.WeightsVersion = version
+	}
+
+	if err := r.setCost(ctx, ad, dep); err != nil {
+		return err
+	}
 
 	ad.Status.ObservedGeneration = ad.Generation
+	setHealthConditions(ad, dep)
 
-	return r.Status().Update(ctx, ad)
+	if err := patchStatus(ctx, r.Client, ad); err != nil {
+		return err
+	}
+	r.notifyTransitions(ctx, ad, previous)
+	return r.setHealthAnnotation(ctx, ad)
 }
 
-// finalizeAgentDeployment handles cleanup before deletion
-func (r *AgentDeploymentReconciler) finalizeAgentDeployment(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
-	r.Log.Info("Finalizing AgentDeployment", "Name", ad.Name, "Namespace", ad.Namespace)
-	// Add cleanup logic here (e.g., delete external resources)
-	return nil
+// setReplicaConditions derives the Ready, Available and Progressing conditions from the Deployment
+func setReplicaConditions(ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment) {
+	desired := *dep.Spec.Replicas
+	gen := ad.Generation
+	rolledOut := rolloutComplete(dep, desired)
+
+	// Ready waits for the rollout, so a sync is not reported healthy while old
+	// pods still serve
+	switch {
+	case desired == 0 && rolledOut:
+		conditions.Set(&ad.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonScaledToZero,
+			"No replicas desired", gen)
+	case dep.Status.ReadyReplicas >= desired && rolledOut:
+		conditions.Set(&ad.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonReplicasReady,
+			fmt.Sprintf("%d/%d replicas ready", dep.Status.ReadyReplicas, desired), gen)
+	case dep.Status.ReadyReplicas >= desired:
+		conditions.Set(&ad.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonRolloutInProgress,
+			fmt.Sprintf("%d/%d replicas updated", dep.Status.UpdatedReplicas, desired), gen)
+	default:
+		conditions.Set(&ad.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonReplicasUnavailable,
+			fmt.Sprintf("%d/%d replicas ready", dep.Status.ReadyReplicas, desired), gen)
+	}
+
+	if dep.Status.AvailableReplicas > 0 || desired == 0 {
+		conditions.Set(&ad.Status.Conditions, conditions.Available, metav1.ConditionTrue, conditions.ReasonAsExpected,
+			fmt.Sprintf("%d replicas available", dep.Status.AvailableReplicas), gen)
+	} else {
+		conditions.Set(&ad.Status.Conditions, conditions.Available, metav1.ConditionFalse, conditions.ReasonReplicasUnavailable,
+			"No replicas available", gen)
+	}
+
+	if !rolledOut {
+		conditions.Set(&ad.Status.Conditions, conditions.Progressing, metav1.ConditionTrue, conditions.ReasonRolloutInProgress,
+			fmt.Sprintf("%d/%d replicas updated", dep.Status.UpdatedReplicas, desired), gen)
+	} else {
+		conditions.Set(&ad.Status.Conditions, conditions.Progressing, metav1.ConditionFalse, conditions.ReasonRolloutComplete,
+			"Latest pod template is rolled out", gen)
+	}
+}
+
+// selectsAgentDeployment reports whether a policy selector matches the agent; a nil
+// selector matches every agent in the namespace
+func selectsAgentDeployment(selector *metav1.LabelSelector, ad *agentopsv1alpha1.AgentDeployment) bool {
+	if selector == nil {
+		return true
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return s.Matches(labels.Set(ad.Labels))
+}
+
+// agentDeploymentsInNamespace maps a namespaced policy object to every AgentDeployment
+// in its namespace
+func (r *AgentDeploymentReconciler) agentDeploymentsInNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &agentopsv1alpha1.AgentDeploymentList{}
+	if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list AgentDeployments", "Namespace", obj.GetNamespace())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, ad := range list.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: ad.Name, Namespace: ad.Namespace},
+		})
+	}
+	return requests
 }
 
 // labelsForAgentDeployment returns the labels for selecting the resources
 func labelsForAgentDeployment(name string) map[string]string {
 	return map[string]string{
-		"app.kubernetes.io/name":     "agent",
-		"app.kubernetes.io/instance": name,
+		"app.kubernetes.io/name":       "agent",
+		"app.kubernetes.io/instance":   name,
 		"app.kubernetes.io/managed-by": "agentops-controller",
 	}
 }
 
 // SetupWithManager sets up the controller with the Manager
 func (r *AgentDeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&agentopsv1alpha1.AgentDeployment{}).
+	b := ctrl.NewControllerManagedBy(mgr).
+		// Status writes do not trigger a reconcile; spec, label and annotation changes
+		// (e.g. the activator waking the agent) do
+		For(&agentopsv1alpha1.AgentDeployment{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.LabelChangedPredicate{},
+			predicate.AnnotationChangedPredicate{},
+		))).
 		Owns(&appsv1.Deployment{}).
-		Complete(r)
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.Endpoints{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&corev1.Secret{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Owns(&networkingv1.Ingress{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		Owns(&agentopsv1alpha1.AgentTask{}).
+		Owns(&agentopsv1alpha1.AgentRevision{}).
+		Owns(&agentopsv1alpha1.EvaluationRun{}).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(r.agentDeploymentForPod)).
+		Watches(&agentopsv1alpha1.PromptTemplate{}, handler.EnqueueRequestsFromMapFunc(r.agentDeploymentsForPromptTemplate)).
+		Watches(&agentopsv1alpha1.TokenBudget{}, handler.EnqueueRequestsFromMapFunc(r.agentDeploymentsInNamespace)).
+		Watches(&agentopsv1alpha1.RateLimitPolicy{}, handler.EnqueueRequestsFromMapFunc(r.agentDeploymentsInNamespace)).
+		Watches(&agentopsv1alpha1.ModelCache{}, handler.EnqueueRequestsFromMapFunc(r.agentDeploymentsInNamespace)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.agentDeploymentsForPodTemplatePatch)).
+		Watches(&agentopsv1alpha1.AgentOpsConfig{}, handler.EnqueueRequestsFromMapFunc(r.agentDeploymentsForConfig)).
+		Watches(&agentopsv1alpha1.TenantQuota{}, handler.EnqueueRequestsFromMapFunc(r.agentDeploymentsInNamespace)).
+		Watches(&agentopsv1alpha1.ModelPolicy{}, handler.EnqueueRequestsFromMapFunc(r.agentDeploymentsInNamespace)).
+		Watches(&agentopsv1alpha1.ToolServer{}, handler.EnqueueRequestsFromMapFunc(r.agentDeploymentsInNamespace)).
+		Watches(&agentopsv1alpha1.AgentDeployment{}, handler.EnqueueRequestsFromMapFunc(r.agentDeploymentsPeeredWith))
+	if r.Triggers != nil {
+		b = b.WatchesRawSource(&source.Channel{Source: r.Triggers}, &handler.EnqueueRequestForObject{})
+	}
+	return b.WithOptions(r.Options).
+		Complete(debug.Reconciler("AgentDeployment", tracing.Reconciler("AgentDeployment", r)))
 }
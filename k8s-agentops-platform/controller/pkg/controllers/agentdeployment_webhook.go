@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/policy"
+)
+
+// AgentDeploymentValidator rejects AgentDeployments that do not fit the TenantQuotas
+// or ModelPolicies of their namespace, that lack the resources required by the
+// ResourcePolicy of the AgentOpsConfig, or that its AdmissionPolicy denies. The
+// AgentDeployment controller enforces the same quotas and model policies for agents
+// admitted before they existed or while the webhook was down.
+type AgentDeploymentValidator struct {
+	client.Reader
+
+	// Policies are organization-specific policies compiled into the controller,
+	// evaluated before those of the AgentOpsConfig
+	Policies []policy.Evaluator
+}
+
+// +kubebuilder:webhook:path=/validate-agentops-io-v1alpha1-agentdeployment,mutating=false,failurePolicy=fail,sideEffects=None,groups=agentops.io,resources=agentdeployments,verbs=create;update,versions=v1alpha1,name=vagentdeployment.agentops.io,admissionReviewVersions=v1
+
+// SetupWebhookWithManager serves the AgentDeployment validating webhook
+func (v *AgentDeploymentValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&agentopsv1alpha1.AgentDeployment{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate checks the new agent against the policies and quotas
+func (v *AgentDeploymentValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	ad := obj.(*agentopsv1alpha1.AgentDeployment)
+	if err := v.validateModelPolicies(ctx, ad, nil); err != nil {
+		return nil, err
+	}
+	if err := v.validateResources(ctx, ad, nil); err != nil {
+		return nil, err
+	}
+	if err := v.validateAdmissionPolicy(ctx, ad, nil); err != nil {
+		return nil, err
+	}
+	return nil, v.validateQuotas(ctx, ad, nil)
+}
+
+// ValidateUpdate checks only what the change adds: a lowered quota does not block
+// updates that keep or reduce an agent's usage, and a new policy does not block
+// updates that keep the model and provider
+func (v *AgentDeploymentValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	ad := newObj.(*agentopsv1alpha1.AgentDeployment)
+	if !ad.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+	old := oldObj.(*agentopsv1alpha1.AgentDeployment)
+	if err := v.validateModelPolicies(ctx, ad, old); err != nil {
+		return nil, err
+	}
+	if err := v.validateResources(ctx, ad, old); err != nil {
+		return nil, err
+	}
+	if err := v.validateAdmissionPolicy(ctx, ad, old); err != nil {
+		return nil, err
+	}
+	return nil, v.validateQuotas(ctx, ad, old)
+}
+
+// ValidateDelete always allows deletion
+func (v *AgentDeploymentValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateModelPolicies returns an error naming every ModelPolicy that forbids the
+// agent's model or provider. Updates are only checked when they change either.
+func (v *AgentDeploymentValidator) validateModelPolicies(ctx context.Context, ad, old *agentopsv1alpha1.AgentDeployment) error {
+	if old != nil && old.Spec.Model == ad.Spec.Model && old.Spec.Provider == ad.Spec.Provider {
+		return nil
+	}
+	policies := &agentopsv1alpha1.ModelPolicyList{}
+	if err := v.List(ctx, policies, client.InNamespace(ad.Namespace)); err != nil {
+		return err
+	}
+	if violations := modelPolicyViolations(policies.Items, ad); len(violations) > 0 {
+		return fmt.Errorf("%s", modelPolicyMessage(violations))
+	}
+	return nil
+}
+
+// validateQuotas returns an error naming every TenantQuota limit the agent exceeds
+func (v *AgentDeploymentValidator) validateQuotas(ctx context.Context, ad, old *agentopsv1alpha1.AgentDeployment) error {
+	quotas := &agentopsv1alpha1.TenantQuotaList{}
+	if err := v.List(ctx, quotas, client.InNamespace(ad.Namespace)); err != nil {
+		return err
+	}
+	if len(quotas.Items) == 0 {
+		return nil
+	}
+	list := &agentopsv1alpha1.AgentDeploymentList{}
+	if err := v.List(ctx, list, client.InNamespace(ad.Namespace)); err != nil {
+		return err
+	}
+
+	usage := quotaUsage(ad)
+	total := usage
+	for i := range list.Items {
+		other := &list.Items[i]
+		if other.Name != ad.Name && other.DeletionTimestamp.IsZero() {
+			total = addUsage(total, quotaUsage(other))
+		}
+	}
+	grown := usage
+	if old != nil {
+		before := quotaUsage(old)
+		grown = agentopsv1alpha1.TenantQuotaUsage{
+			Replicas: usage.Replicas - before.Replicas,
+			GPUs:     usage.GPUs - before.GPUs,
+		}
+	}
+
+	var messages []string
+	for i := range quotas.Items {
+		quota := &quotas.Items[i]
+		violations := quotaViolations(quota, total, grown)
+		if (old == nil || old.Spec.Model != ad.Spec.Model) && !modelAllowedByQuota(quota, ad.Spec.Model) {
+			violations = append(violations, fmt.Sprintf("model %s is not allowed", ad.Spec.Model))
+		}
+		if len(violations) > 0 {
+			messages = append(messages, quotaMessage(quota, violations))
+		}
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,419 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/catalog"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/feedback"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	// experimentRefreshInterval is how often the metrics of a running experiment are read
+	experimentRefreshInterval = 5 * time.Minute
+
+	// experimentRetryInterval is how often an experiment waiting for its variants checks again
+	experimentRetryInterval = time.Minute
+
+	defaultExperimentMinRequests = int64(100)
+	defaultFeedbackTimeout       = 10 * time.Second
+)
+
+// ExperimentMetrics reads the traffic, latency and token usage of experiment variants
+type ExperimentMetrics interface {
+	Requests(ctx context.Context, namespace, service string, window time.Duration) (float64, error)
+	LatencyP95(ctx context.Context, namespace, service string, window time.Duration) (float64, error)
+	TokensByModel(ctx context.Context, namespace string, agents []string, window time.Duration) (map[string]float64, error)
+}
+
+// FeedbackSource reads user feedback scores of experiment variants from a webhook
+type FeedbackSource interface {
+	Score(ctx context.Context, url string, req feedback.Request) (*feedback.Score, error)
+}
+
+// AgentExperimentReconciler reconciles an AgentExperiment object
+type AgentExperimentReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Metrics reads the variants' metrics; nil completes experiments without a winner
+	Metrics ExperimentMetrics
+
+	// Feedback reads spec.feedback webhooks; nil leaves feedback scores out
+	Feedback FeedbackSource
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=agentexperiments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=agentexperiments/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentroutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentrevisions,verbs=get;list;watch
+
+// Reconcile splits the route's traffic between the variants, reads their metrics
+// while the experiment runs and names the winner once its duration has passed
+func (r *AgentExperimentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("agentexperiment", req.NamespacedName)
+
+	exp := &agentopsv1alpha1.AgentExperiment{}
+	if err := r.Get(ctx, req.NamespacedName, exp); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get AgentExperiment")
+		return ctrl.Result{}, err
+	}
+	gen := exp.Generation
+	exp.Status.ObservedGeneration = gen
+
+	// Variants running a revision get an AgentDeployment of their own
+	for _, v := range exp.Spec.Variants {
+		if v.Revision == 0 {
+			continue
+		}
+		missing, err := r.reconcileRevisionVariant(ctx, exp, v)
+		if err != nil {
+			log.Error(err, "Failed to reconcile revision variant", "Variant", v.Name)
+			return ctrl.Result{}, err
+		}
+		if missing != "" {
+			exp.Status.Phase = agentopsv1alpha1.AgentExperimentPending
+			exp.Status.Message = missing
+			conditions.Set(&exp.Status.Conditions, conditions.Complete, metav1.ConditionFalse, conditions.ReasonInvalidSpec, missing, gen)
+			return ctrl.Result{RequeueAfter: experimentRetryInterval}, patchStatus(ctx, r.Client, exp)
+		}
+	}
+
+	if err := r.reconcileExperimentRoute(ctx, exp); err != nil {
+		log.Error(err, "Failed to reconcile AgentRoute")
+		return ctrl.Result{}, err
+	}
+	if exp.Status.Phase == agentopsv1alpha1.AgentExperimentCompleted {
+		return ctrl.Result{}, nil
+	}
+
+	now := metav1.Now()
+	if exp.Status.StartTime == nil {
+		exp.Status.StartTime = &now
+	}
+	exp.Status.Phase = agentopsv1alpha1.AgentExperimentRunning
+	end := exp.Status.StartTime.Add(exp.Spec.Duration.Duration)
+	finished := !now.Time.Before(end)
+
+	if updated := exp.Status.LastUpdateTime; finished || updated == nil || now.Sub(updated.Time) >= experimentRefreshInterval {
+		r.readVariantMetrics(ctx, exp)
+		exp.Status.LastUpdateTime = &now
+	}
+
+	if !finished {
+		exp.Status.Message = fmt.Sprintf("Collecting metrics until %s", end.UTC().Format(time.RFC3339))
+		conditions.Set(&exp.Status.Conditions, conditions.Complete, metav1.ConditionFalse, conditions.ReasonExperimentRunning,
+			exp.Status.Message, gen)
+		after := time.Until(end)
+		if after > experimentRefreshInterval {
+			after = experimentRefreshInterval
+		}
+		return ctrl.Result{RequeueAfter: after}, patchStatus(ctx, r.Client, exp)
+	}
+
+	exp.Status.Phase = agentopsv1alpha1.AgentExperimentCompleted
+	exp.Status.CompletionTime = &now
+	winner, reason := experimentWinner(exp)
+	exp.Status.Winner = winner
+	exp.Status.Message = reason
+	if winner != "" {
+		conditions.Set(&exp.Status.Conditions, conditions.Complete, metav1.ConditionTrue, conditions.ReasonWinnerFound, reason, gen)
+	} else {
+		conditions.Set(&exp.Status.Conditions, conditions.Complete, metav1.ConditionTrue, conditions.ReasonInconclusive, reason, gen)
+	}
+	log.Info("AgentExperiment completed", "Winner", winner, "Reason", reason)
+	if err := patchStatus(ctx, r.Client, exp); err != nil {
+		return ctrl.Result{}, err
+	}
+	// With promoteWinner the route now sends all traffic to the winner
+	return ctrl.Result{}, r.reconcileExperimentRoute(ctx, exp)
+}
+
+// variantAgent returns the AgentDeployment serving a variant
+func variantAgent(exp *agentopsv1alpha1.AgentExperiment, v agentopsv1alpha1.ExperimentVariant) string {
+	if v.Revision != 0 {
+		return exp.Name + "-" + v.Name
+	}
+	return v.AgentDeployment
+}
+
+// reconcileRevisionVariant creates the AgentDeployment running the revision of a
+// variant. It returns why the revision cannot be run, or an empty string.
+func (r *AgentExperimentReconciler) reconcileRevisionVariant(ctx context.Context, exp *agentopsv1alpha1.AgentExperiment, v agentopsv1alpha1.ExperimentVariant) (string, error) {
+	name := variantAgent(exp, v)
+	ad := &agentopsv1alpha1.AgentDeployment{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: exp.Namespace}, ad)
+	if err == nil {
+		if !metav1.IsControlledBy(ad, exp) {
+			return fmt.Sprintf("AgentDeployment %s exists and is not owned by the experiment", name), nil
+		}
+		return "", r.pinVariantImage(ctx, exp, v, ad)
+	} else if !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	list := &agentopsv1alpha1.AgentRevisionList{}
+	if err := r.List(ctx, list, client.InNamespace(exp.Namespace), client.MatchingLabels(labelsForAgentDeployment(v.AgentDeployment))); err != nil {
+		return "", err
+	}
+	var rev *agentopsv1alpha1.AgentRevision
+	for i := range list.Items {
+		if list.Items[i].Spec.Revision == v.Revision {
+			rev = &list.Items[i]
+		}
+	}
+	if rev == nil {
+		return fmt.Sprintf("AgentDeployment %s has no revision %d", v.AgentDeployment, v.Revision), nil
+	}
+	spec, err := revisionSpec(rev)
+	if err != nil {
+		return "", err
+	}
+	// The copy only serves the experiment route
+	spec.Ingress = nil
+	spec.ProgressiveDelivery = nil
+	spec.Evaluation = nil
+	spec.Shadow = nil
+	if spec.Mesh != nil {
+		spec.Mesh.Canary = nil
+	}
+
+	ad = &agentopsv1alpha1.AgentDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: exp.Namespace,
+			Labels:    map[string]string{"agentops.io/experiment": exp.Name},
+		},
+		Spec: *spec,
+	}
+	// Pinned images wait for the digest of the revision before the first reconcile
+	if spec.ImageUpdatePolicy == agentopsv1alpha1.ImageUpdatePolicyPinned {
+		ad.Annotations = map[string]string{agentopsv1alpha1.PausedAnnotation: "true"}
+	}
+	if err := controllerutil.SetControllerReference(exp, ad, r.Scheme); err != nil {
+		return "", err
+	}
+	r.Log.Info("Creating AgentDeployment for revision variant", "AgentDeployment.Namespace", ad.Namespace, "AgentDeployment.Name", ad.Name,
+		"Revision", v.Revision)
+	if err := r.Create(ctx, ad); err != nil {
+		return "", err
+	}
+	return "", r.pinVariantImage(ctx, exp, v, ad)
+}
+
+// pinVariantImage records the image digest of the revision in the status of a
+// paused revision variant and then unpauses it, so it runs the revision's image
+// rather than the one its tag points to now
+func (r *AgentExperimentReconciler) pinVariantImage(ctx context.Context, exp *agentopsv1alpha1.AgentExperiment, v agentopsv1alpha1.ExperimentVariant, ad *agentopsv1alpha1.AgentDeployment) error {
+	if ad.Annotations[agentopsv1alpha1.PausedAnnotation] != "true" {
+		return nil
+	}
+	list := &agentopsv1alpha1.AgentRevisionList{}
+	if err := r.List(ctx, list, client.InNamespace(exp.Namespace), client.MatchingLabels(labelsForAgentDeployment(v.AgentDeployment))); err != nil {
+		return err
+	}
+	for _, rev := range list.Items {
+		if rev.Spec.Revision == v.Revision && rev.Spec.ImageDigest != "" {
+			ad.Status.Image, ad.Status.ImageDigest = rev.Spec.Image, rev.Spec.ImageDigest
+			if err := patchStatus(ctx, r.Client, ad); err != nil {
+				return err
+			}
+		}
+	}
+	delete(ad.Annotations, agentopsv1alpha1.PausedAnnotation)
+	return r.Update(ctx, ad)
+}
+
+// reconcileExperimentRoute renders the AgentRoute splitting the traffic between the
+// variants, or sending it all to the winner with spec.promoteWinner
+func (r *AgentExperimentReconciler) reconcileExperimentRoute(ctx context.Context, exp *agentopsv1alpha1.AgentExperiment) error {
+	var backends []agentopsv1alpha1.AgentRouteBackend
+	for _, v := range exp.Spec.Variants {
+		weight := v.Weight
+		if exp.Spec.PromoteWinner && exp.Status.Winner != "" {
+			weight = 0
+			if v.Name == exp.Status.Winner {
+				weight = 100
+			}
+		}
+		backends = append(backends, agentopsv1alpha1.AgentRouteBackend{Name: variantAgent(exp, v), Weight: weight})
+	}
+
+	route := &agentopsv1alpha1.AgentRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: exp.Name, Namespace: exp.Namespace},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, route, func() error {
+		if !route.CreationTimestamp.IsZero() && !metav1.IsControlledBy(route, exp) {
+			return fmt.Errorf("AgentRoute %s exists and is not owned by the experiment", route.Name)
+		}
+		route.Labels = map[string]string{"agentops.io/experiment": exp.Name}
+		route.Spec = agentopsv1alpha1.AgentRouteSpec{
+			Mode:       exp.Spec.Route.Mode,
+			ParentRefs: exp.Spec.Route.ParentRefs,
+			Hostnames:  exp.Spec.Route.Hostnames,
+			Rules: []agentopsv1alpha1.AgentRouteRule{{
+				PathPrefix: exp.Spec.Route.PathPrefix,
+				Backends:   backends,
+			}},
+		}
+		return controllerutil.SetControllerReference(exp, route, r.Scheme)
+	})
+	return err
+}
+
+// readVariantMetrics records the requests, latency, LLM API spend and feedback of
+// every variant since the experiment started. Metrics that cannot be read leave the
+// previous values in place.
+func (r *AgentExperimentReconciler) readVariantMetrics(ctx context.Context, exp *agentopsv1alpha1.AgentExperiment) {
+	window := time.Since(exp.Status.StartTime.Time)
+	previous := map[string]agentopsv1alpha1.ExperimentVariantStatus{}
+	for _, s := range exp.Status.Variants {
+		previous[s.Name] = s
+	}
+
+	statuses := make([]agentopsv1alpha1.ExperimentVariantStatus, 0, len(exp.Spec.Variants))
+	for _, v := range exp.Spec.Variants {
+		agent := variantAgent(exp, v)
+		s := previous[v.Name]
+		s.Name, s.AgentDeployment = v.Name, agent
+		if r.Metrics != nil {
+			r.readTrafficMetrics(ctx, exp.Namespace, agent, window, &s)
+		}
+		if exp.Spec.Feedback != nil && r.Feedback != nil {
+			timeout := defaultFeedbackTimeout
+			if exp.Spec.Feedback.Timeout != nil {
+				timeout = exp.Spec.Feedback.Timeout.Duration
+			}
+			callCtx, cancel := context.WithTimeout(ctx, timeout)
+			score, err := r.Feedback.Score(callCtx, exp.Spec.Feedback.URL, feedback.Request{
+				Experiment:      exp.Name,
+				Namespace:       exp.Namespace,
+				Variant:         v.Name,
+				AgentDeployment: agent,
+				Since:           exp.Status.StartTime.Time,
+			})
+			cancel()
+			if err != nil {
+				r.Log.Error(err, "Failed to read feedback", "AgentExperiment", exp.Name, "Variant", v.Name)
+			} else {
+				s.FeedbackScore = fmt.Sprintf("%.3f", score.Score)
+				s.FeedbackCount = score.Count
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	exp.Status.Variants = statuses
+}
+
+// readTrafficMetrics reads the requests, p95 latency and LLM API spend of an agent
+func (r *AgentExperimentReconciler) readTrafficMetrics(ctx context.Context, namespace, agent string, window time.Duration, s *agentopsv1alpha1.ExperimentVariantStatus) {
+	requests, err := r.Metrics.Requests(ctx, namespace, agent, window)
+	if err != nil {
+		r.Log.Error(err, "Failed to read requests", "AgentDeployment", agent, "Namespace", namespace)
+		return
+	}
+	s.Requests = int64(requests)
+
+	latency, err := r.Metrics.LatencyP95(ctx, namespace, agent, window)
+	if err != nil {
+		r.Log.Error(err, "Failed to read latency", "AgentDeployment", agent, "Namespace", namespace)
+	} else if !math.IsNaN(latency) {
+		s.LatencyP95 = &metav1.Duration{Duration: time.Duration(latency * float64(time.Second)).Round(time.Millisecond)}
+	}
+
+	tokensByModel, err := r.Metrics.TokensByModel(ctx, namespace, []string{agent}, window)
+	if err != nil {
+		r.Log.Error(err, "Failed to read token usage", "AgentDeployment", agent, "Namespace", namespace)
+	} else if requests > 0 {
+		var spend float64
+		for model, n := range tokensByModel {
+			spend += catalog.Cost(model, n)
+		}
+		s.CostPer1kRequests = usdQuantity(spend / requests * 1000)
+	}
+}
+
+// experimentWinner returns the variant that did best on the objective and why, or
+// an empty winner and why there is none
+func experimentWinner(exp *agentopsv1alpha1.AgentExperiment) (string, string) {
+	minRequests := defaultExperimentMinRequests
+	if exp.Spec.MinRequests != nil {
+		minRequests = *exp.Spec.MinRequests
+	}
+	if len(exp.Status.Variants) != len(exp.Spec.Variants) {
+		return "", "No metrics were read for the variants"
+	}
+	objective := exp.Spec.Objective
+	if objective == "" {
+		objective = agentopsv1alpha1.ExperimentObjectiveLatency
+	}
+
+	// Lower is better for latency and cost, higher for feedback
+	values := make([]float64, len(exp.Status.Variants))
+	for i, s := range exp.Status.Variants {
+		if s.Requests < minRequests {
+			return "", fmt.Sprintf("Variant %s served %d requests, fewer than %d", s.Name, s.Requests, minRequests)
+		}
+		var ok bool
+		switch objective {
+		case agentopsv1alpha1.ExperimentObjectiveLatency:
+			if ok = s.LatencyP95 != nil; ok {
+				values[i] = -s.LatencyP95.Seconds()
+			}
+		case agentopsv1alpha1.ExperimentObjectiveCost:
+			if ok = s.CostPer1kRequests != nil; ok {
+				values[i] = -s.CostPer1kRequests.AsApproximateFloat64()
+			}
+		case agentopsv1alpha1.ExperimentObjectiveFeedback:
+			_, err := fmt.Sscanf(s.FeedbackScore, "%g", &values[i])
+			ok = err == nil && s.FeedbackCount > 0
+		}
+		if !ok {
+			return "", fmt.Sprintf("No %s metrics for variant %s", objective, s.Name)
+		}
+	}
+
+	a, b := exp.Status.Variants[0], exp.Status.Variants[1]
+	switch {
+	case values[0] > values[1]:
+		return a.Name, fmt.Sprintf("Variant %s did better than %s on %s", a.Name, b.Name, objective)
+	case values[1] > values[0]:
+		return b.Name, fmt.Sprintf("Variant %s did better than %s on %s", b.Name, a.Name, objective)
+	}
+	return "", fmt.Sprintf("Variants %s and %s tied on %s", a.Name, b.Name, objective)
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *AgentExperimentReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.AgentExperiment{}).
+		Owns(&agentopsv1alpha1.AgentRoute{}).
+		Owns(&agentopsv1alpha1.AgentDeployment{}).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("AgentExperiment", tracing.Reconciler("AgentExperiment", r)))
+}
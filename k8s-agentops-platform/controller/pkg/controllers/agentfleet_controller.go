@@ -0,0 +1,336 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	// agentFleetFinalizer removes the AgentDeployments of member clusters before the
+	// fleet is deleted
+	agentFleetFinalizer = "agentops.io/fleet"
+	// fleetLabel and fleetNamespaceLabel mark the AgentDeployments a fleet manages in
+	// member clusters
+	fleetLabel          = "agentops.io/fleet"
+	fleetNamespaceLabel = "agentops.io/fleet-namespace"
+	// fleetSyncInterval is how often member clusters are synced; their AgentDeployments
+	// are not watched
+	fleetSyncInterval = time.Minute
+	// defaultKubeconfigKey is the key Cluster API stores kubeconfigs under
+	defaultKubeconfigKey = "value"
+)
+
+// ClusterClients returns clients of member clusters from their kubeconfigs
+type ClusterClients interface {
+	For(key string, kubeconfig []byte) (client.Client, error)
+}
+
+// AgentFleetReconciler reconciles an AgentFleet object
+type AgentFleetReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Clusters connects to the member clusters
+	Clusters ClusterClients
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=agentfleets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=agentfleets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentfleets/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile creates or updates the AgentDeployment of the fleet in every member
+// cluster, removes it from clusters that left the fleet, aggregates their status and
+// fails degraded clusters over
+func (r *AgentFleetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("agentfleet", req.NamespacedName)
+
+	fleet := &agentopsv1alpha1.AgentFleet{}
+	if err := r.Get(ctx, req.NamespacedName, fleet); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get AgentFleet")
+		return ctrl.Result{}, err
+	}
+	gen := fleet.Generation
+
+	deleting := !fleet.DeletionTimestamp.IsZero()
+	if deleting && !controllerutil.ContainsFinalizer(fleet, agentFleetFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	if !deleting && controllerutil.AddFinalizer(fleet, agentFleetFinalizer) {
+		if err := r.Update(ctx, fleet); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	previous := map[string]agentopsv1alpha1.FleetClusterStatus{}
+	for _, s := range fleet.Status.Clusters {
+		previous[s.Name] = s
+	}
+	var statuses []agentopsv1alpha1.FleetClusterStatus
+	members := map[string]bool{}
+	if !deleting {
+		extra := failoverExtraReplicas(fleet, previous)
+		for _, cluster := range fleet.Spec.Clusters {
+			members[cluster.Name] = true
+			status := r.syncCluster(ctx, fleet, cluster, extra[cluster.Name])
+			if !status.Synced && status.LastSyncTime == nil {
+				status.LastSyncTime = previous[cluster.Name].LastSyncTime
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	// Clusters that left the fleet, or all of them when it is deleted
+	for _, s := range fleet.Status.Clusters {
+		if members[s.Name] {
+			continue
+		}
+		if err := r.removeFromCluster(ctx, fleet, s); err != nil {
+			log.Error(err, "Failed to remove AgentDeployment from member cluster", "Cluster", s.Name)
+			s.Synced = false
+			s.Message = fmt.Sprintf("Leaving the fleet: %v", err)
+			statuses = append(statuses, s)
+		}
+	}
+
+	if deleting {
+		if len(statuses) > 0 {
+			fleet.Status.Clusters = statuses
+			if err := patchStatus(ctx, r.Client, fleet); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: fleetSyncInterval}, nil
+		}
+		controllerutil.RemoveFinalizer(fleet, agentFleetFinalizer)
+		return ctrl.Result{}, r.Update(ctx, fleet)
+	}
+
+	fleet.Status.Clusters = statuses
+	fleet.Status.ObservedGeneration = gen
+	transitions := updateFailover(fleet, previous, metav1.Now())
+	events := fleet.Status.FailoverEvents
+	for _, e := range events[max(0, len(events)-transitions):] {
+		log.Info("Member cluster "+e.Type, "Cluster", e.Cluster, "Message", e.Message)
+	}
+	routingErr := r.reconcileFailoverRouting(ctx, fleet)
+	if routingErr != nil {
+		log.Error(routingErr, "Failed to route fleet traffic")
+	}
+	fleet.Status.ReadyClusters, fleet.Status.Replicas, fleet.Status.ReadyReplicas = 0, 0, 0
+	var unavailable []string
+	for _, s := range statuses {
+		fleet.Status.Replicas += s.Replicas
+		fleet.Status.ReadyReplicas += s.ReadyReplicas
+		if s.Synced && s.Message == "" {
+			fleet.Status.ReadyClusters++
+		} else {
+			unavailable = append(unavailable, s.Name)
+		}
+	}
+	if len(unavailable) == 0 {
+		conditions.Set(&fleet.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonClustersReady,
+			fmt.Sprintf("%d clusters ready", fleet.Status.ReadyClusters), gen)
+	} else {
+		conditions.Set(&fleet.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonClustersUnavailable,
+			fmt.Sprintf("Clusters not ready: %s", strings.Join(unavailable, ", ")), gen)
+	}
+	if err := patchStatus(ctx, r.Client, fleet); err != nil {
+		return ctrl.Result{}, err
+	}
+	if routingErr != nil {
+		return ctrl.Result{}, routingErr
+	}
+	if transitions > 0 && fleet.Spec.Failover.ScaleUp {
+		// Move the capacity of the clusters that failed over or back right away
+		return ctrl.Result{Requeue: true}, nil
+	}
+	return ctrl.Result{RequeueAfter: fleetSyncInterval}, nil
+}
+
+// memberClient returns a client of the member cluster whose kubeconfig ref names
+func (r *AgentFleetReconciler) memberClient(ctx context.Context, fleet *agentopsv1alpha1.AgentFleet, ref agentopsv1alpha1.KubeconfigSecretReference) (client.Client, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: fleet.Namespace}, secret); err != nil {
+		return nil, err
+	}
+	key := ref.Key
+	if key == "" {
+		key = defaultKubeconfigKey
+	}
+	kubeconfig, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no key %s", ref.Name, key)
+	}
+	return r.Clusters.For(fleet.Namespace+"/"+ref.Name, kubeconfig)
+}
+
+// memberAgentDeployment returns the AgentDeployment the fleet runs in a cluster
+func memberAgentDeployment(fleet *agentopsv1alpha1.AgentFleet, cluster agentopsv1alpha1.FleetCluster) (*agentopsv1alpha1.AgentDeployment, error) {
+	spec := fleet.Spec.Template.Spec.DeepCopy()
+	if cluster.Overrides != nil && len(cluster.Overrides.Raw) > 0 {
+		original, err := json.Marshal(spec)
+		if err != nil {
+			return nil, err
+		}
+		patched, err := strategicpatch.StrategicMergePatch(original, cluster.Overrides.Raw, agentopsv1alpha1.AgentDeploymentSpec{})
+		if err != nil {
+			return nil, fmt.Errorf("invalid overrides: %w", err)
+		}
+		spec = &agentopsv1alpha1.AgentDeploymentSpec{}
+		if err := json.Unmarshal(patched, spec); err != nil {
+			return nil, fmt.Errorf("invalid overrides: %w", err)
+		}
+	}
+
+	namespace := cluster.Namespace
+	if namespace == "" {
+		namespace = fleet.Namespace
+	}
+	labels := map[string]string{}
+	for k, v := range fleet.Spec.Template.Metadata.Labels {
+		labels[k] = v
+	}
+	labels[fleetLabel] = fleet.Name
+	labels[fleetNamespaceLabel] = fleet.Namespace
+	return &agentopsv1alpha1.AgentDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fleet.Name,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: fleet.Spec.Template.Metadata.Annotations,
+		},
+		Spec: *spec,
+	}, nil
+}
+
+// managedByFleet reports whether an AgentDeployment of a member cluster belongs to
+// the fleet
+func managedByFleet(ad *agentopsv1alpha1.AgentDeployment, fleet *agentopsv1alpha1.AgentFleet) bool {
+	return ad.Labels[fleetLabel] == fleet.Name && ad.Labels[fleetNamespaceLabel] == fleet.Namespace
+}
+
+// syncCluster creates or updates the AgentDeployment of a member cluster, with extra
+// replicas taken over from failed-over clusters, and returns its state
+func (r *AgentFleetReconciler) syncCluster(ctx context.Context, fleet *agentopsv1alpha1.AgentFleet, cluster agentopsv1alpha1.FleetCluster, extra int32) agentopsv1alpha1.FleetClusterStatus {
+	ref := cluster.KubeconfigSecretRef
+	status := agentopsv1alpha1.FleetClusterStatus{Name: cluster.Name, KubeconfigSecretRef: &ref}
+	desired, err := memberAgentDeployment(fleet, cluster)
+	if err != nil {
+		status.Message = err.Error()
+		return status
+	}
+	if extra > 0 {
+		scaleUpAgentDeployment(desired, extra)
+		status.ExtraReplicas = extra
+	}
+	status.Namespace = desired.Namespace
+	member, err := r.memberClient(ctx, fleet, ref)
+	if err != nil {
+		status.Message = fmt.Sprintf("Cannot connect: %v", err)
+		return status
+	}
+
+	ad := &agentopsv1alpha1.AgentDeployment{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: desired.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, member, ad, func() error {
+		if ad.ResourceVersion != "" && !managedByFleet(ad, fleet) {
+			return fmt.Errorf("agentdeployment %s/%s exists and is not managed by the fleet", ad.Namespace, ad.Name)
+		}
+		if ad.Labels == nil {
+			ad.Labels = map[string]string{}
+		}
+		for k, v := range desired.Labels {
+			ad.Labels[k] = v
+		}
+		if len(desired.Annotations) > 0 && ad.Annotations == nil {
+			ad.Annotations = map[string]string{}
+		}
+		for k, v := range desired.Annotations {
+			ad.Annotations[k] = v
+		}
+		ad.Spec = desired.Spec
+		return nil
+	})
+	if err != nil {
+		status.Message = err.Error()
+		return status
+	}
+
+	now := metav1.Now()
+	status.Synced = true
+	status.LastSyncTime = &now
+	status.Phase = ad.Status.Phase
+	status.Replicas = ad.Status.Replicas
+	status.ReadyReplicas = ad.Status.ReadyReplicas
+	if !conditions.IsTrue(ad.Status.Conditions, conditions.Ready) {
+		status.Message = "AgentDeployment is not ready"
+		if c := conditions.Get(ad.Status.Conditions, conditions.Ready); c != nil && c.Message != "" {
+			status.Message = c.Message
+		}
+	}
+	if conditions.IsTrue(ad.Status.Conditions, conditions.Degraded) {
+		status.Degraded = true
+		status.Message = fmt.Sprintf("Degraded: %s", conditions.Get(ad.Status.Conditions, conditions.Degraded).Message)
+	}
+	return status
+}
+
+// removeFromCluster deletes the fleet's AgentDeployment from a cluster. Clusters
+// whose kubeconfig is gone cannot be reached anymore and are dropped.
+func (r *AgentFleetReconciler) removeFromCluster(ctx context.Context, fleet *agentopsv1alpha1.AgentFleet, status agentopsv1alpha1.FleetClusterStatus) error {
+	if status.KubeconfigSecretRef == nil || status.Namespace == "" {
+		return nil
+	}
+	member, err := r.memberClient(ctx, fleet, *status.KubeconfigSecretRef)
+	if errors.IsNotFound(err) {
+		r.Log.Info("Kubeconfig of a member cluster is gone; not removing its AgentDeployment", "Cluster", status.Name)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	ad := &agentopsv1alpha1.AgentDeployment{}
+	err = member.Get(ctx, types.NamespacedName{Name: fleet.Name, Namespace: status.Namespace}, ad)
+	if errors.IsNotFound(err) || (err == nil && !managedByFleet(ad, fleet)) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	r.Log.Info("Deleting AgentDeployment of member cluster", "Cluster", status.Name, "AgentDeployment.Namespace", ad.Namespace)
+	return client.IgnoreNotFound(member.Delete(ctx, ad))
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *AgentFleetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.AgentFleet{}).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("AgentFleet", tracing.Reconciler("AgentFleet", r)))
+}
@@ -0,0 +1,334 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	jobInputMountPath  = "/etc/agentops/input"
+	jobInputFile       = "input.txt"
+	jobInputVolumeName = "input"
+	defaultJobBackoff  = int32(2)
+)
+
+// jobResult is the JSON document the agent writes to its termination message when
+// a run finishes
+type jobResult struct {
+	Output string `json:"output"`
+	Tokens int64  `json:"tokens"`
+}
+
+// AgentJobReconciler reconciles an AgentJob object
+type AgentJobReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=agentjobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=agentjobs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=prompttemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile runs the AgentJob as a Kubernetes Job and mirrors its progress
+func (r *AgentJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("agentjob", req.NamespacedName)
+
+	aj := &agentopsv1alpha1.AgentJob{}
+	if err := r.Get(ctx, req.NamespacedName, aj); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get AgentJob")
+		return ctrl.Result{}, err
+	}
+
+	// Finished AgentJobs are deleted after their TTL by the GarbageCollector
+	if aj.Finished() {
+		return ctrl.Result{}, nil
+	}
+
+	job := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: aj.Name, Namespace: aj.Namespace}, job)
+	if err != nil && errors.IsNotFound(err) {
+		if err := r.reconcileJobPrompt(ctx, aj); err != nil {
+			log.Error(err, "Failed to reconcile prompt ConfigMap")
+			return ctrl.Result{}, err
+		}
+		built, err := r.jobForAgentJob(aj)
+		if err != nil {
+			// The spec cannot be run; fail the AgentJob instead of retrying it
+			log.Error(err, "Invalid AgentJob")
+			now := metav1.Now()
+			aj.Status.Phase = agentopsv1alpha1.AgentJobFailed
+			aj.Status.CompletionTime = &now
+			aj.Status.Message = err.Error()
+			conditions.Set(&aj.Status.Conditions, conditions.Complete, metav1.ConditionFalse, conditions.ReasonInvalidSpec,
+				aj.Status.Message, aj.Generation)
+			aj.Status.ObservedGeneration = aj.Generation
+			return ctrl.Result{}, patchStatus(ctx, r.Client, aj)
+		}
+		job = built
+		log.Info("Creating a new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		if err := r.Create(ctx, job); err != nil {
+			log.Error(err, "Failed to create new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		log.Error(err, "Failed to get Job")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.updateStatus(ctx, aj, job); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// jobForAgentJob returns the Job running the agent to completion
+func (r *AgentJobReconciler) jobForAgentJob(aj *agentopsv1alpha1.AgentJob) (*batchv1.Job, error) {
+	labels := map[string]string{
+		"app.kubernetes.io/name":       "agent-job",
+		"app.kubernetes.io/instance":   aj.Name,
+		"app.kubernetes.io/managed-by": "agentops-controller",
+	}
+
+	image := aj.Spec.Image
+	if image == "" {
+		image = fmt.Sprintf("%s:%s", defaultImage, aj.Spec.Model)
+	}
+
+	env := append(secretEnv(aj.Spec.Secrets),
+		corev1.EnvVar{Name: "AGENTOPS_MODE", Value: "job"},
+		corev1.EnvVar{Name: "AGENTOPS_MODEL", Value: aj.Spec.Model},
+	)
+	if aj.Spec.Output != nil && aj.Spec.Output.Location != "" {
+		env = append(env, corev1.EnvVar{Name: "AGENTOPS_OUTPUT_URI", Value: aj.Spec.Output.Location})
+	}
+
+	volumes, mounts, err := secretFileVolumes(aj.Spec.Secrets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets: %w", err)
+	}
+	if in := aj.Spec.Input; in != nil {
+		switch {
+		case in.ConfigMapRef != nil:
+			volumes = append(volumes, corev1.Volume{
+				Name: jobInputVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: in.ConfigMapRef.LocalObjectReference,
+						Items:                []corev1.KeyToPath{{Key: in.ConfigMapRef.Key, Path: jobInputFile}},
+					},
+				},
+			})
+			mounts = append(mounts, corev1.VolumeMount{Name: jobInputVolumeName, MountPath: jobInputMountPath, ReadOnly: true})
+			env = append(env, corev1.EnvVar{Name: "AGENTOPS_INPUT_FILE", Value: jobInputMountPath + "/" + jobInputFile})
+		case in.Text != "":
+			env = append(env, corev1.EnvVar{Name: "AGENTOPS_INPUT", Value: in.Text})
+		}
+	}
+	if aj.Spec.PromptTemplateRef != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: promptVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: aj.Name + "-prompt"},
+				},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{Name: promptVolumeName, MountPath: promptMountPath, ReadOnly: true})
+	}
+
+	backoff := defaultJobBackoff
+	var deadline *int64
+	if retry := aj.Spec.Retry; retry != nil {
+		if retry.BackoffLimit != nil {
+			backoff = *retry.BackoffLimit
+		}
+		deadline = retry.ActiveDeadlineSeconds
+	}
+
+	annotations, err := vaultAnnotations(aj.Spec.Secrets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets: %w", err)
+	}
+	if annotations != nil {
+		// The injector sidecar would keep the pod running after the agent exits
+		annotations[vaultAnnotationBase+"agent-pre-populate-only"] = "true"
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      aj.Name,
+			Namespace: aj.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoff,
+			ActiveDeadlineSeconds: deadline,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: annotations,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:                     "agent",
+						Image:                    image,
+						Env:                      env,
+						Resources:                aj.Spec.Resources,
+						VolumeMounts:             mounts,
+						TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+					}},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+
+	controllerutil.SetControllerReference(aj, job, r.Scheme)
+	return job, nil
+}
+
+// reconcileJobPrompt copies the referenced prompt revision into a ConfigMap owned by the AgentJob
+func (r *AgentJobReconciler) reconcileJobPrompt(ctx context.Context, aj *agentopsv1alpha1.AgentJob) error {
+	if aj.Spec.PromptTemplateRef == nil {
+		return nil
+	}
+	rev, err := resolvePromptRevision(ctx, r.Client, aj.Namespace, aj.Spec.PromptTemplateRef)
+	if err != nil {
+		return err
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: aj.Name + "-prompt", Namespace: aj.Namespace},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Data = promptConfigMapData(rev)
+		return controllerutil.SetControllerReference(aj, cm, r.Scheme)
+	})
+	return err
+}
+
+// updateStatus mirrors the Job status into the AgentJob and collects the agent's result
+func (r *AgentJobReconciler) updateStatus(ctx context.Context, aj *agentopsv1alpha1.AgentJob, job *batchv1.Job) error {
+	gen := aj.Generation
+	aj.Status.StartTime = job.Status.StartTime
+	aj.Status.Attempts = job.Status.Active + job.Status.Succeeded + job.Status.Failed
+
+	switch {
+	case jobHasCondition(job, batchv1.JobComplete):
+		aj.Status.Phase = agentopsv1alpha1.AgentJobSucceeded
+		aj.Status.CompletionTime = job.Status.CompletionTime
+		aj.Status.Message = "Agent run succeeded"
+		result, err := r.jobResult(ctx, job)
+		if err != nil {
+			return err
+		}
+		aj.Status.TokensUsed = result.Tokens
+		aj.Status.OutputLocation = result.Output
+		if aj.Status.OutputLocation == "" && aj.Spec.Output != nil {
+			aj.Status.OutputLocation = aj.Spec.Output.Location
+		}
+		conditions.Set(&aj.Status.Conditions, conditions.Complete, metav1.ConditionTrue, conditions.ReasonJobSucceeded,
+			aj.Status.Message, gen)
+	case jobHasCondition(job, batchv1.JobFailed):
+		now := metav1.Now()
+		aj.Status.Phase = agentopsv1alpha1.AgentJobFailed
+		aj.Status.CompletionTime = &now
+		aj.Status.Message = jobFailureMessage(job)
+		conditions.Set(&aj.Status.Conditions, conditions.Complete, metav1.ConditionFalse, conditions.ReasonJobFailed,
+			aj.Status.Message, gen)
+	case job.Status.Active > 0:
+		aj.Status.Phase = agentopsv1alpha1.AgentJobRunning
+		aj.Status.Message = fmt.Sprintf("Attempt %d running", aj.Status.Attempts)
+		conditions.Set(&aj.Status.Conditions, conditions.Complete, metav1.ConditionFalse, conditions.ReasonJobRunning,
+			aj.Status.Message, gen)
+	default:
+		aj.Status.Phase = agentopsv1alpha1.AgentJobPending
+		conditions.Set(&aj.Status.Conditions, conditions.Complete, metav1.ConditionFalse, conditions.ReasonJobRunning,
+			"Waiting for the agent pod to start", gen)
+	}
+
+	aj.Status.ObservedGeneration = gen
+	return patchStatus(ctx, r.Client, aj)
+}
+
+// jobResult reads the result the agent reported in the termination message of the
+// succeeded pod. Agents that report nothing leave the result empty.
+func (r *AgentJobReconciler) jobResult(ctx context.Context, job *batchv1.Job) (jobResult, error) {
+	var result jobResult
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return result, err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name != "agent" || cs.State.Terminated == nil {
+				continue
+			}
+			if err := json.Unmarshal([]byte(cs.State.Terminated.Message), &result); err != nil {
+				r.Log.Info("Ignoring unparseable agent result", "Pod", pod.Name, "Error", err.Error())
+			}
+			return result, nil
+		}
+	}
+	return result, nil
+}
+
+// jobHasCondition reports whether the Job has a True condition of the given type
+func jobHasCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == conditionType && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// jobFailureMessage returns the message of the Job's Failed condition
+func jobFailureMessage(job *batchv1.Job) string {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed {
+			return fmt.Sprintf("%s: %s", c.Reason, c.Message)
+		}
+	}
+	return "Agent run failed"
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *AgentJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.AgentJob{}).
+		Owns(&batchv1.Job{}).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("AgentJob", tracing.Reconciler("AgentJob", r)))
+}
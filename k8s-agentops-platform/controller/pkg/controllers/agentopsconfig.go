@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// agentOpsConfig returns the cluster-wide AgentOpsConfig, or nil if there is none
+func (r *AgentDeploymentReconciler) agentOpsConfig(ctx context.Context) (*agentopsv1alpha1.AgentOpsConfig, error) {
+	return getAgentOpsConfig(ctx, r.Client)
+}
+
+// getAgentOpsConfig reads the cluster-wide AgentOpsConfig with c, or returns nil if
+// there is none
+func getAgentOpsConfig(ctx context.Context, c client.Reader) (*agentopsv1alpha1.AgentOpsConfig, error) {
+	cfg := &agentopsv1alpha1.AgentOpsConfig{}
+	if err := c.Get(ctx, types.NamespacedName{Name: agentopsv1alpha1.AgentOpsConfigName}, cfg); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyConfigDefaults merges the spec defaults of the AgentOpsConfig under the
+// AgentDeployment's own settings. Only the in-memory object changes; the defaults
+// are never written back to the AgentDeployment.
+func applyConfigDefaults(ad *agentopsv1alpha1.AgentDeployment, cfg *agentopsv1alpha1.AgentOpsConfig) {
+	applyComponentResources(ad)
+	if cfg == nil {
+		applySizeClassDefaults(ad, nil)
+		return
+	}
+	applyResourceProfiles(ad, cfg)
+	applySizeClassDefaults(ad, cfg)
+
+	if policy := cfg.Spec.Audit; policy != nil && containsString(policy.Namespaces, ad.Namespace) {
+		applyAuditPolicy(ad, policy)
+	}
+
+	if ing := ad.Spec.Ingress; ing != nil && ing.IssuerRef == nil {
+		ing.IssuerRef = cfg.Spec.CertificateIssuerRef
+	}
+
+	if defaults := cfg.Spec.SecurityContext; defaults != nil {
+		if ad.Spec.SecurityContext == nil {
+			ad.Spec.SecurityContext = &agentopsv1alpha1.SecurityContextSpec{}
+		}
+		sc := ad.Spec.SecurityContext
+		if sc.RunAsNonRoot == nil {
+			sc.RunAsNonRoot = defaults.RunAsNonRoot
+		}
+		if sc.ReadOnlyRootFilesystem == nil {
+			sc.ReadOnlyRootFilesystem = defaults.ReadOnlyRootFilesystem
+		}
+		if sc.RunAsUser == nil {
+			sc.RunAsUser = defaults.RunAsUser
+		}
+	}
+}
+
+// applyResourceProfiles fills in the agent container resources the AgentDeployment
+// sets neither a request nor a limit for from the first profile listing its model
+func applyResourceProfiles(ad *agentopsv1alpha1.AgentDeployment, cfg *agentopsv1alpha1.AgentOpsConfig) {
+	for _, profile := range cfg.Spec.ResourceProfiles {
+		if !containsString(profile.Models, ad.Spec.Model) {
+			continue
+		}
+		res := &ad.Spec.Resources
+		for name := range resourceNames(profile.Resources) {
+			if _, ok := res.Requests[name]; ok {
+				continue
+			}
+			if _, ok := res.Limits[name]; ok {
+				continue
+			}
+			if q, ok := profile.Resources.Requests[name]; ok {
+				if res.Requests == nil {
+					res.Requests = corev1.ResourceList{}
+				}
+				res.Requests[name] = q.DeepCopy()
+			}
+			if q, ok := profile.Resources.Limits[name]; ok {
+				if res.Limits == nil {
+					res.Limits = corev1.ResourceList{}
+				}
+				res.Limits[name] = q.DeepCopy()
+			}
+		}
+		break
+	}
+}
+
+// resourceNames returns the resources a requirement sets a request or limit for
+func resourceNames(res corev1.ResourceRequirements) map[corev1.ResourceName]bool {
+	names := map[corev1.ResourceName]bool{}
+	for name := range res.Requests {
+		names[name] = true
+	}
+	for name := range res.Limits {
+		names[name] = true
+	}
+	return names
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// providerAllowed returns an error when the AgentOpsConfig does not allow the
+// agent's provider
+func providerAllowed(ad *agentopsv1alpha1.AgentDeployment, cfg *agentopsv1alpha1.AgentOpsConfig) error {
+	if cfg == nil || len(cfg.Spec.AllowedProviders) == 0 {
+		return nil
+	}
+	p, err := providerForAgentDeployment(ad)
+	if err != nil {
+		return err
+	}
+	if !containsString(cfg.Spec.AllowedProviders, p.Name) {
+		return fmt.Errorf("provider %s is not allowed by AgentOpsConfig %s; allowed: %s",
+			p.Name, cfg.Name, strings.Join(cfg.Spec.AllowedProviders, ", "))
+	}
+	return nil
+}
+
+// rolloutRejected records why the agent is not rolled out. Its existing child
+// objects are left as they are.
+func (r *AgentDeploymentReconciler) rolloutRejected(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, reason, message string) error {
+	r.Log.Info("Rollout rejected", "AgentDeployment", ad.Name, "Namespace", ad.Namespace, "Reason", reason, "Message", message)
+	conditions.Set(&ad.Status.Conditions, conditions.Progressing, metav1.ConditionFalse, reason, message, ad.Generation)
+	return r.patchBlockedStatus(ctx, ad)
+}
+
+// securityContextForAgentDeployment renders spec.securityContext for the agent container
+func securityContextForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) *corev1.SecurityContext {
+	sc := ad.Spec.SecurityContext
+	if sc == nil {
+		return nil
+	}
+	return &corev1.SecurityContext{
+		RunAsNonRoot:           sc.RunAsNonRoot,
+		ReadOnlyRootFilesystem: sc.ReadOnlyRootFilesystem,
+		RunAsUser:              sc.RunAsUser,
+	}
+}
+
+// configOverlay adds the mandatory pod labels and annotations of the AgentOpsConfig
+// and prefixes images without a registry with its image registry
+func configOverlay(cfg *agentopsv1alpha1.AgentOpsConfig) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		if cfg == nil {
+			return
+		}
+		pod := &dep.Spec.Template
+		for k, v := range cfg.Spec.Labels {
+			if _, ok := pod.Labels[k]; !ok {
+				if pod.Labels == nil {
+					pod.Labels = map[string]string{}
+				}
+				pod.Labels[k] = v
+			}
+		}
+		for k, v := range cfg.Spec.Annotations {
+			if _, ok := pod.Annotations[k]; !ok {
+				if pod.Annotations == nil {
+					pod.Annotations = map[string]string{}
+				}
+				pod.Annotations[k] = v
+			}
+		}
+		if cfg.Spec.ImageRegistry == "" {
+			return
+		}
+		for i := range pod.Spec.InitContainers {
+			pod.Spec.InitContainers[i].Image = withRegistry(cfg.Spec.ImageRegistry, pod.Spec.InitContainers[i].Image)
+		}
+		for i := range pod.Spec.Containers {
+			pod.Spec.Containers[i].Image = withRegistry(cfg.Spec.ImageRegistry, pod.Spec.Containers[i].Image)
+		}
+	}
+}
+
+// withRegistry prefixes image with registry unless it names a registry already. As
+// in Docker, the first path component is a registry when it contains a dot or a
+// port, or is localhost.
+func withRegistry(registry, image string) string {
+	if image == "" {
+		return image
+	}
+	if first, _, ok := strings.Cut(image, "/"); ok &&
+		(strings.ContainsAny(first, ".:") || first == "localhost") {
+		return image
+	}
+	return strings.TrimSuffix(registry, "/") + "/" + image
+}
+
+// allAgentDeployments maps a cluster-wide configuration object to every AgentDeployment
+func (r *AgentDeploymentReconciler) allAgentDeployments(ctx context.Context, _ client.Object) []reconcile.Request {
+	list := &agentopsv1alpha1.AgentDeploymentList{}
+	if err := r.List(ctx, list); err != nil {
+		r.Log.Error(err, "Failed to list AgentDeployments")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, ad := range list.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: ad.Name, Namespace: ad.Namespace},
+		})
+	}
+	return requests
+}
+
+// agentDeploymentsForConfig maps the AgentOpsConfig the controller reads to every
+// AgentDeployment
+func (r *AgentDeploymentReconciler) agentDeploymentsForConfig(ctx context.Context, obj client.Object) []reconcile.Request {
+	if obj.GetName() != agentopsv1alpha1.AgentOpsConfigName {
+		return nil
+	}
+	return r.allAgentDeployments(ctx, obj)
+}
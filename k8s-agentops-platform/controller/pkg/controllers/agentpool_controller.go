@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	// poolLabel marks pods belonging to an AgentPool
+	poolLabel = "agentops.io/pool"
+	// poolStateLabel is part of the pool Deployment selector; flipping it to claimed
+	// orphans the pod from its ReplicaSet, which then backfills a new warm pod
+	poolStateLabel = "agentops.io/pool-state"
+	// claimedByLabel records the AgentDeployment that claimed a pool pod
+	claimedByLabel = "agentops.io/claimed-by"
+	// modelAnnotation tells a claimed generic runtime which model to serve; the
+	// runtime watches it through the downward API volume
+	modelAnnotation = "agentops.io/model"
+
+	poolStateWarm    = "warm"
+	poolStateClaimed = "claimed"
+	podInfoPath      = "/etc/agentops/podinfo"
+)
+
+// AgentPoolReconciler reconciles an AgentPool object
+type AgentPoolReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=agentpools,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=agentpools/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch;delete
+
+// Reconcile keeps the pool's warm Deployment at the requested size
+func (r *AgentPoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("agentpool", req.NamespacedName)
+
+	pool := &agentopsv1alpha1.AgentPool{}
+	if err := r.Get(ctx, req.NamespacedName, pool); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("AgentPool resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get AgentPool")
+		return ctrl.Result{}, err
+	}
+
+	desired := r.deploymentForAgentPool(pool)
+	deployment := &appsv1.Deployment{}
+	err := r.Get(ctx, types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, deployment)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new pool Deployment", "Deployment.Namespace", desired.Namespace, "Deployment.Name", desired.Name)
+		if err := r.Create(ctx, desired); err != nil {
+			log.Error(err, "Failed to create pool Deployment")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	} else if err != nil {
+		log.Error(err, "Failed to get pool Deployment")
+		return ctrl.Result{}, err
+	}
+
+	if !equality.Semantic.DeepDerivative(desired.Spec.Template, deployment.Spec.Template) ||
+		!equality.Semantic.DeepEqual(desired.Spec.Replicas, deployment.Spec.Replicas) {
+		deployment.Spec.Replicas = desired.Spec.Replicas
+		deployment.Spec.Template = desired.Spec.Template
+		if err := r.Update(ctx, deployment); err != nil {
+			log.Error(err, "Failed to update pool Deployment")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.updateStatus(ctx, pool, deployment); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// poolDeploymentName returns the name of the Deployment backing a pool
+func poolDeploymentName(pool *agentopsv1alpha1.AgentPool) string {
+	return pool.Name + "-pool"
+}
+
+// labelsForAgentPool returns the labels selecting a pool's warm pods
+func labelsForAgentPool(name string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "agent-pool",
+		"app.kubernetes.io/instance":   name,
+		"app.kubernetes.io/managed-by": "agentops-controller",
+		poolLabel:                      name,
+		poolStateLabel:                 poolStateWarm,
+	}
+}
+
+// deploymentForAgentPool returns the Deployment running the pool's warm pods
+func (r *AgentPoolReconciler) deploymentForAgentPool(pool *agentopsv1alpha1.AgentPool) *appsv1.Deployment {
+	labels := labelsForAgentPool(pool.Name)
+	size := pool.Spec.Size
+	if size == nil {
+		defaultSize := int32(2)
+		size = &defaultSize
+	}
+	image := pool.Spec.Image
+	if image == "" {
+		image = fmt.Sprintf("%s:%s", defaultImage, "generic")
+	}
+
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      poolDeploymentName(pool),
+			Namespace: pool.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: size,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Image: image,
+						Name:  "agent",
+						Ports: []corev1.ContainerPort{{
+							ContainerPort: 8080,
+							Name:          "http",
+						}},
+						Resources: pool.Spec.Resources,
+						VolumeMounts: []corev1.VolumeMount{{
+							Name:      "podinfo",
+							MountPath: podInfoPath,
+							ReadOnly:  true,
+						}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "podinfo",
+						VolumeSource: corev1.VolumeSource{
+							DownwardAPI: &corev1.DownwardAPIVolumeSource{
+								Items: []corev1.DownwardAPIVolumeFile{{
+									Path:     "annotations",
+									FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.annotations"},
+								}},
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+
+	controllerutil.SetControllerReference(pool, dep, r.Scheme)
+	return dep
+}
+
+// updateStatus updates the AgentPool status
+func (r *AgentPoolReconciler) updateStatus(ctx context.Context, pool *agentopsv1alpha1.AgentPool, dep *appsv1.Deployment) error {
+	claimed := &corev1.PodList{}
+	if err := r.List(ctx, claimed, client.InNamespace(pool.Namespace),
+		client.MatchingLabels{poolLabel: pool.Name, poolStateLabel: poolStateClaimed}); err != nil {
+		return err
+	}
+
+	pool.Status.WarmReplicas = dep.Status.ReadyReplicas
+	pool.Status.ClaimedReplicas = int32(len(claimed.Items))
+	pool.Status.ObservedGeneration = pool.Generation
+	return patchStatus(ctx, r.Client, pool)
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *AgentPoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.AgentPool{}).
+		Owns(&appsv1.Deployment{}).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("AgentPool", tracing.Reconciler("AgentPool", r)))
+}
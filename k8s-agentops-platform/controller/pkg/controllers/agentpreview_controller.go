@@ -0,0 +1,331 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	// previewLabel marks AgentDeployments created by an AgentPreview
+	previewLabel = "agentops.io/preview"
+	// pullRequestAnnotation records the pull request an AgentDeployment previews,
+	// e.g. myorg/llm-agent#123
+	pullRequestAnnotation = "agentops.io/pull-request"
+
+	defaultPreviewTTL               = 72 * time.Hour
+	defaultPreviewInactivityTimeout = 24 * time.Hour
+
+	// previewCheckInterval is how often previews are checked for traffic
+	previewCheckInterval = 10 * time.Minute
+)
+
+// AgentPreviewReconciler reconciles an AgentPreview object
+type AgentPreviewReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Activity reports the traffic of previews; without it previews are only deleted
+	// when their TTL runs out
+	Activity ActivitySource
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=agentpreviews,verbs=get;list;watch;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=agentpreviews/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=agentopsconfigs,verbs=get;list;watch
+
+// Reconcile runs the preview's AgentDeployment and deletes the preview once its TTL
+// runs out or it receives no traffic for its inactivity timeout
+func (r *AgentPreviewReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("agentpreview", req.NamespacedName)
+
+	preview := &agentopsv1alpha1.AgentPreview{}
+	if err := r.Get(ctx, req.NamespacedName, preview); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get AgentPreview")
+		return ctrl.Result{}, err
+	}
+	if !preview.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	gen := preview.Generation
+
+	now := time.Now()
+	expires := preview.CreationTimestamp.Add(previewTTL(preview))
+	if !now.Before(expires) {
+		log.Info("Deleting expired AgentPreview", "ExpirationTime", expires)
+		return ctrl.Result{}, r.deletePreview(ctx, preview)
+	}
+	if preview.Status.Image != preview.Spec.Image {
+		preview.Status.Image = preview.Spec.Image
+		preview.Status.ImageUpdateTime = &metav1.Time{Time: now}
+	}
+	if r.previewIdle(ctx, preview, now) {
+		log.Info("Deleting inactive AgentPreview", "InactivityTimeout", previewInactivityTimeout(preview))
+		return ctrl.Result{}, r.deletePreview(ctx, preview)
+	}
+	preview.Status.ExpirationTime = &metav1.Time{Time: expires}
+	result := ctrl.Result{RequeueAfter: previewCheckInterval}
+	if until := time.Until(expires); until < previewCheckInterval {
+		result.RequeueAfter = until
+	}
+
+	spec := preview.Spec.Template.DeepCopy()
+	if name := preview.Spec.BaseRef; name != "" {
+		base := &agentopsv1alpha1.AgentDeployment{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: preview.Namespace}, base); err != nil {
+			if !errors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+			preview.Status.Phase = agentopsv1alpha1.AgentPreviewPending
+			conditions.Set(&preview.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonBaseNotFound,
+				fmt.Sprintf("AgentDeployment %s does not exist", name), gen)
+			return result, r.updateStatus(ctx, preview)
+		}
+		spec = base.Spec.DeepCopy()
+	}
+	if spec == nil {
+		preview.Status.Phase = agentopsv1alpha1.AgentPreviewPending
+		conditions.Set(&preview.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonInvalidSpec,
+			"One of spec.baseRef and spec.template is required", gen)
+		return ctrl.Result{}, r.updateStatus(ctx, preview)
+	}
+
+	cfg, err := getAgentOpsConfig(ctx, r.Client)
+	if err != nil {
+		log.Error(err, "Failed to get AgentOpsConfig")
+		return ctrl.Result{}, err
+	}
+	host := previewHost(preview, cfg)
+	previewAgentSpec(spec, preview, host)
+
+	ad, err := r.reconcilePreviewAgent(ctx, preview, spec)
+	if err != nil {
+		log.Error(err, "Failed to reconcile AgentDeployment")
+		return ctrl.Result{}, err
+	}
+	if ad == nil {
+		preview.Status.Phase = agentopsv1alpha1.AgentPreviewPending
+		conditions.Set(&preview.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonReconcileError,
+			fmt.Sprintf("AgentDeployment %s exists and is not owned by the preview", preview.Name), gen)
+		return result, r.updateStatus(ctx, preview)
+	}
+
+	preview.Status.AgentDeployment = ad.Name
+	preview.Status.URL = previewURL(spec)
+	if ready := conditions.Get(ad.Status.Conditions, conditions.Ready); ready != nil && ready.Status == metav1.ConditionTrue {
+		preview.Status.Phase = agentopsv1alpha1.AgentPreviewReady
+		conditions.Set(&preview.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonAsExpected,
+			"Preview is ready", gen)
+	} else {
+		preview.Status.Phase = agentopsv1alpha1.AgentPreviewPending
+		reason, message := conditions.ReasonRolloutInProgress, fmt.Sprintf("Waiting for AgentDeployment %s", ad.Name)
+		if ready != nil && ready.Reason != "" {
+			reason, message = ready.Reason, ready.Message
+		}
+		conditions.Set(&preview.Status.Conditions, conditions.Ready, metav1.ConditionFalse, reason, message, gen)
+	}
+	return result, r.updateStatus(ctx, preview)
+}
+
+// reconcilePreviewAgent creates or updates the AgentDeployment of the preview. It
+// returns nil when an AgentDeployment of that name exists that the preview does not
+// own.
+func (r *AgentPreviewReconciler) reconcilePreviewAgent(ctx context.Context, preview *agentopsv1alpha1.AgentPreview, spec *agentopsv1alpha1.AgentDeploymentSpec) (*agentopsv1alpha1.AgentDeployment, error) {
+	ad := &agentopsv1alpha1.AgentDeployment{ObjectMeta: metav1.ObjectMeta{Name: preview.Name, Namespace: preview.Namespace}}
+	err := r.Get(ctx, client.ObjectKeyFromObject(ad), ad)
+	if err == nil && !metav1.IsControlledBy(ad, preview) {
+		return nil, nil
+	} else if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, ad, func() error {
+		// The labels of the preview keep its AgentDeployment in the same
+		// --watch-selector as the preview
+		if ad.Labels == nil {
+			ad.Labels = map[string]string{}
+		}
+		for k, v := range preview.Labels {
+			ad.Labels[k] = v
+		}
+		ad.Labels[previewLabel] = preview.Name
+		if pr := preview.Spec.PullRequest; pr != nil {
+			if ad.Annotations == nil {
+				ad.Annotations = map[string]string{}
+			}
+			ad.Annotations[pullRequestAnnotation] = fmt.Sprintf("%s#%d", pr.Repository, pr.Number)
+		}
+		ad.Spec = *spec
+		return controllerutil.SetControllerReference(preview, ad, r.Scheme)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if op != controllerutil.OperationResultNone {
+		r.Log.Info("Reconciled preview AgentDeployment", "AgentDeployment.Namespace", ad.Namespace, "AgentDeployment.Name", ad.Name,
+			"Operation", op, "Image", preview.Spec.Image)
+	}
+	return ad, nil
+}
+
+// previewAgentSpec turns spec into that of the preview: the image under review on a
+// single replica, exposed at host, without the release machinery of the agent it
+// is copied from
+func previewAgentSpec(spec *agentopsv1alpha1.AgentDeploymentSpec, preview *agentopsv1alpha1.AgentPreview, host string) {
+	spec.Image = preview.Spec.Image
+	one := int32(1)
+	spec.Replicas = &one
+	spec.Autoscaling = nil
+	spec.ProgressiveDelivery = nil
+	spec.Evaluation = nil
+	spec.Shadow = nil
+	if spec.Mesh != nil {
+		spec.Mesh.Canary = nil
+	}
+
+	if host == "" {
+		spec.Ingress = nil
+		return
+	}
+	if spec.Ingress == nil {
+		spec.Ingress = &agentopsv1alpha1.IngressSpec{TLS: true}
+	}
+	spec.Ingress.Enabled = true
+	spec.Ingress.Host = host
+	if spec.Ingress.GRPCHost != "" {
+		spec.Ingress.GRPCHost = "grpc-" + host
+	}
+}
+
+// previewHost returns the generated ingress host of the preview, <name>.<domain>, or
+// "" when neither the preview nor the AgentOpsConfig sets a domain
+func previewHost(preview *agentopsv1alpha1.AgentPreview, cfg *agentopsv1alpha1.AgentOpsConfig) string {
+	domain := preview.Spec.Domain
+	if domain == "" && cfg != nil {
+		domain = cfg.Spec.PreviewDomain
+	}
+	if domain == "" {
+		return ""
+	}
+	// Names may contain dots, hosts get one label per preview
+	return strings.ReplaceAll(preview.Name, ".", "-") + "." + strings.TrimPrefix(domain, ".")
+}
+
+// previewURL returns the URL of a preview agent spec, or "" without an ingress
+func previewURL(spec *agentopsv1alpha1.AgentDeploymentSpec) string {
+	ing := spec.Ingress
+	if ing == nil || !ing.Enabled || ing.Host == "" {
+		return ""
+	}
+	if ing.TLS {
+		return "https://" + ing.Host
+	}
+	return "http://" + ing.Host
+}
+
+// previewTTL returns how long after its creation the preview is deleted
+func previewTTL(preview *agentopsv1alpha1.AgentPreview) time.Duration {
+	if preview.Spec.TTL == nil {
+		return defaultPreviewTTL
+	}
+	return preview.Spec.TTL.Duration
+}
+
+// previewInactivityTimeout returns how long the preview may receive no requests
+func previewInactivityTimeout(preview *agentopsv1alpha1.AgentPreview) time.Duration {
+	if preview.Spec.InactivityTimeout == nil {
+		return defaultPreviewInactivityTimeout
+	}
+	return preview.Spec.InactivityTimeout.Duration
+}
+
+// previewIdle reports whether the preview received no requests for its inactivity
+// timeout. The clock starts at its creation or last image change; previews whose
+// traffic cannot be read are never idle.
+func (r *AgentPreviewReconciler) previewIdle(ctx context.Context, preview *agentopsv1alpha1.AgentPreview, now time.Time) bool {
+	timeout := previewInactivityTimeout(preview)
+	if r.Activity == nil || timeout <= 0 {
+		return false
+	}
+	since := preview.CreationTimestamp.Time
+	if t := preview.Status.ImageUpdateTime; t != nil && t.After(since) {
+		since = t.Time
+	}
+	if now.Sub(since) < timeout {
+		return false
+	}
+	requests, err := r.Activity.Requests(ctx, preview.Namespace, preview.Name, timeout)
+	if err != nil {
+		r.Log.Error(err, "Failed to read preview traffic; keeping it", "AgentPreview", preview.Name, "Namespace", preview.Namespace)
+		return false
+	}
+	return requests == 0
+}
+
+// deletePreview deletes the preview; its AgentDeployment is garbage collected
+func (r *AgentPreviewReconciler) deletePreview(ctx context.Context, preview *agentopsv1alpha1.AgentPreview) error {
+	err := r.Delete(ctx, preview, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// updateStatus writes the preview status
+func (r *AgentPreviewReconciler) updateStatus(ctx context.Context, preview *agentopsv1alpha1.AgentPreview) error {
+	preview.Status.ObservedGeneration = preview.Generation
+	return patchStatus(ctx, r.Client, preview)
+}
+
+// previewsForBase maps an AgentDeployment to the previews copying its spec, so
+// changes to the base reach its previews
+func (r *AgentPreviewReconciler) previewsForBase(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &agentopsv1alpha1.AgentPreviewList{}
+	if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list AgentPreviews", "Namespace", obj.GetNamespace())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, preview := range list.Items {
+		if preview.Spec.BaseRef == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&preview)})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *AgentPreviewReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.AgentPreview{}).
+		Owns(&agentopsv1alpha1.AgentDeployment{}).
+		Watches(&agentopsv1alpha1.AgentDeployment{}, handler.EnqueueRequestsFromMapFunc(r.previewsForBase)).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("AgentPreview", tracing.Reconciler("AgentPreview", r)))
+}
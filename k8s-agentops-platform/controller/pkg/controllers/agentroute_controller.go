@@ -0,0 +1,387 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/catalog"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/gateway"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	gatewayImage            = "envoyproxy/envoy:v1.28-latest"
+	gatewayConfigKey        = "envoy.json"
+	gatewayConfigAnnotation = "agentops.io/gateway-config-hash"
+)
+
+var httpRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+
+// AgentRouteReconciler reconciles an AgentRoute object
+type AgentRouteReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=agentroutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=agentroutes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=services;configmaps,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile renders the route into an HTTPRoute or a bundled Envoy gateway
+func (r *AgentRouteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("agentroute", req.NamespacedName)
+
+	route := &agentopsv1alpha1.AgentRoute{}
+	if err := r.Get(ctx, req.NamespacedName, route); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get AgentRoute")
+		return ctrl.Result{}, err
+	}
+
+	rules, backends, err := r.resolveRules(ctx, route)
+	if err != nil {
+		log.Error(err, "Failed to resolve AgentRoute backends")
+		return ctrl.Result{}, err
+	}
+
+	if route.Spec.Mode == agentopsv1alpha1.RouteModeBundled {
+		err = r.reconcileBundledGateway(ctx, route, rules)
+		route.Status.Address = fmt.Sprintf("%s.%s.svc.cluster.local", gatewayName(route), route.Namespace)
+	} else {
+		err = applyUnstructured(ctx, r.Client, r.Scheme, route, httpRouteForAgentRoute(route, rules))
+		route.Status.Address = ""
+	}
+	if err != nil {
+		log.Error(err, "Failed to reconcile routing layer", "Mode", route.Spec.Mode)
+		conditions.Set(&route.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonReconcileError,
+			err.Error(), route.Generation)
+	} else if len(backends) == 0 {
+		conditions.Set(&route.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonNoBackends,
+			"No AgentDeployment matches the route rules", route.Generation)
+	} else {
+		conditions.Set(&route.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonAsExpected,
+			fmt.Sprintf("Routing to %d AgentDeployments", len(backends)), route.Generation)
+	}
+
+	route.Status.Backends = backends
+	route.Status.ObservedGeneration = route.Generation
+	if statusErr := patchStatus(ctx, r.Client, route); statusErr != nil {
+		return ctrl.Result{}, statusErr
+	}
+	return ctrl.Result{}, err
+}
+
+// resolveRules turns the route rules into gateway rules, expanding capability
+// rules and dropping backends whose AgentDeployment does not exist or is being
+// deleted, so that deleted agents are deregistered before they go away
+func (r *AgentRouteReconciler) resolveRules(ctx context.Context, route *agentopsv1alpha1.AgentRoute) ([]gateway.Rule, []agentopsv1alpha1.AgentRouteBackend, error) {
+	list := &agentopsv1alpha1.AgentDeploymentList{}
+	if err := r.List(ctx, list, client.InNamespace(route.Namespace)); err != nil {
+		return nil, nil, err
+	}
+	agents := map[string]*agentopsv1alpha1.AgentDeployment{}
+	for i := range list.Items {
+		if list.Items[i].DeletionTimestamp.IsZero() {
+			agents[list.Items[i].Name] = &list.Items[i]
+		}
+	}
+
+	seen := map[string]bool{}
+	var resolved []agentopsv1alpha1.AgentRouteBackend
+	var rules []gateway.Rule
+	for _, rule := range route.Spec.Rules {
+		backends := rule.Backends
+		if len(backends) == 0 && rule.Capability != "" {
+			for _, ad := range agents {
+				if model, ok := catalog.Lookup(ad.Spec.Model); ok && model.HasCapability(rule.Capability) {
+					backends = append(backends, agentopsv1alpha1.AgentRouteBackend{Name: ad.Name, Weight: 1})
+				}
+			}
+			// Keep the rendered route stable across reconciles
+			sort.Slice(backends, func(i, j int) bool { return backends[i].Name < backends[j].Name })
+		}
+
+		gwRule := gateway.Rule{PathPrefix: rule.PathPrefix, Headers: rule.Headers}
+		for _, b := range backends {
+			ad, ok := agents[b.Name]
+			if !ok {
+				continue
+			}
+			backend := gateway.Backend{
+				Service:   b.Name,
+				Namespace: route.Namespace,
+				Port:      agentServicePort,
+				Weight:    b.Weight,
+			}
+			if b.Adapter != "" {
+				if !hasAdapter(ad, b.Adapter) {
+					r.Log.Info("Skipping backend with unknown adapter", "AgentRoute", route.Name, "AgentDeployment", b.Name, "Adapter", b.Adapter)
+					continue
+				}
+				// Adapters of one agent share its Service; give each a cluster
+				backend.Pool = "adapter-" + b.Adapter
+				backend.RequestHeaders = map[string]string{adapterHeader: b.Adapter}
+			}
+			gwRule.Backends = append(gwRule.Backends, backend)
+			if !seen[b.Name] {
+				seen[b.Name] = true
+				resolved = append(resolved, b)
+			}
+		}
+		rules = append(rules, gwRule)
+	}
+	return rules, resolved, nil
+}
+
+// httpRouteForAgentRoute returns the Gateway API HTTPRoute for the route
+func httpRouteForAgentRoute(route *agentopsv1alpha1.AgentRoute, rules []gateway.Rule) *unstructured.Unstructured {
+	parentRefs := parentRefsToUnstructured(route.Spec.ParentRefs)
+
+	var hostnames []interface{}
+	for _, h := range route.Spec.Hostnames {
+		hostnames = append(hostnames, h)
+	}
+
+	var httpRules []interface{}
+	for _, rule := range rules {
+		if len(rule.Backends) == 0 {
+			continue
+		}
+		httpRules = append(httpRules, httpRouteRule(rule))
+	}
+
+	u := newUnstructured(httpRouteGVK, route.Name, route.Namespace)
+	u.SetLabels(map[string]string{"app.kubernetes.io/managed-by": "agentops-controller"})
+	u.Object["spec"] = map[string]interface{}{
+		"parentRefs": parentRefs,
+		"hostnames":  hostnames,
+		"rules":      httpRules,
+	}
+	return u
+}
+
+// parentRefsToUnstructured converts Gateway references into HTTPRoute parentRefs
+func parentRefsToUnstructured(refs []agentopsv1alpha1.GatewayParentReference) []interface{} {
+	var parentRefs []interface{}
+	for _, p := range refs {
+		ref := map[string]interface{}{"name": p.Name}
+		if p.Namespace != "" {
+			ref["namespace"] = p.Namespace
+		}
+		if p.SectionName != "" {
+			ref["sectionName"] = p.SectionName
+		}
+		parentRefs = append(parentRefs, ref)
+	}
+	return parentRefs
+}
+
+// httpRouteRule converts a gateway rule into an HTTPRoute rule
+func httpRouteRule(rule gateway.Rule) map[string]interface{} {
+	prefix := rule.PathPrefix
+	if prefix == "" {
+		prefix = "/"
+	}
+	match := map[string]interface{}{
+		"path": map[string]interface{}{"type": "PathPrefix", "value": prefix},
+	}
+	if len(rule.Headers) > 0 {
+		names := make([]string, 0, len(rule.Headers))
+		for name := range rule.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var headers []interface{}
+		for _, name := range names {
+			headers = append(headers, map[string]interface{}{"type": "Exact", "name": name, "value": rule.Headers[name]})
+		}
+		match["headers"] = headers
+	}
+
+	var backendRefs []interface{}
+	for _, b := range rule.Backends {
+		weight := b.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		ref := map[string]interface{}{
+			"name":   b.Service,
+			"port":   int64(b.Port),
+			"weight": int64(weight),
+		}
+		if len(b.RequestHeaders) > 0 {
+			names := make([]string, 0, len(b.RequestHeaders))
+			for name := range b.RequestHeaders {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			var set []interface{}
+			for _, name := range names {
+				set = append(set, map[string]interface{}{"name": name, "value": b.RequestHeaders[name]})
+			}
+			ref["filters"] = []interface{}{map[string]interface{}{
+				"type":                  "RequestHeaderModifier",
+				"requestHeaderModifier": map[string]interface{}{"set": set},
+			}}
+		}
+		backendRefs = append(backendRefs, ref)
+	}
+	httpRule := map[string]interface{}{
+		"matches":     []interface{}{match},
+		"backendRefs": backendRefs,
+	}
+	if m := rule.Mirror; m != nil {
+		mirror := map[string]interface{}{
+			"backendRef": map[string]interface{}{"name": m.Backend.Service, "port": int64(m.Backend.Port)},
+		}
+		// Older Gateway API CRDs have no percent and mirror everything
+		if m.Percent > 0 && m.Percent < 100 {
+			mirror["percent"] = int64(m.Percent)
+		}
+		httpRule["filters"] = []interface{}{map[string]interface{}{
+			"type":          "RequestMirror",
+			"requestMirror": mirror,
+		}}
+	}
+	return httpRule
+}
+
+// gatewayName returns the name of the bundled gateway resources for a route
+func gatewayName(route *agentopsv1alpha1.AgentRoute) string {
+	return route.Name + "-gateway"
+}
+
+// reconcileBundledGateway deploys a dedicated Envoy gateway serving the route
+func (r *AgentRouteReconciler) reconcileBundledGateway(ctx context.Context, route *agentopsv1alpha1.AgentRoute, rules []gateway.Rule) error {
+	config, err := gateway.RenderEnvoyBootstrap(gateway.Config{
+		Routes: []gateway.Route{{Name: route.Name, Hostnames: route.Spec.Hostnames, Rules: rules}},
+	})
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(config))
+	name := gatewayName(route)
+	labels := map[string]string{
+		"app.kubernetes.io/name":       "agent-gateway",
+		"app.kubernetes.io/instance":   route.Name,
+		"app.kubernetes.io/managed-by": "agentops-controller",
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: route.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Labels = labels
+		cm.Data = map[string]string{gatewayConfigKey: config}
+		return controllerutil.SetControllerReference(route, cm, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: route.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, dep, func() error {
+		replicas := int32(2)
+		dep.Labels = labels
+		dep.Spec.Replicas = &replicas
+		dep.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+		dep.Spec.Template.Labels = labels
+		// Roll the gateway pods when the rendered configuration changes
+		dep.Spec.Template.Annotations = map[string]string{gatewayConfigAnnotation: hex.EncodeToString(sum[:8])}
+		dep.Spec.Template.Spec.Containers = []corev1.Container{{
+			Name:  "envoy",
+			Image: gatewayImage,
+			Args:  []string{"-c", "/etc/envoy/" + gatewayConfigKey},
+			Ports: []corev1.ContainerPort{
+				{Name: "http", ContainerPort: gateway.ListenPort},
+				{Name: "admin", ContainerPort: gateway.AdminPort},
+			},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					HTTPGet: &corev1.HTTPGetAction{Path: "/ready", Port: intstr.FromInt(gateway.AdminPort)},
+				},
+				PeriodSeconds: 5,
+			},
+			VolumeMounts: []corev1.VolumeMount{{Name: "config", MountPath: "/etc/envoy", ReadOnly: true}},
+		}}
+		dep.Spec.Template.Spec.Volumes = []corev1.Volume{{
+			Name: "config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: name}},
+			},
+		}}
+		return controllerutil.SetControllerReference(route, dep, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: route.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		svc.Labels = labels
+		svc.Spec.Selector = labels
+		svc.Spec.Ports = []corev1.ServicePort{{
+			Name:       "http",
+			Port:       agentServicePort,
+			TargetPort: intstr.FromString("http"),
+		}}
+		return controllerutil.SetControllerReference(route, svc, r.Scheme)
+	})
+	return err
+}
+
+// agentRoutesForAgentDeployment maps AgentDeployment events to the routes in its
+// namespace, so capability rules pick up new agents
+func (r *AgentRouteReconciler) agentRoutesForAgentDeployment(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &agentopsv1alpha1.AgentRouteList{}
+	if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list AgentRoutes", "AgentDeployment", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, route := range list.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: route.Name, Namespace: route.Namespace},
+		})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *AgentRouteReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.AgentRoute{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.ConfigMap{}).
+		Watches(&agentopsv1alpha1.AgentDeployment{}, handler.EnqueueRequestsFromMapFunc(r.agentRoutesForAgentDeployment)).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("AgentRoute", tracing.Reconciler("AgentRoute", r)))
+}
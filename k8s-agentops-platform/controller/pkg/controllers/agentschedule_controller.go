@@ -0,0 +1,277 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	// scheduleLabel marks AgentJobs created by an AgentSchedule
+	scheduleLabel = "agentops.io/schedule"
+	// scheduledAtAnnotation records the run time an AgentJob was created for
+	scheduledAtAnnotation = "agentops.io/scheduled-at"
+	// maxMissedRuns bounds the search for missed runs after a long outage
+	maxMissedRuns = 100
+)
+
+// AgentScheduleReconciler reconciles an AgentSchedule object
+type AgentScheduleReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=agentschedules,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=agentschedules/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentjobs,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile starts due runs, enforces the concurrency policy and prunes run history
+func (r *AgentScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("agentschedule", req.NamespacedName)
+
+	schedule := &agentopsv1alpha1.AgentSchedule{}
+	if err := r.Get(ctx, req.NamespacedName, schedule); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get AgentSchedule")
+		return ctrl.Result{}, err
+	}
+	gen := schedule.Generation
+
+	sched, err := parseSchedule(schedule)
+	if err != nil {
+		conditions.Set(&schedule.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonInvalidSpec,
+			err.Error(), gen)
+		schedule.Status.ObservedGeneration = gen
+		return ctrl.Result{}, patchStatus(ctx, r.Client, schedule)
+	}
+
+	runs := &agentopsv1alpha1.AgentJobList{}
+	if err := r.List(ctx, runs, client.InNamespace(schedule.Namespace), client.MatchingLabels{scheduleLabel: schedule.Name}); err != nil {
+		log.Error(err, "Failed to list AgentJobs")
+		return ctrl.Result{}, err
+	}
+	active, err := r.reconcileHistory(ctx, schedule, runs.Items)
+	if err != nil {
+		log.Error(err, "Failed to prune AgentJob history")
+		return ctrl.Result{}, err
+	}
+
+	now := time.Now()
+	next := sched.Next(now)
+	schedule.Status.NextScheduleTime = &metav1.Time{Time: next}
+	result := ctrl.Result{RequeueAfter: time.Until(next)}
+
+	if schedule.Spec.Suspend {
+		schedule.Status.NextScheduleTime = nil
+		conditions.Set(&schedule.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonSuspended,
+			"Schedule is suspended", gen)
+		return ctrl.Result{}, r.updateStatus(ctx, schedule)
+	}
+
+	due := lastMissedRun(schedule, sched, now)
+	if due.IsZero() || tooLate(schedule, due, now) {
+		if !due.IsZero() {
+			log.Info("Missed run is past its starting deadline", "ScheduledAt", due)
+		}
+		conditions.Set(&schedule.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonAsExpected,
+			fmt.Sprintf("Next run at %s", next.UTC().Format(time.RFC3339)), gen)
+		return result, r.updateStatus(ctx, schedule)
+	}
+
+	if len(active) > 0 {
+		switch schedule.Spec.ConcurrencyPolicy {
+		case agentopsv1alpha1.ConcurrencyPolicyForbid, "":
+			log.Info("Skipping run, previous run still active", "ScheduledAt", due)
+			conditions.Set(&schedule.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonRunSkipped,
+				fmt.Sprintf("Run due at %s skipped, %d still active", due.UTC().Format(time.RFC3339), len(active)), gen)
+			// Retry until the active run finishes or the starting deadline passes
+			return ctrl.Result{RequeueAfter: time.Minute}, r.updateStatus(ctx, schedule)
+		case agentopsv1alpha1.ConcurrencyPolicyReplace:
+			for i := range active {
+				log.Info("Replacing active run", "AgentJob", active[i].Name)
+				if err := r.Delete(ctx, &active[i], client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+					return ctrl.Result{}, err
+				}
+			}
+			schedule.Status.Active = nil
+		}
+	}
+
+	run := agentJobForSchedule(schedule, due)
+	if err := controllerutil.SetControllerReference(schedule, run, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+	log.Info("Starting scheduled run", "AgentJob", run.Name, "ScheduledAt", due)
+	if err := r.Create(ctx, run); err != nil && !errors.IsAlreadyExists(err) {
+		log.Error(err, "Failed to create AgentJob", "AgentJob", run.Name)
+		return ctrl.Result{}, err
+	}
+
+	schedule.Status.Active = append(schedule.Status.Active, run.Name)
+	schedule.Status.LastScheduleTime = &metav1.Time{Time: due}
+	schedule.Status.LastRun = run.Name
+	schedule.Status.LastRunPhase = agentopsv1alpha1.AgentJobPending
+	conditions.Set(&schedule.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonAsExpected,
+		fmt.Sprintf("Next run at %s", next.UTC().Format(time.RFC3339)), gen)
+	return result, r.updateStatus(ctx, schedule)
+}
+
+// reconcileHistory records run state in the schedule status, deletes finished runs
+// beyond the history limits and returns the active runs
+func (r *AgentScheduleReconciler) reconcileHistory(ctx context.Context, schedule *agentopsv1alpha1.AgentSchedule, runs []agentopsv1alpha1.AgentJob) ([]agentopsv1alpha1.AgentJob, error) {
+	sort.Slice(runs, func(i, j int) bool { return runs[i].CreationTimestamp.Before(&runs[j].CreationTimestamp) })
+
+	var active, succeeded, failed []agentopsv1alpha1.AgentJob
+	for _, run := range runs {
+		switch run.Status.Phase {
+		case agentopsv1alpha1.AgentJobSucceeded:
+			succeeded = append(succeeded, run)
+			if t := run.Status.CompletionTime; t != nil &&
+				(schedule.Status.LastSuccessfulTime == nil || schedule.Status.LastSuccessfulTime.Before(t)) {
+				schedule.Status.LastSuccessfulTime = t
+			}
+		case agentopsv1alpha1.AgentJobFailed:
+			failed = append(failed, run)
+		default:
+			active = append(active, run)
+		}
+		if run.Name == schedule.Status.LastRun {
+			schedule.Status.LastRunPhase = run.Status.Phase
+		}
+	}
+
+	schedule.Status.Active = nil
+	for _, run := range active {
+		schedule.Status.Active = append(schedule.Status.Active, run.Name)
+	}
+
+	if err := r.pruneRuns(ctx, succeeded, schedule.Spec.SuccessfulRunsHistoryLimit, 3); err != nil {
+		return nil, err
+	}
+	if err := r.pruneRuns(ctx, failed, schedule.Spec.FailedRunsHistoryLimit, 1); err != nil {
+		return nil, err
+	}
+	return active, nil
+}
+
+// pruneRuns deletes the oldest runs so that at most limit remain
+func (r *AgentScheduleReconciler) pruneRuns(ctx context.Context, runs []agentopsv1alpha1.AgentJob, limit *int32, defaultLimit int) error {
+	keep := defaultLimit
+	if limit != nil {
+		keep = int(*limit)
+	}
+	for i := 0; i < len(runs)-keep; i++ {
+		if err := r.Delete(ctx, &runs[i], client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateStatus writes the schedule status
+func (r *AgentScheduleReconciler) updateStatus(ctx context.Context, schedule *agentopsv1alpha1.AgentSchedule) error {
+	schedule.Status.ObservedGeneration = schedule.Generation
+	return patchStatus(ctx, r.Client, schedule)
+}
+
+// parseSchedule parses the cron expression in the schedule's time zone
+func parseSchedule(schedule *agentopsv1alpha1.AgentSchedule) (cron.Schedule, error) {
+	return parseCron(schedule.Spec.Schedule, schedule.Spec.TimeZone)
+}
+
+// parseCron parses a cron expression in the given time zone, UTC when empty
+func parseCron(expr, tz string) (cron.Schedule, error) {
+	spec := expr
+	if tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", tz, err)
+		}
+		spec = "CRON_TZ=" + tz + " " + spec
+	}
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+	}
+	return sched, nil
+}
+
+// lastMissedRun returns the latest run time after the last scheduled run that is
+// not in the future, or the zero time when no run is due
+func lastMissedRun(schedule *agentopsv1alpha1.AgentSchedule, sched cron.Schedule, now time.Time) time.Time {
+	earliest := schedule.CreationTimestamp.Time
+	if schedule.Status.LastScheduleTime != nil {
+		earliest = schedule.Status.LastScheduleTime.Time
+	}
+	if deadline := schedule.Spec.StartingDeadlineSeconds; deadline != nil {
+		if bound := now.Add(-time.Duration(*deadline) * time.Second); bound.After(earliest) {
+			earliest = bound
+		}
+	}
+
+	return latestRun(sched, earliest, now)
+}
+
+// latestRun returns the latest run time of sched after earliest that is not in the
+// future, or the zero time when there is none
+func latestRun(sched cron.Schedule, earliest, now time.Time) time.Time {
+	var last time.Time
+	for t, n := sched.Next(earliest), 0; !t.After(now) && n < maxMissedRuns; t, n = sched.Next(t), n+1 {
+		last = t
+	}
+	return last
+}
+
+// tooLate reports whether a due run is past the schedule's starting deadline
+func tooLate(schedule *agentopsv1alpha1.AgentSchedule, due, now time.Time) bool {
+	deadline := schedule.Spec.StartingDeadlineSeconds
+	return deadline != nil && due.Add(time.Duration(*deadline)*time.Second).Before(now)
+}
+
+// agentJobForSchedule returns the AgentJob for the run due at the given time. The
+// name is derived from the run time so a retried reconcile cannot start it twice.
+func agentJobForSchedule(schedule *agentopsv1alpha1.AgentSchedule, due time.Time) *agentopsv1alpha1.AgentJob {
+	labels := map[string]string{scheduleLabel: schedule.Name}
+	for k, v := range schedule.Spec.JobTemplate.Labels {
+		labels[k] = v
+	}
+	return &agentopsv1alpha1.AgentJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%d", schedule.Name, due.Unix()/60),
+			Namespace:   schedule.Namespace,
+			Labels:      labels,
+			Annotations: map[string]string{scheduledAtAnnotation: due.UTC().Format(time.RFC3339)},
+		},
+		Spec: *schedule.Spec.JobTemplate.Spec.DeepCopy(),
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *AgentScheduleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.AgentSchedule{}).
+		Owns(&agentopsv1alpha1.AgentJob{}).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("AgentSchedule", tracing.Reconciler("AgentSchedule", r)))
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tasks"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	defaultTaskTimeout    = 10 * time.Minute
+	defaultTaskAttempts   = 3
+	taskRetryBaseInterval = 10 * time.Second
+)
+
+// AgentTaskReconciler executes AgentTasks with the registered handlers
+type AgentTaskReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Log      logr.Logger
+	Handlers *tasks.Registry
+
+	// Workers is the number of tasks executed concurrently
+	Workers int
+
+	// Options sets the workqueue rate limiter; Workers takes precedence over its
+	// MaxConcurrentReconciles
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=agenttasks,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=agenttasks/status,verbs=get;update;patch
+
+// Reconcile runs one attempt of a pending task
+func (r *AgentTaskReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("agenttask", req.NamespacedName)
+
+	task := &agentopsv1alpha1.AgentTask{}
+	if err := r.Get(ctx, req.NamespacedName, task); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get AgentTask")
+		return ctrl.Result{}, err
+	}
+	if task.Done() {
+		return ctrl.Result{}, nil
+	}
+
+	handler, ok := r.Handlers.Lookup(task.Spec.Type)
+	if !ok {
+		return ctrl.Result{}, r.finish(ctx, task, agentopsv1alpha1.TaskPhaseFailed, nil,
+			fmt.Sprintf("no handler registered for task type %q", task.Spec.Type))
+	}
+
+	// Record the attempt before running so a controller restart does not retry forever
+	now := metav1.Now()
+	task.Status.Phase = agentopsv1alpha1.TaskPhaseRunning
+	task.Status.Attempts++
+	task.Status.StartTime = &now
+	task.Status.ObservedGeneration = task.Generation
+	if err := patchStatus(ctx, r.Client, task); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	timeout := defaultTaskTimeout
+	if task.Spec.TimeoutSeconds > 0 {
+		timeout = time.Duration(task.Spec.TimeoutSeconds) * time.Second
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Info("Running task", "Type", task.Spec.Type, "Attempt", task.Status.Attempts)
+	result, err := handler.Run(runCtx, task.Namespace, task.Spec.Params)
+	if err == nil {
+		return ctrl.Result{}, r.finish(ctx, task, agentopsv1alpha1.TaskPhaseSucceeded, result, "")
+	}
+
+	maxAttempts := task.Spec.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultTaskAttempts
+	}
+	if task.Status.Attempts >= maxAttempts || tasks.IsPermanent(err) {
+		log.Info("Task failed", "Type", task.Spec.Type, "Error", err.Error())
+		return ctrl.Result{}, r.finish(ctx, task, agentopsv1alpha1.TaskPhaseFailed, nil, err.Error())
+	}
+
+	task.Status.Phase = agentopsv1alpha1.TaskPhasePending
+	task.Status.Message = err.Error()
+	if err := patchStatus(ctx, r.Client, task); err != nil {
+		return ctrl.Result{}, err
+	}
+	backoff := taskRetryBaseInterval << (task.Status.Attempts - 1)
+	return ctrl.Result{RequeueAfter: backoff}, nil
+}
+
+// finish moves the task to a terminal phase
+func (r *AgentTaskReconciler) finish(ctx context.Context, task *agentopsv1alpha1.AgentTask, phase string, result map[string]string, message string) error {
+	now := metav1.Now()
+	task.Status.Phase = phase
+	task.Status.Result = result
+	task.Status.Message = message
+	task.Status.CompletionTime = &now
+	return patchStatus(ctx, r.Client, task)
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *AgentTaskReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	opts := r.Options
+	opts.MaxConcurrentReconciles = r.Workers
+	if opts.MaxConcurrentReconciles == 0 {
+		opts.MaxConcurrentReconciles = 4
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.AgentTask{}).
+		WithOptions(opts).
+		Complete(debug.Reconciler("AgentTask", tracing.Reconciler("AgentTask", r)))
+}
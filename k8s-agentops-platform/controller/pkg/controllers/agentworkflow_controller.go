@@ -0,0 +1,459 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	defaultStepTimeout = 5 * time.Minute
+	// stepRetryBaseInterval doubles with every failed attempt of a step
+	stepRetryBaseInterval = 10 * time.Second
+	// workflowWaitInterval is how often a step waiting for its AgentDeployment checks again
+	workflowWaitInterval = 15 * time.Second
+	// maxStepOutput bounds the output kept in the status of a step
+	maxStepOutput = 16 << 10
+)
+
+var (
+	workflowsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agentops_workflows_total",
+		Help: "Finished AgentWorkflows, by namespace and phase.",
+	}, []string{"namespace", "phase"})
+	workflowDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "agentops_workflow_duration_seconds",
+		Help:    "Time from the start of the first step to the end of finished AgentWorkflows, by namespace and phase.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 14),
+	}, []string{"namespace", "phase"})
+	workflowStepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "agentops_workflow_step_duration_seconds",
+		Help:    "Duration of workflow step attempts, by namespace, agent and outcome.",
+		Buckets: prometheus.ExponentialBuckets(0.25, 2, 12),
+	}, []string{"namespace", "agent", "outcome"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(workflowsTotal, workflowDuration, workflowStepDuration)
+}
+
+// StepRunner sends the prompts of workflow steps to agents
+type StepRunner interface {
+	Complete(ctx context.Context, url, model, system, prompt string) (string, error)
+}
+
+// AgentWorkflowReconciler executes AgentWorkflows
+type AgentWorkflowReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Runner sends the prompts of the steps; nil leaves workflows pending
+	Runner StepRunner
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// workflowData is what the prompt and output templates of a workflow are rendered with
+type workflowData struct {
+	Input map[string]string
+	Steps map[string]string
+}
+
+// stepAttempt is a step due to run in this reconcile
+type stepAttempt struct {
+	index  int
+	url    string
+	model  string
+	prompt string
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=agentworkflows,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=agentworkflows/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments,verbs=get;list;watch
+
+// Reconcile runs the steps of the AgentWorkflow whose dependencies succeeded,
+// concurrently, and records their outputs. One round of steps per reconcile shows
+// progress in the status; the status update triggers the next round.
+func (r *AgentWorkflowReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("agentworkflow", req.NamespacedName)
+
+	wf := &agentopsv1alpha1.AgentWorkflow{}
+	if err := r.Get(ctx, req.NamespacedName, wf); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get AgentWorkflow")
+		return ctrl.Result{}, err
+	}
+	// Finished AgentWorkflows are deleted after their TTL by the GarbageCollector
+	if wf.Finished() {
+		return ctrl.Result{}, nil
+	}
+	if r.Runner == nil {
+		return ctrl.Result{}, nil
+	}
+
+	if err := validateWorkflow(wf); err != nil {
+		r.finish(wf, agentopsv1alpha1.WorkflowFailed, conditions.ReasonInvalidSpec, err.Error())
+		return ctrl.Result{}, patchStatus(ctx, r.Client, wf)
+	}
+	initStepStatuses(wf)
+	data := workflowData{Input: wf.Spec.Input, Steps: map[string]string{}}
+	for _, st := range wf.Status.Steps {
+		if st.Phase == agentopsv1alpha1.WorkflowSucceeded {
+			data.Steps[st.Name] = st.Output
+		}
+	}
+
+	// Pick the steps whose dependencies succeeded and whose retry backoff passed
+	now := time.Now()
+	var due []stepAttempt
+	var requeue time.Duration
+	for i, step := range wf.Spec.Steps {
+		st := &wf.Status.Steps[i]
+		if st.Phase != agentopsv1alpha1.WorkflowPending || !dependenciesSucceeded(wf, step) {
+			continue
+		}
+		if st.Attempts > 0 && st.CompletionTime != nil {
+			if wait := st.CompletionTime.Add(stepRetryBaseInterval << (st.Attempts - 1)).Sub(now); wait > 0 {
+				requeue = minRequeue(requeue, wait)
+				continue
+			}
+		}
+		ad := &agentopsv1alpha1.AgentDeployment{}
+		if err := r.Get(ctx, types.NamespacedName{Name: step.AgentDeployment, Namespace: wf.Namespace}, ad); err != nil {
+			if !errors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+			st.Message = fmt.Sprintf("AgentDeployment %s not found", step.AgentDeployment)
+			requeue = minRequeue(requeue, workflowWaitInterval)
+			continue
+		}
+		prompt, err := renderWorkflowTemplate(step.Name, step.Prompt, data)
+		if err != nil {
+			st.Phase = agentopsv1alpha1.WorkflowFailed
+			st.Message = fmt.Sprintf("Cannot render prompt: %v", err)
+			continue
+		}
+		due = append(due, stepAttempt{
+			index:  i,
+			url:    fmt.Sprintf("http://%s:%d%s", serviceHost(ad.Name, ad.Namespace), agentServicePort, stepPath(&step)),
+			model:  ad.Spec.Model,
+			prompt: prompt,
+		})
+	}
+
+	if len(due) > 0 {
+		// Record the attempts before running so a controller restart does not retry forever
+		start := metav1.Now()
+		if wf.Status.StartTime == nil {
+			wf.Status.StartTime = &start
+		}
+		for _, a := range due {
+			st := &wf.Status.Steps[a.index]
+			st.Phase = agentopsv1alpha1.WorkflowRunning
+			st.Attempts++
+			st.StartTime = &start
+			st.Message = ""
+		}
+		r.updatePhase(wf)
+		if err := patchStatus(ctx, r.Client, wf); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.runSteps(ctx, wf, due)
+	}
+	r.interruptedSteps(wf)
+
+	r.updatePhase(wf)
+	if wf.Finished() {
+		log.Info("AgentWorkflow finished", "Phase", wf.Status.Phase)
+		workflowsTotal.WithLabelValues(wf.Namespace, wf.Status.Phase).Inc()
+		if wf.Status.StartTime != nil {
+			workflowDuration.WithLabelValues(wf.Namespace, wf.Status.Phase).
+				Observe(wf.Status.CompletionTime.Sub(wf.Status.StartTime.Time).Seconds())
+		}
+	}
+	if err := patchStatus(ctx, r.Client, wf); err != nil {
+		return ctrl.Result{}, err
+	}
+	if wf.Finished() {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: requeue}, nil
+}
+
+// runSteps sends the due steps to their agents concurrently and records the outcome
+// of each attempt
+func (r *AgentWorkflowReconciler) runSteps(ctx context.Context, wf *agentopsv1alpha1.AgentWorkflow, due []stepAttempt) {
+	var wg sync.WaitGroup
+	for _, a := range due {
+		wg.Add(1)
+		go func(a stepAttempt) {
+			defer wg.Done()
+			step := &wf.Spec.Steps[a.index]
+			st := &wf.Status.Steps[a.index]
+			stepCtx, cancel := context.WithTimeout(ctx, stepTimeout(step))
+			defer cancel()
+
+			start := time.Now()
+			output, err := r.Runner.Complete(stepCtx, a.url, a.model, step.System, a.prompt)
+			outcome := "succeeded"
+			if err != nil {
+				outcome = "failed"
+			}
+			workflowStepDuration.WithLabelValues(wf.Namespace, step.AgentDeployment, outcome).Observe(time.Since(start).Seconds())
+
+			end := metav1.Now()
+			st.CompletionTime = &end
+			switch {
+			case err == nil:
+				st.Phase = agentopsv1alpha1.WorkflowSucceeded
+				st.Output = truncate(output, maxStepOutput)
+			case st.Attempts > step.Retries:
+				st.Phase = agentopsv1alpha1.WorkflowFailed
+				st.Message = fmt.Sprintf("Attempt %d failed: %v", st.Attempts, err)
+			default:
+				st.Phase = agentopsv1alpha1.WorkflowPending
+				st.Message = fmt.Sprintf("Attempt %d failed, retrying: %v", st.Attempts, err)
+			}
+		}(a)
+	}
+	wg.Wait()
+}
+
+// interruptedSteps fails the steps left running by a controller restart once they
+// used up their retries, and retries the others
+func (r *AgentWorkflowReconciler) interruptedSteps(wf *agentopsv1alpha1.AgentWorkflow) {
+	for i, step := range wf.Spec.Steps {
+		st := &wf.Status.Steps[i]
+		if st.Phase != agentopsv1alpha1.WorkflowRunning {
+			continue
+		}
+		now := metav1.Now()
+		st.CompletionTime = &now
+		st.Message = fmt.Sprintf("Attempt %d interrupted", st.Attempts)
+		st.Phase = agentopsv1alpha1.WorkflowPending
+		if st.Attempts > step.Retries {
+			st.Phase = agentopsv1alpha1.WorkflowFailed
+		}
+	}
+}
+
+// updatePhase derives the phase of the workflow from its steps. A failed step fails
+// the workflow and skips the steps that did not start; once every step succeeded
+// the output is rendered.
+func (r *AgentWorkflowReconciler) updatePhase(wf *agentopsv1alpha1.AgentWorkflow) {
+	var succeeded, running int
+	var failed []string
+	for _, st := range wf.Status.Steps {
+		switch st.Phase {
+		case agentopsv1alpha1.WorkflowSucceeded:
+			succeeded++
+		case agentopsv1alpha1.WorkflowRunning:
+			running++
+		case agentopsv1alpha1.WorkflowFailed:
+			failed = append(failed, st.Name)
+		}
+	}
+
+	switch {
+	case len(failed) > 0 && running == 0:
+		for i := range wf.Status.Steps {
+			if wf.Status.Steps[i].Phase == agentopsv1alpha1.WorkflowPending {
+				wf.Status.Steps[i].Phase = agentopsv1alpha1.WorkflowSkipped
+			}
+		}
+		r.finish(wf, agentopsv1alpha1.WorkflowFailed, conditions.ReasonWorkflowFailed,
+			fmt.Sprintf("Step %s failed", strings.Join(failed, ", ")))
+	case succeeded == len(wf.Spec.Steps):
+		data := workflowData{Input: wf.Spec.Input, Steps: map[string]string{}}
+		for _, st := range wf.Status.Steps {
+			data.Steps[st.Name] = st.Output
+		}
+		output := wf.Status.Steps[len(wf.Status.Steps)-1].Output
+		if wf.Spec.Output != "" {
+			var err error
+			if output, err = renderWorkflowTemplate("output", wf.Spec.Output, data); err != nil {
+				r.finish(wf, agentopsv1alpha1.WorkflowFailed, conditions.ReasonWorkflowFailed,
+					fmt.Sprintf("Cannot render output: %v", err))
+				return
+			}
+		}
+		wf.Status.Output = truncate(output, maxStepOutput)
+		r.finish(wf, agentopsv1alpha1.WorkflowSucceeded, conditions.ReasonWorkflowSucceeded,
+			fmt.Sprintf("%d steps succeeded", succeeded))
+	default:
+		wf.Status.Phase = agentopsv1alpha1.WorkflowPending
+		if wf.Status.StartTime != nil {
+			wf.Status.Phase = agentopsv1alpha1.WorkflowRunning
+		}
+		wf.Status.Message = fmt.Sprintf("%d/%d steps succeeded", succeeded, len(wf.Spec.Steps))
+		conditions.Set(&wf.Status.Conditions, conditions.Complete, metav1.ConditionFalse, conditions.ReasonWorkflowRunning,
+			wf.Status.Message, wf.Generation)
+	}
+}
+
+// finish moves the workflow to a terminal phase
+func (r *AgentWorkflowReconciler) finish(wf *agentopsv1alpha1.AgentWorkflow, phase, reason, message string) {
+	now := metav1.Now()
+	wf.Status.Phase = phase
+	wf.Status.Message = message
+	wf.Status.CompletionTime = &now
+	status := metav1.ConditionFalse
+	if phase == agentopsv1alpha1.WorkflowSucceeded {
+		status = metav1.ConditionTrue
+	}
+	conditions.Set(&wf.Status.Conditions, conditions.Complete, status, reason, message, wf.Generation)
+}
+
+// validateWorkflow checks that the steps depend on existing steps without cycles
+func validateWorkflow(wf *agentopsv1alpha1.AgentWorkflow) error {
+	deps := map[string][]string{}
+	for _, step := range wf.Spec.Steps {
+		if _, dup := deps[step.Name]; dup {
+			return fmt.Errorf("step %s is defined twice", step.Name)
+		}
+		deps[step.Name] = step.DependsOn
+	}
+	for _, step := range wf.Spec.Steps {
+		for _, dep := range step.DependsOn {
+			if _, ok := deps[dep]; !ok {
+				return fmt.Errorf("step %s depends on unknown step %s", step.Name, dep)
+			}
+		}
+	}
+
+	// Depth-first search; a step reached again while on the path closes a cycle
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("steps form a cycle: %s", strings.Join(append(path, name), " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range deps[name] {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, step := range wf.Spec.Steps {
+		if err := visit(step.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// initStepStatuses adds a pending status for every step that has none
+func initStepStatuses(wf *agentopsv1alpha1.AgentWorkflow) {
+	if len(wf.Status.Steps) == len(wf.Spec.Steps) {
+		return
+	}
+	statuses := make([]agentopsv1alpha1.WorkflowStepStatus, len(wf.Spec.Steps))
+	for i, step := range wf.Spec.Steps {
+		statuses[i] = agentopsv1alpha1.WorkflowStepStatus{Name: step.Name, Phase: agentopsv1alpha1.WorkflowPending}
+		for _, st := range wf.Status.Steps {
+			if st.Name == step.Name {
+				statuses[i] = st
+			}
+		}
+	}
+	wf.Status.Steps = statuses
+}
+
+// dependenciesSucceeded reports whether every step the step depends on succeeded
+func dependenciesSucceeded(wf *agentopsv1alpha1.AgentWorkflow, step agentopsv1alpha1.WorkflowStep) bool {
+	for _, dep := range step.DependsOn {
+		for _, st := range wf.Status.Steps {
+			if st.Name == dep && st.Phase != agentopsv1alpha1.WorkflowSucceeded {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// renderWorkflowTemplate renders a prompt or output template of a workflow
+func renderWorkflowTemplate(name, text string, data workflowData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// stepPath returns the chat endpoint the prompt of the step is sent to
+func stepPath(step *agentopsv1alpha1.WorkflowStep) string {
+	if step.Path == "" {
+		return defaultEvaluationPath
+	}
+	return step.Path
+}
+
+// stepTimeout returns how long an attempt of the step may take
+func stepTimeout(step *agentopsv1alpha1.WorkflowStep) time.Duration {
+	if step.Timeout == nil {
+		return defaultStepTimeout
+	}
+	return step.Timeout.Duration
+}
+
+// minRequeue returns the earlier of two requeue delays, ignoring unset ones
+func minRequeue(current, next time.Duration) time.Duration {
+	if current == 0 || next < current {
+		return next
+	}
+	return current
+}
+
+// truncate cuts s to at most max bytes
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *AgentWorkflowReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.AgentWorkflow{}).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("AgentWorkflow", tracing.Reconciler("AgentWorkflow", r)))
+}
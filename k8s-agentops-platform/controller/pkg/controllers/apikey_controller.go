@@ -0,0 +1,399 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/keybroker"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	// apiKeyFinalizer revokes the keys of an AgentDeployment before it is deleted
+	apiKeyFinalizer = "agentops.io/api-keys"
+	// apiKeyLabel marks the Secrets holding keys issued by the key broker
+	apiKeyLabel = "agentops.io/api-key"
+	// apiKeyAnnotationPrefix prefixes the annotation recording the state of each key
+	// of a Secret
+	apiKeyAnnotationPrefix = "keys.agentops.io/"
+	// keyRevokeDelay is how long a replaced key keeps working, so pods still running
+	// with it drain before it is revoked
+	keyRevokeDelay = time.Hour
+)
+
+// KeyBroker issues and revokes the virtual API keys of agents
+type KeyBroker interface {
+	Issue(ctx context.Context, req keybroker.Request) (*keybroker.Key, error)
+	Revoke(ctx context.Context, id string) error
+}
+
+// APIKeyReconciler provisions the Secrets of AgentDeployment secrets with the broker
+// provider: every agent gets keys of its own from the key broker instead of a shared
+// provider key, replaced every rotationPeriod
+type APIKeyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Broker issues the keys; nil disables the broker provider
+	Broker KeyBroker
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// apiKeyState is recorded on the Secret for each key it holds
+type apiKeyState struct {
+	// ID of the key at the broker
+	ID string `json:"id"`
+	// IssuedAt is when the key was issued
+	IssuedAt metav1.Time `json:"issuedAt"`
+	// PreviousID is the replaced key, revoked at RevokeAt
+	PreviousID string       `json:"previousID,omitempty"`
+	RevokeAt   *metav1.Time `json:"revokeAt,omitempty"`
+	// StaleIDs are older keys the broker failed to revoke, retried at RevokeAt
+	StaleIDs []string `json:"staleIDs,omitempty"`
+}
+
+// brokerSecrets returns the secret references of ad using the broker provider,
+// grouped by Secret name
+func brokerSecrets(ad *agentopsv1alpha1.AgentDeployment) map[string][]agentopsv1alpha1.SecretReference {
+	secrets := map[string][]agentopsv1alpha1.SecretReference{}
+	for _, s := range ad.Spec.Secrets {
+		if secretProvider(s) == agentopsv1alpha1.SecretProviderBroker {
+			secrets[s.Name] = append(secrets[s.Name], s)
+		}
+	}
+	return secrets
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile issues the keys of an AgentDeployment, rotates them when due and revokes
+// the keys it no longer uses
+func (r *APIKeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("agentdeployment", req.NamespacedName)
+
+	ad := &agentopsv1alpha1.AgentDeployment{}
+	if err := r.Get(ctx, req.NamespacedName, ad); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get AgentDeployment")
+		return ctrl.Result{}, err
+	}
+	wanted := brokerSecrets(ad)
+	if r.Broker == nil {
+		// Without a broker (or in observe mode) keys are neither issued nor revoked
+		if len(wanted) > 0 {
+			log.Info("Secrets with provider broker are not provisioned without --key-broker-url")
+		}
+		if !ad.DeletionTimestamp.IsZero() && controllerutil.RemoveFinalizer(ad, apiKeyFinalizer) {
+			return ctrl.Result{}, r.Update(ctx, ad)
+		}
+		return ctrl.Result{}, nil
+	}
+	if !ad.DeletionTimestamp.IsZero() {
+		wanted = nil
+	}
+
+	owned, err := r.keySecrets(ctx, ad)
+	if err != nil {
+		log.Error(err, "Failed to list API key Secrets")
+		return ctrl.Result{}, err
+	}
+	// Keys of secrets dropped from the spec, or of a deleted agent, are revoked
+	for i := range owned {
+		secret := &owned[i]
+		if _, ok := wanted[secret.Name]; ok {
+			continue
+		}
+		if err := r.revokeSecret(ctx, secret); err != nil {
+			log.Error(err, "Failed to revoke API keys", "Secret", secret.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if len(wanted) == 0 {
+		if controllerutil.RemoveFinalizer(ad, apiKeyFinalizer) {
+			return ctrl.Result{}, r.Update(ctx, ad)
+		}
+		return ctrl.Result{}, nil
+	}
+	if controllerutil.AddFinalizer(ad, apiKeyFinalizer) {
+		if err := r.Update(ctx, ad); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	names := make([]string, 0, len(wanted))
+	for name := range wanted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var next time.Time
+	for _, name := range names {
+		due, err := r.reconcileKeySecret(ctx, ad, name, wanted[name])
+		if err != nil {
+			log.Error(err, "Failed to reconcile API key Secret", "Secret", name)
+			return ctrl.Result{}, err
+		}
+		if !due.IsZero() && (next.IsZero() || due.Before(next)) {
+			next = due
+		}
+	}
+	if next.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: time.Until(next) + time.Second}, nil
+}
+
+// keySecrets returns the API key Secrets controlled by ad
+func (r *APIKeyReconciler) keySecrets(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) ([]corev1.Secret, error) {
+	labels := labelsForAgentDeployment(ad.Name)
+	labels[apiKeyLabel] = "true"
+	list := &corev1.SecretList{}
+	if err := r.List(ctx, list, client.InNamespace(ad.Namespace), client.MatchingLabels(labels)); err != nil {
+		return nil, err
+	}
+	var secrets []corev1.Secret
+	for _, s := range list.Items {
+		if metav1.IsControlledBy(&s, ad) {
+			secrets = append(secrets, s)
+		}
+	}
+	return secrets, nil
+}
+
+// reconcileKeySecret brings the keys of a Secret in line with its references and
+// returns when the next rotation or revocation is due
+func (r *APIKeyReconciler) reconcileKeySecret(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, name string, refs []agentopsv1alpha1.SecretReference) (time.Time, error) {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: ad.Namespace}, secret)
+	found := err == nil
+	if errors.IsNotFound(err) {
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ad.Namespace}}
+		if err := controllerutil.SetControllerReference(ad, secret, r.Scheme); err != nil {
+			return time.Time{}, err
+		}
+	} else if err != nil {
+		return time.Time{}, err
+	} else if !metav1.IsControlledBy(secret, ad) {
+		return time.Time{}, fmt.Errorf("secret %s exists and is not owned by the AgentDeployment", name)
+	}
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	for k, v := range labelsForAgentDeployment(ad.Name) {
+		secret.Labels[k] = v
+	}
+	secret.Labels[apiKeyLabel] = "true"
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	now := time.Now()
+	var next time.Time
+	keys := map[string]bool{}
+	for _, ref := range refs {
+		keys[ref.Key] = true
+		due, err := r.reconcileKey(ctx, ad, secret, ref, now)
+		// Keys issued before a failure must be stored, or they would leak
+		if err != nil {
+			if found || len(secret.Data) > 0 {
+				if uerr := r.saveKeySecret(ctx, secret, found); uerr != nil {
+					return time.Time{}, uerr
+				}
+			}
+			return time.Time{}, err
+		}
+		if !due.IsZero() && (next.IsZero() || due.Before(next)) {
+			next = due
+		}
+	}
+	// Keys dropped from a Secret still referenced are revoked too
+	for annotation := range secret.Annotations {
+		key := strings.TrimPrefix(annotation, apiKeyAnnotationPrefix)
+		if key == annotation || keys[key] {
+			continue
+		}
+		if err := r.revokeKey(ctx, secret, key); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return next, r.saveKeySecret(ctx, secret, found)
+}
+
+// saveKeySecret creates or updates the Secret
+func (r *APIKeyReconciler) saveKeySecret(ctx context.Context, secret *corev1.Secret, found bool) error {
+	if !found {
+		r.Log.Info("Creating API key Secret", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name)
+		return r.Create(ctx, secret)
+	}
+	return r.Update(ctx, secret)
+}
+
+// reconcileKey issues the key of ref when it is missing or due for rotation, revokes
+// the key it replaced once the grace period is over, and returns when it next needs
+// attention
+func (r *APIKeyReconciler) reconcileKey(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, secret *corev1.Secret, ref agentopsv1alpha1.SecretReference, now time.Time) (time.Time, error) {
+	annotation := apiKeyAnnotationPrefix + ref.Key
+	state := apiKeyState{}
+	if raw, ok := secret.Annotations[annotation]; ok {
+		if err := json.Unmarshal([]byte(raw), &state); err != nil {
+			return time.Time{}, fmt.Errorf("invalid annotation %s: %w", annotation, err)
+		}
+	}
+
+	rotate := state.ID == "" || len(secret.Data[ref.Key]) == 0
+	if ref.RotationPeriod != nil && ref.RotationPeriod.Duration > 0 &&
+		!now.Before(state.IssuedAt.Add(ref.RotationPeriod.Duration)) {
+		rotate = true
+	}
+	if rotate {
+		key, err := r.Broker.Issue(ctx, keybroker.Request{
+			Namespace:       ad.Namespace,
+			AgentDeployment: ad.Name,
+			Secret:          secret.Name,
+			Key:             ref.Key,
+			Provider:        ad.Spec.Provider,
+			Model:           ad.Spec.Model,
+		})
+		if err != nil {
+			return time.Time{}, fmt.Errorf("issue key %s: %w", ref.Key, err)
+		}
+		r.Log.Info("Issued API key", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name, "Key", ref.Key, "ID", key.ID)
+		// A key replaced again before its grace period ended goes right away. The new
+		// key is recorded regardless: a key the broker fails to revoke now is retried
+		// at the end of the grace period instead.
+		stale := state.StaleIDs
+		if state.PreviousID != "" {
+			if err := r.Broker.Revoke(ctx, state.PreviousID); err != nil {
+				r.Log.Error(err, "Failed to revoke API key, retrying at the end of the grace period",
+					"Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name, "Key", ref.Key, "ID", state.PreviousID)
+				stale = append(stale, state.PreviousID)
+			}
+		}
+		revokeAt := metav1.NewTime(now.Add(keyRevokeDelay))
+		state = apiKeyState{ID: key.ID, IssuedAt: metav1.NewTime(now), PreviousID: state.ID, StaleIDs: stale}
+		if state.PreviousID != "" || len(state.StaleIDs) > 0 {
+			state.RevokeAt = &revokeAt
+		}
+		secret.Data[ref.Key] = []byte(key.Value)
+	}
+
+	if state.RevokeAt != nil && !now.Before(state.RevokeAt.Time) {
+		var failed []string
+		var revokeErr error
+		for _, id := range append([]string{state.PreviousID}, state.StaleIDs...) {
+			if id == "" {
+				continue
+			}
+			if err := r.Broker.Revoke(ctx, id); err != nil {
+				failed = append(failed, id)
+				revokeErr = fmt.Errorf("revoke key %s: %w", id, err)
+				continue
+			}
+			r.Log.Info("Revoked API key", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name, "Key", ref.Key, "ID", id)
+		}
+		state.PreviousID, state.StaleIDs = "", failed
+		if len(failed) == 0 {
+			state.RevokeAt = nil
+		}
+		// Keys revoked so far are recorded before the failure is retried
+		if revokeErr != nil {
+			if err := setKeyState(secret, annotation, state); err != nil {
+				return time.Time{}, err
+			}
+			return time.Time{}, revokeErr
+		}
+	}
+
+	if err := setKeyState(secret, annotation, state); err != nil {
+		return time.Time{}, err
+	}
+	var next time.Time
+	if ref.RotationPeriod != nil && ref.RotationPeriod.Duration > 0 {
+		next = state.IssuedAt.Add(ref.RotationPeriod.Duration)
+	}
+	if state.RevokeAt != nil && (next.IsZero() || state.RevokeAt.Before(&metav1.Time{Time: next})) {
+		next = state.RevokeAt.Time
+	}
+	return next, nil
+}
+
+// setKeyState records the state of a key in its annotation on the Secret
+func setKeyState(secret *corev1.Secret, annotation string, state apiKeyState) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	secret.Annotations[annotation] = string(raw)
+	return nil
+}
+
+// revokeKey revokes a key of the Secret, and the keys it replaced, and removes it
+func (r *APIKeyReconciler) revokeKey(ctx context.Context, secret *corev1.Secret, key string) error {
+	annotation := apiKeyAnnotationPrefix + key
+	state := apiKeyState{}
+	if err := json.Unmarshal([]byte(secret.Annotations[annotation]), &state); err != nil {
+		return fmt.Errorf("invalid annotation %s: %w", annotation, err)
+	}
+	for _, id := range append(append([]string{}, state.StaleIDs...), state.PreviousID, state.ID) {
+		if id == "" {
+			continue
+		}
+		if err := r.Broker.Revoke(ctx, id); err != nil {
+			return fmt.Errorf("revoke key %s: %w", id, err)
+		}
+		r.Log.Info("Revoked API key", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name, "Key", key, "ID", id)
+	}
+	delete(secret.Annotations, annotation)
+	delete(secret.Data, key)
+	return nil
+}
+
+// revokeSecret revokes every key of the Secret and deletes it
+func (r *APIKeyReconciler) revokeSecret(ctx context.Context, secret *corev1.Secret) error {
+	for annotation := range secret.Annotations {
+		if key := strings.TrimPrefix(annotation, apiKeyAnnotationPrefix); key != annotation {
+			if err := r.revokeKey(ctx, secret, key); err != nil {
+				return err
+			}
+		}
+	}
+	r.Log.Info("Deleting API key Secret", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name)
+	return client.IgnoreNotFound(r.Delete(ctx, secret))
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *APIKeyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("apikey").
+		For(&agentopsv1alpha1.AgentDeployment{}).
+		Owns(&corev1.Secret{}).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("APIKey", tracing.Reconciler("APIKey", r)))
+}
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/keybroker"
+)
+
+// fakeBroker issues numbered keys and records revocations, failing those in failRevoke
+type fakeBroker struct {
+	issued     int
+	revoked    []string
+	failRevoke map[string]bool
+}
+
+func (b *fakeBroker) Issue(_ context.Context, _ keybroker.Request) (*keybroker.Key, error) {
+	b.issued++
+	id := fmt.Sprintf("key-%d", b.issued)
+	return &keybroker.Key{ID: id, Value: "value-" + id}, nil
+}
+
+func (b *fakeBroker) Revoke(_ context.Context, id string) error {
+	if b.failRevoke[id] {
+		return fmt.Errorf("broker unavailable")
+	}
+	b.revoked = append(b.revoked, id)
+	return nil
+}
+
+func TestReconcileKey(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	rotation := &metav1.Duration{Duration: 24 * time.Hour}
+	past := metav1.NewTime(now.Add(-time.Minute))
+	future := metav1.NewTime(now.Add(time.Minute))
+
+	tests := []struct {
+		name        string
+		state       *apiKeyState
+		value       string
+		failRevoke  []string
+		wantErr     bool
+		wantIssued  int
+		wantRevoked []string
+		wantState   apiKeyState
+		wantValue   string
+	}{
+		{
+			name:       "issue missing key",
+			wantIssued: 1,
+			wantState:  apiKeyState{ID: "key-1", IssuedAt: metav1.NewTime(now)},
+			wantValue:  "value-key-1",
+		},
+		{
+			name:      "keep key within rotation period",
+			state:     &apiKeyState{ID: "old", IssuedAt: metav1.NewTime(now.Add(-time.Hour))},
+			value:     "old-value",
+			wantState: apiKeyState{ID: "old", IssuedAt: metav1.NewTime(now.Add(-time.Hour))},
+			wantValue: "old-value",
+		},
+		{
+			name:       "rotate due key",
+			state:      &apiKeyState{ID: "old", IssuedAt: metav1.NewTime(now.Add(-25 * time.Hour))},
+			value:      "old-value",
+			wantIssued: 1,
+			wantState: apiKeyState{ID: "key-1", IssuedAt: metav1.NewTime(now), PreviousID: "old",
+				RevokeAt: timePtr(metav1.NewTime(now.Add(keyRevokeDelay)))},
+			wantValue: "value-key-1",
+		},
+		{
+			name:        "revoke replaced key after grace period",
+			state:       &apiKeyState{ID: "new", IssuedAt: metav1.NewTime(now.Add(-2 * time.Hour)), PreviousID: "old", RevokeAt: &past},
+			value:       "new-value",
+			wantRevoked: []string{"old"},
+			wantState:   apiKeyState{ID: "new", IssuedAt: metav1.NewTime(now.Add(-2 * time.Hour))},
+			wantValue:   "new-value",
+		},
+		{
+			name:      "keep replaced key within grace period",
+			state:     &apiKeyState{ID: "new", IssuedAt: metav1.NewTime(now.Add(-time.Minute)), PreviousID: "old", RevokeAt: &future},
+			value:     "new-value",
+			wantState: apiKeyState{ID: "new", IssuedAt: metav1.NewTime(now.Add(-time.Minute)), PreviousID: "old", RevokeAt: &future},
+			wantValue: "new-value",
+		},
+		{
+			name:        "rotate again within grace period revokes replaced key",
+			state:       &apiKeyState{ID: "new", IssuedAt: metav1.NewTime(now.Add(-25 * time.Hour)), PreviousID: "old", RevokeAt: &future},
+			value:       "new-value",
+			wantIssued:  1,
+			wantRevoked: []string{"old"},
+			wantState: apiKeyState{ID: "key-1", IssuedAt: metav1.NewTime(now), PreviousID: "new",
+				RevokeAt: timePtr(metav1.NewTime(now.Add(keyRevokeDelay)))},
+			wantValue: "value-key-1",
+		},
+		{
+			name:       "failed early revoke keeps new key and schedules old one",
+			state:      &apiKeyState{ID: "new", IssuedAt: metav1.NewTime(now.Add(-25 * time.Hour)), PreviousID: "old", RevokeAt: &future},
+			value:      "new-value",
+			failRevoke: []string{"old"},
+			wantIssued: 1,
+			wantState: apiKeyState{ID: "key-1", IssuedAt: metav1.NewTime(now), PreviousID: "new",
+				RevokeAt: timePtr(metav1.NewTime(now.Add(keyRevokeDelay))), StaleIDs: []string{"old"}},
+			wantValue: "value-key-1",
+		},
+		{
+			name: "failed revoke after grace period is recorded and retried",
+			state: &apiKeyState{ID: "new", IssuedAt: metav1.NewTime(now.Add(-2 * time.Hour)), PreviousID: "old",
+				RevokeAt: &past, StaleIDs: []string{"older"}},
+			value:       "new-value",
+			failRevoke:  []string{"older"},
+			wantErr:     true,
+			wantRevoked: []string{"old"},
+			wantState: apiKeyState{ID: "new", IssuedAt: metav1.NewTime(now.Add(-2 * time.Hour)),
+				RevokeAt: &past, StaleIDs: []string{"older"}},
+			wantValue: "new-value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := &fakeBroker{failRevoke: map[string]bool{}}
+			for _, id := range tt.failRevoke {
+				broker.failRevoke[id] = true
+			}
+			r := &APIKeyReconciler{Broker: broker, Log: logr.Discard()}
+			ad := &agentopsv1alpha1.AgentDeployment{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"}}
+			ref := agentopsv1alpha1.SecretReference{Name: "llm-keys", Key: "API_KEY", RotationPeriod: rotation}
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "llm-keys", Namespace: "default", Annotations: map[string]string{}},
+				Data:       map[string][]byte{},
+			}
+			if tt.state != nil {
+				raw, _ := json.Marshal(tt.state)
+				secret.Annotations[apiKeyAnnotationPrefix+ref.Key] = string(raw)
+				secret.Data[ref.Key] = []byte(tt.value)
+			}
+
+			_, err := r.reconcileKey(context.Background(), ad, secret, ref, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("reconcileKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if broker.issued != tt.wantIssued {
+				t.Errorf("issued %d keys, want %d", broker.issued, tt.wantIssued)
+			}
+			if !reflect.DeepEqual(broker.revoked, tt.wantRevoked) {
+				t.Errorf("revoked %v, want %v", broker.revoked, tt.wantRevoked)
+			}
+
+			var state apiKeyState
+			if err := json.Unmarshal([]byte(secret.Annotations[apiKeyAnnotationPrefix+ref.Key]), &state); err != nil {
+				t.Fatalf("invalid state annotation: %v", err)
+			}
+			want, _ := json.Marshal(tt.wantState)
+			got, _ := json.Marshal(state)
+			if string(got) != string(want) {
+				t.Errorf("state = %s, want %s", got, want)
+			}
+			if v := string(secret.Data[ref.Key]); v != tt.wantValue {
+				t.Errorf("secret value = %q, want %q", v, tt.wantValue)
+			}
+		})
+	}
+}
+
+func timePtr(t metav1.Time) *metav1.Time { return &t }
@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	auditImage            = "timberio/vector:0.34.1-distroless-libc"
+	auditContainerName    = "audit"
+	auditConfigKey        = "vector.yaml"
+	auditConfigVolume     = "audit-config"
+	auditDataVolume       = "audit-data"
+	auditConfigAnnotation = "agentops.io/audit-config-hash"
+	auditListenAddress    = "127.0.0.1:8687"
+	auditEndpointEnv      = "AGENTOPS_AUDIT_ENDPOINT"
+	auditReplacement      = "[REDACTED]"
+)
+
+// piiRedactions are masked with spec.audit.redactPII
+var piiRedactions = []agentopsv1alpha1.AuditRedaction{
+	{Name: "email", Pattern: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`, Replacement: "[EMAIL]"},
+	{Name: "credit-card", Pattern: `\b(?:\d[ -]?){13,16}\b`, Replacement: "[CARD]"},
+	{Name: "us-ssn", Pattern: `\b\d{3}-\d{2}-\d{4}\b`, Replacement: "[SSN]"},
+	{Name: "phone", Pattern: `\+?\d{1,3}[ .-]?\(?\d{3}\)?[ .-]?\d{3}[ .-]?\d{4}\b`, Replacement: "[PHONE]"},
+}
+
+// auditEnabled reports whether the agent runs the audit sidecar
+func auditEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.Audit != nil
+}
+
+// auditConfigMapName returns the name of the audit sidecar ConfigMap
+func auditConfigMapName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-audit"
+}
+
+// applyAuditPolicy makes the agent follow the mandatory audit of its namespace. An
+// agent without spec.audit gets the policy's; one with its own keeps its sink and
+// gains the policy's redactions.
+func applyAuditPolicy(ad *agentopsv1alpha1.AgentDeployment, policy *agentopsv1alpha1.AuditPolicy) {
+	if ad.Spec.Audit == nil {
+		ad.Spec.Audit = policy.AuditSpec.DeepCopy()
+		return
+	}
+	audit := ad.Spec.Audit
+	if policy.RedactPII == nil || *policy.RedactPII {
+		redact := true
+		audit.RedactPII = &redact
+	}
+	audit.Redactions = append(audit.Redactions, policy.Redactions...)
+}
+
+// auditRedactions returns the redactions applied to the agent's audit records, in
+// order
+func auditRedactions(ad *agentopsv1alpha1.AgentDeployment) []agentopsv1alpha1.AuditRedaction {
+	audit := ad.Spec.Audit
+	var rules []agentopsv1alpha1.AuditRedaction
+	if audit.RedactPII == nil || *audit.RedactPII {
+		rules = append(rules, piiRedactions...)
+	}
+	return append(rules, audit.Redactions...)
+}
+
+// auditEnvForAgentDeployment returns the environment telling the agent runtime where
+// to post audit records, or nil when auditing is off
+func auditEnvForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) []corev1.EnvVar {
+	if !auditEnabled(ad) {
+		return nil
+	}
+	return []corev1.EnvVar{{Name: auditEndpointEnv, Value: "http://" + auditListenAddress}}
+}
+
+// vrlRegex returns pattern as a VRL regex literal
+func vrlRegex(pattern string) string {
+	return "r'" + strings.ReplaceAll(pattern, "'", `\'`) + "'"
+}
+
+// auditConfig renders the Vector configuration of the audit sidecar: records posted
+// by the agent are tagged with the agent, redacted and shipped to the sink
+func auditConfig(ad *agentopsv1alpha1.AgentDeployment) (string, error) {
+	audit := ad.Spec.Audit
+	source := []string{fmt.Sprintf(
+		`.agentops = {"namespace": %q, "agent": %q, "model": %q, "pod": get_env_var("AGENTOPS_POD_NAME") ?? ""}`,
+		ad.Namespace, ad.Name, ad.Spec.Model)}
+	for _, rule := range auditRedactions(ad) {
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return "", fmt.Errorf("invalid audit redaction %s: %w", rule.Name, err)
+		}
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = auditReplacement
+		}
+		source = append(source, fmt.Sprintf(`. = redact(., filters: [%s], redactor: {"type": "text", "replacement": %q})`,
+			vrlRegex(rule.Pattern), replacement))
+	}
+
+	encoding := map[string]interface{}{"codec": "json"}
+	var sink map[string]interface{}
+	switch s := audit.Sink; s.Type {
+	case agentopsv1alpha1.AuditSinkS3:
+		if s.S3 == nil {
+			return "", fmt.Errorf("spec.audit.sink.s3 is required for the S3 sink")
+		}
+		prefix := s.S3.Prefix
+		if prefix == "" {
+			prefix = "audit/"
+		}
+		sink = map[string]interface{}{
+			"type":        "aws_s3",
+			"bucket":      s.S3.Bucket,
+			"region":      s.S3.Region,
+			"key_prefix":  fmt.Sprintf("%s%s/%s/date=%%F/", prefix, ad.Namespace, ad.Name),
+			"compression": "gzip",
+			"encoding":    encoding,
+			"framing":     map[string]interface{}{"method": "newline_delimited"},
+		}
+		if s.S3.Endpoint != "" {
+			sink["endpoint"] = s.S3.Endpoint
+		}
+	case agentopsv1alpha1.AuditSinkLoki:
+		if s.Loki == nil {
+			return "", fmt.Errorf("spec.audit.sink.loki is required for the Loki sink")
+		}
+		labels := map[string]interface{}{"agent": ad.Name, "namespace": ad.Namespace, "model": ad.Spec.Model}
+		for k, v := range s.Loki.Labels {
+			labels[k] = v
+		}
+		sink = map[string]interface{}{
+			"type":     "loki",
+			"endpoint": s.Loki.URL,
+			"labels":   labels,
+			"encoding": encoding,
+		}
+		if s.Loki.TenantID != "" {
+			sink["tenant_id"] = s.Loki.TenantID
+		}
+	case agentopsv1alpha1.AuditSinkKafka:
+		if s.Kafka == nil {
+			return "", fmt.Errorf("spec.audit.sink.kafka is required for the Kafka sink")
+		}
+		sink = map[string]interface{}{
+			"type":              "kafka",
+			"bootstrap_servers": strings.Join(s.Kafka.Brokers, ","),
+			"topic":             s.Kafka.Topic,
+			"key_field":         "agentops.agent",
+			"encoding":          encoding,
+		}
+	default:
+		return "", fmt.Errorf("unknown audit sink type %q", s.Type)
+	}
+	sink["inputs"] = []string{"redact"}
+
+	config := map[string]interface{}{
+		"data_dir": "/var/lib/vector",
+		"sources": map[string]interface{}{
+			"agent": map[string]interface{}{
+				"type":     "http_server",
+				"address":  auditListenAddress,
+				"decoding": map[string]interface{}{"codec": "json"},
+			},
+		},
+		"transforms": map[string]interface{}{
+			"redact": map[string]interface{}{
+				"type":   "remap",
+				"inputs": []string{"agent"},
+				"source": strings.Join(source, "\n") + "\n",
+			},
+		},
+		"sinks": map[string]interface{}{"audit": sink},
+	}
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// auditSidecar returns the audit container and its volumes, and the hash of the
+// configuration that rolls the pods when it changes
+func auditSidecar(ad *agentopsv1alpha1.AgentDeployment) (corev1.Container, []corev1.Volume, string, error) {
+	config, err := auditConfig(ad)
+	if err != nil {
+		return corev1.Container{}, nil, "", err
+	}
+	sum := sha256.Sum256([]byte(config))
+
+	image := auditImage
+	if ad.Spec.Audit.Image != "" {
+		image = ad.Spec.Audit.Image
+	}
+	container := corev1.Container{
+		Name:  auditContainerName,
+		Image: image,
+		Args:  []string{"--config", "/etc/vector/" + auditConfigKey},
+		Env: []corev1.EnvVar{{Name: "AGENTOPS_POD_NAME", ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+		}}},
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("50m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			},
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: auditConfigVolume, MountPath: "/etc/vector", ReadOnly: true},
+			{Name: auditDataVolume, MountPath: "/var/lib/vector"},
+		},
+	}
+	if ref := ad.Spec.Audit.Sink.CredentialsSecretRef; ref != nil {
+		container.EnvFrom = []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: *ref}}}
+	}
+	volumes := []corev1.Volume{
+		{
+			Name: auditConfigVolume,
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: auditConfigMapName(ad)},
+			}},
+		},
+		{Name: auditDataVolume, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+	}
+	return container, volumes, hex.EncodeToString(sum[:8]), nil
+}
+
+// reconcileAuditConfigMap publishes the audit sidecar configuration, or removes it
+// when auditing is off
+func (r *AgentDeploymentReconciler) reconcileAuditConfigMap(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: auditConfigMapName(ad), Namespace: ad.Namespace},
+	}
+	if !auditEnabled(ad) {
+		if err := r.Delete(ctx, cm); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	config, err := auditConfig(ad)
+	if err != nil {
+		return err
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Labels = labelsForAgentDeployment(ad.Name)
+		cm.Data = map[string]string{auditConfigKey: config}
+		return controllerutil.SetControllerReference(ad, cm, r.Scheme)
+	})
+	return err
+}
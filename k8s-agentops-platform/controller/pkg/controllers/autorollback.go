@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/notify"
+)
+
+// autoRollbackEnabled reports whether failed rollouts of the agent are rolled back
+func autoRollbackEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.Upgrade != nil && ad.Spec.Upgrade.AutoRollback
+}
+
+// applyProgressDeadline sets the progress deadline of spec.upgrade on the Deployment
+func applyProgressDeadline(ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment) {
+	if ad.Spec.Upgrade != nil && ad.Spec.Upgrade.ProgressDeadlineSeconds != nil {
+		deadline := *ad.Spec.Upgrade.ProgressDeadlineSeconds
+		dep.Spec.ProgressDeadlineSeconds = &deadline
+	}
+}
+
+// rolloutFailure returns the reason and message of a failed rollout of the agent,
+// or an empty reason while the rollout is in flight or succeeded. Flagger reports
+// a failed analysis in the phase of its Canary; Deployments and Rollouts set their
+// Progressing condition once they observed the latest pod template.
+func (r *AgentDeploymentReconciler) rolloutFailure(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (string, string, error) {
+	if flaggerEnabled(ad) {
+		canary := newUnstructured(canaryGVK, ad.Name, ad.Namespace)
+		if err := r.Get(ctx, client.ObjectKeyFromObject(canary), canary); err != nil {
+			if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				return "", "", nil
+			}
+			return "", "", err
+		}
+		if phase, _, _ := unstructured.NestedString(canary.Object, "status", "phase"); phase == "Failed" {
+			return conditions.ReasonAnalysisFailed, "Flagger canary analysis failed", nil
+		}
+		return "", "", nil
+	}
+
+	dep := &appsv1.Deployment{}
+	var err error
+	if rolloutEnabled(ad) {
+		dep, err = r.getRollout(ctx, ad)
+	} else {
+		err = r.Get(ctx, client.ObjectKeyFromObject(ad), dep)
+	}
+	if err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	if dep.Status.ObservedGeneration < dep.Generation {
+		return "", "", nil
+	}
+	for _, c := range dep.Status.Conditions {
+		if c.Type != appsv1.DeploymentProgressing {
+			continue
+		}
+		switch c.Reason {
+		case "ProgressDeadlineExceeded":
+			return conditions.ReasonProgressDeadlineExceeded, c.Message, nil
+		case "RolloutAborted":
+			return conditions.ReasonAnalysisFailed, c.Message, nil
+		}
+	}
+	return "", "", nil
+}
+
+// autoRollback restores the revision before the current one when the rollout of the
+// current revision failed, and reports whether the AgentDeployment was updated. The
+// failure is recorded in the RolloutFailed condition at the generation of the
+// restored spec, so the restored revision is not rolled back in turn and the next
+// change of the spec is watched again. Only a spec the controller finished
+// reconciling is checked, so the workload status read belongs to it.
+func (r *AgentDeploymentReconciler) autoRollback(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (bool, error) {
+	if !autoRollbackEnabled(ad) || ad.Status.Revision == 0 || ad.Status.ObservedGeneration != ad.Generation {
+		return false, nil
+	}
+	if c := conditions.Get(ad.Status.Conditions, conditions.RolloutFailed); c != nil && c.Status == metav1.ConditionTrue && c.ObservedGeneration >= ad.Generation {
+		return false, nil
+	}
+	reason, message, err := r.rolloutFailure(ctx, ad)
+	if err != nil || reason == "" {
+		return false, err
+	}
+	failed := ad.Status.Revision
+
+	revisions, err := r.agentRevisions(ctx, ad)
+	if err != nil {
+		return false, err
+	}
+	var target *agentopsv1alpha1.AgentRevision
+	for i := range revisions {
+		if revisions[i].Spec.Revision < failed {
+			target = &revisions[i]
+		}
+	}
+
+	summary := fmt.Sprintf("rollout of revision %d failed", failed)
+	if target == nil {
+		summary += "; no earlier revision to roll back to"
+	} else {
+		summary += fmt.Sprintf("; rolling back to revision %d", target.Spec.Revision)
+		if err := r.restoreRevision(ctx, ad, target); err != nil {
+			return false, err
+		}
+	}
+	conditions.Set(&ad.Status.Conditions, conditions.RolloutFailed, metav1.ConditionTrue, reason,
+		fmt.Sprintf("Rollout of revision %d failed: %s", failed, message), ad.Generation)
+	r.event(ad, corev1.EventTypeWarning, "RolloutFailed", fmt.Sprintf("Rollout of revision %d failed (%s): %s", failed, reason, message))
+	r.notify(ctx, ad, notify.Notification{
+		Event:    notify.EventRolloutFailed,
+		Severity: notify.SeverityCritical,
+		Summary:  summary,
+		Message:  message,
+	})
+	return target != nil, patchStatus(ctx, r.Client, ad)
+}
+
+// resolveRolloutFailed clears RolloutFailed once a spec written after the failure is
+// rolled out completely
+func resolveRolloutFailed(ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment) {
+	c := conditions.Get(ad.Status.Conditions, conditions.RolloutFailed)
+	if c == nil || c.Status != metav1.ConditionTrue || c.ObservedGeneration >= ad.Generation || !rolloutComplete(dep, *dep.Spec.Replicas) {
+		return
+	}
+	conditions.Set(&ad.Status.Conditions, conditions.RolloutFailed, metav1.ConditionFalse, conditions.ReasonRolloutComplete,
+		"Latest pod template is rolled out", ad.Generation)
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// exceededBudget returns the first exceeded TokenBudget covering the agent, preferring
+// one that enforces scale to zero, or nil when the agent is within all its budgets
+func (r *AgentDeploymentReconciler) exceededBudget(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (*agentopsv1alpha1.TokenBudget, error) {
+	list := &agentopsv1alpha1.TokenBudgetList{}
+	if err := r.List(ctx, list, client.InNamespace(ad.Namespace)); err != nil {
+		return nil, err
+	}
+	var exceeded *agentopsv1alpha1.TokenBudget
+	for i := range list.Items {
+		budget := &list.Items[i]
+		if !budget.Status.Exceeded || !selectsAgentDeployment(budget.Spec.Selector, ad) {
+			continue
+		}
+		if budgetScalesToZero(budget) {
+			return budget, nil
+		}
+		if exceeded == nil {
+			exceeded = budget
+		}
+	}
+	return exceeded, nil
+}
+
+// budgetScalesToZero reports whether an exceeded budget takes the agent down
+func budgetScalesToZero(budget *agentopsv1alpha1.TokenBudget) bool {
+	return budget != nil && budget.Spec.Enforcement == agentopsv1alpha1.BudgetEnforcementScaleToZero
+}
+
+// budgetOverlay scales the Deployment to zero when an exceeded budget enforces it
+func budgetOverlay(budget *agentopsv1alpha1.TokenBudget) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		if budgetScalesToZero(budget) {
+			zero := int32(0)
+			dep.Spec.Replicas = &zero
+		}
+	}
+}
+
+// setBudgetCondition mirrors the state of the agent's budgets into its BudgetExceeded condition
+func setBudgetCondition(ad *agentopsv1alpha1.AgentDeployment, budget *agentopsv1alpha1.TokenBudget) {
+	if budget == nil {
+		if conditions.Get(ad.Status.Conditions, conditions.BudgetExceeded) != nil {
+			conditions.Set(&ad.Status.Conditions, conditions.BudgetExceeded, metav1.ConditionFalse, conditions.ReasonWithinBudget,
+				"All TokenBudgets covering the agent are within their limits", ad.Generation)
+		}
+		return
+	}
+	reason := conditions.ReasonTokenLimitExceeded
+	if c := conditions.Get(budget.Status.Conditions, conditions.BudgetExceeded); c != nil {
+		reason = c.Reason
+	}
+	message := fmt.Sprintf("TokenBudget %s exceeded", budget.Name)
+	if budgetScalesToZero(budget) {
+		message += "; scaled to zero until the next period"
+	}
+	conditions.Set(&ad.Status.Conditions, conditions.BudgetExceeded, metav1.ConditionTrue, reason, message, ad.Generation)
+}
@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/cost"
+)
+
+// capacityRetryInterval is how often the free GPUs are checked again while replicas
+// wait for them; node and foreign pod changes are not watched
+const capacityRetryInterval = time.Minute
+
+// gpuCapacity is the room the cluster has for more pods of a GPU-backed agent. The
+// nodes and their usage are read before the Deployment is rendered; the capacity
+// overlay fills in the rest from the rendered pod template.
+type gpuCapacity struct {
+	nodes []corev1.Node
+	// used are the GPUs requested by the pods bound to each node
+	used map[string]corev1.ResourceList
+	// running are the agent's pods bound to a node, which already hold their GPUs
+	running int32
+
+	resource corev1.ResourceName
+	perPod   int64
+	desired  int32
+	allowed  int32
+	fits     int32
+}
+
+// podGPUs returns the GPU resource and count one pod of spec requests. Extended
+// resources are always set as limits, which the requests equal.
+func podGPUs(spec *corev1.PodSpec) (corev1.ResourceName, int64) {
+	var name corev1.ResourceName
+	var count int64
+	for _, c := range spec.Containers {
+		for res, q := range c.Resources.Limits {
+			if cost.IsGPU(res) && (name == "" || name == res) {
+				name = res
+				count += q.Value()
+			}
+		}
+	}
+	return name, count
+}
+
+// requestsGPUs reports whether the agent container asks for GPUs
+func requestsGPUs(res corev1.ResourceRequirements) bool {
+	for _, list := range []corev1.ResourceList{res.Requests, res.Limits} {
+		for name := range list {
+			if cost.IsGPU(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// gpuCapacity reads the nodes and the GPUs their pods use, or returns nil for an
+// agent without GPUs. Only pods the controller's cache sees are counted, so with
+// --watch-namespaces GPUs used in other namespaces look free.
+func (r *AgentDeploymentReconciler) gpuCapacity(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (*gpuCapacity, error) {
+	if !requestsGPUs(resourcesForAgentDeployment(ad)) {
+		return nil, nil
+	}
+	nodes := &corev1.NodeList{}
+	if err := r.List(ctx, nodes); err != nil {
+		return nil, err
+	}
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods); err != nil {
+		return nil, err
+	}
+
+	c := &gpuCapacity{nodes: nodes.Items, used: map[string]corev1.ResourceList{}}
+	agent := labels.SelectorFromSet(labelsForAgentDeployment(ad.Name))
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if pod.Namespace == ad.Namespace && agent.Matches(labels.Set(pod.Labels)) && pod.DeletionTimestamp == nil {
+			c.running++
+		}
+		name, count := podGPUs(&pod.Spec)
+		if count == 0 {
+			continue
+		}
+		used := c.used[pod.Spec.NodeName]
+		if used == nil {
+			used = corev1.ResourceList{}
+			c.used[pod.Spec.NodeName] = used
+		}
+		q := used[name]
+		q.Add(*resource.NewQuantity(count, resource.DecimalSI))
+		used[name] = q
+	}
+	return c, nil
+}
+
+// podsFitting returns how many more pods of spec the free GPUs of the schedulable
+// nodes hold. Node selectors and taints are honored; node affinity is not.
+func (c *gpuCapacity) podsFitting(spec *corev1.PodSpec) int32 {
+	selector := labels.SelectorFromSet(spec.NodeSelector)
+	var fits int64
+	for i := range c.nodes {
+		node := &c.nodes[i]
+		if node.Spec.Unschedulable || !nodeReady(node) || !selector.Matches(labels.Set(node.Labels)) ||
+			!toleratesNode(spec.Tolerations, node) {
+			continue
+		}
+		allocatable := node.Status.Allocatable[c.resource]
+		used := c.used[node.Name][c.resource]
+		if free := allocatable.Value() - used.Value(); free > 0 {
+			fits += free / c.perPod
+		}
+	}
+	return int32(fits)
+}
+
+// nodeReady reports whether the node's kubelet reports Ready
+func nodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// toleratesNode reports whether the tolerations allow scheduling on the node
+func toleratesNode(tolerations []corev1.Toleration, node *corev1.Node) bool {
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for j := range tolerations {
+			if tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// capacityOverlay holds back the replicas the free GPUs cannot hold, as
+// spec.capacityPolicy asks. It runs last, on the final replica count. With
+// spec.autoscaling the HPA owns the replica count, so replicas are only reported.
+func capacityOverlay(ad *agentopsv1alpha1.AgentDeployment, c *gpuCapacity) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		if c == nil || dep.Spec.Replicas == nil {
+			return
+		}
+		c.resource, c.perPod = podGPUs(&dep.Spec.Template.Spec)
+		if c.perPod == 0 {
+			return
+		}
+		c.desired = *dep.Spec.Replicas
+		c.allowed = c.desired
+		c.fits = c.podsFitting(&dep.Spec.Template.Spec)
+		if c.desired <= c.running+c.fits || autoscalingEnabled(ad) {
+			return
+		}
+		switch ad.Spec.CapacityPolicy {
+		case agentopsv1alpha1.CapacityPolicyQueue:
+			c.allowed = c.running + c.fits
+		case agentopsv1alpha1.CapacityPolicyReject:
+			c.allowed = min(c.desired, c.running)
+		default:
+			return
+		}
+		dep.Spec.Replicas = &c.allowed
+	}
+}
+
+// setCapacityCondition records whether the free GPUs hold every desired replica
+func setCapacityCondition(ad *agentopsv1alpha1.AgentDeployment, c *gpuCapacity) {
+	if c == nil || c.perPod == 0 {
+		meta.RemoveStatusCondition(&ad.Status.Conditions, conditions.CapacityPending)
+		return
+	}
+	if c.desired <= c.running+c.fits {
+		conditions.Set(&ad.Status.Conditions, conditions.CapacityPending, metav1.ConditionFalse, conditions.ReasonAsExpected,
+			fmt.Sprintf("Free GPUs hold all %d replicas", c.desired), ad.Generation)
+		return
+	}
+	message := fmt.Sprintf("%d replicas need %d %s each and do not fit the free GPUs of any schedulable node",
+		c.desired-c.running-c.fits, c.perPod, c.resource)
+	if c.allowed < c.desired {
+		message += fmt.Sprintf("; holding %d of %d replicas until GPUs free up", c.allowed, c.desired)
+	} else {
+		message += "; their pods stay Pending until GPUs free up or nodes are added"
+	}
+	conditions.Set(&ad.Status.Conditions, conditions.CapacityPending, metav1.ConditionTrue, conditions.ReasonInsufficientGPUs,
+		message, ad.Generation)
+}
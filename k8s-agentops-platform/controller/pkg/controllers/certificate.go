@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+var certificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// tlsSecretName returns the Secret holding the certificate of an Ingress or Gateway
+func tlsSecretName(name string) string {
+	return name + "-tls"
+}
+
+// certificateHosts returns the hosts to issue certificates for, by the name of the
+// Secret the Ingress or Gateway reads the certificate from. Shared Gateways of the
+// gateway class terminate TLS with their own certificates.
+func certificateHosts(ad *agentopsv1alpha1.AgentDeployment) map[string]string {
+	hosts := map[string]string{}
+	if !ingressEnabled(ad) || !ad.Spec.Ingress.TLS || ad.Spec.Ingress.IssuerRef == nil {
+		return hosts
+	}
+	ownGateway := ad.Spec.Ingress.Gateway != nil && ad.Spec.Ingress.Gateway.GatewayClassName != ""
+	if host := ad.Spec.Ingress.Host; host != "" && (!gatewayClassEnabled(ad) || ownGateway) {
+		hosts[tlsSecretName(ad.Name)] = host
+	}
+	if !gatewayClassEnabled(ad) && grpcPort(ad) != nil && ad.Spec.Ingress.GRPCHost != "" {
+		hosts[tlsSecretName(grpcIngressName(ad))] = ad.Spec.Ingress.GRPCHost
+	}
+	return hosts
+}
+
+// certificateForAgentDeployment returns the cert-manager Certificate writing the
+// certificate of host into the Secret name
+func certificateForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment, name, host string) *unstructured.Unstructured {
+	issuer := ad.Spec.Ingress.IssuerRef
+	kind := issuer.Kind
+	if kind == "" {
+		kind = "Issuer"
+	}
+	cert := newUnstructured(certificateGVK, name, ad.Namespace)
+	cert.SetLabels(labelsForAgentDeployment(ad.Name))
+	cert.Object["spec"] = map[string]interface{}{
+		"secretName": name,
+		"dnsNames":   []interface{}{host},
+		"issuerRef": map[string]interface{}{
+			"name":  issuer.Name,
+			"kind":  kind,
+			"group": certificateGVK.Group,
+		},
+	}
+	return cert
+}
+
+// reconcileCertificates keeps a cert-manager Certificate for every TLS host of the
+// agent while an issuer is configured, and removes the ones no longer needed
+func (r *AgentDeploymentReconciler) reconcileCertificates(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	hosts := certificateHosts(ad)
+	for _, name := range []string{tlsSecretName(ad.Name), tlsSecretName(grpcIngressName(ad))} {
+		host, ok := hosts[name]
+		if !ok {
+			if err := r.deleteOwnedUnstructured(ctx, ad, certificateGVK, name); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := r.reconcileUnstructured(ctx, ad, certificateForAgentDeployment(ad, name, host)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
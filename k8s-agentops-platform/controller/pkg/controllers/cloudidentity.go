@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/providers"
+)
+
+// Workload identity annotations and labels of the clouds
+const (
+	awsRoleARNAnnotation        = "eks.amazonaws.com/role-arn"
+	gcpServiceAccountAnnotation = "iam.gke.io/gcp-service-account"
+	azureClientIDAnnotation     = "azure.workload.identity/client-id"
+	azureTenantIDAnnotation     = "azure.workload.identity/tenant-id"
+	azureUseLabel               = "azure.workload.identity/use"
+
+	// gkeMetadataServerLabel marks GKE nodes serving Workload Identity tokens
+	gkeMetadataServerLabel = "iam.gke.io/gke-metadata-server-enabled"
+)
+
+// cloudIdentityAnnotations are every annotation reconcileCloudIdentity manages on
+// the ServiceAccount, so a switch of cloud drops those of the previous one
+var cloudIdentityAnnotations = []string{awsRoleARNAnnotation, gcpServiceAccountAnnotation, azureClientIDAnnotation, azureTenantIDAnnotation}
+
+// cloudIdentityServiceAccountName returns the name of the ServiceAccount carrying
+// the cloud identity of the agent, which IAM trust policies and federated
+// credentials name as system:serviceaccount:<namespace>:<name>
+func cloudIdentityServiceAccountName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name
+}
+
+// cloudIdentityAnnotationsFor returns the ServiceAccount annotations of the identity
+func cloudIdentityAnnotationsFor(id *agentopsv1alpha1.CloudIdentitySpec) map[string]string {
+	annotations := map[string]string{}
+	switch {
+	case id.AWS != nil:
+		annotations[awsRoleARNAnnotation] = id.AWS.RoleARN
+	case id.GCP != nil:
+		annotations[gcpServiceAccountAnnotation] = id.GCP.ServiceAccount
+	case id.Azure != nil:
+		annotations[azureClientIDAnnotation] = id.Azure.ClientID
+		if id.Azure.TenantID != "" {
+			annotations[azureTenantIDAnnotation] = id.Azure.TenantID
+		}
+	}
+	return annotations
+}
+
+// applyCloudIdentity runs the agent pods under the ServiceAccount of
+// spec.cloudIdentity. Azure's webhook only mutates pods carrying its label, and
+// GKE serves identity tokens only on nodes running its metadata server.
+func applyCloudIdentity(ad *agentopsv1alpha1.AgentDeployment, pod *corev1.PodTemplateSpec) {
+	id := ad.Spec.CloudIdentity
+	if id == nil {
+		return
+	}
+	pod.Spec.ServiceAccountName = cloudIdentityServiceAccountName(ad)
+	switch {
+	case id.Azure != nil:
+		if pod.Labels == nil {
+			pod.Labels = map[string]string{}
+		}
+		pod.Labels[azureUseLabel] = "true"
+	case id.GCP != nil:
+		if pod.Spec.NodeSelector == nil {
+			pod.Spec.NodeSelector = map[string]string{}
+		}
+		pod.Spec.NodeSelector[gkeMetadataServerLabel] = "true"
+	}
+}
+
+// reconcileCloudIdentity keeps the annotated ServiceAccount of spec.cloudIdentity,
+// or deletes it once the field is removed, and validates the binding in the
+// CloudIdentityReady condition
+func (r *AgentDeploymentReconciler) reconcileCloudIdentity(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: cloudIdentityServiceAccountName(ad), Namespace: ad.Namespace}}
+	id := ad.Spec.CloudIdentity
+	if id == nil {
+		meta.RemoveStatusCondition(&ad.Status.Conditions, conditions.CloudIdentityReady)
+		return deleteOwned(ctx, r.Client, ad, sa)
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, sa, func() error {
+		if sa.Annotations == nil {
+			sa.Annotations = map[string]string{}
+		}
+		for _, key := range cloudIdentityAnnotations {
+			delete(sa.Annotations, key)
+		}
+		for k, v := range cloudIdentityAnnotationsFor(id) {
+			sa.Annotations[k] = v
+		}
+		return controllerutil.SetControllerReference(ad, sa, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	status, reason, message, err := r.cloudIdentityBinding(ctx, ad)
+	if err != nil {
+		return err
+	}
+	conditions.Set(&ad.Status.Conditions, conditions.CloudIdentityReady, status, reason, message, ad.Generation)
+	return nil
+}
+
+// cloudIdentityBinding validates spec.cloudIdentity: the identity belongs to the
+// cloud of the agent's provider, no static credentials override it, and the cluster
+// hands it to the pods, which the webhooks of EKS and Azure show in the environment
+// they inject and GKE in the label of its metadata server nodes
+func (r *AgentDeploymentReconciler) cloudIdentityBinding(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (metav1.ConditionStatus, string, string, error) {
+	id := ad.Spec.CloudIdentity
+	cloud, tokenEnv := "AWS", "AWS_WEB_IDENTITY_TOKEN_FILE"
+	switch {
+	case id.GCP != nil:
+		cloud, tokenEnv = "GCP", ""
+	case id.Azure != nil:
+		cloud, tokenEnv = "Azure", "AZURE_FEDERATED_TOKEN_FILE"
+	}
+
+	if p, err := providerForAgentDeployment(ad); err == nil {
+		want := map[string]string{providers.Bedrock: "AWS", providers.Vertex: "GCP", providers.AzureOpenAI: "Azure"}[p.Name]
+		if want != "" && want != cloud {
+			return metav1.ConditionFalse, conditions.ReasonCloudIdentityInvalid,
+				fmt.Sprintf("Provider %s authenticates with %s, not %s", p.Name, want, cloud), nil
+		}
+		if want != "" && ad.Spec.ProviderConfig != nil && ad.Spec.ProviderConfig.CredentialsSecretRef != nil {
+			return metav1.ConditionFalse, conditions.ReasonCloudIdentityInvalid,
+				"providerConfig.credentialsSecretRef takes precedence over the cloud identity; remove it", nil
+		}
+	}
+
+	if cloud == "GCP" {
+		nodes := &corev1.NodeList{}
+		if err := r.List(ctx, nodes, client.MatchingLabels{gkeMetadataServerLabel: "true"}); err != nil {
+			return "", "", "", err
+		}
+		if len(nodes.Items) == 0 {
+			return metav1.ConditionFalse, conditions.ReasonIdentityNotInjected,
+				fmt.Sprintf("No node is labeled %s=true; enable Workload Identity on the node pools", gkeMetadataServerLabel), nil
+		}
+		return metav1.ConditionTrue, conditions.ReasonAsExpected, "Nodes serve GKE Workload Identity tokens", nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(ad.Namespace), client.MatchingLabels(labelsForAgentDeployment(ad.Name))); err != nil {
+		return "", "", "", err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !pod.DeletionTimestamp.IsZero() || pod.Spec.ServiceAccountName != cloudIdentityServiceAccountName(ad) || len(pod.Spec.Containers) == 0 {
+			continue
+		}
+		for _, env := range pod.Spec.Containers[0].Env {
+			if env.Name == tokenEnv {
+				return metav1.ConditionTrue, conditions.ReasonAsExpected, fmt.Sprintf("Pods receive the %s identity", cloud), nil
+			}
+		}
+		return metav1.ConditionFalse, conditions.ReasonIdentityNotInjected,
+			fmt.Sprintf("Pod %s has no %s; is the %s workload identity webhook installed?", pod.Name, tokenEnv, cloud), nil
+	}
+	return metav1.ConditionUnknown, conditions.ReasonRolloutInProgress, "Waiting for a pod running under the ServiceAccount", nil
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/catalog"
+)
+
+const (
+	// costRefreshInterval is how often token spend is projected again
+	costRefreshInterval = 5 * time.Minute
+
+	// tokenProjectionWindow is the recent usage daily token spend is projected from
+	tokenProjectionWindow = time.Hour
+)
+
+// setCost estimates the hourly infrastructure cost of the agent from the pods it
+// runs and, once per costRefreshInterval, projects its daily LLM API spend
+func (r *AgentDeploymentReconciler) setCost(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment) error {
+	hourly := r.Prices.Hourly(&dep.Spec.Template.Spec, dep.Status.Replicas)
+	if modelServerEnabled(ad) {
+		sts := &appsv1.StatefulSet{}
+		err := r.Get(ctx, types.NamespacedName{Name: modelServerName(ad), Namespace: ad.Namespace}, sts)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if err == nil {
+			hourly += r.Prices.Hourly(&sts.Spec.Template.Spec, sts.Status.Replicas)
+		}
+	}
+
+	if ad.Status.Cost == nil {
+		ad.Status.Cost = &agentopsv1alpha1.CostStatus{}
+	}
+	ad.Status.Cost.HourlyInfra = usdQuantity(hourly)
+	r.projectTokenSpend(ctx, ad)
+	return nil
+}
+
+// projectTokenSpend extrapolates the last hour of token usage to a day. Usage that
+// cannot be read leaves the previous projection in place.
+func (r *AgentDeploymentReconciler) projectTokenSpend(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) {
+	if r.Tokens == nil {
+		return
+	}
+	if updated := ad.Status.Cost.LastUpdateTime; updated != nil && time.Since(updated.Time) < costRefreshInterval {
+		return
+	}
+
+	tokensByModel, err := r.Tokens.TokensByModel(ctx, ad.Namespace, []string{ad.Name}, tokenProjectionWindow)
+	if err != nil {
+		r.Log.Error(err, "Failed to read token usage", "AgentDeployment", ad.Name, "Namespace", ad.Namespace)
+		return
+	}
+	var spend float64
+	for model, n := range tokensByModel {
+		spend += catalog.Cost(model, n)
+	}
+	now := metav1.Now()
+	ad.Status.Cost.DailyTokens = usdQuantity(spend * float64(24*time.Hour/tokenProjectionWindow))
+	ad.Status.Cost.LastUpdateTime = &now
+}
+
+// usdQuantity returns an amount in USD rounded to cents
+func usdQuantity(usd float64) *resource.Quantity {
+	q := resource.MustParse(fmt.Sprintf("%.2f", usd))
+	return &q
+}
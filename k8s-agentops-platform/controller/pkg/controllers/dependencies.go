@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	// dependenciesReadyCondition is the pod readiness gate set once every dependency
+	// of spec.dependencies was reachable
+	dependenciesReadyCondition corev1.PodConditionType = "agentops.io/dependencies-ready"
+
+	defaultDependencyTimeout = 5 * time.Second
+
+	// dependencyRetryInterval is how soon unreachable dependencies are checked again
+	dependencyRetryInterval = 15 * time.Second
+)
+
+// applyDependencyGate adds the readiness gate that keeps agent pods out of their
+// Service, and out of the Deployment's ready count, until the dependencies are
+// reachable. It is applied after the warmup gate, which replaces the gates.
+func applyDependencyGate(ad *agentopsv1alpha1.AgentDeployment, pod *corev1.PodSpec) {
+	if len(ad.Spec.Dependencies) > 0 {
+		pod.ReadinessGates = append(pod.ReadinessGates, corev1.PodReadinessGate{ConditionType: dependenciesReadyCondition})
+	}
+}
+
+// readinessGatesRemoved reports whether the desired pod template dropped readiness
+// gates of the live one, which DeepDerivative does not count as a change
+func readinessGatesRemoved(desired, current *appsv1.Deployment) bool {
+	return len(desired.Spec.Template.Spec.ReadinessGates) < len(current.Spec.Template.Spec.ReadinessGates)
+}
+
+// reconcileDependencies checks the dependencies of the agent, records the outcome in
+// the DependenciesReady condition and opens the readiness gate of the agent pods
+// waiting on it once all are reachable. An open gate stays open, so a dependency
+// failing later does not take serving pods out of the Service.
+func (r *AgentDeploymentReconciler) reconcileDependencies(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	if len(ad.Spec.Dependencies) == 0 {
+		meta.RemoveStatusCondition(&ad.Status.Conditions, conditions.DependenciesReady)
+		return nil
+	}
+
+	var unreachable []string
+	for _, dep := range ad.Spec.Dependencies {
+		ok, message, err := r.dependencyReachable(ctx, ad, dep)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			unreachable = append(unreachable, dep.Name+": "+message)
+		}
+	}
+	ready := len(unreachable) == 0
+	if ready {
+		conditions.Set(&ad.Status.Conditions, conditions.DependenciesReady, metav1.ConditionTrue, conditions.ReasonAsExpected,
+			fmt.Sprintf("%d dependencies reachable", len(ad.Spec.Dependencies)), ad.Generation)
+	} else {
+		conditions.Set(&ad.Status.Conditions, conditions.DependenciesReady, metav1.ConditionFalse, conditions.ReasonDependenciesUnavailable,
+			strings.Join(unreachable, "; "), ad.Generation)
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(ad.Namespace), client.MatchingLabels(labelsForAgentDeployment(ad.Name))); err != nil {
+		return err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !pod.DeletionTimestamp.IsZero() || !hasReadinessGate(pod, dependenciesReadyCondition) ||
+			podConditionTrue(pod, dependenciesReadyCondition) {
+			continue
+		}
+		condition := corev1.PodCondition{
+			Type:               dependenciesReadyCondition,
+			Status:             corev1.ConditionTrue,
+			Reason:             "DependenciesReady",
+			LastTransitionTime: metav1.Now(),
+		}
+		if !ready {
+			// Waiting pods are told why once, not on every check
+			if hasPodCondition(pod, dependenciesReadyCondition) {
+				continue
+			}
+			condition.Status = corev1.ConditionFalse
+			condition.Reason = conditions.ReasonDependenciesUnavailable
+			condition.Message = strings.Join(unreachable, "; ")
+		}
+		if err := r.setPodCondition(ctx, pod, condition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependencyReachable checks a single dependency and returns why it is not reachable
+func (r *AgentDeploymentReconciler) dependencyReachable(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, dep agentopsv1alpha1.DependencySpec) (bool, string, error) {
+	switch {
+	case dep.Service != nil:
+		namespace := dep.Service.Namespace
+		if namespace == "" {
+			namespace = ad.Namespace
+		}
+		key := types.NamespacedName{Name: dep.Service.Name, Namespace: namespace}
+		svc := &corev1.Service{}
+		if err := r.Get(ctx, key, svc); errors.IsNotFound(err) {
+			return false, fmt.Sprintf("Service %s not found", key), nil
+		} else if err != nil {
+			return false, "", err
+		}
+		if svc.Spec.Type == corev1.ServiceTypeExternalName {
+			return true, "", nil
+		}
+		endpoints := &corev1.Endpoints{}
+		if err := r.Get(ctx, key, endpoints); err != nil && !errors.IsNotFound(err) {
+			return false, "", err
+		}
+		for _, subset := range endpoints.Subsets {
+			if len(subset.Addresses) > 0 {
+				return true, "", nil
+			}
+		}
+		return false, fmt.Sprintf("Service %s has no ready endpoints", key), nil
+
+	case dep.URL != "":
+		// URLs are only checked when the controller may call out, not in observe mode
+		if r.Warmer == nil {
+			return true, "", nil
+		}
+		timeout := defaultDependencyTimeout
+		if dep.Timeout != nil {
+			timeout = dep.Timeout.Duration
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if err := r.Warmer.HTTP(ctx, http.MethodGet, dep.URL, ""); err != nil {
+			return false, err.Error(), nil
+		}
+		return true, "", nil
+
+	case dep.AgentDeployment != "":
+		other := &agentopsv1alpha1.AgentDeployment{}
+		if err := r.Get(ctx, types.NamespacedName{Name: dep.AgentDeployment, Namespace: ad.Namespace}, other); errors.IsNotFound(err) {
+			return false, fmt.Sprintf("AgentDeployment %s not found", dep.AgentDeployment), nil
+		} else if err != nil {
+			return false, "", err
+		}
+		if !conditions.IsTrue(other.Status.Conditions, conditions.Ready) {
+			return false, fmt.Sprintf("AgentDeployment %s is not Ready", dep.AgentDeployment), nil
+		}
+		return true, "", nil
+
+	case dep.RAGPipeline != "":
+		rp := &agentopsv1alpha1.RAGPipeline{}
+		if err := r.Get(ctx, types.NamespacedName{Name: dep.RAGPipeline, Namespace: ad.Namespace}, rp); errors.IsNotFound(err) {
+			return false, fmt.Sprintf("RAGPipeline %s not found", dep.RAGPipeline), nil
+		} else if err != nil {
+			return false, "", err
+		}
+		if rp.Status.Phase != agentopsv1alpha1.RAGPipelineReady {
+			return false, fmt.Sprintf("RAGPipeline %s is not Ready", dep.RAGPipeline), nil
+		}
+		return true, "", nil
+	}
+	return false, "no service, url, agentDeployment or ragPipeline", nil
+}
@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/gateway"
+)
+
+const (
+	// egressProxyName names the egress proxy Deployment, Service and ConfigMap of a
+	// namespace
+	egressProxyName = "agentops-egress"
+	// egressNoProxy keeps in-cluster and loopback traffic off the proxy
+	egressNoProxy = "localhost,127.0.0.1,.svc,.svc.cluster.local,.cluster.local"
+)
+
+// defaultEgressHosts are the hosts agents may reach through the proxy when the
+// AgentOpsConfig lists none
+var defaultEgressHosts = []string{
+	"api.openai.com",
+	"api.anthropic.com",
+	"*.openai.azure.com",
+	"*.amazonaws.com",
+	"*.googleapis.com",
+}
+
+// egressProxyFor returns the egress proxy configuration the agents of namespace are
+// forced through, or nil
+func egressProxyFor(namespace string, cfg *agentopsv1alpha1.AgentOpsConfig) *agentopsv1alpha1.EgressProxySpec {
+	if cfg == nil || cfg.Spec.EgressProxy == nil || !containsString(cfg.Spec.EgressProxy.Namespaces, namespace) {
+		return nil
+	}
+	return cfg.Spec.EgressProxy
+}
+
+// egressProxyLabels returns the labels of the egress proxy pods
+func egressProxyLabels() map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "agent-egress-proxy",
+		"app.kubernetes.io/managed-by": "agentops-controller",
+	}
+}
+
+// egressProxyURL returns the proxy URL agents of namespace are given
+func egressProxyURL(namespace string) string {
+	return fmt.Sprintf("http://%s:%d", serviceHost(egressProxyName, namespace), gateway.ForwardProxyPort)
+}
+
+// egressOverlay points every container of the agent pods at the egress proxy of the
+// namespace, when the AgentOpsConfig forces its agents through one
+func egressOverlay(namespace string, cfg *agentopsv1alpha1.AgentOpsConfig) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		if egressProxyFor(namespace, cfg) == nil {
+			return
+		}
+		proxy := egressProxyURL(namespace)
+		env := []corev1.EnvVar{
+			{Name: "HTTP_PROXY", Value: proxy},
+			{Name: "HTTPS_PROXY", Value: proxy},
+			{Name: "NO_PROXY", Value: egressNoProxy},
+			{Name: "http_proxy", Value: proxy},
+			{Name: "https_proxy", Value: proxy},
+			{Name: "no_proxy", Value: egressNoProxy},
+		}
+		containers := dep.Spec.Template.Spec.Containers
+		for i := range containers {
+			containers[i].Env = append(containers[i].Env, env...)
+		}
+	}
+}
+
+// reconcileEgress runs the egress proxy of the agent's namespace and restricts the
+// agent pods to in-cluster traffic with a NetworkPolicy, or removes both when the
+// namespace is not forced through a proxy
+func (r *AgentDeploymentReconciler) reconcileEgress(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, cfg *agentopsv1alpha1.AgentOpsConfig) error {
+	spec := egressProxyFor(ad.Namespace, cfg)
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: ad.Name + "-egress", Namespace: ad.Namespace},
+	}
+	if spec == nil {
+		if err := r.Delete(ctx, policy); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return r.deleteEgressProxy(ctx, ad.Namespace)
+	}
+	if err := r.reconcileEgressProxy(ctx, ad.Namespace, cfg); err != nil {
+		return err
+	}
+
+	udp, tcp := corev1.ProtocolUDP, corev1.ProtocolTCP
+	dns := intstr.FromInt(53)
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, policy, func() error {
+		policy.Labels = labelsForAgentDeployment(ad.Name)
+		policy.Spec = networkingv1.NetworkPolicySpec{
+			// Serving and candidate pods of the agent
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"app.kubernetes.io/instance": ad.Name, "app.kubernetes.io/managed-by": "agentops-controller"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      "app.kubernetes.io/name",
+					Operator: metav1.LabelSelectorOpIn,
+					Values:   []string{"agent", "agent-candidate"},
+				}},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress: []networkingv1.NetworkPolicyEgressRule{
+				// Pods of any namespace, the egress proxy among them; addresses outside
+				// the cluster are not selected
+				{To: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{}}}},
+				// DNS, wherever the cluster resolver runs
+				{Ports: []networkingv1.NetworkPolicyPort{{Protocol: &udp, Port: &dns}, {Protocol: &tcp, Port: &dns}}},
+			},
+		}
+		return controllerutil.SetControllerReference(ad, policy, r.Scheme)
+	})
+	return err
+}
+
+// reconcileEgressProxy runs the egress proxy of namespace. It is shared by the
+// namespace's agents and owned by the AgentOpsConfig.
+func (r *AgentDeploymentReconciler) reconcileEgressProxy(ctx context.Context, namespace string, cfg *agentopsv1alpha1.AgentOpsConfig) error {
+	spec := cfg.Spec.EgressProxy
+	hosts := spec.AllowedHosts
+	if len(hosts) == 0 {
+		hosts = defaultEgressHosts
+	}
+	config, err := gateway.RenderForwardProxyBootstrap(gateway.ForwardProxyConfig{
+		AllowedHosts: hosts,
+		RateLimit:    gateway.RateLimit{RequestsPerMinute: spec.RequestsPerMinute},
+	})
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(config))
+	labels := egressProxyLabels()
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: egressProxyName, Namespace: namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Labels = labels
+		cm.Data = map[string]string{gatewayConfigKey: config}
+		return controllerutil.SetControllerReference(cfg, cm, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	image := gatewayImage
+	if spec.Image != "" {
+		image = spec.Image
+	}
+	replicas := int32(2)
+	if spec.Replicas != nil {
+		replicas = *spec.Replicas
+	}
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: egressProxyName, Namespace: namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, dep, func() error {
+		dep.Labels = labels
+		dep.Spec.Replicas = &replicas
+		dep.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+		dep.Spec.Template.Labels = labels
+		// Roll the proxy pods when the rendered configuration changes
+		dep.Spec.Template.Annotations = map[string]string{gatewayConfigAnnotation: hex.EncodeToString(sum[:8])}
+		dep.Spec.Template.Spec.Containers = []corev1.Container{{
+			Name:  "envoy",
+			Image: image,
+			Args:  []string{"-c", "/etc/envoy/" + gatewayConfigKey},
+			Ports: []corev1.ContainerPort{
+				{Name: "proxy", ContainerPort: gateway.ForwardProxyPort},
+				{Name: "admin", ContainerPort: gateway.AdminPort},
+			},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					HTTPGet: &corev1.HTTPGetAction{Path: "/ready", Port: intstr.FromInt(gateway.AdminPort)},
+				},
+				PeriodSeconds: 5,
+			},
+			VolumeMounts: []corev1.VolumeMount{{Name: "config", MountPath: "/etc/envoy", ReadOnly: true}},
+		}}
+		dep.Spec.Template.Spec.Volumes = []corev1.Volume{{
+			Name: "config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: egressProxyName}},
+			},
+		}}
+		return controllerutil.SetControllerReference(cfg, dep, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: egressProxyName, Namespace: namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		svc.Labels = labels
+		svc.Spec.Selector = labels
+		svc.Spec.Ports = []corev1.ServicePort{{
+			Name:       "proxy",
+			Port:       gateway.ForwardProxyPort,
+			TargetPort: intstr.FromString("proxy"),
+		}}
+		return controllerutil.SetControllerReference(cfg, svc, r.Scheme)
+	})
+	return err
+}
+
+// deleteEgressProxy removes the egress proxy of namespace, if the operator runs one
+func (r *AgentDeploymentReconciler) deleteEgressProxy(ctx context.Context, namespace string) error {
+	dep := &appsv1.Deployment{}
+	err := r.Get(ctx, types.NamespacedName{Name: egressProxyName, Namespace: namespace}, dep)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if dep.Labels["app.kubernetes.io/name"] != egressProxyLabels()["app.kubernetes.io/name"] {
+		return nil
+	}
+	for _, obj := range []client.Object{
+		dep,
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: egressProxyName, Namespace: namespace}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: egressProxyName, Namespace: namespace}},
+	} {
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// evaluationRunAnnotation on the candidate Deployment names the EvaluationRun of the
+// pod template it runs
+const evaluationRunAnnotation = "agentops.io/evaluation-run"
+
+// candidateLabels returns the labels of the candidate pods running a pod template
+// held back for spec.evaluation. The name differs from the agent's, so candidates
+// stay out of its Service, its Deployment and the controller's pod lookups.
+func candidateLabels(name string) map[string]string {
+	labels := labelsForAgentDeployment(name)
+	labels["app.kubernetes.io/name"] = "agent-candidate"
+	return labels
+}
+
+// evaluationGate reports whether the desired pod template may replace the live one.
+// With spec.evaluation a changed template first runs in a single candidate pod; an
+// EvaluationRun named after the template sends the suite to it, and the template is
+// promoted once the run passed. The candidate is removed once nothing is held back.
+func (r *AgentDeploymentReconciler) evaluationGate(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, live *appsv1.Deployment, desired *corev1.PodTemplateSpec) (bool, error) {
+	if ad.Spec.Evaluation == nil {
+		meta.RemoveStatusCondition(&ad.Status.Conditions, conditions.Evaluated)
+		return true, r.deleteCandidate(ctx, ad)
+	}
+	if equality.Semantic.DeepDerivative(*desired, live.Spec.Template) {
+		// Keep the outcome of the last promotion, not that of a change since reverted
+		if !conditions.IsTrue(ad.Status.Conditions, conditions.Evaluated) {
+			meta.RemoveStatusCondition(&ad.Status.Conditions, conditions.Evaluated)
+		}
+		return true, r.deleteCandidate(ctx, ad)
+	}
+
+	raw, err := json.Marshal(desired)
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(raw)
+	name := fmt.Sprintf("%s-eval-%s", ad.Name, hex.EncodeToString(sum[:5]))
+
+	run := &agentopsv1alpha1.EvaluationRun{}
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: ad.Namespace}, run)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	found := err == nil
+	gen := ad.Generation
+	switch {
+	case found && run.Status.Phase == agentopsv1alpha1.EvaluationRunPassed:
+		conditions.Set(&ad.Status.Conditions, conditions.Evaluated, metav1.ConditionTrue, conditions.ReasonEvaluationPassed,
+			fmt.Sprintf("EvaluationRun %s: %s", name, run.Status.Message), gen)
+		return true, nil
+	case found && run.Status.Phase == agentopsv1alpha1.EvaluationRunFailed:
+		if !conditions.IsFalse(ad.Status.Conditions, conditions.Evaluated) ||
+			conditions.Get(ad.Status.Conditions, conditions.Evaluated).Reason != conditions.ReasonEvaluationFailed {
+			r.event(ad, corev1.EventTypeWarning, conditions.ReasonEvaluationFailed,
+				fmt.Sprintf("EvaluationRun %s failed; keeping the current pod template: %s", name, run.Status.Message))
+		}
+		conditions.Set(&ad.Status.Conditions, conditions.Evaluated, metav1.ConditionFalse, conditions.ReasonEvaluationFailed,
+			fmt.Sprintf("EvaluationRun %s: %s", name, run.Status.Message), gen)
+		return false, r.deleteCandidate(ctx, ad)
+	}
+
+	available, err := r.reconcileCandidate(ctx, ad, desired, name)
+	if err != nil {
+		return false, err
+	}
+	if !available {
+		conditions.Set(&ad.Status.Conditions, conditions.Evaluated, metav1.ConditionFalse, conditions.ReasonEvaluationRunning,
+			"Waiting for the candidate pod of the new pod template", gen)
+		return false, nil
+	}
+	if !found {
+		if err := r.createEvaluationRun(ctx, ad, name); err != nil {
+			return false, err
+		}
+	}
+	conditions.Set(&ad.Status.Conditions, conditions.Evaluated, metav1.ConditionFalse, conditions.ReasonEvaluationRunning,
+		fmt.Sprintf("EvaluationRun %s is evaluating the new pod template", name), gen)
+	return false, nil
+}
+
+// reconcileCandidate runs the pod template in a single-replica candidate Deployment
+// and reports whether its pod is available
+func (r *AgentDeploymentReconciler) reconcileCandidate(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, template *corev1.PodTemplateSpec, run string) (bool, error) {
+	labels := candidateLabels(ad.Name)
+	pod := template.DeepCopy()
+	for k, v := range labels {
+		pod.Labels[k] = v
+	}
+	// Warmup is only run for serving pods; the evaluation exercises the candidate
+	pod.Spec.ReadinessGates = nil
+
+	dep := &appsv1.Deployment{}
+	err := r.Get(ctx, types.NamespacedName{Name: ad.Name + "-candidate", Namespace: ad.Namespace}, dep)
+	if err != nil && errors.IsNotFound(err) {
+		replicas := int32(1)
+		dep = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        ad.Name + "-candidate",
+				Namespace:   ad.Namespace,
+				Labels:      labels,
+				Annotations: map[string]string{evaluationRunAnnotation: run},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				// Runs only ever see pods of the template they evaluate
+				Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+				Template: *pod,
+			},
+		}
+		if err := controllerutil.SetControllerReference(ad, dep, r.Scheme); err != nil {
+			return false, err
+		}
+		r.Log.Info("Creating candidate Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name, "EvaluationRun", run)
+		return false, r.Create(ctx, dep)
+	} else if err != nil {
+		return false, err
+	}
+	if !metav1.IsControlledBy(dep, ad) {
+		return false, fmt.Errorf("deployment %s exists and is not owned by the AgentDeployment", dep.Name)
+	}
+
+	if dep.Annotations[evaluationRunAnnotation] != run {
+		r.Log.Info("Updating candidate Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name, "EvaluationRun", run)
+		if dep.Annotations == nil {
+			dep.Annotations = map[string]string{}
+		}
+		dep.Annotations[evaluationRunAnnotation] = run
+		dep.Spec.Template = *pod
+		return false, r.Update(ctx, dep)
+	}
+	return dep.Status.ObservedGeneration >= dep.Generation &&
+		dep.Status.UpdatedReplicas == dep.Status.Replicas &&
+		dep.Status.AvailableReplicas > 0, nil
+}
+
+// createEvaluationRun starts the evaluation of the candidate and prunes the oldest
+// runs of the agent beyond its revision history limit
+func (r *AgentDeploymentReconciler) createEvaluationRun(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, name string) error {
+	// The run carries the agent's labels, so the controller instance whose
+	// --watch-selector matches the agent also reconciles its runs
+	labels := map[string]string{}
+	for k, v := range ad.Labels {
+		labels[k] = v
+	}
+	for k, v := range labelsForAgentDeployment(ad.Name) {
+		labels[k] = v
+	}
+	run := &agentopsv1alpha1.EvaluationRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ad.Namespace,
+			Labels:    labels,
+		},
+		Spec: agentopsv1alpha1.EvaluationRunSpec{
+			AgentDeployment: ad.Name,
+			Candidate:       true,
+			EvaluationSuite: *ad.Spec.Evaluation.DeepCopy(),
+		},
+	}
+	if err := controllerutil.SetControllerReference(ad, run, r.Scheme); err != nil {
+		return err
+	}
+	r.Log.Info("Creating EvaluationRun", "EvaluationRun.Namespace", run.Namespace, "EvaluationRun.Name", run.Name)
+	if err := r.Create(ctx, run); err != nil {
+		return err
+	}
+
+	list := &agentopsv1alpha1.EvaluationRunList{}
+	if err := r.List(ctx, list, client.InNamespace(ad.Namespace), client.MatchingLabels(labelsForAgentDeployment(ad.Name))); err != nil {
+		return err
+	}
+	var runs []agentopsv1alpha1.EvaluationRun
+	for _, item := range list.Items {
+		if item.Name != run.Name && metav1.IsControlledBy(&item, ad) {
+			runs = append(runs, item)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreationTimestamp.Before(&runs[j].CreationTimestamp)
+	})
+	for i := 0; i < len(runs)+1-revisionHistoryLimit(ad); i++ {
+		if err := r.Delete(ctx, &runs[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteCandidate removes the candidate Deployment
+func (r *AgentDeploymentReconciler) deleteCandidate(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	dep := &appsv1.Deployment{}
+	err := r.Get(ctx, types.NamespacedName{Name: ad.Name + "-candidate", Namespace: ad.Namespace}, dep)
+	if errors.IsNotFound(err) || (err == nil && !metav1.IsControlledBy(dep, ad)) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	r.Log.Info("Deleting candidate Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
+	return client.IgnoreNotFound(r.Delete(ctx, dep))
+}
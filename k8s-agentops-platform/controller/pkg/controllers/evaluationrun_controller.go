@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	// evaluationRetryInterval is how often a run waiting for a ready agent pod checks again
+	evaluationRetryInterval = 15 * time.Second
+
+	defaultEvaluationPath    = "/v1/chat/completions"
+	defaultEvaluationTimeout = time.Minute
+)
+
+// Evaluator sends the prompts of EvaluationRuns to agent pods
+type Evaluator interface {
+	Complete(ctx context.Context, url, model, system, prompt string) (string, error)
+}
+
+// EvaluationRunReconciler reconciles an EvaluationRun object
+type EvaluationRunReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Evaluator sends the prompts; nil leaves runs pending
+	Evaluator Evaluator
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=evaluationruns,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=evaluationruns/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+// Reconcile sends the next case of the EvaluationRun to a ready agent pod and scores
+// the run once every case has a result
+func (r *EvaluationRunReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("evaluationrun", req.NamespacedName)
+
+	run := &agentopsv1alpha1.EvaluationRun{}
+	if err := r.Get(ctx, req.NamespacedName, run); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get EvaluationRun")
+		return ctrl.Result{}, err
+	}
+	if run.Finished() || r.Evaluator == nil {
+		return ctrl.Result{}, nil
+	}
+
+	ad := &agentopsv1alpha1.AgentDeployment{}
+	if err := r.Get(ctx, types.NamespacedName{Name: run.Spec.AgentDeployment, Namespace: run.Namespace}, ad); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{RequeueAfter: evaluationRetryInterval},
+				r.setPending(ctx, run, fmt.Sprintf("AgentDeployment %s not found", run.Spec.AgentDeployment))
+		}
+		return ctrl.Result{}, err
+	}
+
+	selector := labelsForAgentDeployment(ad.Name)
+	if run.Spec.Candidate {
+		selector = candidateLabels(ad.Name)
+	}
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(run.Namespace), client.MatchingLabels(selector)); err != nil {
+		return ctrl.Result{}, err
+	}
+	var pod *corev1.Pod
+	for i := range pods.Items {
+		if pods.Items[i].DeletionTimestamp.IsZero() && pods.Items[i].Status.PodIP != "" && podReady(&pods.Items[i]) {
+			pod = &pods.Items[i]
+			break
+		}
+	}
+	if pod == nil {
+		return ctrl.Result{RequeueAfter: evaluationRetryInterval}, r.setPending(ctx, run, "Waiting for a ready agent pod")
+	}
+
+	if run.Status.StartTime == nil {
+		now := metav1.Now()
+		run.Status.StartTime = &now
+	}
+	run.Status.Phase = agentopsv1alpha1.EvaluationRunRunning
+	run.Status.Pod = pod.Name
+
+	// One case per reconcile keeps workers free and shows progress in the status;
+	// the status update triggers the next case
+	if next := len(run.Status.Results); next < len(run.Spec.Cases) {
+		c := run.Spec.Cases[next]
+		host := net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(agentPortForAgentDeployment(ad))))
+		result := r.runCase(ctx, run, fmt.Sprintf("http://%s%s", host, evaluationPath(&run.Spec.EvaluationSuite)), ad.Spec.Model, c)
+		run.Status.Results = append(run.Status.Results, result)
+		run.Status.Message = fmt.Sprintf("%d/%d cases run", len(run.Status.Results), len(run.Spec.Cases))
+		conditions.Set(&run.Status.Conditions, conditions.Complete, metav1.ConditionFalse, conditions.ReasonEvaluationRunning,
+			run.Status.Message, run.Generation)
+	}
+	if len(run.Status.Results) == len(run.Spec.Cases) {
+		r.score(run)
+		log.Info("EvaluationRun finished", "Phase", run.Status.Phase, "Score", *run.Status.Score)
+	}
+	return ctrl.Result{}, patchStatus(ctx, r.Client, run)
+}
+
+// setPending records why the run cannot start yet
+func (r *EvaluationRunReconciler) setPending(ctx context.Context, run *agentopsv1alpha1.EvaluationRun, message string) error {
+	run.Status.Phase = agentopsv1alpha1.EvaluationRunPending
+	run.Status.Message = message
+	conditions.Set(&run.Status.Conditions, conditions.Complete, metav1.ConditionFalse, conditions.ReasonEvaluationRunning,
+		message, run.Generation)
+	return patchStatus(ctx, r.Client, run)
+}
+
+// runCase sends a case to url and checks the answer against its assertions
+func (r *EvaluationRunReconciler) runCase(ctx context.Context, run *agentopsv1alpha1.EvaluationRun, url, model string, c agentopsv1alpha1.EvaluationCase) agentopsv1alpha1.EvaluationCaseResult {
+	result := agentopsv1alpha1.EvaluationCaseResult{Name: c.Name}
+	ctx, cancel := context.WithTimeout(ctx, evaluationTimeout(&run.Spec.EvaluationSuite))
+	defer cancel()
+
+	start := time.Now()
+	answer, err := r.Evaluator.Complete(ctx, url, model, c.System, c.Prompt)
+	latency := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Latency = &metav1.Duration{Duration: latency}
+	for _, a := range c.Assertions {
+		if msg := checkAssertion(a, answer, latency); msg != "" {
+			result.Message = msg
+			return result
+		}
+	}
+	result.Passed = true
+	return result
+}
+
+// checkAssertion returns why the answer fails the assertion, or an empty string
+func checkAssertion(a agentopsv1alpha1.EvaluationAssertion, answer string, latency time.Duration) string {
+	switch a.Type {
+	case agentopsv1alpha1.AssertionContains:
+		if !strings.Contains(strings.ToLower(answer), strings.ToLower(a.Value)) {
+			return fmt.Sprintf("answer does not contain %q", a.Value)
+		}
+	case agentopsv1alpha1.AssertionNotContains:
+		if strings.Contains(strings.ToLower(answer), strings.ToLower(a.Value)) {
+			return fmt.Sprintf("answer contains %q", a.Value)
+		}
+	case agentopsv1alpha1.AssertionMatches:
+		re, err := regexp.Compile(a.Value)
+		if err != nil {
+			return fmt.Sprintf("invalid regular expression %q: %v", a.Value, err)
+		}
+		if !re.MatchString(answer) {
+			return fmt.Sprintf("answer does not match %q", a.Value)
+		}
+	case agentopsv1alpha1.AssertionMaxLatency:
+		limit, err := time.ParseDuration(a.Value)
+		if err != nil {
+			return fmt.Sprintf("invalid duration %q: %v", a.Value, err)
+		}
+		if latency > limit {
+			return fmt.Sprintf("answer took %s, more than %s", latency, limit)
+		}
+	default:
+		return fmt.Sprintf("unknown assertion type %q", a.Type)
+	}
+	return ""
+}
+
+// score sets the score and the final phase of a run with results for every case
+func (r *EvaluationRunReconciler) score(run *agentopsv1alpha1.EvaluationRun) {
+	var passed, total int32
+	for i, c := range run.Spec.Cases {
+		weight := int32(1)
+		if c.Weight != nil {
+			weight = *c.Weight
+		}
+		total += weight
+		if run.Status.Results[i].Passed {
+			passed += weight
+		}
+	}
+	score := int32(100)
+	if total > 0 {
+		score = passed * 100 / total
+	}
+	threshold := evaluationThreshold(&run.Spec.EvaluationSuite)
+
+	now := metav1.Now()
+	run.Status.Score = &score
+	run.Status.CompletionTime = &now
+	if score >= threshold {
+		run.Status.Phase = agentopsv1alpha1.EvaluationRunPassed
+		run.Status.Message = fmt.Sprintf("Scored %d%%, threshold %d%%", score, threshold)
+		conditions.Set(&run.Status.Conditions, conditions.Complete, metav1.ConditionTrue, conditions.ReasonEvaluationPassed,
+			run.Status.Message, run.Generation)
+		return
+	}
+	run.Status.Phase = agentopsv1alpha1.EvaluationRunFailed
+	run.Status.Message = fmt.Sprintf("Scored %d%%, below the threshold of %d%%", score, threshold)
+	conditions.Set(&run.Status.Conditions, conditions.Complete, metav1.ConditionFalse, conditions.ReasonEvaluationFailed,
+		run.Status.Message, run.Generation)
+}
+
+// evaluationPath returns the chat endpoint the suite's prompts are sent to
+func evaluationPath(suite *agentopsv1alpha1.EvaluationSuite) string {
+	if suite.Path == "" {
+		return defaultEvaluationPath
+	}
+	return suite.Path
+}
+
+// evaluationTimeout returns how long a case may take
+func evaluationTimeout(suite *agentopsv1alpha1.EvaluationSuite) time.Duration {
+	if suite.Timeout == nil {
+		return defaultEvaluationTimeout
+	}
+	return suite.Timeout.Duration
+}
+
+// evaluationThreshold returns the score the suite needs to pass
+func evaluationThreshold(suite *agentopsv1alpha1.EvaluationSuite) int32 {
+	if suite.Threshold == nil {
+		return 100
+	}
+	return *suite.Threshold
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *EvaluationRunReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.EvaluationRun{}).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("EvaluationRun", tracing.Reconciler("EvaluationRun", r)))
+}
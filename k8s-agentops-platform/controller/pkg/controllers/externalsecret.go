@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+var externalSecretGVK = schema.GroupVersionKind{Group: "external-secrets.io", Version: "v1beta1", Kind: "ExternalSecret"}
+
+// externalSecretsForAgentDeployment returns one ExternalSecret per target Secret name
+// referenced by secrets using the external provider. Each ExternalSecret syncs into a
+// Secret of the same name, so the pod env references look the same as for native secrets.
+// External secrets without a remoteRef or a spec.secretStoreRef are an error, as the
+// Secrets their pods reference would never be synced.
+func externalSecretsForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) ([]*unstructured.Unstructured, error) {
+	data := map[string][]interface{}{}
+	for _, s := range ad.Spec.Secrets {
+		if secretProvider(s) != agentopsv1alpha1.SecretProviderExternal {
+			continue
+		}
+		if s.RemoteRef == nil {
+			return nil, fmt.Errorf("secret %s: provider external requires remoteRef", s.Name)
+		}
+		if ad.Spec.SecretStoreRef == nil {
+			return nil, fmt.Errorf("secret %s: provider external requires spec.secretStoreRef", s.Name)
+		}
+		remoteRef := map[string]interface{}{"key": s.RemoteRef.Key}
+		if s.RemoteRef.Property != "" {
+			remoteRef["property"] = s.RemoteRef.Property
+		}
+		data[s.Name] = append(data[s.Name], map[string]interface{}{
+			"secretKey": s.Key,
+			"remoteRef": remoteRef,
+		})
+	}
+
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	storeKind := ad.Spec.SecretStoreRef.Kind
+	if storeKind == "" {
+		storeKind = "SecretStore"
+	}
+	refreshInterval := ad.Spec.SecretStoreRef.RefreshInterval
+	if refreshInterval == "" {
+		refreshInterval = "1h"
+	}
+
+	var objs []*unstructured.Unstructured
+	for _, name := range names {
+		es := newUnstructured(externalSecretGVK, name, ad.Namespace)
+		es.SetLabels(labelsForAgentDeployment(ad.Name))
+		es.Object["spec"] = map[string]interface{}{
+			"refreshInterval": refreshInterval,
+			"secretStoreRef": map[string]interface{}{
+				"name": ad.Spec.SecretStoreRef.Name,
+				"kind": storeKind,
+			},
+			"target": map[string]interface{}{
+				"name":           name,
+				"creationPolicy": "Owner",
+			},
+			"data": data[name],
+		}
+		objs = append(objs, es)
+	}
+	return objs, nil
+}
+
+// reconcileExternalSecrets creates or updates the ExternalSecrets for the AgentDeployment
+// and deletes those it no longer needs, which takes their synced Secrets with them
+func (r *AgentDeploymentReconciler) reconcileExternalSecrets(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	desired, err := externalSecretsForAgentDeployment(ad)
+	if err != nil {
+		return err
+	}
+	wanted := map[string]bool{}
+	for _, es := range desired {
+		if err := r.reconcileUnstructured(ctx, ad, es); err != nil {
+			return err
+		}
+		wanted[es.GetName()] = true
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(externalSecretGVK.GroupVersion().WithKind(externalSecretGVK.Kind + "List"))
+	if err := r.List(ctx, list, client.InNamespace(ad.Namespace), client.MatchingLabels(labelsForAgentDeployment(ad.Name))); err != nil {
+		// Without the External Secrets Operator CRDs there is nothing to clean up
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+	for i := range list.Items {
+		es := &list.Items[i]
+		if wanted[es.GetName()] || !metav1.IsControlledBy(es, ad) {
+			continue
+		}
+		r.Log.Info("Deleting ExternalSecret", "ExternalSecret.Namespace", es.GetNamespace(), "ExternalSecret.Name", es.GetName())
+		if err := r.Delete(ctx, es); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	defaultTerminationGracePeriod = 5 * time.Minute
+
+	// drainWindow is how long a draining agent must serve no requests to be drained
+	drainWindow = time.Minute
+
+	// finalizeRetryInterval is how often pending cleanup of a deleted agent is checked
+	finalizeRetryInterval = 5 * time.Second
+)
+
+// terminationGracePeriod returns how long deletion waits for cleanup to finish
+func terminationGracePeriod(ad *agentopsv1alpha1.AgentDeployment) time.Duration {
+	if ad.Spec.Termination == nil || ad.Spec.Termination.GracePeriod == nil {
+		return defaultTerminationGracePeriod
+	}
+	return ad.Spec.Termination.GracePeriod.Duration
+}
+
+// finalizeAgentDeployment takes a deleted agent out of service and releases what
+// garbage collection would remove too late or not at all. It returns false while
+// cleanup is still in progress, in the order:
+//
+//  1. AgentRoutes drop the agent from their gateways (they ignore terminating agents)
+//  2. with spec.termination.drainRequests, in-flight requests finish
+//  3. ExternalSecrets are deleted, revoking the provider credentials they synced
+//  4. the Service and Ingresses are deleted, which hold until cloud load balancers
+//     are released
+//
+// Once the grace period since deletion has passed, steps still waiting are skipped
+// so a broken dependency never blocks deletion forever.
+func (r *AgentDeploymentReconciler) finalizeAgentDeployment(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (bool, error) {
+	log := r.Log.WithValues("agentdeployment", client.ObjectKeyFromObject(ad))
+	expired := time.Since(ad.DeletionTimestamp.Time) > terminationGracePeriod(ad)
+
+	routed, err := r.routesReferencing(ctx, ad)
+	if err != nil {
+		return false, err
+	}
+	if len(routed) > 0 && !expired {
+		log.Info("Waiting for AgentRoutes to deregister the agent", "AgentRoutes", routed)
+		return false, nil
+	}
+
+	if ad.Spec.Termination != nil && ad.Spec.Termination.DrainRequests && !expired {
+		if !r.agentDrained(ctx, ad) {
+			log.Info("Waiting for in-flight requests to drain")
+			return false, nil
+		}
+	}
+
+	if err := r.deleteExternalSecrets(ctx, ad); err != nil {
+		return false, err
+	}
+
+	released, err := r.releaseLoadBalancers(ctx, ad)
+	if err != nil {
+		return false, err
+	}
+	if !released && !expired {
+		log.Info("Waiting for the agent Service and Ingresses to be deleted")
+		return false, nil
+	}
+
+	if expired {
+		log.Info("Termination grace period expired; removing the AgentDeployment", "GracePeriod", terminationGracePeriod(ad))
+	}
+	return true, nil
+}
+
+// routesReferencing returns the AgentRoutes that still send traffic to the agent
+func (r *AgentDeploymentReconciler) routesReferencing(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) ([]string, error) {
+	list := &agentopsv1alpha1.AgentRouteList{}
+	if err := r.List(ctx, list, client.InNamespace(ad.Namespace)); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, route := range list.Items {
+		for _, b := range route.Status.Backends {
+			if b.Name == ad.Name {
+				names = append(names, route.Name)
+				break
+			}
+		}
+	}
+	return names, nil
+}
+
+// agentDrained reports whether the agent served no requests over the drain window.
+// Without an activity source the agent is drained only when the grace period ends.
+func (r *AgentDeploymentReconciler) agentDrained(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) bool {
+	if r.Activity == nil {
+		return false
+	}
+	if time.Since(ad.DeletionTimestamp.Time) < drainWindow {
+		return false
+	}
+	requests, err := r.Activity.Requests(ctx, ad.Namespace, ad.Name, drainWindow)
+	if err != nil {
+		r.Log.Error(err, "Failed to read agent traffic; still draining", "AgentDeployment", ad.Name, "Namespace", ad.Namespace)
+		return false
+	}
+	return requests == 0
+}
+
+// deleteExternalSecrets deletes the agent's ExternalSecrets. Their target Secrets use
+// creationPolicy Owner, so the synced provider credentials go with them.
+func (r *AgentDeploymentReconciler) deleteExternalSecrets(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	es := newUnstructured(externalSecretGVK, "", ad.Namespace)
+	err := r.DeleteAllOf(ctx, es, client.InNamespace(ad.Namespace), client.MatchingLabels(labelsForAgentDeployment(ad.Name)))
+	if err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return err
+	}
+	return nil
+}
+
+// releaseLoadBalancers deletes the agent Service, Ingresses and Gateway and reports
+// whether they are gone. A LoadBalancer Service, and Ingresses and Gateways of cloud
+// controllers, keep a cleanup finalizer until the cloud load balancer is deleted, so
+// waiting for them guarantees nothing is left behind in the cloud account.
+func (r *AgentDeploymentReconciler) releaseLoadBalancers(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (bool, error) {
+	objs := []client.Object{
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: ad.Name, Namespace: ad.Namespace}},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: ad.Name, Namespace: ad.Namespace}},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: grpcIngressName(ad), Namespace: ad.Namespace}},
+		newUnstructured(gatewayGVK, ad.Name, ad.Namespace),
+	}
+	released := true
+	for _, obj := range objs {
+		if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return false, err
+		}
+		if !metav1.IsControlledBy(obj, ad) {
+			continue
+		}
+		released = false
+		if obj.GetDeletionTimestamp().IsZero() {
+			if err := r.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+				return false, err
+			}
+		}
+	}
+	return released, nil
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+var (
+	canaryGVK         = schema.GroupVersionKind{Group: "flagger.app", Version: "v1beta1", Kind: "Canary"}
+	metricTemplateGVK = schema.GroupVersionKind{Group: "flagger.app", Version: "v1beta1", Kind: "MetricTemplate"}
+)
+
+// flaggerPods matches the pods of the canary Deployment in Flagger metric templates,
+// but not those of its primary
+const flaggerPods = `namespace="{{ namespace }}",pod=~"{{ target }}-[0-9a-zA-Z]+(-[0-9a-zA-Z]+)"`
+
+// defaultFlaggerIterations is the number of analysis intervals of a rollout without
+// traffic weights when spec.progressiveDelivery.autoPromotionSeconds is unset
+const defaultFlaggerIterations = 5
+
+// flaggerEnabled reports whether a Flagger Canary rolls out the agent Deployment
+func flaggerEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return deliveryEngine(ad) == agentopsv1alpha1.DeliveryEngineFlagger
+}
+
+// metricTemplateName returns the name of the MetricTemplate of an agent SLI
+func metricTemplateName(ad *agentopsv1alpha1.AgentDeployment, sli string) string {
+	return ad.Name + "-" + sli
+}
+
+// flaggerSLIs returns the SLIs Flagger measures for the agent, or none when no
+// analysis is configured or no Prometheus is known
+func (r *AgentDeploymentReconciler) flaggerSLIs(ad *agentopsv1alpha1.AgentDeployment) []deliverySLI {
+	analysis := ad.Spec.ProgressiveDelivery.Analysis
+	if analysis == nil || r.PrometheusAddress == "" {
+		return nil
+	}
+	return deliverySLIs(analysis, flaggerPods, "{{ interval }}")
+}
+
+// metricTemplatesForAgentDeployment renders the agent SLIs into Flagger MetricTemplates
+func (r *AgentDeploymentReconciler) metricTemplatesForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) []*unstructured.Unstructured {
+	var templates []*unstructured.Unstructured
+	for _, sli := range r.flaggerSLIs(ad) {
+		tmpl := newUnstructured(metricTemplateGVK, metricTemplateName(ad, sli.name), ad.Namespace)
+		tmpl.SetLabels(labelsForAgentDeployment(ad.Name))
+		tmpl.Object["spec"] = map[string]interface{}{
+			"provider": map[string]interface{}{
+				"type":    "prometheus",
+				"address": r.PrometheusAddress,
+			},
+			"query": sli.query,
+		}
+		templates = append(templates, tmpl)
+	}
+	return templates
+}
+
+// canaryForAgentDeployment renders spec.progressiveDelivery into a Flagger Canary for
+// the agent Deployment. Traffic weights need the mesh of spec.mesh; without one
+// Flagger rolls out blue-green, analysing the new version for a number of intervals.
+func (r *AgentDeploymentReconciler) canaryForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment, managedAutoscaler bool) *unstructured.Unstructured {
+	spec := ad.Spec.ProgressiveDelivery
+	var intervalSpec *metav1.Duration
+	if spec.Analysis != nil {
+		intervalSpec = spec.Analysis.Interval
+	}
+	interval := time.Minute
+	if intervalSpec != nil {
+		interval = intervalSpec.Duration
+	}
+
+	analysis := map[string]interface{}{
+		"interval": durationOrDefault(intervalSpec, time.Minute),
+		// Flagger rolls back once the failed checks reach the threshold
+		"threshold": int64(deliveryFailureLimit(spec.Analysis)) + 1,
+	}
+	provider := "kubernetes"
+	if ad.Spec.Mesh != nil {
+		provider = ad.Spec.Mesh.Provider
+	}
+	if provider != "kubernetes" && spec.Strategy != agentopsv1alpha1.DeliveryStrategyBlueGreen {
+		steps := spec.Steps
+		if len(steps) == 0 {
+			steps = defaultRolloutSteps
+		}
+		var weights []interface{}
+		for _, step := range steps {
+			if step.Weight < 100 {
+				weights = append(weights, int64(step.Weight))
+			}
+		}
+		analysis["stepWeights"] = weights
+	} else {
+		iterations := int64(defaultFlaggerIterations)
+		if spec.AutoPromotionSeconds != nil {
+			iterations = int64(time.Duration(*spec.AutoPromotionSeconds)*time.Second/interval) + 1
+		}
+		analysis["iterations"] = iterations
+	}
+
+	var metrics []interface{}
+	for _, sli := range r.flaggerSLIs(ad) {
+		metrics = append(metrics, map[string]interface{}{
+			"name":           sli.name,
+			"templateRef":    map[string]interface{}{"name": metricTemplateName(ad, sli.name)},
+			"thresholdRange": map[string]interface{}{"max": sli.max},
+			"interval":       analysis["interval"],
+		})
+	}
+	if len(metrics) > 0 {
+		analysis["metrics"] = metrics
+	}
+
+	var webhooks []interface{}
+	for _, hook := range spec.Webhooks {
+		webhook := map[string]interface{}{
+			"name": hook.Name,
+			"url":  hook.URL,
+		}
+		if hook.Type != "" {
+			webhook["type"] = hook.Type
+		}
+		if hook.Timeout != nil {
+			webhook["timeout"] = durationOrDefault(hook.Timeout, 0)
+		}
+		if len(hook.Metadata) > 0 {
+			metadata := map[string]interface{}{}
+			for k, v := range hook.Metadata {
+				metadata[k] = v
+			}
+			webhook["metadata"] = metadata
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if len(webhooks) > 0 {
+		analysis["webhooks"] = webhooks
+	}
+
+	canarySpec := map[string]interface{}{
+		"provider": provider,
+		"targetRef": map[string]interface{}{
+			"apiVersion": appsv1.SchemeGroupVersion.String(),
+			"kind":       "Deployment",
+			"name":       ad.Name,
+		},
+		"service": map[string]interface{}{
+			"name":       ad.Name,
+			"port":       int64(agentServicePort),
+			"targetPort": httpPortName,
+		},
+		// Hand the Deployment and Service back when the Canary is removed
+		"revertOnDeletion": true,
+		"analysis":         analysis,
+	}
+	if managedAutoscaler {
+		canarySpec["autoscalerRef"] = map[string]interface{}{
+			"apiVersion": autoscalingv2.SchemeGroupVersion.String(),
+			"kind":       "HorizontalPodAutoscaler",
+			"name":       ad.Name,
+		}
+	}
+
+	canary := newUnstructured(canaryGVK, ad.Name, ad.Namespace)
+	canary.SetLabels(labelsForAgentDeployment(ad.Name))
+	canary.Object["spec"] = canarySpec
+	return canary
+}
+
+// reconcileFlagger keeps the agent's Flagger Canary and MetricTemplates in line with
+// spec.progressiveDelivery, and removes them when Flagger no longer rolls out the agent
+func (r *AgentDeploymentReconciler) reconcileFlagger(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, managedAutoscaler bool) error {
+	var desired []*unstructured.Unstructured
+	if flaggerEnabled(ad) {
+		desired = r.metricTemplatesForAgentDeployment(ad)
+	} else {
+		// Without a Canary there is nothing else to clean up
+		canary := newUnstructured(canaryGVK, ad.Name, ad.Namespace)
+		if err := r.Get(ctx, client.ObjectKeyFromObject(canary), canary); err != nil {
+			if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	wanted := map[string]bool{}
+	for _, tmpl := range desired {
+		wanted[tmpl.GetName()] = true
+		if err := r.reconcileUnstructured(ctx, ad, tmpl); err != nil {
+			return err
+		}
+	}
+	for _, sli := range []string{"error-rate", "latency", "token-latency"} {
+		if name := metricTemplateName(ad, sli); !wanted[name] {
+			if err := r.deleteOwnedUnstructured(ctx, ad, metricTemplateGVK, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !flaggerEnabled(ad) {
+		return r.deleteOwnedUnstructured(ctx, ad, canaryGVK, ad.Name)
+	}
+	return r.reconcileUnstructured(ctx, ad, r.canaryForAgentDeployment(ad, managedAutoscaler))
+}
+
+// flaggerPrimary returns the Deployment serving the agent: the primary Deployment
+// Flagger promotes new versions to, once it exists, or dep otherwise
+func (r *AgentDeploymentReconciler) flaggerPrimary(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment) (*appsv1.Deployment, error) {
+	if !flaggerEnabled(ad) {
+		return dep, nil
+	}
+	primary := &appsv1.Deployment{}
+	if err := r.Get(ctx, client.ObjectKey{Name: ad.Name + "-primary", Namespace: ad.Namespace}, primary); err != nil {
+		if errors.IsNotFound(err) {
+			return dep, nil
+		}
+		return nil, err
+	}
+	return primary, nil
+}
@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+var dnsEndpointGVK = schema.GroupVersionKind{Group: "externaldns.k8s.io", Version: "v1alpha1", Kind: "DNSEndpoint"}
+
+const (
+	defaultFailoverAfter    = 2 * time.Minute
+	defaultFleetWeight      = int32(100)
+	defaultFleetDNSTTL      = int64(60)
+	defaultFleetGatewayPort = int32(80)
+	// maxFailoverEvents bounds status.failoverEvents
+	maxFailoverEvents = 20
+)
+
+// clusterHealthy reports whether a member cluster was reached and its agents are
+// not degraded
+func clusterHealthy(s agentopsv1alpha1.FleetClusterStatus) bool {
+	return s.Synced && !s.Degraded
+}
+
+// clusterWeight returns the traffic weight of a member cluster
+func clusterWeight(cluster agentopsv1alpha1.FleetCluster) int32 {
+	if cluster.Weight == 0 {
+		return defaultFleetWeight
+	}
+	return cluster.Weight
+}
+
+// failoverAfter returns how long a cluster's health must hold before it fails
+// over or back
+func failoverAfter(failover *agentopsv1alpha1.FleetFailoverSpec) time.Duration {
+	if failover.After.Duration <= 0 {
+		return defaultFailoverAfter
+	}
+	return failover.After.Duration
+}
+
+// clusterCapacity returns the replicas an AgentDeployment asks for; autoscaled
+// agents count their minimum
+func clusterCapacity(ad *agentopsv1alpha1.AgentDeployment) int32 {
+	if autoscalingEnabled(ad) {
+		minReplicas, _ := autoscalingBounds(ad)
+		return minReplicas
+	}
+	return specReplicas(ad)
+}
+
+// failoverExtraReplicas returns the replicas each cluster takes over from the
+// failed-over clusters, split by weight
+func failoverExtraReplicas(fleet *agentopsv1alpha1.AgentFleet, previous map[string]agentopsv1alpha1.FleetClusterStatus) map[string]int32 {
+	failover := fleet.Spec.Failover
+	if failover == nil || !failover.ScaleUp {
+		return nil
+	}
+	var moved, totalWeight int32
+	for _, cluster := range fleet.Spec.Clusters {
+		if !previous[cluster.Name].FailedOver {
+			totalWeight += clusterWeight(cluster)
+			continue
+		}
+		if ad, err := memberAgentDeployment(fleet, cluster); err == nil {
+			moved += clusterCapacity(ad)
+		}
+	}
+	if moved == 0 || totalWeight == 0 {
+		return nil
+	}
+	extra := map[string]int32{}
+	for _, cluster := range fleet.Spec.Clusters {
+		if !previous[cluster.Name].FailedOver {
+			extra[cluster.Name] = (moved*clusterWeight(cluster) + totalWeight - 1) / totalWeight
+		}
+	}
+	return extra
+}
+
+// scaleUpAgentDeployment adds replicas to an AgentDeployment; autoscaled agents get
+// a higher minimum instead
+func scaleUpAgentDeployment(ad *agentopsv1alpha1.AgentDeployment, extra int32) {
+	if autoscalingEnabled(ad) {
+		minReplicas, maxReplicas := autoscalingBounds(ad)
+		minReplicas += extra
+		if maxReplicas < minReplicas {
+			maxReplicas = minReplicas
+		}
+		ad.Spec.Autoscaling.MinReplicas, ad.Spec.Autoscaling.MaxReplicas = &minReplicas, &maxReplicas
+		return
+	}
+	replicas := specReplicas(ad) + extra
+	ad.Spec.Replicas = &replicas
+}
+
+// updateFailover fails clusters over once they have been degraded or unreachable
+// for failover.after and back once they have been healthy as long, records the
+// transitions and sets the traffic weight of every cluster. It returns the number of
+// clusters that failed over or back.
+func updateFailover(fleet *agentopsv1alpha1.AgentFleet, previous map[string]agentopsv1alpha1.FleetClusterStatus, now metav1.Time) int {
+	weights := map[string]int32{}
+	for _, cluster := range fleet.Spec.Clusters {
+		weights[cluster.Name] = clusterWeight(cluster)
+	}
+	failover := fleet.Spec.Failover
+	transitions, serving := 0, false
+	for i := range fleet.Status.Clusters {
+		s := &fleet.Status.Clusters[i]
+		weight, member := weights[s.Name]
+		if !member {
+			s.Weight = 0
+			continue
+		}
+
+		prev, seen := previous[s.Name]
+		s.HealthChangedTime = prev.HealthChangedTime
+		if !seen || s.HealthChangedTime == nil || clusterHealthy(prev) != clusterHealthy(*s) {
+			s.HealthChangedTime = &now
+		}
+		s.FailedOver = prev.FailedOver && failover != nil
+		if failover != nil && now.Sub(s.HealthChangedTime.Time) >= failoverAfter(failover) {
+			switch healthy := clusterHealthy(*s); {
+			case !healthy && !s.FailedOver:
+				s.FailedOver = true
+				transitions++
+				fleet.Status.FailoverEvents = append(fleet.Status.FailoverEvents, agentopsv1alpha1.FleetFailoverEvent{
+					Time: now, Cluster: s.Name, Type: agentopsv1alpha1.FleetFailedOver, Message: s.Message,
+				})
+			case healthy && s.FailedOver:
+				s.FailedOver = false
+				transitions++
+				fleet.Status.FailoverEvents = append(fleet.Status.FailoverEvents, agentopsv1alpha1.FleetFailoverEvent{
+					Time: now, Cluster: s.Name, Type: agentopsv1alpha1.FleetFailedBack,
+					Message: fmt.Sprintf("Healthy for %s", failoverAfter(failover)),
+				})
+			}
+		}
+
+		s.Weight = weight
+		if s.FailedOver {
+			s.Weight = 0
+		} else {
+			serving = true
+		}
+	}
+	// With no healthy cluster to take the traffic, it stays where it was
+	if !serving {
+		for i := range fleet.Status.Clusters {
+			s := &fleet.Status.Clusters[i]
+			s.Weight = weights[s.Name]
+		}
+	}
+	if n := len(fleet.Status.FailoverEvents); n > maxFailoverEvents {
+		fleet.Status.FailoverEvents = fleet.Status.FailoverEvents[n-maxFailoverEvents:]
+	}
+	return transitions
+}
+
+// reconcileFailoverRouting points the fleet's DNS records and hub HTTPRoute at the
+// cluster endpoints with their current weights, and removes those no longer wanted
+func (r *AgentFleetReconciler) reconcileFailoverRouting(ctx context.Context, fleet *agentopsv1alpha1.AgentFleet) error {
+	failover := fleet.Spec.Failover
+	if failover != nil && failover.DNS != nil {
+		if err := applyUnstructured(ctx, r.Client, r.Scheme, fleet, dnsEndpointForFleet(fleet)); err != nil {
+			return fmt.Errorf("failed to apply DNSEndpoint: %w", err)
+		}
+	} else if err := deleteControlledUnstructured(ctx, r.Client, fleet, dnsEndpointGVK, fleet.Name); err != nil {
+		return err
+	}
+
+	wanted := map[string]bool{}
+	if failover != nil && failover.Gateway != nil {
+		for _, desired := range fleetBackendServices(fleet) {
+			wanted[desired.Name] = true
+			svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: desired.Namespace}}
+			if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+				svc.Labels = desired.Labels
+				svc.Spec.Type = desired.Spec.Type
+				svc.Spec.ExternalName = desired.Spec.ExternalName
+				svc.Spec.Ports = desired.Spec.Ports
+				return controllerutil.SetControllerReference(fleet, svc, r.Scheme)
+			}); err != nil {
+				return err
+			}
+		}
+		if err := applyUnstructured(ctx, r.Client, r.Scheme, fleet, httpRouteForFleet(fleet)); err != nil {
+			return fmt.Errorf("failed to apply HTTPRoute: %w", err)
+		}
+	} else if err := deleteControlledUnstructured(ctx, r.Client, fleet, httpRouteGVK, fleet.Name); err != nil {
+		return err
+	}
+
+	// Services of clusters that left the fleet or lost their endpoint
+	services := &corev1.ServiceList{}
+	if err := r.List(ctx, services, client.InNamespace(fleet.Namespace), client.MatchingLabels{fleetLabel: fleet.Name}); err != nil {
+		return err
+	}
+	for i := range services.Items {
+		svc := &services.Items[i]
+		if wanted[svc.Name] || !metav1.IsControlledBy(svc, fleet) {
+			continue
+		}
+		if err := client.IgnoreNotFound(r.Delete(ctx, svc)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statusWeights returns the current traffic weight of every cluster
+func statusWeights(fleet *agentopsv1alpha1.AgentFleet) map[string]int32 {
+	weights := map[string]int32{}
+	for _, s := range fleet.Status.Clusters {
+		weights[s.Name] = s.Weight
+	}
+	return weights
+}
+
+// dnsEndpointForFleet returns the external-dns DNSEndpoint with a weighted record
+// per cluster endpoint
+func dnsEndpointForFleet(fleet *agentopsv1alpha1.AgentFleet) *unstructured.Unstructured {
+	dns := fleet.Spec.Failover.DNS
+	ttl := dns.TTL
+	if ttl == 0 {
+		ttl = defaultFleetDNSTTL
+	}
+	weights := statusWeights(fleet)
+	var endpoints []interface{}
+	for _, cluster := range fleet.Spec.Clusters {
+		if cluster.Endpoint == "" {
+			continue
+		}
+		endpoints = append(endpoints, map[string]interface{}{
+			"dnsName":       dns.Hostname,
+			"recordTTL":     ttl,
+			"recordType":    recordType(cluster.Endpoint),
+			"targets":       []interface{}{cluster.Endpoint},
+			"setIdentifier": cluster.Name,
+			"providerSpecific": []interface{}{
+				map[string]interface{}{"name": "aws/weight", "value": strconv.Itoa(int(weights[cluster.Name]))},
+			},
+		})
+	}
+
+	u := newUnstructured(dnsEndpointGVK, fleet.Name, fleet.Namespace)
+	u.SetLabels(map[string]string{fleetLabel: fleet.Name, "app.kubernetes.io/managed-by": "agentops-controller"})
+	u.Object["spec"] = map[string]interface{}{"endpoints": endpoints}
+	return u
+}
+
+// recordType returns the DNS record type pointing to an endpoint
+func recordType(endpoint string) string {
+	ip := net.ParseIP(endpoint)
+	switch {
+	case ip == nil:
+		return "CNAME"
+	case ip.To4() != nil:
+		return "A"
+	}
+	return "AAAA"
+}
+
+// fleetBackendName returns the name of the hub Service standing for a cluster
+func fleetBackendName(fleet *agentopsv1alpha1.AgentFleet, cluster string) string {
+	return fmt.Sprintf("%s-%s", fleet.Name, cluster)
+}
+
+// fleetGatewayPort returns the port of the cluster endpoints behind the hub route
+func fleetGatewayPort(gw *agentopsv1alpha1.FleetGatewaySpec) int32 {
+	if gw.Port == 0 {
+		return defaultFleetGatewayPort
+	}
+	return gw.Port
+}
+
+// fleetBackendServices returns an ExternalName Service in the hub cluster for every
+// cluster endpoint, for the HTTPRoute to send traffic to
+func fleetBackendServices(fleet *agentopsv1alpha1.AgentFleet) []*corev1.Service {
+	port := fleetGatewayPort(fleet.Spec.Failover.Gateway)
+	var services []*corev1.Service
+	for _, cluster := range fleet.Spec.Clusters {
+		if cluster.Endpoint == "" {
+			continue
+		}
+		services = append(services, &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fleetBackendName(fleet, cluster.Name),
+				Namespace: fleet.Namespace,
+				Labels:    map[string]string{fleetLabel: fleet.Name, "app.kubernetes.io/managed-by": "agentops-controller"},
+			},
+			Spec: corev1.ServiceSpec{
+				Type:         corev1.ServiceTypeExternalName,
+				ExternalName: cluster.Endpoint,
+				Ports:        []corev1.ServicePort{{Name: "http", Port: port}},
+			},
+		})
+	}
+	return services
+}
+
+// httpRouteForFleet returns the hub HTTPRoute splitting the fleet's traffic across
+// the cluster endpoints by weight
+func httpRouteForFleet(fleet *agentopsv1alpha1.AgentFleet) *unstructured.Unstructured {
+	gw := fleet.Spec.Failover.Gateway
+	weights := statusWeights(fleet)
+	var backendRefs []interface{}
+	for _, cluster := range fleet.Spec.Clusters {
+		if cluster.Endpoint == "" {
+			continue
+		}
+		backendRefs = append(backendRefs, map[string]interface{}{
+			"name":   fleetBackendName(fleet, cluster.Name),
+			"port":   int64(fleetGatewayPort(gw)),
+			"weight": int64(weights[cluster.Name]),
+		})
+	}
+	var hostnames []interface{}
+	for _, h := range gw.Hostnames {
+		hostnames = append(hostnames, h)
+	}
+
+	u := newUnstructured(httpRouteGVK, fleet.Name, fleet.Namespace)
+	u.SetLabels(map[string]string{fleetLabel: fleet.Name, "app.kubernetes.io/managed-by": "agentops-controller"})
+	u.Object["spec"] = map[string]interface{}{
+		"parentRefs": parentRefsToUnstructured(gw.ParentRefs),
+		"hostnames":  hostnames,
+		"rules": []interface{}{map[string]interface{}{
+			"matches":     []interface{}{map[string]interface{}{"path": map[string]interface{}{"type": "PathPrefix", "value": "/"}}},
+			"backendRefs": backendRefs,
+		}},
+	}
+	return u
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// GarbageCollector deletes finished AgentJobs and AgentWorkflows once their
+// ttlSecondsAfterFinished has passed, and AgentDeployments that received no requests
+// for their ttlSinceLastActivity, recording an event on each
+type GarbageCollector struct {
+	Client client.Client
+	Log    logr.Logger
+
+	// Recorder records an event on every deleted object; events are skipped without
+	// one, e.g. in observe mode
+	Recorder record.EventRecorder
+
+	// Activity reports the traffic of agents; without it AgentDeployments are never
+	// collected
+	Activity ActivitySource
+
+	// Interval between two collections
+	Interval time.Duration
+}
+
+// Start collects until ctx is cancelled; it implements manager.Runnable
+func (gc *GarbageCollector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(gc.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			gc.collect(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection is true: only the leader deletes objects
+func (gc *GarbageCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// collect runs one collection; failures are logged and retried on the next
+func (gc *GarbageCollector) collect(ctx context.Context) {
+	now := time.Now()
+
+	jobs := &agentopsv1alpha1.AgentJobList{}
+	if err := gc.Client.List(ctx, jobs); err != nil {
+		gc.Log.Error(err, "Failed to list AgentJobs")
+	}
+	for i := range jobs.Items {
+		aj := &jobs.Items[i]
+		if !aj.Finished() {
+			continue
+		}
+		if expired, ttl := finishedExpired(aj.Spec.TTLSecondsAfterFinished, aj.Status.CompletionTime, now); expired {
+			gc.delete(ctx, aj, fmt.Sprintf("Finished more than %s ago (ttlSecondsAfterFinished)", ttl),
+				client.PropagationPolicy(metav1.DeletePropagationBackground))
+		}
+	}
+
+	workflows := &agentopsv1alpha1.AgentWorkflowList{}
+	if err := gc.Client.List(ctx, workflows); err != nil {
+		gc.Log.Error(err, "Failed to list AgentWorkflows")
+	}
+	for i := range workflows.Items {
+		wf := &workflows.Items[i]
+		if !wf.Finished() {
+			continue
+		}
+		if expired, ttl := finishedExpired(wf.Spec.TTLSecondsAfterFinished, wf.Status.CompletionTime, now); expired {
+			gc.delete(ctx, wf, fmt.Sprintf("Finished more than %s ago (ttlSecondsAfterFinished)", ttl))
+		}
+	}
+
+	agents := &agentopsv1alpha1.AgentDeploymentList{}
+	if err := gc.Client.List(ctx, agents); err != nil {
+		gc.Log.Error(err, "Failed to list AgentDeployments")
+	}
+	for i := range agents.Items {
+		ad := &agents.Items[i]
+		// Agents run by a preview, experiment or other owner follow its lifecycle
+		if ad.Spec.TTLSinceLastActivity == nil || metav1.GetControllerOf(ad) != nil || !ad.DeletionTimestamp.IsZero() {
+			continue
+		}
+		ttl := ad.Spec.TTLSinceLastActivity.Duration
+		idle, err := agentIdleFor(ctx, gc.Activity, ad, ttl)
+		if err != nil {
+			gc.Log.Error(err, "Failed to read agent traffic; keeping it", "AgentDeployment", ad.Name, "Namespace", ad.Namespace)
+			continue
+		}
+		if idle {
+			gc.delete(ctx, ad, fmt.Sprintf("Received no requests for %s (ttlSinceLastActivity)", ttl))
+		}
+	}
+}
+
+// finishedExpired reports whether an object that completed at completion is past
+// its ttlSecondsAfterFinished, and returns the TTL
+func finishedExpired(ttlSeconds *int32, completion *metav1.Time, now time.Time) (bool, time.Duration) {
+	if ttlSeconds == nil || completion == nil {
+		return false, 0
+	}
+	ttl := time.Duration(*ttlSeconds) * time.Second
+	return !now.Before(completion.Add(ttl)), ttl
+}
+
+// delete records an Expired event on obj and deletes it
+func (gc *GarbageCollector) delete(ctx context.Context, obj client.Object, message string, opts ...client.DeleteOption) {
+	kind := fmt.Sprintf("%T", obj)
+	if gvk, err := gc.Client.GroupVersionKindFor(obj); err == nil {
+		kind = gvk.Kind
+	}
+	gc.Log.Info("Deleting expired object", "Kind", kind, "Namespace", obj.GetNamespace(), "Name", obj.GetName(), "Reason", message)
+	if gc.Recorder != nil {
+		gc.Recorder.Event(obj, corev1.EventTypeNormal, "Expired", message)
+	}
+	if err := gc.Client.Delete(ctx, obj, opts...); err != nil && !errors.IsNotFound(err) {
+		gc.Log.Error(err, "Failed to delete expired object", "Kind", kind, "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+	}
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/usage"
+)
+
+const (
+	// gpuStatusInterval is how often status.gpu is read again
+	gpuStatusInterval = time.Minute
+
+	// gpuUtilizationMetric and gpuMemoryUtilizationMetric are the pod metrics, in
+	// percent, the custom metrics API serves from the DCGM exporter
+	gpuUtilizationMetric       = "DCGM_FI_DEV_GPU_UTIL"
+	gpuMemoryUtilizationMetric = "agentops_gpu_memory_utilization"
+)
+
+// GPUUsageSource reports the utilization of the GPUs of a set of pods
+type GPUUsageSource interface {
+	GPUUtilization(ctx context.Context, namespace, podPattern string) (*usage.GPUUtilization, error)
+}
+
+// agentServesOnGPU reports whether the agent pods serve the model themselves on GPUs
+func agentServesOnGPU(ad *agentopsv1alpha1.AgentDeployment) bool {
+	p, err := providerForAgentDeployment(ad)
+	if err != nil || !p.SelfHosted || modelServerEnabled(ad) {
+		return false
+	}
+	return gpusOf(resourcesForAgentDeployment(ad)) > 0
+}
+
+// gpuAutoscalingMetrics returns the HPA metrics of spec.autoscaling.gpu. The agent
+// pods must hold the GPUs: the HPA scales them, not the model server.
+func gpuAutoscalingMetrics(ad *agentopsv1alpha1.AgentDeployment) ([]autoscalingv2.MetricSpec, error) {
+	gpu := ad.Spec.Autoscaling.GPU
+	if gpu == nil || (gpu.TargetUtilization == nil && gpu.TargetMemoryUtilization == nil) {
+		return nil, nil
+	}
+	if modelServerEnabled(ad) {
+		return nil, fmt.Errorf("autoscaling.gpu scales agent pods serving the model, not the model server of serving.selfHosted")
+	}
+	if !agentServesOnGPU(ad) {
+		return nil, fmt.Errorf("autoscaling.gpu requires agent pods serving the model on GPUs with a self-hosted provider")
+	}
+
+	var metrics []autoscalingv2.MetricSpec
+	if gpu.TargetUtilization != nil {
+		metrics = append(metrics, podsPercentMetric(gpuUtilizationMetric, *gpu.TargetUtilization))
+	}
+	if gpu.TargetMemoryUtilization != nil {
+		metrics = append(metrics, podsPercentMetric(gpuMemoryUtilizationMetric, *gpu.TargetMemoryUtilization))
+	}
+	return metrics, nil
+}
+
+// podsPercentMetric targets an average of percent across the pods of the agent
+func podsPercentMetric(name string, percent int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.PodsMetricSourceType,
+		Pods: &autoscalingv2.PodsMetricSource{
+			Metric: autoscalingv2.MetricIdentifier{Name: name},
+			Target: autoscalingv2.MetricTarget{
+				Type:         autoscalingv2.AverageValueMetricType,
+				AverageValue: resource.NewQuantity(int64(percent), resource.DecimalSI),
+			},
+		},
+	}
+}
+
+// gpuPodPattern returns a regular expression matching the names of the pods holding
+// the GPUs that serve the agent's model, or "" when it is not served on GPUs
+func gpuPodPattern(ad *agentopsv1alpha1.AgentDeployment) string {
+	switch {
+	case modelServerEnabled(ad):
+		// StatefulSet pods are <name>-<ordinal>, LeaderWorkerSet pods
+		// <name>-<group>[-<index>]
+		return regexp.QuoteMeta(modelServerName(ad)) + `-[0-9]+(-[0-9]+)?`
+	case agentServesOnGPU(ad):
+		return regexp.QuoteMeta(ad.Name) + `-[a-z0-9]+-[a-z0-9]+`
+	}
+	return ""
+}
+
+// reconcileGPUStatus refreshes status.gpu once per gpuStatusInterval. Utilization
+// that cannot be read leaves the previous status in place.
+func (r *AgentDeploymentReconciler) reconcileGPUStatus(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) {
+	pattern := gpuPodPattern(ad)
+	if r.GPUs == nil || pattern == "" {
+		ad.Status.GPU = nil
+		return
+	}
+	if s := ad.Status.GPU; s != nil && s.LastUpdateTime != nil && time.Since(s.LastUpdateTime.Time) < gpuStatusInterval {
+		return
+	}
+
+	u, err := r.GPUs.GPUUtilization(ctx, ad.Namespace, pattern)
+	if err != nil {
+		r.Log.Error(err, "Failed to read GPU utilization", "AgentDeployment", ad.Name, "Namespace", ad.Namespace)
+		return
+	}
+	now := metav1.Now()
+	status := &agentopsv1alpha1.GPUStatus{LastUpdateTime: &now}
+	if u != nil {
+		utilization, memory := int32(math.Round(u.Utilization)), int32(math.Round(u.MemoryUtilization))
+		status.Utilization, status.MemoryUtilization = &utilization, &memory
+	}
+	ad.Status.GPU = status
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// blockingReasons are the Progressing reasons of a rollout the controller refused
+var blockingReasons = map[string]bool{
+	conditions.ReasonProviderNotAllowed: true,
+	conditions.ReasonQuotaExceeded:      true,
+	conditions.ReasonSignatureInvalid:   true,
+	conditions.ReasonHookRejected:       true,
+	conditions.ReasonPreflightFailed:    true,
+}
+
+// rolloutComplete reports whether the Deployment runs only pods of its latest
+// template, all of them available, like kubectl rollout status
+func rolloutComplete(dep *appsv1.Deployment, desired int32) bool {
+	return dep.Status.ObservedGeneration >= dep.Generation &&
+		dep.Status.UpdatedReplicas >= desired &&
+		dep.Status.Replicas <= dep.Status.UpdatedReplicas &&
+		dep.Status.AvailableReplicas >= dep.Status.UpdatedReplicas
+}
+
+// progressDeadlineExceeded reports whether the Deployment gave up on its rollout
+func progressDeadlineExceeded(dep *appsv1.Deployment) bool {
+	for _, c := range dep.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return true
+		}
+	}
+	return false
+}
+
+// stallReason returns the reason and message of what keeps the agent from
+// converging, or an empty reason. dep is nil when the rollout was refused before
+// the Deployment was read.
+func stallReason(ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment) (string, string) {
+	if c := conditions.Get(ad.Status.Conditions, conditions.Rejected); c != nil && c.Status == metav1.ConditionTrue {
+		return c.Reason, c.Message
+	}
+	if c := conditions.Get(ad.Status.Conditions, conditions.Progressing); c != nil && c.Status == metav1.ConditionFalse && blockingReasons[c.Reason] {
+		return c.Reason, c.Message
+	}
+	if c := conditions.Get(ad.Status.Conditions, conditions.Evaluated); c != nil && c.Reason == conditions.ReasonEvaluationFailed {
+		return c.Reason, c.Message
+	}
+	if ad.Status.Phase == "Failed" {
+		if c := conditions.Get(ad.Status.Conditions, conditions.Degraded); c != nil && c.Status == metav1.ConditionTrue {
+			return c.Reason, c.Message
+		}
+	}
+	if dep != nil && progressDeadlineExceeded(dep) {
+		return conditions.ReasonRolloutInProgress, "Deployment exceeded its progress deadline"
+	}
+	return "", ""
+}
+
+// setHealthConditions derives the Reconciling and Stalled conditions from the
+// other conditions, so tools following kstatus do not report the agent as
+// current before the latest spec is rolled out and ready
+func setHealthConditions(ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment) {
+	gen := ad.Generation
+	reason, message := stallReason(ad, dep)
+	if reason != "" {
+		conditions.Set(&ad.Status.Conditions, conditions.Stalled, metav1.ConditionTrue, reason, message, gen)
+		conditions.Set(&ad.Status.Conditions, conditions.Reconciling, metav1.ConditionFalse, reason,
+			"Reconciling is blocked, see the Stalled condition", gen)
+		return
+	}
+	conditions.Set(&ad.Status.Conditions, conditions.Stalled, metav1.ConditionFalse, conditions.ReasonAsExpected,
+		"Nothing blocks the rollout", gen)
+
+	if c := conditions.Get(ad.Status.Conditions, conditions.Progressing); c != nil && c.Status == metav1.ConditionTrue {
+		conditions.Set(&ad.Status.Conditions, conditions.Reconciling, metav1.ConditionTrue, c.Reason, c.Message, gen)
+		return
+	}
+	for _, store := range []string{conditions.SessionStoreReady, conditions.QueueReady, conditions.ResponseCacheReady, conditions.DependenciesReady} {
+		if c := conditions.Get(ad.Status.Conditions, store); c != nil && c.Status == metav1.ConditionFalse {
+			conditions.Set(&ad.Status.Conditions, conditions.Reconciling, metav1.ConditionTrue, c.Reason, c.Message, gen)
+			return
+		}
+	}
+	if c := conditions.Get(ad.Status.Conditions, conditions.Ready); c == nil || c.Status != metav1.ConditionTrue {
+		reason, message := conditions.ReasonReplicasUnavailable, "Waiting for replicas"
+		if c != nil {
+			reason, message = c.Reason, c.Message
+		}
+		conditions.Set(&ad.Status.Conditions, conditions.Reconciling, metav1.ConditionTrue, reason, message, gen)
+		return
+	}
+	conditions.Set(&ad.Status.Conditions, conditions.Reconciling, metav1.ConditionFalse, conditions.ReasonRolloutComplete,
+		"Latest spec is rolled out and ready", gen)
+}
+
+// agentHealth returns the value of the health annotation for the agent
+func agentHealth(ad *agentopsv1alpha1.AgentDeployment) string {
+	switch {
+	case ad.Annotations[agentopsv1alpha1.PausedAnnotation] == "true",
+		conditions.IsTrue(ad.Status.Conditions, conditions.Hibernated):
+		return agentopsv1alpha1.HealthSuspended
+	case conditions.IsTrue(ad.Status.Conditions, conditions.Stalled):
+		return agentopsv1alpha1.HealthDegraded
+	case ad.Status.ObservedGeneration < ad.Generation,
+		!conditions.IsFalse(ad.Status.Conditions, conditions.Reconciling):
+		return agentopsv1alpha1.HealthProgressing
+	}
+	return agentopsv1alpha1.HealthHealthy
+}
+
+// setHealthAnnotation writes the health annotation when it changed. Only the
+// annotation is patched, so the in-memory spec may carry defaults.
+func (r *AgentDeploymentReconciler) setHealthAnnotation(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	health := agentHealth(ad)
+	if ad.Annotations[agentopsv1alpha1.HealthAnnotation] == health {
+		return nil
+	}
+	patch := []byte(`{"metadata":{"annotations":{"` + agentopsv1alpha1.HealthAnnotation + `":"` + health + `"}}}`)
+	return r.Patch(ctx, ad.DeepCopy(), client.RawPatch(types.MergePatchType, patch))
+}
+
+// patchBlockedStatus persists the status of an agent whose rollout was refused.
+// The generation counts as observed: the controller has acted on it, and GitOps
+// tools then see Stalled instead of waiting for the status to catch up.
+func (r *AgentDeploymentReconciler) patchBlockedStatus(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	ad.Status.ObservedGeneration = ad.Generation
+	setHealthConditions(ad, nil)
+	if err := patchStatus(ctx, r.Client, ad); err != nil {
+		return err
+	}
+	return r.setHealthAnnotation(ctx, ad)
+}
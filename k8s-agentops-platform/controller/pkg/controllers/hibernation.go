@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// hibernationEnabled reports whether the agent hibernates when idle
+func hibernationEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.Hibernation != nil && ad.Spec.Hibernation.IdleAfter.Duration > 0
+}
+
+// hibernationReplicas returns the replica count of a hibernated agent
+func hibernationReplicas(ad *agentopsv1alpha1.AgentDeployment) int32 {
+	if ad.Spec.Hibernation.Replicas == nil {
+		return 0
+	}
+	return *ad.Spec.Hibernation.Replicas
+}
+
+// hibernationOverlay scales the Deployment down while the agent is hibernated; a
+// lower replica count, e.g. from an exceeded budget, is kept
+func hibernationOverlay(ad *agentopsv1alpha1.AgentDeployment, hibernated bool) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		if !hibernated {
+			return
+		}
+		replicas := hibernationReplicas(ad)
+		if dep.Spec.Replicas == nil || *dep.Spec.Replicas > replicas {
+			dep.Spec.Replicas = &replicas
+		}
+	}
+}
+
+// reconcileHibernation reports whether the agent is hibernated, records why in the
+// Hibernated condition and emits an event when the agent hibernates or resumes
+func (r *AgentDeploymentReconciler) reconcileHibernation(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) bool {
+	wasHibernated := conditions.IsTrue(ad.Status.Conditions, conditions.Hibernated)
+	if !hibernationEnabled(ad) {
+		if conditions.Get(ad.Status.Conditions, conditions.Hibernated) != nil {
+			conditions.Set(&ad.Status.Conditions, conditions.Hibernated, metav1.ConditionFalse, conditions.ReasonAsExpected,
+				"Hibernation is disabled", ad.Generation)
+		}
+		return false
+	}
+
+	idleAfter := ad.Spec.Hibernation.IdleAfter.Duration
+	if r.idleFor(ctx, ad, idleAfter) {
+		message := fmt.Sprintf("No requests for %s; scaled down to %d replicas", idleAfter, hibernationReplicas(ad))
+		conditions.Set(&ad.Status.Conditions, conditions.Hibernated, metav1.ConditionTrue, conditions.ReasonIdle, message, ad.Generation)
+		if !wasHibernated {
+			r.event(ad, corev1.EventTypeNormal, "Hibernated", message)
+		}
+		return true
+	}
+
+	message := fmt.Sprintf("Received requests or was activated within the last %s", idleAfter)
+	conditions.Set(&ad.Status.Conditions, conditions.Hibernated, metav1.ConditionFalse, conditions.ReasonReceivingTraffic,
+		message, ad.Generation)
+	if wasHibernated {
+		r.event(ad, corev1.EventTypeNormal, "Resumed", message)
+	}
+	return false
+}
+
+// event records an event on the AgentDeployment; events are skipped without a
+// recorder, e.g. in observe mode
+func (r *AgentDeploymentReconciler) event(ad *agentopsv1alpha1.AgentDeployment, eventType, reason, message string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Event(ad, eventType, reason, message)
+}
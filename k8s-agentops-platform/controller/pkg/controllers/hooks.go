@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/hooks"
+)
+
+// hookRetryInterval is how long a change rejected by a preApply hook waits before
+// it is proposed again
+const hookRetryInterval = time.Minute
+
+// runHooks calls the agent's hooks of the given phase for a change to obj.
+// PreApply failures are returned as *hooks.RejectedError unless the hook's failure
+// policy is Ignore; PostApply failures are only logged. Hooks are disabled when the
+// reconciler has no hook client.
+func (r *AgentDeploymentReconciler) runHooks(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, phase, operation string, obj client.Object) error {
+	if ad.Spec.Hooks == nil || r.Hooks == nil {
+		return nil
+	}
+	specs := ad.Spec.Hooks.PreApply
+	if phase == hooks.PostApply {
+		specs = ad.Spec.Hooks.PostApply
+	}
+	if len(specs) == 0 {
+		return nil
+	}
+
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return err
+	}
+	req := hooks.Request{
+		Phase:     phase,
+		Operation: operation,
+		AgentDeployment: hooks.ObjectReference{
+			APIVersion: agentopsv1alpha1.GroupVersion.String(),
+			Kind:       "AgentDeployment",
+			Namespace:  ad.Namespace,
+			Name:       ad.Name,
+		},
+		Generation: ad.Generation,
+		Object: hooks.ObjectReference{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+		},
+		Desired: obj,
+	}
+
+	for _, spec := range specs {
+		ep, err := r.hookEndpoint(ctx, ad, spec)
+		if err == nil {
+			err = r.Hooks.Call(ctx, ep, req)
+		}
+		if err == nil {
+			continue
+		}
+		if phase == hooks.PostApply {
+			r.Log.Error(err, "Post-apply hook failed", "Hook", spec.Name, "AgentDeployment", ad.Name)
+			continue
+		}
+		if hooks.IsRejected(err) {
+			return err
+		}
+		if spec.FailurePolicy == agentopsv1alpha1.HookFailurePolicyIgnore {
+			r.Log.Error(err, "Ignoring failed pre-apply hook", "Hook", spec.Name, "AgentDeployment", ad.Name)
+			continue
+		}
+		return &hooks.RejectedError{Hook: spec.Name, Reason: err.Error()}
+	}
+	return nil
+}
+
+// hookEndpoint resolves a webhook spec, reading its bearer token from the referenced Secret
+func (r *AgentDeploymentReconciler) hookEndpoint(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, spec agentopsv1alpha1.WebhookSpec) (hooks.Endpoint, error) {
+	ep := hooks.Endpoint{
+		Name:    spec.Name,
+		URL:     spec.URL,
+		Timeout: time.Duration(spec.TimeoutSeconds) * time.Second,
+	}
+	if ref := spec.TokenSecretRef; ref != nil {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ad.Namespace}, secret); err != nil {
+			return ep, fmt.Errorf("read token for hook %s: %w", spec.Name, err)
+		}
+		token, ok := secret.Data[ref.Key]
+		if !ok {
+			return ep, fmt.Errorf("secret %s has no key %s for hook %s", ref.Name, ref.Key, spec.Name)
+		}
+		ep.Token = string(token)
+	}
+	return ep, nil
+}
+
+// hookRejected records a rejected change on the AgentDeployment and retries later
+func (r *AgentDeploymentReconciler) hookRejected(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, err error) (ctrl.Result, error) {
+	r.Log.Info("Change rejected by pre-apply hook", "AgentDeployment", ad.Name, "Reason", err.Error())
+	conditions.Set(&ad.Status.Conditions, conditions.Progressing, metav1.ConditionFalse, conditions.ReasonHookRejected,
+		err.Error(), ad.Generation)
+	if statusErr := r.patchBlockedStatus(ctx, ad); statusErr != nil {
+		return ctrl.Result{}, statusErr
+	}
+	return ctrl.Result{RequeueAfter: hookRetryInterval}, nil
+}
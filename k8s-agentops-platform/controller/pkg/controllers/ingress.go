@@ -0,0 +1,318 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/gateway"
+)
+
+const (
+	nginxAnnotationBase = "nginx.ingress.kubernetes.io/"
+
+	// streamTimeoutSeconds keeps SSE, WebSocket and gRPC streams open through the
+	// ingress for as long as a long generation may take
+	streamTimeoutSeconds = 3600
+)
+
+var gatewayGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "Gateway"}
+
+// ingressEnabled reports whether the agent is exposed outside the cluster
+func ingressEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.Ingress != nil && ad.Spec.Ingress.Enabled
+}
+
+// gatewayClassEnabled reports whether the agent is exposed through the Gateway API
+// instead of Ingresses
+func gatewayClassEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ingressEnabled(ad) && ad.Spec.Ingress.Class == agentopsv1alpha1.IngressClassGateway
+}
+
+// grpcIngressName returns the name of the Ingress serving the agent's gRPC port
+func grpcIngressName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-grpc"
+}
+
+// streamingAnnotations returns the ingress-nginx settings for long-lived streams.
+// Buffering is turned off so server-sent events reach the client token by token.
+func streamingAnnotations() map[string]string {
+	timeout := strconv.Itoa(streamTimeoutSeconds)
+	return map[string]string{
+		nginxAnnotationBase + "proxy-read-timeout": timeout,
+		nginxAnnotationBase + "proxy-send-timeout": timeout,
+		nginxAnnotationBase + "proxy-buffering":    "off",
+	}
+}
+
+// reconcileIngress exposes the agent's "http" port on spec.ingress.host and, with a
+// grpc port and spec.ingress.grpcHost, the gRPC port on a second Ingress. The gateway
+// class renders an HTTPRoute instead. Whatever is not needed is removed.
+func (r *AgentDeploymentReconciler) reconcileIngress(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	if err := validateShadow(ad); err != nil {
+		return err
+	}
+	httpIngress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: ad.Name, Namespace: ad.Namespace},
+	}
+	grpcIngress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: grpcIngressName(ad), Namespace: ad.Namespace},
+	}
+
+	if gatewayClassEnabled(ad) {
+		if err := r.deleteIngresses(ctx, ad, httpIngress, grpcIngress); err != nil {
+			return err
+		}
+		return r.reconcileGatewayRoute(ctx, ad)
+	}
+	if err := r.deleteGatewayRoute(ctx, ad); err != nil {
+		return err
+	}
+	if !ingressEnabled(ad) {
+		return r.deleteIngresses(ctx, ad, httpIngress, grpcIngress)
+	}
+	annotations := streamingAnnotations()
+	for k, v := range shadowAnnotations(ad) {
+		annotations[k] = v
+	}
+	if err := r.applyIngress(ctx, ad, httpIngress, ad.Spec.Ingress.Host, httpPortName, annotations); err != nil {
+		return err
+	}
+
+	port := grpcPort(ad)
+	if port == nil || ad.Spec.Ingress.GRPCHost == "" {
+		return r.deleteIngresses(ctx, ad, grpcIngress)
+	}
+	annotations = streamingAnnotations()
+	annotations[nginxAnnotationBase+"backend-protocol"] = "GRPC"
+	return r.applyIngress(ctx, ad, grpcIngress, ad.Spec.Ingress.GRPCHost, port.Name, annotations)
+}
+
+// applyIngress creates or updates an Ingress routing host to a port of the agent Service
+func (r *AgentDeploymentReconciler) applyIngress(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, ing *networkingv1.Ingress, host, port string, annotations map[string]string) error {
+	pathType := networkingv1.PathTypePrefix
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, ing, func() error {
+		ing.Labels = labelsForAgentDeployment(ad.Name)
+		if ing.Annotations == nil {
+			ing.Annotations = map[string]string{}
+		}
+		// The mirror follows spec.shadow, so drop the one of a removed shadow
+		delete(ing.Annotations, nginxMirrorAnnotation)
+		for k, v := range annotations {
+			ing.Annotations[k] = v
+		}
+		ing.Spec.IngressClassName = ad.Spec.Ingress.ClassName
+		ing.Spec.Rules = []networkingv1.IngressRule{{
+			Host: host,
+			IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+				Paths: []networkingv1.HTTPIngressPath{{
+					Path:     "/",
+					PathType: &pathType,
+					Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+						Name: ad.Name,
+						Port: networkingv1.ServiceBackendPort{Name: port},
+					}},
+				}},
+			}},
+		}}
+		ing.Spec.TLS = nil
+		if ad.Spec.Ingress.TLS && host != "" {
+			ing.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{host}, SecretName: tlsSecretName(ing.Name)}}
+		}
+		return controllerutil.SetControllerReference(ad, ing, r.Scheme)
+	})
+	return err
+}
+
+// deleteIngresses deletes the given Ingresses if the agent owns them; Ingresses that
+// users created by hand before the controller managed them are left alone
+func (r *AgentDeploymentReconciler) deleteIngresses(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, ingresses ...*networkingv1.Ingress) error {
+	for _, ing := range ingresses {
+		if err := r.Get(ctx, client.ObjectKeyFromObject(ing), ing); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if !metav1.IsControlledBy(ing, ad) {
+			continue
+		}
+		if err := r.Delete(ctx, ing); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileGatewayRoute creates or updates the agent's HTTPRoute and, with
+// spec.ingress.gateway.gatewayClassName, the Gateway it attaches to
+func (r *AgentDeploymentReconciler) reconcileGatewayRoute(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	spec := ad.Spec.Ingress.Gateway
+	if spec == nil || (len(spec.ParentRefs) == 0 && spec.GatewayClassName == "") {
+		return fmt.Errorf("spec.ingress.gateway needs parentRefs or a gatewayClassName")
+	}
+	rules, err := r.gatewayRulesForAgentDeployment(ctx, ad)
+	if err != nil {
+		return err
+	}
+	if spec.GatewayClassName != "" {
+		if err := r.reconcileUnstructured(ctx, ad, gatewayForAgentDeployment(ad)); err != nil {
+			return err
+		}
+	} else if err := r.deleteOwnedUnstructured(ctx, ad, gatewayGVK, ad.Name); err != nil {
+		return err
+	}
+	return r.reconcileUnstructured(ctx, ad, httpRouteForAgentDeployment(ad, rules))
+}
+
+// deleteGatewayRoute removes the agent's HTTPRoute and Gateway
+func (r *AgentDeploymentReconciler) deleteGatewayRoute(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	if err := r.deleteOwnedUnstructured(ctx, ad, httpRouteGVK, ad.Name); err != nil {
+		return err
+	}
+	return r.deleteOwnedUnstructured(ctx, ad, gatewayGVK, ad.Name)
+}
+
+// gatewayRulesForAgentDeployment resolves spec.ingress.gateway.rules into gateway
+// rules. Model rules expand to the AgentDeployments serving the model, and backends
+// whose AgentDeployment does not exist, is being deleted or does not serve their
+// adapter are dropped. Rules sending traffic to the agent itself mirror it to
+// spec.shadow.
+func (r *AgentDeploymentReconciler) gatewayRulesForAgentDeployment(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) ([]gateway.Rule, error) {
+	self := gateway.Backend{Service: ad.Name, Namespace: ad.Namespace, Port: agentServicePort, Weight: 1}
+	specRules := ad.Spec.Ingress.Gateway.Rules
+	if len(specRules) == 0 {
+		return []gateway.Rule{{Backends: []gateway.Backend{self}, Mirror: shadowMirror(ad)}}, nil
+	}
+
+	list := &agentopsv1alpha1.AgentDeploymentList{}
+	if err := r.List(ctx, list, client.InNamespace(ad.Namespace)); err != nil {
+		return nil, err
+	}
+	agents := map[string]*agentopsv1alpha1.AgentDeployment{}
+	for i := range list.Items {
+		if list.Items[i].DeletionTimestamp.IsZero() {
+			agents[list.Items[i].Name] = &list.Items[i]
+		}
+	}
+
+	var rules []gateway.Rule
+	for _, rule := range specRules {
+		gwRule := gateway.Rule{PathPrefix: rule.PathPrefix, Headers: rule.Headers}
+		backends := rule.Backends
+		if len(backends) == 0 && rule.Model != "" {
+			for _, other := range agents {
+				if other.Spec.Model == rule.Model {
+					backends = append(backends, agentopsv1alpha1.AgentRouteBackend{Name: other.Name, Weight: 1})
+				}
+			}
+			// Keep the rendered route stable across reconciles
+			sort.Slice(backends, func(i, j int) bool { return backends[i].Name < backends[j].Name })
+		}
+		if len(backends) == 0 && rule.Model == "" {
+			gwRule.Backends = []gateway.Backend{self}
+			gwRule.Mirror = shadowMirror(ad)
+		}
+		for _, b := range backends {
+			target, ok := agents[b.Name]
+			if b.Name == ad.Name {
+				target = ad
+			} else if !ok {
+				continue
+			}
+			backend := gateway.Backend{
+				Service:   b.Name,
+				Namespace: ad.Namespace,
+				Port:      agentServicePort,
+				Weight:    b.Weight,
+			}
+			if b.Adapter != "" {
+				if !hasAdapter(target, b.Adapter) {
+					r.Log.Info("Skipping backend with unknown adapter", "AgentDeployment", ad.Name, "Backend", b.Name, "Adapter", b.Adapter)
+					continue
+				}
+				backend.Pool = "adapter-" + b.Adapter
+				backend.RequestHeaders = map[string]string{adapterHeader: b.Adapter}
+			}
+			if b.Name == ad.Name {
+				gwRule.Mirror = shadowMirror(ad)
+			}
+			gwRule.Backends = append(gwRule.Backends, backend)
+		}
+		rules = append(rules, gwRule)
+	}
+	return rules, nil
+}
+
+// httpRouteForAgentDeployment returns the HTTPRoute exposing the agent
+func httpRouteForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment, rules []gateway.Rule) *unstructured.Unstructured {
+	spec := ad.Spec.Ingress.Gateway
+	parentRefs := parentRefsToUnstructured(spec.ParentRefs)
+	if spec.GatewayClassName != "" {
+		parentRefs = append(parentRefs, map[string]interface{}{"name": ad.Name})
+	}
+	var hostnames []interface{}
+	if host := ad.Spec.Ingress.Host; host != "" {
+		hostnames = append(hostnames, host)
+	}
+	var httpRules []interface{}
+	for _, rule := range rules {
+		if len(rule.Backends) == 0 {
+			continue
+		}
+		httpRules = append(httpRules, httpRouteRule(rule))
+	}
+
+	route := newUnstructured(httpRouteGVK, ad.Name, ad.Namespace)
+	route.SetLabels(labelsForAgentDeployment(ad.Name))
+	route.Object["spec"] = map[string]interface{}{
+		"parentRefs": parentRefs,
+		"hostnames":  hostnames,
+		"rules":      httpRules,
+	}
+	return route
+}
+
+// gatewayForAgentDeployment returns the Gateway created for the agent. With TLS it
+// also terminates HTTPS with the certificate in the <name>-tls Secret.
+func gatewayForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) *unstructured.Unstructured {
+	host := ad.Spec.Ingress.Host
+	listener := func(name, protocol string, port int64) map[string]interface{} {
+		l := map[string]interface{}{
+			"name":     name,
+			"protocol": protocol,
+			"port":     port,
+		}
+		if host != "" {
+			l["hostname"] = host
+		}
+		return l
+	}
+	listeners := []interface{}{listener("http", "HTTP", 80)}
+	if ad.Spec.Ingress.TLS && host != "" {
+		https := listener("https", "HTTPS", 443)
+		https["tls"] = map[string]interface{}{
+			"mode":            "Terminate",
+			"certificateRefs": []interface{}{map[string]interface{}{"name": tlsSecretName(ad.Name)}},
+		}
+		listeners = append(listeners, https)
+	}
+
+	gw := newUnstructured(gatewayGVK, ad.Name, ad.Namespace)
+	gw.SetLabels(labelsForAgentDeployment(ad.Name))
+	gw.Object["spec"] = map[string]interface{}{
+		"gatewayClassName": ad.Spec.Ingress.Gateway.GatewayClassName,
+		"listeners":        listeners,
+	}
+	return gw
+}
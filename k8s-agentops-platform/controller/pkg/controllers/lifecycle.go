@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// applyLifecycle renders spec.lifecycle into the agent Deployment: the termination
+// grace period and minimum ready time, and a preStop hook on the agent container
+// that calls the drain endpoint before the container receives SIGTERM
+func applyLifecycle(ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment) {
+	spec := ad.Spec.Lifecycle
+	if spec == nil {
+		return
+	}
+	dep.Spec.MinReadySeconds = spec.MinReadySeconds
+	pod := &dep.Spec.Template.Spec
+	pod.TerminationGracePeriodSeconds = spec.TerminationGracePeriodSeconds
+
+	if spec.PreStopDrainPath == "" {
+		return
+	}
+	agent := &pod.Containers[0]
+	if agent.Lifecycle == nil {
+		agent.Lifecycle = &corev1.Lifecycle{}
+	}
+	agent.Lifecycle.PreStop = &corev1.LifecycleHandler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Path: spec.PreStopDrainPath,
+			Port: intstr.FromString("http"),
+		},
+	}
+}
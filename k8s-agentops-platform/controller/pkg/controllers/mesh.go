@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+var (
+	virtualServiceGVK     = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1beta1", Kind: "VirtualService"}
+	destinationRuleGVK    = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1beta1", Kind: "DestinationRule"}
+	peerAuthenticationGVK = schema.GroupVersionKind{Group: "security.istio.io", Version: "v1beta1", Kind: "PeerAuthentication"}
+)
+
+// istioEnabled reports whether the agent joins an Istio mesh
+func istioEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.Mesh != nil && ad.Spec.Mesh.Provider == agentopsv1alpha1.MeshProviderIstio
+}
+
+// meshMTLSMode returns the Istio mutual TLS mode of the agent pods; spec.peerAuth
+// mtls requires it strictly, so that peers are authenticated by their mesh identity
+func meshMTLSMode(ad *agentopsv1alpha1.AgentDeployment) string {
+	if ad.Spec.PeerAuth == agentopsv1alpha1.PeerAuthMTLS {
+		return "STRICT"
+	}
+	return ad.Spec.Mesh.MTLS
+}
+
+// meshAnnotations returns the sidecar injection annotations of the agent pods
+func meshAnnotations(ad *agentopsv1alpha1.AgentDeployment) map[string]string {
+	mesh := ad.Spec.Mesh
+	if mesh == nil || (mesh.InjectSidecar != nil && !*mesh.InjectSidecar) {
+		return nil
+	}
+	switch mesh.Provider {
+	case agentopsv1alpha1.MeshProviderIstio:
+		return map[string]string{"sidecar.istio.io/inject": "true"}
+	case agentopsv1alpha1.MeshProviderLinkerd:
+		return map[string]string{"linkerd.io/inject": "enabled"}
+	}
+	return nil
+}
+
+// serviceHost returns the in-cluster DNS name of an agent's Service
+func serviceHost(name, namespace string) string {
+	return fmt.Sprintf("%s.%s.svc.cluster.local", name, namespace)
+}
+
+// virtualServiceForAgentDeployment renders the timeout, retries and canary split of
+// spec.mesh, and the mirror of spec.shadow, into a VirtualService for the agent's
+// Service, or returns nil when none is set
+func virtualServiceForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) *unstructured.Unstructured {
+	// Flagger owns the VirtualService of agents it rolls out
+	if !istioEnabled(ad) || flaggerEnabled(ad) {
+		return nil
+	}
+	mesh := ad.Spec.Mesh
+	if mesh.Timeout == nil && mesh.Retries == nil && mesh.Canary == nil && ad.Spec.Shadow == nil {
+		return nil
+	}
+
+	host := serviceHost(ad.Name, ad.Namespace)
+	destination := func(host string, weight int32) interface{} {
+		return map[string]interface{}{
+			"destination": map[string]interface{}{
+				"host": host,
+				"port": map[string]interface{}{"number": int64(agentServicePort)},
+			},
+			"weight": int64(weight),
+		}
+	}
+	route := []interface{}{destination(host, 100)}
+	if c := mesh.Canary; c != nil {
+		route = []interface{}{
+			destination(host, 100-c.Weight),
+			destination(serviceHost(c.AgentDeployment, ad.Namespace), c.Weight),
+		}
+	}
+	http := map[string]interface{}{"route": route}
+	if mesh.Timeout != nil {
+		http["timeout"] = mesh.Timeout.Duration.String()
+	}
+	if r := mesh.Retries; r != nil {
+		retries := map[string]interface{}{"attempts": int64(r.Attempts)}
+		if r.PerTryTimeout != nil {
+			retries["perTryTimeout"] = r.PerTryTimeout.Duration.String()
+		}
+		if r.RetryOn != "" {
+			retries["retryOn"] = r.RetryOn
+		}
+		http["retries"] = retries
+	}
+	if s := ad.Spec.Shadow; s != nil {
+		http["mirror"] = map[string]interface{}{
+			"host": serviceHost(s.AgentDeployment, ad.Namespace),
+			"port": map[string]interface{}{"number": int64(agentServicePort)},
+		}
+		http["mirrorPercentage"] = map[string]interface{}{"value": float64(shadowPercent(ad))}
+	}
+
+	vs := newUnstructured(virtualServiceGVK, ad.Name, ad.Namespace)
+	vs.SetLabels(labelsForAgentDeployment(ad.Name))
+	vs.Object["spec"] = map[string]interface{}{
+		"hosts": []interface{}{host},
+		"http":  []interface{}{http},
+	}
+	return vs
+}
+
+// destinationRuleForAgentDeployment makes mesh clients of the agent use Istio mutual
+// TLS, or returns nil when neither spec.mesh.mtls nor spec.peerAuth mtls is set
+func destinationRuleForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) *unstructured.Unstructured {
+	if !istioEnabled(ad) || meshMTLSMode(ad) == "" {
+		return nil
+	}
+	dr := newUnstructured(destinationRuleGVK, ad.Name, ad.Namespace)
+	dr.SetLabels(labelsForAgentDeployment(ad.Name))
+	dr.Object["spec"] = map[string]interface{}{
+		"host": serviceHost(ad.Name, ad.Namespace),
+		"trafficPolicy": map[string]interface{}{
+			"tls": map[string]interface{}{"mode": "ISTIO_MUTUAL"},
+		},
+	}
+	return dr
+}
+
+// peerAuthenticationForAgentDeployment enforces the mutual TLS mode of the agent
+// pods, or returns nil when none is set
+func peerAuthenticationForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) *unstructured.Unstructured {
+	if !istioEnabled(ad) || meshMTLSMode(ad) == "" {
+		return nil
+	}
+	matchLabels := map[string]interface{}{}
+	for k, v := range labelsForAgentDeployment(ad.Name) {
+		matchLabels[k] = v
+	}
+	pa := newUnstructured(peerAuthenticationGVK, ad.Name, ad.Namespace)
+	pa.SetLabels(labelsForAgentDeployment(ad.Name))
+	pa.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{"matchLabels": matchLabels},
+		"mtls":     map[string]interface{}{"mode": meshMTLSMode(ad)},
+	}
+	return pa
+}
+
+// reconcileMesh creates or updates the Istio resources of spec.mesh and removes the
+// ones it created that are no longer needed
+func (r *AgentDeploymentReconciler) reconcileMesh(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	if c := ad.Spec.Mesh; c != nil && c.Canary != nil && c.Canary.AgentDeployment == ad.Name {
+		return fmt.Errorf("spec.mesh.canary must name another AgentDeployment")
+	}
+	for _, res := range []struct {
+		gvk     schema.GroupVersionKind
+		desired *unstructured.Unstructured
+	}{
+		{virtualServiceGVK, virtualServiceForAgentDeployment(ad)},
+		{destinationRuleGVK, destinationRuleForAgentDeployment(ad)},
+		{peerAuthenticationGVK, peerAuthenticationForAgentDeployment(ad)},
+	} {
+		if res.desired != nil {
+			if err := r.reconcileUnstructured(ctx, ad, res.desired); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := r.deleteOwnedUnstructured(ctx, ad, res.gvk, ad.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	modelCacheDir        = "/cache"
+	modelCacheHostRoot   = "/var/lib/agentops/models"
+	modelCacheVolumeName = "cache"
+	hfFetchImage         = "python:3.11-slim"
+	ociFetchImage        = "ghcr.io/oras-project/oras:v1.1.0"
+	pauseImage           = "registry.k8s.io/pause:3.9"
+	modelCacheBackoff    = int32(3)
+)
+
+// ModelCacheReconciler reconciles a ModelCache object
+type ModelCacheReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=modelcaches,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=modelcaches/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile downloads the model artifacts into the cache and reports when they are ready
+func (r *ModelCacheReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("modelcache", req.NamespacedName)
+
+	mc := &agentopsv1alpha1.ModelCache{}
+	if err := r.Get(ctx, req.NamespacedName, mc); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get ModelCache")
+		return ctrl.Result{}, err
+	}
+
+	fetch, err := fetchContainer(mc)
+	switch {
+	case err != nil:
+		err = r.invalidSpec(mc, err.Error())
+	case mc.Spec.Storage.Mode == agentopsv1alpha1.ModelCacheNodeLocal:
+		err = r.reconcileNodeLocalCache(ctx, mc, fetch)
+	default:
+		err = r.reconcilePVCCache(ctx, mc, fetch)
+	}
+	if err != nil {
+		log.Error(err, "Failed to reconcile model cache")
+		return ctrl.Result{}, err
+	}
+
+	mc.Status.ObservedGeneration = mc.Generation
+	return ctrl.Result{}, patchStatus(ctx, r.Client, mc)
+}
+
+// reconcilePVCCache populates a ReadOnlyMany claim with a one-shot Job
+func (r *ModelCacheReconciler) reconcilePVCCache(ctx context.Context, mc *agentopsv1alpha1.ModelCache, fetch corev1.Container) error {
+	if mc.Spec.Storage.Size == nil {
+		return r.invalidSpec(mc, "storage.size is required in PVC mode")
+	}
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: mc.Name, Namespace: mc.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, pvc, func() error {
+		pvc.Labels = labelsForModelCache(mc.Name)
+		if pvc.CreationTimestamp.IsZero() {
+			// Written once by the fetch Job, then mounted read-only by every model server
+			pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce, corev1.ReadOnlyMany}
+			pvc.Spec.StorageClassName = mc.Spec.Storage.StorageClassName
+		}
+		// Claims can grow but never shrink
+		if current, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; !ok || mc.Spec.Storage.Size.Cmp(current) > 0 {
+			pvc.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: *mc.Spec.Storage.Size}
+		}
+		return controllerutil.SetControllerReference(mc, pvc, r.Scheme)
+	}); err != nil {
+		return err
+	}
+	mc.Status.ClaimName = pvc.Name
+
+	job := &batchv1.Job{}
+	err := r.Get(ctx, client.ObjectKey{Name: mc.Name + "-fetch", Namespace: mc.Namespace}, job)
+	if errors.IsNotFound(err) {
+		job = r.fetchJob(mc, fetch)
+		r.Log.Info("Creating model fetch Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		if err := r.Create(ctx, job); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	gen := mc.Generation
+	switch {
+	case jobHasCondition(job, batchv1.JobComplete):
+		mc.Status.Phase = agentopsv1alpha1.ModelCacheReady
+		conditions.Set(&mc.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonCached,
+			fmt.Sprintf("Artifacts cached in PersistentVolumeClaim %s", pvc.Name), gen)
+	case jobHasCondition(job, batchv1.JobFailed):
+		mc.Status.Phase = agentopsv1alpha1.ModelCacheFailed
+		conditions.Set(&mc.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonDownloadFailed,
+			jobFailureMessage(job), gen)
+	default:
+		mc.Status.Phase = agentopsv1alpha1.ModelCacheDownloading
+		conditions.Set(&mc.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonDownloading,
+			"Downloading model artifacts", gen)
+	}
+	return nil
+}
+
+// fetchJob returns the Job downloading the artifacts into the cache claim
+func (r *ModelCacheReconciler) fetchJob(mc *agentopsv1alpha1.ModelCache, fetch corev1.Container) *batchv1.Job {
+	labels := labelsForModelCache(mc.Name)
+	backoff := modelCacheBackoff
+	fetch.VolumeMounts = append(fetch.VolumeMounts, corev1.VolumeMount{Name: modelCacheVolumeName, MountPath: modelCacheDir})
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: mc.Name + "-fetch", Namespace: mc.Namespace, Labels: labels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoff,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers:    []corev1.Container{fetch},
+					Volumes: append(fetchVolumes(mc), corev1.Volume{
+						Name: modelCacheVolumeName,
+						VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: mc.Name,
+						}},
+					}),
+				},
+			},
+		},
+	}
+	controllerutil.SetControllerReference(mc, job, r.Scheme)
+	return job
+}
+
+// reconcileNodeLocalCache populates a host directory on every selected node with a
+// DaemonSet whose init container downloads the artifacts; a pod becomes ready once
+// its node holds them
+func (r *ModelCacheReconciler) reconcileNodeLocalCache(ctx context.Context, mc *agentopsv1alpha1.ModelCache, fetch corev1.Container) error {
+	hostPath := modelCacheHostPath(mc)
+	labels := labelsForModelCache(mc.Name)
+	fetch.VolumeMounts = append(fetch.VolumeMounts, corev1.VolumeMount{Name: modelCacheVolumeName, MountPath: modelCacheDir})
+	hostPathType := corev1.HostPathDirectoryOrCreate
+
+	ds := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: mc.Name + "-fetch", Namespace: mc.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, ds, func() error {
+		ds.Labels = labels
+		if ds.CreationTimestamp.IsZero() {
+			ds.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+		}
+		ds.Spec.Template.Labels = labels
+		ds.Spec.Template.Spec.NodeSelector = mc.Spec.Storage.NodeSelector
+		ds.Spec.Template.Spec.InitContainers = []corev1.Container{fetch}
+		ds.Spec.Template.Spec.Containers = []corev1.Container{{Name: "hold", Image: pauseImage}}
+		ds.Spec.Template.Spec.Volumes = append(fetchVolumes(mc), corev1.Volume{
+			Name: modelCacheVolumeName,
+			VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{
+				Path: hostPath,
+				Type: &hostPathType,
+			}},
+		})
+		return controllerutil.SetControllerReference(mc, ds, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	mc.Status.HostPath = hostPath
+	mc.Status.NodesReady = ds.Status.NumberReady
+	gen := mc.Generation
+	if ds.Status.DesiredNumberScheduled > 0 && ds.Status.NumberReady == ds.Status.DesiredNumberScheduled {
+		mc.Status.Phase = agentopsv1alpha1.ModelCacheReady
+		conditions.Set(&mc.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonCached,
+			fmt.Sprintf("Artifacts cached on %d nodes", ds.Status.NumberReady), gen)
+	} else {
+		mc.Status.Phase = agentopsv1alpha1.ModelCacheDownloading
+		conditions.Set(&mc.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonDownloading,
+			fmt.Sprintf("Artifacts cached on %d/%d nodes", ds.Status.NumberReady, ds.Status.DesiredNumberScheduled), gen)
+	}
+	return nil
+}
+
+// invalidSpec marks the cache failed with an InvalidSpec reason
+func (r *ModelCacheReconciler) invalidSpec(mc *agentopsv1alpha1.ModelCache, message string) error {
+	mc.Status.Phase = agentopsv1alpha1.ModelCacheFailed
+	conditions.Set(&mc.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonInvalidSpec, message, mc.Generation)
+	return nil
+}
+
+// fetchContainer returns the container downloading the artifacts of the source into
+// modelCacheDir. Every download tool skips files already present, so re-running it
+// on a populated cache is cheap.
+func fetchContainer(mc *agentopsv1alpha1.ModelCache) (corev1.Container, error) {
+	src := mc.Spec.Source
+	c := corev1.Container{Name: "fetch"}
+	switch {
+	case src.HuggingFace != nil:
+		revision := src.HuggingFace.Revision
+		if revision == "" {
+			revision = "main"
+		}
+		c.Image = hfFetchImage
+		c.Command = []string{"sh", "-c",
+			`pip install --quiet 'huggingface_hub[hf_transfer]' && huggingface-cli download "$HF_REPO" --revision "$HF_REVISION" --local-dir ` + modelCacheDir}
+		c.Env = []corev1.EnvVar{
+			{Name: "HF_REPO", Value: src.HuggingFace.Repo},
+			{Name: "HF_REVISION", Value: revision},
+			{Name: "HF_HUB_ENABLE_HF_TRANSFER", Value: "1"},
+		}
+		if ref := src.HuggingFace.TokenSecretRef; ref != nil {
+			c.Env = append(c.Env, corev1.EnvVar{Name: "HF_TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: ref}})
+		}
+	case src.S3 != nil:
+		c.Image = s3SyncImage
+		c.Command = []string{"aws", "s3", "sync", src.S3.URI, modelCacheDir}
+		if src.S3.CredentialsSecretRef != nil {
+			c.EnvFrom = []corev1.EnvFromSource{{
+				SecretRef: &corev1.SecretEnvSource{LocalObjectReference: *src.S3.CredentialsSecretRef},
+			}}
+		}
+	case src.OCI != nil:
+		c.Image = ociFetchImage
+		c.Command = []string{"oras", "pull", src.OCI.Reference, "--output", modelCacheDir}
+		if src.OCI.PullSecretRef != nil {
+			c.Env = []corev1.EnvVar{{Name: "DOCKER_CONFIG", Value: "/etc/oras"}}
+			c.VolumeMounts = []corev1.VolumeMount{{Name: "registry-auth", MountPath: "/etc/oras", ReadOnly: true}}
+		}
+	default:
+		return c, fmt.Errorf("source must set one of huggingFace, s3 or oci")
+	}
+	return c, nil
+}
+
+// fetchVolumes returns the volumes the fetch container needs besides the cache
+func fetchVolumes(mc *agentopsv1alpha1.ModelCache) []corev1.Volume {
+	oci := mc.Spec.Source.OCI
+	if oci == nil || oci.PullSecretRef == nil {
+		return nil
+	}
+	return []corev1.Volume{{
+		Name: "registry-auth",
+		VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+			SecretName: oci.PullSecretRef.Name,
+			Items:      []corev1.KeyToPath{{Key: corev1.DockerConfigJsonKey, Path: "config.json"}},
+		}},
+	}}
+}
+
+// modelCacheHostPath returns the node directory of a NodeLocal cache
+func modelCacheHostPath(mc *agentopsv1alpha1.ModelCache) string {
+	return path.Join(modelCacheHostRoot, mc.Namespace, mc.Name)
+}
+
+// labelsForModelCache returns the labels of the cache's child resources
+func labelsForModelCache(name string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "model-cache",
+		"app.kubernetes.io/instance":   name,
+		"app.kubernetes.io/managed-by": "agentops-controller",
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *ModelCacheReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.ModelCache{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		Owns(&batchv1.Job{}).
+		Owns(&appsv1.DaemonSet{}).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("ModelCache", tracing.Reconciler("ModelCache", r)))
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// modelPolicyViolation is why a ModelPolicy rejects an agent
+type modelPolicyViolation struct {
+	Reason  string
+	Message string
+}
+
+// modelPolicyViolations returns why the ModelPolicies of the namespace reject the
+// agent's model or provider. An agent whose provider cannot be resolved is only
+// checked for its model; the invalid provider is reported elsewhere.
+func modelPolicyViolations(policies []agentopsv1alpha1.ModelPolicy, ad *agentopsv1alpha1.AgentDeployment) []modelPolicyViolation {
+	provider := ""
+	if p, err := providerForAgentDeployment(ad); err == nil {
+		provider = p.Name
+	}
+	var violations []modelPolicyViolation
+	for i := range policies {
+		spec := policies[i].Spec
+		name := policies[i].Name
+		if !listAllows(spec.AllowedModels, spec.DeniedModels, ad.Spec.Model) {
+			violations = append(violations, modelPolicyViolation{
+				Reason:  conditions.ReasonModelNotAllowed,
+				Message: fmt.Sprintf("ModelPolicy %s: model %s is not allowed", name, ad.Spec.Model),
+			})
+		}
+		if provider != "" && !listAllows(spec.AllowedProviders, spec.DeniedProviders, provider) {
+			violations = append(violations, modelPolicyViolation{
+				Reason:  conditions.ReasonProviderNotAllowed,
+				Message: fmt.Sprintf("ModelPolicy %s: provider %s is not allowed", name, provider),
+			})
+		}
+	}
+	return violations
+}
+
+// listAllows reports whether s passes an allow list, where empty allows all, and
+// a deny list
+func listAllows(allowed, denied []string, s string) bool {
+	if containsString(denied, s) {
+		return false
+	}
+	return len(allowed) == 0 || containsString(allowed, s)
+}
+
+// modelPolicyMessage joins violations into one message
+func modelPolicyMessage(violations []modelPolicyViolation) string {
+	messages := make([]string, 0, len(violations))
+	for _, v := range violations {
+		messages = append(messages, v.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// reconcileModelPolicies sets the Rejected condition from the ModelPolicies of the
+// namespace and reports whether the agent is rejected. Agents created before a
+// policy existed are not rolled out further; their existing child objects are left
+// as they are.
+func (r *AgentDeploymentReconciler) reconcileModelPolicies(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (bool, error) {
+	policies := &agentopsv1alpha1.ModelPolicyList{}
+	if err := r.List(ctx, policies, client.InNamespace(ad.Namespace)); err != nil {
+		return false, err
+	}
+	violations := modelPolicyViolations(policies.Items, ad)
+	if len(violations) == 0 {
+		if conditions.Get(ad.Status.Conditions, conditions.Rejected) != nil {
+			conditions.Set(&ad.Status.Conditions, conditions.Rejected, metav1.ConditionFalse, conditions.ReasonAsExpected,
+				"Model and provider are allowed by the namespace's ModelPolicies", ad.Generation)
+		}
+		return false, nil
+	}
+	message := modelPolicyMessage(violations)
+	r.Log.Info("AgentDeployment rejected by ModelPolicy", "AgentDeployment", ad.Name, "Namespace", ad.Namespace, "Message", message)
+	conditions.Set(&ad.Status.Conditions, conditions.Rejected, metav1.ConditionTrue, violations[0].Reason, message, ad.Generation)
+	return true, r.patchBlockedStatus(ctx, ad)
+}
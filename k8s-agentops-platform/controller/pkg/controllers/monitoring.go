@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/grafana"
+)
+
+var prometheusRuleGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule"}
+
+// monitoringEnabled reports whether observability resources are generated for the agent
+func monitoringEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.Monitoring != nil && ad.Spec.Monitoring.Enabled
+}
+
+// dashboardConfigMapName returns the name of the agent's Grafana dashboard ConfigMap
+func dashboardConfigMapName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-dashboard"
+}
+
+// reconcileDashboard publishes the agent's Grafana dashboard as a ConfigMap picked up
+// by the Grafana dashboard sidecar, or removes it when monitoring is disabled
+func (r *AgentDeploymentReconciler) reconcileDashboard(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: dashboardConfigMapName(ad), Namespace: ad.Namespace},
+	}
+	if !monitoringEnabled(ad) {
+		if err := r.Delete(ctx, cm); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	dashboard, err := grafana.RenderDashboard(grafana.Agent{
+		Namespace:     ad.Namespace,
+		Name:          ad.Name,
+		Service:       ad.Name,
+		Deployment:    ad.Name,
+		ResponseCache: responseCacheEnabled(ad),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Labels = labelsForAgentDeployment(ad.Name)
+		cm.Labels[grafana.SidecarLabel] = "1"
+		if cm.Annotations == nil {
+			cm.Annotations = map[string]string{}
+		}
+		cm.Annotations[grafana.FolderAnnotation] = grafana.Folder
+		cm.Data = map[string]string{ad.Namespace + "-" + ad.Name + ".json": dashboard}
+		return controllerutil.SetControllerReference(ad, cm, r.Scheme)
+	})
+	return err
+}
+
+// prometheusRuleName returns the name of the agent's PrometheusRule
+func prometheusRuleName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-alerts"
+}
+
+// prometheusRuleForAgentDeployment renders spec.monitoring.alerts into a
+// PrometheusRule, or returns nil when no alert is configured
+func prometheusRuleForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) *unstructured.Unstructured {
+	if !monitoringEnabled(ad) || ad.Spec.Monitoring.Alerts == nil {
+		return nil
+	}
+	alerts := ad.Spec.Monitoring.Alerts
+	traffic := fmt.Sprintf(`namespace=%q,service=%q`, ad.Namespace, ad.Name)
+	pods := fmt.Sprintf(`namespace=%q,pod=~"%s-[a-z0-9]+-[a-z0-9]+",container="agent"`, ad.Namespace, ad.Name)
+	subject := ad.Namespace + "/" + ad.Name
+
+	var rules []interface{}
+	if l := alerts.Latency; l != nil {
+		percentile := l.Percentile
+		if percentile == 0 {
+			percentile = 95
+		}
+		rules = append(rules, alertRule(alerts, "AgentHighLatency",
+			fmt.Sprintf(`histogram_quantile(%g, sum(rate(http_request_duration_seconds_bucket{%s}[5m])) by (le)) > %g`,
+				float64(percentile)/100, traffic, l.Threshold.Seconds()),
+			durationOrDefault(l.For, 5*time.Minute),
+			fmt.Sprintf("p%d latency of agent %s above %s", percentile, subject, l.Threshold.Duration),
+			fmt.Sprintf("p%d latency is {{ $value | humanizeDuration }}, objective is %s.", percentile, l.Threshold.Duration),
+		))
+	}
+	if e := alerts.ErrorRate; e != nil {
+		threshold := e.ThresholdPercent
+		if threshold == 0 {
+			threshold = 5
+		}
+		rules = append(rules, alertRule(alerts, "AgentHighErrorRate",
+			fmt.Sprintf(`sum(rate(http_requests_total{%s,status=~"5.."}[5m])) / sum(rate(http_requests_total{%s}[5m])) > %g`,
+				traffic, traffic, float64(threshold)/100),
+			durationOrDefault(e.For, 5*time.Minute),
+			fmt.Sprintf("Error rate of agent %s above %d%%", subject, threshold),
+			"Error rate is {{ $value | humanizePercentage }} over the last 5 minutes.",
+		))
+	}
+	if c := alerts.CrashLoop; c != nil && c.Enabled {
+		restarts := c.Restarts
+		if restarts == 0 {
+			restarts = 3
+		}
+		window := durationOrDefault(c.Window, 10*time.Minute)
+		rules = append(rules, alertRule(alerts, "AgentCrashLooping",
+			fmt.Sprintf(`increase(kube_pod_container_status_restarts_total{%s}[%s]) >= %d`, pods, window, restarts),
+			"0s",
+			fmt.Sprintf("Agent %s is crash looping", subject),
+			fmt.Sprintf("Pod {{ $labels.pod }} restarted {{ $value | humanize }} times in %s.", window),
+		))
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	rule := newUnstructured(prometheusRuleGVK, prometheusRuleName(ad), ad.Namespace)
+	rule.SetLabels(labelsForAgentDeployment(ad.Name))
+	rule.Object["spec"] = map[string]interface{}{
+		"groups": []interface{}{map[string]interface{}{
+			"name":  fmt.Sprintf("agentops.%s.%s", ad.Namespace, ad.Name),
+			"rules": rules,
+		}},
+	}
+	return rule
+}
+
+func alertRule(alerts *agentopsv1alpha1.AlertsSpec, name, expr, forDuration, summary, description string) map[string]interface{} {
+	severity := alerts.Severity
+	if severity == "" {
+		severity = "warning"
+	}
+	labels := map[string]interface{}{"severity": severity}
+	keys := make([]string, 0, len(alerts.Labels))
+	for k := range alerts.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		labels[k] = alerts.Labels[k]
+	}
+	return map[string]interface{}{
+		"alert":  name,
+		"expr":   expr,
+		"for":    forDuration,
+		"labels": labels,
+		"annotations": map[string]interface{}{
+			"summary":     summary,
+			"description": description,
+		},
+	}
+}
+
+// durationOrDefault renders d as a Prometheus duration in seconds
+func durationOrDefault(d *metav1.Duration, def time.Duration) string {
+	if d != nil {
+		def = d.Duration
+	}
+	return fmt.Sprintf("%ds", int64(def.Seconds()))
+}
+
+// reconcilePrometheusRule creates or updates the agent's PrometheusRule, or removes
+// it when no alert is configured
+func (r *AgentDeploymentReconciler) reconcilePrometheusRule(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	desired := prometheusRuleForAgentDeployment(ad)
+	if desired == nil {
+		rule := newUnstructured(prometheusRuleGVK, prometheusRuleName(ad), ad.Namespace)
+		// Without the Prometheus Operator CRDs there is nothing to clean up
+		if err := r.Delete(ctx, rule); err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return err
+		}
+		return nil
+	}
+	return r.reconcileUnstructured(ctx, ad, desired)
+}
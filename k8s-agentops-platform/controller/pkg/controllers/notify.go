@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/notify"
+)
+
+// Notifier delivers notifications to channels configured on the controller
+type Notifier interface {
+	Notify(ctx context.Context, channels []string, n notify.Notification) error
+}
+
+// notifiedConditions are the conditions whose transitions to and from True are
+// notified, with the severity of the transition to True
+var notifiedConditions = []struct {
+	condition string
+	event     string
+	severity  string
+}{
+	{conditions.Degraded, notify.EventDegraded, notify.SeverityCritical},
+	{conditions.Stalled, notify.EventStalled, notify.SeverityWarning},
+	{conditions.BudgetExceeded, notify.EventBudgetExceeded, notify.SeverityWarning},
+}
+
+// notifyTransitions notifies the phase and condition changes between the previous
+// and the persisted status
+func (r *AgentDeploymentReconciler) notifyTransitions(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, previous *agentopsv1alpha1.AgentDeploymentStatus) {
+	if previous.Phase != "" && previous.Phase != ad.Status.Phase {
+		severity := notify.SeverityInfo
+		if ad.Status.Phase == "Failed" {
+			severity = notify.SeverityCritical
+		}
+		r.notify(ctx, ad, notify.Notification{
+			Event:    notify.EventPhaseChanged,
+			Severity: severity,
+			Summary:  fmt.Sprintf("phase changed from %s to %s", previous.Phase, ad.Status.Phase),
+		})
+	}
+
+	for _, nc := range notifiedConditions {
+		was := conditions.IsTrue(previous.Conditions, nc.condition)
+		c := conditions.Get(ad.Status.Conditions, nc.condition)
+		is := c != nil && c.Status == metav1.ConditionTrue
+		switch {
+		case is && !was:
+			r.notify(ctx, ad, notify.Notification{
+				Event:    nc.event,
+				Severity: nc.severity,
+				Summary:  fmt.Sprintf("%s (%s)", nc.condition, c.Reason),
+				Message:  c.Message,
+			})
+		case was && !is:
+			r.notify(ctx, ad, notify.Notification{
+				Event:    nc.event,
+				Severity: notify.SeverityInfo,
+				Summary:  fmt.Sprintf("no longer %s", nc.condition),
+				Resolved: true,
+			})
+		}
+	}
+}
+
+// notify sends n to the channels the agent opted in to. Delivery is best effort:
+// a failure is logged and does not fail the reconcile.
+func (r *AgentDeploymentReconciler) notify(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, n notify.Notification) {
+	if r.Notifier == nil {
+		return
+	}
+	channels := splitList(ad.Annotations[agentopsv1alpha1.NotifyAnnotation])
+	if len(channels) == 0 {
+		return
+	}
+	if events := splitList(ad.Annotations[agentopsv1alpha1.NotifyEventsAnnotation]); len(events) > 0 && !containsString(events, n.Event) {
+		return
+	}
+	n.Namespace, n.AgentDeployment, n.Time = ad.Namespace, ad.Name, time.Now().UTC()
+	if err := r.Notifier.Notify(ctx, channels, n); err != nil {
+		r.Log.Error(err, "Failed to send notification", "AgentDeployment", ad.Name, "Namespace", ad.Namespace, "Event", n.Event)
+	}
+}
+
+// splitList splits a comma-separated annotation value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,378 @@
+package controllers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/gateway"
+)
+
+const (
+	// peerIssuer signs the tokens peer agents present
+	peerIssuer = "agentops.io"
+	// peerTokenHeader carries the token of a calling agent
+	peerTokenHeader = "x-agentops-peer-token"
+	// peerIdentityHeader tells the agent which peer called, once its token verified
+	peerIdentityHeader = "x-agentops-peer"
+
+	peerSigningKey    = "key"
+	peerJWKSKey       = "jwks.json"
+	peerKeyVolumeName = "peer-key"
+	peerKeyMountPath  = "/etc/agentops/peer"
+
+	// peerTokensAnnotation on the pod template rolls the pods when the tokens of
+	// their peers change
+	peerTokensAnnotation = "agentops.io/peer-tokens-hash"
+)
+
+// peerNamespace returns the namespace of a peer
+func peerNamespace(ad *agentopsv1alpha1.AgentDeployment, ref agentopsv1alpha1.PeerReference) string {
+	if ref.Namespace != "" {
+		return ref.Namespace
+	}
+	return ad.Namespace
+}
+
+// peerID identifies an agent in peer tokens
+func peerID(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// peerTokenAuth reports whether peers calling the agent present signed tokens
+func peerTokenAuth(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.PeerAuth == agentopsv1alpha1.PeerAuthToken
+}
+
+// peerKeySecretName returns the name of the Secret holding the key peer tokens
+// for the agent are signed with
+func peerKeySecretName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-peer-key"
+}
+
+// peerTokensSecretName returns the name of the Secret holding the tokens the agent
+// presents to its peers
+func peerTokensSecretName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-peer-tokens"
+}
+
+// peerTokenKey returns the key of the token for a peer in the tokens Secret
+func peerTokenKey(namespace, name string) string {
+	return namespace + "." + name
+}
+
+// peerEnvForAgentDeployment returns the endpoints of spec.peers: AGENT_PEERS lists
+// them, and PEER_<NAME>_URL and PEER_<NAME>_TOKEN hold the URL of each peer and the
+// token it expects, if any. The endpoints only depend on the peer names, so the
+// agent starts before its peers.
+func peerEnvForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) []corev1.EnvVar {
+	if len(ad.Spec.Peers) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(ad.Spec.Peers))
+	var env []corev1.EnvVar
+	for _, ref := range ad.Spec.Peers {
+		namespace := peerNamespace(ad, ref)
+		names = append(names, ref.Name)
+		prefix := envPrefix("PEER", ref.Name)
+		env = append(env,
+			corev1.EnvVar{Name: prefix + "URL", Value: fmt.Sprintf("http://%s:%d", serviceHost(ref.Name, namespace), agentServicePort)},
+			credentialEnv(prefix+"TOKEN", peerTokensSecretName(ad), peerTokenKey(namespace, ref.Name), true),
+		)
+	}
+	return append([]corev1.EnvVar{
+		{Name: "AGENT_PEERS", Value: strings.Join(names, ",")},
+		{Name: "AGENT_PEER_TOKEN_HEADER", Value: peerTokenHeader},
+	}, env...)
+}
+
+// reconcilePeers enforces spec.peers and spec.peerAuth: a NetworkPolicy admitting
+// only declared peers, the key peer tokens for the agent are signed with, and the
+// tokens the agent presents to its peers. It returns the hash of those tokens.
+func (r *AgentDeploymentReconciler) reconcilePeers(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (string, error) {
+	if ad.Spec.PeerAuth == agentopsv1alpha1.PeerAuthMTLS && !istioEnabled(ad) {
+		return "", fmt.Errorf("spec.peerAuth mtls requires spec.mesh.provider istio")
+	}
+	callers, err := r.peerCallers(ctx, ad)
+	if err != nil {
+		return "", err
+	}
+	if err := r.reconcilePeerPolicy(ctx, ad, callers); err != nil {
+		return "", err
+	}
+	if err := r.reconcilePeerKey(ctx, ad); err != nil {
+		return "", err
+	}
+	return r.reconcilePeerTokens(ctx, ad)
+}
+
+// peerCallers returns the AgentDeployments declaring the agent as a peer
+func (r *AgentDeploymentReconciler) peerCallers(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) ([]agentopsv1alpha1.AgentDeployment, error) {
+	list := &agentopsv1alpha1.AgentDeploymentList{}
+	if err := r.List(ctx, list); err != nil {
+		return nil, err
+	}
+	var callers []agentopsv1alpha1.AgentDeployment
+	for _, caller := range list.Items {
+		if caller.Namespace == ad.Namespace && caller.Name == ad.Name {
+			continue
+		}
+		for _, ref := range caller.Spec.Peers {
+			if ref.Name == ad.Name && peerNamespace(&caller, ref) == ad.Namespace {
+				callers = append(callers, caller)
+				break
+			}
+		}
+	}
+	sort.Slice(callers, func(i, j int) bool {
+		return peerID(callers[i].Namespace, callers[i].Name) < peerID(callers[j].Namespace, callers[j].Name)
+	})
+	return callers, nil
+}
+
+// reconcilePeerPolicy admits to the pods of an agent taking part in a peering, by
+// declaring peers or being declared one, only its own pods, pods that are no agents
+// and the agents declaring it. Agents outside any peering accept every caller.
+func (r *AgentDeploymentReconciler) reconcilePeerPolicy(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, callers []agentopsv1alpha1.AgentDeployment) error {
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: ad.Name + "-peers", Namespace: ad.Namespace},
+	}
+	if len(ad.Spec.Peers) == 0 && len(callers) == 0 {
+		return deleteOwned(ctx, r.Client, ad, policy)
+	}
+
+	agentNames := []string{"agent", "agent-candidate"}
+	agentPods := func(name string) metav1.LabelSelector {
+		return metav1.LabelSelector{
+			MatchLabels: map[string]string{"app.kubernetes.io/instance": name, "app.kubernetes.io/managed-by": "agentops-controller"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      "app.kubernetes.io/name",
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   agentNames,
+			}},
+		}
+	}
+	self := agentPods(ad.Name)
+	from := []networkingv1.NetworkPolicyPeer{
+		{PodSelector: &self},
+		// Ingress controllers, gateways, the activator and every other non-agent pod
+		{
+			NamespaceSelector: &metav1.LabelSelector{},
+			PodSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      "app.kubernetes.io/name",
+				Operator: metav1.LabelSelectorOpNotIn,
+				Values:   agentNames,
+			}}},
+		},
+	}
+	for _, caller := range callers {
+		pods := agentPods(caller.Name)
+		from = append(from, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelMetadataName: caller.Namespace}},
+			PodSelector:       &pods,
+		})
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, policy, func() error {
+		policy.Labels = labelsForAgentDeployment(ad.Name)
+		policy.Spec = networkingv1.NetworkPolicySpec{
+			PodSelector: self,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: from}},
+		}
+		return controllerutil.SetControllerReference(ad, policy, r.Scheme)
+	})
+	return err
+}
+
+// reconcilePeerKey creates the signing key of an agent accepting peer tokens once,
+// or removes it when the agent no longer does
+func (r *AgentDeploymentReconciler) reconcilePeerKey(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: peerKeySecretName(ad), Namespace: ad.Namespace}, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+	if !peerTokenAuth(ad) {
+		if exists && metav1.IsControlledBy(secret, ad) {
+			return client.IgnoreNotFound(r.Delete(ctx, secret))
+		}
+		return nil
+	}
+	if exists {
+		if !metav1.IsControlledBy(secret, ad) {
+			return fmt.Errorf("secret %s exists and is not owned by the AgentDeployment", secret.Name)
+		}
+		return nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	jwks, err := json.Marshal(map[string]interface{}{"keys": []interface{}{map[string]interface{}{
+		"kty": "oct",
+		"alg": "HS256",
+		"k":   base64.RawURLEncoding.EncodeToString(key),
+	}}})
+	if err != nil {
+		return err
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: peerKeySecretName(ad), Namespace: ad.Namespace, Labels: labelsForAgentDeployment(ad.Name)},
+		Data:       map[string][]byte{peerSigningKey: key, peerJWKSKey: jwks},
+	}
+	if err := controllerutil.SetControllerReference(ad, secret, r.Scheme); err != nil {
+		return err
+	}
+	r.Log.Info("Creating peer signing key", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name)
+	return r.Create(ctx, secret)
+}
+
+// reconcilePeerTokens signs a token for every peer of the agent accepting peer
+// tokens with the peer's key, and keeps them in the tokens Secret. Peers whose key
+// does not exist yet are signed for once it does, as their changes requeue the
+// agents declaring them.
+func (r *AgentDeploymentReconciler) reconcilePeerTokens(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (string, error) {
+	tokens := map[string][]byte{}
+	for _, ref := range ad.Spec.Peers {
+		namespace := peerNamespace(ad, ref)
+		peer := &agentopsv1alpha1.AgentDeployment{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, peer); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return "", err
+		}
+		if !peerTokenAuth(peer) {
+			continue
+		}
+		key := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: peerKeySecretName(peer), Namespace: namespace}, key); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return "", err
+		}
+		token, err := signPeerToken(key.Data[peerSigningKey], peerID(ad.Namespace, ad.Name), peerID(namespace, ref.Name))
+		if err != nil {
+			return "", err
+		}
+		tokens[peerTokenKey(namespace, ref.Name)] = []byte(token)
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: peerTokensSecretName(ad), Namespace: ad.Namespace}}
+	if len(tokens) == 0 {
+		return "", deleteOwned(ctx, r.Client, ad, secret)
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Labels = labelsForAgentDeployment(ad.Name)
+		secret.Data = tokens
+		return controllerutil.SetControllerReference(ad, secret, r.Scheme)
+	}); err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(tokens))
+	for k := range tokens {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, tokens[k])
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// signPeerToken returns an HS256 JWT naming the calling agent as subject and the
+// called one as audience. It does not expire; rotating the key of the called agent
+// revokes it.
+func signPeerToken(key []byte, subject, audience string) (string, error) {
+	if len(key) == 0 {
+		return "", fmt.Errorf("peer signing key of %s is empty", audience)
+	}
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]string{"iss": peerIssuer, "sub": subject, "aud": audience})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// peerAuthForAgentDeployment returns the peer token verification of the agent's
+// gateway sidecar
+func peerAuthForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) gateway.PeerAuth {
+	return gateway.PeerAuth{
+		Issuer:         peerIssuer,
+		Audience:       peerID(ad.Namespace, ad.Name),
+		JWKSFile:       peerKeyMountPath + "/" + peerJWKSKey,
+		TokenHeader:    peerTokenHeader,
+		IdentityHeader: peerIdentityHeader,
+	}
+}
+
+// peerTokensOverlay sets the hash of the agent's peer tokens on the pod template
+func peerTokensOverlay(hash string) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		if hash == "" {
+			return
+		}
+		pod := &dep.Spec.Template
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[peerTokensAnnotation] = hash
+	}
+}
+
+// agentDeploymentsPeeredWith maps an AgentDeployment to its peers and the agents
+// declaring it, whose policies and tokens depend on it
+func (r *AgentDeploymentReconciler) agentDeploymentsPeeredWith(ctx context.Context, obj client.Object) []reconcile.Request {
+	ad, ok := obj.(*agentopsv1alpha1.AgentDeployment)
+	if !ok {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, ref := range ad.Spec.Peers {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: ref.Name, Namespace: peerNamespace(ad, ref)},
+		})
+	}
+	callers, err := r.peerCallers(ctx, ad)
+	if err != nil {
+		r.Log.Error(err, "Failed to list AgentDeployments")
+		return requests
+	}
+	for _, caller := range callers {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: caller.Name, Namespace: caller.Namespace},
+		})
+	}
+	return requests
+}
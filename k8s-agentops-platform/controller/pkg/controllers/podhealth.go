@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// podFailure is the failure reason of a single pod, as published on the Degraded condition
+type podFailure struct {
+	Reason string
+	Detail string
+}
+
+// podFailureSeverity orders failure reasons; a reason listed first wins ties when
+// several affect the same number of pods
+var podFailureSeverity = []string{
+	conditions.ReasonOOMKilled,
+	conditions.ReasonCrashLoop,
+	conditions.ReasonImagePullError,
+	conditions.ReasonContainerConfigError,
+	conditions.ReasonUnschedulable,
+}
+
+// podFailureSummaries phrase each reason for the condition message
+var podFailureSummaries = map[string]string{
+	conditions.ReasonOOMKilled:            "killed for exceeding their memory limit",
+	conditions.ReasonCrashLoop:            "crash looping",
+	conditions.ReasonImagePullError:       "failing to pull their image",
+	conditions.ReasonContainerConfigError: "failing to create their containers",
+	conditions.ReasonUnschedulable:        "unschedulable",
+}
+
+// failureForPod classifies why a pod is not serving, or returns nil for a healthy
+// or merely starting pod
+func failureForPod(pod *corev1.Pod) *podFailure {
+	if pod.DeletionTimestamp != nil {
+		return nil
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse && c.Reason == corev1.PodReasonUnschedulable {
+			return &podFailure{Reason: conditions.ReasonUnschedulable, Detail: c.Message}
+		}
+	}
+
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if t := cs.State.Terminated; t != nil && t.Reason == "OOMKilled" {
+			return &podFailure{Reason: conditions.ReasonOOMKilled, Detail: fmt.Sprintf("container %s was OOMKilled", cs.Name)}
+		}
+		w := cs.State.Waiting
+		if w == nil {
+			continue
+		}
+		switch w.Reason {
+		case "CrashLoopBackOff":
+			if t := cs.LastTerminationState.Terminated; t != nil && t.Reason == "OOMKilled" {
+				return &podFailure{Reason: conditions.ReasonOOMKilled,
+					Detail: fmt.Sprintf("container %s was OOMKilled (%d restarts)", cs.Name, cs.RestartCount)}
+			}
+			detail := fmt.Sprintf("container %s restarted %d times", cs.Name, cs.RestartCount)
+			if t := cs.LastTerminationState.Terminated; t != nil {
+				detail += fmt.Sprintf(", last exit code %d", t.ExitCode)
+			}
+			return &podFailure{Reason: conditions.ReasonCrashLoop, Detail: detail}
+		case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull":
+			return &podFailure{Reason: conditions.ReasonImagePullError, Detail: waitingDetail(cs.Name, w)}
+		case "CreateContainerConfigError", "CreateContainerError":
+			return &podFailure{Reason: conditions.ReasonContainerConfigError, Detail: waitingDetail(cs.Name, w)}
+		}
+	}
+	return nil
+}
+
+func waitingDetail(container string, w *corev1.ContainerStateWaiting) string {
+	if w.Message == "" {
+		return fmt.Sprintf("container %s: %s", container, w.Reason)
+	}
+	return fmt.Sprintf("container %s: %s", container, w.Message)
+}
+
+// dominantPodFailure returns the failure affecting the most pods, with a message
+// naming how many pods it affects and an example, or nil when no pod is failing
+func dominantPodFailure(pods []corev1.Pod) (*podFailure, string) {
+	counts := map[string]int{}
+	examples := map[string]string{}
+	for i := range pods {
+		f := failureForPod(&pods[i])
+		if f == nil {
+			continue
+		}
+		counts[f.Reason]++
+		if _, ok := examples[f.Reason]; !ok {
+			examples[f.Reason] = fmt.Sprintf("%s: %s", pods[i].Name, f.Detail)
+		}
+	}
+
+	var dominant string
+	for _, reason := range podFailureSeverity {
+		if counts[reason] > counts[dominant] {
+			dominant = reason
+		}
+	}
+	if dominant == "" {
+		return nil, ""
+	}
+
+	message := fmt.Sprintf("%d/%d pods %s (%s)", counts[dominant], len(pods), podFailureSummaries[dominant], examples[dominant])
+	var others []string
+	for _, reason := range podFailureSeverity {
+		if reason != dominant && counts[reason] > 0 {
+			others = append(others, fmt.Sprintf("%d %s", counts[reason], podFailureSummaries[reason]))
+		}
+	}
+	if len(others) > 0 {
+		message += "; also " + strings.Join(others, ", ")
+	}
+	return &podFailure{Reason: dominant, Detail: examples[dominant]}, message
+}
+
+// setDegradedCondition rolls the failure reasons of the agent's pods up into the
+// Degraded condition and returns whether any pod is failing
+func (r *AgentDeploymentReconciler) setDegradedCondition(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (bool, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(ad.Namespace), client.MatchingLabels(labelsForAgentDeployment(ad.Name))); err != nil {
+		return false, err
+	}
+	failure, message := dominantPodFailure(pods.Items)
+	if failure == nil {
+		conditions.Set(&ad.Status.Conditions, conditions.Degraded, metav1.ConditionFalse, conditions.ReasonAsExpected,
+			"No failing pods", ad.Generation)
+		return false, nil
+	}
+	conditions.Set(&ad.Status.Conditions, conditions.Degraded, metav1.ConditionTrue, failure.Reason, message, ad.Generation)
+	return true, nil
+}
+
+// agentDeploymentForPod maps an agent pod to the AgentDeployment that runs it, so
+// pod failures show up in status without waiting for the Deployment to change
+func (r *AgentDeploymentReconciler) agentDeploymentForPod(ctx context.Context, obj client.Object) []reconcile.Request {
+	l := obj.GetLabels()
+	if l["app.kubernetes.io/name"] != "agent" || l["app.kubernetes.io/managed-by"] != "agentops-controller" || l["app.kubernetes.io/instance"] == "" {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Name: l["app.kubernetes.io/instance"], Namespace: obj.GetNamespace()},
+	}}
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/podpatch"
+)
+
+// podTemplatePatch renders the cluster-wide pod template patch for the agent. The
+// patch is a Go template executed with the AgentDeployment, so it can refer to e.g.
+// {{ .Name }} or {{ .Spec.Model }}. A missing ConfigMap is an error: silently
+// dropping org-wide requirements such as proxies or CA bundles is worse than not
+// rolling out.
+func (r *AgentDeploymentReconciler) podTemplatePatch(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) ([]byte, error) {
+	if r.PodTemplatePatch.Name == "" {
+		return nil, nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, r.PodTemplatePatch, cm); err != nil {
+		return nil, err
+	}
+	patch, err := podpatch.Render(cm.Data[podpatch.Key], ad)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod template patch %s: %w", r.PodTemplatePatch, err)
+	}
+	return patch, nil
+}
+
+// agentDeploymentsForPodTemplatePatch maps the pod template patch ConfigMap to every
+// AgentDeployment, so changes to it roll out to all agents
+func (r *AgentDeploymentReconciler) agentDeploymentsForPodTemplatePatch(ctx context.Context, obj client.Object) []reconcile.Request {
+	if r.PodTemplatePatch.Name == "" || client.ObjectKeyFromObject(obj) != r.PodTemplatePatch {
+		return nil
+	}
+	return r.allAgentDeployments(ctx, obj)
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// reconcilePoolClaims bridges cold starts with warm pool pods. While the
+// AgentDeployment's own pods are not ready, idle pool pods are claimed to cover
+// the shortfall; as its pods become ready the claimed pods are released.
+func (r *AgentDeploymentReconciler) reconcilePoolClaims(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment) error {
+	if ad.Spec.PoolRef == nil {
+		return nil
+	}
+
+	claimed := &corev1.PodList{}
+	if err := r.List(ctx, claimed, client.InNamespace(ad.Namespace),
+		client.MatchingLabels{poolLabel: ad.Spec.PoolRef.Name, claimedByLabel: ad.Name}); err != nil {
+		return err
+	}
+
+	var desired int32
+	if dep.Spec.Replicas != nil {
+		desired = *dep.Spec.Replicas
+	}
+	shortfall := int(desired - dep.Status.ReadyReplicas)
+	if shortfall < 0 {
+		shortfall = 0
+	}
+
+	// Release claims no longer needed; the pool Deployment already backfilled them
+	for i := shortfall; i < len(claimed.Items); i++ {
+		pod := &claimed.Items[i]
+		r.Log.Info("Releasing pool pod", "Pod", pod.Name, "AgentDeployment", ad.Name)
+		if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	if shortfall <= len(claimed.Items) {
+		ad.Status.PoolClaims = int32(shortfall)
+		return nil
+	}
+
+	pool := &agentopsv1alpha1.AgentPool{}
+	if err := r.Get(ctx, types.NamespacedName{Name: ad.Spec.PoolRef.Name, Namespace: ad.Namespace}, pool); err != nil {
+		if errors.IsNotFound(err) {
+			r.Log.Info("Referenced AgentPool not found", "AgentPool", ad.Spec.PoolRef.Name)
+			ad.Status.PoolClaims = int32(len(claimed.Items))
+			return nil
+		}
+		return err
+	}
+	if !poolAllowsModel(pool, ad.Spec.Model) {
+		r.Log.Info("AgentPool does not serve this model", "AgentPool", pool.Name, "Model", ad.Spec.Model)
+		ad.Status.PoolClaims = int32(len(claimed.Items))
+		return nil
+	}
+
+	warm := &corev1.PodList{}
+	if err := r.List(ctx, warm, client.InNamespace(ad.Namespace), client.MatchingLabels(labelsForAgentPool(pool.Name))); err != nil {
+		return err
+	}
+
+	count := len(claimed.Items)
+	for i := range warm.Items {
+		if count >= shortfall {
+			break
+		}
+		pod := &warm.Items[i]
+		if !podReady(pod) {
+			continue
+		}
+		if err := r.claimPoolPod(ctx, ad, pod); err != nil {
+			if errors.IsConflict(err) {
+				// Another AgentDeployment claimed it first
+				continue
+			}
+			return err
+		}
+		r.Log.Info("Claimed pool pod", "Pod", pod.Name, "AgentPool", pool.Name, "AgentDeployment", ad.Name)
+		count++
+	}
+	ad.Status.PoolClaims = int32(count)
+	return nil
+}
+
+// claimPoolPod detaches a warm pod from its pool and assigns it to the AgentDeployment.
+// The update is guarded by the pod's resourceVersion so two claimers cannot race.
+func (r *AgentDeploymentReconciler) claimPoolPod(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, pod *corev1.Pod) error {
+	pod.Labels[poolStateLabel] = poolStateClaimed
+	pod.Labels[claimedByLabel] = ad.Name
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[modelAnnotation] = ad.Spec.Model
+
+	// Hand ownership to the AgentDeployment so claimed pods are garbage collected with it
+	pod.OwnerReferences = nil
+	if err := controllerutil.SetControllerReference(ad, pod, r.Scheme); err != nil {
+		return err
+	}
+	return r.Update(ctx, pod)
+}
+
+// poolAllowsModel reports whether a pool may serve the given model
+func poolAllowsModel(pool *agentopsv1alpha1.AgentPool, model string) bool {
+	if len(pool.Spec.Models) == 0 {
+		return true
+	}
+	for _, m := range pool.Spec.Models {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+// podReady reports whether the pod has a true Ready condition
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	// httpPortName is the main API port; probes, the activator and gateways use it
+	httpPortName = "http"
+
+	// defaultGRPCPort is the conventional gRPC inference port (KServe v2 protocol)
+	defaultGRPCPort = 8081
+)
+
+// appProtocols maps agent port protocols to Service appProtocol values, so that
+// gateways and meshes proxy WebSocket upgrades and HTTP/2 correctly
+var appProtocols = map[string]string{
+	agentopsv1alpha1.AgentPortProtocolHTTP:      "http",
+	agentopsv1alpha1.AgentPortProtocolWebSocket: "kubernetes.io/ws",
+	agentopsv1alpha1.AgentPortProtocolGRPC:      "kubernetes.io/h2c",
+}
+
+// agentPortsForAgentDeployment returns the ports of the agent container with
+// defaults applied. The "http" port always comes first; it defaults to the port of
+// the runtime's health profile, which an "http" entry in spec.ports overrides.
+func agentPortsForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) []agentopsv1alpha1.AgentPort {
+	httpPort := agentopsv1alpha1.AgentPort{
+		Name:          httpPortName,
+		Protocol:      agentopsv1alpha1.AgentPortProtocolHTTP,
+		ContainerPort: agentPortForAgentDeployment(ad),
+	}
+	var extra []agentopsv1alpha1.AgentPort
+	for _, p := range ad.Spec.Ports {
+		if p.Protocol == "" {
+			p.Protocol = agentopsv1alpha1.AgentPortProtocolHTTP
+		}
+		if p.ContainerPort == 0 && p.Protocol == agentopsv1alpha1.AgentPortProtocolGRPC {
+			p.ContainerPort = defaultGRPCPort
+		}
+		if p.Name == httpPortName {
+			// The container port is applied through the health profile, see declaredHTTPPort
+			httpPort.Protocol = p.Protocol
+			continue
+		}
+		extra = append(extra, p)
+	}
+	return append([]agentopsv1alpha1.AgentPort{httpPort}, extra...)
+}
+
+// declaredHTTPPort returns the container port of an "http" entry in spec.ports, or 0
+func declaredHTTPPort(ad *agentopsv1alpha1.AgentDeployment) int32 {
+	for _, p := range ad.Spec.Ports {
+		if p.Name == httpPortName {
+			return p.ContainerPort
+		}
+	}
+	return 0
+}
+
+// containerPortsForAgentDeployment returns the ports of the agent container
+func containerPortsForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) []corev1.ContainerPort {
+	var ports []corev1.ContainerPort
+	for _, p := range agentPortsForAgentDeployment(ad) {
+		ports = append(ports, corev1.ContainerPort{
+			Name:          p.Name,
+			ContainerPort: p.ContainerPort,
+			Protocol:      corev1.ProtocolTCP,
+		})
+	}
+	return ports
+}
+
+// servicePortsForAgentDeployment returns the ports of the agent Service: "http" on
+// agentServicePort, every other port on its container port number
+func servicePortsForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) []corev1.ServicePort {
+	var ports []corev1.ServicePort
+	for _, p := range agentPortsForAgentDeployment(ad) {
+		port := p.ContainerPort
+		if p.Name == httpPortName {
+			port = agentServicePort
+		}
+		appProtocol := appProtocols[p.Protocol]
+		ports = append(ports, corev1.ServicePort{
+			Name:        p.Name,
+			Port:        port,
+			TargetPort:  intstr.FromString(p.Name),
+			Protocol:    corev1.ProtocolTCP,
+			AppProtocol: &appProtocol,
+		})
+	}
+	return ports
+}
+
+// grpcPort returns the first grpc port of the agent, or nil
+func grpcPort(ad *agentopsv1alpha1.AgentDeployment) *agentopsv1alpha1.AgentPort {
+	for _, p := range agentPortsForAgentDeployment(ad) {
+		if p.Protocol == agentopsv1alpha1.AgentPortProtocolGRPC {
+			return &p
+		}
+	}
+	return nil
+}
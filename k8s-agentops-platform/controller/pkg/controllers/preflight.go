@@ -0,0 +1,363 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/providerhealth"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/registry"
+)
+
+// preflightRetryInterval is how soon failed preflight checks run again
+const preflightRetryInterval = 30 * time.Second
+
+// ImagePullChecker checks that an image can be pulled with the credentials of a
+// pull secret, or anonymously with nil credentials
+type ImagePullChecker interface {
+	Pullable(ctx context.Context, image string, creds *registry.Credentials) error
+}
+
+// preflight checks the agent's Deployment before it is created: the Secrets and
+// keys its pods reference exist, its images can be pulled, the cluster has nodes
+// with the GPUs it requests and, with spec.preflight.providerAuth, the provider
+// accepts the agent's key. Each check is recorded in its own condition. When a
+// check fails the rollout is blocked and false is returned; the status is
+// persisted either way, since creating the Deployment ends the reconcile.
+func (r *AgentDeploymentReconciler) preflight(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment) (bool, error) {
+	pod := &dep.Spec.Template.Spec
+	var failed []string
+	record := func(conditionType, reason string, problems []string, message string) {
+		if len(problems) == 0 {
+			conditions.Set(&ad.Status.Conditions, conditionType, metav1.ConditionTrue, conditions.ReasonAsExpected, message, ad.Generation)
+			return
+		}
+		conditions.Set(&ad.Status.Conditions, conditionType, metav1.ConditionFalse, reason, strings.Join(problems, "; "), ad.Generation)
+		failed = append(failed, conditionType)
+	}
+
+	secrets, problems, err := r.podSecrets(ctx, ad.Namespace, pod)
+	if err != nil {
+		return false, err
+	}
+	record(conditions.SecretsFound, conditions.ReasonSecretNotFound, problems, "Referenced Secrets and keys exist")
+
+	if r.Pulls != nil {
+		problems, err := r.unpullableImages(ctx, ad.Namespace, pod, secrets)
+		if err != nil {
+			return false, err
+		}
+		record(conditions.ImagePullable, conditions.ReasonImagePullError, problems, "Images can be pulled")
+	} else {
+		meta.RemoveStatusCondition(&ad.Status.Conditions, conditions.ImagePullable)
+	}
+
+	if name, count := podGPUs(pod); count > 0 {
+		fits, err := r.gpuNodesExist(ctx, name, count)
+		if err != nil {
+			return false, err
+		}
+		var problems []string
+		if !fits {
+			problems = append(problems, fmt.Sprintf("no node has %d allocatable %s", count, name))
+		}
+		record(conditions.GPUsAvailable, conditions.ReasonNoGPUNodes, problems, fmt.Sprintf("Nodes with %d %s exist", count, name))
+	} else {
+		meta.RemoveStatusCondition(&ad.Status.Conditions, conditions.GPUsAvailable)
+	}
+
+	if ad.Spec.Preflight != nil && ad.Spec.Preflight.ProviderAuth && r.Providers != nil && providerProbeEnabled(ad) {
+		reason, problems := r.providerAuthProblems(ctx, ad, secrets)
+		if reason == conditions.ReasonProviderUnreachable {
+			// An unreachable provider does not say the key is wrong; it does not block
+			conditions.Set(&ad.Status.Conditions, conditions.ProviderAuthenticated, metav1.ConditionUnknown, reason,
+				strings.Join(problems, "; "), ad.Generation)
+		} else {
+			record(conditions.ProviderAuthenticated, reason, problems, "Provider accepts the API key and serves the model")
+		}
+	} else {
+		meta.RemoveStatusCondition(&ad.Status.Conditions, conditions.ProviderAuthenticated)
+	}
+
+	if len(failed) == 0 {
+		return true, patchStatus(ctx, r.Client, ad)
+	}
+	message := fmt.Sprintf("Preflight checks failed: %s", strings.Join(failed, ", "))
+	r.event(ad, corev1.EventTypeWarning, conditions.ReasonPreflightFailed, message)
+	return false, r.rolloutRejected(ctx, ad, conditions.ReasonPreflightFailed, message)
+}
+
+// podSecrets reads the Secrets the pod references in its environment, volumes and
+// image pull secrets, and returns them by name with the missing Secrets and keys.
+// References marked optional may be missing.
+func (r *AgentDeploymentReconciler) podSecrets(ctx context.Context, namespace string, pod *corev1.PodSpec) (map[string]*corev1.Secret, []string, error) {
+	secrets := map[string]*corev1.Secret{}
+	missing := map[string]bool{}
+	get := func(name string) (*corev1.Secret, error) {
+		if secret, ok := secrets[name]; ok {
+			return secret, nil
+		}
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret); apierrors.IsNotFound(err) {
+			secret = nil
+		} else if err != nil {
+			return nil, err
+		}
+		secrets[name] = secret
+		return secret, nil
+	}
+	check := func(name, key string, optional *bool) error {
+		if optional != nil && *optional {
+			return nil
+		}
+		secret, err := get(name)
+		if err != nil {
+			return err
+		}
+		switch {
+		case secret == nil:
+			missing[fmt.Sprintf("Secret %s not found", name)] = true
+		case key != "":
+			if _, ok := secret.Data[key]; !ok {
+				missing[fmt.Sprintf("Secret %s has no key %s", name, key)] = true
+			}
+		}
+		return nil
+	}
+
+	containers := append(append([]corev1.Container{}, pod.InitContainers...), pod.Containers...)
+	for _, c := range containers {
+		for _, env := range c.Env {
+			if ref := env.ValueFrom; ref != nil && ref.SecretKeyRef != nil {
+				if err := check(ref.SecretKeyRef.Name, ref.SecretKeyRef.Key, ref.SecretKeyRef.Optional); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		for _, from := range c.EnvFrom {
+			if from.SecretRef != nil {
+				if err := check(from.SecretRef.Name, "", from.SecretRef.Optional); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+	for _, v := range pod.Volumes {
+		var refs []corev1.SecretProjection
+		switch {
+		case v.Secret != nil:
+			refs = append(refs, corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: v.Secret.SecretName},
+				Items:                v.Secret.Items,
+				Optional:             v.Secret.Optional,
+			})
+		case v.Projected != nil:
+			for _, source := range v.Projected.Sources {
+				if source.Secret != nil {
+					refs = append(refs, *source.Secret)
+				}
+			}
+		}
+		for _, ref := range refs {
+			if err := check(ref.Name, "", ref.Optional); err != nil {
+				return nil, nil, err
+			}
+			for _, item := range ref.Items {
+				if err := check(ref.Name, item.Key, ref.Optional); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+	for _, ref := range pod.ImagePullSecrets {
+		if err := check(ref.Name, "", nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	problems := make([]string, 0, len(missing))
+	for problem := range missing {
+		problems = append(problems, problem)
+	}
+	sort.Strings(problems)
+	return secrets, problems, nil
+}
+
+// unpullableImages tries to read the manifest of every image of the pod with the
+// credentials of its pull secrets and those of its ServiceAccount, as the kubelet
+// would pull them, and returns the images that cannot be pulled
+func (r *AgentDeploymentReconciler) unpullableImages(ctx context.Context, namespace string, pod *corev1.PodSpec, secrets map[string]*corev1.Secret) ([]string, error) {
+	pullSecrets := append([]corev1.LocalObjectReference{}, pod.ImagePullSecrets...)
+	account := pod.ServiceAccountName
+	if account == "" {
+		account = "default"
+	}
+	sa := &corev1.ServiceAccount{}
+	if err := r.Get(ctx, types.NamespacedName{Name: account, Namespace: namespace}, sa); err == nil {
+		pullSecrets = append(pullSecrets, sa.ImagePullSecrets...)
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	var configs []*corev1.Secret
+	for _, ref := range pullSecrets {
+		secret, ok := secrets[ref.Name]
+		if !ok {
+			secret = &corev1.Secret{}
+			if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, secret); apierrors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if secret != nil {
+			configs = append(configs, secret)
+		}
+	}
+
+	var problems []string
+	seen := map[string]bool{}
+	containers := append(append([]corev1.Container{}, pod.InitContainers...), pod.Containers...)
+	for _, c := range containers {
+		if seen[c.Image] {
+			continue
+		}
+		seen[c.Image] = true
+		ref, err := registry.ParseReference(c.Image)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if err := r.imagePullable(ctx, c.Image, pullCredentials(configs, ref.Registry)); err != nil {
+			if errors.Is(err, registry.ErrNotFound) {
+				problems = append(problems, fmt.Sprintf("image %s not found", c.Image))
+			} else {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+	return problems, nil
+}
+
+// imagePullable tries the credentials in turn, and anonymous pulls without any
+func (r *AgentDeploymentReconciler) imagePullable(ctx context.Context, image string, creds []*registry.Credentials) error {
+	if len(creds) == 0 {
+		return r.Pulls.Pullable(ctx, image, nil)
+	}
+	var err error
+	for _, c := range creds {
+		if err = r.Pulls.Pullable(ctx, image, c); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// dockerConfigEntry is a registry entry of a Docker config file
+type dockerConfigEntry struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     []byte `json:"auth"`
+}
+
+// pullCredentials returns the credentials the kubernetes.io/dockerconfigjson and
+// kubernetes.io/dockercfg Secrets hold for the registry
+func pullCredentials(secrets []*corev1.Secret, registryHost string) []*registry.Credentials {
+	var creds []*registry.Credentials
+	for _, secret := range secrets {
+		var entries map[string]dockerConfigEntry
+		switch secret.Type {
+		case corev1.SecretTypeDockerConfigJson:
+			var config struct {
+				Auths map[string]dockerConfigEntry `json:"auths"`
+			}
+			if json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config) != nil {
+				continue
+			}
+			entries = config.Auths
+		case corev1.SecretTypeDockercfg:
+			if json.Unmarshal(secret.Data[corev1.DockerConfigKey], &entries) != nil {
+				continue
+			}
+		default:
+			continue
+		}
+		for server, entry := range entries {
+			if dockerConfigHost(server) != registryHost {
+				continue
+			}
+			if entry.Username == "" && len(entry.Auth) > 0 {
+				entry.Username, entry.Password, _ = strings.Cut(string(entry.Auth), ":")
+			}
+			creds = append(creds, &registry.Credentials{Username: entry.Username, Password: entry.Password})
+		}
+	}
+	return creds
+}
+
+// dockerConfigHost returns the registry of a Docker config server key, which may
+// be a URL such as https://index.docker.io/v1/
+func dockerConfigHost(server string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	if host == "docker.io" || host == "registry-1.docker.io" {
+		host = "index.docker.io"
+	}
+	return host
+}
+
+// gpuNodesExist reports whether a schedulable node has count allocatable GPUs of
+// the resource, whether or not they are in use
+func (r *AgentDeploymentReconciler) gpuNodesExist(ctx context.Context, resource corev1.ResourceName, count int64) (bool, error) {
+	nodes := &corev1.NodeList{}
+	if err := r.List(ctx, nodes); err != nil {
+		return false, err
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if allocatable := node.Status.Allocatable[resource]; !node.Spec.Unschedulable && allocatable.Value() >= count {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// providerAuthProblems probes the provider with the agent's API key and returns the
+// reason and problems of a failed check
+func (r *AgentDeploymentReconciler) providerAuthProblems(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, secrets map[string]*corev1.Secret) (string, []string) {
+	target, err := providerProbeTarget(ad)
+	if err != nil {
+		return conditions.ReasonAuthenticationFailed, []string{err.Error()}
+	}
+	ref := ad.Spec.ProviderConfig.CredentialsSecretRef
+	secret := secrets[ref.Name]
+	if secret == nil || len(secret.Data[apiKeySecretKey]) == 0 {
+		return conditions.ReasonAuthenticationFailed, []string{fmt.Sprintf("Secret %s has no key %s", ref.Name, apiKeySecretKey)}
+	}
+	target.APIKey = string(secret.Data[apiKeySecretKey])
+
+	result, err := r.Providers.Probe(ctx, target)
+	switch {
+	case err != nil:
+		return conditions.ReasonProviderUnreachable, []string{err.Error()}
+	case result.Auth == providerhealth.AuthInvalid:
+		return conditions.ReasonAuthenticationFailed, []string{result.Message}
+	case result.ModelAvailable != nil && !*result.ModelAvailable:
+		return conditions.ReasonModelUnavailable, []string{result.Message}
+	case result.Auth == providerhealth.AuthUnknown:
+		return conditions.ReasonProviderUnreachable, []string{result.Message}
+	}
+	return "", nil
+}
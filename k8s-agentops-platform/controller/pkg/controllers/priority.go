@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// priorityTier is a PriorityClass managed by the controller
+type priorityTier struct {
+	value       int32
+	preemption  corev1.PreemptionPolicy
+	description string
+}
+
+// priorityTiers are created on first use. Production agents preempt experimental
+// ones on scarce GPU nodes; batch agents never preempt anything.
+var priorityTiers = map[string]priorityTier{
+	agentopsv1alpha1.PriorityTierCritical: {
+		value:       1000000,
+		preemption:  corev1.PreemptLowerPriority,
+		description: "Production AgentOps agents; preempt standard and batch agents",
+	},
+	agentopsv1alpha1.PriorityTierStandard: {
+		value:       100000,
+		preemption:  corev1.PreemptLowerPriority,
+		description: "Default tier for AgentOps agents; preempts batch agents",
+	},
+	agentopsv1alpha1.PriorityTierBatch: {
+		value:       1000,
+		preemption:  corev1.PreemptNever,
+		description: "Experimental and batch AgentOps agents; never preempt other pods",
+	},
+}
+
+// priorityTierClassName returns the name of the PriorityClass of a tier
+func priorityTierClassName(tier string) string {
+	return "agentops-" + tier
+}
+
+// priorityClassNameForAgentDeployment returns the PriorityClass of the agent pods;
+// an explicit priorityClassName wins over the tier
+func priorityClassNameForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) string {
+	if ad.Spec.PriorityClassName != "" {
+		return ad.Spec.PriorityClassName
+	}
+	if ad.Spec.PriorityTier != "" {
+		return priorityTierClassName(ad.Spec.PriorityTier)
+	}
+	return ""
+}
+
+// reconcilePriorityClass creates the PriorityClass of the agent's tier if it does not
+// exist. Existing classes are left alone: their value is immutable and cluster
+// administrators may have created them with their own preemption settings.
+func (r *AgentDeploymentReconciler) reconcilePriorityClass(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	if ad.Spec.PriorityClassName != "" || ad.Spec.PriorityTier == "" {
+		return nil
+	}
+	tier, ok := priorityTiers[ad.Spec.PriorityTier]
+	if !ok {
+		return nil
+	}
+	pc := &schedulingv1.PriorityClass{}
+	err := r.Get(ctx, client.ObjectKey{Name: priorityTierClassName(ad.Spec.PriorityTier)}, pc)
+	if err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+
+	preemption := tier.preemption
+	pc = &schedulingv1.PriorityClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   priorityTierClassName(ad.Spec.PriorityTier),
+			Labels: map[string]string{"app.kubernetes.io/managed-by": "agentops-controller"},
+		},
+		Value:            tier.value,
+		PreemptionPolicy: &preemption,
+		Description:      tier.description,
+	}
+	if err := r.Create(ctx, pc); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/health"
+)
+
+// healthTargetsForAgentDeployment resolves the runtime profile and spec overrides
+// into the checker and the liveness/readiness targets it should probe.
+func healthTargetsForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) (string, health.Target, health.Target) {
+	spec := ad.Spec.Health
+	if spec == nil {
+		spec = &agentopsv1alpha1.HealthSpec{}
+	}
+
+	// Self-hosted runtimes expose their own health endpoints
+	profileName := spec.Profile
+	if profileName == "" && !modelServerEnabled(ad) {
+		if p, err := providerForAgentDeployment(ad); err == nil {
+			profileName = p.HealthProfile
+		}
+	}
+	profile := health.ProfileFor(profileName)
+	checker := profile.Checker
+	if spec.Checker != "" {
+		checker = spec.Checker
+	}
+	port := profile.Port
+	if declared := declaredHTTPPort(ad); declared != 0 {
+		port = declared
+	}
+	if spec.Port != nil {
+		port = *spec.Port
+	}
+
+	liveness := health.Target{Port: port, Path: profile.LivenessPath, Service: spec.GRPCService, Command: spec.Command}
+	readiness := health.Target{Port: port, Path: profile.ReadinessPath, Service: spec.GRPCService, Command: spec.Command}
+	if spec.LivenessPath != "" {
+		liveness.Path = spec.LivenessPath
+	}
+	if spec.ReadinessPath != "" {
+		readiness.Path = spec.ReadinessPath
+	}
+	return checker, liveness, readiness
+}
+
+// agentPortForAgentDeployment returns the port the agent container serves on
+func agentPortForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) int32 {
+	_, liveness, _ := healthTargetsForAgentDeployment(ad)
+	return liveness.Port
+}
+
+// modelLoadFailureThreshold gives runtimes loading weights 30 minutes (at the 10s
+// period) to come up, like the model server StatefulSet
+const modelLoadFailureThreshold = 180
+
+// loadsModelInPod reports whether the agent pod serves a self-hosted model itself
+// and therefore takes minutes to start
+func loadsModelInPod(ad *agentopsv1alpha1.AgentDeployment) bool {
+	p, err := providerForAgentDeployment(ad)
+	return err == nil && p.SelfHosted && !modelServerEnabled(ad)
+}
+
+// probesForAgentDeployment returns the liveness, readiness and startup probes for the
+// agent container. Disabled probes, and the startup probe unless configured, are nil.
+func probesForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) (*corev1.Probe, *corev1.Probe, *corev1.Probe, error) {
+	name, livenessTarget, readinessTarget := healthTargetsForAgentDeployment(ad)
+	checker, err := health.Lookup(name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	overrides := ad.Spec.Probes
+	if overrides == nil {
+		overrides = &agentopsv1alpha1.ProbesSpec{}
+	}
+
+	liveness, err := buildProbe(checker, livenessTarget, overrides.Liveness, corev1.Probe{
+		InitialDelaySeconds: 30,
+		PeriodSeconds:       10,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	readiness, err := buildProbe(checker, readinessTarget, overrides.Readiness, corev1.Probe{
+		InitialDelaySeconds: 10,
+		PeriodSeconds:       5,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var startup *corev1.Probe
+	if overrides.Startup != nil || loadsModelInPod(ad) {
+		// Allow five minutes to start, or thirty when the pod loads model weights,
+		// unless told otherwise
+		failureThreshold := int32(30)
+		if loadsModelInPod(ad) {
+			failureThreshold = modelLoadFailureThreshold
+		}
+		startup, err = buildProbe(checker, livenessTarget, overrides.Startup, corev1.Probe{
+			PeriodSeconds:    10,
+			FailureThreshold: failureThreshold,
+		})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return liveness, readiness, startup, nil
+}
+
+// buildProbe renders a probe for target from the defaults and the spec override,
+// returning nil when the probe is disabled
+func buildProbe(checker health.Checker, target health.Target, override *agentopsv1alpha1.ProbeSpec, defaults corev1.Probe) (*corev1.Probe, error) {
+	probe := defaults
+	if override != nil {
+		if override.Disabled {
+			return nil, nil
+		}
+		if override.Path != "" {
+			target.Path = override.Path
+		}
+		if override.InitialDelaySeconds != nil {
+			probe.InitialDelaySeconds = *override.InitialDelaySeconds
+		}
+		if override.PeriodSeconds != nil {
+			probe.PeriodSeconds = *override.PeriodSeconds
+		}
+		if override.TimeoutSeconds != nil {
+			probe.TimeoutSeconds = *override.TimeoutSeconds
+		}
+		if override.FailureThreshold != nil {
+			probe.FailureThreshold = *override.FailureThreshold
+		}
+	}
+	handler, err := checker.Handler(target)
+	if err != nil {
+		return nil, err
+	}
+	probe.ProbeHandler = handler
+	return &probe, nil
+}
+
+// probesRemoved reports whether the desired agent container drops a probe the
+// current one still has. The template comparison ignores fields unset in the desired
+// Deployment, so disabling a probe would otherwise never be rolled out.
+func probesRemoved(desired, current *appsv1.Deployment) bool {
+	if len(desired.Spec.Template.Spec.Containers) == 0 || len(current.Spec.Template.Spec.Containers) == 0 {
+		return false
+	}
+	want, have := desired.Spec.Template.Spec.Containers[0], current.Spec.Template.Spec.Containers[0]
+	return (want.LivenessProbe == nil && have.LivenessProbe != nil) ||
+		(want.ReadinessProbe == nil && have.ReadinessProbe != nil) ||
+		(want.StartupProbe == nil && have.StartupProbe != nil)
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	// promptRevisionAnnotation on the pod template rolls the pods when the mounted
+	// prompt revision changes
+	promptRevisionAnnotation = "agentops.io/prompt-revision"
+	promptMountPath          = "/etc/agentops/prompt"
+	promptVolumeName         = "prompt"
+)
+
+// promptConfigMapName returns the name of the ConfigMap holding the mounted prompt
+func promptConfigMapName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-prompt"
+}
+
+// reconcilePromptConfigMap copies the selected PromptTemplate revision into a ConfigMap
+// owned by the AgentDeployment and records the revision in status.
+func (r *AgentDeploymentReconciler) reconcilePromptConfigMap(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	ref := ad.Spec.PromptTemplateRef
+	if ref == nil {
+		ad.Status.PromptRevision = 0
+		return nil
+	}
+
+	rev, err := resolvePromptRevision(ctx, r.Client, ad.Namespace, ref)
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: promptConfigMapName(ad), Namespace: ad.Namespace},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Labels = labelsForAgentDeployment(ad.Name)
+		cm.Data = promptConfigMapData(rev)
+		return controllerutil.SetControllerReference(ad, cm, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	ad.Status.PromptRevision = rev.Revision
+	return nil
+}
+
+// resolvePromptRevision returns the PromptTemplate revision selected by ref
+func resolvePromptRevision(ctx context.Context, c client.Client, namespace string, ref *agentopsv1alpha1.PromptTemplateReference) (*agentopsv1alpha1.PromptRevision, error) {
+	tmpl := &agentopsv1alpha1.PromptTemplate{}
+	if err := c.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, tmpl); err != nil {
+		return nil, fmt.Errorf("get PromptTemplate %s: %w", ref.Name, err)
+	}
+
+	var rev *agentopsv1alpha1.PromptRevision
+	if ref.Revision != nil {
+		rev = tmpl.RevisionByNumber(*ref.Revision)
+	} else {
+		rev = tmpl.Latest()
+	}
+	if rev == nil {
+		return nil, fmt.Errorf("PromptTemplate %s has no revision %v", ref.Name, ref.Revision)
+	}
+	return rev, nil
+}
+
+// promptConfigMapData returns the files mounted for a prompt revision
+func promptConfigMapData(rev *agentopsv1alpha1.PromptRevision) map[string]string {
+	return map[string]string{
+		"system.txt":   rev.SystemPrompt,
+		"template.txt": rev.Template,
+		"revision":     strconv.Itoa(int(rev.Revision)),
+	}
+}
+
+// promptVolumeForAgentDeployment returns the prompt volume and mount, if a template is referenced
+func promptVolumeForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) ([]corev1.Volume, []corev1.VolumeMount) {
+	if ad.Spec.PromptTemplateRef == nil {
+		return nil, nil
+	}
+	volumes := []corev1.Volume{{
+		Name: promptVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: promptConfigMapName(ad)},
+			},
+		},
+	}}
+	mounts := []corev1.VolumeMount{{
+		Name:      promptVolumeName,
+		MountPath: promptMountPath,
+		ReadOnly:  true,
+	}}
+	return volumes, mounts
+}
+
+// agentDeploymentsForPromptTemplate maps a PromptTemplate event to the AgentDeployments referencing it
+func (r *AgentDeploymentReconciler) agentDeploymentsForPromptTemplate(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &agentopsv1alpha1.AgentDeploymentList{}
+	if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list AgentDeployments for PromptTemplate", "PromptTemplate", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, ad := range list.Items {
+		if ad.Spec.PromptTemplateRef != nil && ad.Spec.PromptTemplateRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: ad.Name, Namespace: ad.Namespace},
+			})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+// PromptTemplateReconciler reconciles a PromptTemplate object
+type PromptTemplateReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=prompttemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=agentops.io,resources=prompttemplates/status,verbs=get;update;patch
+
+// Reconcile validates the template revisions and publishes the latest revision
+func (r *PromptTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("prompttemplate", req.NamespacedName)
+
+	tmpl := &agentopsv1alpha1.PromptTemplate{}
+	if err := r.Get(ctx, req.NamespacedName, tmpl); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get PromptTemplate")
+		return ctrl.Result{}, err
+	}
+
+	seen := map[int32]bool{}
+	var duplicate int32
+	for _, rev := range tmpl.Spec.Revisions {
+		if seen[rev.Revision] {
+			duplicate = rev.Revision
+		}
+		seen[rev.Revision] = true
+	}
+
+	if duplicate != 0 {
+		conditions.Set(&tmpl.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonInvalidSpec,
+			fmt.Sprintf("revision %d is defined more than once", duplicate), tmpl.Generation)
+	} else {
+		conditions.Set(&tmpl.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonAsExpected,
+			fmt.Sprintf("%d revisions", len(tmpl.Spec.Revisions)), tmpl.Generation)
+	}
+	if latest := tmpl.Latest(); latest != nil {
+		tmpl.Status.LatestRevision = latest.Revision
+	}
+	tmpl.Status.ObservedGeneration = tmpl.Generation
+
+	return ctrl.Result{}, patchStatus(ctx, r.Client, tmpl)
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *PromptTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.PromptTemplate{}).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("PromptTemplate", tracing.Reconciler("PromptTemplate", r)))
+}
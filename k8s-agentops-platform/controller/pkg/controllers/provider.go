@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/catalog"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/providers"
+)
+
+const (
+	providerCredentialsVolume = "provider-credentials"
+	providerCredentialsDir    = "/var/run/secrets/agentops/provider"
+
+	// Keys of the provider credentials Secret
+	apiKeySecretKey             = "api-key"
+	awsAccessKeyIDSecretKey     = "aws-access-key-id"
+	awsSecretAccessKeySecretKey = "aws-secret-access-key"
+	gcpCredentialsSecretKey     = "credentials.json"
+	hfTokenSecretKey            = "hf-token"
+)
+
+// agentRuntime is the provider-specific part of the agent container
+type agentRuntime struct {
+	Image     string
+	Args      []string
+	Env       []corev1.EnvVar
+	Volumes   []corev1.Volume
+	Mounts    []corev1.VolumeMount
+	Lifecycle *corev1.Lifecycle
+}
+
+// providerForAgentDeployment returns the provider serving the agent, defaulting to
+// the usual provider of the model
+func providerForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) (providers.Provider, error) {
+	name := ad.Spec.Provider
+	if name == "" {
+		model, _ := catalog.Lookup(ad.Spec.Model)
+		name = model.DefaultProvider
+	}
+	if name == "" {
+		name = providers.Anthropic
+	}
+	return providers.Lookup(name)
+}
+
+// runtimeForAgentDeployment renders the image, arguments, environment and credential
+// mounts of the agent container for its provider. Self-hosted runtimes keep their
+// model files under cacheDir.
+func runtimeForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment, cacheDir string) (agentRuntime, error) {
+	p, err := providerForAgentDeployment(ad)
+	if err != nil {
+		return agentRuntime{}, err
+	}
+	cfg := ad.Spec.ProviderConfig
+	if cfg == nil {
+		cfg = &agentopsv1alpha1.ProviderConfig{}
+	}
+	model, _ := catalog.Lookup(ad.Spec.Model)
+	modelID := catalog.ProviderModelID(ad.Spec.Model, p.Name)
+
+	rt := agentRuntime{Image: fmt.Sprintf("%s:%s", defaultImage, ad.Spec.Model)}
+	if ad.Spec.Image != "" {
+		rt.Image = ad.Spec.Image
+	}
+	if modelServerEnabled(ad) {
+		// The agent image calls the separately served runtime over its OpenAI-compatible API
+		if p, err = modelServerProvider(ad); err != nil {
+			return agentRuntime{}, err
+		}
+		rt.Env = []corev1.EnvVar{
+			{Name: "AGENTOPS_PROVIDER", Value: p.Name},
+			{Name: "AGENTOPS_MODEL_ID", Value: modelServerModelID(ad, p)},
+			{Name: "OPENAI_BASE_URL", Value: modelServerURL(ad, p)},
+		}
+		if adaptersEnabled(ad) {
+			rt.Env = append(rt.Env, corev1.EnvVar{Name: adapterHeaderEnv, Value: adapterHeader})
+		}
+		return rt, nil
+	}
+	if p.SelfHosted {
+		if cfg.SelfHosted != nil && cfg.SelfHosted.ModelID != "" {
+			modelID = cfg.SelfHosted.ModelID
+		} else if model.SelfHosted {
+			modelID = quantizedModelID(ad, modelID)
+		} else {
+			return agentRuntime{}, fmt.Errorf("model %s has no open weights for provider %s; set providerConfig.selfHosted.modelId", ad.Spec.Model, p.Name)
+		}
+		rt.Image = p.Image
+		if cfg.SelfHosted != nil && cfg.SelfHosted.Image != "" {
+			rt.Image = cfg.SelfHosted.Image
+		}
+	} else if model.SelfHosted && p.Name != providers.Bedrock {
+		return agentRuntime{}, fmt.Errorf("model %s is not available from provider %s", ad.Spec.Model, p.Name)
+	}
+
+	rt.Env = append(rt.Env,
+		corev1.EnvVar{Name: "AGENTOPS_PROVIDER", Value: p.Name},
+		corev1.EnvVar{Name: "AGENTOPS_MODEL_ID", Value: modelID},
+	)
+	if cfg.CredentialsSecretRef != nil && p.APIKeyEnv != "" {
+		rt.Env = append(rt.Env, credentialEnv(p.APIKeyEnv, cfg.CredentialsSecretRef.Name, apiKeySecretKey, false))
+	}
+
+	port := strconv.Itoa(int(agentPortForAgentDeployment(ad)))
+	switch p.Name {
+	case providers.Anthropic:
+		if cfg.Anthropic != nil && cfg.Anthropic.Endpoint != "" {
+			rt.Env = append(rt.Env, corev1.EnvVar{Name: "ANTHROPIC_BASE_URL", Value: cfg.Anthropic.Endpoint})
+		}
+	case providers.OpenAI:
+		if cfg.OpenAI != nil {
+			rt.Env = appendNonEmpty(rt.Env, "OPENAI_BASE_URL", cfg.OpenAI.Endpoint)
+			rt.Env = appendNonEmpty(rt.Env, "OPENAI_ORG_ID", cfg.OpenAI.Organization)
+		}
+	case providers.AzureOpenAI:
+		if cfg.AzureOpenAI == nil {
+			return agentRuntime{}, fmt.Errorf("provider %s requires providerConfig.azureOpenAI", p.Name)
+		}
+		rt.Env[1].Value = cfg.AzureOpenAI.DeploymentName
+		rt.Env = append(rt.Env,
+			corev1.EnvVar{Name: "AZURE_OPENAI_ENDPOINT", Value: cfg.AzureOpenAI.Endpoint},
+			corev1.EnvVar{Name: "AZURE_OPENAI_DEPLOYMENT", Value: cfg.AzureOpenAI.DeploymentName},
+		)
+		rt.Env = appendNonEmpty(rt.Env, "OPENAI_API_VERSION", cfg.AzureOpenAI.APIVersion)
+	case providers.Bedrock:
+		if cfg.Bedrock == nil {
+			return agentRuntime{}, fmt.Errorf("provider %s requires providerConfig.bedrock", p.Name)
+		}
+		if cfg.Bedrock.ModelID != "" {
+			rt.Env[1].Value = cfg.Bedrock.ModelID
+		}
+		rt.Env = append(rt.Env, corev1.EnvVar{Name: "AWS_REGION", Value: cfg.Bedrock.Region})
+		// Without a Secret the pod relies on IRSA or EKS Pod Identity
+		if cfg.CredentialsSecretRef != nil {
+			rt.Env = append(rt.Env,
+				credentialEnv("AWS_ACCESS_KEY_ID", cfg.CredentialsSecretRef.Name, awsAccessKeyIDSecretKey, false),
+				credentialEnv("AWS_SECRET_ACCESS_KEY", cfg.CredentialsSecretRef.Name, awsSecretAccessKeySecretKey, false),
+			)
+		}
+	case providers.Vertex:
+		if cfg.Vertex == nil {
+			return agentRuntime{}, fmt.Errorf("provider %s requires providerConfig.vertex", p.Name)
+		}
+		rt.Env = append(rt.Env,
+			corev1.EnvVar{Name: "GOOGLE_CLOUD_PROJECT", Value: cfg.Vertex.Project},
+			corev1.EnvVar{Name: "GOOGLE_CLOUD_REGION", Value: cfg.Vertex.Region},
+		)
+		// Without a Secret the pod relies on GKE Workload Identity
+		if cfg.CredentialsSecretRef != nil {
+			rt.Volumes = append(rt.Volumes, corev1.Volume{
+				Name: providerCredentialsVolume,
+				VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+					SecretName: cfg.CredentialsSecretRef.Name,
+					Items:      []corev1.KeyToPath{{Key: gcpCredentialsSecretKey, Path: gcpCredentialsSecretKey}},
+				}},
+			})
+			rt.Mounts = append(rt.Mounts, corev1.VolumeMount{Name: providerCredentialsVolume, MountPath: providerCredentialsDir, ReadOnly: true})
+			rt.Env = append(rt.Env, corev1.EnvVar{
+				Name:  "GOOGLE_APPLICATION_CREDENTIALS",
+				Value: path.Join(providerCredentialsDir, gcpCredentialsSecretKey),
+			})
+		}
+	case providers.VLLM:
+		rt.Args = []string{"--model", modelID, "--port", port}
+		rt.Env = append(rt.Env, corev1.EnvVar{Name: "HF_HOME", Value: path.Join(cacheDir, "huggingface")})
+		if cfg.CredentialsSecretRef != nil {
+			rt.Env = append(rt.Env, credentialEnv("HF_TOKEN", cfg.CredentialsSecretRef.Name, hfTokenSecretKey, true))
+		}
+	case providers.TGI:
+		rt.Args = []string{"--model-id", modelID, "--port", port}
+		rt.Env = append(rt.Env, corev1.EnvVar{Name: "HUGGINGFACE_HUB_CACHE", Value: path.Join(cacheDir, "huggingface")})
+		if cfg.CredentialsSecretRef != nil {
+			rt.Env = append(rt.Env, credentialEnv("HUGGING_FACE_HUB_TOKEN", cfg.CredentialsSecretRef.Name, hfTokenSecretKey, true))
+		}
+	case providers.Ollama:
+		rt.Env = append(rt.Env,
+			corev1.EnvVar{Name: "OLLAMA_HOST", Value: "0.0.0.0:" + port},
+			corev1.EnvVar{Name: "OLLAMA_MODELS", Value: path.Join(cacheDir, "ollama")},
+		)
+		// Ollama serves only pulled models; pulling in postStart keeps the container
+		// from starting its probes until the model is available
+		rt.Lifecycle = &corev1.Lifecycle{PostStart: &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{Command: []string{"sh", "-c",
+				`until ollama list >/dev/null 2>&1; do sleep 1; done; exec ollama pull "$0"`, modelID}},
+		}}
+	}
+
+	serving, err := modelArgs(ad, p, cfg.SelfHosted != nil && cfg.SelfHosted.ModelID != "")
+	if err != nil {
+		return agentRuntime{}, err
+	}
+	batching, err := batchingArgs(ad, p)
+	if err != nil {
+		return agentRuntime{}, err
+	}
+	parallel, err := parallelArgs(ad, p)
+	if err != nil {
+		return agentRuntime{}, err
+	}
+	rt.Args = append(rt.Args, serving...)
+	rt.Args = append(rt.Args, batching...)
+	rt.Args = append(rt.Args, parallel...)
+	if p.SelfHosted && cfg.SelfHosted != nil {
+		rt.Args = append(rt.Args, cfg.SelfHosted.Args...)
+	}
+	return rt, nil
+}
+
+// credentialEnv exposes a key of the provider credentials Secret as a variable
+func credentialEnv(name, secret, key string, optional bool) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secret},
+				Key:                  key,
+				Optional:             &optional,
+			},
+		},
+	}
+}
+
+// envPrefix returns the prefix of the environment variables describing a named
+// object to an agent, e.g. TOOL_K8S_READER_ for kind TOOL and name k8s-reader
+func envPrefix(kind, name string) string {
+	return kind + "_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name)) + "_"
+}
+
+// appendNonEmpty appends the variable when value is set
+func appendNonEmpty(env []corev1.EnvVar, name, value string) []corev1.EnvVar {
+	if value == "" {
+		return env
+	}
+	return append(env, corev1.EnvVar{Name: name, Value: value})
+}
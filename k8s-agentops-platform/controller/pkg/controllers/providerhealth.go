@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/catalog"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/providerhealth"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/providers"
+)
+
+// providerProbeInterval is how often status.provider is probed again
+const providerProbeInterval = providerhealth.DefaultTTL
+
+// ProviderProber checks the key, model and rate limit headroom of a hosted provider
+type ProviderProber interface {
+	Probe(ctx context.Context, target providerhealth.Target) (providerhealth.Result, error)
+}
+
+// providerProbeEnabled reports whether the agent calls a hosted provider API the
+// prober knows, with a key from providerConfig.credentialsSecretRef
+func providerProbeEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	p, err := providerForAgentDeployment(ad)
+	if err != nil || modelServerEnabled(ad) || !providerhealth.Supported(p.Name) {
+		return false
+	}
+	cfg := ad.Spec.ProviderConfig
+	return cfg != nil && cfg.CredentialsSecretRef != nil
+}
+
+// providerProbeTarget returns the provider model the agent calls, without its key
+func providerProbeTarget(ad *agentopsv1alpha1.AgentDeployment) (providerhealth.Target, error) {
+	p, err := providerForAgentDeployment(ad)
+	if err != nil {
+		return providerhealth.Target{}, err
+	}
+	cfg := ad.Spec.ProviderConfig
+	target := providerhealth.Target{Provider: p.Name, Model: catalog.ProviderModelID(ad.Spec.Model, p.Name)}
+	switch p.Name {
+	case providers.Anthropic:
+		if cfg.Anthropic != nil {
+			target.Endpoint = cfg.Anthropic.Endpoint
+		}
+	case providers.OpenAI:
+		if cfg.OpenAI != nil {
+			target.Endpoint = cfg.OpenAI.Endpoint
+		}
+	case providers.AzureOpenAI:
+		if cfg.AzureOpenAI == nil {
+			return target, fmt.Errorf("provider %s requires providerConfig.azureOpenAI", p.Name)
+		}
+		target.Endpoint = cfg.AzureOpenAI.Endpoint
+		target.Model = cfg.AzureOpenAI.DeploymentName
+		target.APIVersion = cfg.AzureOpenAI.APIVersion
+	}
+	return target, nil
+}
+
+// reconcileProviderStatus refreshes status.provider once per providerProbeInterval,
+// or as soon as the provider or model changes. The prober shares its results among
+// agents with the same provider, model and key. A provider that cannot be reached
+// leaves the previous status in place.
+func (r *AgentDeploymentReconciler) reconcileProviderStatus(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) {
+	if r.Providers == nil || !providerProbeEnabled(ad) {
+		ad.Status.Provider = nil
+		return
+	}
+	target, err := providerProbeTarget(ad)
+	if err != nil {
+		ad.Status.Provider = nil
+		return
+	}
+	if s := ad.Status.Provider; s != nil && s.Name == target.Provider && s.Model == target.Model &&
+		s.LastProbeTime != nil && time.Since(s.LastProbeTime.Time) < providerProbeInterval {
+		return
+	}
+
+	ref := ad.Spec.ProviderConfig.CredentialsSecretRef
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ad.Namespace}, secret); err != nil && !errors.IsNotFound(err) {
+		r.Log.Error(err, "Failed to read provider credentials", "AgentDeployment", ad.Name, "Namespace", ad.Namespace)
+		return
+	}
+	now := metav1.Now()
+	key, ok := secret.Data[apiKeySecretKey]
+	if !ok {
+		ad.Status.Provider = &agentopsv1alpha1.ProviderStatus{
+			Name:          target.Provider,
+			Model:         target.Model,
+			Auth:          providerhealth.AuthUnknown,
+			Message:       fmt.Sprintf("Secret %s has no key %s", ref.Name, apiKeySecretKey),
+			LastProbeTime: &now,
+		}
+		return
+	}
+	target.APIKey = string(key)
+
+	result, err := r.Providers.Probe(ctx, target)
+	if err != nil {
+		r.Log.Error(err, "Failed to probe provider", "AgentDeployment", ad.Name, "Namespace", ad.Namespace, "Provider", target.Provider)
+		return
+	}
+	probed := metav1.NewTime(result.Time)
+	ad.Status.Provider = &agentopsv1alpha1.ProviderStatus{
+		Name:              target.Provider,
+		Model:             target.Model,
+		Auth:              result.Auth,
+		ModelAvailable:    result.ModelAvailable,
+		RequestsLimit:     result.RequestsLimit,
+		RequestsRemaining: result.RequestsRemaining,
+		TokensLimit:       result.TokensLimit,
+		TokensRemaining:   result.TokensRemaining,
+		Message:           result.Message,
+		LastProbeTime:     &probed,
+	}
+}
@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	queueProxyImage        = "ghcr.io/myorg/agentops-queue:latest"
+	defaultNATSImage       = "nats:2.10-alpine"
+	natsPort               = 4222
+	natsMonitorPort        = 8222
+	queueContainerName     = "queue"
+	queueListenPort        = 18090
+	queueMetricsPort       = 9464
+	defaultQueueRetries    = int32(5)
+	defaultQueueBackoff    = 2 * time.Second
+	defaultQueueMaxBackoff = 5 * time.Minute
+
+	// queueDepthMetric is exported by the queue proxy, labelled with the namespace
+	// and agent, and read by the HPA through an external metrics adapter
+	queueDepthMetric = "agentops_queue_depth"
+)
+
+// queueEnabled reports whether the agent's requests go through a queue
+func queueEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.Queue != nil && ad.Spec.Queue.Enabled
+}
+
+// queueBackend returns the broker of the agent's queue
+func queueBackend(ad *agentopsv1alpha1.AgentDeployment) string {
+	if ad.Spec.Queue.Backend == "" {
+		return agentopsv1alpha1.QueueBackendRedis
+	}
+	return ad.Spec.Queue.Backend
+}
+
+// queueBrokerName returns the name of the broker StatefulSet and Service
+func queueBrokerName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-queue"
+}
+
+// queueStream returns the stream the agent's requests are queued in; failed
+// requests go to the stream of the same name with a .dead suffix
+func queueStream(ad *agentopsv1alpha1.AgentDeployment) string {
+	return fmt.Sprintf("agentops.%s.%s", ad.Namespace, ad.Name)
+}
+
+// upstreamPort returns the port the gateway sidecar forwards to: the response cache
+// proxy's or the queue proxy's when the agent has them, otherwise the agent's
+func upstreamPort(ad *agentopsv1alpha1.AgentDeployment) int32 {
+	if responseCacheEnabled(ad) {
+		return responseCacheListenPort
+	}
+	if queueEnabled(ad) {
+		return queueListenPort
+	}
+	return agentPortForAgentDeployment(ad)
+}
+
+// reconcileQueue deploys the broker of spec.queue, or removes it when the agent no
+// longer has a queue, and records whether it is up
+func (r *AgentDeploymentReconciler) reconcileQueue(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	name := queueBrokerName(ad)
+	if !queueEnabled(ad) {
+		meta.RemoveStatusCondition(&ad.Status.Conditions, conditions.QueueReady)
+		return r.deleteStoreStatefulSet(ctx, ad, name)
+	}
+
+	spec := ad.Spec.Queue
+	podSpec := func(persistent bool) corev1.PodSpec { return redisPodSpec(spec.Image, persistent) }
+	ports := []corev1.ServicePort{{Name: "redis", Port: redisPort, TargetPort: intstr.FromString("redis"), Protocol: corev1.ProtocolTCP}}
+	if queueBackend(ad) == agentopsv1alpha1.QueueBackendNATS {
+		podSpec = func(persistent bool) corev1.PodSpec { return natsPodSpec(spec.Image, persistent) }
+		ports = []corev1.ServicePort{{Name: "nats", Port: natsPort, TargetPort: intstr.FromString("nats"), Protocol: corev1.ProtocolTCP}}
+	}
+	sts, err := r.reconcileStoreStatefulSet(ctx, ad, name, labelsForQueueBroker(ad.Name), spec.Storage, spec.StorageClassName, podSpec, ports)
+	if err != nil {
+		return err
+	}
+
+	if sts.Status.ReadyReplicas > 0 {
+		conditions.Set(&ad.Status.Conditions, conditions.QueueReady, metav1.ConditionTrue, conditions.ReasonAsExpected,
+			fmt.Sprintf("%s queue broker is ready", queueBackend(ad)), ad.Generation)
+	} else {
+		conditions.Set(&ad.Status.Conditions, conditions.QueueReady, metav1.ConditionFalse, conditions.ReasonQueueUnavailable,
+			fmt.Sprintf("Waiting for the %s queue broker to become ready", queueBackend(ad)), ad.Generation)
+	}
+	return nil
+}
+
+// natsPodSpec builds the pod of a deployed NATS broker with JetStream. Without a
+// volume it keeps its streams in memory only.
+func natsPodSpec(image string, persistent bool) corev1.PodSpec {
+	if image == "" {
+		image = defaultNATSImage
+	}
+	var volumes []corev1.Volume
+	if !persistent {
+		volumes = []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+	}
+	return corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name:  "nats",
+			Image: image,
+			Args:  []string{"--jetstream", "--store_dir", "/data", "--http_port", strconv.Itoa(natsMonitorPort)},
+			Ports: []corev1.ContainerPort{
+				{Name: "nats", ContainerPort: natsPort},
+				{Name: "monitor", ContainerPort: natsMonitorPort},
+			},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				},
+			},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					HTTPGet: &corev1.HTTPGetAction{Path: "/healthz?js-enabled-only=true", Port: intstr.FromString("monitor")},
+				},
+				PeriodSeconds: 10,
+			},
+			VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+		}},
+		Volumes: volumes,
+	}
+}
+
+// queueOverlay runs the queue proxy in front of the agent container. It takes over
+// the "http" port the Service targets, unless the gateway sidecar or the response
+// cache proxy already did and forward to it.
+func queueOverlay(ad *agentopsv1alpha1.AgentDeployment, behindProxy bool) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		if !queueEnabled(ad) {
+			return
+		}
+		spec := &dep.Spec.Template.Spec
+		agent := &spec.Containers[0]
+		portName := "queue"
+		if !behindProxy {
+			portName = "http"
+			for i := range agent.Ports {
+				if agent.Ports[i].Name == "http" {
+					agent.Ports[i].Name = "agent"
+				}
+			}
+		}
+
+		q := ad.Spec.Queue
+		retries := defaultQueueRetries
+		if q.MaxRetries != nil {
+			retries = *q.MaxRetries
+		}
+		backoff, maxBackoff := defaultQueueBackoff, defaultQueueMaxBackoff
+		if q.Backoff != nil {
+			backoff = q.Backoff.Duration
+		}
+		if q.MaxBackoff != nil {
+			maxBackoff = q.MaxBackoff.Duration
+		}
+		brokerPort, scheme := int32(redisPort), "redis"
+		if queueBackend(ad) == agentopsv1alpha1.QueueBackendNATS {
+			brokerPort, scheme = natsPort, "nats"
+		}
+		stream := queueStream(ad)
+
+		spec.Containers = append(spec.Containers, corev1.Container{
+			Name:  queueContainerName,
+			Image: queueProxyImage,
+			Env: []corev1.EnvVar{
+				{Name: "QUEUE_BACKEND", Value: queueBackend(ad)},
+				{Name: "QUEUE_URL", Value: fmt.Sprintf("%s://%s:%d", scheme, serviceHost(queueBrokerName(ad), ad.Namespace), brokerPort)},
+				{Name: "QUEUE_STREAM", Value: stream},
+				{Name: "QUEUE_DEAD_LETTER_STREAM", Value: stream + ".dead"},
+				{Name: "QUEUE_LISTEN_PORT", Value: strconv.Itoa(queueListenPort)},
+				{Name: "QUEUE_UPSTREAM", Value: fmt.Sprintf("http://127.0.0.1:%d", agentPortForAgentDeployment(ad))},
+				{Name: "QUEUE_MAX_RETRIES", Value: strconv.Itoa(int(retries))},
+				{Name: "QUEUE_BACKOFF", Value: backoff.String()},
+				{Name: "QUEUE_MAX_BACKOFF", Value: maxBackoff.String()},
+				{Name: "QUEUE_METRICS_PORT", Value: strconv.Itoa(queueMetricsPort)},
+				{Name: "QUEUE_METRIC_LABELS", Value: fmt.Sprintf("namespace=%s,agent=%s", ad.Namespace, ad.Name)},
+			},
+			Ports: []corev1.ContainerPort{
+				{Name: portName, ContainerPort: queueListenPort},
+				{Name: "queue-metrics", ContainerPort: queueMetricsPort},
+			},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					HTTPGet: &corev1.HTTPGetAction{Path: "/ready", Port: intstr.FromInt(queueMetricsPort)},
+				},
+				PeriodSeconds: 5,
+			},
+		})
+	}
+}
+
+// queueDepthMetricSpec returns the HPA metric scaling the agent on the depth of its
+// queue, or nil when spec.queue.targetDepth is not set
+func queueDepthMetricSpec(ad *agentopsv1alpha1.AgentDeployment) *autoscalingv2.MetricSpec {
+	if !queueEnabled(ad) || ad.Spec.Queue.TargetDepth == nil {
+		return nil
+	}
+	target := resource.NewQuantity(int64(*ad.Spec.Queue.TargetDepth), resource.DecimalSI)
+	return &autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ExternalMetricSourceType,
+		External: &autoscalingv2.ExternalMetricSource{
+			Metric: autoscalingv2.MetricIdentifier{
+				Name: queueDepthMetric,
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{
+					"namespace": ad.Namespace,
+					"agent":     ad.Name,
+				}},
+			},
+			Target: autoscalingv2.MetricTarget{
+				Type:         autoscalingv2.AverageValueMetricType,
+				AverageValue: target,
+			},
+		},
+	}
+}
+
+// labelsForQueueBroker returns the labels of the deployed queue broker pods
+func labelsForQueueBroker(name string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "queue-broker",
+		"app.kubernetes.io/instance":   name,
+		"app.kubernetes.io/managed-by": "agentops-controller",
+	}
+}
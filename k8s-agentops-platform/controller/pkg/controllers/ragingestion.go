@@ -0,0 +1,332 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	// ragRevisionLabel marks an ingestion Job with the revision of the source it embeds
+	ragRevisionLabel = "agentops.io/rag-revision"
+	// ragSourceLabel marks an ingestion Job with the name of its source
+	ragSourceLabel = "agentops.io/rag-source"
+
+	documentsDir        = "/documents"
+	documentsVolumeName = "documents"
+)
+
+// sourceChunking returns the chunk size and overlap of a source
+func sourceChunking(rp *agentopsv1alpha1.RAGPipeline, src *agentopsv1alpha1.DocumentSource) (int32, int32) {
+	size, overlap := int32(512), int32(64)
+	c := rp.Spec.Chunking
+	if src.Chunking != nil {
+		c = src.Chunking
+	}
+	if c != nil {
+		if c.Size > 0 {
+			size = c.Size
+		}
+		overlap = c.Overlap
+	}
+	return size, overlap
+}
+
+// sourceDeduplication returns the deduplication mode of the pipeline
+func sourceDeduplication(rp *agentopsv1alpha1.RAGPipeline) string {
+	if in := rp.Spec.Ingestion; in != nil && in.Deduplication != "" {
+		return in.Deduplication
+	}
+	return agentopsv1alpha1.DeduplicationContent
+}
+
+// sourceRevision hashes what the ingested vectors of a source depend on: where its
+// documents come from, their chunking, the embedding model and the store they are
+// written to. The schedule and credentials are left out, so changing them does not
+// ingest the source again.
+func sourceRevision(rp *agentopsv1alpha1.RAGPipeline, src *agentopsv1alpha1.DocumentSource) (string, error) {
+	size, overlap := sourceChunking(rp, src)
+	raw, err := json.Marshal([]interface{}{src.URI, src.Connector, size, overlap, sourceDeduplication(rp),
+		rp.Spec.Embedder.Model, rp.Spec.VectorStore})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:5]), nil
+}
+
+// ragRevision combines the revisions of all sources of the pipeline
+func ragRevision(rp *agentopsv1alpha1.RAGPipeline) (string, error) {
+	revisions := make([]string, 0, len(rp.Spec.Sources))
+	for i := range rp.Spec.Sources {
+		rev, err := sourceRevision(rp, &rp.Spec.Sources[i])
+		if err != nil {
+			return "", err
+		}
+		revisions = append(revisions, rp.Spec.Sources[i].Name+"="+rev)
+	}
+	sort.Strings(revisions)
+	sum := sha256.Sum256([]byte(fmt.Sprint(revisions)))
+	return hex.EncodeToString(sum[:5]), nil
+}
+
+// sourceSchedule returns the re-ingestion schedule of a source, or nil when it is
+// only ingested on changes
+func sourceSchedule(rp *agentopsv1alpha1.RAGPipeline, src *agentopsv1alpha1.DocumentSource) (cron.Schedule, error) {
+	in := rp.Spec.Ingestion
+	if in == nil || in.Suspend {
+		return nil, nil
+	}
+	expr := src.Schedule
+	if expr == "" {
+		expr = in.Schedule
+	}
+	if expr == "" {
+		return nil, nil
+	}
+	return parseCron(expr, in.TimeZone)
+}
+
+// reconcileSources ingests every source whose revision changed or whose scheduled
+// re-ingestion is due, one Job per source, and records their state. New Jobs only
+// start when canStart is set. It returns the sources that still wait for their
+// current revision, those whose ingestion of it failed, and when the next
+// scheduled ingestion is due.
+func (r *RAGPipelineReconciler) reconcileSources(ctx context.Context, rp *agentopsv1alpha1.RAGPipeline, canStart bool) ([]string, []string, time.Duration, error) {
+	previous := map[string]agentopsv1alpha1.SourceStatus{}
+	for _, st := range rp.Status.Sources {
+		previous[st.Name] = st
+	}
+
+	now := time.Now()
+	var pending, failed []string
+	var next time.Time
+	statuses := make([]agentopsv1alpha1.SourceStatus, 0, len(rp.Spec.Sources))
+	keep := map[string]bool{}
+	for i := range rp.Spec.Sources {
+		src := &rp.Spec.Sources[i]
+		st := previous[src.Name]
+		st.Name = src.Name
+		rev, err := r.reconcileSource(ctx, rp, src, &st, canStart, now)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		statuses = append(statuses, st)
+		keep[st.LastJob] = true
+
+		if st.Revision != rev {
+			if st.Phase == agentopsv1alpha1.SourceFailed {
+				failed = append(failed, src.Name)
+			} else {
+				pending = append(pending, src.Name)
+			}
+		}
+		if st.NextScheduleTime != nil && (next.IsZero() || st.NextScheduleTime.Time.Before(next)) {
+			next = st.NextScheduleTime.Time
+		}
+	}
+	rp.Status.Sources = statuses
+	for _, st := range statuses {
+		if st.LastIngestionTime != nil && (rp.Status.LastIngestionTime == nil || rp.Status.LastIngestionTime.Before(st.LastIngestionTime)) {
+			rp.Status.LastIngestionTime = st.LastIngestionTime
+		}
+	}
+
+	if err := r.pruneIngestJobs(ctx, rp, keep); err != nil {
+		return nil, nil, 0, err
+	}
+	var after time.Duration
+	if !next.IsZero() {
+		after = time.Until(next)
+	}
+	return pending, failed, after, nil
+}
+
+// reconcileSource records the state of the latest ingestion Job of a source in st
+// and starts a new one when the source changed or its schedule is due. A Job still
+// running is never replaced, and a failed ingestion of a revision is only retried
+// on schedule. It returns the current revision of the source.
+func (r *RAGPipelineReconciler) reconcileSource(ctx context.Context, rp *agentopsv1alpha1.RAGPipeline, src *agentopsv1alpha1.DocumentSource, st *agentopsv1alpha1.SourceStatus, canStart bool, now time.Time) (string, error) {
+	rev, err := sourceRevision(rp, src)
+	if err != nil {
+		return "", err
+	}
+
+	var job *batchv1.Job
+	if st.LastJob != "" {
+		job = &batchv1.Job{}
+		err := r.Get(ctx, client.ObjectKey{Name: st.LastJob, Namespace: rp.Namespace}, job)
+		if errors.IsNotFound(err) {
+			job = nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+	active := false
+	if job != nil {
+		switch {
+		case jobHasCondition(job, batchv1.JobComplete):
+			st.Phase = agentopsv1alpha1.SourceIngested
+			st.Revision = job.Labels[ragRevisionLabel]
+			st.LastIngestionTime = job.Status.CompletionTime
+			st.Message = ""
+		case jobHasCondition(job, batchv1.JobFailed):
+			st.Phase = agentopsv1alpha1.SourceFailed
+			st.Message = jobFailureMessage(job)
+		default:
+			st.Phase = agentopsv1alpha1.SourceIngesting
+			active = true
+		}
+	}
+
+	var due time.Time
+	st.NextScheduleTime = nil
+	sched, err := sourceSchedule(rp, src)
+	if err != nil {
+		st.Message = err.Error()
+	} else if sched != nil {
+		earliest := rp.CreationTimestamp.Time
+		if st.LastScheduleTime != nil {
+			earliest = st.LastScheduleTime.Time
+		}
+		due = latestRun(sched, earliest, now)
+		st.NextScheduleTime = &metav1.Time{Time: sched.Next(now)}
+	}
+
+	changed := st.Revision != rev && (job == nil || job.Labels[ragRevisionLabel] != rev)
+	if active || !canStart || (!changed && due.IsZero()) {
+		if st.Phase == "" {
+			st.Phase = agentopsv1alpha1.SourcePending
+		}
+		return rev, nil
+	}
+
+	job, err = r.ingestJob(rp, src, rev, due)
+	if err != nil {
+		return "", err
+	}
+	r.Log.Info("Creating ingestion Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name, "Source", src.Name)
+	if err := r.Create(ctx, job); err != nil && !errors.IsAlreadyExists(err) {
+		return "", err
+	}
+	st.LastJob = job.Name
+	st.Phase = agentopsv1alpha1.SourceIngesting
+	st.Message = ""
+	if !due.IsZero() {
+		st.LastScheduleTime = &metav1.Time{Time: due}
+	}
+	return rev, nil
+}
+
+// pruneIngestJobs deletes the ingestion Jobs that are no longer the latest of a
+// source of the pipeline
+func (r *RAGPipelineReconciler) pruneIngestJobs(ctx context.Context, rp *agentopsv1alpha1.RAGPipeline, keep map[string]bool) error {
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(rp.Namespace), client.MatchingLabels(labelsForRAGPipeline(rp.Name, "ingest"))); err != nil {
+		return err
+	}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if keep[job.Name] || !metav1.IsControlledBy(job, rp) {
+			continue
+		}
+		r.Log.Info("Deleting superseded ingestion Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ingestJob returns the Job chunking and embedding a source into the vector store.
+// The name is derived from the revision and the scheduled time, so a retried
+// reconcile cannot start it twice.
+func (r *RAGPipelineReconciler) ingestJob(rp *agentopsv1alpha1.RAGPipeline, src *agentopsv1alpha1.DocumentSource, revision string, due time.Time) (*batchv1.Job, error) {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d", src.Name, revision, due.Unix())))
+	name := fmt.Sprintf("%s-ingest-%s", rp.Name, hex.EncodeToString(sum[:5]))
+
+	size, overlap := sourceChunking(rp, src)
+	uri := src.URI
+	if src.Connector != nil {
+		uri = "file://" + documentsDir
+	}
+	env := append(ragEnv(rp),
+		corev1.EnvVar{Name: "SOURCE_NAME", Value: src.Name},
+		corev1.EnvVar{Name: "SOURCE_URI", Value: uri},
+		corev1.EnvVar{Name: "SOURCE_REVISION", Value: revision},
+		corev1.EnvVar{Name: "CHUNK_SIZE", Value: strconv.Itoa(int(size))},
+		corev1.EnvVar{Name: "CHUNK_OVERLAP", Value: strconv.Itoa(int(overlap))},
+		corev1.EnvVar{Name: "DEDUPLICATION", Value: sourceDeduplication(rp)},
+	)
+	var envFrom []corev1.EnvFromSource
+	if src.CredentialsSecretRef != nil {
+		envFrom = []corev1.EnvFromSource{{
+			SecretRef: &corev1.SecretEnvSource{LocalObjectReference: *src.CredentialsSecretRef},
+		}}
+	}
+
+	pod := corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+		Containers: []corev1.Container{{
+			Name:    "ingest",
+			Image:   ragComponentImage(rp),
+			Args:    []string{"ingest"},
+			Env:     env,
+			EnvFrom: envFrom,
+		}},
+	}
+	// A connector writes the documents into a scratch volume the ingestion reads
+	if c := src.Connector; c != nil {
+		mount := corev1.VolumeMount{Name: documentsVolumeName, MountPath: documentsDir}
+		pod.Volumes = []corev1.Volume{{
+			Name:         documentsVolumeName,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}}
+		pod.InitContainers = []corev1.Container{{
+			Name:         "connector",
+			Image:        c.Image,
+			Args:         c.Args,
+			Env:          append([]corev1.EnvVar{{Name: "DOCUMENTS_DIR", Value: documentsDir}}, c.Env...),
+			EnvFrom:      envFrom,
+			VolumeMounts: []corev1.VolumeMount{mount},
+		}}
+		mount.ReadOnly = true
+		pod.Containers[0].VolumeMounts = []corev1.VolumeMount{mount}
+	}
+
+	labels := labelsForRAGPipeline(rp.Name, "ingest")
+	jobLabels := map[string]string{ragRevisionLabel: revision, ragSourceLabel: src.Name}
+	for k, v := range labels {
+		jobLabels[k] = v
+	}
+	backoff := ragIngestRetry
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: rp.Namespace, Labels: jobLabels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoff,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       pod,
+			},
+		},
+	}
+	if !due.IsZero() {
+		job.Annotations = map[string]string{scheduledAtAnnotation: due.UTC().Format(time.RFC3339)}
+	}
+	return job, controllerutil.SetControllerReference(rp, job, r.Scheme)
+}
@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	embedderImage  = "ghcr.io/huggingface/text-embeddings-inference:cpu-1.2"
+	ragImage       = "ghcr.io/myorg/agentops-rag:latest"
+	ragPort        = int32(8080)
+	ragIngestRetry = int32(3)
+)
+
+// RAGPipelineReconciler reconciles a RAGPipeline object
+type RAGPipelineReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=ragpipelines,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=ragpipelines/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile runs the embedder and retriever of the pipeline and ingests its sources
+// into the vector store once the embedder is up
+func (r *RAGPipelineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("ragpipeline", req.NamespacedName)
+
+	rp := &agentopsv1alpha1.RAGPipeline{}
+	if err := r.Get(ctx, req.NamespacedName, rp); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get RAGPipeline")
+		return ctrl.Result{}, err
+	}
+
+	after, err := r.reconcilePipeline(ctx, rp)
+	if err != nil {
+		log.Error(err, "Failed to reconcile RAG pipeline")
+		return ctrl.Result{}, err
+	}
+	rp.Status.ObservedGeneration = rp.Generation
+	return ctrl.Result{RequeueAfter: after}, patchStatus(ctx, r.Client, rp)
+}
+
+// reconcilePipeline brings up the pipeline components, ingests the sources and
+// records its phase. It returns when the next scheduled ingestion is due.
+func (r *RAGPipelineReconciler) reconcilePipeline(ctx context.Context, rp *agentopsv1alpha1.RAGPipeline) (time.Duration, error) {
+	gen := rp.Generation
+	store := rp.Spec.VectorStore
+	if store.URL == "" && store.CredentialsSecretRef == nil {
+		rp.Status.Phase = agentopsv1alpha1.RAGPipelineFailed
+		conditions.Set(&rp.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonInvalidSpec,
+			"vectorStore needs a url or a credentialsSecretRef with a url key", gen)
+		return 0, nil
+	}
+
+	embedder := rp.Spec.Embedder
+	embedderReady, err := r.reconcileComponent(ctx, rp, "embedder", embedder.Replicas, embedderPodSpec(rp))
+	if err != nil {
+		return 0, err
+	}
+	retrieverReady, err := r.reconcileComponent(ctx, rp, "retriever", rp.Spec.Retriever.Replicas, retrieverPodSpec(rp))
+	if err != nil {
+		return 0, err
+	}
+	rp.Status.EmbedderReadyReplicas = embedderReady
+	rp.Status.RetrieverReadyReplicas = retrieverReady
+	rp.Status.Endpoint = ragEndpoint(rp.Namespace, rp.Name)
+
+	// The ingestion embeds through the embedder, so it waits for it
+	pending, failed, after, err := r.reconcileSources(ctx, rp, embedderReady > 0)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case len(failed) > 0:
+		rp.Status.Phase = agentopsv1alpha1.RAGPipelineFailed
+		conditions.Set(&rp.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonJobFailed,
+			fmt.Sprintf("Ingestion of %s failed, see status.sources", strings.Join(failed, ", ")), gen)
+		return after, nil
+	case len(pending) > 0 && embedderReady == 0:
+		rp.Status.Phase = agentopsv1alpha1.RAGPipelinePending
+		conditions.Set(&rp.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonReplicasUnavailable,
+			"Waiting for the embedder before ingesting the sources", gen)
+		return after, nil
+	case len(pending) > 0:
+		rp.Status.Phase = agentopsv1alpha1.RAGPipelineIngesting
+		conditions.Set(&rp.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonJobRunning,
+			fmt.Sprintf("Ingesting %s", strings.Join(pending, ", ")), gen)
+		return after, nil
+	}
+
+	revision, err := ragRevision(rp)
+	if err != nil {
+		return 0, err
+	}
+	rp.Status.IngestedRevision = revision
+	if retrieverReady == 0 {
+		rp.Status.Phase = agentopsv1alpha1.RAGPipelinePending
+		conditions.Set(&rp.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonReplicasUnavailable,
+			"Waiting for the retriever", gen)
+		return after, nil
+	}
+	rp.Status.Phase = agentopsv1alpha1.RAGPipelineReady
+	conditions.Set(&rp.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonAsExpected,
+		fmt.Sprintf("Retrieval is served at %s", rp.Status.Endpoint), gen)
+	return after, nil
+}
+
+// reconcileComponent creates or updates the Deployment and Service of the embedder
+// or the retriever and returns its ready replicas
+func (r *RAGPipelineReconciler) reconcileComponent(ctx context.Context, rp *agentopsv1alpha1.RAGPipeline, component string, replicas *int32, pod corev1.PodSpec) (int32, error) {
+	name := rp.Name + "-" + component
+	labels := labelsForRAGPipeline(rp.Name, component)
+
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: rp.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, dep, func() error {
+		count := int32(1)
+		if replicas != nil {
+			count = *replicas
+		}
+		dep.Labels = labels
+		dep.Spec.Replicas = &count
+		// The selector is immutable after creation
+		if dep.CreationTimestamp.IsZero() {
+			dep.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+		}
+		dep.Spec.Template.Labels = labels
+		dep.Spec.Template.Spec = pod
+		return controllerutil.SetControllerReference(rp, dep, r.Scheme)
+	}); err != nil {
+		return 0, err
+	}
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: rp.Namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		svc.Labels = labels
+		svc.Spec.Selector = labels
+		svc.Spec.Ports = []corev1.ServicePort{{
+			Name:       "http",
+			Port:       ragPort,
+			TargetPort: intstr.FromString("http"),
+			Protocol:   corev1.ProtocolTCP,
+		}}
+		return controllerutil.SetControllerReference(rp, svc, r.Scheme)
+	})
+	return dep.Status.ReadyReplicas, err
+}
+
+// embedderPodSpec returns the pod serving the embedding model
+func embedderPodSpec(rp *agentopsv1alpha1.RAGPipeline) corev1.PodSpec {
+	spec := rp.Spec.Embedder
+	image := embedderImage
+	if spec.Image != "" {
+		image = spec.Image
+	}
+	var env []corev1.EnvVar
+	if spec.TokenSecretRef != nil {
+		env = append(env, corev1.EnvVar{Name: "HF_TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: spec.TokenSecretRef}})
+	}
+	return corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name:      "embedder",
+			Image:     image,
+			Args:      []string{"--model-id", spec.Model, "--port", strconv.Itoa(int(ragPort))},
+			Env:       env,
+			Ports:     []corev1.ContainerPort{{Name: "http", ContainerPort: ragPort}},
+			Resources: spec.Resources,
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					HTTPGet: &corev1.HTTPGetAction{Path: "/health", Port: intstr.FromString("http")},
+				},
+				PeriodSeconds: 10,
+			},
+			// Downloading the model takes a while on first start
+			StartupProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					HTTPGet: &corev1.HTTPGetAction{Path: "/health", Port: intstr.FromString("http")},
+				},
+				PeriodSeconds:    10,
+				FailureThreshold: modelLoadFailureThreshold,
+			},
+		}},
+	}
+}
+
+// retrieverPodSpec returns the pod answering retrieval queries
+func retrieverPodSpec(rp *agentopsv1alpha1.RAGPipeline) corev1.PodSpec {
+	topK := rp.Spec.Retriever.TopK
+	if topK == 0 {
+		topK = 5
+	}
+	env := append(ragEnv(rp),
+		corev1.EnvVar{Name: "PORT", Value: strconv.Itoa(int(ragPort))},
+		corev1.EnvVar{Name: "TOP_K", Value: strconv.Itoa(int(topK))},
+	)
+	return corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name:  "retriever",
+			Image: ragComponentImage(rp),
+			Args:  []string{"serve"},
+			Env:   env,
+			Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: ragPort}},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromString("http")},
+				},
+				PeriodSeconds: 10,
+			},
+		}},
+	}
+}
+
+// ragEnv returns the vector store and embedder settings shared by the retriever
+// and the ingestion
+func ragEnv(rp *agentopsv1alpha1.RAGPipeline) []corev1.EnvVar {
+	store := rp.Spec.VectorStore
+	collection := store.Collection
+	if collection == "" {
+		collection = rp.Name
+	}
+	env := []corev1.EnvVar{
+		{Name: "VECTOR_STORE_TYPE", Value: store.Type},
+		{Name: "VECTOR_STORE_COLLECTION", Value: collection},
+		{Name: "EMBEDDER_URL", Value: fmt.Sprintf("http://%s-embedder.%s.svc.cluster.local:%d", rp.Name, rp.Namespace, ragPort)},
+		{Name: "EMBEDDING_MODEL", Value: rp.Spec.Embedder.Model},
+	}
+	if store.URL != "" {
+		env = append(env, corev1.EnvVar{Name: "VECTOR_STORE_URL", Value: store.URL})
+	}
+	if ref := store.CredentialsSecretRef; ref != nil {
+		if store.URL == "" {
+			env = append(env, credentialEnv("VECTOR_STORE_URL", ref.Name, "url", false))
+		}
+		env = append(env, credentialEnv("VECTOR_STORE_API_KEY", ref.Name, apiKeySecretKey, true))
+	}
+	return env
+}
+
+// ragComponentImage returns the image running the retriever and the ingestion
+func ragComponentImage(rp *agentopsv1alpha1.RAGPipeline) string {
+	if rp.Spec.Retriever.Image != "" {
+		return rp.Spec.Retriever.Image
+	}
+	return ragImage
+}
+
+// ragEnvForAgentDeployment returns the retrieval endpoint of spec.ragRef. The
+// endpoint only depends on the pipeline name, so agents start before the pipeline
+// finished ingesting and retrieve whatever it holds.
+func ragEnvForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) []corev1.EnvVar {
+	if ad.Spec.RAGRef == nil {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{Name: "RAG_PIPELINE", Value: ad.Spec.RAGRef.Name},
+		{Name: "RAG_ENDPOINT", Value: ragEndpoint(ad.Namespace, ad.Spec.RAGRef.Name)},
+	}
+}
+
+// ragEndpoint returns the retrieval URL of a pipeline
+func ragEndpoint(namespace, name string) string {
+	return fmt.Sprintf("http://%s-retriever.%s.svc.cluster.local:%d", name, namespace, ragPort)
+}
+
+// labelsForRAGPipeline returns the labels of a component of the pipeline
+func labelsForRAGPipeline(name, component string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "rag-pipeline",
+		"app.kubernetes.io/instance":   name,
+		"app.kubernetes.io/component":  component,
+		"app.kubernetes.io/managed-by": "agentops-controller",
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *RAGPipelineReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.RAGPipeline{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Service{}).
+		Owns(&batchv1.Job{}).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("RAGPipeline", tracing.Reconciler("RAGPipeline", r)))
+}
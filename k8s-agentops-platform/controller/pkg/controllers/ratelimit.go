@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/gateway"
+)
+
+const (
+	// rateLimitConfigAnnotation on the pod template rolls the pods when the sidecar
+	// configuration changes
+	rateLimitConfigAnnotation = "agentops.io/ratelimit-config-hash"
+	rateLimitContainerName    = "ratelimit"
+	rateLimitVolumeName       = "ratelimit-config"
+	rateLimitListenPort       = 18080
+	tokensPerMinuteEnv        = "AGENTOPS_TOKENS_PER_MINUTE"
+)
+
+// agentRateLimit is the merged effect of the RateLimitPolicies selecting an agent
+type agentRateLimit struct {
+	gateway.RateLimit
+
+	// TokensPerMinute is enforced by the agent runtime; zero means unlimited
+	TokensPerMinute int32
+}
+
+// rateLimitForAgentDeployment merges the RateLimitPolicies selecting the agent, keeping
+// the lowest limit when several policies set the same one. It returns nil when no
+// policy applies.
+func (r *AgentDeploymentReconciler) rateLimitForAgentDeployment(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (*agentRateLimit, error) {
+	list := &agentopsv1alpha1.RateLimitPolicyList{}
+	if err := r.List(ctx, list, client.InNamespace(ad.Namespace)); err != nil {
+		return nil, err
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Name < list.Items[j].Name })
+
+	var rl *agentRateLimit
+	for _, policy := range list.Items {
+		if !selectsAgentDeployment(policy.Spec.Selector, ad) {
+			continue
+		}
+		if rl == nil {
+			rl = &agentRateLimit{}
+		}
+		if v := policy.Spec.RequestsPerMinute; v != nil {
+			rl.RequestsPerMinute = minLimit(rl.RequestsPerMinute, *v)
+		}
+		if v := policy.Spec.TokensPerMinute; v != nil {
+			rl.TokensPerMinute = minLimit(rl.TokensPerMinute, *v)
+		}
+		if pc := policy.Spec.PerClient; pc != nil {
+			if rl.ClientHeader == "" {
+				rl.ClientHeader = pc.Header
+			}
+			if rl.Clients == nil {
+				rl.Clients = map[string]int32{}
+			}
+			for _, c := range pc.Clients {
+				rl.Clients[c.Name] = minLimit(rl.Clients[c.Name], c.RequestsPerMinute)
+			}
+		}
+	}
+	return rl, nil
+}
+
+// minLimit returns the stricter of two limits, where zero means unset
+func minLimit(current, limit int32) int32 {
+	if current == 0 || limit < current {
+		return limit
+	}
+	return current
+}
+
+// rateLimitConfigMapName returns the name of the ConfigMap holding the sidecar configuration
+func rateLimitConfigMapName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-ratelimit"
+}
+
+// needsRateLimitProxy reports whether any request limit requires the proxy sidecar
+func needsRateLimitProxy(rl *agentRateLimit) bool {
+	return rl != nil && (rl.RequestsPerMinute > 0 || len(rl.Clients) > 0)
+}
+
+// needsGatewaySidecar reports whether the agent pods run the gateway sidecar, which
+// enforces request limits and the traffic policy and verifies the tokens of calling
+// peers
+func needsGatewaySidecar(ad *agentopsv1alpha1.AgentDeployment, rl *agentRateLimit) bool {
+	return needsRateLimitProxy(rl) || peerTokenAuth(ad) || trafficPolicyEnabled(ad)
+}
+
+// reconcileRateLimitConfigMap renders the sidecar Envoy configuration and returns
+// its hash, or deletes the ConfigMap when the agent needs no sidecar
+func (r *AgentDeploymentReconciler) reconcileRateLimitConfigMap(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, rl *agentRateLimit) (string, error) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: rateLimitConfigMapName(ad), Namespace: ad.Namespace},
+	}
+	if !needsGatewaySidecar(ad, rl) {
+		if err := r.Delete(ctx, cm); err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+		return "", nil
+	}
+
+	backend := gateway.Backend{
+		Service:   ad.Name,
+		Namespace: ad.Namespace,
+		Host:      "127.0.0.1",
+		Port:      upstreamPort(ad),
+	}
+	route := gateway.Route{
+		Name:  ad.Name,
+		Rules: []gateway.Rule{{Backends: []gateway.Backend{backend}}},
+	}
+	if trafficPolicyEnabled(ad) {
+		route.Rules = trafficRules(ad, backend)
+	}
+	cfg := gateway.Config{ListenPort: rateLimitListenPort}
+	if peerTokenAuth(ad) {
+		// Only the gateway may tell the agent which peer called
+		cfg.HTTPFilters = append(cfg.HTTPFilters, gateway.PeerAuthFilter(peerAuthForAgentDeployment(ad)))
+		cfg.RemoveRequestHeaders = []string{peerIdentityHeader}
+	}
+	if needsRateLimitProxy(rl) {
+		route.RateLimits = gateway.RateLimitActions(rl.RateLimit)
+		cfg.HTTPFilters = append(cfg.HTTPFilters, gateway.LocalRateLimitFilter(rl.RateLimit))
+	}
+	cfg.Routes = []gateway.Route{route}
+	config, err := gateway.RenderEnvoyBootstrap(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Labels = labelsForAgentDeployment(ad.Name)
+		cm.Data = map[string]string{gatewayConfigKey: config}
+		return controllerutil.SetControllerReference(ad, cm, r.Scheme)
+	}); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(config))
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// rateLimitOverlay injects the rate limit proxy sidecar in front of the agent
+// container and passes the token limit to the runtime. The sidecar takes over the
+// "http" port the Service targets; the agent port is renamed. Agents with a traffic
+// policy run it too, as do agents accepting peer tokens, with the key the tokens are
+// verified with.
+func rateLimitOverlay(ad *agentopsv1alpha1.AgentDeployment, rl *agentRateLimit, configHash string) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		spec := &dep.Spec.Template.Spec
+		agent := &spec.Containers[0]
+		if rl != nil && rl.TokensPerMinute > 0 {
+			agent.Env = append(agent.Env, corev1.EnvVar{
+				Name:  tokensPerMinuteEnv,
+				Value: strconv.Itoa(int(rl.TokensPerMinute)),
+			})
+		}
+		if !needsGatewaySidecar(ad, rl) {
+			return
+		}
+
+		for i := range agent.Ports {
+			if agent.Ports[i].Name == "http" {
+				agent.Ports[i].Name = "agent"
+			}
+		}
+		sidecar := corev1.Container{
+			Name:  rateLimitContainerName,
+			Image: gatewayImage,
+			Args:  []string{"-c", "/etc/envoy/" + gatewayConfigKey},
+			Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: rateLimitListenPort}},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					HTTPGet: &corev1.HTTPGetAction{Path: "/ready", Port: intstr.FromInt(gateway.AdminPort)},
+				},
+				PeriodSeconds: 5,
+			},
+			VolumeMounts: []corev1.VolumeMount{{Name: rateLimitVolumeName, MountPath: "/etc/envoy", ReadOnly: true}},
+		}
+		if peerTokenAuth(ad) {
+			sidecar.VolumeMounts = append(sidecar.VolumeMounts, corev1.VolumeMount{Name: peerKeyVolumeName, MountPath: peerKeyMountPath, ReadOnly: true})
+			spec.Volumes = append(spec.Volumes, corev1.Volume{
+				Name: peerKeyVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: peerKeySecretName(ad),
+						Items:      []corev1.KeyToPath{{Key: peerJWKSKey, Path: peerJWKSKey}},
+					},
+				},
+			})
+		}
+		spec.Containers = append(spec.Containers, sidecar)
+		spec.Volumes = append(spec.Volumes, corev1.Volume{
+			Name: rateLimitVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: rateLimitConfigMapName(ad)},
+				},
+			},
+		})
+		if dep.Spec.Template.Annotations == nil {
+			dep.Spec.Template.Annotations = map[string]string{}
+		}
+		dep.Spec.Template.Annotations[rateLimitConfigAnnotation] = configHash
+	}
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+// RateLimitPolicyReconciler reconciles a RateLimitPolicy object. The limits are
+// applied by the AgentDeployment controller; this controller reports which agents
+// a policy covers.
+type RateLimitPolicyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=ratelimitpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=ratelimitpolicies/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments,verbs=get;list;watch
+
+// Reconcile records the AgentDeployments selected by the policy
+func (r *RateLimitPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("ratelimitpolicy", req.NamespacedName)
+
+	policy := &agentopsv1alpha1.RateLimitPolicy{}
+	if err := r.Get(ctx, req.NamespacedName, policy); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get RateLimitPolicy")
+		return ctrl.Result{}, err
+	}
+
+	if policy.Spec.Selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(policy.Spec.Selector); err != nil {
+			conditions.Set(&policy.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonInvalidSpec,
+				fmt.Sprintf("Invalid selector: %v", err), policy.Generation)
+			policy.Status.ObservedGeneration = policy.Generation
+			return ctrl.Result{}, patchStatus(ctx, r.Client, policy)
+		}
+	}
+
+	list := &agentopsv1alpha1.AgentDeploymentList{}
+	if err := r.List(ctx, list, client.InNamespace(policy.Namespace)); err != nil {
+		log.Error(err, "Failed to list AgentDeployments")
+		return ctrl.Result{}, err
+	}
+	var names []string
+	for i := range list.Items {
+		if selectsAgentDeployment(policy.Spec.Selector, &list.Items[i]) {
+			names = append(names, list.Items[i].Name)
+		}
+	}
+	sort.Strings(names)
+
+	policy.Status.AgentDeployments = names
+	conditions.Set(&policy.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonAsExpected,
+		fmt.Sprintf("Applied to %d AgentDeployments", len(names)), policy.Generation)
+	policy.Status.ObservedGeneration = policy.Generation
+	return ctrl.Result{}, patchStatus(ctx, r.Client, policy)
+}
+
+// policiesForAgentDeployment maps an AgentDeployment to the policies in its namespace
+func (r *RateLimitPolicyReconciler) policiesForAgentDeployment(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &agentopsv1alpha1.RateLimitPolicyList{}
+	if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list RateLimitPolicies", "AgentDeployment", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, policy := range list.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: policy.Name, Namespace: policy.Namespace},
+		})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *RateLimitPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.RateLimitPolicy{}).
+		Watches(&agentopsv1alpha1.AgentDeployment{}, handler.EnqueueRequestsFromMapFunc(r.policiesForAgentDeployment)).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("RateLimitPolicy", tracing.Reconciler("RateLimitPolicy", r)))
+}
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"context"
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/usage"
+)
+
+const (
+	// recommendationWindow is the usage history recommendations are sized from; a
+	// week covers weekday and weekend traffic
+	recommendationWindow = 7 * 24 * time.Hour
+
+	// recommendationInterval is how often recommendations are recomputed
+	recommendationInterval = time.Hour
+
+	// Headroom added to observed usage: requests cover the 95th percentile, limits
+	// the peak
+	cpuRequestHeadroomPercent    = 15
+	cpuLimitHeadroomPercent      = 50
+	memoryRequestHeadroomPercent = 20
+	memoryLimitHeadroomPercent   = 30
+
+	// minCPUMillis keeps idle agents schedulable with a non-zero CPU request
+	minCPUMillis = 10
+)
+
+// ResourceUsageSource reports the resource usage of a container across the pods of
+// a Deployment over a window
+type ResourceUsageSource interface {
+	ContainerUsage(ctx context.Context, namespace, deployment, container string, window time.Duration) (*usage.ContainerUsage, error)
+}
+
+// reconcileRecommendations refreshes status.recommendations from the agent
+// container's usage once per recommendationInterval. Usage that cannot be read
+// leaves the previous recommendations in place.
+func (r *AgentDeploymentReconciler) reconcileRecommendations(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) {
+	if r.Usage == nil {
+		return
+	}
+	if rec := ad.Status.Recommendations; rec != nil && time.Since(rec.LastUpdateTime.Time) < recommendationInterval {
+		return
+	}
+
+	u, err := r.Usage.ContainerUsage(ctx, ad.Namespace, ad.Name, "agent", recommendationWindow)
+	if err != nil {
+		r.Log.Error(err, "Failed to read agent resource usage", "AgentDeployment", ad.Name, "Namespace", ad.Namespace)
+		return
+	}
+	if u == nil {
+		return
+	}
+	resources := resourcesForAgentDeployment(ad)
+	_, requestsGPU := resources.Requests[defaultGPUResource]
+	_, limitsGPU := resources.Limits[defaultGPUResource]
+	ad.Status.Recommendations = recommendationsFromUsage(u, requestsGPU || limitsGPU)
+}
+
+// recommendationsFromUsage sizes the agent container from its usage. GPUs are only
+// recommended for agents that already request them.
+func recommendationsFromUsage(u *usage.ContainerUsage, gpu bool) *agentopsv1alpha1.ResourceRecommendations {
+	cpuRequest := cpuQuantity(u.CPUP95, cpuRequestHeadroomPercent)
+	cpuLimit := cpuQuantity(u.CPUPeak, cpuLimitHeadroomPercent)
+	if cpuLimit.Cmp(cpuRequest) < 0 {
+		cpuLimit = cpuRequest
+	}
+	memoryRequest := memoryQuantity(u.MemoryP95, memoryRequestHeadroomPercent)
+	memoryLimit := memoryQuantity(u.MemoryPeak, memoryLimitHeadroomPercent)
+	if memoryLimit.Cmp(memoryRequest) < 0 {
+		memoryLimit = memoryRequest
+	}
+
+	rec := &agentopsv1alpha1.ResourceRecommendations{
+		Requests:       corev1.ResourceList{corev1.ResourceCPU: cpuRequest, corev1.ResourceMemory: memoryRequest},
+		Limits:         corev1.ResourceList{corev1.ResourceCPU: cpuLimit, corev1.ResourceMemory: memoryLimit},
+		Window:         &metav1.Duration{Duration: recommendationWindow},
+		LastUpdateTime: metav1.Now(),
+	}
+	if gpu && u.GPUPeak > 0 {
+		gpus := *resource.NewQuantity(int64(math.Max(1, math.Ceil(u.GPUPeak))), resource.DecimalSI)
+		rec.Requests[defaultGPUResource] = gpus
+		rec.Limits[defaultGPUResource] = gpus
+	}
+	return rec
+}
+
+// cpuQuantity returns cores plus headroom, rounded up to 10 millicores
+func cpuQuantity(cores float64, headroomPercent float64) resource.Quantity {
+	millis := int64(math.Ceil(cores*(100+headroomPercent)/100*1000/minCPUMillis)) * minCPUMillis
+	if millis < minCPUMillis {
+		millis = minCPUMillis
+	}
+	return *resource.NewMilliQuantity(millis, resource.DecimalSI)
+}
+
+// memoryQuantity returns bytes plus headroom, rounded up to a mebibyte
+func memoryQuantity(bytes float64, headroomPercent float64) resource.Quantity {
+	mebibytes := int64(math.Ceil(bytes * (100 + headroomPercent) / 100 / (1 << 20)))
+	if mebibytes < 1 {
+		mebibytes = 1
+	}
+	return *resource.NewQuantity(mebibytes<<20, resource.BinarySI)
+}
+
+// autoRightSizeEnabled reports whether recommendations are applied on rollouts
+func autoRightSizeEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.AutoRightSize && ad.Status.Recommendations != nil
+}
+
+// rightSizedResources are the agent container resources auto right-sizing manages
+var rightSizedResources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, defaultGPUResource}
+
+// agentContainer returns the agent container of a pod, or nil
+func agentContainer(pod *corev1.PodSpec) *corev1.Container {
+	for i := range pod.Containers {
+		if pod.Containers[i].Name == "agent" {
+			return &pod.Containers[i]
+		}
+	}
+	return nil
+}
+
+// keepLiveResources copies the right-sized resources of the live agent container,
+// so that right-sizing applied by an earlier rollout is not seen as drift
+func keepLiveResources(pod, live *corev1.PodSpec) {
+	container, liveContainer := agentContainer(pod), agentContainer(live)
+	if container == nil || liveContainer == nil {
+		return
+	}
+	for _, name := range rightSizedResources {
+		copyResource(&container.Resources.Requests, liveContainer.Resources.Requests, name)
+		copyResource(&container.Resources.Limits, liveContainer.Resources.Limits, name)
+	}
+}
+
+// copyResource sets name in dst to its value in src, or removes it if src has none
+func copyResource(dst *corev1.ResourceList, src corev1.ResourceList, name corev1.ResourceName) {
+	value, ok := src[name]
+	if !ok {
+		delete(*dst, name)
+		return
+	}
+	if *dst == nil {
+		*dst = corev1.ResourceList{}
+	}
+	(*dst)[name] = value
+}
+
+// applyRecommendations sets the recommended requests on the agent container. Limits
+// are only replaced where the container has one, so agents without a CPU limit do
+// not get one.
+func applyRecommendations(pod *corev1.PodSpec, rec *agentopsv1alpha1.ResourceRecommendations) {
+	container := agentContainer(pod)
+	if container == nil {
+		return
+	}
+	if container.Resources.Requests == nil {
+		container.Resources.Requests = corev1.ResourceList{}
+	}
+	for name, value := range rec.Requests {
+		container.Resources.Requests[name] = value
+	}
+	for name, value := range rec.Limits {
+		if _, ok := container.Resources.Limits[name]; ok {
+			container.Resources.Limits[name] = value
+		}
+	}
+}
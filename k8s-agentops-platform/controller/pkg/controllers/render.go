@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// ChildChange is a write the controller would make to a child object of an
+// AgentDeployment. Live is nil for objects that would be created and Desired is nil
+// for objects that would be deleted.
+type ChildChange struct {
+	Live    client.Object
+	Desired client.Object
+}
+
+// Render returns the changes Reconcile would make to the Deployment (or Rollout),
+// Service, HorizontalPodAutoscaler and Ingresses of ad, without making them. Writes are sent
+// to the API server as dry runs, so Desired carries the defaults and admission
+// changes a real write would get. AgentOpsConfig defaults and image verification are
+// applied; overlays driven by runtime state (idleness, token budgets, rate limit
+// policies) and hooks are not.
+func (r *AgentDeploymentReconciler) Render(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) ([]ChildChange, error) {
+	recorder := &changeRecorder{Client: client.NewDryRunClient(r.Client), live: r.Client}
+	dryRun := *r
+	dryRun.Client = recorder
+	dryRun.Hooks = nil
+
+	config, err := r.agentOpsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ad = ad.DeepCopy()
+	applyConfigDefaults(ad, config)
+	if err := providerAllowed(ad, config); err != nil {
+		return nil, err
+	}
+	if err := dryRun.reconcileService(ctx, ad); err != nil {
+		return nil, err
+	}
+	if err := dryRun.reconcileIngress(ctx, ad); err != nil {
+		return nil, err
+	}
+	managedAutoscaler, err := dryRun.managedAutoscaler(ctx, ad)
+	if err != nil {
+		return nil, err
+	}
+	templatePatch, err := r.podTemplatePatch(ctx, ad)
+	if err != nil {
+		return nil, err
+	}
+	image, err := r.agentImageFor(ad, config, templatePatch)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := r.resolveAgentImage(ctx, ad, image)
+	if err != nil {
+		return nil, err
+	}
+	if digest, err = r.verifyAgentImage(ctx, config, image, digest); err != nil {
+		return nil, err
+	}
+	apiKeys, err := r.apiKeysChecksum(ctx, ad)
+	if err != nil {
+		return nil, err
+	}
+	overlays := []deploymentOverlay{configOverlay(config), imageDigestOverlay(image, digest), apiKeysOverlay(apiKeys),
+		egressOverlay(ad.Namespace, config), componentResourcesOverlay(ad)}
+
+	deployment := &appsv1.Deployment{}
+	update := dryRun.updateDeployment
+	if rolloutEnabled(ad) {
+		if err := dryRun.reconcileAnalysisTemplate(ctx, ad); err != nil {
+			return nil, err
+		}
+		deployment, err = r.getRollout(ctx, ad)
+		update = dryRun.updateRollout
+	} else {
+		err = r.Get(ctx, types.NamespacedName{Name: ad.Name, Namespace: ad.Namespace}, deployment)
+	}
+	if errors.IsNotFound(err) {
+		dep, err := dryRun.deploymentForAgentDeployment(ad, templatePatch)
+		if err != nil {
+			return nil, err
+		}
+		replicas := replicasForDeployment(ad, nil, managedAutoscaler)
+		dep.Spec.Replicas = &replicas
+		for _, overlay := range overlays {
+			overlay(dep)
+		}
+		var obj client.Object = dep
+		if rolloutEnabled(ad) {
+			if obj, err = dryRun.rolloutForDeployment(ad, dep); err != nil {
+				return nil, err
+			}
+		}
+		if err := recorder.Create(ctx, obj); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	} else if err := update(ctx, ad, deployment, managedAutoscaler, templatePatch, overlays); err != nil {
+		return nil, err
+	}
+
+	hibernated := conditions.IsTrue(ad.Status.Conditions, conditions.Hibernated)
+	if err := dryRun.reconcileHPA(ctx, ad, managedAutoscaler, hibernated); err != nil {
+		return nil, err
+	}
+	return recorder.changes, nil
+}
+
+// changeRecorder records the writes made through a dry-run client together with the
+// objects they would replace
+type changeRecorder struct {
+	client.Client
+	live    client.Reader
+	changes []ChildChange
+}
+
+func (c *changeRecorder) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if err := c.Client.Create(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.changes = append(c.changes, ChildChange{Desired: obj.DeepCopyObject().(client.Object)})
+	return nil
+}
+
+func (c *changeRecorder) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	live := obj.DeepCopyObject().(client.Object)
+	if err := c.live.Get(ctx, client.ObjectKeyFromObject(obj), live); err != nil {
+		return err
+	}
+	if err := c.Client.Update(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.changes = append(c.changes, ChildChange{Live: live, Desired: obj.DeepCopyObject().(client.Object)})
+	return nil
+}
+
+func (c *changeRecorder) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if err := c.Client.Delete(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.changes = append(c.changes, ChildChange{Live: obj.DeepCopyObject().(client.Object)})
+	return nil
+}
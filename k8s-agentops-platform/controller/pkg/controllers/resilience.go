@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/catalog"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/gateway"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/providers"
+)
+
+const (
+	// providerProxyConfigAnnotation on the pod template rolls the pods when the
+	// provider proxy configuration changes
+	providerProxyConfigAnnotation = "agentops.io/provider-proxy-config-hash"
+	providerProxyContainerName    = "provider-proxy"
+	providerProxyVolumeName       = "provider-proxy-config"
+	providerProxyListenPort       = 18070
+
+	defaultResilienceTimeout   = 10 * time.Minute
+	defaultRetryAttempts       = int32(2)
+	defaultConsecutiveFailures = int32(5)
+	defaultCircuitInterval     = 10 * time.Second
+	defaultCircuitOpenDuration = 30 * time.Second
+)
+
+var (
+	defaultRetryOn          = []string{"5xx", "reset", "connect-failure"}
+	defaultRetryStatusCodes = []int32{429, 529}
+)
+
+// providerEndpoints are the API base URLs of the providers the provider proxy can
+// front, and the variable pointing the agent runtime at it
+var providerEndpoints = map[string]struct {
+	URL, Env string
+}{
+	providers.Anthropic:   {URL: "https://api.anthropic.com", Env: "ANTHROPIC_BASE_URL"},
+	providers.OpenAI:      {URL: "https://api.openai.com/v1", Env: "OPENAI_BASE_URL"},
+	providers.AzureOpenAI: {Env: "AZURE_OPENAI_ENDPOINT"},
+}
+
+// resilienceEnabled reports whether the agent calls its provider through the
+// provider proxy sidecar
+func resilienceEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.Resilience != nil
+}
+
+// providerProxyConfigMapName returns the name of the ConfigMap holding the provider
+// proxy configuration
+func providerProxyConfigMapName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-provider-proxy"
+}
+
+// providerProxyURL returns the base URL the agent runtime calls the provider at
+func providerProxyURL() string {
+	return fmt.Sprintf("http://127.0.0.1:%d", providerProxyListenPort)
+}
+
+// fallbackProvider returns the secondary provider of spec.resilience.fallback, or
+// "" when the agent falls back to cached responses or not at all
+func fallbackProvider(ad *agentopsv1alpha1.AgentDeployment) string {
+	fb := ad.Spec.Resilience.Fallback
+	if fb == nil || fb.Mode == agentopsv1alpha1.FallbackModeCached {
+		return ""
+	}
+	return fb.Provider
+}
+
+// providerUpstream returns the upstream of a hosted provider, at endpoint when set
+func providerUpstream(name, provider, endpoint string) (gateway.Upstream, error) {
+	ep, ok := providerEndpoints[provider]
+	if !ok {
+		return gateway.Upstream{}, fmt.Errorf("spec.resilience supports the anthropic, openai and azure-openai providers, not %s", provider)
+	}
+	if endpoint == "" {
+		endpoint = ep.URL
+	}
+	if endpoint == "" {
+		return gateway.Upstream{}, fmt.Errorf("provider %s requires an endpoint", provider)
+	}
+	return gateway.ParseUpstream(name, endpoint)
+}
+
+// providerProxyConfigFor renders spec.resilience into the provider proxy configuration
+func providerProxyConfigFor(ad *agentopsv1alpha1.AgentDeployment) (gateway.ProviderProxyConfig, error) {
+	if modelServerEnabled(ad) {
+		return gateway.ProviderProxyConfig{}, fmt.Errorf("spec.resilience does not apply to agents calling a self-hosted model server")
+	}
+	p, err := providerForAgentDeployment(ad)
+	if err != nil {
+		return gateway.ProviderProxyConfig{}, err
+	}
+	var endpoint string
+	if cfg := ad.Spec.ProviderConfig; cfg != nil {
+		switch {
+		case p.Name == providers.Anthropic && cfg.Anthropic != nil:
+			endpoint = cfg.Anthropic.Endpoint
+		case p.Name == providers.OpenAI && cfg.OpenAI != nil:
+			endpoint = cfg.OpenAI.Endpoint
+		case p.Name == providers.AzureOpenAI && cfg.AzureOpenAI != nil:
+			endpoint = cfg.AzureOpenAI.Endpoint
+		}
+	}
+	primary, err := providerUpstream("primary", p.Name, endpoint)
+	if err != nil {
+		return gateway.ProviderProxyConfig{}, err
+	}
+
+	spec := ad.Spec.Resilience
+	pc := gateway.ProviderProxyConfig{
+		ListenPort: providerProxyListenPort,
+		Primary:    primary,
+		Timeout:    defaultResilienceTimeout,
+		Retry: gateway.RetryPolicy{
+			Attempts:    defaultRetryAttempts,
+			RetryOn:     defaultRetryOn,
+			StatusCodes: defaultRetryStatusCodes,
+		},
+		CircuitBreaker: gateway.CircuitBreaker{
+			ConsecutiveFailures: defaultConsecutiveFailures,
+			Interval:            defaultCircuitInterval,
+			OpenDuration:        defaultCircuitOpenDuration,
+		},
+	}
+	if spec.Timeout != nil {
+		pc.Timeout = spec.Timeout.Duration
+	}
+	if r := spec.Retries; r != nil {
+		if r.Attempts != nil {
+			pc.Retry.Attempts = *r.Attempts
+		}
+		if r.PerTryTimeout != nil {
+			pc.Retry.PerTryTimeout = r.PerTryTimeout.Duration
+		}
+		if r.RetryOn != nil {
+			pc.Retry.RetryOn = r.RetryOn
+		}
+		if r.StatusCodes != nil {
+			pc.Retry.StatusCodes = r.StatusCodes
+		}
+	}
+	if cb := spec.CircuitBreaker; cb != nil {
+		if cb.ConsecutiveFailures != nil {
+			pc.CircuitBreaker.ConsecutiveFailures = *cb.ConsecutiveFailures
+		}
+		if cb.Interval != nil {
+			pc.CircuitBreaker.Interval = cb.Interval.Duration
+		}
+		if cb.OpenDuration != nil {
+			pc.CircuitBreaker.OpenDuration = cb.OpenDuration.Duration
+		}
+		if cb.MaxPendingRequests != nil {
+			pc.CircuitBreaker.MaxPendingRequests = *cb.MaxPendingRequests
+		}
+		if cb.MaxRequests != nil {
+			pc.CircuitBreaker.MaxRequests = *cb.MaxRequests
+		}
+	}
+
+	if name := fallbackProvider(ad); name != "" {
+		fb := spec.Fallback
+		if name == providers.AzureOpenAI && fb.Model == "" {
+			return gateway.ProviderProxyConfig{}, fmt.Errorf("spec.resilience.fallback.model must name the azure-openai deployment")
+		}
+		upstream, err := providerUpstream("fallback", name, fb.Endpoint)
+		if err != nil {
+			return gateway.ProviderProxyConfig{}, err
+		}
+		pc.Fallback = &upstream
+	} else if spec.Fallback != nil && spec.Fallback.Mode != agentopsv1alpha1.FallbackModeCached {
+		return gateway.ProviderProxyConfig{}, fmt.Errorf("spec.resilience.fallback requires a provider, or mode cached")
+	}
+	return pc, nil
+}
+
+// reconcileResilience renders the provider proxy configuration and returns its
+// hash, or deletes the ConfigMap when the agent has no spec.resilience. The proxy
+// calls the provider directly, so it cannot run behind the namespace egress proxy.
+func (r *AgentDeploymentReconciler) reconcileResilience(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, cfg *agentopsv1alpha1.AgentOpsConfig) (string, error) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: providerProxyConfigMapName(ad), Namespace: ad.Namespace},
+	}
+	if !resilienceEnabled(ad) {
+		if err := r.Delete(ctx, cm); err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+		return "", nil
+	}
+	if egressProxyFor(ad.Namespace, cfg) != nil {
+		return "", fmt.Errorf("spec.resilience cannot be used in namespace %s, whose agents reach providers through the egress proxy", ad.Namespace)
+	}
+
+	pc, err := providerProxyConfigFor(ad)
+	if err != nil {
+		return "", err
+	}
+	config, err := gateway.RenderProviderProxyBootstrap(pc)
+	if err != nil {
+		return "", err
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Labels = labelsForAgentDeployment(ad.Name)
+		cm.Data = map[string]string{gatewayConfigKey: config}
+		return controllerutil.SetControllerReference(ad, cm, r.Scheme)
+	}); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(config))
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// resilienceEnv points the agent runtime at the provider proxy and describes the
+// fallback it switches to while the proxy reports the provider down
+func resilienceEnv(ad *agentopsv1alpha1.AgentDeployment, provider string) []corev1.EnvVar {
+	env := []corev1.EnvVar{{Name: providerEndpoints[provider].Env, Value: providerProxyURL()}}
+	fb := ad.Spec.Resilience.Fallback
+	if fb == nil {
+		return env
+	}
+	if fb.Mode == agentopsv1alpha1.FallbackModeCached {
+		return append(env, corev1.EnvVar{Name: "AGENTOPS_FALLBACK_MODE", Value: agentopsv1alpha1.FallbackModeCached})
+	}
+
+	model := fb.Model
+	if model == "" {
+		model = catalog.ProviderModelID(ad.Spec.Model, fb.Provider)
+	}
+	env = append(env,
+		corev1.EnvVar{Name: "AGENTOPS_FALLBACK_MODE", Value: agentopsv1alpha1.FallbackModeProvider},
+		corev1.EnvVar{Name: "AGENTOPS_FALLBACK_PROVIDER", Value: fb.Provider},
+		corev1.EnvVar{Name: "AGENTOPS_FALLBACK_MODEL_ID", Value: model},
+		corev1.EnvVar{Name: "AGENTOPS_FALLBACK_BASE_URL", Value: providerProxyURL() + strings.TrimSuffix(gateway.FallbackPathPrefix, "/")},
+	)
+	secret := fb.CredentialsSecretRef
+	if secret == nil && ad.Spec.ProviderConfig != nil {
+		secret = ad.Spec.ProviderConfig.CredentialsSecretRef
+	}
+	if secret != nil {
+		env = append(env, credentialEnv("AGENTOPS_FALLBACK_API_KEY", secret.Name, apiKeySecretKey, false))
+	}
+	return env
+}
+
+// resilienceOverlay runs the provider proxy sidecar and points the agent container
+// at it in place of the provider's API
+func resilienceOverlay(ad *agentopsv1alpha1.AgentDeployment, configHash string) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		if !resilienceEnabled(ad) {
+			return
+		}
+		p, err := providerForAgentDeployment(ad)
+		if err != nil {
+			return
+		}
+		spec := &dep.Spec.Template.Spec
+		agent := &spec.Containers[0]
+		env := resilienceEnv(ad, p.Name)
+		overridden := map[string]bool{}
+		for _, e := range env {
+			overridden[e.Name] = true
+		}
+		kept := agent.Env[:0]
+		for _, e := range agent.Env {
+			if !overridden[e.Name] {
+				kept = append(kept, e)
+			}
+		}
+		agent.Env = append(kept, env...)
+
+		spec.Containers = append(spec.Containers, corev1.Container{
+			Name:  providerProxyContainerName,
+			Image: gatewayImage,
+			Args:  []string{"-c", "/etc/envoy/" + gatewayConfigKey},
+			Ports: []corev1.ContainerPort{{Name: "proxy-admin", ContainerPort: gateway.ProviderProxyAdminPort}},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					HTTPGet: &corev1.HTTPGetAction{Path: "/ready", Port: intstr.FromInt(gateway.ProviderProxyAdminPort)},
+				},
+				PeriodSeconds: 5,
+			},
+			VolumeMounts: []corev1.VolumeMount{{Name: providerProxyVolumeName, MountPath: "/etc/envoy", ReadOnly: true}},
+		})
+		spec.Volumes = append(spec.Volumes, corev1.Volume{
+			Name: providerProxyVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: providerProxyConfigMapName(ad)},
+				},
+			},
+		})
+		if dep.Spec.Template.Annotations == nil {
+			dep.Spec.Template.Annotations = map[string]string{}
+		}
+		dep.Spec.Template.Annotations[providerProxyConfigAnnotation] = configHash
+	}
+}
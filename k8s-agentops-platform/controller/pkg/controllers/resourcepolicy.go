@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// Requests and limits required by a ResourcePolicy that lists none
+var (
+	defaultPolicyRequests = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+	defaultPolicyLimits   = []corev1.ResourceName{corev1.ResourceMemory}
+)
+
+// resourcePolicyFor returns the ResourcePolicy of the AgentOpsConfig covering
+// namespace, or nil
+func resourcePolicyFor(cfg *agentopsv1alpha1.AgentOpsConfig, namespace string) *agentopsv1alpha1.ResourcePolicy {
+	if cfg == nil || cfg.Spec.ResourcePolicy == nil {
+		return nil
+	}
+	policy := cfg.Spec.ResourcePolicy
+	if len(policy.Namespaces) > 0 && !containsString(policy.Namespaces, namespace) {
+		return nil
+	}
+	return policy
+}
+
+// policyRequests and policyLimits return the resources the policy requires a
+// request and a limit of
+func policyRequests(policy *agentopsv1alpha1.ResourcePolicy) []corev1.ResourceName {
+	if len(policy.Requests) == 0 {
+		return defaultPolicyRequests
+	}
+	return policy.Requests
+}
+
+func policyLimits(policy *agentopsv1alpha1.ResourcePolicy) []corev1.ResourceName {
+	if len(policy.Limits) == 0 {
+		return defaultPolicyLimits
+	}
+	return policy.Limits
+}
+
+// missingResources lists the requests and limits the policy requires of res but
+// res does not set, e.g. "cpu request"
+func missingResources(res corev1.ResourceRequirements, policy *agentopsv1alpha1.ResourcePolicy) []string {
+	var missing []string
+	for _, name := range policyRequests(policy) {
+		if _, ok := res.Requests[name]; !ok {
+			missing = append(missing, fmt.Sprintf("%s request", name))
+		}
+	}
+	for _, name := range policyLimits(policy) {
+		if _, ok := res.Limits[name]; !ok {
+			missing = append(missing, fmt.Sprintf("%s limit", name))
+		}
+	}
+	return missing
+}
+
+// fillResources sets the requests and limits the policy requires of the agent but
+// it lacks, in spec.resourcesByComponent.agent when it is set. An agent without any
+// resources gets the full defaults of its profile or size class, GPUs included,
+// like the controller would give it; otherwise only the missing entries are taken
+// from them, kept consistent with what is set.
+func fillResources(ad *agentopsv1alpha1.AgentDeployment, cfg *agentopsv1alpha1.AgentOpsConfig, policy *agentopsv1alpha1.ResourcePolicy) {
+	defaulted := ad.DeepCopy()
+	defaulted.Spec.Resources = corev1.ResourceRequirements{}
+	applyResourceProfiles(defaulted, cfg)
+	applySizeClassDefaults(defaulted, cfg)
+	defaults := defaulted.Spec.Resources
+
+	res := agentResources(ad)
+	if len(res.Requests) == 0 && len(res.Limits) == 0 && len(res.Claims) == 0 {
+		*res = defaults
+		return
+	}
+	for _, name := range policyRequests(policy) {
+		q, ok := defaults.Requests[name]
+		if _, set := res.Requests[name]; set || !ok {
+			continue
+		}
+		if limit, hasLimit := res.Limits[name]; hasLimit && q.Cmp(limit) > 0 {
+			q = limit
+		}
+		if res.Requests == nil {
+			res.Requests = corev1.ResourceList{}
+		}
+		res.Requests[name] = q.DeepCopy()
+	}
+	for _, name := range policyLimits(policy) {
+		q, ok := defaults.Limits[name]
+		if _, set := res.Limits[name]; set || !ok {
+			continue
+		}
+		if request, hasRequest := res.Requests[name]; hasRequest && q.Cmp(request) < 0 {
+			q = request
+		}
+		if res.Limits == nil {
+			res.Limits = corev1.ResourceList{}
+		}
+		res.Limits[name] = q.DeepCopy()
+	}
+}
+
+// AgentDeploymentDefaulter fills in the agent container resources required by a
+// ResourcePolicy with action Default. Unlike the in-memory defaults of the
+// controller, these are written to the AgentDeployment, where the policy can see
+// them.
+type AgentDeploymentDefaulter struct {
+	client.Reader
+}
+
+// +kubebuilder:webhook:path=/mutate-agentops-io-v1alpha1-agentdeployment,mutating=true,failurePolicy=fail,sideEffects=None,groups=agentops.io,resources=agentdeployments,verbs=create;update,versions=v1alpha1,name=magentdeployment.agentops.io,admissionReviewVersions=v1
+
+// SetupWebhookWithManager serves the AgentDeployment mutating webhook
+func (d *AgentDeploymentDefaulter) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&agentopsv1alpha1.AgentDeployment{}).
+		WithDefaulter(d).
+		Complete()
+}
+
+// Default fills in the resources an agent under a Default ResourcePolicy lacks
+func (d *AgentDeploymentDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	ad := obj.(*agentopsv1alpha1.AgentDeployment)
+	if !ad.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	cfg, err := getAgentOpsConfig(ctx, d.Reader)
+	if err != nil {
+		return err
+	}
+	policy := resourcePolicyFor(cfg, ad.Namespace)
+	if policy == nil || policy.Action != agentopsv1alpha1.ResourcePolicyDefault || len(missingResources(*agentResources(ad), policy)) == 0 {
+		return nil
+	}
+	fillResources(ad, cfg, policy)
+	return nil
+}
+
+// validateResources returns an error listing the requests and limits the
+// ResourcePolicy of the agent's namespace requires but it lacks. Updates are only
+// checked when they change the resources, so that a new policy does not block
+// unrelated changes to existing agents.
+func (v *AgentDeploymentValidator) validateResources(ctx context.Context, ad, old *agentopsv1alpha1.AgentDeployment) error {
+	if old != nil && equality.Semantic.DeepEqual(agentResources(old), agentResources(ad)) {
+		return nil
+	}
+	cfg, err := getAgentOpsConfig(ctx, v.Reader)
+	if err != nil {
+		return err
+	}
+	policy := resourcePolicyFor(cfg, ad.Namespace)
+	if policy == nil {
+		return nil
+	}
+	if missing := missingResources(*agentResources(ad), policy); len(missing) > 0 {
+		return fmt.Errorf("the resource policy of AgentOpsConfig %s requires the agent resources to set %s",
+			agentopsv1alpha1.AgentOpsConfigName, strings.Join(missing, ", "))
+	}
+	return nil
+}
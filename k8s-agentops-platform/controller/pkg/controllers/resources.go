@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/catalog"
+)
+
+const (
+	scratchVolumeName = "scratch"
+	// ephemeralHeadroomPercent is added to the catalog cache footprint for the request;
+	// the limit gets twice the headroom so bursts don't trigger eviction
+	ephemeralHeadroomPercent = 20
+)
+
+// resourcesForAgentDeployment returns the agent container resources, adding
+// ephemeral-storage requests and limits unless they are set explicitly
+func resourcesForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) corev1.ResourceRequirements {
+	res := *ad.Spec.Resources.DeepCopy()
+
+	request, limit := ephemeralStorageForAgentDeployment(ad)
+	if request != nil {
+		if res.Requests == nil {
+			res.Requests = corev1.ResourceList{}
+		}
+		if _, ok := res.Requests[corev1.ResourceEphemeralStorage]; !ok {
+			res.Requests[corev1.ResourceEphemeralStorage] = *request
+		}
+	}
+	if limit != nil {
+		if res.Limits == nil {
+			res.Limits = corev1.ResourceList{}
+		}
+		if _, ok := res.Limits[corev1.ResourceEphemeralStorage]; !ok {
+			res.Limits[corev1.ResourceEphemeralStorage] = *limit
+		}
+	}
+	return res
+}
+
+// ephemeralStorageForAgentDeployment returns the ephemeral-storage request and limit,
+// sized from the spec or, when unset, from the model's catalog cache footprint
+func ephemeralStorageForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) (*resource.Quantity, *resource.Quantity) {
+	spec := ad.Spec.EphemeralStorage
+	if spec != nil && spec.Request != nil {
+		limit := spec.Limit
+		if limit == nil {
+			limit = withHeadroom(*spec.Request, ephemeralHeadroomPercent)
+		}
+		return spec.Request, limit
+	}
+
+	model, ok := catalog.Lookup(ad.Spec.Model)
+	if !ok || model.CacheFootprint.IsZero() {
+		return nil, nil
+	}
+	request := withHeadroom(model.CacheFootprint, ephemeralHeadroomPercent)
+	limit := withHeadroom(model.CacheFootprint, 2*ephemeralHeadroomPercent)
+	if spec != nil && spec.Limit != nil {
+		limit = spec.Limit
+	}
+	return request, limit
+}
+
+// applySizeClassDefaults gives an agent that sets no resources the defaults of its
+// model's size class: those of the AgentOpsConfig when it sets the class, else the
+// catalog's, with as many GPUs as the model needs at its quantization and context
+func applySizeClassDefaults(ad *agentopsv1alpha1.AgentDeployment, cfg *agentopsv1alpha1.AgentOpsConfig) {
+	res := ad.Spec.Resources
+	if len(res.Requests) > 0 || len(res.Limits) > 0 || len(res.Claims) > 0 {
+		return
+	}
+	class := sizeClassForAgentDeployment(ad)
+	defaults, ok := catalog.SizeClassResources(class)
+	if _, hasGPU := defaults.Limits[defaultGPUResource]; hasGPU {
+		if gpus := tensorParallel(ad); gpus > 0 {
+			q := *resource.NewQuantity(int64(gpus), resource.DecimalSI)
+			defaults.Requests[defaultGPUResource] = q
+			defaults.Limits[defaultGPUResource] = q
+		} else if gpus, sized := defaultGPUs(ad, defaultGPUMemory); sized {
+			q := *resource.NewQuantity(int64(gpus), resource.DecimalSI)
+			defaults.Requests[defaultGPUResource] = q
+			defaults.Limits[defaultGPUResource] = q
+		}
+	}
+	if cfg != nil {
+		for _, sc := range cfg.Spec.SizeClasses {
+			if sc.Name == class {
+				defaults, ok = *sc.Resources.DeepCopy(), true
+			}
+		}
+	}
+	if ok {
+		ad.Spec.Resources = defaults
+	}
+}
+
+// sizeClassForAgentDeployment returns the size class of the agent's model, or ""
+// when the catalog does not know it. Only an agent pod serving the model itself
+// needs the GPUs and memory of a large model; one calling a hosted API or a
+// separate model server is sized as medium.
+func sizeClassForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) string {
+	model, _ := catalog.Lookup(ad.Spec.Model)
+	if model.SizeClass != catalog.SizeLarge && model.SizeClass != catalog.SizeXL {
+		return model.SizeClass
+	}
+	p, err := providerForAgentDeployment(ad)
+	if err != nil || !p.SelfHosted || modelServerEnabled(ad) {
+		return catalog.SizeMedium
+	}
+	return model.SizeClass
+}
+
+// agentResources returns the agent container resources as written: those of
+// spec.resourcesByComponent when it sets them, else spec.resources
+func agentResources(ad *agentopsv1alpha1.AgentDeployment) *corev1.ResourceRequirements {
+	if rc := ad.Spec.ResourcesByComponent; rc != nil && rc.Agent != nil {
+		return rc.Agent
+	}
+	return &ad.Spec.Resources
+}
+
+// applyComponentResources moves the agent and server entries of
+// spec.resourcesByComponent into spec.resources and serving.selfHosted.resources,
+// from which the pods, quotas and costs are sized
+func applyComponentResources(ad *agentopsv1alpha1.AgentDeployment) {
+	rc := ad.Spec.ResourcesByComponent
+	if rc == nil {
+		return
+	}
+	if rc.Agent != nil {
+		ad.Spec.Resources = *rc.Agent.DeepCopy()
+	}
+	if rc.Server != nil && ad.Spec.Serving != nil && ad.Spec.Serving.SelfHosted != nil {
+		ad.Spec.Serving.SelfHosted.Resources = *rc.Server.DeepCopy()
+	}
+}
+
+// componentResourcesOverlay sets the sidecar resources of spec.resourcesByComponent
+// on the agent pods. It runs after the overlays adding sidecars.
+func componentResourcesOverlay(ad *agentopsv1alpha1.AgentDeployment) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		applySidecarResources(ad, &dep.Spec.Template.Spec)
+	}
+}
+
+// applySidecarResources replaces the resources of the containers of spec named in
+// spec.resourcesByComponent.sidecars; the first container, the agent or the model
+// server, is never a sidecar
+func applySidecarResources(ad *agentopsv1alpha1.AgentDeployment, spec *corev1.PodSpec) {
+	rc := ad.Spec.ResourcesByComponent
+	if rc == nil {
+		return
+	}
+	for _, sidecar := range rc.Sidecars {
+		for i := 1; i < len(spec.Containers); i++ {
+			if spec.Containers[i].Name == sidecar.Name {
+				spec.Containers[i].Resources = *sidecar.Resources.DeepCopy()
+			}
+		}
+	}
+}
+
+// withHeadroom returns q increased by percent
+func withHeadroom(q resource.Quantity, percent int64) *resource.Quantity {
+	return resource.NewQuantity(q.Value()+q.Value()*percent/100, resource.BinarySI)
+}
+
+// scratchVolumeForAgentDeployment returns a size-limited emptyDir for temporary
+// tensors, so writes land in accounted storage instead of the read-only root filesystem
+func scratchVolumeForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) (corev1.Volume, corev1.VolumeMount) {
+	_, limit := ephemeralStorageForAgentDeployment(ad)
+	path := "/tmp"
+	if ad.Spec.EphemeralStorage != nil && ad.Spec.EphemeralStorage.ScratchPath != "" {
+		path = ad.Spec.EphemeralStorage.ScratchPath
+	}
+	volume := corev1.Volume{
+		Name: scratchVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: limit},
+		},
+	}
+	mount := corev1.VolumeMount{Name: scratchVolumeName, MountPath: path}
+	return volume, mount
+}
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	responseCacheProxyImage    = "ghcr.io/myorg/agentops-cache:latest"
+	defaultRedisStackImage     = "redis/redis-stack-server:7.2.0-v10"
+	responseCacheContainerName = "cache"
+	responseCacheListenPort    = 18100
+	responseCacheMetricsPort   = 9465
+	defaultResponseCacheTTL    = time.Hour
+	defaultSimilarityThreshold = "0.95"
+)
+
+// responseCacheEnabled reports whether the agent's answers are cached
+func responseCacheEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.ResponseCache != nil && ad.Spec.ResponseCache.Enabled
+}
+
+// responseCacheMode returns the matching mode of the agent's cache
+func responseCacheMode(ad *agentopsv1alpha1.AgentDeployment) string {
+	if ad.Spec.ResponseCache.Mode == "" {
+		return agentopsv1alpha1.ResponseCacheExact
+	}
+	return ad.Spec.ResponseCache.Mode
+}
+
+// responseCacheName returns the name of the cache StatefulSet and Service
+func responseCacheName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-cache"
+}
+
+// responseCacheEmbedderURL returns the embedder of semantic mode, or "" when the
+// agent has neither embedderURL nor a RAGPipeline to borrow one from
+func responseCacheEmbedderURL(ad *agentopsv1alpha1.AgentDeployment) string {
+	if url := ad.Spec.ResponseCache.EmbedderURL; url != "" {
+		return url
+	}
+	if ad.Spec.RAGRef != nil {
+		return fmt.Sprintf("http://%s:%d", serviceHost(ad.Spec.RAGRef.Name+"-embedder", ad.Namespace), ragPort)
+	}
+	return ""
+}
+
+// reconcileResponseCache deploys the redis of spec.responseCache, or removes it when
+// the agent no longer caches, and records whether it is up
+func (r *AgentDeploymentReconciler) reconcileResponseCache(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	name := responseCacheName(ad)
+	if !responseCacheEnabled(ad) {
+		meta.RemoveStatusCondition(&ad.Status.Conditions, conditions.ResponseCacheReady)
+		return r.deleteStoreStatefulSet(ctx, ad, name)
+	}
+
+	spec := ad.Spec.ResponseCache
+	if responseCacheMode(ad) == agentopsv1alpha1.ResponseCacheSemantic && responseCacheEmbedderURL(ad) == "" {
+		return fmt.Errorf("semantic response caching needs responseCache.embedderURL or spec.ragRef")
+	}
+	image := spec.Image
+	if image == "" && responseCacheMode(ad) == agentopsv1alpha1.ResponseCacheSemantic {
+		image = defaultRedisStackImage
+	}
+	podSpec := func(persistent bool) corev1.PodSpec { return redisPodSpec(image, persistent) }
+	ports := []corev1.ServicePort{{Name: "redis", Port: redisPort, TargetPort: intstr.FromString("redis"), Protocol: corev1.ProtocolTCP}}
+	sts, err := r.reconcileStoreStatefulSet(ctx, ad, name, labelsForResponseCache(ad.Name), spec.Storage, spec.StorageClassName, podSpec, ports)
+	if err != nil {
+		return err
+	}
+
+	if sts.Status.ReadyReplicas > 0 {
+		conditions.Set(&ad.Status.Conditions, conditions.ResponseCacheReady, metav1.ConditionTrue, conditions.ReasonAsExpected,
+			fmt.Sprintf("%s response cache is ready", responseCacheMode(ad)), ad.Generation)
+	} else {
+		conditions.Set(&ad.Status.Conditions, conditions.ResponseCacheReady, metav1.ConditionFalse, conditions.ReasonResponseCacheUnavailable,
+			"Waiting for the response cache to become ready", ad.Generation)
+	}
+	return nil
+}
+
+// responseCacheOverlay runs the cache proxy in front of the queue proxy, or the
+// agent container without a queue. Like the queue proxy it takes over the "http"
+// port the Service targets, unless the gateway sidecar already did and forwards to
+// it.
+func responseCacheOverlay(ad *agentopsv1alpha1.AgentDeployment, behindGateway bool) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		if !responseCacheEnabled(ad) {
+			return
+		}
+		spec := &dep.Spec.Template.Spec
+		agent := &spec.Containers[0]
+		portName := "cache"
+		if !behindGateway {
+			portName = "http"
+			for i := range agent.Ports {
+				if agent.Ports[i].Name == "http" {
+					agent.Ports[i].Name = "agent"
+				}
+			}
+		}
+
+		rc := ad.Spec.ResponseCache
+		ttl := defaultResponseCacheTTL
+		if rc.TTL != nil {
+			ttl = rc.TTL.Duration
+		}
+		upstream := agentPortForAgentDeployment(ad)
+		if queueEnabled(ad) {
+			upstream = queueListenPort
+		}
+		env := []corev1.EnvVar{
+			{Name: "CACHE_MODE", Value: responseCacheMode(ad)},
+			{Name: "CACHE_URL", Value: fmt.Sprintf("redis://%s:%d", serviceHost(responseCacheName(ad), ad.Namespace), redisPort)},
+			{Name: "CACHE_KEY_PREFIX", Value: fmt.Sprintf("agentops:%s:%s:", ad.Namespace, ad.Name)},
+			{Name: "CACHE_TTL", Value: ttl.String()},
+			{Name: "CACHE_LISTEN_PORT", Value: strconv.Itoa(responseCacheListenPort)},
+			{Name: "CACHE_UPSTREAM", Value: fmt.Sprintf("http://127.0.0.1:%d", upstream)},
+			{Name: "CACHE_METRICS_PORT", Value: strconv.Itoa(responseCacheMetricsPort)},
+			{Name: "CACHE_METRIC_LABELS", Value: fmt.Sprintf("namespace=%s,agent=%s", ad.Namespace, ad.Name)},
+		}
+		if responseCacheMode(ad) == agentopsv1alpha1.ResponseCacheSemantic {
+			threshold := rc.SimilarityThreshold
+			if threshold == "" {
+				threshold = defaultSimilarityThreshold
+			}
+			env = append(env,
+				corev1.EnvVar{Name: "CACHE_SIMILARITY_THRESHOLD", Value: threshold},
+				corev1.EnvVar{Name: "CACHE_EMBEDDER_URL", Value: responseCacheEmbedderURL(ad)},
+			)
+		}
+
+		spec.Containers = append(spec.Containers, corev1.Container{
+			Name:  responseCacheContainerName,
+			Image: responseCacheProxyImage,
+			Env:   env,
+			Ports: []corev1.ContainerPort{
+				{Name: portName, ContainerPort: responseCacheListenPort},
+				{Name: "cache-metrics", ContainerPort: responseCacheMetricsPort},
+			},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					HTTPGet: &corev1.HTTPGetAction{Path: "/ready", Port: intstr.FromInt(responseCacheMetricsPort)},
+				},
+				PeriodSeconds: 5,
+			},
+		})
+	}
+}
+
+// labelsForResponseCache returns the labels of the deployed response cache pods
+func labelsForResponseCache(name string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "response-cache",
+		"app.kubernetes.io/instance":   name,
+		"app.kubernetes.io/managed-by": "agentops-controller",
+	}
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/notify"
+)
+
+// defaultRevisionHistoryLimit is the number of AgentRevisions kept without
+// spec.revisionHistoryLimit
+const defaultRevisionHistoryLimit = 10
+
+// revisionTemplate returns the spec recorded in an AgentRevision. Replicas are left
+// out, so scaling is not a new revision and rollbacks keep the current scale.
+func revisionTemplate(ad *agentopsv1alpha1.AgentDeployment) *agentopsv1alpha1.AgentDeploymentSpec {
+	spec := ad.Spec.DeepCopy()
+	spec.Replicas = nil
+	return spec
+}
+
+// revisionHistoryLimit returns the number of AgentRevisions, and EvaluationRuns,
+// kept for the AgentDeployment
+func revisionHistoryLimit(ad *agentopsv1alpha1.AgentDeployment) int {
+	if ad.Spec.RevisionHistoryLimit == nil {
+		return defaultRevisionHistoryLimit
+	}
+	return int(*ad.Spec.RevisionHistoryLimit)
+}
+
+// agentRevisions returns the AgentRevisions of the AgentDeployment, oldest first
+func (r *AgentDeploymentReconciler) agentRevisions(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) ([]agentopsv1alpha1.AgentRevision, error) {
+	list := &agentopsv1alpha1.AgentRevisionList{}
+	if err := r.List(ctx, list, client.InNamespace(ad.Namespace), client.MatchingLabels(labelsForAgentDeployment(ad.Name))); err != nil {
+		return nil, err
+	}
+	var revisions []agentopsv1alpha1.AgentRevision
+	for _, rev := range list.Items {
+		if metav1.IsControlledBy(&rev, ad) {
+			revisions = append(revisions, rev)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Spec.Revision < revisions[j].Spec.Revision })
+	return revisions, nil
+}
+
+// reconcileRevisions records the rolled out template, image digest and prompt
+// revision as an AgentRevision and prunes revisions beyond the history limit.
+// Rolling out an earlier revision again moves it to the newest number.
+func (r *AgentDeploymentReconciler) reconcileRevisions(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, template *agentopsv1alpha1.AgentDeploymentSpec, image, digest string) error {
+	raw, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(raw)
+	configHash := hex.EncodeToString(sum[:8])
+	sum = sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d", configHash, digest, ad.Status.PromptRevision)))
+	name := ad.Name + "-" + hex.EncodeToString(sum[:5])
+
+	revisions, err := r.agentRevisions(ctx, ad)
+	if err != nil {
+		return err
+	}
+	var latest int64
+	var current *agentopsv1alpha1.AgentRevision
+	for i := range revisions {
+		if revisions[i].Name == name {
+			current = &revisions[i]
+		}
+		latest = revisions[i].Spec.Revision
+	}
+
+	switch {
+	case current == nil:
+		rev := agentopsv1alpha1.AgentRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ad.Namespace,
+				Labels:    labelsForAgentDeployment(ad.Name),
+			},
+			Spec: agentopsv1alpha1.AgentRevisionSpec{
+				Revision:       latest + 1,
+				Model:          ad.Spec.Model,
+				Image:          image,
+				ImageDigest:    digest,
+				PromptRevision: ad.Status.PromptRevision,
+				ConfigHash:     configHash,
+				Template:       runtime.RawExtension{Raw: raw},
+			},
+		}
+		if err := controllerutil.SetControllerReference(ad, &rev, r.Scheme); err != nil {
+			return err
+		}
+		if err := r.Create(ctx, &rev); err != nil {
+			return err
+		}
+		revisions = append(revisions, rev)
+		current = &revisions[len(revisions)-1]
+	case current.Spec.Revision != latest:
+		current.Spec.Revision = latest + 1
+		if err := r.Update(ctx, current); err != nil {
+			return err
+		}
+		sort.Slice(revisions, func(i, j int) bool { return revisions[i].Spec.Revision < revisions[j].Spec.Revision })
+	}
+	ad.Status.Revision = current.Spec.Revision
+
+	for i := 0; i < len(revisions)-revisionHistoryLimit(ad); i++ {
+		if err := r.Delete(ctx, &revisions[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revisionSpec returns the AgentDeployment spec recorded in the revision with its
+// prompt revision, and with spec.imageUpdatePolicy its image, pinned
+func revisionSpec(rev *agentopsv1alpha1.AgentRevision) (*agentopsv1alpha1.AgentDeploymentSpec, error) {
+	spec := &agentopsv1alpha1.AgentDeploymentSpec{}
+	if err := json.Unmarshal(rev.Spec.Template.Raw, spec); err != nil {
+		return nil, fmt.Errorf("decode AgentRevision %s: %w", rev.Name, err)
+	}
+	if spec.PromptTemplateRef != nil && rev.Spec.PromptRevision != 0 {
+		promptRevision := rev.Spec.PromptRevision
+		spec.PromptTemplateRef.Revision = &promptRevision
+	}
+	if spec.ImageUpdatePolicy != "" && rev.Spec.ImageDigest != "" {
+		spec.ImageUpdatePolicy = agentopsv1alpha1.ImageUpdatePolicyPinned
+	}
+	return spec, nil
+}
+
+// rollback restores the AgentRevision named by the rollback-to annotation into the
+// spec and removes the annotation. It reports whether the AgentDeployment was
+// updated; the update triggers the reconcile that rolls the revision out. The
+// prompt and, with spec.imageUpdatePolicy, the image digest of the revision are
+// pinned so the rollback is not undone by a newer prompt revision or tag.
+func (r *AgentDeploymentReconciler) rollback(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (bool, error) {
+	value, ok := ad.Annotations[agentopsv1alpha1.RollbackToAnnotation]
+	if !ok {
+		return false, nil
+	}
+	delete(ad.Annotations, agentopsv1alpha1.RollbackToAnnotation)
+
+	revisions, err := r.agentRevisions(ctx, ad)
+	if err != nil {
+		return false, err
+	}
+	var target *agentopsv1alpha1.AgentRevision
+	if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+		for i := range revisions {
+			if revisions[i].Spec.Revision == number {
+				target = &revisions[i]
+			}
+		}
+	}
+	if target == nil {
+		r.event(ad, corev1.EventTypeWarning, "RollbackFailed", "No revision "+value+" to roll back to")
+		return true, r.Update(ctx, ad)
+	}
+	return true, r.restoreRevision(ctx, ad, target)
+}
+
+// restoreRevision writes the spec recorded in target into the AgentDeployment,
+// keeping its replicas, and updates it
+func (r *AgentDeploymentReconciler) restoreRevision(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, target *agentopsv1alpha1.AgentRevision) error {
+	spec, err := revisionSpec(target)
+	if err != nil {
+		return err
+	}
+	spec.Replicas = ad.Spec.Replicas
+	ad.Spec = *spec
+	if err := r.Update(ctx, ad); err != nil {
+		return err
+	}
+	r.event(ad, corev1.EventTypeNormal, "RolledBack", fmt.Sprintf("Rolled back to revision %d", target.Spec.Revision))
+	r.notify(ctx, ad, notify.Notification{
+		Event:    notify.EventRolledBack,
+		Severity: notify.SeverityWarning,
+		Summary:  fmt.Sprintf("rolled back to revision %d", target.Spec.Revision),
+	})
+
+	// Pinned agents keep the digest recorded for their image
+	if spec.ImageUpdatePolicy == agentopsv1alpha1.ImageUpdatePolicyPinned && target.Spec.ImageDigest != "" {
+		ad.Status.Image, ad.Status.ImageDigest = target.Spec.Image, target.Spec.ImageDigest
+		return patchStatus(ctx, r.Client, ad)
+	}
+	return nil
+}
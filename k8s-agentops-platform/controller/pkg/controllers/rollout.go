@@ -0,0 +1,352 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/hooks"
+)
+
+var (
+	rolloutGVK          = schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"}
+	analysisTemplateGVK = schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "AnalysisTemplate"}
+)
+
+const (
+	// rolloutPodHashLabel is added by Argo Rollouts to the pods of a version and to
+	// the Service selector of a blue-green rollout
+	rolloutPodHashLabel = "rollouts-pod-template-hash"
+
+	// rolloutPodHashArg passes the pod template hash of the new version to the analysis
+	rolloutPodHashArg = "pod-template-hash"
+)
+
+// defaultRolloutSteps are the canary steps without spec.progressiveDelivery.steps
+var defaultRolloutSteps = []agentopsv1alpha1.CanaryStep{
+	{Weight: 20, Pause: &metav1.Duration{Duration: 5 * time.Minute}},
+	{Weight: 50, Pause: &metav1.Duration{Duration: 5 * time.Minute}},
+}
+
+// deliveryEngine returns the engine of spec.progressiveDelivery, or "" without it
+func deliveryEngine(ad *agentopsv1alpha1.AgentDeployment) string {
+	if ad.Spec.ProgressiveDelivery == nil {
+		return ""
+	}
+	if ad.Spec.ProgressiveDelivery.Engine == "" {
+		return agentopsv1alpha1.DeliveryEngineArgoRollouts
+	}
+	return ad.Spec.ProgressiveDelivery.Engine
+}
+
+// rolloutEnabled reports whether Argo Rollouts runs the agent pods instead of a Deployment
+func rolloutEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return deliveryEngine(ad) == agentopsv1alpha1.DeliveryEngineArgoRollouts
+}
+
+// deliverySLI is an objective of a new agent version: query, over the pods of the
+// version, must not return more than max
+type deliverySLI struct {
+	name  string
+	query string
+	max   float64
+}
+
+// deliverySLIs returns the objectives of spec.progressiveDelivery.analysis, measured
+// over the pods matched by the label matchers in pods and a rate window
+func deliverySLIs(analysis *agentopsv1alpha1.DeliveryAnalysisSpec, pods, window string) []deliverySLI {
+	errorPercent := analysis.MaxErrorPercent
+	if errorPercent == 0 {
+		errorPercent = 5
+	}
+	slis := []deliverySLI{{
+		name: "error-rate",
+		query: fmt.Sprintf(`sum(rate(http_requests_total{%s,status=~"5.."}[%s])) / sum(rate(http_requests_total{%s}[%s]))`,
+			pods, window, pods, window),
+		max: float64(errorPercent) / 100,
+	}}
+	if analysis.MaxLatency != nil {
+		slis = append(slis, deliverySLI{
+			name:  "latency",
+			query: fmt.Sprintf(`histogram_quantile(0.95, sum(rate(http_request_duration_seconds_bucket{%s}[%s])) by (le))`, pods, window),
+			max:   analysis.MaxLatency.Seconds(),
+		})
+	}
+	if analysis.MaxTokenLatency != nil {
+		slis = append(slis, deliverySLI{
+			name: "token-latency",
+			query: fmt.Sprintf(`sum(rate(http_request_duration_seconds_sum{%s}[%s])) / sum(rate(agent_tokens_total{%s}[%s]))`,
+				pods, window, pods, window),
+			max: analysis.MaxTokenLatency.Seconds(),
+		})
+	}
+	return slis
+}
+
+// deliveryFailureLimit returns the number of missed measurements a rollout tolerates
+func deliveryFailureLimit(analysis *agentopsv1alpha1.DeliveryAnalysisSpec) int32 {
+	if analysis == nil || analysis.FailureLimit == nil {
+		return 2
+	}
+	return *analysis.FailureLimit
+}
+
+// blueGreenEnabled reports whether the agent is rolled out blue-green
+func blueGreenEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return rolloutEnabled(ad) && ad.Spec.ProgressiveDelivery.Strategy == agentopsv1alpha1.DeliveryStrategyBlueGreen
+}
+
+// workloadRef returns the API version and kind of the object running the agent pods,
+// the scale target of its autoscalers
+func workloadRef(ad *agentopsv1alpha1.AgentDeployment) (string, string) {
+	if rolloutEnabled(ad) {
+		return rolloutGVK.GroupVersion().String(), rolloutGVK.Kind
+	}
+	return appsv1.SchemeGroupVersion.String(), "Deployment"
+}
+
+// analysisTemplateName returns the name of the agent's AnalysisTemplate
+func analysisTemplateName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-analysis"
+}
+
+// rolloutForDeployment renders the agent Deployment as a Rollout: the replicas,
+// selector and pod template carry over, the strategy comes from spec.progressiveDelivery
+func (r *AgentDeploymentReconciler) rolloutForDeployment(ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment) (*unstructured.Unstructured, error) {
+	selector, err := runtime.DefaultUnstructuredConverter.ToUnstructured(dep.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	template, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&dep.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	rollout := newUnstructured(rolloutGVK, dep.Name, dep.Namespace)
+	rollout.SetLabels(dep.Labels)
+	rollout.SetAnnotations(dep.Annotations)
+	rollout.SetOwnerReferences(dep.OwnerReferences)
+	rollout.Object["spec"] = map[string]interface{}{
+		"replicas":        int64(*dep.Spec.Replicas),
+		"selector":        selector,
+		"template":        template,
+		"minReadySeconds": int64(dep.Spec.MinReadySeconds),
+		"strategy":        rolloutStrategy(ad, r.analysisTemplateForAgentDeployment(ad) != nil),
+	}
+	if dep.Spec.ProgressDeadlineSeconds != nil {
+		rollout.Object["spec"].(map[string]interface{})["progressDeadlineSeconds"] = int64(*dep.Spec.ProgressDeadlineSeconds)
+	}
+	return rollout, nil
+}
+
+// rolloutStrategy maps spec.progressiveDelivery to the strategy of the Rollout. Without traffic
+// routing the canary weight is the share of replicas running the new version.
+func rolloutStrategy(ad *agentopsv1alpha1.AgentDeployment, analysis bool) map[string]interface{} {
+	spec := ad.Spec.ProgressiveDelivery
+	var analysisRef map[string]interface{}
+	if analysis {
+		analysisRef = map[string]interface{}{
+			"templates": []interface{}{
+				map[string]interface{}{"templateName": analysisTemplateName(ad)},
+			},
+			"args": []interface{}{
+				map[string]interface{}{
+					"name":      rolloutPodHashArg,
+					"valueFrom": map[string]interface{}{"podTemplateHashValue": "Latest"},
+				},
+			},
+		}
+	}
+
+	if blueGreenEnabled(ad) {
+		blueGreen := map[string]interface{}{
+			"activeService":        ad.Name,
+			"autoPromotionEnabled": spec.AutoPromotionSeconds != nil,
+		}
+		if spec.AutoPromotionSeconds != nil {
+			blueGreen["autoPromotionSeconds"] = int64(*spec.AutoPromotionSeconds)
+		}
+		if analysisRef != nil {
+			blueGreen["prePromotionAnalysis"] = analysisRef
+		}
+		return map[string]interface{}{"blueGreen": blueGreen}
+	}
+
+	steps := spec.Steps
+	if len(steps) == 0 {
+		steps = defaultRolloutSteps
+	}
+	var canarySteps []interface{}
+	for _, step := range steps {
+		pause := map[string]interface{}{}
+		if step.Pause != nil {
+			pause["duration"] = int64(step.Pause.Seconds())
+		}
+		canarySteps = append(canarySteps,
+			map[string]interface{}{"setWeight": int64(step.Weight)},
+			map[string]interface{}{"pause": pause},
+		)
+	}
+	canary := map[string]interface{}{"steps": canarySteps}
+	if analysisRef != nil {
+		canary["analysis"] = analysisRef
+	}
+	return map[string]interface{}{"canary": canary}
+}
+
+// analysisTemplateForAgentDeployment renders spec.progressiveDelivery.analysis into an
+// AnalysisTemplate measuring the pods of the new version, or returns nil when no
+// analysis is configured or no Prometheus is known
+func (r *AgentDeploymentReconciler) analysisTemplateForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) *unstructured.Unstructured {
+	if !rolloutEnabled(ad) || ad.Spec.ProgressiveDelivery.Analysis == nil || r.PrometheusAddress == "" {
+		return nil
+	}
+	analysis := ad.Spec.ProgressiveDelivery.Analysis
+	pods := fmt.Sprintf(`namespace=%q,pod=~"%s-{{args.%s}}-[a-z0-9]+"`, ad.Namespace, ad.Name, rolloutPodHashArg)
+	var metrics []interface{}
+	for _, sli := range deliverySLIs(analysis, pods, "5m") {
+		// Versions without traffic yet return NaN and pass
+		metrics = append(metrics, map[string]interface{}{
+			"name":             sli.name,
+			"interval":         durationOrDefault(analysis.Interval, time.Minute),
+			"failureLimit":     int64(deliveryFailureLimit(analysis)),
+			"successCondition": fmt.Sprintf("isNaN(result[0]) || result[0] <= %g", sli.max),
+			"provider": map[string]interface{}{
+				"prometheus": map[string]interface{}{
+					"address": r.PrometheusAddress,
+					"query":   sli.query,
+				},
+			},
+		})
+	}
+
+	tmpl := newUnstructured(analysisTemplateGVK, analysisTemplateName(ad), ad.Namespace)
+	tmpl.SetLabels(labelsForAgentDeployment(ad.Name))
+	tmpl.Object["spec"] = map[string]interface{}{
+		"args":    []interface{}{map[string]interface{}{"name": rolloutPodHashArg}},
+		"metrics": metrics,
+	}
+	return tmpl
+}
+
+// reconcileAnalysisTemplate creates or updates the agent's AnalysisTemplate, or
+// removes it when no analysis is configured
+func (r *AgentDeploymentReconciler) reconcileAnalysisTemplate(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	if desired := r.analysisTemplateForAgentDeployment(ad); desired != nil {
+		return r.reconcileUnstructured(ctx, ad, desired)
+	}
+	return r.deleteOwnedUnstructured(ctx, ad, analysisTemplateGVK, analysisTemplateName(ad))
+}
+
+// getRollout reads the agent's Rollout as a Deployment, so replica handling, status
+// and rollouts of the pod template work the same for both
+func (r *AgentDeploymentReconciler) getRollout(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (*appsv1.Deployment, error) {
+	rollout := newUnstructured(rolloutGVK, ad.Name, ad.Namespace)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(rollout), rollout); err != nil {
+		return nil, err
+	}
+
+	content := runtime.DeepCopyJSON(rollout.Object)
+	unstructured.RemoveNestedField(content, "spec", "strategy")
+	// Of the status only the conditions share the Deployment's shape; an aborted
+	// rollout is Progressing=False with reason RolloutAborted
+	delete(content, "status")
+	if conds, found, _ := unstructured.NestedSlice(rollout.Object, "status", "conditions"); found {
+		content["status"] = map[string]interface{}{"conditions": conds}
+	}
+	dep := &appsv1.Deployment{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, dep); err != nil {
+		return nil, fmt.Errorf("read Rollout %s: %w", ad.Name, err)
+	}
+	dep.TypeMeta = metav1.TypeMeta{}
+
+	status := func(field string) int32 {
+		v, _, _ := unstructured.NestedInt64(rollout.Object, "status", field)
+		return int32(v)
+	}
+	dep.Status.Replicas = status("replicas")
+	dep.Status.ReadyReplicas = status("readyReplicas")
+	dep.Status.AvailableReplicas = status("availableReplicas")
+	dep.Status.UpdatedReplicas = status("updatedReplicas")
+	// Rollouts report the observed generation as a string
+	observed, _, _ := unstructured.NestedString(rollout.Object, "status", "observedGeneration")
+	if generation, err := strconv.ParseInt(observed, 10, 64); err == nil {
+		dep.Status.ObservedGeneration = generation
+	}
+	return dep, nil
+}
+
+// updateRollout brings an existing Rollout in line with the AgentDeployment spec
+func (r *AgentDeploymentReconciler) updateRollout(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment, managedAutoscaler bool, templatePatch []byte, overlays []deploymentOverlay) error {
+	changed, err := r.syncDeployment(ctx, ad, dep, managedAutoscaler, templatePatch, overlays)
+	if err != nil {
+		return err
+	}
+	desired, err := r.rolloutForDeployment(ad, dep)
+	if err != nil {
+		return err
+	}
+	live := newUnstructured(rolloutGVK, ad.Name, ad.Namespace)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(live), live); err != nil {
+		return err
+	}
+	strategy, _, _ := unstructured.NestedMap(live.Object, "spec", "strategy")
+	if !changed && equality.Semantic.DeepEqual(strategy, desired.Object["spec"].(map[string]interface{})["strategy"]) {
+		return nil
+	}
+
+	r.Log.Info("Updating Rollout", "Rollout.Namespace", live.GetNamespace(), "Rollout.Name", live.GetName())
+	live.SetAnnotations(desired.GetAnnotations())
+	live.Object["spec"] = desired.Object["spec"]
+	if err := r.runHooks(ctx, ad, hooks.PreApply, "update", live); err != nil {
+		return err
+	}
+	if err := r.Update(ctx, live); err != nil {
+		return err
+	}
+	r.runHooks(ctx, ad, hooks.PostApply, "update", live)
+	return nil
+}
+
+// handOverWorkload removes the Deployment after switching to a Rollout, or the
+// Rollout after switching back, once the workload now running the agent has all its
+// replicas available, so the agent keeps serving during the switch
+func (r *AgentDeploymentReconciler) handOverWorkload(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, current *appsv1.Deployment) error {
+	if current.Spec.Replicas == nil || current.Status.AvailableReplicas < *current.Spec.Replicas {
+		return nil
+	}
+	if !rolloutEnabled(ad) {
+		if err := r.deleteOwnedUnstructured(ctx, ad, rolloutGVK, ad.Name); err != nil {
+			return err
+		}
+		return r.deleteOwnedUnstructured(ctx, ad, analysisTemplateGVK, analysisTemplateName(ad))
+	}
+
+	dep := &appsv1.Deployment{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(current), dep); err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+	if !metav1.IsControlledBy(dep, ad) {
+		return nil
+	}
+	r.Log.Info("Deleting Deployment replaced by Rollout", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
+	if err := r.Delete(ctx, dep); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/activator"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	defaultIdleTimeout = 15 * time.Minute
+
+	// idleCheckInterval is how often agents with scale-to-zero are checked for traffic
+	idleCheckInterval = time.Minute
+)
+
+// ActivitySource reports how many requests an agent Service received over a window
+type ActivitySource interface {
+	Requests(ctx context.Context, namespace, service string, window time.Duration) (float64, error)
+}
+
+// scaleToZeroEnabled reports whether the agent scales down when idle
+func scaleToZeroEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.ScaleToZero != nil && ad.Spec.ScaleToZero.Enabled
+}
+
+// idleTimeout returns the idle period after which the agent is scaled to zero
+func idleTimeout(ad *agentopsv1alpha1.AgentDeployment) time.Duration {
+	if ad.Spec.ScaleToZero == nil || ad.Spec.ScaleToZero.IdleTimeout == nil {
+		return defaultIdleTimeout
+	}
+	return ad.Spec.ScaleToZero.IdleTimeout.Duration
+}
+
+// agentIdle reports whether a scale-to-zero agent received no requests for its idle
+// timeout
+func (r *AgentDeploymentReconciler) agentIdle(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) bool {
+	if !scaleToZeroEnabled(ad) {
+		return false
+	}
+	return r.idleFor(ctx, ad, idleTimeout(ad))
+}
+
+// idleFor reports whether the agent received no requests for timeout. Agents whose
+// traffic cannot be read are never idle.
+func (r *AgentDeploymentReconciler) idleFor(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, timeout time.Duration) bool {
+	idle, err := agentIdleFor(ctx, r.Activity, ad, timeout)
+	if err != nil {
+		r.Log.Error(err, "Failed to read agent traffic; keeping it scaled up", "AgentDeployment", ad.Name, "Namespace", ad.Namespace)
+		return false
+	}
+	return idle
+}
+
+// agentIdleFor reports whether the agent received no requests for timeout. Agents
+// younger than the timeout or recently activated are never idle, nor are any
+// without an activity source.
+func agentIdleFor(ctx context.Context, activity ActivitySource, ad *agentopsv1alpha1.AgentDeployment, timeout time.Duration) (bool, error) {
+	if activity == nil {
+		return false, nil
+	}
+	if time.Since(ad.CreationTimestamp.Time) < timeout {
+		return false, nil
+	}
+	if activated, err := time.Parse(time.RFC3339, ad.Annotations[activator.ActivatedAtAnnotation]); err == nil && time.Since(activated) < timeout {
+		return false, nil
+	}
+	requests, err := activity.Requests(ctx, ad.Namespace, ad.Name, timeout)
+	if err != nil {
+		return false, err
+	}
+	return requests == 0, nil
+}
+
+// scaleToZeroOverlay scales the Deployment to zero while the agent is idle
+func scaleToZeroOverlay(idle bool) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		if idle {
+			zero := int32(0)
+			dep.Spec.Replicas = &zero
+		}
+	}
+}
+
+// routesToActivator reports whether the agent Service should send traffic to the
+// activator: scale-to-zero is enabled and the agent has no ready pods
+func (r *AgentDeploymentReconciler) routesToActivator(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (bool, error) {
+	if !scaleToZeroEnabled(ad) || r.ActivatorService.Name == "" {
+		return false, nil
+	}
+	dep := &appsv1.Deployment{}
+	var err error
+	if rolloutEnabled(ad) {
+		dep, err = r.getRollout(ctx, ad)
+	} else {
+		err = r.Get(ctx, types.NamespacedName{Name: ad.Name, Namespace: ad.Namespace}, dep)
+	}
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return dep.Status.ReadyReplicas == 0, nil
+}
+
+// reconcileActivatorEndpoints points the selector-less agent Service at the activator
+// pods by copying the addresses of the activator Service's Endpoints
+func (r *AgentDeploymentReconciler) reconcileActivatorEndpoints(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	source := &corev1.Endpoints{}
+	if err := r.Get(ctx, r.ActivatorService, source); err != nil {
+		return err
+	}
+	var subsets []corev1.EndpointSubset
+	for _, s := range source.Subsets {
+		for _, p := range s.Ports {
+			if p.Name != "http" {
+				continue
+			}
+			subsets = append(subsets, corev1.EndpointSubset{
+				Addresses: s.Addresses,
+				Ports:     []corev1.EndpointPort{{Name: "http", Port: p.Port, Protocol: corev1.ProtocolTCP}},
+			})
+		}
+	}
+
+	ep := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: ad.Name, Namespace: ad.Namespace},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, ep, func() error {
+		ep.Labels = labelsForAgentDeployment(ad.Name)
+		ep.Subsets = subsets
+		return controllerutil.SetControllerReference(ad, ep, r.Scheme)
+	})
+	return err
+}
@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	defaultReplicas             = int32(2)
+	defaultMinReplicas          = int32(2)
+	defaultMaxReplicas          = int32(10)
+	defaultCPUTargetUtilization = int32(70)
+	appliedReplicasAnnotation   = "agentops.io/applied-replicas"
+)
+
+// autoscalingEnabled reports whether spec.autoscaling asks for an autoscaler
+func autoscalingEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.Autoscaling != nil && ad.Spec.Autoscaling.Enabled
+}
+
+// autoscalingBounds returns the minimum and maximum replicas of the agent's autoscaler
+func autoscalingBounds(ad *agentopsv1alpha1.AgentDeployment) (int32, int32) {
+	minReplicas, maxReplicas := defaultMinReplicas, defaultMaxReplicas
+	if ad.Spec.Autoscaling.MinReplicas != nil {
+		minReplicas = *ad.Spec.Autoscaling.MinReplicas
+	}
+	if ad.Spec.Autoscaling.MaxReplicas != nil {
+		maxReplicas = *ad.Spec.Autoscaling.MaxReplicas
+	}
+	if maxReplicas < minReplicas {
+		maxReplicas = minReplicas
+	}
+	return minReplicas, maxReplicas
+}
+
+// clampReplicas keeps replicas within the autoscaling bounds
+func clampReplicas(ad *agentopsv1alpha1.AgentDeployment, replicas int32) int32 {
+	minReplicas, maxReplicas := autoscalingBounds(ad)
+	switch {
+	case replicas < minReplicas:
+		return minReplicas
+	case replicas > maxReplicas:
+		return maxReplicas
+	}
+	return replicas
+}
+
+// specReplicas returns spec.replicas, which is also what the scale subresource writes
+func specReplicas(ad *agentopsv1alpha1.AgentDeployment) int32 {
+	if ad.Spec.Replicas == nil {
+		return defaultReplicas
+	}
+	return *ad.Spec.Replicas
+}
+
+// replicasForDeployment returns the replica count of the agent Deployment.
+//
+// Without a controller-managed autoscaler spec.replicas is authoritative, whether it
+// is edited directly or through the scale subresource (kubectl scale, or an HPA that
+// targets the AgentDeployment). With one, the HPA owns the Deployment's replica
+// count: a change of spec.replicas is applied once, clamped to the autoscaling
+// bounds, and the HPA takes over from there. The last applied value is recorded on
+// the Deployment so that unchanged specs never undo the HPA's decisions.
+func replicasForDeployment(ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment, managedAutoscaler bool) int32 {
+	// Flagger scales the Deployment while it analyses a new version
+	if flaggerEnabled(ad) && dep != nil && dep.Spec.Replicas != nil {
+		return *dep.Spec.Replicas
+	}
+	want := specReplicas(ad)
+	if !managedAutoscaler {
+		return want
+	}
+	if dep == nil || dep.Spec.Replicas == nil || dep.Annotations[appliedReplicasAnnotation] != strconv.Itoa(int(want)) {
+		return clampReplicas(ad, want)
+	}
+	// Scale-to-zero and budgets park the Deployment at zero; resume at the minimum
+	return clampReplicas(ad, *dep.Spec.Replicas)
+}
+
+// externalAutoscaler returns the name of a HorizontalPodAutoscaler that targets the
+// AgentDeployment itself through its scale subresource, or "" if there is none
+func (r *AgentDeploymentReconciler) externalAutoscaler(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (string, error) {
+	list := &autoscalingv2.HorizontalPodAutoscalerList{}
+	if err := r.List(ctx, list, client.InNamespace(ad.Namespace)); err != nil {
+		return "", err
+	}
+	for _, hpa := range list.Items {
+		ref := hpa.Spec.ScaleTargetRef
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			continue
+		}
+		if gv.Group == agentopsv1alpha1.GroupVersion.Group && ref.Kind == "AgentDeployment" && ref.Name == ad.Name {
+			return hpa.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// managedAutoscaler reports whether the controller runs an HPA for the agent's
+// Deployment. An HPA targeting the AgentDeployment wins over spec.autoscaling: two
+// autoscalers would fight over the replica count, so the controller then only
+// propagates spec.replicas.
+func (r *AgentDeploymentReconciler) managedAutoscaler(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (bool, error) {
+	if !autoscalingEnabled(ad) {
+		return false, nil
+	}
+	external, err := r.externalAutoscaler(ctx, ad)
+	if err != nil {
+		return false, err
+	}
+	if external != "" {
+		r.Log.Info("HorizontalPodAutoscaler targets the AgentDeployment; ignoring spec.autoscaling",
+			"AgentDeployment", ad.Name, "Namespace", ad.Namespace, "HorizontalPodAutoscaler", external)
+		return false, nil
+	}
+	return true, nil
+}
+
+// autoscalingMetrics returns the HPA metrics of the agent, scaling on CPU when none
+// are configured, on GPU utilization with spec.autoscaling.gpu, and on the depth of
+// its queue with spec.queue.targetDepth
+func autoscalingMetrics(ad *agentopsv1alpha1.AgentDeployment) ([]autoscalingv2.MetricSpec, error) {
+	var metrics []autoscalingv2.MetricSpec
+	if len(ad.Spec.Autoscaling.Metrics) > 0 {
+		raw, err := json.Marshal(ad.Spec.Autoscaling.Metrics)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &metrics); err != nil {
+			return nil, fmt.Errorf("invalid autoscaling metrics: %w", err)
+		}
+	}
+	gpuMetrics, err := gpuAutoscalingMetrics(ad)
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) == 0 && len(gpuMetrics) == 0 {
+		target := defaultCPUTargetUtilization
+		metrics = []autoscalingv2.MetricSpec{{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: corev1.ResourceCPU,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: &target,
+				},
+			},
+		}}
+	}
+	metrics = append(metrics, gpuMetrics...)
+	if m := queueDepthMetricSpec(ad); m != nil {
+		metrics = append(metrics, *m)
+	}
+	return metrics, nil
+}
+
+// reconcileHPA keeps a HorizontalPodAutoscaler on the agent Deployment while the
+// controller manages autoscaling, and removes it otherwise. While the agent is
+// hibernated the HPA may scale down to the hibernation replicas.
+func (r *AgentDeploymentReconciler) reconcileHPA(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, managed, hibernated bool) error {
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: ad.Name, Namespace: ad.Namespace},
+	}
+	if !managed {
+		// Never delete an HPA of the same name the user created for the AgentDeployment
+		if err := r.Get(ctx, client.ObjectKeyFromObject(hpa), hpa); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if !metav1.IsControlledBy(hpa, ad) {
+			return nil
+		}
+		if err := r.Delete(ctx, hpa); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	metrics, err := autoscalingMetrics(ad)
+	if err != nil {
+		return err
+	}
+	minReplicas, maxReplicas := autoscalingBounds(ad)
+	if hibernated && hibernationReplicas(ad) > 0 && hibernationReplicas(ad) < minReplicas {
+		minReplicas = hibernationReplicas(ad)
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, hpa, func() error {
+		hpa.Labels = labelsForAgentDeployment(ad.Name)
+		apiVersion, kind := workloadRef(ad)
+		hpa.Spec.ScaleTargetRef = autoscalingv2.CrossVersionObjectReference{
+			APIVersion: apiVersion,
+			Kind:       kind,
+			Name:       ad.Name,
+		}
+		hpa.Spec.MinReplicas = &minReplicas
+		hpa.Spec.MaxReplicas = maxReplicas
+		hpa.Spec.Metrics = metrics
+		return controllerutil.SetControllerReference(ad, hpa, r.Scheme)
+	})
+	return err
+}
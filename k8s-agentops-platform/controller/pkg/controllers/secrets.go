@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	vaultSecretsDir     = "/vault/secrets"
+	vaultAnnotationBase = "vault.hashicorp.com/"
+	defaultVaultField   = "value"
+
+	// secretFilesDir is where secrets delivered as files are mounted by default
+	secretFilesDir = "/var/run/secrets/agentops/secrets"
+
+	// secretFilesVolume holds the secret files at secretFilesDir, and
+	// secretFileMountsVolume those with a mountPath of their own
+	secretFilesVolume      = "secret-files"
+	secretFileMountsVolume = "secret-file-mounts"
+
+	// apiKeysChecksumAnnotation on the pod template rolls the pods when the key broker
+	// rotates one of their keys
+	apiKeysChecksumAnnotation = "agentops.io/api-keys-checksum"
+)
+
+// secretEnv returns the environment variables for an agent's secrets.
+// Native and external secrets are referenced directly (External Secrets syncs into a
+// Secret of the same name); Vault secrets are rendered to files by the injector, and
+// secrets with as: file are mounted by secretFileVolumes, so for those the agent gets
+// a <KEY>_FILE variable pointing at the file.
+func secretEnv(secrets []agentopsv1alpha1.SecretReference) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	for _, s := range secrets {
+		switch {
+		case secretProvider(s) == agentopsv1alpha1.SecretProviderVault:
+			env = append(env, corev1.EnvVar{
+				Name:  s.Key + "_FILE",
+				Value: path.Join(vaultSecretsDir, vaultFileName(s)),
+			})
+		case secretAsFile(s):
+			env = append(env, corev1.EnvVar{
+				Name:  s.Key + "_FILE",
+				Value: secretFilePath(s),
+			})
+		default:
+			env = append(env, corev1.EnvVar{
+				Name: s.Key,
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: s.Name},
+						Key:                  s.Key,
+					},
+				},
+			})
+		}
+	}
+	return env
+}
+
+// secretAsFile reports whether a secret is mounted as a file by secretFileVolumes
+func secretAsFile(s agentopsv1alpha1.SecretReference) bool {
+	return s.As == agentopsv1alpha1.SecretAsFile && secretProvider(s) != agentopsv1alpha1.SecretProviderVault
+}
+
+// secretFilePath returns the path of the file a secret is mounted at
+func secretFilePath(s agentopsv1alpha1.SecretReference) string {
+	if s.MountPath != "" {
+		return path.Clean(s.MountPath)
+	}
+	return path.Join(secretFilesDir, s.Name, s.Key)
+}
+
+// secretFileVolumes returns the read-only volumes and mounts of the secrets with
+// as: file. Files at the default location share a projected volume mounted at
+// secretFilesDir, so they follow updates of their Secrets. Files with a mountPath
+// are mounted one by one with subPath, which leaves the rest of their directory in
+// place but is not updated when the Secret changes.
+func secretFileVolumes(secrets []agentopsv1alpha1.SecretReference) ([]corev1.Volume, []corev1.VolumeMount, error) {
+	var defaults, explicit []corev1.VolumeProjection
+	var mounts []corev1.VolumeMount
+	seen := map[string]agentopsv1alpha1.SecretReference{}
+	for _, s := range secrets {
+		if !secretAsFile(s) {
+			continue
+		}
+		file := secretFilePath(s)
+		if other, ok := seen[file]; ok {
+			if other.Name == s.Name && other.Key == s.Key {
+				continue
+			}
+			return nil, nil, fmt.Errorf("secrets %s/%s and %s/%s are both mounted at %s", other.Name, other.Key, s.Name, s.Key, file)
+		}
+		seen[file] = s
+
+		if s.MountPath == "" {
+			defaults = append(defaults, corev1.VolumeProjection{Secret: &corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: s.Name},
+				Items:                []corev1.KeyToPath{{Key: s.Key, Path: path.Join(s.Name, s.Key)}},
+			}})
+			continue
+		}
+		if file == "/" || file == secretFilesDir || strings.HasPrefix(file, secretFilesDir+"/") {
+			return nil, nil, fmt.Errorf("secret %s/%s: mountPath %s is reserved", s.Name, s.Key, file)
+		}
+		item := strconv.Itoa(len(explicit))
+		explicit = append(explicit, corev1.VolumeProjection{Secret: &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: s.Name},
+			Items:                []corev1.KeyToPath{{Key: s.Key, Path: item}},
+		}})
+		mounts = append(mounts, corev1.VolumeMount{Name: secretFileMountsVolume, MountPath: file, SubPath: item, ReadOnly: true})
+	}
+
+	var volumes []corev1.Volume
+	if len(defaults) > 0 {
+		volumes = append(volumes, corev1.Volume{
+			Name:         secretFilesVolume,
+			VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: defaults}},
+		})
+		mounts = append([]corev1.VolumeMount{{Name: secretFilesVolume, MountPath: secretFilesDir, ReadOnly: true}}, mounts...)
+	}
+	if len(explicit) > 0 {
+		volumes = append(volumes, corev1.Volume{
+			Name:         secretFileMountsVolume,
+			VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: explicit}},
+		})
+	}
+	return volumes, mounts, nil
+}
+
+// vaultAnnotations returns the Vault Agent injector annotations for the pod template,
+// or nil when no secret uses the vault provider. The injector authenticates a pod
+// with a single role, so vault secrets naming different roles are rejected.
+func vaultAnnotations(secrets []agentopsv1alpha1.SecretReference) (map[string]string, error) {
+	var annotations map[string]string
+	var first agentopsv1alpha1.SecretReference
+	for _, s := range secrets {
+		if secretProvider(s) != agentopsv1alpha1.SecretProviderVault {
+			continue
+		}
+		if s.Vault == nil {
+			return nil, fmt.Errorf("secret %s: provider vault requires vault", s.Name)
+		}
+		if annotations == nil {
+			first = s
+			annotations = map[string]string{
+				vaultAnnotationBase + "agent-inject": "true",
+				vaultAnnotationBase + "role":         s.Vault.Role,
+			}
+		} else if s.Vault.Role != first.Vault.Role {
+			return nil, fmt.Errorf("secrets %s and %s use Vault roles %q and %q; a pod authenticates with a single role",
+				first.Name, s.Name, first.Vault.Role, s.Vault.Role)
+		}
+		field := s.Vault.Field
+		if field == "" {
+			field = defaultVaultField
+		}
+		file := vaultFileName(s)
+		annotations[vaultAnnotationBase+"agent-inject-secret-"+file] = s.Vault.Path
+		annotations[vaultAnnotationBase+"agent-inject-template-"+file] = fmt.Sprintf(
+			`{{- with secret %q -}}{{ .Data.data.%s }}{{- end -}}`, s.Vault.Path, field)
+	}
+	return annotations, nil
+}
+
+// vaultFileName returns the name of the file the injector renders a vault secret to.
+// It names the key as well, as several keys may come from secrets of the same name,
+// and only holds characters valid in annotation names.
+func vaultFileName(s agentopsv1alpha1.SecretReference) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '-'
+	}, s.Name+"-"+s.Key)
+}
+
+// secretProvider returns the provider of a secret reference, defaulting to native
+func secretProvider(s agentopsv1alpha1.SecretReference) string {
+	if s.Provider == "" {
+		return agentopsv1alpha1.SecretProviderNative
+	}
+	return s.Provider
+}
+
+// apiKeysChecksum returns a checksum of the keys issued by the key broker for the
+// agent, or "" when it has none
+func (r *AgentDeploymentReconciler) apiKeysChecksum(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (string, error) {
+	h := sha256.New()
+	found := false
+	for _, s := range ad.Spec.Secrets {
+		if secretProvider(s) != agentopsv1alpha1.SecretProviderBroker {
+			continue
+		}
+		found = true
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: s.Name, Namespace: ad.Namespace}, secret)
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s/%s=%s\n", s.Name, s.Key, secret.Data[s.Key])
+	}
+	if !found {
+		return "", nil
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// apiKeysOverlay sets the checksum of the agent's broker keys on the pod template
+func apiKeysOverlay(checksum string) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		if checksum == "" {
+			return
+		}
+		pod := &dep.Spec.Template
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[apiKeysChecksumAnnotation] = checksum
+	}
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"testing"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+func TestVaultSecretsOfSameName(t *testing.T) {
+	secrets := []agentopsv1alpha1.SecretReference{
+		{Name: "llm", Key: "ANTHROPIC_API_KEY", Provider: agentopsv1alpha1.SecretProviderVault,
+			Vault: &agentopsv1alpha1.VaultSecretSource{Path: "secret/data/llm/anthropic", Role: "agent"}},
+		{Name: "llm", Key: "OPENAI_API_KEY", Provider: agentopsv1alpha1.SecretProviderVault,
+			Vault: &agentopsv1alpha1.VaultSecretSource{Path: "secret/data/llm/openai", Role: "agent"}},
+	}
+
+	annotations, err := vaultAnnotations(secrets)
+	if err != nil {
+		t.Fatalf("vaultAnnotations() error = %v", err)
+	}
+	for file, vaultPath := range map[string]string{
+		"llm-ANTHROPIC_API_KEY": "secret/data/llm/anthropic",
+		"llm-OPENAI_API_KEY":    "secret/data/llm/openai",
+	} {
+		if got := annotations[vaultAnnotationBase+"agent-inject-secret-"+file]; got != vaultPath {
+			t.Errorf("agent-inject-secret-%s = %q, want %q", file, got, vaultPath)
+		}
+	}
+
+	files := map[string]string{}
+	for _, env := range secretEnv(secrets) {
+		files[env.Name] = env.Value
+	}
+	want := map[string]string{
+		"ANTHROPIC_API_KEY_FILE": "/vault/secrets/llm-ANTHROPIC_API_KEY",
+		"OPENAI_API_KEY_FILE":    "/vault/secrets/llm-OPENAI_API_KEY",
+	}
+	for name, file := range want {
+		if files[name] != file {
+			t.Errorf("%s = %q, want %q", name, files[name], file)
+		}
+	}
+}
+
+func TestVaultAnnotationsRejectInvalidSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []agentopsv1alpha1.SecretReference
+	}{
+		{
+			name: "missing vault",
+			secrets: []agentopsv1alpha1.SecretReference{
+				{Name: "llm", Key: "API_KEY", Provider: agentopsv1alpha1.SecretProviderVault},
+			},
+		},
+		{
+			name: "conflicting roles",
+			secrets: []agentopsv1alpha1.SecretReference{
+				{Name: "a", Key: "A", Provider: agentopsv1alpha1.SecretProviderVault,
+					Vault: &agentopsv1alpha1.VaultSecretSource{Path: "secret/data/a", Role: "one"}},
+				{Name: "b", Key: "B", Provider: agentopsv1alpha1.SecretProviderVault,
+					Vault: &agentopsv1alpha1.VaultSecretSource{Path: "secret/data/b", Role: "two"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := vaultAnnotations(tt.secrets); err == nil {
+				t.Error("vaultAnnotations() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestSecretFileVolumes(t *testing.T) {
+	file := func(name, key, mountPath string) agentopsv1alpha1.SecretReference {
+		return agentopsv1alpha1.SecretReference{Name: name, Key: key, As: agentopsv1alpha1.SecretAsFile, MountPath: mountPath}
+	}
+
+	t.Run("default location shares one volume", func(t *testing.T) {
+		volumes, mounts, err := secretFileVolumes([]agentopsv1alpha1.SecretReference{
+			file("anthropic", "API_KEY", ""),
+			file("openai", "API_KEY", ""),
+			{Name: "env", Key: "TOKEN"},
+		})
+		if err != nil {
+			t.Fatalf("secretFileVolumes() error = %v", err)
+		}
+		if len(volumes) != 1 || volumes[0].Name != secretFilesVolume || len(volumes[0].Projected.Sources) != 2 {
+			t.Fatalf("volumes = %+v, want one %s volume with two sources", volumes, secretFilesVolume)
+		}
+		if got := volumes[0].Projected.Sources[1].Secret.Items[0].Path; got != "openai/API_KEY" {
+			t.Errorf("projected path = %q, want openai/API_KEY", got)
+		}
+		if len(mounts) != 1 || mounts[0].MountPath != secretFilesDir || mounts[0].SubPath != "" || !mounts[0].ReadOnly {
+			t.Errorf("mounts = %+v, want one read-only mount at %s", mounts, secretFilesDir)
+		}
+	})
+
+	t.Run("mountPath is mounted as a single file", func(t *testing.T) {
+		volumes, mounts, err := secretFileVolumes([]agentopsv1alpha1.SecretReference{
+			file("ca", "ca.pem", "/etc/ssl/certs/ca.pem"),
+			file("token", "TOKEN", "/token"),
+		})
+		if err != nil {
+			t.Fatalf("secretFileVolumes() error = %v", err)
+		}
+		if len(volumes) != 1 || volumes[0].Name != secretFileMountsVolume {
+			t.Fatalf("volumes = %+v, want one %s volume", volumes, secretFileMountsVolume)
+		}
+		want := map[string]string{"/etc/ssl/certs/ca.pem": "0", "/token": "1"}
+		if len(mounts) != len(want) {
+			t.Fatalf("mounts = %+v, want %d", mounts, len(want))
+		}
+		for _, m := range mounts {
+			if m.Name != secretFileMountsVolume || m.SubPath != want[m.MountPath] || !m.ReadOnly {
+				t.Errorf("mount %+v, want read-only subPath %q of %s", m, want[m.MountPath], secretFileMountsVolume)
+			}
+		}
+	})
+
+	t.Run("default and mountPath together", func(t *testing.T) {
+		volumes, mounts, err := secretFileVolumes([]agentopsv1alpha1.SecretReference{
+			file("anthropic", "API_KEY", ""),
+			file("ca", "ca.pem", "/etc/ssl/certs/ca.pem"),
+		})
+		if err != nil {
+			t.Fatalf("secretFileVolumes() error = %v", err)
+		}
+		if len(volumes) != 2 || len(mounts) != 2 {
+			t.Errorf("got %d volumes and %d mounts, want 2 and 2", len(volumes), len(mounts))
+		}
+	})
+
+	t.Run("same secret twice is mounted once", func(t *testing.T) {
+		_, mounts, err := secretFileVolumes([]agentopsv1alpha1.SecretReference{
+			file("ca", "ca.pem", "/etc/agent/ca.pem"),
+			file("ca", "ca.pem", "/etc/agent/ca.pem"),
+		})
+		if err != nil {
+			t.Fatalf("secretFileVolumes() error = %v", err)
+		}
+		if len(mounts) != 1 {
+			t.Errorf("mounts = %+v, want one", mounts)
+		}
+	})
+
+	for _, tt := range []struct {
+		name    string
+		secrets []agentopsv1alpha1.SecretReference
+	}{
+		{"duplicate mountPath", []agentopsv1alpha1.SecretReference{
+			file("a", "KEY", "/etc/agent/key"),
+			file("b", "KEY", "/etc/agent/key"),
+		}},
+		{"root", []agentopsv1alpha1.SecretReference{file("a", "KEY", "/")}},
+		{"inside default directory", []agentopsv1alpha1.SecretReference{file("a", "KEY", secretFilesDir+"/a/KEY")}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := secretFileVolumes(tt.secrets); err == nil {
+				t.Error("secretFileVolumes() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// agentServicePort is the port the agent Service exposes inside the cluster
+const agentServicePort = 80
+
+// reconcileService creates or updates the ClusterIP Service in front of the agent pods.
+// While a scale-to-zero agent has no ready pods the Service drops its selector and
+// its Endpoints point at the activator instead.
+func (r *AgentDeploymentReconciler) reconcileService(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	toActivator, err := r.routesToActivator(ctx, ad)
+	if err != nil {
+		return err
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: ad.Name, Namespace: ad.Namespace},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		svc.Labels = labelsForAgentDeployment(ad.Name)
+		selector := labelsForAgentDeployment(ad.Name)
+		// Argo Rollouts points the Service at the active version of a blue-green rollout
+		if hash := svc.Spec.Selector[rolloutPodHashLabel]; hash != "" && blueGreenEnabled(ad) {
+			selector[rolloutPodHashLabel] = hash
+		}
+		// Flagger points the Service at its primary pods
+		if flaggerEnabled(ad) && svc.Spec.Selector != nil {
+			selector = svc.Spec.Selector
+		}
+		svc.Spec.Selector = selector
+		if toActivator {
+			svc.Spec.Selector = nil
+		}
+		svc.Spec.Ports = servicePortsForAgentDeployment(ad)
+		return controllerutil.SetControllerReference(ad, svc, r.Scheme)
+	})
+	if err != nil || !toActivator {
+		return err
+	}
+	return r.reconcileActivatorEndpoints(ctx, ad)
+}
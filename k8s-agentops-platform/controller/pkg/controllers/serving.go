@@ -0,0 +1,581 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/catalog"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/health"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/providers"
+)
+
+const (
+	weightsVolumeName      = "weights"
+	weightsDir             = "/models"
+	sharedMemoryVolumeName = "dshm"
+	defaultGPUResource     = corev1.ResourceName("nvidia.com/gpu")
+	s3SyncImage            = "amazon/aws-cli:2.15.0"
+	gcsSyncImage           = "google/cloud-sdk:470.0.0-slim"
+)
+
+var (
+	defaultSharedMemory = resource.MustParse("16Gi")
+	defaultGPUMemory    = resource.MustParse("80Gi")
+)
+
+// gpuMemoryUtilization is the share of GPU memory the runtimes fill with weights and
+// attention cache by default
+const gpuMemoryUtilization = 0.9
+
+// modelServerEnabled reports whether the model is served by a separate StatefulSet
+func modelServerEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.Serving != nil && ad.Spec.Serving.SelfHosted != nil
+}
+
+// modelServerName returns the name of the model server StatefulSet and Service
+func modelServerName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-model"
+}
+
+// modelServerProvider returns the runtime of the model server; only vllm and tgi
+// can be served separately
+func modelServerProvider(ad *agentopsv1alpha1.AgentDeployment) (providers.Provider, error) {
+	p, err := providerForAgentDeployment(ad)
+	if err != nil {
+		return providers.Provider{}, err
+	}
+	if p.Name != providers.VLLM && p.Name != providers.TGI {
+		return providers.Provider{}, fmt.Errorf("serving.selfHosted requires provider vllm or tgi, got %s", p.Name)
+	}
+	return p, nil
+}
+
+// modelServerModelID returns the model the server loads and serves under
+func modelServerModelID(ad *agentopsv1alpha1.AgentDeployment, p providers.Provider) string {
+	if id := ad.Spec.Serving.SelfHosted.ModelID; id != "" {
+		return id
+	}
+	return quantizedModelID(ad, catalog.ProviderModelID(ad.Spec.Model, p.Name))
+}
+
+// quantization returns the quantization method of spec.serving, or ""
+func quantization(ad *agentopsv1alpha1.AgentDeployment) string {
+	if ad.Spec.Serving == nil {
+		return ""
+	}
+	return ad.Spec.Serving.Quantization
+}
+
+// quantizedModelID returns the repository of the pre-quantized weights of the model
+// for awq and gptq, or modelID when there is none
+func quantizedModelID(ad *agentopsv1alpha1.AgentDeployment, modelID string) string {
+	q := quantization(ad)
+	if q != agentopsv1alpha1.QuantizationAWQ && q != agentopsv1alpha1.QuantizationGPTQ {
+		return modelID
+	}
+	if id, ok := catalog.QuantizedModelID(ad.Spec.Model, q); ok {
+		return id
+	}
+	return modelID
+}
+
+// modelArgs renders spec.serving.quantization and maxContextTokens into the
+// arguments of the runtime. Weights quantized with awq or gptq must come from the
+// catalog, unless ownWeights says the spec names them itself.
+func modelArgs(ad *agentopsv1alpha1.AgentDeployment, p providers.Provider, ownWeights bool) ([]string, error) {
+	if ad.Spec.Serving == nil {
+		return nil, nil
+	}
+	q, maxContext := ad.Spec.Serving.Quantization, ad.Spec.Serving.MaxContextTokens
+	if q == "" && maxContext == nil {
+		return nil, nil
+	}
+	if p.Name != providers.VLLM && p.Name != providers.TGI {
+		return nil, fmt.Errorf("serving.quantization and maxContextTokens require provider vllm or tgi, got %s", p.Name)
+	}
+	if q == agentopsv1alpha1.QuantizationAWQ || q == agentopsv1alpha1.QuantizationGPTQ {
+		if _, ok := catalog.QuantizedModelID(ad.Spec.Model, q); !ok && !ownWeights {
+			return nil, fmt.Errorf("the catalog has no %s weights of model %s; set the modelId of pre-quantized weights", q, ad.Spec.Model)
+		}
+	}
+	if model, _ := catalog.Lookup(ad.Spec.Model); maxContext != nil && model.ContextTokens > 0 && *maxContext > model.ContextTokens {
+		return nil, fmt.Errorf("serving.maxContextTokens %d exceeds the %d token context of model %s", *maxContext, model.ContextTokens, ad.Spec.Model)
+	}
+
+	var args []string
+	switch p.Name {
+	case providers.VLLM:
+		if q != "" {
+			args = append(args, "--quantization", q)
+		}
+		if maxContext != nil {
+			args = append(args, "--max-model-len", strconv.Itoa(int(*maxContext)))
+		}
+	case providers.TGI:
+		if q != "" {
+			args = append(args, "--quantize", q)
+		}
+		if maxContext != nil {
+			args = append(args, "--max-total-tokens", strconv.Itoa(int(*maxContext)))
+		}
+	}
+	return args, nil
+}
+
+// defaultGPUs returns the GPUs of gpuMemory each that hold the model's weights, at
+// the precision of spec.serving.quantization, and the attention cache of one
+// sequence of the full context. It rounds up to a power of two, the tensor parallel
+// sizes the runtimes shard across, and reports false for models the catalog cannot
+// size.
+func defaultGPUs(ad *agentopsv1alpha1.AgentDeployment, gpuMemory resource.Quantity) (int32, bool) {
+	model, ok := catalog.Lookup(ad.Spec.Model)
+	if !ok || !model.SelfHosted || model.CacheFootprint.IsZero() || gpuMemory.IsZero() {
+		return 0, false
+	}
+	// The cache footprint of self-hosted models is their 16-bit weights
+	weights := float64(model.CacheFootprint.Value())
+	switch quantization(ad) {
+	case agentopsv1alpha1.QuantizationAWQ, agentopsv1alpha1.QuantizationGPTQ:
+		// 4-bit weights and their scales
+		weights *= 0.3
+	case agentopsv1alpha1.QuantizationFP8:
+		weights *= 0.5
+	}
+	contextTokens := model.ContextTokens
+	if ad.Spec.Serving != nil && ad.Spec.Serving.MaxContextTokens != nil {
+		contextTokens = *ad.Spec.Serving.MaxContextTokens
+	}
+	needed := weights + float64(int64(contextTokens)*model.KVCacheBytesPerToken)
+
+	gpus := int32(1)
+	for float64(gpus)*float64(gpuMemory.Value())*gpuMemoryUtilization < needed {
+		gpus *= 2
+	}
+	return gpus, true
+}
+
+// modelServerGPUs returns the GPUs of each model server pod. By default the GPUs
+// the model needs are spread over the pipeline stages.
+func modelServerGPUs(ad *agentopsv1alpha1.AgentDeployment) int32 {
+	if tp := tensorParallel(ad); tp > 0 {
+		return tp
+	}
+	gpu := ad.Spec.Serving.SelfHosted.GPU
+	if gpu != nil && gpu.Count > 0 {
+		return gpu.Count
+	}
+	memory := defaultGPUMemory
+	if gpu != nil && gpu.Memory != nil {
+		memory = *gpu.Memory
+	}
+	if gpus, ok := defaultGPUs(ad, memory); ok {
+		perPod := int32(1)
+		for perPod*pipelineStages(ad) < gpus {
+			perPod *= 2
+		}
+		return perPod
+	}
+	return 1
+}
+
+// modelServerURL returns the OpenAI-compatible base URL of the model server
+func modelServerURL(ad *agentopsv1alpha1.AgentDeployment, p providers.Provider) string {
+	port := health.ProfileFor(p.HealthProfile).Port
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d/v1", modelServerName(ad), ad.Namespace, port)
+}
+
+// reconcileModelServer creates or updates the model server StatefulSet and Service,
+// or removes them when self-hosted serving is turned off
+func (r *AgentDeploymentReconciler) reconcileModelServer(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	name := modelServerName(ad)
+	if !modelServerEnabled(ad) {
+		ad.Status.ModelServerReadyReplicas = 0
+		if err := r.deleteOwnedUnstructured(ctx, ad, leaderWorkerSetGVK, name); err != nil {
+			return err
+		}
+		for _, obj := range []client.Object{
+			&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ad.Namespace}},
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ad.Namespace}},
+		} {
+			if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				return err
+			}
+			if !metav1.IsControlledBy(obj, ad) {
+				continue
+			}
+			if err := r.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	spec := ad.Spec.Serving.SelfHosted
+	p, err := modelServerProvider(ad)
+	if err != nil {
+		return err
+	}
+	profile := health.ProfileFor(p.HealthProfile)
+	labels := labelsForModelServer(ad.Name)
+
+	var cache *agentopsv1alpha1.ModelCache
+	if spec.Weights != nil && spec.Weights.ModelCache != nil {
+		cache = &agentopsv1alpha1.ModelCache{}
+		err := r.Get(ctx, types.NamespacedName{Name: spec.Weights.ModelCache.Name, Namespace: ad.Namespace}, cache)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if err != nil || cache.Status.Phase != agentopsv1alpha1.ModelCacheReady {
+			// Starting the server on an empty cache would fail to load the model
+			r.Log.Info("Waiting for ModelCache", "AgentDeployment", ad.Name, "ModelCache", spec.Weights.ModelCache.Name)
+			return nil
+		}
+	}
+
+	pod, claims, err := modelServerPodSpec(ad, p, profile, cache)
+	if err != nil {
+		return err
+	}
+	if err := validateModelServerParallelism(ad, p, claims); err != nil {
+		return err
+	}
+
+	replicas := int32(1)
+	if spec.Replicas != nil {
+		replicas = *spec.Replicas
+	}
+	ready, selector, err := r.reconcileModelServerWorkload(ctx, ad, name, labels, replicas, pod, claims)
+	if err != nil {
+		return err
+	}
+	ad.Status.ModelServerReadyReplicas = ready
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ad.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		svc.Labels = labels
+		svc.Spec.Selector = selector
+		svc.Spec.Ports = []corev1.ServicePort{{
+			Name:       "http",
+			Port:       profile.Port,
+			TargetPort: intstr.FromString("http"),
+			Protocol:   corev1.ProtocolTCP,
+		}}
+		return controllerutil.SetControllerReference(ad, svc, r.Scheme)
+	})
+	return err
+}
+
+// reconcileModelServerStatefulSet creates or updates the StatefulSet of a model
+// server whose replicas are single pods
+func (r *AgentDeploymentReconciler) reconcileModelServerStatefulSet(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, name string, labels map[string]string, replicas int32, pod corev1.PodSpec, claims []corev1.PersistentVolumeClaim) (*appsv1.StatefulSet, error) {
+	sts := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ad.Namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, sts, func() error {
+		sts.Labels = labels
+		sts.Spec.Replicas = &replicas
+		// Selector, service name and claim templates are immutable after creation
+		if sts.CreationTimestamp.IsZero() {
+			sts.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+			sts.Spec.ServiceName = name
+			sts.Spec.VolumeClaimTemplates = claims
+			// Model servers load independently; start them all at once
+			sts.Spec.PodManagementPolicy = appsv1.ParallelPodManagement
+		}
+		sts.Spec.Template.Labels = labels
+		sts.Spec.Template.Spec = pod
+		return controllerutil.SetControllerReference(ad, sts, r.Scheme)
+	})
+	return sts, err
+}
+
+// modelServerPodSpec builds the server pod and the claim templates for weights synced
+// from an object store
+func modelServerPodSpec(ad *agentopsv1alpha1.AgentDeployment, p providers.Provider, profile health.Profile, cache *agentopsv1alpha1.ModelCache) (corev1.PodSpec, []corev1.PersistentVolumeClaim, error) {
+	spec := ad.Spec.Serving.SelfHosted
+	modelID := modelServerModelID(ad, p)
+	if !catalogSelfHosted(ad.Spec.Model) && spec.ModelID == "" && spec.Weights == nil {
+		return corev1.PodSpec{}, nil, fmt.Errorf("model %s has no open weights; set serving.selfHosted.modelId or weights", ad.Spec.Model)
+	}
+
+	gpus := modelServerGPUs(ad)
+	gpuResource := defaultGPUResource
+	var nodeSelector map[string]string
+	if spec.GPU != nil {
+		if spec.GPU.ResourceName != "" {
+			gpuResource = spec.GPU.ResourceName
+		}
+		nodeSelector = spec.GPU.NodeSelector
+	}
+	resources := *spec.Resources.DeepCopy()
+	if resources.Limits == nil {
+		resources.Limits = corev1.ResourceList{}
+	}
+	if resources.Requests == nil {
+		resources.Requests = corev1.ResourceList{}
+	}
+	gpuQuantity := *resource.NewQuantity(int64(gpus), resource.DecimalSI)
+	resources.Limits[gpuResource] = gpuQuantity
+	resources.Requests[gpuResource] = gpuQuantity
+
+	shm := defaultSharedMemory
+	if spec.SharedMemory != nil {
+		shm = *spec.SharedMemory
+	}
+	volumes := []corev1.Volume{{
+		Name: sharedMemoryVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory, SizeLimit: &shm},
+		},
+	}}
+	mounts := []corev1.VolumeMount{{Name: sharedMemoryVolumeName, MountPath: "/dev/shm"}}
+	var env []corev1.EnvVar
+	var initContainers []corev1.Container
+	var claims []corev1.PersistentVolumeClaim
+
+	// Weights from a volume are loaded by path; otherwise the runtime pulls modelID
+	model := modelID
+	switch {
+	case cache != nil:
+		source := corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: cache.Status.ClaimName,
+			ReadOnly:  true,
+		}}
+		if cache.Spec.Storage.Mode == agentopsv1alpha1.ModelCacheNodeLocal {
+			source = corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: cache.Status.HostPath}}
+			// Only nodes holding the cache can serve
+			if len(cache.Spec.Storage.NodeSelector) > 0 {
+				merged := map[string]string{}
+				for k, v := range nodeSelector {
+					merged[k] = v
+				}
+				for k, v := range cache.Spec.Storage.NodeSelector {
+					merged[k] = v
+				}
+				nodeSelector = merged
+			}
+		}
+		volumes = append(volumes, corev1.Volume{Name: weightsVolumeName, VolumeSource: source})
+		mounts = append(mounts, corev1.VolumeMount{Name: weightsVolumeName, MountPath: weightsDir, ReadOnly: true})
+		model = path.Join(weightsDir, spec.Weights.ModelCache.SubPath)
+	case spec.Weights != nil && spec.Weights.PersistentVolumeClaim != nil:
+		pvc := spec.Weights.PersistentVolumeClaim
+		volumes = append(volumes, corev1.Volume{
+			Name: weightsVolumeName,
+			VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: pvc.ClaimName,
+				ReadOnly:  true,
+			}},
+		})
+		mounts = append(mounts, corev1.VolumeMount{Name: weightsVolumeName, MountPath: weightsDir, ReadOnly: true})
+		model = path.Join(weightsDir, pvc.SubPath)
+	case spec.Weights != nil && spec.Weights.ObjectStore != nil:
+		store := spec.Weights.ObjectStore
+		claims = []corev1.PersistentVolumeClaim{{
+			ObjectMeta: metav1.ObjectMeta{Name: weightsVolumeName},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				StorageClassName: store.StorageClassName,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: store.Size},
+				},
+			},
+		}}
+		initContainers = append(initContainers, weightsSyncContainer(store))
+		mounts = append(mounts, corev1.VolumeMount{Name: weightsVolumeName, MountPath: weightsDir, ReadOnly: true})
+		model = weightsDir
+	default:
+		volumes = append(volumes, corev1.Volume{
+			Name:         weightsVolumeName,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+		mounts = append(mounts, corev1.VolumeMount{Name: weightsVolumeName, MountPath: weightsDir})
+		env = append(env, corev1.EnvVar{Name: "HF_HOME", Value: path.Join(weightsDir, "huggingface")})
+		if cfg := ad.Spec.ProviderConfig; cfg != nil && cfg.CredentialsSecretRef != nil {
+			env = append(env, credentialEnv("HF_TOKEN", cfg.CredentialsSecretRef.Name, hfTokenSecretKey, true))
+		}
+	}
+
+	port := strconv.Itoa(int(profile.Port))
+	shards := strconv.Itoa(int(gpus))
+	var args []string
+	switch p.Name {
+	case providers.VLLM:
+		args = []string{"--model", model, "--served-model-name", modelID, "--port", port, "--tensor-parallel-size", shards}
+		if stages := pipelineStages(ad); stages > 1 {
+			args = append(args, "--pipeline-parallel-size", strconv.Itoa(int(stages)), "--distributed-executor-backend", "ray")
+		}
+	case providers.TGI:
+		args = []string{"--model-id", model, "--port", port, "--num-shard", shards}
+	}
+	serving, err := modelArgs(ad, p, spec.ModelID != "" || spec.Weights != nil)
+	if err != nil {
+		return corev1.PodSpec{}, nil, err
+	}
+	batching, err := batchingArgs(ad, p)
+	if err != nil {
+		return corev1.PodSpec{}, nil, err
+	}
+	if err := validateAdapters(ad, p, modelID); err != nil {
+		return corev1.PodSpec{}, nil, err
+	}
+	args = append(args, serving...)
+	args = append(args, batching...)
+	var sidecars []corev1.Container
+	if adaptersEnabled(ad) {
+		args = append(args, adapterArgs(ad)...)
+		// Lets the sync sidecar load and unload adapters while the server runs
+		env = append(env, corev1.EnvVar{Name: "VLLM_ALLOW_RUNTIME_LORA_UPDATING", Value: "True"})
+		volumes = append(volumes, adapterVolumes(ad)...)
+		mounts = append(mounts, corev1.VolumeMount{Name: adaptersVolumeName, MountPath: adaptersDir, ReadOnly: true})
+		sidecars = append(sidecars, adapterSyncContainer(ad, profile.Port))
+	}
+	args = append(args, spec.Args...)
+
+	image := p.Image
+	if spec.Image != "" {
+		image = spec.Image
+	}
+
+	probe := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{Path: profile.ReadinessPath, Port: intstr.FromString("http")},
+		},
+		PeriodSeconds: 10,
+	}
+	pod := corev1.PodSpec{
+		InitContainers: initContainers,
+		Containers: append([]corev1.Container{{
+			Name:           "server",
+			Image:          image,
+			Args:           args,
+			Env:            env,
+			Ports:          []corev1.ContainerPort{{Name: "http", ContainerPort: profile.Port}},
+			Resources:      resources,
+			ReadinessProbe: probe,
+			// Loading weights takes minutes; hold liveness checks until the server is up
+			StartupProbe: &corev1.Probe{
+				ProbeHandler:     probe.ProbeHandler,
+				PeriodSeconds:    10,
+				FailureThreshold: modelLoadFailureThreshold,
+			},
+			VolumeMounts: mounts,
+		}}, sidecars...),
+		Volumes:      volumes,
+		NodeSelector: nodeSelector,
+		Tolerations: []corev1.Toleration{{
+			Key:      string(gpuResource),
+			Operator: corev1.TolerationOpExists,
+			Effect:   corev1.TaintEffectNoSchedule,
+		}},
+	}
+	applySidecarResources(ad, &pod)
+	return pod, claims, nil
+}
+
+// weightsSyncContainer copies the weights from an object store into the weights volume
+func weightsSyncContainer(store *agentopsv1alpha1.ObjectStoreWeightsSource) corev1.Container {
+	c := corev1.Container{
+		Name:         "sync-weights",
+		VolumeMounts: []corev1.VolumeMount{{Name: weightsVolumeName, MountPath: weightsDir}},
+	}
+	if strings.HasPrefix(store.URI, "gs://") {
+		c.Image = gcsSyncImage
+		c.Command = []string{"gsutil", "-m", "rsync", "-r", store.URI, weightsDir}
+	} else {
+		c.Image = s3SyncImage
+		c.Command = []string{"aws", "s3", "sync", store.URI, weightsDir}
+	}
+	if store.CredentialsSecretRef != nil {
+		c.EnvFrom = []corev1.EnvFromSource{{
+			SecretRef: &corev1.SecretEnvSource{LocalObjectReference: *store.CredentialsSecretRef},
+		}}
+	}
+	return c
+}
+
+// batchingFlag is a runtime flag set from a batching setting
+type batchingFlag struct {
+	name  string
+	value *int32
+}
+
+// batchingArgs renders spec.serving.batching into the arguments of the runtime,
+// rejecting settings the runtime has no flag for
+func batchingArgs(ad *agentopsv1alpha1.AgentDeployment, p providers.Provider) ([]string, error) {
+	if ad.Spec.Serving == nil || ad.Spec.Serving.Batching == nil {
+		return nil, nil
+	}
+	b := ad.Spec.Serving.Batching
+	if b.MaxBatchTokens != nil && b.MaxPrefillTokens != nil && *b.MaxPrefillTokens > *b.MaxBatchTokens {
+		return nil, fmt.Errorf("serving.batching.maxPrefillTokens must not exceed maxBatchTokens")
+	}
+
+	var flags []batchingFlag
+	var args []string
+	switch p.Name {
+	case providers.VLLM:
+		if b.MaxPrefillTokens != nil || b.MaxWaitingTokens != nil {
+			return nil, fmt.Errorf("serving.batching.maxPrefillTokens and maxWaitingTokens are not supported by %s", p.Name)
+		}
+		flags = []batchingFlag{
+			{"--max-num-seqs", b.MaxBatchSize},
+			{"--max-num-batched-tokens", b.MaxBatchTokens},
+		}
+		if b.ChunkedPrefill != nil && *b.ChunkedPrefill {
+			args = append(args, "--enable-chunked-prefill")
+		}
+		if b.PrefixCaching != nil && *b.PrefixCaching {
+			args = append(args, "--enable-prefix-caching")
+		}
+	case providers.TGI:
+		if b.ChunkedPrefill != nil || b.PrefixCaching != nil {
+			return nil, fmt.Errorf("serving.batching.chunkedPrefill and prefixCaching are not supported by %s", p.Name)
+		}
+		flags = []batchingFlag{
+			{"--max-batch-size", b.MaxBatchSize},
+			{"--max-batch-total-tokens", b.MaxBatchTokens},
+			{"--max-batch-prefill-tokens", b.MaxPrefillTokens},
+			{"--max-waiting-tokens", b.MaxWaitingTokens},
+		}
+	default:
+		return nil, fmt.Errorf("serving.batching requires provider vllm or tgi, got %s", p.Name)
+	}
+
+	for _, f := range flags {
+		if f.value != nil {
+			args = append(args, f.name, strconv.Itoa(int(*f.value)))
+		}
+	}
+	return args, nil
+}
+
+// catalogSelfHosted reports whether the catalog lists open weights for the model
+func catalogSelfHosted(model string) bool {
+	m, _ := catalog.Lookup(model)
+	return m.SelfHosted
+}
+
+// labelsForModelServer returns the labels of the model server pods
+func labelsForModelServer(name string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "model-server",
+		"app.kubernetes.io/instance":   name,
+		"app.kubernetes.io/managed-by": "agentops-controller",
+	}
+}
@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	defaultRedisImage = "redis:7.2-alpine"
+	redisPort         = 6379
+	postgresPort      = 5432
+
+	// sessionStoreURLKey is the key of the connection URL in spec.sessionStore.secretRef
+	sessionStoreURLKey = "url"
+
+	// sessionStoreRetryInterval is how often a store that is not up is checked again;
+	// stores the agent does not own are not watched
+	sessionStoreRetryInterval = 30 * time.Second
+)
+
+// sessionStoreEnabled reports whether the agent keeps its sessions in a store
+func sessionStoreEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	s := ad.Spec.SessionStore
+	return s != nil && s.Type != "" && s.Type != agentopsv1alpha1.SessionStoreNone
+}
+
+// sessionStoreDeployed reports whether the controller runs the store for the agent
+func sessionStoreDeployed(ad *agentopsv1alpha1.AgentDeployment) bool {
+	s := ad.Spec.SessionStore
+	return sessionStoreEnabled(ad) && s.Type == agentopsv1alpha1.SessionStoreRedis && s.SecretRef == nil && s.ServiceRef == nil
+}
+
+// sessionStoreName returns the name of the deployed store StatefulSet and Service
+func sessionStoreName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-session"
+}
+
+// sessionStorePort returns the port the store of the given type listens on
+func sessionStorePort(storeType string) int32 {
+	if storeType == agentopsv1alpha1.SessionStorePostgres {
+		return postgresPort
+	}
+	return redisPort
+}
+
+// sessionStoreService returns the Service in front of the store, or an empty name
+// when the store is only reachable through the URL of its Secret
+func sessionStoreService(ad *agentopsv1alpha1.AgentDeployment) string {
+	s := ad.Spec.SessionStore
+	if s.ServiceRef != nil {
+		return s.ServiceRef.Name
+	}
+	if sessionStoreDeployed(ad) {
+		return sessionStoreName(ad)
+	}
+	return ""
+}
+
+// sessionStoreEnv returns the connection variables of the session store. The URL
+// comes from spec.sessionStore.secretRef, or is built from the Service in front of
+// the store.
+func sessionStoreEnv(ad *agentopsv1alpha1.AgentDeployment) []corev1.EnvVar {
+	if !sessionStoreEnabled(ad) {
+		return nil
+	}
+	s := ad.Spec.SessionStore
+	env := []corev1.EnvVar{{Name: "SESSION_STORE_TYPE", Value: s.Type}}
+	if svc := sessionStoreService(ad); svc != "" {
+		host := fmt.Sprintf("%s.%s.svc.cluster.local", svc, ad.Namespace)
+		port := strconv.Itoa(int(sessionStorePort(s.Type)))
+		env = append(env,
+			corev1.EnvVar{Name: "SESSION_STORE_HOST", Value: host},
+			corev1.EnvVar{Name: "SESSION_STORE_PORT", Value: port},
+		)
+		if s.SecretRef == nil {
+			env = append(env, corev1.EnvVar{Name: "SESSION_STORE_URL", Value: fmt.Sprintf("%s://%s:%s", s.Type, host, port)})
+		}
+	}
+	if s.SecretRef != nil {
+		env = append(env, credentialEnv("SESSION_STORE_URL", s.SecretRef.Name, sessionStoreURLKey, false))
+	}
+	return env
+}
+
+// reconcileSessionStore deploys the redis store of an agent without store refs, or
+// removes it when no longer needed, and records whether the store is up
+func (r *AgentDeploymentReconciler) reconcileSessionStore(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	name := sessionStoreName(ad)
+	if !sessionStoreDeployed(ad) {
+		if err := r.deleteStoreStatefulSet(ctx, ad, name); err != nil {
+			return err
+		}
+		if !sessionStoreEnabled(ad) {
+			meta.RemoveStatusCondition(&ad.Status.Conditions, conditions.SessionStoreReady)
+			return nil
+		}
+		ready, message, err := r.sessionStoreReachable(ctx, ad)
+		if err != nil {
+			return err
+		}
+		setSessionStoreCondition(ad, ready, message)
+		return nil
+	}
+
+	spec := ad.Spec.SessionStore
+	sts, err := r.reconcileStoreStatefulSet(ctx, ad, name, labelsForSessionStore(ad.Name), spec.Storage, spec.StorageClassName,
+		func(persistent bool) corev1.PodSpec { return redisPodSpec(spec.Image, persistent) },
+		[]corev1.ServicePort{{
+			Name:       "redis",
+			Port:       redisPort,
+			TargetPort: intstr.FromString("redis"),
+			Protocol:   corev1.ProtocolTCP,
+		}})
+	if err != nil {
+		return err
+	}
+
+	if sts.Status.ReadyReplicas > 0 {
+		setSessionStoreCondition(ad, true, "Redis session store is ready")
+	} else {
+		setSessionStoreCondition(ad, false, "Waiting for the redis session store to become ready")
+	}
+	return nil
+}
+
+// reconcileStoreStatefulSet deploys a single replica store for the agent, such as
+// its session store or queue broker, and the Service in front of it. With storage
+// the store keeps its data on a volume of that size; podSpec is told whether it has one.
+func (r *AgentDeploymentReconciler) reconcileStoreStatefulSet(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, name string, labels map[string]string,
+	storage *resource.Quantity, storageClassName *string, podSpec func(persistent bool) corev1.PodSpec, ports []corev1.ServicePort) (*appsv1.StatefulSet, error) {
+	sts := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ad.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, sts, func() error {
+		replicas := int32(1)
+		sts.Labels = labels
+		sts.Spec.Replicas = &replicas
+		// Selector, service name and claim templates are immutable after creation
+		if sts.CreationTimestamp.IsZero() {
+			sts.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+			sts.Spec.ServiceName = name
+			if storage != nil {
+				sts.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{
+					ObjectMeta: metav1.ObjectMeta{Name: "data"},
+					Spec: corev1.PersistentVolumeClaimSpec{
+						AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+						StorageClassName: storageClassName,
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceStorage: *storage},
+						},
+					},
+				}}
+			}
+		}
+		sts.Spec.Template.Labels = labels
+		sts.Spec.Template.Spec = podSpec(len(sts.Spec.VolumeClaimTemplates) > 0)
+		return controllerutil.SetControllerReference(ad, sts, r.Scheme)
+	}); err != nil {
+		return nil, err
+	}
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ad.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		svc.Labels = labels
+		svc.Spec.Selector = labels
+		svc.Spec.Ports = ports
+		return controllerutil.SetControllerReference(ad, svc, r.Scheme)
+	}); err != nil {
+		return nil, err
+	}
+	return sts, nil
+}
+
+// deleteStoreStatefulSet removes the StatefulSet and Service of a store the agent
+// no longer needs
+func (r *AgentDeploymentReconciler) deleteStoreStatefulSet(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, name string) error {
+	for _, obj := range []client.Object{
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ad.Namespace}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ad.Namespace}},
+	} {
+		if err := deleteOwned(ctx, r.Client, ad, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redisPodSpec builds the pod of a deployed redis store. Without a volume it keeps
+// its data in memory only.
+func redisPodSpec(image string, persistent bool) corev1.PodSpec {
+	if image == "" {
+		image = defaultRedisImage
+	}
+	args := []string{"--save", "", "--appendonly", "no"}
+	var volumes []corev1.Volume
+	if persistent {
+		args = []string{"--appendonly", "yes", "--dir", "/data"}
+	} else {
+		volumes = []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+	}
+	return corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name:  "redis",
+			Image: image,
+			Args:  args,
+			Ports: []corev1.ContainerPort{{Name: "redis", ContainerPort: redisPort}},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				},
+			},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					Exec: &corev1.ExecAction{Command: []string{"redis-cli", "ping"}},
+				},
+				PeriodSeconds: 10,
+			},
+			VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+		}},
+		Volumes: volumes,
+	}
+}
+
+// sessionStoreReachable checks the Secret and Service of an existing store. A store
+// only known by its URL cannot be checked beyond its Secret.
+func (r *AgentDeploymentReconciler) sessionStoreReachable(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (bool, string, error) {
+	spec := ad.Spec.SessionStore
+	if spec.SecretRef == nil && spec.ServiceRef == nil {
+		return false, "sessionStore." + spec.Type + " needs a secretRef or serviceRef; only redis stores are deployed", nil
+	}
+	if spec.SecretRef != nil {
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: spec.SecretRef.Name, Namespace: ad.Namespace}, secret)
+		if errors.IsNotFound(err) {
+			return false, fmt.Sprintf("Secret %s not found", spec.SecretRef.Name), nil
+		} else if err != nil {
+			return false, "", err
+		}
+		if len(secret.Data[sessionStoreURLKey]) == 0 {
+			return false, fmt.Sprintf("Secret %s has no %s key", spec.SecretRef.Name, sessionStoreURLKey), nil
+		}
+	}
+	if spec.ServiceRef == nil {
+		return true, fmt.Sprintf("Connection URL is set by Secret %s", spec.SecretRef.Name), nil
+	}
+
+	name := spec.ServiceRef.Name
+	svc := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: ad.Namespace}, svc)
+	if errors.IsNotFound(err) {
+		return false, fmt.Sprintf("Service %s not found", name), nil
+	} else if err != nil {
+		return false, "", err
+	}
+	port := sessionStorePort(spec.Type)
+	exposed := false
+	for _, p := range svc.Spec.Ports {
+		exposed = exposed || p.Port == port
+	}
+	if !exposed {
+		return false, fmt.Sprintf("Service %s does not expose port %d", name, port), nil
+	}
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		return true, fmt.Sprintf("Service %s points to %s", name, svc.Spec.ExternalName), nil
+	}
+
+	endpoints := &corev1.Endpoints{}
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: ad.Namespace}, endpoints)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, "", err
+	}
+	for _, subset := range endpoints.Subsets {
+		if len(subset.Addresses) > 0 {
+			return true, fmt.Sprintf("Service %s has ready endpoints", name), nil
+		}
+	}
+	return false, fmt.Sprintf("Service %s has no ready endpoints", name), nil
+}
+
+// setSessionStoreCondition records whether the session store is up
+func setSessionStoreCondition(ad *agentopsv1alpha1.AgentDeployment, ready bool, message string) {
+	if ready {
+		conditions.Set(&ad.Status.Conditions, conditions.SessionStoreReady, metav1.ConditionTrue, conditions.ReasonAsExpected,
+			message, ad.Generation)
+		return
+	}
+	conditions.Set(&ad.Status.Conditions, conditions.SessionStoreReady, metav1.ConditionFalse, conditions.ReasonSessionStoreUnavailable,
+		message, ad.Generation)
+}
+
+// labelsForSessionStore returns the labels of the deployed session store pods
+func labelsForSessionStore(name string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "session-store",
+		"app.kubernetes.io/instance":   name,
+		"app.kubernetes.io/managed-by": "agentops-controller",
+	}
+}
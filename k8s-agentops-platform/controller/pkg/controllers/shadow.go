@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"fmt"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/gateway"
+)
+
+// nginxMirrorAnnotation sends a copy of each request to the shadow of an Ingress
+const nginxMirrorAnnotation = nginxAnnotationBase + "mirror-target"
+
+// shadowPercent returns the share of requests mirrored with spec.shadow
+func shadowPercent(ad *agentopsv1alpha1.AgentDeployment) int32 {
+	if ad.Spec.Shadow.Percent == nil {
+		return 100
+	}
+	return *ad.Spec.Shadow.Percent
+}
+
+// shadowMirror returns the gateway mirror of spec.shadow, or nil when it is not set
+func shadowMirror(ad *agentopsv1alpha1.AgentDeployment) *gateway.Mirror {
+	if ad.Spec.Shadow == nil {
+		return nil
+	}
+	return &gateway.Mirror{
+		Backend: gateway.Backend{Service: ad.Spec.Shadow.AgentDeployment, Namespace: ad.Namespace, Port: agentServicePort},
+		Percent: shadowPercent(ad),
+	}
+}
+
+// shadowAnnotations returns the ingress-nginx settings mirroring the requests of the
+// agent's Ingress to spec.shadow. ingress-nginx mirrors every request.
+func shadowAnnotations(ad *agentopsv1alpha1.AgentDeployment) map[string]string {
+	if ad.Spec.Shadow == nil {
+		return nil
+	}
+	target := fmt.Sprintf("http://%s:%d$request_uri", serviceHost(ad.Spec.Shadow.AgentDeployment, ad.Namespace), agentServicePort)
+	return map[string]string{nginxMirrorAnnotation: target}
+}
+
+// validateShadow rejects a shadow that would mirror the agent's requests back to it
+func validateShadow(ad *agentopsv1alpha1.AgentDeployment) error {
+	if s := ad.Spec.Shadow; s != nil && s.AgentDeployment == ad.Name {
+		return fmt.Errorf("spec.shadow must name another AgentDeployment")
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/providers"
+)
+
+var leaderWorkerSetGVK = schema.GroupVersionKind{Group: "leaderworkerset.x-k8s.io", Version: "v1", Kind: "LeaderWorkerSet"}
+
+const (
+	// rayPort is the port of the Ray head the workers of a replica join
+	rayPort = 6379
+
+	// lwsWorkerIndexLabel is set by the LeaderWorkerSet controller; the leader of a
+	// group has index 0
+	lwsWorkerIndexLabel = "leaderworkerset.sigs.k8s.io/worker-index"
+)
+
+// tensorParallel returns the GPUs each pod shards the model across as set by
+// spec.serving.tensorParallel, or 0
+func tensorParallel(ad *agentopsv1alpha1.AgentDeployment) int32 {
+	if ad.Spec.Serving == nil || ad.Spec.Serving.TensorParallel == nil {
+		return 0
+	}
+	return *ad.Spec.Serving.TensorParallel
+}
+
+// pipelineStages returns the model server pods serving one replica of the model
+func pipelineStages(ad *agentopsv1alpha1.AgentDeployment) int32 {
+	if ad.Spec.Serving == nil || ad.Spec.Serving.PipelineParallel == nil {
+		return 1
+	}
+	return *ad.Spec.Serving.PipelineParallel
+}
+
+// parallelArgs renders spec.serving.tensorParallel into the arguments of a runtime
+// serving the model in the agent pod; layers can only be split across pods by the
+// model server
+func parallelArgs(ad *agentopsv1alpha1.AgentDeployment, p providers.Provider) ([]string, error) {
+	if pipelineStages(ad) > 1 {
+		return nil, fmt.Errorf("serving.pipelineParallel requires serving.selfHosted")
+	}
+	tp := tensorParallel(ad)
+	if tp == 0 {
+		return nil, nil
+	}
+	switch p.Name {
+	case providers.VLLM:
+		return []string{"--tensor-parallel-size", strconv.Itoa(int(tp))}, nil
+	case providers.TGI:
+		return []string{"--num-shard", strconv.Itoa(int(tp))}, nil
+	}
+	return nil, fmt.Errorf("serving.tensorParallel requires provider vllm or tgi, got %s", p.Name)
+}
+
+// validateModelServerParallelism checks spec.serving.tensorParallel and
+// pipelineParallel against the model server spec
+func validateModelServerParallelism(ad *agentopsv1alpha1.AgentDeployment, p providers.Provider, claims []corev1.PersistentVolumeClaim) error {
+	gpu := ad.Spec.Serving.SelfHosted.GPU
+	if tp := tensorParallel(ad); tp > 0 && gpu != nil && gpu.Count > 0 && gpu.Count != tp {
+		return fmt.Errorf("serving.tensorParallel %d conflicts with serving.selfHosted.gpu.count %d", tp, gpu.Count)
+	}
+	if pipelineStages(ad) == 1 {
+		return nil
+	}
+	// tgi shards within one node only
+	if p.Name != providers.VLLM {
+		return fmt.Errorf("serving.pipelineParallel requires provider vllm, got %s", p.Name)
+	}
+	// LeaderWorkerSets have no claim templates for the per-pod copies
+	if len(claims) > 0 {
+		return fmt.Errorf("serving.pipelineParallel cannot load weights from an object store; use a PVC or a ModelCache")
+	}
+	return nil
+}
+
+// leaderWorkerSetForModelServer renders the model server as a LeaderWorkerSet of
+// replicas groups of pipelineStages pods with stable network identities. Each
+// group's leader starts a Ray head and the runtime, which places the pipeline
+// stages on the workers once they have joined; the leader alone serves requests.
+// A group is recreated as a whole when one of its pods restarts, since the runtime
+// cannot recover a lost stage.
+func leaderWorkerSetForModelServer(ad *agentopsv1alpha1.AgentDeployment, name string, labels map[string]string, replicas int32, pod corev1.PodSpec) (*unstructured.Unstructured, error) {
+	leader := *pod.DeepCopy()
+	server := &leader.Containers[0]
+	server.Command = []string{"/bin/sh", "-c",
+		fmt.Sprintf(`ray start --head --port=%d && exec python3 -m vllm.entrypoints.openai.api_server "$@"`, rayPort), "vllm"}
+
+	worker := *pod.DeepCopy()
+	worker.Containers = []corev1.Container{worker.Containers[0]}
+	worker.Containers[0].Command = []string{"/bin/sh", "-c",
+		fmt.Sprintf(`exec ray start --address="$LWS_LEADER_ADDRESS:%d" --block`, rayPort)}
+	worker.Containers[0].Args = nil
+	worker.Containers[0].Ports = nil
+	worker.Containers[0].ReadinessProbe = nil
+	worker.Containers[0].StartupProbe = nil
+
+	leaderSpec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&leader)
+	if err != nil {
+		return nil, err
+	}
+	workerSpec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&worker)
+	if err != nil {
+		return nil, err
+	}
+	metadata := map[string]interface{}{"labels": stringMapToUnstructured(labels)}
+
+	lws := newUnstructured(leaderWorkerSetGVK, name, ad.Namespace)
+	lws.SetLabels(labels)
+	lws.Object["spec"] = map[string]interface{}{
+		"replicas":      int64(replicas),
+		"startupPolicy": "LeaderCreated",
+		"leaderWorkerTemplate": map[string]interface{}{
+			"size":           int64(pipelineStages(ad)),
+			"restartPolicy":  "RecreateGroupOnPodRestart",
+			"leaderTemplate": map[string]interface{}{"metadata": metadata, "spec": leaderSpec},
+			"workerTemplate": map[string]interface{}{"metadata": metadata, "spec": workerSpec},
+		},
+	}
+	return lws, nil
+}
+
+// reconcileModelServerWorkload creates or updates the StatefulSet, or with pipeline
+// parallelism the LeaderWorkerSet, running the model server and removes the other.
+// It returns the ready replicas and the selector of the pods serving requests.
+func (r *AgentDeploymentReconciler) reconcileModelServerWorkload(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, name string, labels map[string]string, replicas int32, pod corev1.PodSpec, claims []corev1.PersistentVolumeClaim) (int32, map[string]string, error) {
+	if pipelineStages(ad) == 1 {
+		if err := r.deleteOwnedUnstructured(ctx, ad, leaderWorkerSetGVK, name); err != nil {
+			return 0, nil, err
+		}
+		sts, err := r.reconcileModelServerStatefulSet(ctx, ad, name, labels, replicas, pod, claims)
+		if err != nil {
+			return 0, nil, err
+		}
+		return sts.Status.ReadyReplicas, labels, nil
+	}
+
+	if err := deleteOwned(ctx, r.Client, ad, &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ad.Namespace}}); err != nil {
+		return 0, nil, err
+	}
+	desired, err := leaderWorkerSetForModelServer(ad, name, labels, replicas, pod)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := r.reconcileUnstructured(ctx, ad, desired); err != nil {
+		return 0, nil, err
+	}
+	lws := newUnstructured(leaderWorkerSetGVK, name, ad.Namespace)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(lws), lws); err != nil {
+		return 0, nil, err
+	}
+	ready, _, _ := unstructured.NestedInt64(lws.Object, "status", "readyReplicas")
+
+	selector := map[string]string{lwsWorkerIndexLabel: "0"}
+	for k, v := range labels {
+		selector[k] = v
+	}
+	return int32(ready), selector, nil
+}
+
+// stringMapToUnstructured converts a string map into its unstructured form
+func stringMapToUnstructured(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/cosign"
+)
+
+const (
+	// signatureRetryInterval is how often a rejected agent image is verified again.
+	// Images are signed outside the cluster, so there is no event to wait for.
+	signatureRetryInterval = 5 * time.Minute
+
+	// trackTagInterval is how often agents with the track-tag image update policy
+	// check their tag for a new digest
+	trackTagInterval = 5 * time.Minute
+)
+
+// SignatureVerifier checks the cosign signatures of an image and returns the
+// verified manifest digest
+type SignatureVerifier interface {
+	Verify(ctx context.Context, image string, policy cosign.Policy) (string, error)
+}
+
+// ImageResolver resolves an image tag to its manifest digest
+type ImageResolver interface {
+	Resolve(ctx context.Context, image string) (string, error)
+}
+
+// agentImageFor returns the image of the agent container as it would be deployed
+func (r *AgentDeploymentReconciler) agentImageFor(ad *agentopsv1alpha1.AgentDeployment, cfg *agentopsv1alpha1.AgentOpsConfig, templatePatch []byte) (string, error) {
+	dep, err := r.deploymentForAgentDeployment(ad, templatePatch)
+	if err != nil {
+		return "", err
+	}
+	configOverlay(cfg)(dep)
+	for _, c := range dep.Spec.Template.Spec.Containers {
+		if c.Name == "agent" {
+			return c.Image, nil
+		}
+	}
+	return "", nil
+}
+
+// resolveAgentImage returns the digest to pin the agent image to for
+// spec.imageUpdatePolicy and records it in the status, or "" when the policy is
+// unset. Pinned agents keep the recorded digest until their image changes.
+func (r *AgentDeploymentReconciler) resolveAgentImage(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, image string) (string, error) {
+	policy := ad.Spec.ImageUpdatePolicy
+	if policy == "" || r.Images == nil {
+		ad.Status.Image, ad.Status.ImageDigest = "", ""
+		return "", nil
+	}
+	if policy == agentopsv1alpha1.ImageUpdatePolicyPinned && ad.Status.Image == image && ad.Status.ImageDigest != "" {
+		return ad.Status.ImageDigest, nil
+	}
+	digest, err := r.Images.Resolve(ctx, image)
+	if err != nil {
+		return "", err
+	}
+	if ad.Status.Image == image && ad.Status.ImageDigest != "" && ad.Status.ImageDigest != digest {
+		r.event(ad, corev1.EventTypeNormal, "ImageUpdated", "Tag "+image+" moved to "+digest)
+	}
+	ad.Status.Image, ad.Status.ImageDigest = image, digest
+	return digest, nil
+}
+
+// verifyAgentImage verifies the agent image, at digest when it is pinned, against
+// the image verification of the AgentOpsConfig and returns the digest to pin it to.
+// Without verification configured digest is returned unchanged. Images that fail
+// verification return a *cosign.VerificationError.
+func (r *AgentDeploymentReconciler) verifyAgentImage(ctx context.Context, cfg *agentopsv1alpha1.AgentOpsConfig, image, digest string) (string, error) {
+	if cfg == nil || cfg.Spec.ImageVerification == nil || r.Signatures == nil {
+		return digest, nil
+	}
+	keys, err := cosign.ParsePublicKeys(cfg.Spec.ImageVerification.PublicKeys)
+	if err != nil {
+		return "", &cosign.VerificationError{Image: "of AgentOpsConfig " + cfg.Name, Reason: err.Error()}
+	}
+	return r.Signatures.Verify(ctx, withDigest(image, digest), cosign.Policy{
+		Keys:         keys,
+		Attestations: cfg.Spec.ImageVerification.Attestations,
+	})
+}
+
+// withDigest pins image to digest unless it already names one
+func withDigest(image, digest string) string {
+	if digest == "" || strings.Contains(image, "@") {
+		return image
+	}
+	return image + "@" + digest
+}
+
+// imageDigestOverlay pins the agent image to its resolved or verified digest, so
+// pods never run an image a tag was moved to afterwards
+func imageDigestOverlay(image, digest string) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		containers := dep.Spec.Template.Spec.Containers
+		for i := range containers {
+			if containers[i].Name == "agent" && containers[i].Image == image {
+				containers[i].Image = withDigest(image, digest)
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	defaultSpotNodeLabel = "eks.amazonaws.com/capacityType"
+	defaultSpotValue     = "SPOT"
+	defaultSpotPercent   = int32(50)
+
+	// capacityTypeLabel tells the pods of the on-demand Deployment apart
+	capacityTypeLabel = "agentops.io/capacity-type"
+	capacityOnDemand  = "on-demand"
+)
+
+// spotNodeLabel returns the node label and value marking spot nodes
+func spotNodeLabel(spot *agentopsv1alpha1.SpotPlacementSpec) (string, string) {
+	label, value := spot.NodeLabel, spot.SpotValue
+	if label == "" {
+		label = defaultSpotNodeLabel
+	}
+	if value == "" {
+		value = defaultSpotValue
+	}
+	return label, value
+}
+
+// spotPlacement returns spec.placement.spot, or nil
+func spotPlacement(ad *agentopsv1alpha1.AgentDeployment) *agentopsv1alpha1.SpotPlacementSpec {
+	if ad.Spec.Placement == nil {
+		return nil
+	}
+	return ad.Spec.Placement.Spot
+}
+
+// spotFallback reports whether part of the replicas run in the on-demand Deployment
+func spotFallback(ad *agentopsv1alpha1.AgentDeployment) bool {
+	spot := spotPlacement(ad)
+	return spot != nil && spot.Mode == agentopsv1alpha1.SpotModeFallback
+}
+
+// applySpotPlacement renders spec.placement.spot into the agent pod spec: spot
+// tolerations, and a node affinity that prefers or requires spot nodes
+func applySpotPlacement(ad *agentopsv1alpha1.AgentDeployment, spec *corev1.PodSpec) {
+	spot := spotPlacement(ad)
+	if spot == nil {
+		return
+	}
+	label, value := spotNodeLabel(spot)
+	tolerations := spot.Tolerations
+	if len(tolerations) == 0 {
+		tolerations = []corev1.Toleration{{Key: label, Operator: corev1.TolerationOpExists}}
+	}
+	spec.Tolerations = append(spec.Tolerations, tolerations...)
+
+	term := corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{
+		Key: label, Operator: corev1.NodeSelectorOpIn, Values: []string{value},
+	}}}
+	affinity := &corev1.NodeAffinity{}
+	if spot.Mode == agentopsv1alpha1.SpotModeRequire {
+		affinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{term}}
+	} else {
+		affinity.PreferredDuringSchedulingIgnoredDuringExecution = []corev1.PreferredSchedulingTerm{{Weight: 100, Preference: term}}
+	}
+	spec.Affinity = &corev1.Affinity{NodeAffinity: affinity}
+}
+
+// placementRemoved reports whether the live pod template has an affinity or
+// tolerations the desired one dropped, which a derivative comparison cannot see
+func placementRemoved(desired, current *appsv1.Deployment) bool {
+	d, c := desired.Spec.Template.Spec, current.Spec.Template.Spec
+	return (d.Affinity == nil && c.Affinity != nil) || (len(d.Tolerations) == 0 && len(c.Tolerations) > 0)
+}
+
+// onDemandReplicas returns the on-demand share of replicas, rounded up
+func onDemandReplicas(spot *agentopsv1alpha1.SpotPlacementSpec, replicas int32) int32 {
+	percent := defaultSpotPercent
+	if spot.SpotPercent != nil {
+		percent = *spot.SpotPercent
+	}
+	return replicas - replicas*percent/100
+}
+
+// spotSplit carries the on-demand share computed by the spot overlay to the
+// reconcile of the on-demand Deployment
+type spotSplit struct {
+	onDemand int32
+}
+
+// spotOverlay moves the on-demand share of the replicas out of the agent Deployment
+// in fallback-to-on-demand mode. With spec.autoscaling the HPA scales the agent
+// Deployment, and the on-demand share is taken from the minimum replicas.
+func spotOverlay(ad *agentopsv1alpha1.AgentDeployment, split *spotSplit) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		if !spotFallback(ad) || dep.Spec.Replicas == nil {
+			return
+		}
+		spot := spotPlacement(ad)
+		if autoscalingEnabled(ad) {
+			minReplicas, _ := autoscalingBounds(ad)
+			if *dep.Spec.Replicas == 0 {
+				minReplicas = 0
+			}
+			split.onDemand = onDemandReplicas(spot, minReplicas)
+			return
+		}
+		split.onDemand = onDemandReplicas(spot, *dep.Spec.Replicas)
+		replicas := *dep.Spec.Replicas - split.onDemand
+		dep.Spec.Replicas = &replicas
+	}
+}
+
+// onDemandName returns the name of the on-demand Deployment of the agent
+func onDemandName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-" + capacityOnDemand
+}
+
+// reconcileOnDemand runs the on-demand share of the replicas in fallback-to-on-demand
+// mode, with the pod template of the agent Deployment kept off spot nodes, and
+// removes the on-demand Deployment otherwise. Its pods carry the agent labels, so
+// the agent Service sends them traffic.
+func (r *AgentDeploymentReconciler) reconcileOnDemand(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, agent *appsv1.Deployment, split *spotSplit) error {
+	dep := &appsv1.Deployment{}
+	err := r.Get(ctx, types.NamespacedName{Name: onDemandName(ad), Namespace: ad.Namespace}, dep)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+	if exists && !metav1.IsControlledBy(dep, ad) {
+		return fmt.Errorf("deployment %s exists and is not owned by the AgentDeployment", dep.Name)
+	}
+	if !spotFallback(ad) {
+		if !exists {
+			return nil
+		}
+		r.Log.Info("Deleting on-demand Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
+		return client.IgnoreNotFound(r.Delete(ctx, dep))
+	}
+
+	labels := labelsForAgentDeployment(ad.Name)
+	labels[capacityTypeLabel] = capacityOnDemand
+	template := agent.Spec.Template.DeepCopy()
+	for k, v := range labels {
+		template.Labels[k] = v
+	}
+	label, value := spotNodeLabel(spotPlacement(ad))
+	template.Spec.Tolerations = nil
+	template.Spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+			MatchExpressions: []corev1.NodeSelectorRequirement{{Key: label, Operator: corev1.NodeSelectorOpNotIn, Values: []string{value}}},
+		}}},
+	}}
+	replicas := split.onDemand
+
+	if !exists {
+		dep = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: onDemandName(ad), Namespace: ad.Namespace, Labels: labels},
+			Spec: appsv1.DeploymentSpec{
+				Replicas:        &replicas,
+				Selector:        &metav1.LabelSelector{MatchLabels: labels},
+				Strategy:        agent.Spec.Strategy,
+				MinReadySeconds: agent.Spec.MinReadySeconds,
+				Template:        *template,
+			},
+		}
+		if err := controllerutil.SetControllerReference(ad, dep, r.Scheme); err != nil {
+			return err
+		}
+		r.Log.Info("Creating on-demand Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
+		return r.Create(ctx, dep)
+	}
+
+	if equality.Semantic.DeepDerivative(*template, dep.Spec.Template) &&
+		equality.Semantic.DeepEqual(&replicas, dep.Spec.Replicas) &&
+		dep.Spec.MinReadySeconds == agent.Spec.MinReadySeconds {
+		return nil
+	}
+	dep.Spec.Replicas = &replicas
+	dep.Spec.MinReadySeconds = agent.Spec.MinReadySeconds
+	dep.Spec.Template = *template
+	r.Log.Info("Updating on-demand Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
+	return r.Update(ctx, dep)
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const defaultGPUPoolLabel = "eks.amazonaws.com/nodegroup"
+
+// gpuPoolLabel returns the node label naming the node pool of a node
+func gpuPoolLabel(policy *agentopsv1alpha1.SpreadPolicySpec) string {
+	if policy == nil || policy.GPUPoolLabel == "" {
+		return defaultGPUPoolLabel
+	}
+	return policy.GPUPoolLabel
+}
+
+// spreadTopologyKey returns the node label of a spread topology
+func spreadTopologyKey(policy *agentopsv1alpha1.SpreadPolicySpec, topology agentopsv1alpha1.SpreadTopology) string {
+	switch topology {
+	case agentopsv1alpha1.SpreadZone:
+		return corev1.LabelTopologyZone
+	case agentopsv1alpha1.SpreadNode:
+		return corev1.LabelHostname
+	}
+	return gpuPoolLabel(policy)
+}
+
+// topologySpreadConstraintsForAgentDeployment renders spec.spreadPolicy as one
+// constraint per topology
+func topologySpreadConstraintsForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) []corev1.TopologySpreadConstraint {
+	policy := ad.Spec.SpreadPolicy
+	if policy == nil {
+		return nil
+	}
+	maxSkew := policy.MaxSkew
+	if maxSkew == 0 {
+		maxSkew = 1
+	}
+	whenUnsatisfiable := corev1.ScheduleAnyway
+	if policy.WhenUnsatisfiable == string(corev1.DoNotSchedule) {
+		whenUnsatisfiable = corev1.DoNotSchedule
+	}
+	var constraints []corev1.TopologySpreadConstraint
+	for _, topology := range policy.Across {
+		constraints = append(constraints, corev1.TopologySpreadConstraint{
+			MaxSkew:           maxSkew,
+			TopologyKey:       spreadTopologyKey(policy, topology),
+			WhenUnsatisfiable: whenUnsatisfiable,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: labelsForAgentDeployment(ad.Name)},
+		})
+	}
+	return constraints
+}
+
+// spreadRemoved reports whether the live pod template has topology spread
+// constraints the desired one dropped, which a derivative comparison cannot see
+func spreadRemoved(desired, current *appsv1.Deployment) bool {
+	return len(desired.Spec.Template.Spec.TopologySpreadConstraints) == 0 &&
+		len(current.Spec.Template.Spec.TopologySpreadConstraints) > 0
+}
+
+// spreadsAcross reports whether spec.spreadPolicy spreads the pods over a topology
+func spreadsAcross(ad *agentopsv1alpha1.AgentDeployment, topology agentopsv1alpha1.SpreadTopology) bool {
+	if ad.Spec.SpreadPolicy == nil {
+		return false
+	}
+	for _, t := range ad.Spec.SpreadPolicy.Across {
+		if t == topology {
+			return true
+		}
+	}
+	return false
+}
+
+// setTopologyStatus records how the ready agent pods are spread across zones,
+// nodes and, with a gpu-pool spread policy, node pools. With spec.placement.spot
+// the pods on spot and on other nodes are counted too.
+func (r *AgentDeploymentReconciler) setTopologyStatus(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(ad.Namespace), client.MatchingLabels(labelsForAgentDeployment(ad.Name))); err != nil {
+		return err
+	}
+
+	perNode := map[string]int32{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp == nil && pod.Spec.NodeName != "" && podReady(pod) {
+			perNode[pod.Spec.NodeName]++
+		}
+	}
+	if len(perNode) == 0 {
+		ad.Status.Topology = nil
+		return nil
+	}
+
+	poolLabel := ""
+	if spreadsAcross(ad, agentopsv1alpha1.SpreadGPUPool) {
+		poolLabel = gpuPoolLabel(ad.Spec.SpreadPolicy)
+	}
+	topology := &agentopsv1alpha1.TopologyStatus{Nodes: int32(len(perNode))}
+	zones, pools := map[string]int32{}, map[string]int32{}
+	for name, count := range perNode {
+		if count > topology.MaxPerNode {
+			topology.MaxPerNode = count
+		}
+		node := &corev1.Node{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name}, node); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if zone := node.Labels[corev1.LabelTopologyZone]; zone != "" {
+			zones[zone] += count
+		}
+		if pool := node.Labels[poolLabel]; poolLabel != "" && pool != "" {
+			pools[pool] += count
+		}
+		if spot := spotPlacement(ad); spot != nil {
+			if label, value := spotNodeLabel(spot); node.Labels[label] == value {
+				topology.Spot += count
+			} else {
+				topology.OnDemand += count
+			}
+		}
+	}
+	topology.Zones = topologyDomains(zones)
+	topology.GPUPools = topologyDomains(pools)
+	ad.Status.Topology = topology
+	return nil
+}
+
+// topologyDomains returns the domains of counts sorted by name
+func topologyDomains(counts map[string]int32) []agentopsv1alpha1.TopologyDomain {
+	var domains []agentopsv1alpha1.TopologyDomain
+	for name, replicas := range counts {
+		domains = append(domains, agentopsv1alpha1.TopologyDomain{Name: name, Replicas: replicas})
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Name < domains[j].Name })
+	return domains
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// patchStatus persists the status computed on obj. The status is carried over to
+// the latest copy of the object and sent as a merge patch guarded by its
+// resourceVersion, so a reconcile working on a stale object no longer fails with
+// a conflict; conflicts from concurrent writers are retried against a fresh read.
+// Nothing is written when the status is unchanged, so periodic reconciles do not
+// bump the resourceVersion.
+func patchStatus(ctx context.Context, c client.Client, obj client.Object) error {
+	computed, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	desired := computed["status"]
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := obj.DeepCopyObject().(client.Object)
+		if err := c.Get(ctx, client.ObjectKeyFromObject(obj), latest); err != nil {
+			return err
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(latest)
+		if err != nil {
+			return err
+		}
+		if equality.Semantic.DeepEqual(content["status"], desired) {
+			return nil
+		}
+
+		base := latest.DeepCopyObject().(client.Object)
+		content["status"] = desired
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, latest); err != nil {
+			return err
+		}
+		return c.Status().Patch(ctx, latest, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+	})
+}
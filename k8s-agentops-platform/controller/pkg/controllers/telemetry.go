@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	collectorImage            = "otel/opentelemetry-collector:0.88.0"
+	collectorConfigKey        = "config.yaml"
+	collectorConfigVolume     = "otel-collector-config"
+	collectorConfigAnnotation = "agentops.io/otel-collector-config-hash"
+	collectorGRPCEndpoint     = "127.0.0.1:4317"
+	collectorHTTPEndpoint     = "127.0.0.1:4318"
+	otlpProtocolGRPC          = "grpc"
+	otlpProtocolHTTP          = "http/protobuf"
+)
+
+// collectorSidecarEnabled reports whether the agent exports through a collector sidecar
+func collectorSidecarEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	t := ad.Spec.Telemetry
+	return t != nil && t.CollectorSidecar != nil && t.CollectorSidecar.Enabled
+}
+
+// collectorConfigMapName returns the name of the collector sidecar ConfigMap
+func collectorConfigMapName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-otel-collector"
+}
+
+// telemetryEnvForAgentDeployment returns the OpenTelemetry SDK environment of the
+// agent container, or nil when telemetry is not configured
+func telemetryEnvForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) []corev1.EnvVar {
+	t := ad.Spec.Telemetry
+	if t == nil {
+		return nil
+	}
+	endpoint, protocol := t.Endpoint, t.Protocol
+	if protocol == "" {
+		protocol = otlpProtocolGRPC
+	}
+	// The sidecar receives on localhost and forwards to the configured endpoint
+	if collectorSidecarEnabled(ad) {
+		endpoint, protocol = "http://"+collectorGRPCEndpoint, otlpProtocolGRPC
+	}
+	ratio := 1.0
+	if t.SamplingPercent != nil {
+		ratio = float64(*t.SamplingPercent) / 100
+	}
+
+	attrs := []string{
+		"k8s.namespace.name=" + ad.Namespace,
+		"k8s.pod.name=$(AGENTOPS_POD_NAME)",
+		"k8s.deployment.name=" + ad.Name,
+		"agentops.model=" + ad.Spec.Model,
+	}
+	keys := make([]string, 0, len(t.ResourceAttributes))
+	for k := range t.ResourceAttributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attrs = append(attrs, k+"="+t.ResourceAttributes[k])
+	}
+
+	return []corev1.EnvVar{
+		{Name: "AGENTOPS_POD_NAME", ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+		}},
+		{Name: "OTEL_SERVICE_NAME", Value: ad.Name},
+		{Name: "OTEL_TRACES_EXPORTER", Value: "otlp"},
+		{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: endpoint},
+		{Name: "OTEL_EXPORTER_OTLP_PROTOCOL", Value: protocol},
+		{Name: "OTEL_PROPAGATORS", Value: "tracecontext,baggage"},
+		{Name: "OTEL_TRACES_SAMPLER", Value: "parentbased_traceidratio"},
+		{Name: "OTEL_TRACES_SAMPLER_ARG", Value: fmt.Sprintf("%g", ratio)},
+		{Name: "OTEL_RESOURCE_ATTRIBUTES", Value: strings.Join(attrs, ",")},
+	}
+}
+
+// collectorConfig renders the configuration of the collector sidecar
+func collectorConfig(ad *agentopsv1alpha1.AgentDeployment) (string, error) {
+	t := ad.Spec.Telemetry
+	exporter, exporterConfig := "otlp", map[string]interface{}{}
+	if t.Protocol == otlpProtocolHTTP {
+		exporter = "otlphttp"
+		exporterConfig["endpoint"] = t.Endpoint
+	} else {
+		// The gRPC exporter takes host:port and a separate TLS switch
+		u, err := url.Parse(t.Endpoint)
+		if err != nil || u.Host == "" {
+			return "", fmt.Errorf("invalid telemetry endpoint %q", t.Endpoint)
+		}
+		exporterConfig["endpoint"] = u.Host
+		exporterConfig["tls"] = map[string]interface{}{"insecure": u.Scheme != "https"}
+	}
+
+	config := map[string]interface{}{
+		"receivers": map[string]interface{}{
+			"otlp": map[string]interface{}{
+				"protocols": map[string]interface{}{
+					"grpc": map[string]interface{}{"endpoint": collectorGRPCEndpoint},
+					"http": map[string]interface{}{"endpoint": collectorHTTPEndpoint},
+				},
+			},
+		},
+		"processors": map[string]interface{}{
+			"memory_limiter": map[string]interface{}{"check_interval": "1s", "limit_mib": 100},
+			"batch":          map[string]interface{}{},
+		},
+		"exporters": map[string]interface{}{exporter: exporterConfig},
+		"service": map[string]interface{}{
+			"pipelines": map[string]interface{}{
+				"traces": map[string]interface{}{
+					"receivers":  []string{"otlp"},
+					"processors": []string{"memory_limiter", "batch"},
+					"exporters":  []string{exporter},
+				},
+			},
+		},
+	}
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// collectorSidecar returns the collector container and its config volume, and the
+// hash of the configuration that rolls the pods when it changes
+func collectorSidecar(ad *agentopsv1alpha1.AgentDeployment) (corev1.Container, corev1.Volume, string, error) {
+	config, err := collectorConfig(ad)
+	if err != nil {
+		return corev1.Container{}, corev1.Volume{}, "", err
+	}
+	sum := sha256.Sum256([]byte(config))
+
+	image := collectorImage
+	if ad.Spec.Telemetry.CollectorSidecar.Image != "" {
+		image = ad.Spec.Telemetry.CollectorSidecar.Image
+	}
+	container := corev1.Container{
+		Name:  "otel-collector",
+		Image: image,
+		Args:  []string{"--config=/etc/otelcol/" + collectorConfigKey},
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("50m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			},
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+		},
+		VolumeMounts: []corev1.VolumeMount{{Name: collectorConfigVolume, MountPath: "/etc/otelcol", ReadOnly: true}},
+	}
+	volume := corev1.Volume{
+		Name: collectorConfigVolume,
+		VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: collectorConfigMapName(ad)},
+		}},
+	}
+	return container, volume, hex.EncodeToString(sum[:8]), nil
+}
+
+// reconcileCollectorConfigMap publishes the collector sidecar configuration, or
+// removes it when the sidecar is disabled
+func (r *AgentDeploymentReconciler) reconcileCollectorConfigMap(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: collectorConfigMapName(ad), Namespace: ad.Namespace},
+	}
+	if !collectorSidecarEnabled(ad) {
+		if err := r.Delete(ctx, cm); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	config, err := collectorConfig(ad)
+	if err != nil {
+		return err
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Labels = labelsForAgentDeployment(ad.Name)
+		cm.Data = map[string]string{collectorConfigKey: config}
+		return controllerutil.SetControllerReference(ad, cm, r.Scheme)
+	})
+	return err
+}
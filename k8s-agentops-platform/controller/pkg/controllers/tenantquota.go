@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/cost"
+)
+
+// quotaRetryInterval is how often an agent rejected by a TenantQuota checks whether
+// other agents made room
+const quotaRetryInterval = time.Minute
+
+// quotaUsage returns what an agent counts against TenantQuotas: its pods and GPUs at
+// maximum scale, including the model server
+func quotaUsage(ad *agentopsv1alpha1.AgentDeployment) agentopsv1alpha1.TenantQuotaUsage {
+	replicas := specReplicas(ad)
+	if autoscalingEnabled(ad) {
+		_, replicas = autoscalingBounds(ad)
+	}
+	usage := agentopsv1alpha1.TenantQuotaUsage{
+		Agents:   1,
+		Replicas: replicas,
+		GPUs:     replicas * gpusOf(*agentResources(ad)),
+	}
+	if modelServerEnabled(ad) {
+		spec := ad.Spec.Serving.SelfHosted
+		serverReplicas := int32(1)
+		if spec.Replicas != nil {
+			serverReplicas = *spec.Replicas
+		}
+		usage.GPUs += serverReplicas * pipelineStages(ad) * modelServerGPUs(ad)
+	}
+	return usage
+}
+
+// gpusOf returns the GPUs a container asks for. Extended resources only need a
+// limit, which then doubles as the request.
+func gpusOf(res corev1.ResourceRequirements) int32 {
+	var gpus int64
+	for name, q := range res.Limits {
+		if cost.IsGPU(name) {
+			gpus += q.Value()
+		}
+	}
+	return int32(gpus)
+}
+
+// addUsage returns the sum of two usages
+func addUsage(a, b agentopsv1alpha1.TenantQuotaUsage) agentopsv1alpha1.TenantQuotaUsage {
+	return agentopsv1alpha1.TenantQuotaUsage{
+		Agents:   a.Agents + b.Agents,
+		Replicas: a.Replicas + b.Replicas,
+		GPUs:     a.GPUs + b.GPUs,
+	}
+}
+
+// quotaViolations returns the limits of the quota that total exceeds. Only limits
+// of dimensions that grew are checked, so the webhook lets updates through that do
+// not ask for more even when a quota was lowered below the namespace's usage.
+func quotaViolations(quota *agentopsv1alpha1.TenantQuota, total, grown agentopsv1alpha1.TenantQuotaUsage) []string {
+	var violations []string
+	check := func(what string, max *int32, used, grew int32) {
+		if max != nil && grew > 0 && used > *max {
+			violations = append(violations, fmt.Sprintf("%s %d exceeds %d", what, used, *max))
+		}
+	}
+	check("agents", quota.Spec.MaxAgents, total.Agents, grown.Agents)
+	check("replicas", quota.Spec.MaxReplicas, total.Replicas, grown.Replicas)
+	check("GPUs", quota.Spec.MaxGPUs, total.GPUs, grown.GPUs)
+	return violations
+}
+
+// modelAllowedByQuota reports whether the quota allows the model
+func modelAllowedByQuota(quota *agentopsv1alpha1.TenantQuota, model string) bool {
+	return len(quota.Spec.AllowedModels) == 0 || containsString(quota.Spec.AllowedModels, model)
+}
+
+// quotaMessage joins the violations of a quota into one message
+func quotaMessage(quota *agentopsv1alpha1.TenantQuota, violations []string) string {
+	return fmt.Sprintf("TenantQuota %s: %s", quota.Name, strings.Join(violations, ", "))
+}
+
+// evaluateQuotas admits the agents of a namespace in creation order and returns
+// their total usage and, by name, why each agent that does not fit is rejected.
+// Rejected agents do not count against the agents created after them.
+func evaluateQuotas(quotas []agentopsv1alpha1.TenantQuota, ads []agentopsv1alpha1.AgentDeployment) (agentopsv1alpha1.TenantQuotaUsage, map[string]string) {
+	sort.Slice(ads, func(i, j int) bool {
+		if !ads[i].CreationTimestamp.Equal(&ads[j].CreationTimestamp) {
+			return ads[i].CreationTimestamp.Before(&ads[j].CreationTimestamp)
+		}
+		return ads[i].Name < ads[j].Name
+	})
+
+	var used, admitted agentopsv1alpha1.TenantQuotaUsage
+	rejected := map[string]string{}
+	for i := range ads {
+		ad := &ads[i]
+		if !ad.DeletionTimestamp.IsZero() {
+			continue
+		}
+		usage := quotaUsage(ad)
+		used = addUsage(used, usage)
+		total := addUsage(admitted, usage)
+		var messages []string
+		for j := range quotas {
+			violations := quotaViolations(&quotas[j], total, usage)
+			if !modelAllowedByQuota(&quotas[j], ad.Spec.Model) {
+				violations = append(violations, fmt.Sprintf("model %s is not allowed", ad.Spec.Model))
+			}
+			if len(violations) > 0 {
+				messages = append(messages, quotaMessage(&quotas[j], violations))
+			}
+		}
+		if len(messages) > 0 {
+			rejected[ad.Name] = strings.Join(messages, "; ")
+			continue
+		}
+		admitted = total
+	}
+	return used, rejected
+}
+
+// quotaRejection returns why the TenantQuotas of the namespace reject the agent, or
+// "" if it fits
+func (r *AgentDeploymentReconciler) quotaRejection(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (string, error) {
+	quotas := &agentopsv1alpha1.TenantQuotaList{}
+	if err := r.List(ctx, quotas, client.InNamespace(ad.Namespace)); err != nil {
+		return "", err
+	}
+	if len(quotas.Items) == 0 {
+		return "", nil
+	}
+	list := &agentopsv1alpha1.AgentDeploymentList{}
+	if err := r.List(ctx, list, client.InNamespace(ad.Namespace)); err != nil {
+		return "", err
+	}
+	// The in-memory agent carries the AgentOpsConfig defaults
+	for i := range list.Items {
+		if list.Items[i].Name == ad.Name {
+			list.Items[i] = *ad
+		}
+	}
+	_, rejected := evaluateQuotas(quotas.Items, list.Items)
+	return rejected[ad.Name], nil
+}
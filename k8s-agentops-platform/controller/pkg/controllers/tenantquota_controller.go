@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+// TenantQuotaReconciler reconciles a TenantQuota object. Quotas are enforced by the
+// AgentDeployment webhook and controller; this controller reports the namespace's
+// usage and the agents the quota rejects.
+type TenantQuotaReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=tenantquotas,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=tenantquotas/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments,verbs=get;list;watch
+
+// Reconcile records the usage of the quota's namespace
+func (r *TenantQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("tenantquota", req.NamespacedName)
+
+	quota := &agentopsv1alpha1.TenantQuota{}
+	if err := r.Get(ctx, req.NamespacedName, quota); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get TenantQuota")
+		return ctrl.Result{}, err
+	}
+
+	list := &agentopsv1alpha1.AgentDeploymentList{}
+	if err := r.List(ctx, list, client.InNamespace(quota.Namespace)); err != nil {
+		log.Error(err, "Failed to list AgentDeployments")
+		return ctrl.Result{}, err
+	}
+	used, rejected := evaluateQuotas([]agentopsv1alpha1.TenantQuota{*quota}, list.Items)
+	names := make([]string, 0, len(rejected))
+	for name := range rejected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	quota.Status.Used = used
+	quota.Status.Rejected = names
+	if len(names) > 0 {
+		conditions.Set(&quota.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonQuotaExceeded,
+			fmt.Sprintf("%d AgentDeployments exceed the quota: %s", len(names), rejected[names[0]]), quota.Generation)
+	} else {
+		conditions.Set(&quota.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonAsExpected,
+			fmt.Sprintf("%d AgentDeployments within the quota", used.Agents), quota.Generation)
+	}
+	quota.Status.ObservedGeneration = quota.Generation
+	return ctrl.Result{}, patchStatus(ctx, r.Client, quota)
+}
+
+// quotasForAgentDeployment maps an AgentDeployment to the quotas in its namespace
+func (r *TenantQuotaReconciler) quotasForAgentDeployment(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &agentopsv1alpha1.TenantQuotaList{}
+	if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list TenantQuotas", "AgentDeployment", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, quota := range list.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: quota.Name, Namespace: quota.Namespace},
+		})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *TenantQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.TenantQuota{}).
+		Watches(&agentopsv1alpha1.AgentDeployment{}, handler.EnqueueRequestsFromMapFunc(r.quotasForAgentDeployment)).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("TenantQuota", tracing.Reconciler("TenantQuota", r)))
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/catalog"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+// budgetRefreshInterval is how often usage is re-read; usage changes without any
+// Kubernetes event, so budgets are polled
+const budgetRefreshInterval = 5 * time.Minute
+
+// UsageSource reports token consumption of agents
+type UsageSource interface {
+	TokensByModel(ctx context.Context, namespace string, agents []string, window time.Duration) (map[string]float64, error)
+}
+
+// TokenBudgetReconciler reconciles a TokenBudget object
+type TokenBudgetReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+	Usage  UsageSource
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=tokenbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=tokenbudgets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments,verbs=get;list;watch
+
+// Reconcile aggregates the usage of the selected agents and records whether the budget is exceeded
+func (r *TokenBudgetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("tokenbudget", req.NamespacedName)
+
+	budget := &agentopsv1alpha1.TokenBudget{}
+	if err := r.Get(ctx, req.NamespacedName, budget); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get TokenBudget")
+		return ctrl.Result{}, err
+	}
+
+	agents, err := r.agentsForBudget(ctx, budget)
+	if err != nil {
+		log.Error(err, "Failed to list AgentDeployments for TokenBudget")
+		return ctrl.Result{}, err
+	}
+	names := make([]string, 0, len(agents))
+	for _, ad := range agents {
+		names = append(names, ad.Name)
+	}
+
+	now := time.Now().UTC()
+	start := budgetPeriodStart(budget.Spec.Period, now)
+	tokensByModel, err := r.Usage.TokensByModel(ctx, budget.Namespace, names, now.Sub(start))
+	if err != nil {
+		// Keep the last known usage; flapping enforcement on a metrics outage would
+		// scale agents up and down for no reason
+		log.Error(err, "Failed to read token usage")
+		conditions.Set(&budget.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonUsageUnavailable,
+			err.Error(), budget.Generation)
+		budget.Status.ObservedGeneration = budget.Generation
+		if statusErr := patchStatus(ctx, r.Client, budget); statusErr != nil {
+			return ctrl.Result{}, statusErr
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	var tokens, cost float64
+	for model, n := range tokensByModel {
+		tokens += n
+		cost += catalog.Cost(model, n)
+	}
+	costQuantity := resource.MustParse(fmt.Sprintf("%.2f", cost))
+
+	periodStart := metav1.NewTime(start)
+	budget.Status.PeriodStart = &periodStart
+	budget.Status.TokensUsed = int64(tokens)
+	budget.Status.Cost = &costQuantity
+	budget.Status.AgentDeployments = names
+
+	conditions.Set(&budget.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonAsExpected,
+		fmt.Sprintf("Tracking %d AgentDeployments", len(names)), budget.Generation)
+	reason, message := budgetVerdict(budget, tokens, cost)
+	budget.Status.Exceeded = reason != conditions.ReasonWithinBudget
+	status := metav1.ConditionFalse
+	if budget.Status.Exceeded {
+		status = metav1.ConditionTrue
+		log.Info("TokenBudget exceeded", "Reason", reason, "Enforcement", budget.Spec.Enforcement)
+	}
+	conditions.Set(&budget.Status.Conditions, conditions.BudgetExceeded, status, reason, message, budget.Generation)
+
+	budget.Status.ObservedGeneration = budget.Generation
+	if err := patchStatus(ctx, r.Client, budget); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Come back at the period boundary at the latest so a reset is picked up promptly
+	next := budgetRefreshInterval
+	if untilReset := budgetPeriodEnd(budget.Spec.Period, start).Sub(now); untilReset < next {
+		next = untilReset + time.Second
+	}
+	return ctrl.Result{RequeueAfter: next}, nil
+}
+
+// agentsForBudget returns the AgentDeployments selected by the budget, sorted by name
+func (r *TokenBudgetReconciler) agentsForBudget(ctx context.Context, budget *agentopsv1alpha1.TokenBudget) ([]agentopsv1alpha1.AgentDeployment, error) {
+	selector := labels.Everything()
+	if budget.Spec.Selector != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(budget.Spec.Selector)
+		if err != nil {
+			return nil, err
+		}
+	}
+	list := &agentopsv1alpha1.AgentDeploymentList{}
+	if err := r.List(ctx, list, client.InNamespace(budget.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Name < list.Items[j].Name })
+	return list.Items, nil
+}
+
+// budgetVerdict compares usage with the budget limits and returns the BudgetExceeded reason and message
+func budgetVerdict(budget *agentopsv1alpha1.TokenBudget, tokens, cost float64) (string, string) {
+	if max := budget.Spec.MaxTokens; max != nil && tokens >= float64(*max) {
+		return conditions.ReasonTokenLimitExceeded, fmt.Sprintf("%d of %d tokens used", int64(tokens), *max)
+	}
+	if max := budget.Spec.MaxCost; max != nil && cost >= max.AsApproximateFloat64() {
+		return conditions.ReasonCostLimitExceeded, fmt.Sprintf("$%.2f of $%s spent", cost, max.String())
+	}
+	return conditions.ReasonWithinBudget, fmt.Sprintf("%d tokens, $%.2f spent this period", int64(tokens), cost)
+}
+
+// budgetPeriodStart returns the start of the accounting period containing now
+func budgetPeriodStart(period string, now time.Time) time.Time {
+	if period == agentopsv1alpha1.BudgetPeriodDaily {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// budgetPeriodEnd returns the end of the accounting period beginning at start
+func budgetPeriodEnd(period string, start time.Time) time.Time {
+	if period == agentopsv1alpha1.BudgetPeriodDaily {
+		return start.AddDate(0, 0, 1)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *TokenBudgetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.TokenBudget{}).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("TokenBudget", tracing.Reconciler("TokenBudget", r)))
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// attachedTool is a ToolServer of spec.tools with the attach mode the agent uses
+type attachedTool struct {
+	server  *agentopsv1alpha1.ToolServer
+	sidecar bool
+}
+
+// toolsServiceAccountName returns the name of the ServiceAccount, Role and
+// RoleBinding granting the sidecar tools of an agent their rules
+func toolsServiceAccountName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-tools"
+}
+
+// resolveTools looks up the ToolServers of spec.tools and records in ToolsReady
+// whether all of them can be attached. A missing ToolServer, or a sidecar whose
+// port another container of the pod already uses, is left out; the agent runs
+// with the others.
+func (r *AgentDeploymentReconciler) resolveTools(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) ([]attachedTool, error) {
+	if len(ad.Spec.Tools) == 0 {
+		meta.RemoveStatusCondition(&ad.Status.Conditions, conditions.ToolsReady)
+		return nil, nil
+	}
+
+	ports := map[int32]string{}
+	for _, p := range containerPortsForAgentDeployment(ad) {
+		ports[p.ContainerPort] = "the agent"
+	}
+	var tools []attachedTool
+	var missing, conflicts []string
+	for _, ref := range ad.Spec.Tools {
+		ts := &agentopsv1alpha1.ToolServer{}
+		err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ad.Namespace}, ts)
+		if errors.IsNotFound(err) {
+			missing = append(missing, ref.Name)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		attach := ref.Attach
+		if attach == "" {
+			attach = ts.Spec.Attach
+		}
+		tool := attachedTool{server: ts, sidecar: attach == agentopsv1alpha1.ToolAttachSidecar}
+		if tool.sidecar {
+			port := toolPort(ts)
+			if owner, taken := ports[port]; taken {
+				conflicts = append(conflicts, fmt.Sprintf("%s listens on port %d of %s", ref.Name, port, owner))
+				continue
+			}
+			ports[port] = ref.Name
+		}
+		tools = append(tools, tool)
+	}
+
+	gen := ad.Generation
+	switch {
+	case len(missing) > 0:
+		conditions.Set(&ad.Status.Conditions, conditions.ToolsReady, metav1.ConditionFalse, conditions.ReasonToolServerNotFound,
+			fmt.Sprintf("ToolServer %s not found", strings.Join(missing, ", ")), gen)
+	case len(conflicts) > 0:
+		conditions.Set(&ad.Status.Conditions, conditions.ToolsReady, metav1.ConditionFalse, conditions.ReasonInvalidSpec,
+			fmt.Sprintf("Cannot attach sidecar: %s", strings.Join(conflicts, "; ")), gen)
+	default:
+		conditions.Set(&ad.Status.Conditions, conditions.ToolsReady, metav1.ConditionTrue, conditions.ReasonAsExpected,
+			fmt.Sprintf("%d tool servers attached", len(tools)), gen)
+	}
+	return tools, nil
+}
+
+// toolRules returns the rules of the sidecar tools of an agent
+func toolRules(tools []attachedTool) []rbacv1.PolicyRule {
+	var rules []rbacv1.PolicyRule
+	for _, tool := range tools {
+		if tool.sidecar {
+			rules = append(rules, tool.server.Spec.Rules...)
+		}
+	}
+	return rules
+}
+
+// reconcileToolsRBAC grants the agent pods the rules of their sidecar tools, which
+// share the pod's ServiceAccount, or removes the grant when they have none
+func (r *AgentDeploymentReconciler) reconcileToolsRBAC(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, tools []attachedTool) error {
+	return reconcileToolRBAC(ctx, r.Client, r.Scheme, ad, toolsServiceAccountName(ad), agentServiceAccountName(ad), toolRules(tools))
+}
+
+// agentServiceAccountName returns the ServiceAccount the agent pods run under when
+// they need one: the account of spec.cloudIdentity, which sidecar tools share, or
+// the one granting the tools their rules
+func agentServiceAccountName(ad *agentopsv1alpha1.AgentDeployment) string {
+	if ad.Spec.CloudIdentity != nil {
+		return cloudIdentityServiceAccountName(ad)
+	}
+	return toolsServiceAccountName(ad)
+}
+
+// toolsOverlay attaches the tool servers to the agent: sidecar tools run next to
+// it, and every tool is described to the agent by TOOL_SERVERS and TOOL_<NAME>_*
+// variables holding its URL, transport, capabilities and token
+func toolsOverlay(ad *agentopsv1alpha1.AgentDeployment, tools []attachedTool) deploymentOverlay {
+	return func(dep *appsv1.Deployment) {
+		if len(tools) == 0 {
+			return
+		}
+		spec := &dep.Spec.Template.Spec
+		names := make([]string, 0, len(tools))
+		var env []corev1.EnvVar
+		for _, tool := range tools {
+			ts := tool.server
+			names = append(names, ts.Name)
+			prefix := envPrefix("TOOL", ts.Name)
+			url := toolServerEndpoint(ts)
+			if tool.sidecar {
+				url = fmt.Sprintf("http://127.0.0.1:%d%s", toolPort(ts), toolPath(ts))
+				spec.Containers = append(spec.Containers, toolContainer(ts, "tool-"+ts.Name))
+			}
+			env = append(env,
+				corev1.EnvVar{Name: prefix + "URL", Value: url},
+				corev1.EnvVar{Name: prefix + "TRANSPORT", Value: toolTransport(ts)},
+			)
+			env = appendNonEmpty(env, prefix+"CAPABILITIES", strings.Join(ts.Spec.Capabilities, ","))
+			if auth := ts.Spec.Auth; auth != nil {
+				env = append(env, corev1.EnvVar{Name: prefix + "TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: auth.TokenSecretRef.DeepCopy()}})
+			}
+		}
+		agent := &spec.Containers[0]
+		agent.Env = append(agent.Env, corev1.EnvVar{Name: "TOOL_SERVERS", Value: strings.Join(names, ",")})
+		agent.Env = append(agent.Env, env...)
+		if len(toolRules(tools)) > 0 {
+			spec.ServiceAccountName = agentServiceAccountName(ad)
+		}
+	}
+}
+
+// sidecarsRemoved reports whether the live pod template has containers or a
+// ServiceAccount the desired one dropped, which a derivative comparison cannot see
+func sidecarsRemoved(desired, current *appsv1.Deployment) bool {
+	d, c := desired.Spec.Template.Spec, current.Spec.Template.Spec
+	return len(d.Containers) < len(c.Containers) || (d.ServiceAccountName == "" && c.ServiceAccountName != "")
+}
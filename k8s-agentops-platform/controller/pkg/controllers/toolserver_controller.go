@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/api/conditions"
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/debug"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tracing"
+)
+
+const (
+	defaultToolPort      = int32(3000)
+	defaultToolPath      = "/mcp"
+	defaultToolTransport = "streamable-http"
+)
+
+// ToolServerReconciler reconciles a ToolServer object
+type ToolServerReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Options tunes concurrency and the workqueue rate limiter
+	Options controller.Options
+}
+
+// +kubebuilder:rbac:groups=agentops.io,resources=toolservers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=agentops.io,resources=toolservers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=agentops.io,resources=agentdeployments,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=services;serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update;patch;delete;escalate;bind
+
+// Reconcile runs a tool server that agents reach through its Service, or only
+// reports it ready when every agent runs it as a sidecar
+func (r *ToolServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("toolserver", req.NamespacedName)
+
+	ts := &agentopsv1alpha1.ToolServer{}
+	if err := r.Get(ctx, req.NamespacedName, ts); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get ToolServer")
+		return ctrl.Result{}, err
+	}
+
+	served, err := r.toolServerServed(ctx, ts)
+	if err != nil {
+		log.Error(err, "Failed to list AgentDeployments")
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileToolServer(ctx, ts, served); err != nil {
+		log.Error(err, "Failed to reconcile tool server")
+		return ctrl.Result{}, err
+	}
+	ts.Status.ObservedGeneration = ts.Generation
+	return ctrl.Result{}, patchStatus(ctx, r.Client, ts)
+}
+
+// toolServerServed reports whether the tool server runs behind its own Service:
+// in service mode, or when an agent references it with attach: service
+func (r *ToolServerReconciler) toolServerServed(ctx context.Context, ts *agentopsv1alpha1.ToolServer) (bool, error) {
+	if ts.Spec.Attach != agentopsv1alpha1.ToolAttachSidecar {
+		return true, nil
+	}
+	list := &agentopsv1alpha1.AgentDeploymentList{}
+	if err := r.List(ctx, list, client.InNamespace(ts.Namespace)); err != nil {
+		return false, err
+	}
+	for i := range list.Items {
+		for _, ref := range list.Items[i].Spec.Tools {
+			if ref.Name == ts.Name && ref.Attach == agentopsv1alpha1.ToolAttachService {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// reconcileToolServer creates or removes the Deployment, Service and RBAC of the
+// tool server and records its state
+func (r *ToolServerReconciler) reconcileToolServer(ctx context.Context, ts *agentopsv1alpha1.ToolServer, served bool) error {
+	name := toolServerName(ts.Name)
+	gen := ts.Generation
+	if !served {
+		for _, obj := range []client.Object{
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ts.Namespace}},
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ts.Namespace}},
+		} {
+			if err := deleteOwned(ctx, r.Client, ts, obj); err != nil {
+				return err
+			}
+		}
+		if err := reconcileToolRBAC(ctx, r.Client, r.Scheme, ts, name, name, nil); err != nil {
+			return err
+		}
+		ts.Status.Endpoint = ""
+		ts.Status.ReadyReplicas = 0
+		conditions.Set(&ts.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonAsExpected,
+			"Runs as a sidecar of the agents referencing it", gen)
+		return nil
+	}
+
+	if err := reconcileToolRBAC(ctx, r.Client, r.Scheme, ts, name, name, ts.Spec.Rules); err != nil {
+		return err
+	}
+
+	labels := labelsForToolServer(ts.Name)
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ts.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, dep, func() error {
+		replicas := int32(1)
+		if ts.Spec.Replicas != nil {
+			replicas = *ts.Spec.Replicas
+		}
+		dep.Labels = labels
+		dep.Spec.Replicas = &replicas
+		// The selector is immutable after creation
+		if dep.CreationTimestamp.IsZero() {
+			dep.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+		}
+		container := toolContainer(ts, "tool")
+		container.Ports[0].Name = "http"
+		container.ReadinessProbe = &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("http")},
+			},
+			PeriodSeconds: 10,
+		}
+		dep.Spec.Template.Labels = labels
+		dep.Spec.Template.Spec = corev1.PodSpec{Containers: []corev1.Container{container}}
+		if len(ts.Spec.Rules) > 0 {
+			dep.Spec.Template.Spec.ServiceAccountName = name
+		}
+		return controllerutil.SetControllerReference(ts, dep, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ts.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		svc.Labels = labels
+		svc.Spec.Selector = labels
+		svc.Spec.Ports = []corev1.ServicePort{{
+			Name:       "http",
+			Port:       toolPort(ts),
+			TargetPort: intstr.FromString("http"),
+			Protocol:   corev1.ProtocolTCP,
+		}}
+		return controllerutil.SetControllerReference(ts, svc, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	ts.Status.Endpoint = toolServerEndpoint(ts)
+	ts.Status.ReadyReplicas = dep.Status.ReadyReplicas
+	if dep.Status.ReadyReplicas > 0 {
+		conditions.Set(&ts.Status.Conditions, conditions.Ready, metav1.ConditionTrue, conditions.ReasonReplicasReady,
+			fmt.Sprintf("Serving tools at %s", ts.Status.Endpoint), gen)
+	} else {
+		conditions.Set(&ts.Status.Conditions, conditions.Ready, metav1.ConditionFalse, conditions.ReasonReplicasUnavailable,
+			"Waiting for the tool server to become ready", gen)
+	}
+	return nil
+}
+
+// toolContainer returns the container running the tool server, in its own pod or
+// as a sidecar of an agent
+func toolContainer(ts *agentopsv1alpha1.ToolServer, name string) corev1.Container {
+	port := toolPort(ts)
+	env := []corev1.EnvVar{{Name: "PORT", Value: strconv.Itoa(int(port))}}
+	if auth := ts.Spec.Auth; auth != nil {
+		env = append(env, corev1.EnvVar{Name: "AUTH_TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: auth.TokenSecretRef.DeepCopy()}})
+	}
+	return corev1.Container{
+		Name:      name,
+		Image:     ts.Spec.Image,
+		Args:      ts.Spec.Args,
+		Env:       append(env, ts.Spec.Env...),
+		Ports:     []corev1.ContainerPort{{ContainerPort: port, Protocol: corev1.ProtocolTCP}},
+		Resources: ts.Spec.Resources,
+	}
+}
+
+// reconcileToolRBAC gives a tool server the Kubernetes API permissions of rules
+// through a ServiceAccount, Role and RoleBinding of the given name, or removes
+// them when there are no rules. When the pods run under another account, the Role
+// is bound to it and no ServiceAccount of the given name is kept.
+func reconcileToolRBAC(ctx context.Context, c client.Client, scheme *runtime.Scheme, owner client.Object, name, account string, rules []rbacv1.PolicyRule) error {
+	namespace := owner.GetNamespace()
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	role := &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	binding := &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	if len(rules) == 0 {
+		for _, obj := range []client.Object{binding, role, sa} {
+			if err := deleteOwned(ctx, c, owner, obj); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if account != name {
+		if err := deleteOwned(ctx, c, owner, sa); err != nil {
+			return err
+		}
+	} else if _, err := controllerutil.CreateOrUpdate(ctx, c, sa, func() error {
+		return controllerutil.SetControllerReference(owner, sa, scheme)
+	}); err != nil {
+		return err
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, c, role, func() error {
+		role.Rules = rules
+		return controllerutil.SetControllerReference(owner, role, scheme)
+	}); err != nil {
+		return err
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, binding, func() error {
+		// The role ref is immutable, and always names the Role above
+		binding.RoleRef = rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name}
+		binding.Subjects = []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: account, Namespace: namespace}}
+		return controllerutil.SetControllerReference(owner, binding, scheme)
+	})
+	return err
+}
+
+// deleteOwned deletes obj, looked up by its name and namespace, if owner controls it
+func deleteOwned(ctx context.Context, c client.Client, owner, obj client.Object) error {
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(obj, owner) {
+		return nil
+	}
+	return client.IgnoreNotFound(c.Delete(ctx, obj))
+}
+
+// toolServerName returns the name of the Deployment, Service and RBAC objects of
+// a tool server in service mode
+func toolServerName(name string) string {
+	return name + "-tool"
+}
+
+// toolPort returns the port the tool server listens on
+func toolPort(ts *agentopsv1alpha1.ToolServer) int32 {
+	if ts.Spec.Port != 0 {
+		return ts.Spec.Port
+	}
+	return defaultToolPort
+}
+
+// toolPath returns the path of the tool endpoint
+func toolPath(ts *agentopsv1alpha1.ToolServer) string {
+	if ts.Spec.Path != "" {
+		return ts.Spec.Path
+	}
+	return defaultToolPath
+}
+
+// toolTransport returns the transport agents speak to the tool server
+func toolTransport(ts *agentopsv1alpha1.ToolServer) string {
+	if ts.Spec.Transport != "" {
+		return ts.Spec.Transport
+	}
+	return defaultToolTransport
+}
+
+// toolServerEndpoint returns the URL of the tool server behind its Service
+func toolServerEndpoint(ts *agentopsv1alpha1.ToolServer) string {
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d%s", toolServerName(ts.Name), ts.Namespace, toolPort(ts), toolPath(ts))
+}
+
+// labelsForToolServer returns the labels of the tool server pods
+func labelsForToolServer(name string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "tool-server",
+		"app.kubernetes.io/instance":   name,
+		"app.kubernetes.io/managed-by": "agentops-controller",
+	}
+}
+
+// toolServersForAgentDeployment maps an AgentDeployment to the ToolServers it
+// references, which run behind a Service while an agent attaches them that way
+func (r *ToolServerReconciler) toolServersForAgentDeployment(ctx context.Context, obj client.Object) []reconcile.Request {
+	ad, ok := obj.(*agentopsv1alpha1.AgentDeployment)
+	if !ok {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, ref := range ad.Spec.Tools {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: ref.Name, Namespace: ad.Namespace},
+		})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *ToolServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&agentopsv1alpha1.ToolServer{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Service{}).
+		Watches(&agentopsv1alpha1.AgentDeployment{}, handler.EnqueueRequestsFromMapFunc(r.toolServersForAgentDeployment)).
+		WithOptions(r.Options).
+		Complete(debug.Reconciler("ToolServer", tracing.Reconciler("ToolServer", r)))
+}
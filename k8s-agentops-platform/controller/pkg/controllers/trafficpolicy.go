@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/gateway"
+)
+
+const (
+	defaultPriorityHeader = "x-agentops-priority"
+	defaultClientHeader   = "x-agentops-client"
+	defaultBatchShare     = int32(50)
+)
+
+// trafficPolicyEnabled reports whether the gateway sidecar classes and admits the
+// agent's requests
+func trafficPolicyEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.TrafficPolicy != nil
+}
+
+// routingPriority maps a traffic class to the Envoy routing priority holding its
+// concurrency limit
+func routingPriority(class string) string {
+	if class == agentopsv1alpha1.TrafficPriorityBatch {
+		return gateway.PriorityDefault
+	}
+	return gateway.PriorityHigh
+}
+
+// trafficRules renders spec.trafficPolicy into the rules of the gateway sidecar
+// route to backend: requests of listed clients go to pools of their own, the others
+// are classed by the priority header or the agent's priority, and batch requests
+// may only take BatchShare of the agent's concurrency
+func trafficRules(ad *agentopsv1alpha1.AgentDeployment, backend gateway.Backend) []gateway.Rule {
+	tp := ad.Spec.TrafficPolicy
+	class := tp.Priority
+	if class == "" {
+		class = agentopsv1alpha1.TrafficPriorityInteractive
+	}
+	priorityHeader, clientHeader := tp.PriorityHeader, tp.ClientHeader
+	if priorityHeader == "" {
+		priorityHeader = defaultPriorityHeader
+	}
+	if clientHeader == "" {
+		clientHeader = defaultClientHeader
+	}
+
+	var rules []gateway.Rule
+	for _, c := range tp.Clients {
+		priority := class
+		if c.Priority != "" {
+			priority = c.Priority
+		}
+		pool := backend
+		pool.Pool = "client-" + c.Name
+		pool.Limits = []gateway.ConcurrencyLimit{{Priority: routingPriority(priority), MaxRequests: c.MaxConcurrentRequests}}
+		rules = append(rules, gateway.Rule{
+			Headers:  map[string]string{clientHeader: c.Name},
+			Backends: []gateway.Backend{pool},
+			Priority: routingPriority(priority),
+		})
+	}
+
+	if limit := tp.MaxConcurrentRequests; limit != nil {
+		share := defaultBatchShare
+		if tp.BatchShare != nil {
+			share = *tp.BatchShare
+		}
+		batch := *limit * share / 100
+		if batch < 1 {
+			batch = 1
+		}
+		backend.Limits = []gateway.ConcurrencyLimit{
+			{Priority: gateway.PriorityHigh, MaxRequests: *limit},
+			{Priority: gateway.PriorityDefault, MaxRequests: batch},
+		}
+	}
+	for _, c := range []string{agentopsv1alpha1.TrafficPriorityInteractive, agentopsv1alpha1.TrafficPriorityBatch} {
+		rules = append(rules, gateway.Rule{
+			Headers:  map[string]string{priorityHeader: c},
+			Backends: []gateway.Backend{backend},
+			Priority: routingPriority(c),
+		})
+	}
+	return append(rules, gateway.Rule{
+		Backends: []gateway.Backend{backend},
+		Priority: routingPriority(class),
+	})
+}
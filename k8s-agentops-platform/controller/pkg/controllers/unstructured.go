@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// newUnstructured returns an empty object of the given kind. Third-party resources
+// (External Secrets, Prometheus Operator, Istio, ...) are managed as unstructured
+// objects so the controller does not depend on their Go clients.
+func newUnstructured(gvk schema.GroupVersionKind, name, namespace string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	u.SetName(name)
+	u.SetNamespace(namespace)
+	return u
+}
+
+// reconcileUnstructured creates or updates a third-party child object owned by the
+// AgentDeployment. Only labels and spec are managed; other fields are left to the
+// owning operator.
+func (r *AgentDeploymentReconciler) reconcileUnstructured(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, desired *unstructured.Unstructured) error {
+	return applyUnstructured(ctx, r.Client, r.Scheme, ad, desired)
+}
+
+// deleteOwnedUnstructured deletes a third-party object if the agent owns it. Objects
+// users created by hand are left alone, and without the third-party CRDs there is
+// nothing to clean up.
+func (r *AgentDeploymentReconciler) deleteOwnedUnstructured(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, gvk schema.GroupVersionKind, name string) error {
+	return deleteControlledUnstructured(ctx, r.Client, ad, gvk, name)
+}
+
+// deleteControlledUnstructured deletes a third-party object in the owner's namespace
+// if the owner controls it
+func deleteControlledUnstructured(ctx context.Context, c client.Client, owner client.Object, gvk schema.GroupVersionKind, name string) error {
+	obj := newUnstructured(gvk, name, owner.GetNamespace())
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+	if !metav1.IsControlledBy(obj, owner) {
+		return nil
+	}
+	if err := c.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// applyUnstructured creates or updates desired with owner as its controller
+func applyUnstructured(ctx context.Context, c client.Client, scheme *runtime.Scheme, owner client.Object, desired *unstructured.Unstructured) error {
+	obj := newUnstructured(desired.GroupVersionKind(), desired.GetName(), desired.GetNamespace())
+	_, err := controllerutil.CreateOrUpdate(ctx, c, obj, func() error {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		for k, v := range desired.GetLabels() {
+			labels[k] = v
+		}
+		obj.SetLabels(labels)
+		if spec, ok := desired.Object["spec"]; ok {
+			obj.Object["spec"] = spec
+		}
+		return controllerutil.SetControllerReference(owner, obj, scheme)
+	})
+	return err
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+var vpaGVK = schema.GroupVersionKind{Group: "autoscaling.k8s.io", Version: "v1", Kind: "VerticalPodAutoscaler"}
+
+// verticalAutoscalingEnabled reports whether spec.autoscaling.vertical asks for a VPA
+func verticalAutoscalingEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.Autoscaling != nil && ad.Spec.Autoscaling.Vertical != nil && ad.Spec.Autoscaling.Vertical.Enabled
+}
+
+// vpaControlledResources returns the resources the VPA right-sizes. The HPA scales
+// on CPU by default, so with a managed HPA only memory is right-sized unless the
+// user says otherwise.
+func vpaControlledResources(ad *agentopsv1alpha1.AgentDeployment, managedAutoscaler bool) []interface{} {
+	names := ad.Spec.Autoscaling.Vertical.ControlledResources
+	if len(names) == 0 {
+		names = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+		if managedAutoscaler {
+			names = []corev1.ResourceName{corev1.ResourceMemory}
+		}
+	}
+	resources := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		resources = append(resources, string(name))
+	}
+	return resources
+}
+
+// resourceListToUnstructured converts a resource list to its JSON form
+func resourceListToUnstructured(list corev1.ResourceList) map[string]interface{} {
+	out := make(map[string]interface{}, len(list))
+	for name, quantity := range list {
+		out[string(name)] = quantity.String()
+	}
+	return out
+}
+
+// vpaForAgentDeployment returns the VerticalPodAutoscaler of the agent Deployment.
+// Only the agent container is right-sized; sidecars keep their requests.
+func vpaForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment, managedAutoscaler bool) *unstructured.Unstructured {
+	vertical := ad.Spec.Autoscaling.Vertical
+	mode := vertical.Mode
+	if mode == "" {
+		mode = agentopsv1alpha1.VerticalModeOff
+	}
+
+	policy := map[string]interface{}{
+		"containerName":       "agent",
+		"controlledResources": vpaControlledResources(ad, managedAutoscaler),
+	}
+	if len(vertical.MinAllowed) > 0 {
+		policy["minAllowed"] = resourceListToUnstructured(vertical.MinAllowed)
+	}
+	if len(vertical.MaxAllowed) > 0 {
+		policy["maxAllowed"] = resourceListToUnstructured(vertical.MaxAllowed)
+	}
+
+	apiVersion, kind := workloadRef(ad)
+	vpa := newUnstructured(vpaGVK, ad.Name, ad.Namespace)
+	vpa.SetLabels(labelsForAgentDeployment(ad.Name))
+	vpa.Object["spec"] = map[string]interface{}{
+		"targetRef": map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"name":       ad.Name,
+		},
+		"updatePolicy": map[string]interface{}{
+			"updateMode": mode,
+		},
+		"resourcePolicy": map[string]interface{}{
+			"containerPolicies": []interface{}{
+				policy,
+				map[string]interface{}{"containerName": "*", "mode": "Off"},
+			},
+		},
+	}
+	return vpa
+}
+
+// reconcileVPA keeps a VerticalPodAutoscaler on the agent Deployment while vertical
+// autoscaling is enabled and records its recommendation for the agent container in
+// the status. The VPA is removed when vertical autoscaling is disabled.
+func (r *AgentDeploymentReconciler) reconcileVPA(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, managedAutoscaler bool) error {
+	if !verticalAutoscalingEnabled(ad) {
+		ad.Status.RecommendedResources = nil
+		vpa := newUnstructured(vpaGVK, ad.Name, ad.Namespace)
+		if err := r.Get(ctx, client.ObjectKeyFromObject(vpa), vpa); err != nil {
+			// Without the VPA CRDs there is nothing to clean up
+			if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				return nil
+			}
+			return err
+		}
+		if !metav1.IsControlledBy(vpa, ad) {
+			return nil
+		}
+		if err := r.Delete(ctx, vpa); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	desired := vpaForAgentDeployment(ad, managedAutoscaler)
+	if err := r.reconcileUnstructured(ctx, ad, desired); err != nil {
+		return err
+	}
+
+	vpa := newUnstructured(vpaGVK, ad.Name, ad.Namespace)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(vpa), vpa); err != nil {
+		return err
+	}
+	ad.Status.RecommendedResources = vpaRecommendation(vpa, "agent")
+	return nil
+}
+
+// vpaRecommendation returns the target requests recommended for a container, or nil
+// while the recommender has not produced one yet
+func vpaRecommendation(vpa *unstructured.Unstructured, container string) corev1.ResourceList {
+	recommendations, _, _ := unstructured.NestedSlice(vpa.Object, "status", "recommendation", "containerRecommendations")
+	for _, item := range recommendations {
+		rec, ok := item.(map[string]interface{})
+		if !ok || rec["containerName"] != container {
+			continue
+		}
+		target, _, _ := unstructured.NestedStringMap(rec, "target")
+		list := corev1.ResourceList{}
+		for name, value := range target {
+			quantity, err := resource.ParseQuantity(value)
+			if err != nil {
+				continue
+			}
+			list[corev1.ResourceName(name)] = quantity
+		}
+		if len(list) > 0 {
+			return list
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const (
+	// warmedUpCondition is the pod readiness gate set once the warmup hook succeeded
+	warmedUpCondition corev1.PodConditionType = "agentops.io/warmed-up"
+
+	defaultWarmupTimeout = 5 * time.Minute
+
+	// warmupRetryInterval is how soon a failed warmup is attempted again
+	warmupRetryInterval = 30 * time.Second
+)
+
+// Warmer runs warmup hooks against agent pods
+type Warmer interface {
+	HTTP(ctx context.Context, method, url, body string) error
+	Exec(ctx context.Context, namespace, pod, container string, command []string) error
+}
+
+// warmupEnabled reports whether agent pods are warmed up before receiving traffic
+func warmupEnabled(ad *agentopsv1alpha1.AgentDeployment) bool {
+	return ad.Spec.Warmup != nil && (ad.Spec.Warmup.HTTP != nil || ad.Spec.Warmup.Exec != nil)
+}
+
+// warmupTimeout returns how long a single warmup attempt may take
+func warmupTimeout(ad *agentopsv1alpha1.AgentDeployment) time.Duration {
+	if ad.Spec.Warmup.Timeout == nil {
+		return defaultWarmupTimeout
+	}
+	return ad.Spec.Warmup.Timeout.Duration
+}
+
+// applyWarmupGate adds the readiness gate that keeps agent pods out of their Service,
+// and out of the Deployment's ready count, until they are warmed up
+func applyWarmupGate(ad *agentopsv1alpha1.AgentDeployment, pod *corev1.PodSpec) {
+	if warmupEnabled(ad) {
+		pod.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: warmedUpCondition}}
+	}
+}
+
+// reconcileWarmup runs the warmup hook against agent pods whose containers are ready
+// and that are not warmed up yet, and records the outcome in their readiness gate.
+// It returns true when a warmup failed and should be retried.
+func (r *AgentDeploymentReconciler) reconcileWarmup(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) (bool, error) {
+	if !warmupEnabled(ad) || r.Warmer == nil {
+		return false, nil
+	}
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(ad.Namespace), client.MatchingLabels(labelsForAgentDeployment(ad.Name))); err != nil {
+		return false, err
+	}
+
+	retry := false
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !pod.DeletionTimestamp.IsZero() || pod.Status.PodIP == "" || !hasReadinessGate(pod, warmedUpCondition) ||
+			!podConditionTrue(pod, corev1.ContainersReady) || podConditionTrue(pod, warmedUpCondition) {
+			continue
+		}
+
+		condition := corev1.PodCondition{
+			Type:               warmedUpCondition,
+			Status:             corev1.ConditionTrue,
+			Reason:             "WarmedUp",
+			LastTransitionTime: metav1.Now(),
+		}
+		if err := r.warmUp(ctx, ad, pod); err != nil {
+			r.Log.Error(err, "Failed to warm up agent pod", "Pod", pod.Name, "Namespace", pod.Namespace)
+			retry = true
+			condition.Status = corev1.ConditionFalse
+			condition.Reason = "WarmupFailed"
+			condition.Message = err.Error()
+		}
+		if err := r.setPodCondition(ctx, pod, condition); err != nil {
+			return retry, err
+		}
+	}
+	return retry, nil
+}
+
+// warmUp runs the warmup hook of the agent against a single pod
+func (r *AgentDeploymentReconciler) warmUp(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, pod *corev1.Pod) error {
+	ctx, cancel := context.WithTimeout(ctx, warmupTimeout(ad))
+	defer cancel()
+
+	spec := ad.Spec.Warmup
+	if spec.HTTP != nil {
+		method := spec.HTTP.Method
+		if method == "" {
+			method = http.MethodPost
+		}
+		host := net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(agentPortForAgentDeployment(ad))))
+		return r.Warmer.HTTP(ctx, method, fmt.Sprintf("http://%s%s", host, spec.HTTP.Path), spec.HTTP.Body)
+	}
+	return r.Warmer.Exec(ctx, pod.Namespace, pod.Name, "agent", spec.Exec.Command)
+}
+
+// setPodCondition adds or replaces a condition in the pod status
+func (r *AgentDeploymentReconciler) setPodCondition(ctx context.Context, pod *corev1.Pod, condition corev1.PodCondition) error {
+	base := pod.DeepCopy()
+	replaced := false
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == condition.Type {
+			pod.Status.Conditions[i] = condition
+			replaced = true
+		}
+	}
+	if !replaced {
+		pod.Status.Conditions = append(pod.Status.Conditions, condition)
+	}
+	// Pod conditions merge by type, so kubelet updates to other conditions are kept
+	return r.Status().Patch(ctx, pod, client.StrategicMergeFrom(base))
+}
+
+// hasReadinessGate reports whether the pod was created with the readiness gate
+func hasReadinessGate(pod *corev1.Pod, conditionType corev1.PodConditionType) bool {
+	for _, gate := range pod.Spec.ReadinessGates {
+		if gate.ConditionType == conditionType {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPodCondition reports whether the pod has the condition with any status
+func hasPodCondition(pod *corev1.Pod, conditionType corev1.PodConditionType) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == conditionType {
+			return true
+		}
+	}
+	return false
+}
+
+// podConditionTrue reports whether the pod has the condition with status True
+func podConditionTrue(pod *corev1.Pod, conditionType corev1.PodConditionType) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == conditionType {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
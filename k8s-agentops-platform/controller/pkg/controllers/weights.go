@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/tasks"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/weightswap"
+)
+
+const (
+	// weightsVersionAnnotation on the pod template records the weights version the
+	// pods were rolled out with; changing it triggers a rolling update
+	weightsVersionAnnotation = "agentops.io/weights-version"
+	weightsVersionKey        = "weightsVersion"
+	weightsVersionEnv        = "AGENT_WEIGHTS_VERSION"
+)
+
+// weightsConfigMapName returns the name of the ConfigMap holding the desired weights version
+func weightsConfigMapName(ad *agentopsv1alpha1.AgentDeployment) string {
+	return ad.Name + "-weights"
+}
+
+// desiredWeightsVersion returns the weights version requested in the spec, if any
+func desiredWeightsVersion(ad *agentopsv1alpha1.AgentDeployment) string {
+	if ad.Spec.Upgrade == nil {
+		return ""
+	}
+	return ad.Spec.Upgrade.WeightsVersion
+}
+
+// weightsEnvForAgentDeployment returns the env var that tells new pods which weights to
+// load at startup. It is read from a ConfigMap rather than set inline so that changing
+// the version does not by itself roll the pods.
+func weightsEnvForAgentDeployment(ad *agentopsv1alpha1.AgentDeployment) []corev1.EnvVar {
+	if desiredWeightsVersion(ad) == "" {
+		return nil
+	}
+	return []corev1.EnvVar{{
+		Name: weightsVersionEnv,
+		ValueFrom: &corev1.EnvVarSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: weightsConfigMapName(ad)},
+				Key:                  weightsVersionKey,
+			},
+		},
+	}}
+}
+
+// reconcileWeightsConfigMap publishes the desired weights version for new pods
+func (r *AgentDeploymentReconciler) reconcileWeightsConfigMap(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment) error {
+	version := desiredWeightsVersion(ad)
+	if version == "" {
+		return nil
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: weightsConfigMapName(ad), Namespace: ad.Namespace},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Labels = labelsForAgentDeployment(ad.Name)
+		cm.Data = map[string]string{weightsVersionKey: version}
+		return controllerutil.SetControllerReference(ad, cm, r.Scheme)
+	})
+	return err
+}
+
+// weightsRolloutVersion returns the weights version the pod template should carry.
+// With the DualSlot strategy the running pods are asked to swap weights in place by a
+// weight-swap AgentTask while the template keeps its current version, so no pods are
+// replaced. If the swap fails (unsupported runtime, not enough GPU memory) the new
+// version is written to the template and the Deployment falls back to a rolling update.
+func (r *AgentDeploymentReconciler) weightsRolloutVersion(ctx context.Context, ad *agentopsv1alpha1.AgentDeployment, dep *appsv1.Deployment) (string, error) {
+	desired := desiredWeightsVersion(ad)
+	current := dep.Spec.Template.Annotations[weightsVersionAnnotation]
+	if desired == "" || desired == current || current == "" ||
+		ad.Spec.Upgrade.Strategy != agentopsv1alpha1.UpgradeStrategyDualSlot {
+		return desired, nil
+	}
+
+	task, err := tasks.Ensure(ctx, r.Client, r.Scheme, ad, WeightSwapTaskType, map[string]string{
+		"agentDeployment": ad.Name,
+		"version":         desired,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	switch task.Status.Phase {
+	case agentopsv1alpha1.TaskPhaseSucceeded:
+		ad.Status.WeightsVersion = desired
+		return current, nil
+	case agentopsv1alpha1.TaskPhaseFailed:
+		r.Log.Info("Dual-slot weight swap not possible, falling back to rolling update",
+			"Name", ad.Name, "Namespace", ad.Namespace, "WeightsVersion", desired, "Reason", task.Status.Message)
+		return desired, nil
+	default:
+		// Swap in progress; keep the pods as they are until the task finishes
+		return current, nil
+	}
+}
+
+// WeightSwapTaskType is the AgentTask type performing dual-slot weight swaps
+const WeightSwapTaskType = "weight-swap"
+
+// NewWeightSwapHandler returns the task handler that loads the desired weights into
+// the standby slot of every running pod of an AgentDeployment. Unsupported runtimes
+// and insufficient memory fail immediately since retrying will not help.
+func NewWeightSwapHandler(c client.Client, swapper WeightSwapper) tasks.Handler {
+	if swapper == nil {
+		swapper = weightswap.NewClient()
+	}
+	return tasks.HandlerFunc(func(ctx context.Context, namespace string, params map[string]string) (map[string]string, error) {
+		ad := &agentopsv1alpha1.AgentDeployment{}
+		if err := c.Get(ctx, types.NamespacedName{Name: params["agentDeployment"], Namespace: namespace}, ad); err != nil {
+			return nil, err
+		}
+
+		pods := &corev1.PodList{}
+		if err := c.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabels(labelsForAgentDeployment(ad.Name))); err != nil {
+			return nil, err
+		}
+		if len(pods.Items) == 0 {
+			return nil, errors.New("no running pods")
+		}
+
+		port := agentPortForAgentDeployment(ad)
+		for _, pod := range pods.Items {
+			if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+				return nil, fmt.Errorf("pod %s is not running", pod.Name)
+			}
+			baseURL := fmt.Sprintf("http://%s:%d", pod.Status.PodIP, port)
+			if err := swapper.Swap(ctx, baseURL, params["version"]); err != nil {
+				if errors.Is(err, weightswap.ErrUnsupported) || errors.Is(err, weightswap.ErrInsufficientMemory) {
+					return nil, tasks.Permanent(fmt.Errorf("pod %s: %w", pod.Name, err))
+				}
+				return nil, fmt.Errorf("pod %s: %w", pod.Name, err)
+			}
+		}
+		return map[string]string{"pods": fmt.Sprint(len(pods.Items))}, nil
+	})
+}
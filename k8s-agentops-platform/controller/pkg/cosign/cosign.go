@@ -0,0 +1,310 @@
+// Package cosign verifies cosign signatures and attestations of container images
+// against public keys. Signatures are read from the registry where cosign stores
+// them, as the sha256-<digest>.sig and .att tags of the image repository. Only
+// key-based signing is supported; keyless (Fulcio/Rekor) signatures are not.
+package cosign
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/registry"
+)
+
+const (
+	// signatureAnnotation carries the base64 signature of a signature layer
+	signatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	// dsseMediaType is the layer media type of attestations
+	dsseMediaType = "application/vnd.dsse.envelope.v1+json"
+
+	// cacheTTL is how long a verification result is reused. Tags can move to
+	// unsigned images, so results are not kept forever.
+	cacheTTL = 10 * time.Minute
+)
+
+// Policy is what an image must satisfy
+type Policy struct {
+	// Keys verify signatures; a signature by any of them is accepted
+	Keys []crypto.PublicKey
+
+	// Attestations are in-toto predicate types that must be attested with Keys
+	Attestations []string
+}
+
+// ParsePublicKeys parses PEM-encoded public keys as written by cosign generate-key-pair
+func ParsePublicKeys(pems []string) ([]crypto.PublicKey, error) {
+	keys := make([]crypto.PublicKey, 0, len(pems))
+	for i, text := range pems {
+		block, _ := pem.Decode([]byte(text))
+		if block == nil {
+			return nil, fmt.Errorf("public key %d is not PEM-encoded", i)
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("public key %d: %w", i, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// VerificationError is returned when an image is not signed or attested as the
+// policy requires. Other errors mean the registry could not be read.
+type VerificationError struct {
+	Image  string
+	Reason string
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("image %s: %s", e.Image, e.Reason)
+}
+
+// IsVerificationError reports whether err is a failed verification
+func IsVerificationError(err error) bool {
+	var verr *VerificationError
+	return errors.As(err, &verr)
+}
+
+// Verifier verifies images and caches the results
+type Verifier struct {
+	registry *registry.Client
+
+	mu    sync.Mutex
+	cache map[string]result
+}
+
+// result is a cached verification
+type result struct {
+	digest string
+	err    error
+	at     time.Time
+}
+
+// NewVerifier returns a Verifier reading signatures through client
+func NewVerifier(client *registry.Client) *Verifier {
+	return &Verifier{registry: client, cache: map[string]result{}}
+}
+
+// Verify checks that image is signed, and attested, as the policy requires and
+// returns the verified manifest digest. Pods should run the image by this digest,
+// so a tag moved after verification is not picked up.
+func (v *Verifier) Verify(ctx context.Context, image string, policy Policy) (string, error) {
+	key := cacheKey(image, policy)
+	v.mu.Lock()
+	cached, ok := v.cache[key]
+	v.mu.Unlock()
+	if ok && time.Since(cached.at) < cacheTTL {
+		return cached.digest, cached.err
+	}
+
+	digest, err := v.verify(ctx, image, policy)
+	// Registry failures are retried on the next call rather than cached
+	if err == nil || IsVerificationError(err) {
+		v.mu.Lock()
+		v.cache[key] = result{digest: digest, err: err, at: time.Now()}
+		v.mu.Unlock()
+	}
+	return digest, err
+}
+
+// cacheKey identifies a verification of image under a policy
+func cacheKey(image string, policy Policy) string {
+	parts := []string{image}
+	for _, key := range policy.Keys {
+		der, _ := x509.MarshalPKIXPublicKey(key)
+		parts = append(parts, base64.StdEncoding.EncodeToString(der))
+	}
+	return strings.Join(append(parts, policy.Attestations...), "|")
+}
+
+func (v *Verifier) verify(ctx context.Context, image string, policy Policy) (string, error) {
+	if len(policy.Keys) == 0 {
+		return "", fmt.Errorf("no public keys to verify image %s with", image)
+	}
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return "", &VerificationError{Image: image, Reason: err.Error()}
+	}
+	digest := ref.Digest
+	if digest == "" {
+		if _, digest, err = v.registry.Manifest(ctx, ref, ref.Tag); err != nil {
+			if err == registry.ErrNotFound {
+				return "", &VerificationError{Image: image, Reason: "image not found"}
+			}
+			return "", err
+		}
+	}
+	tag := strings.Replace(digest, ":", "-", 1)
+
+	signed, err := v.verifySignatures(ctx, ref, tag+".sig", digest, policy.Keys)
+	if err != nil {
+		return "", err
+	}
+	if !signed {
+		return "", &VerificationError{Image: image, Reason: "no signature matches the configured keys"}
+	}
+	if len(policy.Attestations) == 0 {
+		return digest, nil
+	}
+	attested, err := v.verifyAttestations(ctx, ref, tag+".att", digest, policy.Keys)
+	if err != nil {
+		return "", err
+	}
+	for _, predicateType := range policy.Attestations {
+		if !attested[predicateType] {
+			return "", &VerificationError{Image: image, Reason: fmt.Sprintf("no %s attestation matches the configured keys", predicateType)}
+		}
+	}
+	return digest, nil
+}
+
+// simpleSigning is the payload cosign signs
+type simpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// verifySignatures reports whether a signature layer of the signature manifest is
+// signed by one of keys and covers digest
+func (v *Verifier) verifySignatures(ctx context.Context, ref registry.Reference, tag, digest string, keys []crypto.PublicKey) (bool, error) {
+	layers, err := v.layers(ctx, ref, tag)
+	if err != nil || layers == nil {
+		return false, err
+	}
+	for _, layer := range layers {
+		sig, err := base64.StdEncoding.DecodeString(layer.Annotations[signatureAnnotation])
+		if err != nil || len(sig) == 0 {
+			continue
+		}
+		payload, err := v.registry.Blob(ctx, ref, layer.Digest)
+		if err != nil {
+			return false, err
+		}
+		if !verifyWithAny(keys, payload, sig) {
+			continue
+		}
+		var signing simpleSigning
+		if json.Unmarshal(payload, &signing) == nil && signing.Critical.Image.DockerManifestDigest == digest {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// envelope is a DSSE envelope
+type envelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+	Signatures  []struct {
+		Sig string `json:"sig"`
+	} `json:"signatures"`
+}
+
+// statement is the part of an in-toto statement attestations are matched on
+type statement struct {
+	PredicateType string `json:"predicateType"`
+	Subject       []struct {
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+}
+
+// verifyAttestations returns the predicate types attested for digest with one of keys
+func (v *Verifier) verifyAttestations(ctx context.Context, ref registry.Reference, tag, digest string, keys []crypto.PublicKey) (map[string]bool, error) {
+	attested := map[string]bool{}
+	layers, err := v.layers(ctx, ref, tag)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, hexDigest, _ := strings.Cut(digest, ":")
+	for _, layer := range layers {
+		if layer.MediaType != dsseMediaType {
+			continue
+		}
+		blob, err := v.registry.Blob(ctx, ref, layer.Digest)
+		if err != nil {
+			return nil, err
+		}
+		var env envelope
+		if json.Unmarshal(blob, &env) != nil {
+			continue
+		}
+		payload, err := base64.StdEncoding.DecodeString(env.Payload)
+		if err != nil {
+			continue
+		}
+		pae := []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(env.PayloadType), env.PayloadType, len(payload), payload))
+		signed := false
+		for _, s := range env.Signatures {
+			if sig, err := base64.StdEncoding.DecodeString(s.Sig); err == nil && verifyWithAny(keys, pae, sig) {
+				signed = true
+				break
+			}
+		}
+		var st statement
+		if !signed || json.Unmarshal(payload, &st) != nil {
+			continue
+		}
+		for _, subject := range st.Subject {
+			if subject.Digest[algorithm] == hexDigest {
+				attested[st.PredicateType] = true
+			}
+		}
+	}
+	return attested, nil
+}
+
+// layers returns the layers of a signature or attestation manifest, or nil if the
+// image has none
+func (v *Verifier) layers(ctx context.Context, ref registry.Reference, tag string) ([]registry.Descriptor, error) {
+	body, _, err := v.registry.Manifest(ctx, ref, tag)
+	if err == registry.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m registry.Manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, err
+	}
+	return m.Layers, nil
+}
+
+// verifyWithAny reports whether sig is a signature of payload by one of keys
+func verifyWithAny(keys []crypto.PublicKey, payload, sig []byte) bool {
+	hash := sha256.Sum256(payload)
+	for _, key := range keys {
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, hash[:], sig) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], sig) == nil {
+				return true
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, payload, sig) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package cost
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Prices are the hourly on-demand prices in USD of the resources agent pods request
+type Prices struct {
+	// CPUCoreHour is the price of one requested core for an hour
+	CPUCoreHour float64
+
+	// MemoryGiBHour is the price of one requested GiB of memory for an hour
+	MemoryGiBHour float64
+
+	// GPUHour is the price of one requested GPU for an hour, whatever its vendor
+	GPUHour float64
+}
+
+// DefaultPrices approximate on-demand prices of general-purpose cloud instances
+// and of a data center GPU
+var DefaultPrices = Prices{
+	CPUCoreHour:   0.0316,
+	MemoryGiBHour: 0.0042,
+	GPUHour:       2.48,
+}
+
+// Hourly returns the hourly price of replicas pods of the given template. Pods are
+// priced by what they request, which is what the scheduler reserves on the node.
+func (p Prices) Hourly(pod *corev1.PodSpec, replicas int32) float64 {
+	if replicas <= 0 {
+		return 0
+	}
+	var total float64
+	for _, c := range pod.Containers {
+		total += p.containerHourly(c.Resources)
+	}
+	return total * float64(replicas)
+}
+
+// containerHourly returns the hourly price of a container's requests. Extended
+// resources only need a limit, which then doubles as the request.
+func (p Prices) containerHourly(res corev1.ResourceRequirements) float64 {
+	var total float64
+	if cpu, ok := res.Requests[corev1.ResourceCPU]; ok {
+		total += cpu.AsApproximateFloat64() * p.CPUCoreHour
+	}
+	if memory, ok := res.Requests[corev1.ResourceMemory]; ok {
+		total += memory.AsApproximateFloat64() / (1 << 30) * p.MemoryGiBHour
+	}
+	for name, quantity := range res.Limits {
+		if IsGPU(name) {
+			total += quantity.AsApproximateFloat64() * p.GPUHour
+		}
+	}
+	return total
+}
+
+// IsGPU reports whether a resource is a GPU, such as nvidia.com/gpu or amd.com/gpu
+func IsGPU(name corev1.ResourceName) bool {
+	return strings.HasSuffix(string(name), "/gpu")
+}
@@ -0,0 +1,92 @@
+package debug
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// recentReconciles bounds the finished reconciles kept for /debug/reconciles
+const recentReconciles = 256
+
+// reconcileRecord is one reconcile, running or finished
+type reconcileRecord struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Start     time.Time
+	Duration  time.Duration
+	Err       string
+}
+
+// tracker records the reconciles in flight and the most recent finished ones
+type tracker struct {
+	mu       sync.Mutex
+	nextID   uint64
+	inFlight map[uint64]reconcileRecord
+	// recent is a ring of the last recentReconciles finished reconciles
+	recent []reconcileRecord
+	next   int
+}
+
+// reconciles is fed by every reconciler wrapped with Reconciler and served by
+// the debug Server
+var reconciles = &tracker{inFlight: map[uint64]reconcileRecord{}}
+
+func (t *tracker) start(rec reconcileRecord) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	t.inFlight[t.nextID] = rec
+	return t.nextID
+}
+
+func (t *tracker) finish(id uint64, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rec := t.inFlight[id]
+	delete(t.inFlight, id)
+	rec.Duration = time.Since(rec.Start)
+	if err != nil {
+		rec.Err = err.Error()
+	}
+	if len(t.recent) < recentReconciles {
+		t.recent = append(t.recent, rec)
+		return
+	}
+	t.recent[t.next] = rec
+	t.next = (t.next + 1) % recentReconciles
+}
+
+// snapshot returns the reconciles in flight, longest running first, and the
+// finished ones, slowest first
+func (t *tracker) snapshot() ([]reconcileRecord, []reconcileRecord) {
+	t.mu.Lock()
+	now := time.Now()
+	running := make([]reconcileRecord, 0, len(t.inFlight))
+	for _, rec := range t.inFlight {
+		rec.Duration = now.Sub(rec.Start)
+		running = append(running, rec)
+	}
+	finished := append([]reconcileRecord(nil), t.recent...)
+	t.mu.Unlock()
+
+	sort.Slice(running, func(i, j int) bool { return running[i].Duration > running[j].Duration })
+	sort.Slice(finished, func(i, j int) bool { return finished[i].Duration > finished[j].Duration })
+	return running, finished
+}
+
+// Reconciler wraps r so that its reconciles show up on /debug/reconciles while
+// they run and among the recent ones once done
+func Reconciler(kind string, r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		id := reconciles.start(reconcileRecord{Kind: kind, Namespace: req.Namespace, Name: req.Name, Start: time.Now()})
+		result, err := r.Reconcile(ctx, req)
+		reconciles.finish(id, err)
+		return result, err
+	})
+}
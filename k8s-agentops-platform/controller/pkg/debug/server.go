@@ -0,0 +1,150 @@
+package debug
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// shownReconciles bounds the finished reconciles listed on /debug/reconciles
+const shownReconciles = 50
+
+// Server serves pprof profiles, the reconciles in flight and the slowest recent
+// ones, and on-demand AgentDeployment reconciles, to diagnose slow reconciles
+// without restarting the controller. Every request must carry the bearer token of
+// TokenFile.
+type Server struct {
+	// Client reads AgentDeployments from the cache
+	Client client.Client
+
+	Log logr.Logger
+
+	// Addr is the address the server listens on
+	Addr string
+
+	// TokenFile holds the bearer token requests are authenticated with; it is read
+	// on every request so that a mounted Secret can be rotated
+	TokenFile string
+
+	// Trigger queues a reconcile of the AgentDeployment sent on it
+	Trigger chan<- event.GenericEvent
+}
+
+// Start serves until ctx is cancelled; it implements manager.Runnable
+func (s *Server) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/reconciles", s.serveReconciles)
+	mux.HandleFunc("/debug/reconcile", s.serveReconcile)
+
+	srv := &http.Server{Addr: s.Addr, Handler: s.authenticate(mux), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	s.Log.Info("Starting debug server", "Addr", s.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection is false: profiles of standby replicas are useful too, though
+// only the leader runs reconciles
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// authenticate rejects requests without the bearer token of TokenFile
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, err := os.ReadFile(s.TokenFile)
+		if err != nil {
+			s.Log.Error(err, "Failed to read debug token", "File", s.TokenFile)
+			http.Error(w, "debug token unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		want := strings.TrimSpace(string(data))
+		got, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || want == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// serveReconciles lists the reconciles in flight, longest running first, then the
+// slowest of the recent ones
+func (s *Server) serveReconciles(w http.ResponseWriter, req *http.Request) {
+	running, finished := reconciles.snapshot()
+	if len(finished) > shownReconciles {
+		finished = finished[:shownReconciles]
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "IN FLIGHT (%d)\nKIND\tNAMESPACE\tNAME\tSTARTED\tRUNNING\n", len(running))
+	for _, rec := range running {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", rec.Kind, rec.Namespace, rec.Name, rec.Start.UTC().Format(time.RFC3339), rec.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(tw, "\nSLOWEST RECENT (%d)\nKIND\tNAMESPACE\tNAME\tSTARTED\tDURATION\tERROR\n", len(finished))
+	for _, rec := range finished {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", rec.Kind, rec.Namespace, rec.Name, rec.Start.UTC().Format(time.RFC3339), rec.Duration.Round(time.Millisecond), rec.Err)
+	}
+	tw.Flush()
+}
+
+// serveReconcile queues a reconcile of the AgentDeployment given by the name and
+// namespace query parameters
+func (s *Server) serveReconcile(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	name, namespace := req.URL.Query().Get("name"), req.URL.Query().Get("namespace")
+	if name == "" || namespace == "" {
+		http.Error(w, "name and namespace are required", http.StatusBadRequest)
+		return
+	}
+
+	ad := &agentopsv1alpha1.AgentDeployment{}
+	if err := s.Client.Get(req.Context(), client.ObjectKey{Name: name, Namespace: namespace}, ad); err != nil {
+		status := http.StatusInternalServerError
+		if apierrors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	// The queue is only drained on the leader; do not block on the others
+	select {
+	case s.Trigger <- event.GenericEvent{Object: ad}:
+	default:
+		http.Error(w, "reconcile queue is full or this replica is not the leader", http.StatusServiceUnavailable)
+		return
+	}
+	s.Log.Info("Queued reconcile", "AgentDeployment", name, "Namespace", namespace)
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, "reconcile of AgentDeployment %s/%s queued\n", namespace, name)
+}
@@ -0,0 +1,76 @@
+package evaluation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxOutput bounds how much of an error response body is kept
+const maxOutput = 512
+
+// message is a message of an OpenAI-style chat completion request
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Client sends the prompts of EvaluationRuns to agent pods
+type Client struct {
+	// HTTPClient sends the requests; the caller's context bounds each request
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client using a default HTTP client
+func NewClient() *Client {
+	return &Client{HTTPClient: &http.Client{}}
+}
+
+// Complete posts a chat completion request with the optional system message and the
+// prompt to url and returns the reply, or the raw response body when it is not an
+// OpenAI-style chat completion
+func (c *Client) Complete(ctx context.Context, url, model, system, prompt string) (string, error) {
+	var messages []message
+	if system != "" {
+		messages = append(messages, message{Role: "system", Content: system})
+	}
+	messages = append(messages, message{Role: "user", Content: prompt})
+	body, err := json.Marshal(map[string]interface{}{"model": model, "messages": messages})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 300 {
+		if len(data) > maxOutput {
+			data = data[:maxOutput]
+		}
+		return "", fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, url, bytes.TrimSpace(data))
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message message `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &completion); err == nil && len(completion.Choices) > 0 {
+		return completion.Choices[0].Message.Content, nil
+	}
+	return string(bytes.TrimSpace(data)), nil
+}
@@ -0,0 +1,70 @@
+package feedback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxOutput bounds how much of an error response body is kept
+const maxOutput = 512
+
+// Request is posted to a feedback webhook to ask for the feedback users gave a
+// variant of an AgentExperiment
+type Request struct {
+	Experiment      string    `json:"experiment"`
+	Namespace       string    `json:"namespace"`
+	Variant         string    `json:"variant"`
+	AgentDeployment string    `json:"agentDeployment"`
+	Since           time.Time `json:"since"`
+}
+
+// Score is the answer of a feedback webhook: the mean feedback score of the variant
+// and the number of ratings it is based on
+type Score struct {
+	Score float64 `json:"score"`
+	Count int64   `json:"count"`
+}
+
+// Client reads user feedback scores from webhooks
+type Client struct {
+	// HTTPClient sends the requests; the caller's context bounds each request
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client using a default HTTP client
+func NewClient() *Client {
+	return &Client{HTTPClient: &http.Client{}}
+}
+
+// Score posts req to url and decodes the score from the answer
+func (c *Client) Score(ctx context.Context, url string, req Request) (*Score, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		out, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+		return nil, fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, url, bytes.TrimSpace(out))
+	}
+	score := &Score{}
+	if err := json.NewDecoder(resp.Body).Decode(score); err != nil {
+		return nil, fmt.Errorf("decode feedback from %s: %w", url, err)
+	}
+	return score, nil
+}
@@ -0,0 +1,59 @@
+package fleet
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Clients builds clients of member clusters from their kubeconfigs, and keeps them
+// until the kubeconfig changes
+type Clients struct {
+	// Scheme of the objects written to member clusters
+	Scheme *runtime.Scheme
+
+	// Wrap, when set, wraps every client, e.g. to dry-run writes in observe mode
+	Wrap func(client.Client) client.Client
+
+	mu      sync.Mutex
+	clients map[string]cachedClient
+}
+
+type cachedClient struct {
+	sum    [sha256.Size]byte
+	client client.Client
+}
+
+// NewClients returns Clients for objects of scheme
+func NewClients(scheme *runtime.Scheme, wrap func(client.Client) client.Client) *Clients {
+	return &Clients{Scheme: scheme, Wrap: wrap, clients: map[string]cachedClient{}}
+}
+
+// For returns a client of the member cluster with the given kubeconfig; key
+// identifies the cluster across calls
+func (c *Clients) For(key string, kubeconfig []byte) (client.Client, error) {
+	sum := sha256.Sum256(kubeconfig)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.clients[key]; ok && cached.sum == sum {
+		return cached.client, nil
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	cl, err := client.New(config, client.Options{Scheme: c.Scheme})
+	if err != nil {
+		return nil, err
+	}
+	var member client.Client = cl
+	if c.Wrap != nil {
+		member = c.Wrap(member)
+	}
+	c.clients[key] = cachedClient{sum: sum, client: member}
+	return member, nil
+}
@@ -0,0 +1,341 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Route is a set of routing rules served for a list of hostnames
+type Route struct {
+	// Name identifies the route in the generated configuration
+	Name string
+
+	// Hostnames the route answers for; empty matches any host
+	Hostnames []string
+
+	// Rules are evaluated in order; the first match wins
+	Rules []Rule
+
+	// RateLimits are the rate limit actions of the virtual host, producing the
+	// descriptors a rate limit filter in HTTPFilters matches on
+	RateLimits []interface{}
+}
+
+// Rule matches requests and splits them across weighted backends
+type Rule struct {
+	// PathPrefix matches the request path; empty matches "/"
+	PathPrefix string
+
+	// Headers must all match exactly
+	Headers map[string]string
+
+	// Backends receive the matched traffic in proportion to their weights
+	Backends []Backend
+
+	// Mirror receives a copy of the matched requests; its responses are discarded
+	Mirror *Mirror
+
+	// Priority is the routing priority of the matched requests, PriorityHigh or
+	// PriorityDefault; backends hold separate concurrency limits per priority
+	Priority string
+}
+
+// Routing priorities
+const (
+	PriorityHigh    = "HIGH"
+	PriorityDefault = "DEFAULT"
+)
+
+// ConcurrencyLimit caps the requests in flight to a backend at a routing priority.
+// As many more requests wait for a free slot; beyond that the gateway answers 503.
+type ConcurrencyLimit struct {
+	Priority    string
+	MaxRequests int32
+}
+
+// Mirror is a backend receiving copies of a share of the requests
+type Mirror struct {
+	Backend Backend
+
+	// Percent of the requests mirrored; 0 mirrors all
+	Percent int32
+}
+
+// Backend is a Kubernetes Service receiving routed traffic
+type Backend struct {
+	Service   string
+	Namespace string
+	Port      int32
+	Weight    int32
+
+	// Host overrides the Service address, e.g. 127.0.0.1 for a sidecar proxy
+	Host string
+
+	// Pool separates backends of the same address into clusters of their own, so
+	// they hold their own Limits
+	Pool string
+
+	// Limits cap the requests in flight per routing priority
+	Limits []ConcurrencyLimit
+
+	// RequestHeaders are set on the requests sent to the backend, replacing any
+	// the client sent
+	RequestHeaders map[string]string
+}
+
+// ClusterName returns the Envoy cluster name of the backend
+func (b Backend) ClusterName() string {
+	if b.Pool != "" {
+		return fmt.Sprintf("%s_%s_%d_%s", b.Namespace, b.Service, b.Port, b.Pool)
+	}
+	return fmt.Sprintf("%s_%s_%d", b.Namespace, b.Service, b.Port)
+}
+
+// Address returns the in-cluster DNS name of the backend
+func (b Backend) Address() string {
+	if b.Host != "" {
+		return b.Host
+	}
+	return fmt.Sprintf("%s.%s.svc.cluster.local", b.Service, b.Namespace)
+}
+
+const (
+	// ListenPort is the port the bundled gateway listens on
+	ListenPort = 8080
+	// AdminPort serves Envoy's admin interface (stats, readiness)
+	AdminPort = 9901
+)
+
+// Config is the input to RenderEnvoyBootstrap. Routes produce the virtual hosts;
+// HTTPFilters are inserted before the router filter, so other features (rate
+// limiting, mirroring, ...) can extend the chain.
+type Config struct {
+	Routes      []Route
+	HTTPFilters []map[string]interface{}
+
+	// ListenPort overrides the default listener port
+	ListenPort int32
+
+	// RemoveRequestHeaders are stripped from requests before any filter runs, so
+	// clients cannot set headers a filter adds
+	RemoveRequestHeaders []string
+}
+
+// RenderEnvoyBootstrap renders a static Envoy bootstrap (JSON) for the routes
+func RenderEnvoyBootstrap(cfg Config) (string, error) {
+	clusters := map[string]Backend{}
+	var virtualHosts []interface{}
+
+	for _, route := range cfg.Routes {
+		domains := route.Hostnames
+		if len(domains) == 0 {
+			domains = []string{"*"}
+		}
+		var routes []interface{}
+		for _, rule := range route.Rules {
+			if len(rule.Backends) == 0 {
+				continue
+			}
+			routes = append(routes, envoyRoute(rule, clusters))
+		}
+		virtualHost := map[string]interface{}{
+			"name":    route.Name,
+			"domains": domains,
+			"routes":  routes,
+		}
+		if len(route.RateLimits) > 0 {
+			virtualHost["rate_limits"] = route.RateLimits
+		}
+		virtualHosts = append(virtualHosts, virtualHost)
+	}
+
+	names := make([]string, 0, len(clusters))
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var envoyClusters []interface{}
+	for _, name := range names {
+		envoyClusters = append(envoyClusters, envoyCluster(clusters[name]))
+	}
+
+	filters := append([]map[string]interface{}{}, cfg.HTTPFilters...)
+	filters = append(filters, map[string]interface{}{
+		"name": "envoy.filters.http.router",
+		"typed_config": map[string]interface{}{
+			"@type": "type.googleapis.com/envoy.extensions.filters.http.router.v3.Router",
+		},
+	})
+
+	listenPort := cfg.ListenPort
+	if listenPort == 0 {
+		listenPort = ListenPort
+	}
+
+	manager := map[string]interface{}{
+		"@type":       "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+		"stat_prefix": "ingress_http",
+		// LLM responses stream for minutes; never time out an active stream
+		"stream_idle_timeout": "0s",
+		"route_config": map[string]interface{}{
+			"name":          "agent_routes",
+			"virtual_hosts": virtualHosts,
+		},
+		"http_filters": filters,
+	}
+	if len(cfg.RemoveRequestHeaders) > 0 {
+		var mutations []interface{}
+		for _, header := range cfg.RemoveRequestHeaders {
+			mutations = append(mutations, map[string]interface{}{"remove": header})
+		}
+		manager["early_header_mutation_extensions"] = []interface{}{map[string]interface{}{
+			"name": "envoy.http.early_header_mutation.header_mutation",
+			"typed_config": map[string]interface{}{
+				"@type":     "type.googleapis.com/envoy.extensions.http.early_header_mutation.header_mutation.v3.HeaderMutation",
+				"mutations": mutations,
+			},
+		}}
+	}
+
+	bootstrap := map[string]interface{}{
+		"admin": map[string]interface{}{
+			"address": socketAddress("0.0.0.0", AdminPort),
+		},
+		"static_resources": map[string]interface{}{
+			"listeners": []interface{}{map[string]interface{}{
+				"name":    "ingress",
+				"address": socketAddress("0.0.0.0", listenPort),
+				"filter_chains": []interface{}{map[string]interface{}{
+					"filters": []interface{}{map[string]interface{}{
+						"name":         "envoy.filters.network.http_connection_manager",
+						"typed_config": manager,
+					}},
+				}},
+			}},
+			"clusters": envoyClusters,
+		},
+	}
+
+	out, err := json.MarshalIndent(bootstrap, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func envoyRoute(rule Rule, clusters map[string]Backend) map[string]interface{} {
+	prefix := rule.PathPrefix
+	if prefix == "" {
+		prefix = "/"
+	}
+	match := map[string]interface{}{"prefix": prefix}
+	if len(rule.Headers) > 0 {
+		keys := make([]string, 0, len(rule.Headers))
+		for k := range rule.Headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var headers []interface{}
+		for _, k := range keys {
+			headers = append(headers, map[string]interface{}{
+				"name":         k,
+				"string_match": map[string]interface{}{"exact": rule.Headers[k]},
+			})
+		}
+		match["headers"] = headers
+	}
+
+	var weighted []interface{}
+	for _, b := range rule.Backends {
+		clusters[b.ClusterName()] = b
+		weight := b.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		cluster := map[string]interface{}{
+			"name":   b.ClusterName(),
+			"weight": weight,
+		}
+		if len(b.RequestHeaders) > 0 {
+			keys := make([]string, 0, len(b.RequestHeaders))
+			for k := range b.RequestHeaders {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			var headers []interface{}
+			for _, k := range keys {
+				headers = append(headers, map[string]interface{}{
+					"header":        map[string]interface{}{"key": k, "value": b.RequestHeaders[k]},
+					"append_action": "OVERWRITE_IF_EXISTS_OR_ADD",
+				})
+			}
+			cluster["request_headers_to_add"] = headers
+		}
+		weighted = append(weighted, cluster)
+	}
+
+	route := map[string]interface{}{
+		"timeout":           "0s",
+		"weighted_clusters": map[string]interface{}{"clusters": weighted},
+	}
+	if rule.Priority != "" {
+		route["priority"] = rule.Priority
+	}
+	if m := rule.Mirror; m != nil {
+		clusters[m.Backend.ClusterName()] = m.Backend
+		policy := map[string]interface{}{"cluster": m.Backend.ClusterName()}
+		if m.Percent > 0 && m.Percent < 100 {
+			policy["runtime_fraction"] = map[string]interface{}{
+				"default_value": map[string]interface{}{"numerator": m.Percent, "denominator": "HUNDRED"},
+			}
+		}
+		route["request_mirror_policies"] = []interface{}{policy}
+	}
+
+	return map[string]interface{}{
+		"match": match,
+		"route": route,
+	}
+}
+
+func envoyCluster(b Backend) map[string]interface{} {
+	cluster := map[string]interface{}{
+		"name":            b.ClusterName(),
+		"type":            "STRICT_DNS",
+		"connect_timeout": "5s",
+		"load_assignment": map[string]interface{}{
+			"cluster_name": b.ClusterName(),
+			"endpoints": []interface{}{map[string]interface{}{
+				"lb_endpoints": []interface{}{map[string]interface{}{
+					"endpoint": map[string]interface{}{
+						"address": socketAddress(b.Address(), b.Port),
+					},
+				}},
+			}},
+		},
+	}
+	if len(b.Limits) > 0 {
+		var thresholds []interface{}
+		for _, l := range b.Limits {
+			// HTTP/1.1 upstreams serve one request per connection
+			thresholds = append(thresholds, map[string]interface{}{
+				"priority":             l.Priority,
+				"max_connections":      l.MaxRequests,
+				"max_requests":         l.MaxRequests,
+				"max_pending_requests": l.MaxRequests,
+			})
+		}
+		cluster["circuit_breakers"] = map[string]interface{}{"thresholds": thresholds}
+	}
+	return cluster
+}
+
+func socketAddress(address string, port int32) map[string]interface{} {
+	return map[string]interface{}{
+		"socket_address": map[string]interface{}{
+			"address":    address,
+			"port_value": port,
+		},
+	}
+}
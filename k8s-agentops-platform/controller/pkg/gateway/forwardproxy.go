@@ -0,0 +1,150 @@
+package gateway
+
+import "encoding/json"
+
+// ForwardProxyPort is the port the egress proxy listens on
+const ForwardProxyPort = 3128
+
+// ForwardProxyConfig is the input to RenderForwardProxyBootstrap
+type ForwardProxyConfig struct {
+	// AllowedHosts are the hosts requests are forwarded to; "*.example.com" matches
+	// any subdomain. Other hosts are answered with 403.
+	AllowedHosts []string
+
+	// RateLimit limits the requests forwarded; a CONNECT tunnel counts as one
+	RateLimit RateLimit
+}
+
+// RenderForwardProxyBootstrap renders a static Envoy bootstrap (JSON) of an HTTP
+// forward proxy: plain HTTP requests and HTTPS CONNECT tunnels to the allowed hosts
+// are forwarded to the host they name, and every request is logged to stdout
+func RenderForwardProxyBootstrap(cfg ForwardProxyConfig) (string, error) {
+	dnsCache := map[string]interface{}{
+		"name":              "egress_dns_cache",
+		"dns_lookup_family": "V4_PREFERRED",
+	}
+	forward := map[string]interface{}{
+		// Tunnels last as long as the streamed LLM response; never time them out
+		"timeout": "0s",
+		"cluster": "egress",
+		"upgrade_configs": []interface{}{map[string]interface{}{
+			"upgrade_type":   "CONNECT",
+			"connect_config": map[string]interface{}{},
+		}},
+	}
+	virtualHosts := []interface{}{
+		map[string]interface{}{
+			"name":    "allowed",
+			"domains": cfg.AllowedHosts,
+			"routes": []interface{}{
+				map[string]interface{}{"match": map[string]interface{}{"connect_matcher": map[string]interface{}{}}, "route": forward},
+				map[string]interface{}{"match": map[string]interface{}{"prefix": "/"}, "route": map[string]interface{}{
+					"timeout": "0s",
+					"cluster": "egress",
+				}},
+			},
+		},
+		map[string]interface{}{
+			"name":    "denied",
+			"domains": []string{"*"},
+			"routes": []interface{}{map[string]interface{}{
+				"match": map[string]interface{}{"prefix": "/"},
+				"direct_response": map[string]interface{}{
+					"status": 403,
+					"body":   map[string]interface{}{"inline_string": "host not allowed by the agentops egress proxy\n"},
+				},
+			}},
+		},
+	}
+	if len(cfg.AllowedHosts) == 0 {
+		virtualHosts = virtualHosts[1:]
+	}
+
+	var filters []interface{}
+	if cfg.RateLimit.RequestsPerMinute > 0 {
+		filters = append(filters, LocalRateLimitFilter(cfg.RateLimit))
+	}
+	filters = append(filters,
+		map[string]interface{}{
+			"name": "envoy.filters.http.dynamic_forward_proxy",
+			"typed_config": map[string]interface{}{
+				"@type":            "type.googleapis.com/envoy.extensions.filters.http.dynamic_forward_proxy.v3.FilterConfig",
+				"dns_cache_config": dnsCache,
+			},
+		},
+		map[string]interface{}{
+			"name": "envoy.filters.http.router",
+			"typed_config": map[string]interface{}{
+				"@type": "type.googleapis.com/envoy.extensions.filters.http.router.v3.Router",
+			},
+		},
+	)
+
+	accessLog := map[string]interface{}{
+		"name": "envoy.access_loggers.stdout",
+		"typed_config": map[string]interface{}{
+			"@type": "type.googleapis.com/envoy.extensions.access_loggers.stream.v3.StdoutAccessLog",
+			"log_format": map[string]interface{}{"json_format": map[string]interface{}{
+				"start_time":     "%START_TIME%",
+				"client":         "%DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT%",
+				"method":         "%REQ(:METHOD)%",
+				"host":           "%REQ(:AUTHORITY)%",
+				"path":           "%REQ(X-ENVOY-ORIGINAL-PATH?:PATH)%",
+				"status":         "%RESPONSE_CODE%",
+				"flags":          "%RESPONSE_FLAGS%",
+				"bytes_sent":     "%BYTES_SENT%",
+				"bytes_received": "%BYTES_RECEIVED%",
+				"duration_ms":    "%DURATION%",
+			}},
+		},
+	}
+
+	bootstrap := map[string]interface{}{
+		"admin": map[string]interface{}{
+			"address": socketAddress("0.0.0.0", AdminPort),
+		},
+		"static_resources": map[string]interface{}{
+			"listeners": []interface{}{map[string]interface{}{
+				"name":    "egress",
+				"address": socketAddress("0.0.0.0", ForwardProxyPort),
+				"filter_chains": []interface{}{map[string]interface{}{
+					"filters": []interface{}{map[string]interface{}{
+						"name": "envoy.filters.network.http_connection_manager",
+						"typed_config": map[string]interface{}{
+							"@type":               "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+							"stat_prefix":         "egress_http",
+							"stream_idle_timeout": "0s",
+							// CONNECT authorities carry the port; hosts are matched without it
+							"strip_any_host_port": true,
+							"upgrade_configs":     []interface{}{map[string]interface{}{"upgrade_type": "CONNECT"}},
+							"access_log":          []interface{}{accessLog},
+							"route_config": map[string]interface{}{
+								"name":          "egress_routes",
+								"virtual_hosts": virtualHosts,
+							},
+							"http_filters": filters,
+						},
+					}},
+				}},
+			}},
+			"clusters": []interface{}{map[string]interface{}{
+				"name":            "egress",
+				"connect_timeout": "5s",
+				"lb_policy":       "CLUSTER_PROVIDED",
+				"cluster_type": map[string]interface{}{
+					"name": "envoy.clusters.dynamic_forward_proxy",
+					"typed_config": map[string]interface{}{
+						"@type":            "type.googleapis.com/envoy.extensions.clusters.dynamic_forward_proxy.v3.ClusterConfig",
+						"dns_cache_config": dnsCache,
+					},
+				},
+			}},
+		},
+	}
+
+	out, err := json.MarshalIndent(bootstrap, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
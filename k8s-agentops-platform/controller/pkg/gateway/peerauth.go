@@ -0,0 +1,52 @@
+package gateway
+
+// PeerAuth describes the signed tokens agents present when they call each other
+type PeerAuth struct {
+	// Issuer and Audience the token must carry
+	Issuer   string
+	Audience string
+
+	// JWKSFile is the path of the JWKS holding the key tokens are signed with
+	JWKSFile string
+
+	// TokenHeader carries the token
+	TokenHeader string
+
+	// IdentityHeader receives the subject of a verified token, the calling agent
+	IdentityHeader string
+}
+
+// PeerAuthFilter returns the Envoy JWT authentication HTTP filter for pa. Requests
+// without a token pass, as they do not come from a peer agent; a token that does
+// not verify is rejected.
+func PeerAuthFilter(pa PeerAuth) map[string]interface{} {
+	return map[string]interface{}{
+		"name": "envoy.filters.http.jwt_authn",
+		"typed_config": map[string]interface{}{
+			"@type": "type.googleapis.com/envoy.extensions.filters.http.jwt_authn.v3.JwtAuthentication",
+			"providers": map[string]interface{}{
+				"peers": map[string]interface{}{
+					"issuer":       pa.Issuer,
+					"audiences":    []interface{}{pa.Audience},
+					"local_jwks":   map[string]interface{}{"filename": pa.JWKSFile},
+					"from_headers": []interface{}{map[string]interface{}{"name": pa.TokenHeader}},
+					"claim_to_headers": []interface{}{map[string]interface{}{
+						"header_name": pa.IdentityHeader,
+						"claim_name":  "sub",
+					}},
+				},
+			},
+			"rules": []interface{}{map[string]interface{}{
+				"match": map[string]interface{}{"prefix": "/"},
+				"requires": map[string]interface{}{
+					"requires_any": map[string]interface{}{
+						"requirements": []interface{}{
+							map[string]interface{}{"provider_name": "peers"},
+							map[string]interface{}{"allow_missing": map[string]interface{}{}},
+						},
+					},
+				},
+			}},
+		},
+	}
+}
@@ -0,0 +1,255 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// ProviderProxyAdminPort serves the admin interface of the provider proxy; it
+	// differs from AdminPort as both sidecars may run in the same pod
+	ProviderProxyAdminPort = 9902
+
+	// FallbackPathPrefix routes calls to the fallback upstream
+	FallbackPathPrefix = "/fallback/"
+)
+
+// Upstream is a remote model API the provider proxy forwards to
+type Upstream struct {
+	// Name identifies the upstream in the generated configuration and its stats
+	Name string
+
+	// Host and Port of the API
+	Host string
+	Port int32
+
+	// TLS originates TLS to the upstream, with Host as SNI
+	TLS bool
+
+	// PathPrefix is prepended to the request paths, for APIs served below a path
+	PathPrefix string
+}
+
+// ParseUpstream returns the upstream serving baseURL, e.g. https://api.anthropic.com
+func ParseUpstream(name, baseURL string) (Upstream, error) {
+	scheme, rest, ok := strings.Cut(baseURL, "://")
+	if !ok || (scheme != "http" && scheme != "https") {
+		return Upstream{}, fmt.Errorf("endpoint %q is not an http(s) URL", baseURL)
+	}
+	u := Upstream{Name: name, TLS: scheme == "https", Port: 80}
+	if u.TLS {
+		u.Port = 443
+	}
+	hostPort, path, _ := strings.Cut(rest, "/")
+	u.Host = hostPort
+	if host, port, ok := strings.Cut(hostPort, ":"); ok {
+		var p int32
+		if _, err := fmt.Sscanf(port, "%d", &p); err != nil || p <= 0 {
+			return Upstream{}, fmt.Errorf("endpoint %q has an invalid port", baseURL)
+		}
+		u.Host, u.Port = host, p
+	}
+	if u.Host == "" {
+		return Upstream{}, fmt.Errorf("endpoint %q has no host", baseURL)
+	}
+	if path = strings.Trim(path, "/"); path != "" {
+		u.PathPrefix = "/" + path
+	}
+	return u, nil
+}
+
+// RetryPolicy defines which failed calls are retried
+type RetryPolicy struct {
+	Attempts      int32
+	PerTryTimeout time.Duration
+	RetryOn       []string
+	StatusCodes   []int32
+}
+
+// CircuitBreaker ejects an upstream after consecutive failures and caps the calls
+// in flight; zero limits are left at Envoy's defaults
+type CircuitBreaker struct {
+	ConsecutiveFailures int32
+	Interval            time.Duration
+	OpenDuration        time.Duration
+	MaxPendingRequests  int32
+	MaxRequests         int32
+}
+
+// ProviderProxyConfig is the input to RenderProviderProxyBootstrap
+type ProviderProxyConfig struct {
+	// ListenPort is the loopback port the agent calls
+	ListenPort int32
+
+	// Primary receives the calls; Fallback, when set, the calls under
+	// FallbackPathPrefix
+	Primary  Upstream
+	Fallback *Upstream
+
+	// Timeout bounds a call including its retries; zero disables it
+	Timeout time.Duration
+
+	Retry          RetryPolicy
+	CircuitBreaker CircuitBreaker
+}
+
+// RenderProviderProxyBootstrap renders a static Envoy bootstrap (JSON) of the proxy
+// an agent calls its provider through. Calls are retried per the retry policy, and
+// an upstream failing ConsecutiveFailures times in a row is ejected: until it is
+// tried again calls to it are answered with 503 "no healthy upstream", which tells
+// the agent runtime to fall back.
+func RenderProviderProxyBootstrap(cfg ProviderProxyConfig) (string, error) {
+	var routes []interface{}
+	clusters := []interface{}{providerCluster(cfg.Primary, cfg.CircuitBreaker)}
+	if fb := cfg.Fallback; fb != nil {
+		routes = append(routes, providerRoute(FallbackPathPrefix, *fb, cfg))
+		clusters = append(clusters, providerCluster(*fb, cfg.CircuitBreaker))
+	}
+	routes = append(routes, providerRoute("/", cfg.Primary, cfg))
+
+	manager := map[string]interface{}{
+		"@type":       "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+		"stat_prefix": "provider_http",
+		// LLM responses stream for minutes; never time out an active stream
+		"stream_idle_timeout": "0s",
+		"route_config": map[string]interface{}{
+			"name": "provider_routes",
+			"virtual_hosts": []interface{}{map[string]interface{}{
+				"name":    "provider",
+				"domains": []string{"*"},
+				"routes":  routes,
+			}},
+		},
+		"http_filters": []interface{}{map[string]interface{}{
+			"name": "envoy.filters.http.router",
+			"typed_config": map[string]interface{}{
+				"@type": "type.googleapis.com/envoy.extensions.filters.http.router.v3.Router",
+			},
+		}},
+	}
+
+	bootstrap := map[string]interface{}{
+		"admin": map[string]interface{}{
+			"address": socketAddress("0.0.0.0", ProviderProxyAdminPort),
+		},
+		"static_resources": map[string]interface{}{
+			"listeners": []interface{}{map[string]interface{}{
+				"name": "provider",
+				// Only the agent container may call the provider through the proxy
+				"address": socketAddress("127.0.0.1", cfg.ListenPort),
+				"filter_chains": []interface{}{map[string]interface{}{
+					"filters": []interface{}{map[string]interface{}{
+						"name":         "envoy.filters.network.http_connection_manager",
+						"typed_config": manager,
+					}},
+				}},
+			}},
+			"clusters": clusters,
+		},
+	}
+
+	out, err := json.MarshalIndent(bootstrap, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func providerRoute(prefix string, u Upstream, cfg ProviderProxyConfig) map[string]interface{} {
+	route := map[string]interface{}{
+		"cluster":              u.Name,
+		"host_rewrite_literal": u.Host,
+		"prefix_rewrite":       u.PathPrefix + "/",
+		"timeout":              envoyDuration(cfg.Timeout),
+	}
+	if r := cfg.Retry; r.Attempts > 0 {
+		retryOn := append([]string{}, r.RetryOn...)
+		if len(r.StatusCodes) > 0 {
+			retryOn = append(retryOn, "retriable-status-codes")
+		}
+		policy := map[string]interface{}{
+			"retry_on":    strings.Join(retryOn, ","),
+			"num_retries": r.Attempts,
+			"retry_back_off": map[string]interface{}{
+				"base_interval": "1s",
+				"max_interval":  "10s",
+			},
+			// Honour the Retry-After of rate limited answers
+			"rate_limited_retry_back_off": map[string]interface{}{
+				"reset_headers": []interface{}{map[string]interface{}{"name": "retry-after", "format": "SECONDS"}},
+				"max_interval":  "60s",
+			},
+		}
+		if len(r.StatusCodes) > 0 {
+			policy["retriable_status_codes"] = r.StatusCodes
+		}
+		if r.PerTryTimeout > 0 {
+			policy["per_try_timeout"] = envoyDuration(r.PerTryTimeout)
+		}
+		route["retry_policy"] = policy
+	}
+	return map[string]interface{}{
+		"match": map[string]interface{}{"prefix": prefix},
+		"route": route,
+	}
+}
+
+func providerCluster(u Upstream, cb CircuitBreaker) map[string]interface{} {
+	cluster := map[string]interface{}{
+		"name":              u.Name,
+		"type":              "LOGICAL_DNS",
+		"dns_lookup_family": "V4_PREFERRED",
+		"connect_timeout":   "5s",
+		// Without a panic threshold Envoy keeps sending to an ejected provider when
+		// it is the only host, and the circuit never opens
+		"common_lb_config": map[string]interface{}{
+			"healthy_panic_threshold": map[string]interface{}{"value": 0},
+		},
+		"load_assignment": map[string]interface{}{
+			"cluster_name": u.Name,
+			"endpoints": []interface{}{map[string]interface{}{
+				"lb_endpoints": []interface{}{map[string]interface{}{
+					"endpoint": map[string]interface{}{
+						"address": socketAddress(u.Host, u.Port),
+					},
+				}},
+			}},
+		},
+	}
+	if u.TLS {
+		cluster["transport_socket"] = map[string]interface{}{
+			"name": "envoy.transport_sockets.tls",
+			"typed_config": map[string]interface{}{
+				"@type": "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.UpstreamTlsContext",
+				"sni":   u.Host,
+			},
+		}
+	}
+	if cb.ConsecutiveFailures > 0 {
+		cluster["outlier_detection"] = map[string]interface{}{
+			"consecutive_5xx":             cb.ConsecutiveFailures,
+			"consecutive_gateway_failure": cb.ConsecutiveFailures,
+			"interval":                    envoyDuration(cb.Interval),
+			"base_ejection_time":          envoyDuration(cb.OpenDuration),
+			"max_ejection_percent":        100,
+		}
+	}
+	thresholds := map[string]interface{}{}
+	if cb.MaxPendingRequests > 0 {
+		thresholds["max_pending_requests"] = cb.MaxPendingRequests
+	}
+	if cb.MaxRequests > 0 {
+		thresholds["max_requests"] = cb.MaxRequests
+	}
+	if len(thresholds) > 0 {
+		cluster["circuit_breakers"] = map[string]interface{}{"thresholds": []interface{}{thresholds}}
+	}
+	return cluster
+}
+
+// envoyDuration formats d as an Envoy duration, e.g. "2.5s"
+func envoyDuration(d time.Duration) string {
+	return fmt.Sprintf("%gs", d.Seconds())
+}
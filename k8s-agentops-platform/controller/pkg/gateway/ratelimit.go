@@ -0,0 +1,91 @@
+package gateway
+
+import "sort"
+
+// clientDescriptorKey is the descriptor key carrying the client identity
+const clientDescriptorKey = "client"
+
+// RateLimit describes the local request limits enforced by a proxy
+type RateLimit struct {
+	// RequestsPerMinute limits all requests not covered by a client limit; zero disables it
+	RequestsPerMinute int32
+
+	// ClientHeader identifies the client of a request
+	ClientHeader string
+
+	// Clients maps client identities to their own requests-per-minute limit
+	Clients map[string]int32
+}
+
+// LocalRateLimitFilter returns the Envoy local rate limit HTTP filter for rl
+func LocalRateLimitFilter(rl RateLimit) map[string]interface{} {
+	config := map[string]interface{}{
+		"@type":       "type.googleapis.com/envoy.extensions.filters.http.local_ratelimit.v3.LocalRateLimit",
+		"stat_prefix": "agent_ratelimit",
+		"filter_enabled": map[string]interface{}{
+			"runtime_key":   "local_rate_limit_enabled",
+			"default_value": map[string]interface{}{"numerator": 100, "denominator": "HUNDRED"},
+		},
+		"filter_enforced": map[string]interface{}{
+			"runtime_key":   "local_rate_limit_enforced",
+			"default_value": map[string]interface{}{"numerator": 100, "denominator": "HUNDRED"},
+		},
+		"response_headers_to_add": []interface{}{map[string]interface{}{
+			"append_action": "OVERWRITE_IF_EXISTS_OR_ADD",
+			"header":        map[string]interface{}{"key": "x-agentops-ratelimited", "value": "true"},
+		}},
+	}
+
+	// Envoy requires a bucket on the filter; a huge one is effectively unlimited
+	// when only client limits are set
+	perMinute := rl.RequestsPerMinute
+	if perMinute == 0 {
+		perMinute = 1 << 30
+	}
+	config["token_bucket"] = tokenBucket(perMinute)
+
+	clients := make([]string, 0, len(rl.Clients))
+	for client := range rl.Clients {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	var descriptors []interface{}
+	for _, client := range clients {
+		descriptors = append(descriptors, map[string]interface{}{
+			"entries":      []interface{}{map[string]interface{}{"key": clientDescriptorKey, "value": client}},
+			"token_bucket": tokenBucket(rl.Clients[client]),
+		})
+	}
+	if len(descriptors) > 0 {
+		config["descriptors"] = descriptors
+	}
+
+	return map[string]interface{}{
+		"name":         "envoy.filters.http.local_ratelimit",
+		"typed_config": config,
+	}
+}
+
+// RateLimitActions returns the virtual host rate limit actions producing the client
+// descriptors LocalRateLimitFilter matches on
+func RateLimitActions(rl RateLimit) []interface{} {
+	if len(rl.Clients) == 0 || rl.ClientHeader == "" {
+		return nil
+	}
+	return []interface{}{map[string]interface{}{
+		"actions": []interface{}{map[string]interface{}{
+			"request_headers": map[string]interface{}{
+				"header_name":    rl.ClientHeader,
+				"descriptor_key": clientDescriptorKey,
+			},
+		}},
+	}}
+}
+
+func tokenBucket(perMinute int32) map[string]interface{} {
+	return map[string]interface{}{
+		"max_tokens":      perMinute,
+		"tokens_per_fill": perMinute,
+		"fill_interval":   "60s",
+	}
+}
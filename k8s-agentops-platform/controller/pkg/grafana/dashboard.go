@@ -0,0 +1,119 @@
+package grafana
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// SidecarLabel marks ConfigMaps the Grafana dashboard sidecar imports
+	SidecarLabel = "grafana_dashboard"
+	// FolderAnnotation selects the Grafana folder when the sidecar runs with
+	// folderAnnotation=grafana_folder
+	FolderAnnotation = "grafana_folder"
+	// Folder is the folder agent dashboards are filed under
+	Folder = "AgentOps"
+)
+
+// Agent identifies the workload a dashboard is generated for
+type Agent struct {
+	Namespace string
+	Name      string
+
+	// Service is the Service label agent metrics are scraped with
+	Service string
+
+	// Deployment is the Deployment running the agent pods
+	Deployment string
+
+	// ResponseCache adds the hit ratio of the agent's response cache
+	ResponseCache bool
+}
+
+// UID returns a stable dashboard UID for the agent; Grafana limits UIDs to 40 characters
+func (a Agent) UID() string {
+	sum := sha256.Sum256([]byte(a.Namespace + "/" + a.Name))
+	return "agentops-" + hex.EncodeToString(sum[:8])
+}
+
+// RenderDashboard renders the dashboard model (JSON) for an agent: replicas,
+// request rate, latency, error rate, token usage and the response cache hit ratio
+func RenderDashboard(a Agent) (string, error) {
+	traffic := fmt.Sprintf(`namespace=%q,service=%q`, a.Namespace, a.Service)
+	deployment := fmt.Sprintf(`namespace=%q,deployment=%q`, a.Namespace, a.Deployment)
+	agent := fmt.Sprintf(`namespace=%q,agent=%q`, a.Namespace, a.Name)
+
+	panels := []interface{}{
+		panel(1, "Replicas", "short", 0, 0, 12,
+			target(`max(kube_deployment_spec_replicas{`+deployment+`})`, "desired"),
+			target(`max(kube_deployment_status_replicas_available{`+deployment+`})`, "available"),
+		),
+		panel(2, "Request Rate", "reqps", 12, 0, 12,
+			target(`sum(rate(http_requests_total{`+traffic+`}[5m]))`, "requests"),
+		),
+		panel(3, "Request Latency", "s", 0, 8, 12,
+			target(`histogram_quantile(0.99, sum(rate(http_request_duration_seconds_bucket{`+traffic+`}[5m])) by (le))`, "p99"),
+			target(`histogram_quantile(0.95, sum(rate(http_request_duration_seconds_bucket{`+traffic+`}[5m])) by (le))`, "p95"),
+			target(`histogram_quantile(0.50, sum(rate(http_request_duration_seconds_bucket{`+traffic+`}[5m])) by (le))`, "p50"),
+		),
+		panel(4, "Error Rate", "percentunit", 12, 8, 12,
+			target(`sum(rate(http_requests_total{`+traffic+`,status=~"5.."}[5m])) / sum(rate(http_requests_total{`+traffic+`}[5m]))`, "5xx"),
+		),
+		panel(5, "Token Usage", "short", 0, 16, 24,
+			target(`sum(rate(agent_tokens_total{`+traffic+`}[5m])) by (model) * 60`, "{{model}} tokens/min"),
+		),
+	}
+	if a.ResponseCache {
+		panels = append(panels, panel(6, "Response Cache Hit Ratio", "percentunit", 0, 24, 24,
+			target(`sum(rate(agentops_response_cache_requests_total{`+agent+`,result="hit"}[5m])) / sum(rate(agentops_response_cache_requests_total{`+agent+`}[5m]))`, "hits"),
+		))
+	}
+
+	dashboard := map[string]interface{}{
+		"uid":           a.UID(),
+		"title":         fmt.Sprintf("Agent %s/%s", a.Namespace, a.Name),
+		"tags":          []string{"agentops", "agent", a.Namespace},
+		"timezone":      "browser",
+		"schemaVersion": 38,
+		"refresh":       "30s",
+		"time":          map[string]interface{}{"from": "now-6h", "to": "now"},
+		"templating": map[string]interface{}{
+			"list": []interface{}{map[string]interface{}{
+				"name":  "datasource",
+				"type":  "datasource",
+				"query": "prometheus",
+			}},
+		},
+		"panels": panels,
+	}
+
+	out, err := json.MarshalIndent(dashboard, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func panel(id int, title, unit string, x, y, width int, targets ...map[string]interface{}) map[string]interface{} {
+	for i, t := range targets {
+		t["refId"] = string(rune('A' + i))
+	}
+	return map[string]interface{}{
+		"id":         id,
+		"title":      title,
+		"type":       "timeseries",
+		"datasource": map[string]interface{}{"type": "prometheus", "uid": "${datasource}"},
+		"gridPos":    map[string]interface{}{"x": x, "y": y, "w": width, "h": 8},
+		"fieldConfig": map[string]interface{}{
+			"defaults":  map[string]interface{}{"unit": unit},
+			"overrides": []interface{}{},
+		},
+		"targets": targets,
+	}
+}
+
+func target(expr, legend string) map[string]interface{} {
+	return map[string]interface{}{"expr": expr, "legendFormat": legend}
+}
@@ -0,0 +1,132 @@
+package health
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Target describes the endpoint a checker probes
+type Target struct {
+	// Port is the container port to probe
+	Port int32
+
+	// Path is the HTTP path for http checkers
+	Path string
+
+	// Service is the gRPC health service name for grpc checkers
+	Service string
+
+	// Command is the command run by exec checkers
+	Command []string
+}
+
+// Checker renders a health check protocol into a Kubernetes probe handler
+type Checker interface {
+	// Name is the protocol name used in AgentDeployment specs
+	Name() string
+
+	// Handler returns the probe handler for the given target
+	Handler(target Target) (corev1.ProbeHandler, error)
+}
+
+var (
+	mu       sync.RWMutex
+	checkers = map[string]Checker{}
+)
+
+// Register makes a checker available by name. Registering a name twice replaces
+// the previous checker, which lets distributions override the built-ins.
+func Register(c Checker) {
+	mu.Lock()
+	defer mu.Unlock()
+	checkers[c.Name()] = c
+}
+
+// Lookup returns the checker registered under name
+func Lookup(name string) (Checker, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := checkers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown health checker %q (registered: %v)", name, registeredLocked())
+	}
+	return c, nil
+}
+
+func registeredLocked() []string {
+	names := make([]string, 0, len(checkers))
+	for name := range checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register(httpChecker{})
+	Register(grpcChecker{})
+	Register(tcpChecker{})
+	Register(execChecker{})
+}
+
+// httpChecker probes an HTTP endpoint returning a JSON health document
+type httpChecker struct{}
+
+func (httpChecker) Name() string { return "http" }
+
+func (httpChecker) Handler(t Target) (corev1.ProbeHandler, error) {
+	if t.Path == "" {
+		return corev1.ProbeHandler{}, fmt.Errorf("http checker requires a path")
+	}
+	return corev1.ProbeHandler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Path: t.Path,
+			Port: intstr.FromInt(int(t.Port)),
+		},
+	}, nil
+}
+
+// grpcChecker uses the standard grpc.health.v1 protocol
+type grpcChecker struct{}
+
+func (grpcChecker) Name() string { return "grpc" }
+
+func (grpcChecker) Handler(t Target) (corev1.ProbeHandler, error) {
+	action := &corev1.GRPCAction{Port: t.Port}
+	if t.Service != "" {
+		service := t.Service
+		action.Service = &service
+	}
+	return corev1.ProbeHandler{GRPC: action}, nil
+}
+
+// tcpChecker only verifies that the port accepts connections
+type tcpChecker struct{}
+
+func (tcpChecker) Name() string { return "tcp" }
+
+func (tcpChecker) Handler(t Target) (corev1.ProbeHandler, error) {
+	return corev1.ProbeHandler{
+		TCPSocket: &corev1.TCPSocketAction{
+			Port: intstr.FromInt(int(t.Port)),
+		},
+	}, nil
+}
+
+// execChecker runs a custom command inside the container
+type execChecker struct{}
+
+func (execChecker) Name() string { return "exec" }
+
+func (execChecker) Handler(t Target) (corev1.ProbeHandler, error) {
+	if len(t.Command) == 0 {
+		return corev1.ProbeHandler{}, fmt.Errorf("exec checker requires a command")
+	}
+	return corev1.ProbeHandler{
+		Exec: &corev1.ExecAction{Command: t.Command},
+	}, nil
+}
@@ -0,0 +1,42 @@
+package health
+
+// Profile is the default health check configuration for an agent runtime
+type Profile struct {
+	// Checker is the name of the registered checker
+	Checker string
+
+	// Port is the container port probed
+	Port int32
+
+	// LivenessPath is the liveness endpoint for http checkers
+	LivenessPath string
+
+	// ReadinessPath is the readiness endpoint for http checkers
+	ReadinessPath string
+}
+
+// DefaultProfile is used when an AgentDeployment does not select a profile
+const DefaultProfile = "default"
+
+var profiles = map[string]Profile{
+	// The reference agent image
+	DefaultProfile: {Checker: "http", Port: 8080, LivenessPath: "/health", ReadinessPath: "/ready"},
+	// vLLM's OpenAI-compatible server only exposes /health
+	"vllm": {Checker: "http", Port: 8000, LivenessPath: "/health", ReadinessPath: "/health"},
+	// Text Generation Inference reports readiness once the model is loaded
+	"tgi": {Checker: "http", Port: 8080, LivenessPath: "/health", ReadinessPath: "/health"},
+	// Ollama has no health endpoint; its root path returns 200 once serving
+	"ollama": {Checker: "http", Port: 11434, LivenessPath: "/", ReadinessPath: "/"},
+	// Agents implementing grpc.health.v1
+	"grpc": {Checker: "grpc", Port: 9090},
+	// Runtimes without any health endpoint
+	"tcp": {Checker: "tcp", Port: 8080},
+}
+
+// ProfileFor returns the named profile, falling back to the default profile
+func ProfileFor(name string) Profile {
+	if p, ok := profiles[name]; ok {
+		return p
+	}
+	return profiles[DefaultProfile]
+}
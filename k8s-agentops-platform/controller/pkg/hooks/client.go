@@ -0,0 +1,150 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Hook phases
+const (
+	PreApply  = "preApply"
+	PostApply = "postApply"
+)
+
+// DefaultTimeout bounds a hook call when the hook sets no timeout
+const DefaultTimeout = 10 * time.Second
+
+// Endpoint is a resolved webhook
+type Endpoint struct {
+	// Name identifies the hook in errors and events
+	Name string
+
+	// URL receives the POSTed Request
+	URL string
+
+	// Token is sent as a bearer token when set
+	Token string
+
+	// Timeout bounds the call
+	Timeout time.Duration
+}
+
+// ObjectReference identifies the child object being changed
+type ObjectReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+}
+
+// Request is the payload POSTed to a hook
+type Request struct {
+	// Phase is preApply or postApply
+	Phase string `json:"phase"`
+
+	// Operation is create or update
+	Operation string `json:"operation"`
+
+	// AgentDeployment is the owner of the changed object
+	AgentDeployment ObjectReference `json:"agentDeployment"`
+
+	// Generation is the AgentDeployment generation being rolled out
+	Generation int64 `json:"generation"`
+
+	// Object is the child object being changed
+	Object ObjectReference `json:"object"`
+
+	// Desired is the full desired state of the child object
+	Desired interface{} `json:"desired,omitempty"`
+}
+
+// Response is the optional body a hook returns. A 2xx response without a body
+// allows the change.
+type Response struct {
+	Allowed *bool  `json:"allowed,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// RejectedError is returned when a preApply hook denies a change
+type RejectedError struct {
+	Hook   string
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("hook %s rejected the change", e.Hook)
+	}
+	return fmt.Sprintf("hook %s rejected the change: %s", e.Hook, e.Reason)
+}
+
+// IsRejected reports whether err is a hook rejection
+func IsRejected(err error) bool {
+	var rejected *RejectedError
+	return errors.As(err, &rejected)
+}
+
+// Client calls reconcile hooks over HTTP
+type Client struct {
+	// HTTPClient is used for hook calls; per-hook timeouts are applied through the context
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client using a plain HTTP client
+func NewClient() *Client {
+	return &Client{HTTPClient: &http.Client{}}
+}
+
+// Call POSTs req to the hook. A 403 response or a body with allowed=false is
+// reported as a *RejectedError; transport failures and other non-2xx responses
+// are returned as plain errors.
+func (c *Client) Call(ctx context.Context, ep Endpoint, req Request) error {
+	timeout := ep.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if ep.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+ep.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("hook %s: %w", ep.Name, err)
+	}
+	defer resp.Body.Close()
+
+	var decoded Response
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if len(bytes.TrimSpace(raw)) > 0 {
+		// Hooks may answer with any body; only a well-formed Response is interpreted
+		_ = json.Unmarshal(raw, &decoded)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusForbidden:
+		return &RejectedError{Hook: ep.Name, Reason: decoded.Reason}
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("hook %s: unexpected status %d", ep.Name, resp.StatusCode)
+	case decoded.Allowed != nil && !*decoded.Allowed:
+		return &RejectedError{Hook: ep.Name, Reason: decoded.Reason}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package keybroker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Broker types
+const (
+	// TypeGeneric speaks the agentops key broker protocol: POST <url>/keys issues a
+	// key, DELETE <url>/keys/<id> revokes it
+	TypeGeneric = "generic"
+
+	// TypeLiteLLM issues LiteLLM proxy virtual keys through /key/generate and
+	// revokes them through /key/delete
+	TypeLiteLLM = "litellm"
+)
+
+// maxOutput bounds how much of an error response body is kept
+const maxOutput = 512
+
+// Request describes the agent a key is issued for
+type Request struct {
+	Namespace       string `json:"namespace"`
+	AgentDeployment string `json:"agentDeployment"`
+	Secret          string `json:"secret"`
+	Key             string `json:"key"`
+	Provider        string `json:"provider"`
+	Model           string `json:"model"`
+}
+
+// Key is an issued virtual API key
+type Key struct {
+	// ID revokes the key; it is not secret
+	ID string `json:"id"`
+
+	// Value is the API key the agent sends
+	Value string `json:"key"`
+}
+
+// Client issues and revokes virtual API keys at a key broker
+type Client struct {
+	// URL is the base URL of the broker
+	URL string
+
+	// Type is TypeGeneric or TypeLiteLLM
+	Type string
+
+	// TokenFile holds the bearer token sent to the broker; it is read on every call,
+	// so the token can be rotated without a restart
+	TokenFile string
+
+	// HTTPClient sends the requests; the caller's context bounds each request
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client for the broker at url
+func NewClient(url, brokerType, tokenFile string) *Client {
+	if brokerType == "" {
+		brokerType = TypeGeneric
+	}
+	return &Client{URL: strings.TrimSuffix(url, "/"), Type: brokerType, TokenFile: tokenFile, HTTPClient: &http.Client{}}
+}
+
+// Issue creates a key for req
+func (c *Client) Issue(ctx context.Context, req Request) (*Key, error) {
+	if c.Type == TypeLiteLLM {
+		// Keys are revoked by alias, so the alias must be unique per key
+		alias := fmt.Sprintf("%s/%s/%s-%d", req.Namespace, req.AgentDeployment, req.Key, time.Now().UnixNano())
+		body := map[string]interface{}{
+			"key_alias": alias,
+			"models":    []string{req.Model},
+			"metadata": map[string]string{
+				"namespace":       req.Namespace,
+				"agentDeployment": req.AgentDeployment,
+			},
+		}
+		var out struct {
+			Key string `json:"key"`
+		}
+		if err := c.do(ctx, http.MethodPost, "/key/generate", body, &out); err != nil {
+			return nil, err
+		}
+		return &Key{ID: alias, Value: out.Key}, nil
+	}
+
+	key := &Key{}
+	if err := c.do(ctx, http.MethodPost, "/keys", req, key); err != nil {
+		return nil, err
+	}
+	if key.ID == "" || key.Value == "" {
+		return nil, fmt.Errorf("key broker %s answered without an id or key", c.URL)
+	}
+	return key, nil
+}
+
+// Revoke invalidates the key with the given ID. Keys the broker does not know are
+// considered revoked.
+func (c *Client) Revoke(ctx context.Context, id string) error {
+	if c.Type == TypeLiteLLM {
+		return c.do(ctx, http.MethodPost, "/key/delete", map[string]interface{}{"key_aliases": []string{id}}, nil)
+	}
+	return c.do(ctx, http.MethodDelete, "/keys/"+url.PathEscape(id), nil, nil)
+}
+
+// do sends a JSON request to the broker and decodes the answer into out
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.URL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.TokenFile != "" {
+		token, err := os.ReadFile(c.TokenFile)
+		if err != nil {
+			return fmt.Errorf("read key broker token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if method != http.MethodPost && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+		return fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, c.URL+path, bytes.TrimSpace(raw))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode answer of %s: %w", c.URL+path, err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LevelKey is the key of the log config ConfigMap holding the level
+const LevelKey = "level"
+
+// ConfigWatcher applies the level of a ConfigMap to the controller's logs. The
+// ConfigMap is read directly rather than from the cache, since its namespace need
+// not be watched; a missing ConfigMap or key restores the initial level.
+type ConfigWatcher struct {
+	// Reader reads the ConfigMap, e.g. the manager's API reader
+	Reader client.Reader
+
+	Log logr.Logger
+
+	// Key of the ConfigMap
+	Key client.ObjectKey
+
+	// Interval between two reads of the ConfigMap
+	Interval time.Duration
+}
+
+// Start polls the ConfigMap until ctx is cancelled; it implements manager.Runnable
+func (w *ConfigWatcher) Start(ctx context.Context) error {
+	initial := Level()
+	w.Log.Info("Watching log config", "ConfigMap", w.Key.String(), "Interval", w.Interval)
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		w.apply(ctx, initial)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection is false: every replica logs
+func (w *ConfigWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// apply sets the level of the ConfigMap, or initial without one
+func (w *ConfigWatcher) apply(ctx context.Context, initial zapcore.Level) {
+	want := initial
+	cm := &corev1.ConfigMap{}
+	err := w.Reader.Get(ctx, w.Key, cm)
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		w.Log.Error(err, "Failed to read log config", "ConfigMap", w.Key.String())
+		return
+	case cm.Data[LevelKey] == "":
+	default:
+		l, err := ParseLevel(cm.Data[LevelKey])
+		if err != nil {
+			w.Log.Error(err, "Ignoring log config", "ConfigMap", w.Key.String())
+			return
+		}
+		want = l
+	}
+	if want != Level() {
+		w.Log.Info("Changing log level", "From", Level().String(), "To", want.String())
+		SetLevel(want)
+	}
+}
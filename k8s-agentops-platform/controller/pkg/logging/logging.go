@@ -0,0 +1,138 @@
+package logging
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// maxVerbosity is the highest logr V-level that can be enabled
+const maxVerbosity = 10
+
+// level is the verbosity of the controller, changed at runtime by the log config
+// ConfigMap. logr V-levels are negative zap levels: V(1) is debug.
+var level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+// New returns the root logger for opts as bound to the --zap-* flags, with the
+// level of --zap-log-level as the initial verbosity. The zap core lets every level
+// through and the returned logger filters, so that the verbosity can be changed
+// at runtime and raised for single objects.
+func New(opts *crzap.Options) logr.Logger {
+	switch l := opts.Level.(type) {
+	case zap.AtomicLevel:
+		level.SetLevel(l.Level())
+	case zapcore.Level:
+		level.SetLevel(l)
+	case nil:
+		if opts.Development {
+			level.SetLevel(zapcore.DebugLevel)
+		}
+	}
+	opts.Level = zapcore.Level(-maxVerbosity)
+	base := crzap.New(crzap.UseFlagOptions(opts))
+	return logr.New(&levelSink{sink: base.GetSink(), verbosity: -1})
+}
+
+// ParseLevel parses a level as accepted by --zap-log-level: debug, info, error or
+// a logr V-level greater than 0
+func ParseLevel(s string) (zapcore.Level, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v <= 0 || v > maxVerbosity {
+		return 0, fmt.Errorf("invalid log level %q, expected debug, info, error or 1-%d", s, maxVerbosity)
+	}
+	return zapcore.Level(-v), nil
+}
+
+// SetLevel changes the verbosity of every logger returned by New
+func SetLevel(l zapcore.Level) {
+	level.SetLevel(l)
+}
+
+// Level returns the current verbosity
+func Level() zapcore.Level {
+	return level.Level()
+}
+
+// ForObject returns log with the verbosity of obj's LogLevelAnnotation while it is
+// set and LogLevelUntilAnnotation has not passed. It never lowers the verbosity
+// below the controller's.
+func ForObject(log logr.Logger, obj client.Object) logr.Logger {
+	s, ok := log.GetSink().(*levelSink)
+	if !ok {
+		return log
+	}
+	annotations := obj.GetAnnotations()
+	value, set := annotations[agentopsv1alpha1.LogLevelAnnotation]
+	if !set {
+		return log
+	}
+	if until, err := time.Parse(time.RFC3339, annotations[agentopsv1alpha1.LogLevelUntilAnnotation]); err == nil && time.Now().After(until) {
+		return log
+	}
+	l, err := ParseLevel(value)
+	if err != nil {
+		log.Info("Ignoring invalid log level annotation", "Annotation", agentopsv1alpha1.LogLevelAnnotation, "Value", value)
+		return log
+	}
+	return log.WithSink(&levelSink{sink: s.sink, verbosity: -int(l)})
+}
+
+// levelSink filters the log lines of an unfiltered sink by the controller's level
+// or, when verbosity is not -1, the higher verbosity of one object
+type levelSink struct {
+	sink      logr.LogSink
+	verbosity int
+}
+
+var _ logr.CallDepthLogSink = &levelSink{}
+
+// Init accounts for the frame levelSink adds between logr and sink
+func (s *levelSink) Init(info logr.RuntimeInfo) {
+	s.sink.Init(info)
+}
+
+func (s *levelSink) Enabled(v int) bool {
+	return v <= s.verbosity || zapcore.Level(-v) >= level.Level()
+}
+
+func (s *levelSink) Info(v int, msg string, keysAndValues ...interface{}) {
+	s.sink.Info(v, msg, keysAndValues...)
+}
+
+func (s *levelSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.sink.Error(err, msg, keysAndValues...)
+}
+
+func (s *levelSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &levelSink{sink: s.sink.WithValues(keysAndValues...), verbosity: s.verbosity}
+}
+
+func (s *levelSink) WithName(name string) logr.LogSink {
+	return &levelSink{sink: s.sink.WithName(name), verbosity: s.verbosity}
+}
+
+func (s *levelSink) WithCallDepth(depth int) logr.LogSink {
+	sink := s.sink
+	if cd, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = cd.WithCallDepth(depth)
+	}
+	return &levelSink{sink: sink, verbosity: s.verbosity}
+}
@@ -0,0 +1,248 @@
+package metering
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/catalog"
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/usage"
+)
+
+// Report formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+var (
+	tokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agentops_usage_tokens_total",
+		Help: "Tokens consumed by agents, by namespace, agent and model.",
+	}, []string{"namespace", "agent", "model"})
+	costTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agentops_usage_cost_usd_total",
+		Help: "List price in USD of the tokens consumed by agents, by namespace, agent and model.",
+	}, []string{"namespace", "agent", "model"})
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agentops_usage_requests_total",
+		Help: "Requests served by agents, by namespace and agent.",
+	}, []string{"namespace", "agent"})
+	costPerRequest = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "agentops_usage_cost_per_request_usd",
+		Help: "LLM API spend in USD per request of agents over the last metering interval.",
+	}, []string{"namespace", "agent"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(tokensTotal, costTotal, requestsTotal, costPerRequest)
+}
+
+// Source reads the token usage of all agents
+type Source interface {
+	UsageByAgent(ctx context.Context, window time.Duration) ([]usage.AgentUsage, error)
+}
+
+// Store receives usage reports
+type Store interface {
+	Put(ctx context.Context, key, contentType string, body []byte) error
+}
+
+// Record is a line of a usage report: the tokens an agent consumed with a model
+// during the period, and what they cost
+type Record struct {
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	Namespace   string    `json:"namespace"`
+	Agent       string    `json:"agent"`
+	Model       string    `json:"model"`
+	Tokens      float64   `json:"tokens"`
+	CostUSD     float64   `json:"costUSD"`
+
+	// Requests and CostPerRequestUSD cover all models of the agent
+	Requests          float64 `json:"requests"`
+	CostPerRequestUSD float64 `json:"costPerRequestUSD"`
+}
+
+// Meter exports the token usage and LLM API spend of every agent as Prometheus
+// metrics, and writes a usage report for every report period to the Store
+type Meter struct {
+	Source Source
+	Log    logr.Logger
+
+	// Interval between two reads of the usage source
+	Interval time.Duration
+
+	// Store receives the reports; nil disables them
+	Store Store
+
+	// ReportPeriod is the period a report covers; periods are aligned to it in UTC,
+	// so 24h writes a report of every day after midnight
+	ReportPeriod time.Duration
+
+	// Prefix is prepended to the report object keys, e.g. agentops/usage/
+	Prefix string
+
+	// Format of the reports, FormatCSV or FormatJSON
+	Format string
+}
+
+// Start meters until ctx is cancelled; it implements manager.Runnable
+func (m *Meter) Start(ctx context.Context) error {
+	m.Log.Info("Starting metering", "Interval", m.Interval, "ReportPeriod", m.ReportPeriod)
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	var nextReport time.Time
+	if m.Store != nil {
+		nextReport = time.Now().UTC().Truncate(m.ReportPeriod).Add(m.ReportPeriod)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if err := m.meter(ctx); err != nil {
+				m.Log.Error(err, "Failed to read agent usage")
+			}
+			if !nextReport.IsZero() && !now.Before(nextReport) {
+				if err := m.report(ctx, nextReport.Add(-m.ReportPeriod), nextReport); err != nil {
+					// The next tick retries the same period
+					m.Log.Error(err, "Failed to write usage report", "PeriodEnd", nextReport)
+					continue
+				}
+				nextReport = nextReport.Add(m.ReportPeriod)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is true: only the leader counts usage, so it is exported once
+func (m *Meter) NeedLeaderElection() bool {
+	return true
+}
+
+// meter adds the usage of the last interval to the metrics
+func (m *Meter) meter(ctx context.Context) error {
+	records, err := m.usage(ctx, m.Interval)
+	if err != nil {
+		return err
+	}
+	counted := map[[2]string]bool{}
+	for _, r := range records {
+		tokensTotal.WithLabelValues(r.Namespace, r.Agent, r.Model).Add(r.Tokens)
+		costTotal.WithLabelValues(r.Namespace, r.Agent, r.Model).Add(r.CostUSD)
+		agent := [2]string{r.Namespace, r.Agent}
+		if counted[agent] {
+			continue
+		}
+		counted[agent] = true
+		requestsTotal.WithLabelValues(r.Namespace, r.Agent).Add(r.Requests)
+		if r.Requests > 0 {
+			costPerRequest.WithLabelValues(r.Namespace, r.Agent).Set(r.CostPerRequestUSD)
+		}
+	}
+	return nil
+}
+
+// usage returns the records of every agent and model over the last window
+func (m *Meter) usage(ctx context.Context, window time.Duration) ([]Record, error) {
+	samples, err := m.Source.UsageByAgent(ctx, window)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0, len(samples))
+	spend := map[[2]string]float64{}
+	for _, s := range samples {
+		cost := catalog.Cost(s.Model, s.Tokens)
+		spend[[2]string{s.Namespace, s.Agent}] += cost
+		records = append(records, Record{
+			Namespace: s.Namespace,
+			Agent:     s.Agent,
+			Model:     s.Model,
+			Tokens:    s.Tokens,
+			CostUSD:   cost,
+			Requests:  s.Requests,
+		})
+	}
+	for i := range records {
+		if r := &records[i]; r.Requests > 0 {
+			r.CostPerRequestUSD = spend[[2]string{r.Namespace, r.Agent}] / r.Requests
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Agent != b.Agent {
+			return a.Agent < b.Agent
+		}
+		return a.Model < b.Model
+	})
+	return records, nil
+}
+
+// report writes the usage report of the period ending at end
+func (m *Meter) report(ctx context.Context, start, end time.Time) error {
+	records, err := m.usage(ctx, end.Sub(start))
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		records[i].PeriodStart, records[i].PeriodEnd = start, end
+	}
+
+	var body []byte
+	contentType := "text/csv"
+	if m.Format == FormatJSON {
+		contentType = "application/json"
+		if body, err = json.MarshalIndent(records, "", "  "); err != nil {
+			return err
+		}
+	} else if body, err = encodeCSV(records); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%susage-%s.%s", m.Prefix, start.Format("20060102T150405Z"), m.format())
+	m.Log.Info("Writing usage report", "Key", key, "Records", len(records))
+	return m.Store.Put(ctx, key, contentType, body)
+}
+
+// format returns the file extension of the reports
+func (m *Meter) format() string {
+	if m.Format == FormatJSON {
+		return FormatJSON
+	}
+	return FormatCSV
+}
+
+// encodeCSV renders the records as CSV with a header line
+func encodeCSV(records []Record) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"period_start", "period_end", "namespace", "agent", "model", "tokens", "cost_usd", "requests", "cost_per_request_usd"})
+	for _, r := range records {
+		w.Write([]string{
+			r.PeriodStart.Format(time.RFC3339),
+			r.PeriodEnd.Format(time.RFC3339),
+			r.Namespace,
+			r.Agent,
+			r.Model,
+			strconv.FormatFloat(r.Tokens, 'f', 0, 64),
+			strconv.FormatFloat(r.CostUSD, 'f', 6, 64),
+			strconv.FormatFloat(r.Requests, 'f', 0, 64),
+			strconv.FormatFloat(r.CostPerRequestUSD, 'f', 6, 64),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,121 @@
+package metering
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxOutput bounds how much of an error response body is kept
+const maxOutput = 512
+
+// S3Store writes reports to an S3 bucket, or any S3-compatible object store, with
+// the credentials of the standard AWS environment variables (AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN), read on every write
+type S3Store struct {
+	// Bucket receives the reports
+	Bucket string
+
+	// Region of the bucket, e.g. eu-west-1
+	Region string
+
+	// Endpoint overrides the AWS endpoint, e.g. http://minio.storage:9000; objects are
+	// then addressed path-style
+	Endpoint string
+
+	// HTTPClient sends the requests; the caller's context bounds each request
+	HTTPClient *http.Client
+}
+
+// NewS3Store returns an S3Store for the bucket
+func NewS3Store(bucket, region, endpoint string) *S3Store {
+	return &S3Store{Bucket: bucket, Region: region, Endpoint: strings.TrimSuffix(endpoint, "/"), HTTPClient: &http.Client{}}
+}
+
+// Put uploads body as the object key
+func (s *S3Store) Put(ctx context.Context, key, contentType string, body []byte) error {
+	var target string
+	if s.Endpoint != "" {
+		target = fmt.Sprintf("%s/%s/%s", s.Endpoint, s.Bucket, key)
+	} else {
+		target = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.Bucket, s.Region, key)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if err := s.sign(req, body, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		out, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+		return fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, target, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// sign adds an AWS Signature Version 4 to the request
+func (s *S3Store) sign(req *http.Request, body []byte, now time.Time) error {
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set to write usage reports")
+	}
+	payload := sha256.Sum256(body)
+	date, stamp := now.Format("20060102"), now.Format("20060102T150405Z")
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payload[:]))
+	req.Header.Set("X-Amz-Date", stamp)
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+
+	names := []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	if req.Header.Get("X-Amz-Security-Token") != "" {
+		names = append(names, "x-amz-security-token")
+	}
+	var headers strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&headers, "%s:%s\n", name, strings.TrimSpace(req.Header.Get(name)))
+	}
+	signed := strings.Join(names, ";")
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		headers.String(),
+		signed,
+		hex.EncodeToString(payload[:]),
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.Region)
+	hashed := sha256.Sum256([]byte(canonical))
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", stamp, scope, hex.EncodeToString(hashed[:])}, "\n")
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	for _, part := range []string{s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign))))
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,248 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Channel types
+const (
+	// TypeSlack posts a message to a Slack incoming webhook
+	TypeSlack = "slack"
+
+	// TypeWebhook POSTs the Notification as JSON to any HTTP endpoint
+	TypeWebhook = "webhook"
+
+	// TypePagerDuty triggers and resolves alerts through the PagerDuty Events API v2
+	TypePagerDuty = "pagerduty"
+)
+
+// Events that notifications are sent for; the agentops.io/notify-events annotation
+// of an AgentDeployment selects among them
+const (
+	EventPhaseChanged   = "PhaseChanged"
+	EventDegraded       = "Degraded"
+	EventStalled        = "Stalled"
+	EventBudgetExceeded = "BudgetExceeded"
+	EventRolledBack     = "RolledBack"
+	EventRolloutFailed  = "RolloutFailed"
+)
+
+// Severities, as understood by PagerDuty
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// DefaultPagerDutyURL is the Events API v2 endpoint
+const DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// timeout bounds the delivery to one channel
+const timeout = 10 * time.Second
+
+// maxOutput bounds how much of an error response body is kept
+const maxOutput = 512
+
+// Channel is a destination configured by the operator
+type Channel struct {
+	// Name is what AgentDeployments list in their agentops.io/notify annotation
+	Name string `json:"name"`
+
+	// Type is slack, webhook or pagerduty
+	Type string `json:"type"`
+
+	// URL is the Slack incoming webhook or the webhook endpoint; for pagerduty it
+	// defaults to DefaultPagerDutyURL
+	URL string `json:"url,omitempty"`
+
+	// RoutingKey is the integration key of the PagerDuty service
+	RoutingKey string `json:"routingKey,omitempty"`
+
+	// Headers are added to webhook requests, e.g. Authorization
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Config is the file given to the controller with --notification-config
+type Config struct {
+	Channels []Channel `json:"channels"`
+}
+
+// Notification describes a transition of an AgentDeployment
+type Notification struct {
+	Namespace       string    `json:"namespace"`
+	AgentDeployment string    `json:"agentDeployment"`
+	Event           string    `json:"event"`
+	Severity        string    `json:"severity"`
+	Summary         string    `json:"summary"`
+	Message         string    `json:"message,omitempty"`
+	Time            time.Time `json:"time"`
+
+	// Resolved is set when the condition that triggered an earlier notification
+	// of the same event cleared
+	Resolved bool `json:"resolved,omitempty"`
+}
+
+// key identifies the alert a notification triggers or resolves
+func (n Notification) key() string {
+	return fmt.Sprintf("agentops/%s/%s/%s", n.Namespace, n.AgentDeployment, n.Event)
+}
+
+// LoadConfig reads and checks a notification config file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	names := map[string]bool{}
+	for _, ch := range cfg.Channels {
+		if ch.Name == "" || names[ch.Name] {
+			return nil, fmt.Errorf("%s: channel names must be set and unique, got %q", path, ch.Name)
+		}
+		names[ch.Name] = true
+		switch ch.Type {
+		case TypeSlack, TypeWebhook:
+			if ch.URL == "" {
+				return nil, fmt.Errorf("%s: channel %s needs a url", path, ch.Name)
+			}
+		case TypePagerDuty:
+			if ch.RoutingKey == "" {
+				return nil, fmt.Errorf("%s: channel %s needs a routingKey", path, ch.Name)
+			}
+		default:
+			return nil, fmt.Errorf("%s: channel %s has type %q, expected slack, webhook or pagerduty", path, ch.Name, ch.Type)
+		}
+	}
+	return cfg, nil
+}
+
+// Client delivers notifications to the channels of a Config
+type Client struct {
+	channels map[string]Channel
+
+	// HTTPClient sends the requests
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client for the channels of cfg
+func NewClient(cfg *Config) *Client {
+	c := &Client{channels: map[string]Channel{}, HTTPClient: &http.Client{}}
+	for _, ch := range cfg.Channels {
+		c.channels[ch.Name] = ch
+	}
+	return c
+}
+
+// Notify sends n to the named channels. Every channel is tried; the errors of
+// those that failed are joined.
+func (c *Client) Notify(ctx context.Context, channels []string, n Notification) error {
+	var errs []error
+	for _, name := range channels {
+		ch, ok := c.channels[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown notification channel %s", name))
+			continue
+		}
+		if err := c.send(ctx, ch, n); err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// send delivers n to one channel in its format
+func (c *Client) send(ctx context.Context, ch Channel, n Notification) error {
+	switch ch.Type {
+	case TypeSlack:
+		return c.post(ctx, ch.URL, nil, map[string]string{"text": slackText(n)})
+	case TypePagerDuty:
+		url := ch.URL
+		if url == "" {
+			url = DefaultPagerDutyURL
+		}
+		return c.post(ctx, url, nil, pagerDutyEvent(ch.RoutingKey, n))
+	}
+	return c.post(ctx, ch.URL, ch.Headers, n)
+}
+
+// slackText renders n as a Slack message
+func slackText(n Notification) string {
+	icon := map[string]string{SeverityInfo: ":information_source:", SeverityWarning: ":warning:", SeverityCritical: ":rotating_light:"}[n.Severity]
+	if n.Resolved {
+		icon = ":white_check_mark:"
+	}
+	text := fmt.Sprintf("%s *%s/%s*: %s", icon, n.Namespace, n.AgentDeployment, n.Summary)
+	if n.Message != "" {
+		text += "\n" + n.Message
+	}
+	return text
+}
+
+// pagerDutyEvent renders n as an Events API v2 event. Notifications of the same
+// event of an agent share a dedup key, so a resolved one closes the alert.
+func pagerDutyEvent(routingKey string, n Notification) map[string]interface{} {
+	event := map[string]interface{}{
+		"routing_key":  routingKey,
+		"event_action": "trigger",
+		"dedup_key":    n.key(),
+	}
+	if n.Resolved {
+		event["event_action"] = "resolve"
+		return event
+	}
+	event["payload"] = map[string]interface{}{
+		"summary":   fmt.Sprintf("%s/%s: %s", n.Namespace, n.AgentDeployment, n.Summary),
+		"source":    n.Namespace + "/" + n.AgentDeployment,
+		"severity":  n.Severity,
+		"component": n.AgentDeployment,
+		"group":     n.Namespace,
+		"class":     n.Event,
+		"timestamp": n.Time.Format(time.RFC3339),
+		"custom_details": map[string]string{
+			"message": n.Message,
+		},
+	}
+	return event
+}
+
+// post sends body as JSON to url
+func (c *Client) post(ctx context.Context, url string, headers map[string]string, body interface{}) error {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		out, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package observe
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Controller modes
+const (
+	ModeEnforce = "enforce"
+	ModeObserve = "observe"
+)
+
+var skippedWrites = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "agentops_observe_skipped_writes_total",
+	Help: "Writes the controller would have made in observe mode, i.e. drift between desired and live state.",
+}, []string{"verb", "kind", "namespace"})
+
+func init() {
+	metrics.Registry.MustRegister(skippedWrites)
+}
+
+// Client wraps a client so that every write is sent as a server-side dry run.
+// The API server still validates and defaults the request, so reconcilers see
+// realistic results, but nothing is persisted. Each skipped write is logged and
+// counted as drift.
+type Client struct {
+	client.Client
+	log logr.Logger
+}
+
+// NewClient returns a read-only view of c for observe mode
+func NewClient(c client.Client, log logr.Logger) client.Client {
+	return &Client{Client: c, log: log}
+}
+
+// Create records and dry-runs a create
+func (c *Client) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	c.record("create", obj, "", nil)
+	return c.Client.Create(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+// Update records and dry-runs an update
+func (c *Client) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.record("update", obj, "", nil)
+	return c.Client.Update(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+// Patch records and dry-runs a patch
+func (c *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.record("patch", obj, "", patch)
+	return c.Client.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...)
+}
+
+// Delete records and dry-runs a delete
+func (c *Client) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	c.record("delete", obj, "", nil)
+	return c.Client.Delete(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+// DeleteAllOf records and dry-runs a collection delete
+func (c *Client) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	c.record("deletecollection", obj, "", nil)
+	return c.Client.DeleteAllOf(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+// Status returns a status writer that dry-runs and reports status changes
+func (c *Client) Status() client.SubResourceWriter {
+	return &subResourceClient{SubResourceClient: c.Client.SubResource("status"), parent: c, subResource: "status"}
+}
+
+// SubResource returns a subresource client that dry-runs writes
+func (c *Client) SubResource(subResource string) client.SubResourceClient {
+	return &subResourceClient{SubResourceClient: c.Client.SubResource(subResource), parent: c, subResource: subResource}
+}
+
+type subResourceClient struct {
+	client.SubResourceClient
+	parent      *Client
+	subResource string
+}
+
+func (s *subResourceClient) Create(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+	s.parent.record("create", obj, s.subResource, nil)
+	return s.SubResourceClient.Create(ctx, obj, subResource, append(opts, client.DryRunAll)...)
+}
+
+func (s *subResourceClient) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	s.parent.record("update", obj, s.subResource, nil)
+	return s.SubResourceClient.Update(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+func (s *subResourceClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	s.parent.record("patch", obj, s.subResource, patch)
+	return s.SubResourceClient.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...)
+}
+
+// record logs and counts a skipped write and adds it to the dry-run report. Status
+// writes also log the conditions the controller computed, since they are not
+// persisted for anyone to read, and patches the changes they carry.
+func (c *Client) record(verb string, obj client.Object, subResource string, patch client.Patch) {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if gvk, err := apiutil.GVKForObject(obj, c.Scheme()); err == nil {
+		kind = gvk.Kind
+	}
+	if subResource != "" {
+		kind += "/" + subResource
+	}
+	skippedWrites.WithLabelValues(verb, kind, obj.GetNamespace()).Inc()
+
+	w := Write{Verb: verb, Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	keysAndValues := []interface{}{"Verb", verb, "Kind", kind, "Namespace", obj.GetNamespace(), "Name", obj.GetName()}
+	if subResource == "status" {
+		if w.Conditions = conditionSummary(obj); w.Conditions != "" {
+			keysAndValues = append(keysAndValues, "Conditions", w.Conditions)
+		}
+	}
+	if patch != nil {
+		if w.Patch = patchSummary(obj, patch); w.Patch != "" {
+			keysAndValues = append(keysAndValues, "Patch", w.Patch)
+		}
+	}
+	skipped.add(w)
+	c.log.Info("Observe mode: skipped write", keysAndValues...)
+}
+
+// patchSummary returns the body of a patch, truncated to maxPatchBytes. Apply
+// patches carry the whole object and are left out.
+func patchSummary(obj client.Object, patch client.Patch) string {
+	if patch.Type() == types.ApplyPatchType {
+		return ""
+	}
+	data, err := patch.Data(obj)
+	if err != nil {
+		return ""
+	}
+	if len(data) > maxPatchBytes {
+		return string(data[:maxPatchBytes]) + "..."
+	}
+	return string(data)
+}
+
+// conditionSummary renders status.conditions as "Type=Status(Reason)" pairs
+func conditionSummary(obj runtime.Object) string {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return ""
+	}
+	conditions, _, _ := unstructured.NestedSlice(content, "status", "conditions")
+	parts := make([]string, 0, len(conditions))
+	for _, item := range conditions {
+		cond, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%v=%v(%v)", cond["type"], cond["status"], cond["reason"]))
+	}
+	return strings.Join(parts, ", ")
+}
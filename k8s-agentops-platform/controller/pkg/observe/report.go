@@ -0,0 +1,155 @@
+package observe
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ReportKey is the key of the dry-run report ConfigMap holding the writes
+	ReportKey = "writes.json"
+
+	// maxReportEntries and maxPatchBytes keep the report well below the 1MiB limit
+	// of a ConfigMap
+	maxReportEntries = 1000
+	maxPatchBytes    = 512
+)
+
+// Write is a write the controller skipped in observe mode. Repeated writes to the
+// same object with the same verb are folded into one entry.
+type Write struct {
+	Verb       string      `json:"verb"`
+	Kind       string      `json:"kind"`
+	Namespace  string      `json:"namespace,omitempty"`
+	Name       string      `json:"name"`
+	Patch      string      `json:"patch,omitempty"`
+	Conditions string      `json:"conditions,omitempty"`
+	Count      int         `json:"count"`
+	FirstSeen  metav1.Time `json:"firstSeen"`
+	LastSeen   metav1.Time `json:"lastSeen"`
+}
+
+// writeLog collects the skipped writes for the dry-run report
+type writeLog struct {
+	mu      sync.Mutex
+	writes  map[string]*Write
+	dropped int
+}
+
+// skipped is fed by every observe Client and published by the ReportWriter
+var skipped = &writeLog{writes: map[string]*Write{}}
+
+func (l *writeLog) add(w Write) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := w.Verb + "/" + w.Kind + "/" + w.Namespace + "/" + w.Name
+	now := metav1.Now()
+	if prev, ok := l.writes[key]; ok {
+		prev.Count++
+		prev.LastSeen = now
+		prev.Patch, prev.Conditions = w.Patch, w.Conditions
+		return
+	}
+	if len(l.writes) >= maxReportEntries {
+		l.dropped++
+		return
+	}
+	w.Count, w.FirstSeen, w.LastSeen = 1, now, now
+	l.writes[key] = &w
+}
+
+// list returns the writes ordered by namespace, kind and name, and how many
+// objects were left out because the report was full
+func (l *writeLog) list() ([]Write, int) {
+	l.mu.Lock()
+	out := make([]Write, 0, len(l.writes))
+	for _, w := range l.writes {
+		out = append(out, *w)
+	}
+	dropped := l.dropped
+	l.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Verb < b.Verb
+	})
+	return out, dropped
+}
+
+// ReportWriter publishes the writes skipped in observe mode to a ConfigMap, so a
+// dry run of a new controller version can be reviewed before it is let loose
+type ReportWriter struct {
+	// Client writes the ConfigMap; it must not be an observe Client
+	Client client.Client
+
+	Log logr.Logger
+
+	// Namespace and Name of the report ConfigMap
+	Namespace string
+	Name      string
+
+	// Interval between two updates of the report
+	Interval time.Duration
+}
+
+// Start updates the report until ctx is cancelled; it implements manager.Runnable
+func (r *ReportWriter) Start(ctx context.Context) error {
+	r.Log.Info("Starting dry-run report", "ConfigMap", r.Namespace+"/"+r.Name, "Interval", r.Interval)
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.write(ctx); err != nil {
+				r.Log.Error(err, "Failed to write dry-run report")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is true: only the leader reconciles, so only its writes are
+// complete
+func (r *ReportWriter) NeedLeaderElection() bool {
+	return true
+}
+
+// write applies the report ConfigMap. It is applied server-side rather than read
+// first, since the report namespace need not be in the cache.
+func (r *ReportWriter) write(ctx context.Context) error {
+	writes, dropped := skipped.list()
+	data, err := json.MarshalIndent(writes, "", "  ")
+	if err != nil {
+		return err
+	}
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: r.Name, Namespace: r.Namespace},
+		Data:       map[string]string{ReportKey: string(data)},
+	}
+	cm.Annotations = map[string]string{
+		"agentops.io/report-updated": time.Now().UTC().Format(time.RFC3339),
+	}
+	if dropped > 0 {
+		cm.Annotations["agentops.io/report-truncated"] = "true"
+	}
+	return r.Client.Patch(ctx, cm, client.Apply, client.FieldOwner("agentops-controller"), client.ForceOwnership)
+}
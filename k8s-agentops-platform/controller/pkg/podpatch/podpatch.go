@@ -0,0 +1,59 @@
+package podpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+)
+
+// Key is the ConfigMap key holding the pod template patch
+const Key = "patch.yaml"
+
+// Render executes the patch as a Go template with data and returns the resulting
+// strategic merge patch as JSON, or nil if it renders to nothing
+func Render(text string, data interface{}) ([]byte, error) {
+	tmpl, err := template.New(Key).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(out.String()) == "" {
+		return nil, nil
+	}
+	patch, err := yaml.YAMLToJSON(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("rendered patch is not YAML: %w", err)
+	}
+	return patch, nil
+}
+
+// Apply applies a strategic merge patch to a pod template. Lists such as containers,
+// env and volumes are merged by name, so the patch only lists what it adds or changes.
+func Apply(patch []byte, pod *corev1.PodTemplateSpec) error {
+	if len(patch) == 0 {
+		return nil
+	}
+	original, err := json.Marshal(pod)
+	if err != nil {
+		return err
+	}
+	patched, err := strategicpatch.StrategicMergePatch(original, patch, corev1.PodTemplateSpec{})
+	if err != nil {
+		return err
+	}
+	result := corev1.PodTemplateSpec{}
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return err
+	}
+	*pod = result
+	return nil
+}
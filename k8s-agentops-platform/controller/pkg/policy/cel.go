@@ -0,0 +1,125 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// celCostLimit bounds the work of a single rule, so that a rule iterating over
+// large lists cannot stall the webhook
+const celCostLimit = 1000000
+
+var (
+	celEnvOnce sync.Once
+	celEnv     *cel.Env
+	celEnvErr  error
+
+	// programs caches compiled rules by expression; rules change rarely and are
+	// evaluated on every admission
+	programs sync.Map
+)
+
+// env returns the CEL environment of rules: object and oldObject are the admitted
+// AgentDeployment and, on update, the stored one; namespaceObject has the name,
+// labels and annotations of its namespace ("namespace" is reserved in CEL)
+func env() (*cel.Env, error) {
+	celEnvOnce.Do(func() {
+		celEnv, celEnvErr = cel.NewEnv(
+			cel.Variable("object", cel.DynType),
+			cel.Variable("oldObject", cel.DynType),
+			cel.Variable("namespaceObject", cel.MapType(cel.StringType, cel.DynType)),
+		)
+	})
+	return celEnv, celEnvErr
+}
+
+// compile returns the program of a rule expression, which must evaluate to a bool
+func compile(expression string) (cel.Program, error) {
+	if prg, ok := programs.Load(expression); ok {
+		return prg.(cel.Program), nil
+	}
+	e, err := env()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := e.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if t := ast.OutputType().String(); t != "bool" && t != "dyn" {
+		return nil, fmt.Errorf("expression evaluates to %s, expected bool", t)
+	}
+	prg, err := e.Program(ast, cel.CostLimit(celCostLimit))
+	if err != nil {
+		return nil, err
+	}
+	programs.Store(expression, prg)
+	return prg, nil
+}
+
+// CEL admits AgentDeployments for which every rule evaluates to true
+type CEL struct {
+	Rules []agentopsv1alpha1.AdmissionRule
+}
+
+// Evaluate runs the rules. A rule that does not compile or fails to evaluate, e.g.
+// on a missing field, denies the request, since a broken rule must not let agents
+// through unnoticed.
+func (c *CEL) Evaluate(ctx context.Context, in Input) (Decision, error) {
+	var oldObject interface{}
+	if in.OldObject != nil {
+		oldObject = in.OldObject
+	}
+	vars := map[string]interface{}{
+		"object":    in.Object,
+		"oldObject": oldObject,
+		"namespaceObject": map[string]interface{}{
+			"name":        in.Namespace.Name,
+			"labels":      stringMap(in.Namespace.Labels),
+			"annotations": stringMap(in.Namespace.Annotations),
+		},
+	}
+
+	d := Decision{Allowed: true}
+	for _, rule := range c.Rules {
+		prg, err := compile(rule.Expression)
+		if err != nil {
+			d.Allowed = false
+			d.Reasons = append(d.Reasons, fmt.Sprintf("rule %s is invalid: %v", rule.Name, err))
+			continue
+		}
+		out, _, err := prg.ContextEval(ctx, vars)
+		if err != nil {
+			d.Allowed = false
+			d.Reasons = append(d.Reasons, fmt.Sprintf("rule %s could not be evaluated: %v", rule.Name, err))
+			continue
+		}
+		if allowed, ok := out.Value().(bool); !ok || !allowed {
+			d.Allowed = false
+			d.Reasons = append(d.Reasons, ruleMessage(rule))
+		}
+	}
+	return d, nil
+}
+
+// ruleMessage returns the message of a denying rule
+func ruleMessage(rule agentopsv1alpha1.AdmissionRule) string {
+	if rule.Message != "" {
+		return fmt.Sprintf("%s: %s", rule.Name, rule.Message)
+	}
+	return fmt.Sprintf("%s: %s is false", rule.Name, rule.Expression)
+}
+
+// stringMap returns m, or an empty map for nil, so that rules can test keys with
+// "in" on namespaces without labels or annotations
+func stringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
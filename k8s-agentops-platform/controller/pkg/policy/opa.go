@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+const defaultOPATimeout = 5 * time.Second
+
+// OPA asks an Open Policy Agent, or any server speaking its data API, whether to
+// admit an AgentDeployment. The input is posted as {"input": ...} to the URL of a
+// decision, e.g. http://opa.opa:8181/v1/data/agentops/admission, which answers
+// with {"result": true|false} or {"result": {"allowed": bool, "reasons": [...]}}.
+type OPA struct {
+	Spec agentopsv1alpha1.ExternalPolicy
+
+	HTTP *http.Client
+}
+
+type opaResponse struct {
+	Result json.RawMessage `json:"result"`
+}
+
+type opaDecision struct {
+	Allowed bool     `json:"allowed"`
+	Reasons []string `json:"reasons"`
+}
+
+// Evaluate queries the decision. Failures to reach the server or to read its
+// answer deny the request, or admit it with failurePolicy Ignore.
+func (o *OPA) Evaluate(ctx context.Context, in Input) (Decision, error) {
+	d, err := o.query(ctx, in)
+	if err != nil {
+		if o.Spec.FailurePolicy == agentopsv1alpha1.ExternalPolicyIgnore {
+			return Decision{Allowed: true}, nil
+		}
+		return Decision{}, fmt.Errorf("admission policy %s: %w", o.Spec.URL, err)
+	}
+	return d, nil
+}
+
+func (o *OPA) query(ctx context.Context, in Input) (Decision, error) {
+	timeout := defaultOPATimeout
+	if o.Spec.TimeoutSeconds != nil {
+		timeout = time.Duration(*o.Spec.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := json.Marshal(map[string]interface{}{"input": in})
+	if err != nil {
+		return Decision{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.Spec.URL, bytes.NewReader(body))
+	if err != nil {
+		return Decision{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.HTTP.Do(req)
+	if err != nil {
+		return Decision{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Decision{}, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var out opaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Decision{}, err
+	}
+	// An undefined decision has no result
+	if len(out.Result) == 0 {
+		return Decision{}, fmt.Errorf("decision is undefined")
+	}
+	var allowed bool
+	if err := json.Unmarshal(out.Result, &allowed); err == nil {
+		if !allowed {
+			return Decision{Reasons: []string{fmt.Sprintf("denied by %s", o.Spec.URL)}}, nil
+		}
+		return Decision{Allowed: true}, nil
+	}
+	var decision opaDecision
+	if err := json.Unmarshal(out.Result, &decision); err != nil {
+		return Decision{}, fmt.Errorf("result is neither a bool nor {allowed, reasons}: %w", err)
+	}
+	if !decision.Allowed && len(decision.Reasons) == 0 {
+		decision.Reasons = []string{fmt.Sprintf("denied by %s", o.Spec.URL)}
+	}
+	return Decision{Allowed: decision.Allowed, Reasons: decision.Reasons}, nil
+}
@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Input is what an AgentDeployment is admitted on: the object as JSON, the stored
+// object of an update, and the namespace it lives in
+type Input struct {
+	// Operation is CREATE or UPDATE
+	Operation string `json:"operation"`
+
+	Object map[string]interface{} `json:"object"`
+
+	// OldObject is nil on create
+	OldObject map[string]interface{} `json:"oldObject"`
+
+	Namespace Namespace `json:"namespace"`
+}
+
+// Namespace describes the namespace of the admitted object to rules
+type Namespace struct {
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// Decision is the outcome of a policy; Reasons explain a denial
+type Decision struct {
+	Allowed bool
+	Reasons []string
+}
+
+// Evaluator is a policy consulted by the AgentDeployment validating webhook on
+// every create and update. An error denies the request unless the evaluator
+// handles its own failures.
+type Evaluator interface {
+	Evaluate(ctx context.Context, in Input) (Decision, error)
+}
+
+// Evaluate runs every evaluator and returns an error joining the reasons of all
+// denials, or nil when all of them admit the input
+func Evaluate(ctx context.Context, in Input, evaluators ...Evaluator) error {
+	var reasons []string
+	for _, e := range evaluators {
+		d, err := e.Evaluate(ctx, in)
+		if err != nil {
+			return err
+		}
+		if !d.Allowed {
+			reasons = append(reasons, d.Reasons...)
+		}
+	}
+	if len(reasons) > 0 {
+		return fmt.Errorf("denied by admission policy: %s", strings.Join(reasons, "; "))
+	}
+	return nil
+}
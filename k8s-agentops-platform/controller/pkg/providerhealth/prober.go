@@ -0,0 +1,245 @@
+// Package providerhealth probes hosted model provider APIs: whether a key is
+// accepted, whether the model is served and how much of the rate limit is left.
+// Results are shared for a TTL by every agent using the same endpoint, model and
+// key, so a fleet of agents costs one request per provider model and key.
+package providerhealth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/k8s-agentops-platform/controller/pkg/providers"
+)
+
+const (
+	// DefaultTTL is how long a probe result is reused
+	DefaultTTL = 5 * time.Minute
+
+	// maxOutput bounds how much of an error response body is kept
+	maxOutput = 256
+
+	anthropicEndpoint = "https://api.anthropic.com"
+	anthropicVersion  = "2023-06-01"
+	openAIEndpoint    = "https://api.openai.com/v1"
+)
+
+// Auth states of a Result
+const (
+	AuthValid   = "Valid"
+	AuthInvalid = "Invalid"
+	AuthUnknown = "Unknown"
+)
+
+// Target is a provider model to probe
+type Target struct {
+	// Provider is one of providers.Anthropic, providers.OpenAI and providers.AzureOpenAI
+	Provider string
+
+	// Endpoint overrides the API base URL; required for Azure OpenAI
+	Endpoint string
+
+	// Model is the model ID, or the deployment name for Azure OpenAI
+	Model string
+
+	// APIVersion is the Azure OpenAI API version
+	APIVersion string
+
+	// APIKey authenticates the probe
+	APIKey string
+}
+
+// Result is the outcome of a probe
+type Result struct {
+	// Auth is AuthValid, AuthInvalid or AuthUnknown
+	Auth string
+
+	// ModelAvailable is nil when the provider did not say, e.g. for a rejected key
+	ModelAvailable *bool
+
+	// Rate limit headroom as reported by the provider's response headers, nil when
+	// absent
+	RequestsLimit, RequestsRemaining *int64
+	TokensLimit, TokensRemaining     *int64
+
+	// Message explains a failed probe
+	Message string
+
+	// Time is when the provider was called; cached results keep it
+	Time time.Time
+}
+
+// rateLimitHeaders are the response headers of a provider's rate limits
+type rateLimitHeaders struct {
+	requestsLimit, requestsRemaining, tokensLimit, tokensRemaining string
+}
+
+var (
+	anthropicRateLimits = rateLimitHeaders{
+		requestsLimit:     "anthropic-ratelimit-requests-limit",
+		requestsRemaining: "anthropic-ratelimit-requests-remaining",
+		tokensLimit:       "anthropic-ratelimit-tokens-limit",
+		tokensRemaining:   "anthropic-ratelimit-tokens-remaining",
+	}
+	openAIRateLimits = rateLimitHeaders{
+		requestsLimit:     "x-ratelimit-limit-requests",
+		requestsRemaining: "x-ratelimit-remaining-requests",
+		tokensLimit:       "x-ratelimit-limit-tokens",
+		tokensRemaining:   "x-ratelimit-remaining-tokens",
+	}
+)
+
+// Prober probes provider APIs and caches the results
+type Prober struct {
+	// HTTPClient sends the probes
+	HTTPClient *http.Client
+
+	// TTL is how long a result is reused
+	TTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]Result
+}
+
+// NewProber returns a Prober reusing results for ttl, with a 10s request timeout
+func NewProber(ttl time.Duration) *Prober {
+	return &Prober{HTTPClient: &http.Client{Timeout: 10 * time.Second}, TTL: ttl, cache: map[string]Result{}}
+}
+
+// Supported reports whether the provider can be probed
+func Supported(provider string) bool {
+	switch provider {
+	case providers.Anthropic, providers.OpenAI, providers.AzureOpenAI:
+		return true
+	}
+	return false
+}
+
+// Probe returns the cached result for the target while it is younger than the TTL
+// and calls the provider otherwise. Errors are failures to reach the provider; any
+// answer of the provider is a Result.
+func (p *Prober) Probe(ctx context.Context, t Target) (Result, error) {
+	key := cacheKey(t)
+	p.mu.Lock()
+	if result, ok := p.cache[key]; ok && time.Since(result.Time) < p.TTL {
+		p.mu.Unlock()
+		return result, nil
+	}
+	p.mu.Unlock()
+
+	result, err := p.probe(ctx, t)
+	if err != nil {
+		return Result{}, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k, cached := range p.cache {
+		if time.Since(cached.Time) >= p.TTL {
+			delete(p.cache, k)
+		}
+	}
+	p.cache[key] = result
+	return result, nil
+}
+
+// cacheKey identifies a target without keeping its API key
+func cacheKey(t Target) string {
+	sum := sha256.Sum256([]byte(t.APIKey))
+	return strings.Join([]string{t.Provider, t.Endpoint, t.Model, t.APIVersion, hex.EncodeToString(sum[:])}, "\x00")
+}
+
+// probe calls the provider. Models are read with the provider's model endpoints,
+// which cost no tokens: a 401 or 403 rejects the key, a 404 means the model is not
+// served to it.
+func (p *Prober) probe(ctx context.Context, t Target) (Result, error) {
+	var (
+		u      string
+		header = http.Header{}
+		limits rateLimitHeaders
+	)
+	switch t.Provider {
+	case providers.Anthropic:
+		u = strings.TrimSuffix(orDefault(t.Endpoint, anthropicEndpoint), "/") + "/v1/models/" + url.PathEscape(t.Model)
+		header.Set("x-api-key", t.APIKey)
+		header.Set("anthropic-version", anthropicVersion)
+		limits = anthropicRateLimits
+	case providers.OpenAI:
+		u = strings.TrimSuffix(orDefault(t.Endpoint, openAIEndpoint), "/") + "/models/" + url.PathEscape(t.Model)
+		header.Set("Authorization", "Bearer "+t.APIKey)
+		limits = openAIRateLimits
+	case providers.AzureOpenAI:
+		if t.Endpoint == "" {
+			return Result{}, fmt.Errorf("provider %s requires an endpoint", t.Provider)
+		}
+		u = fmt.Sprintf("%s/openai/deployments/%s?api-version=%s",
+			strings.TrimSuffix(t.Endpoint, "/"), url.PathEscape(t.Model), url.QueryEscape(t.APIVersion))
+		header.Set("api-key", t.APIKey)
+		limits = openAIRateLimits
+	default:
+		return Result{}, fmt.Errorf("provider %s cannot be probed", t.Provider)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header = header
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+
+	result := Result{Auth: AuthUnknown, Time: time.Now()}
+	result.RequestsLimit = headerInt(resp.Header, limits.requestsLimit)
+	result.RequestsRemaining = headerInt(resp.Header, limits.requestsRemaining)
+	result.TokensLimit = headerInt(resp.Header, limits.tokensLimit)
+	result.TokensRemaining = headerInt(resp.Header, limits.tokensRemaining)
+
+	available := func(ok bool) *bool { return &ok }
+	switch {
+	case resp.StatusCode < 300:
+		result.Auth, result.ModelAvailable = AuthValid, available(true)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		result.Auth = AuthInvalid
+		result.Message = fmt.Sprintf("API key rejected (%d)", resp.StatusCode)
+	case resp.StatusCode == http.StatusNotFound:
+		result.Auth, result.ModelAvailable = AuthValid, available(false)
+		result.Message = fmt.Sprintf("model %s not found", t.Model)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		result.Auth = AuthValid
+		result.Message = "rate limited"
+		if result.RequestsRemaining == nil {
+			zero := int64(0)
+			result.RequestsRemaining = &zero
+		}
+	default:
+		result.Message = fmt.Sprintf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return result, nil
+}
+
+// headerInt parses an integer response header, nil when absent or malformed
+func headerInt(h http.Header, name string) *int64 {
+	v, err := strconv.ParseInt(h.Get(name), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Provider names used in AgentDeployment specs
+const (
+	Anthropic   = "anthropic"
+	OpenAI      = "openai"
+	AzureOpenAI = "azure-openai"
+	Bedrock     = "bedrock"
+	Vertex      = "vertex"
+	VLLM        = "vllm"
+	Ollama      = "ollama"
+	TGI         = "tgi"
+)
+
+// Provider describes how an agent reaches a model backend
+type Provider struct {
+	// Name is the provider identifier used in AgentDeployment specs
+	Name string
+
+	// SelfHosted providers run the serving runtime in the agent pod; hosted providers
+	// run the agent image and call a remote API
+	SelfHosted bool
+
+	// Image is the runtime image of self-hosted providers
+	Image string
+
+	// HealthProfile is the health profile used unless the spec selects one
+	HealthProfile string
+
+	// APIKeyEnv is the variable the credentials Secret's api-key is exposed as, for
+	// providers authenticating with an API key
+	APIKeyEnv string
+}
+
+var providers = map[string]Provider{
+	Anthropic:   {Name: Anthropic, HealthProfile: "default", APIKeyEnv: "ANTHROPIC_API_KEY"},
+	OpenAI:      {Name: OpenAI, HealthProfile: "default", APIKeyEnv: "OPENAI_API_KEY"},
+	AzureOpenAI: {Name: AzureOpenAI, HealthProfile: "default", APIKeyEnv: "AZURE_OPENAI_API_KEY"},
+	// Bedrock and Vertex authenticate with cloud credentials, see the controller
+	Bedrock: {Name: Bedrock, HealthProfile: "default"},
+	Vertex:  {Name: Vertex, HealthProfile: "default"},
+	VLLM:    {Name: VLLM, SelfHosted: true, Image: "vllm/vllm-openai:v0.4.2", HealthProfile: "vllm"},
+	Ollama:  {Name: Ollama, SelfHosted: true, Image: "ollama/ollama:0.1.38", HealthProfile: "ollama"},
+	TGI:     {Name: TGI, SelfHosted: true, Image: "ghcr.io/huggingface/text-generation-inference:2.0", HealthProfile: "tgi"},
+}
+
+// Lookup returns the named provider
+func Lookup(name string) (Provider, error) {
+	p, ok := providers[name]
+	if !ok {
+		names := make([]string, 0, len(providers))
+		for n := range providers {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Provider{}, fmt.Errorf("unknown provider %q (supported: %v)", name, names)
+	}
+	return p, nil
+}
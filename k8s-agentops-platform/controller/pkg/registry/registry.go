@@ -0,0 +1,291 @@
+// Package registry reads image manifests and blobs from OCI registries. Registries
+// are read anonymously, fetching bearer tokens for registries that require them even
+// for public pulls; Pullable also reads with the credentials of a pull secret.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBlobSize bounds manifests and signature blobs read from a registry
+const maxBlobSize = 4 << 20
+
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference is a parsed image reference
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses an image reference the way Docker does: the first path
+// component is a registry when it contains a dot or a port, or is localhost, and
+// Docker Hub images without a namespace live under library/
+func ParseReference(image string) (Reference, error) {
+	ref := Reference{Registry: "index.docker.io"}
+	name := image
+	if before, digest, ok := strings.Cut(name, "@"); ok {
+		name, ref.Digest = before, digest
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+	if first, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry, name = first, rest
+	}
+	if ref.Registry == "docker.io" {
+		ref.Registry = "index.docker.io"
+	}
+	if ref.Registry == "index.docker.io" && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	if name == "" {
+		return Reference{}, fmt.Errorf("invalid image reference %q", image)
+	}
+	ref.Repository = name
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return ref, nil
+}
+
+// host returns the registry API host
+func (r Reference) host() string {
+	if r.Registry == "index.docker.io" {
+		return "registry-1.docker.io"
+	}
+	return r.Registry
+}
+
+// Manifest is the part of an OCI or Docker image manifest listing its layers
+type Manifest struct {
+	Layers []Descriptor `json:"layers"`
+}
+
+// Descriptor references a blob
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Client reads manifests and blobs
+type Client struct {
+	http *http.Client
+
+	// authorizations caches the Authorization header per repository and user
+	mu             sync.Mutex
+	authorizations map[string]string
+}
+
+// Credentials authenticate pulls from a registry, e.g. from an image pull secret
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// NewClient returns a Client with a 30s request timeout
+func NewClient() *Client {
+	return &Client{http: &http.Client{Timeout: 30 * time.Second}, authorizations: map[string]string{}}
+}
+
+// ErrNotFound is returned for missing manifests, e.g. an image without signatures
+var ErrNotFound = errors.New("not found")
+
+// ErrUnauthorized is returned when the registry refuses the pull
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Resolve returns the manifest digest of an image. Images given by digest are
+// returned as they are, without a registry request.
+func (c *Client) Resolve(ctx context.Context, image string) (string, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+	_, digest, err := c.Manifest(ctx, ref, ref.Tag)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", image, err)
+	}
+	return digest, nil
+}
+
+// Pullable checks that the manifest of an image can be read with creds, or
+// anonymously when creds is nil, as a kubelet pulling the image would
+func (c *Client) Pullable(ctx context.Context, image string, creds *Credentials) error {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return err
+	}
+	tagOrDigest := ref.Digest
+	if tagOrDigest == "" {
+		tagOrDigest = ref.Tag
+	}
+	if _, _, err := c.fetch(ctx, ref, creds, "manifests/"+tagOrDigest, strings.Join(manifestMediaTypes, ", ")); err != nil {
+		return fmt.Errorf("pulling %s: %w", image, err)
+	}
+	return nil
+}
+
+// Manifest fetches a manifest by tag or digest and returns it with its digest
+func (c *Client) Manifest(ctx context.Context, ref Reference, tagOrDigest string) ([]byte, string, error) {
+	body, header, err := c.get(ctx, ref, "manifests/"+tagOrDigest, strings.Join(manifestMediaTypes, ", "))
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(body)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if d := header.Get("Docker-Content-Digest"); d != "" && strings.HasPrefix(tagOrDigest, "sha256:") && d != digest {
+		return nil, "", fmt.Errorf("manifest %s has digest %s", tagOrDigest, digest)
+	}
+	return body, digest, nil
+}
+
+// Blob fetches a blob and checks its digest
+func (c *Client) Blob(ctx context.Context, ref Reference, digest string) ([]byte, error) {
+	body, _, err := c.get(ctx, ref, "blobs/"+digest, "")
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	if "sha256:"+hex.EncodeToString(sum[:]) != digest {
+		return nil, fmt.Errorf("blob %s does not match its digest", digest)
+	}
+	return body, nil
+}
+
+// get reads a registry API path of the repository anonymously
+func (c *Client) get(ctx context.Context, ref Reference, path, accept string) ([]byte, http.Header, error) {
+	return c.fetch(ctx, ref, nil, path, accept)
+}
+
+// fetch reads a registry API path of the repository, authenticating once on a 401
+func (c *Client) fetch(ctx context.Context, ref Reference, creds *Credentials, path, accept string) ([]byte, http.Header, error) {
+	endpoint := fmt.Sprintf("https://%s/v2/%s/%s", ref.host(), ref.Repository, path)
+	key := ref.host() + "/" + ref.Repository
+	if creds != nil {
+		key += "\x00" + creds.Username
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		c.mu.Lock()
+		authorization := c.authorizations[key]
+		c.mu.Unlock()
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBlobSize))
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0:
+			authorization, err := c.authorize(ctx, resp.Header.Get("WWW-Authenticate"), ref, creds)
+			if err != nil {
+				return nil, nil, err
+			}
+			c.mu.Lock()
+			c.authorizations[key] = authorization
+			c.mu.Unlock()
+			continue
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, nil, ErrNotFound
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return nil, nil, fmt.Errorf("GET %s: %s: %w", endpoint, resp.Status, ErrUnauthorized)
+		case resp.StatusCode/100 != 2:
+			return nil, nil, fmt.Errorf("GET %s: %s", endpoint, resp.Status)
+		}
+		return body, resp.Header, nil
+	}
+}
+
+// authorize answers the challenge of a 401 with an Authorization header: basic
+// authentication with creds, or a pull token from the realm of a Bearer challenge,
+// fetched with creds when given and anonymously otherwise
+func (c *Client) authorize(ctx context.Context, challenge string, ref Reference, creds *Credentials) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if strings.EqualFold(scheme, "Basic") && creds != nil {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(creds.Username+":"+creds.Password)), nil
+	}
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("registry %s requires unsupported authentication %q", ref.Registry, scheme)
+	}
+	values := map[string]string{}
+	for _, param := range strings.Split(params, ",") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(param), "="); ok {
+			values[k] = strings.Trim(v, `"`)
+		}
+	}
+	realm, err := url.Parse(values["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("registry %s sent an invalid token realm", ref.Registry)
+	}
+	q := realm.Query()
+	if service := values["service"]; service != "" {
+		q.Set("service", service)
+	}
+	q.Set("scope", fmt.Sprintf("repository:%s:pull", ref.Repository))
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if creds != nil {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("registry %s token endpoint: %s: %w", ref.Registry, resp.Status, ErrUnauthorized)
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("registry %s token endpoint: %s", ref.Registry, resp.Status)
+	}
+	var decoded struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBlobSize)).Decode(&decoded); err != nil {
+		return "", err
+	}
+	if decoded.Token != "" {
+		return "Bearer " + decoded.Token, nil
+	}
+	return "Bearer " + decoded.AccessToken, nil
+}
@@ -0,0 +1,136 @@
+package tasks
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	agentopsv1alpha1 "github.com/yourusername/k8s-agentops-platform/controller/pkg/apis/agentops/v1alpha1"
+)
+
+// Handler executes one type of slow operation. Handlers run in the AgentTask
+// controller's workers, never inside another resource's reconcile loop.
+type Handler interface {
+	Run(ctx context.Context, namespace string, params map[string]string) (map[string]string, error)
+}
+
+// HandlerFunc adapts a function to the Handler interface
+type HandlerFunc func(ctx context.Context, namespace string, params map[string]string) (map[string]string, error)
+
+// Run calls f
+func (f HandlerFunc) Run(ctx context.Context, namespace string, params map[string]string) (map[string]string, error) {
+	return f(ctx, namespace, params)
+}
+
+// permanentError marks an error that retrying will not fix
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the task fails without using its remaining attempts
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return stderrors.As(err, &p)
+}
+
+// Registry maps task types to handlers
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// NewRegistry returns an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{handlers: map[string]Handler{}}
+}
+
+// Register adds a handler for a task type
+func (r *Registry) Register(taskType string, h Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[taskType] = h
+}
+
+// Lookup returns the handler for a task type
+func (r *Registry) Lookup(taskType string) (Handler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	h, ok := r.handlers[taskType]
+	return h, ok
+}
+
+// Name returns the deterministic task name for an owner, type and params, so
+// submitting the same work twice returns the existing task and its result
+func Name(ownerName, taskType string, params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, params[k])
+	}
+	sum := hex.EncodeToString(h.Sum(nil))[:10]
+
+	name := fmt.Sprintf("%s-%s", ownerName, taskType)
+	// Keep the result a valid DNS subdomain
+	if len(name) > 240 {
+		name = name[:240]
+	}
+	return strings.ToLower(name + "-" + sum)
+}
+
+// Ensure returns the task for the given work, creating it if needed. The task is
+// owned by owner, so it is garbage collected with it.
+func Ensure(ctx context.Context, c client.Client, scheme *runtime.Scheme, owner client.Object, taskType string, params map[string]string) (*agentopsv1alpha1.AgentTask, error) {
+	task := &agentopsv1alpha1.AgentTask{}
+	key := types.NamespacedName{Name: Name(owner.GetName(), taskType, params), Namespace: owner.GetNamespace()}
+	err := c.Get(ctx, key, task)
+	if err == nil {
+		return task, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	task = &agentopsv1alpha1.AgentTask{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Labels: map[string]string{
+				"agentops.io/task-type": taskType,
+				"agentops.io/owner":     owner.GetName(),
+			},
+		},
+		Spec: agentopsv1alpha1.AgentTaskSpec{
+			Type:   taskType,
+			Params: params,
+		},
+	}
+	if err := controllerutil.SetControllerReference(owner, task, scheme); err != nil {
+		return nil, err
+	}
+	if err := c.Create(ctx, task); err != nil && !errors.IsAlreadyExists(err) {
+		return nil, err
+	}
+	return task, nil
+}
@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// Client wraps a client so that every child-resource operation is recorded as a
+// span under the reconcile that made it
+type Client struct {
+	client.Client
+}
+
+// NewClient returns a traced view of c
+func NewClient(c client.Client) client.Client {
+	return &Client{Client: c}
+}
+
+// Get traces a read, usually served from the informer cache
+func (c *Client) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	ctx, span := c.start(ctx, "get", obj, "")
+	return end(span, c.Client.Get(ctx, key, obj, opts...))
+}
+
+// List traces a list
+func (c *Client) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	ctx, span := c.start(ctx, "list", list, "")
+	return end(span, c.Client.List(ctx, list, opts...))
+}
+
+// Create traces a create
+func (c *Client) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	ctx, span := c.start(ctx, "create", obj, "")
+	return end(span, c.Client.Create(ctx, obj, opts...))
+}
+
+// Update traces an update
+func (c *Client) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	ctx, span := c.start(ctx, "update", obj, "")
+	return end(span, c.Client.Update(ctx, obj, opts...))
+}
+
+// Patch traces a patch
+func (c *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, span := c.start(ctx, "patch", obj, "")
+	return end(span, c.Client.Patch(ctx, obj, patch, opts...))
+}
+
+// Delete traces a delete
+func (c *Client) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	ctx, span := c.start(ctx, "delete", obj, "")
+	return end(span, c.Client.Delete(ctx, obj, opts...))
+}
+
+// DeleteAllOf traces a collection delete
+func (c *Client) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	ctx, span := c.start(ctx, "deletecollection", obj, "")
+	return end(span, c.Client.DeleteAllOf(ctx, obj, opts...))
+}
+
+// Status returns a traced status writer
+func (c *Client) Status() client.SubResourceWriter {
+	return &subResourceClient{SubResourceClient: c.Client.SubResource("status"), parent: c, subResource: "status"}
+}
+
+// SubResource returns a traced subresource client
+func (c *Client) SubResource(subResource string) client.SubResourceClient {
+	return &subResourceClient{SubResourceClient: c.Client.SubResource(subResource), parent: c, subResource: subResource}
+}
+
+type subResourceClient struct {
+	client.SubResourceClient
+	parent      *Client
+	subResource string
+}
+
+func (s *subResourceClient) Get(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceGetOption) error {
+	ctx, span := s.parent.start(ctx, "get", obj, s.subResource)
+	return end(span, s.SubResourceClient.Get(ctx, obj, subResource, opts...))
+}
+
+func (s *subResourceClient) Create(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+	ctx, span := s.parent.start(ctx, "create", obj, s.subResource)
+	return end(span, s.SubResourceClient.Create(ctx, obj, subResource, opts...))
+}
+
+func (s *subResourceClient) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	ctx, span := s.parent.start(ctx, "update", obj, s.subResource)
+	return end(span, s.SubResourceClient.Update(ctx, obj, opts...))
+}
+
+func (s *subResourceClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	ctx, span := s.parent.start(ctx, "patch", obj, s.subResource)
+	return end(span, s.SubResourceClient.Patch(ctx, obj, patch, opts...))
+}
+
+// start opens a span named "<verb> <Kind>[/<subresource>]" for an operation on obj
+func (c *Client) start(ctx context.Context, verb string, obj runtime.Object, subResource string) (context.Context, trace.Span) {
+	kind := ""
+	if gvk, err := apiutil.GVKForObject(obj, c.Scheme()); err == nil {
+		kind = gvk.Kind
+	}
+	if subResource != "" {
+		kind += "/" + subResource
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("k8s.verb", verb),
+		attribute.String("k8s.kind", kind),
+	}
+	if o, ok := obj.(client.Object); ok {
+		attrs = append(attrs,
+			attribute.String("k8s.namespace.name", o.GetNamespace()),
+			attribute.String("k8s.object.name", o.GetName()),
+		)
+	}
+	return tracer().Start(ctx, verb+" "+kind, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// end records err on the span and closes it. NotFound is an expected outcome of
+// lookups and is not marked as a failure.
+func end(span trace.Span, err error) error {
+	switch {
+	case errors.IsNotFound(err):
+		span.SetAttributes(attribute.Bool("k8s.not_found", true))
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	return err
+}
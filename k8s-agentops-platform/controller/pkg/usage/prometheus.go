@@ -0,0 +1,308 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// tokensMetric is the counter agents export for every completion, labelled by model
+	tokensMetric = "agent_tokens_total"
+	// requestsMetric is the request counter agents export
+	requestsMetric = "http_requests_total"
+	// requestDurationMetric is the request latency histogram agents export
+	requestDurationMetric = "http_request_duration_seconds"
+
+	// cAdvisor container metrics, as scraped from the kubelet
+	cpuMetric    = "container_cpu_usage_seconds_total"
+	memoryMetric = "container_memory_working_set_bytes"
+	// gpuMetric is the GPU utilization in percent exported by the NVIDIA DCGM exporter
+	gpuMetric = "DCGM_FI_DEV_GPU_UTIL"
+	// gpuMemoryUsedMetric and gpuMemoryFreeMetric are the used and free GPU memory
+	// in MiB exported by the DCGM exporter
+	gpuMemoryUsedMetric = "DCGM_FI_DEV_FB_USED"
+	gpuMemoryFreeMetric = "DCGM_FI_DEV_FB_FREE"
+)
+
+// Prometheus reads agent token usage, traffic and container resource usage from the
+// Prometheus HTTP API
+type Prometheus struct {
+	// URL is the base URL of the Prometheus server
+	URL string
+
+	// HTTPClient is used for queries
+	HTTPClient *http.Client
+}
+
+// NewPrometheus returns a Prometheus usage source for the server at baseURL
+func NewPrometheus(baseURL string) *Prometheus {
+	return &Prometheus{URL: strings.TrimSuffix(baseURL, "/"), HTTPClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+type queryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// TokensByModel returns the tokens consumed by the given agents over the last
+// window, keyed by model. Agents are matched on the service label the scrape
+// config derives from the agent Service, which is named after its AgentDeployment.
+func (p *Prometheus) TokensByModel(ctx context.Context, namespace string, agents []string, window time.Duration) (map[string]float64, error) {
+	if len(agents) == 0 {
+		return map[string]float64{}, nil
+	}
+	quoted := make([]string, len(agents))
+	for i, a := range agents {
+		quoted[i] = regexp.QuoteMeta(a)
+	}
+	query := fmt.Sprintf(`sum by (model) (increase(%s{namespace=%q,service=~"%s"}[%ds]))`,
+		tokensMetric, namespace, strings.Join(quoted, "|"), rangeSeconds(window))
+	samples, err := p.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := map[string]float64{}
+	for _, s := range samples {
+		tokens[s.labels["model"]] += s.value
+	}
+	return tokens, nil
+}
+
+// AgentUsage is the token usage of an agent with a model
+type AgentUsage struct {
+	Namespace string
+	Agent     string
+	Model     string
+	Tokens    float64
+
+	// Requests is the number of requests the agent received, over all its models
+	Requests float64
+}
+
+// UsageByAgent returns the tokens consumed by every agent of the cluster over the
+// last window, by model, along with the requests each agent received
+func (p *Prometheus) UsageByAgent(ctx context.Context, window time.Duration) ([]AgentUsage, error) {
+	seconds := rangeSeconds(window)
+	tokens, err := p.query(ctx, fmt.Sprintf(`sum by (namespace, service, model) (increase(%s[%ds]))`, tokensMetric, seconds))
+	if err != nil {
+		return nil, err
+	}
+	requests, err := p.query(ctx, fmt.Sprintf(`sum by (namespace, service) (increase(%s[%ds]))`, requestsMetric, seconds))
+	if err != nil {
+		return nil, err
+	}
+	perAgent := map[[2]string]float64{}
+	for _, s := range requests {
+		perAgent[[2]string{s.labels["namespace"], s.labels["service"]}] += s.value
+	}
+
+	var out []AgentUsage
+	for _, s := range tokens {
+		ns, agent := s.labels["namespace"], s.labels["service"]
+		if agent == "" {
+			continue
+		}
+		out = append(out, AgentUsage{
+			Namespace: ns,
+			Agent:     agent,
+			Model:     s.labels["model"],
+			Tokens:    s.value,
+			Requests:  perAgent[[2]string{ns, agent}],
+		})
+	}
+	return out, nil
+}
+
+// Requests returns the number of requests an agent Service received over the last window
+func (p *Prometheus) Requests(ctx context.Context, namespace, service string, window time.Duration) (float64, error) {
+	query := fmt.Sprintf(`sum(increase(%s{namespace=%q,service=%q}[%ds]))`, requestsMetric, namespace, service, rangeSeconds(window))
+	samples, err := p.query(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, s := range samples {
+		total += s.value
+	}
+	return total, nil
+}
+
+// LatencyP95 returns the 95th percentile latency, in seconds, of the requests an
+// agent Service received over the last window, or NaN when it received none
+func (p *Prometheus) LatencyP95(ctx context.Context, namespace, service string, window time.Duration) (float64, error) {
+	query := fmt.Sprintf(`histogram_quantile(0.95, sum by (le) (increase(%s_bucket{namespace=%q,service=%q}[%ds])))`,
+		requestDurationMetric, namespace, service, rangeSeconds(window))
+	samples, err := p.query(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	if len(samples) == 0 {
+		return math.NaN(), nil
+	}
+	return samples[0].value, nil
+}
+
+// ContainerUsage is the resource usage of a container across the pods of a Deployment
+type ContainerUsage struct {
+	// CPUP95 and CPUPeak are the 95th percentile and the peak CPU usage of a pod, in cores
+	CPUP95  float64
+	CPUPeak float64
+
+	// MemoryP95 and MemoryPeak are the 95th percentile and the peak working set of a
+	// pod, in bytes
+	MemoryP95  float64
+	MemoryPeak float64
+
+	// GPUPeak is the peak number of GPUs a pod kept busy; zero without GPU metrics
+	GPUPeak float64
+}
+
+// ContainerUsage returns the usage of a container of a Deployment's pods over the
+// last window, or nil if there are no samples for it yet
+func (p *Prometheus) ContainerUsage(ctx context.Context, namespace, deployment, container string, window time.Duration) (*ContainerUsage, error) {
+	// Deployment pods are named <deployment>-<pod-template-hash>-<suffix>; the backtick
+	// string keeps the regular expression free of PromQL escapes
+	selector := fmt.Sprintf("namespace=%q,pod=~`%s-[a-z0-9]+-[a-z0-9]+`,container=%q",
+		namespace, regexp.QuoteMeta(deployment), container)
+	cpu := fmt.Sprintf(`sum by (pod) (rate(%s{%s}[5m]))`, cpuMetric, selector)
+	memory := fmt.Sprintf(`max by (pod) (%s{%s})`, memoryMetric, selector)
+	gpu := fmt.Sprintf(`sum by (pod) (%s{%s}) / 100`, gpuMetric, selector)
+	seconds := rangeSeconds(window)
+
+	u := &ContainerUsage{}
+	queries := []struct {
+		query string
+		value *float64
+	}{
+		{fmt.Sprintf(`max(quantile_over_time(0.95, (%s)[%ds:5m]))`, cpu, seconds), &u.CPUP95},
+		{fmt.Sprintf(`max(max_over_time((%s)[%ds:5m]))`, cpu, seconds), &u.CPUPeak},
+		{fmt.Sprintf(`max(quantile_over_time(0.95, (%s)[%ds:5m]))`, memory, seconds), &u.MemoryP95},
+		{fmt.Sprintf(`max(max_over_time((%s)[%ds:5m]))`, memory, seconds), &u.MemoryPeak},
+		{fmt.Sprintf(`max(max_over_time((%s)[%ds:5m]))`, gpu, seconds), &u.GPUPeak},
+	}
+	for _, q := range queries {
+		samples, err := p.query(ctx, q.query)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range samples {
+			*q.value = s.value
+		}
+	}
+	if u.CPUPeak == 0 && u.MemoryPeak == 0 {
+		return nil, nil
+	}
+	return u, nil
+}
+
+// GPUUtilization is the average utilization of the GPUs of a set of pods
+type GPUUtilization struct {
+	// Utilization is the percent of time the GPUs were busy
+	Utilization float64
+
+	// MemoryUtilization is the percent of GPU memory in use
+	MemoryUtilization float64
+}
+
+// GPUUtilization returns the utilization, averaged over the last five minutes, of
+// the GPUs the DCGM exporter attributes to the namespace's pods whose name matches
+// the podPattern regular expression, or nil without samples
+func (p *Prometheus) GPUUtilization(ctx context.Context, namespace, podPattern string) (*GPUUtilization, error) {
+	selector := fmt.Sprintf("namespace=%q,pod=~`%s`", namespace, podPattern)
+	utilization := fmt.Sprintf(`avg(avg_over_time(%s{%s}[5m]))`, gpuMetric, selector)
+	memory := fmt.Sprintf(`100 * sum(avg_over_time(%s{%s}[5m])) / (sum(avg_over_time(%s{%s}[5m])) + sum(avg_over_time(%s{%s}[5m])))`,
+		gpuMemoryUsedMetric, selector, gpuMemoryUsedMetric, selector, gpuMemoryFreeMetric, selector)
+
+	u := &GPUUtilization{}
+	found := false
+	for _, q := range []struct {
+		query string
+		value *float64
+	}{
+		{utilization, &u.Utilization},
+		{memory, &u.MemoryUtilization},
+	} {
+		samples, err := p.query(ctx, q.query)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range samples {
+			if !math.IsNaN(s.value) {
+				*q.value = s.value
+				found = true
+			}
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return u, nil
+}
+
+// rangeSeconds converts a window into a PromQL range, at least a minute long so
+// increase() has two scrapes to work with
+func rangeSeconds(window time.Duration) int64 {
+	seconds := int64(window.Seconds())
+	if seconds < 60 {
+		seconds = 60
+	}
+	return seconds
+}
+
+type sample struct {
+	labels map[string]string
+	value  float64
+}
+
+// query runs an instant query and returns the vector result
+func (p *Prometheus) query(ctx context.Context, query string) ([]sample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL+"/api/v1/query?"+url.Values{"query": {query}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body queryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if body.Status != "success" {
+		return nil, fmt.Errorf("prometheus query failed: %s", body.Error)
+	}
+
+	samples := make([]sample, 0, len(body.Data.Result))
+	for _, result := range body.Data.Result {
+		if len(result.Value) != 2 {
+			continue
+		}
+		raw, ok := result.Value[1].(string)
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse sample value %q: %w", raw, err)
+		}
+		samples = append(samples, sample{labels: result.Metric, value: v})
+	}
+	return samples, nil
+}
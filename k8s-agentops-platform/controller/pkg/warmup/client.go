@@ -0,0 +1,99 @@
+package warmup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// maxOutput bounds how much command output or response body is kept for errors
+const maxOutput = 512
+
+// Client runs warmup hooks against agent pods, either as an HTTP request to the
+// pod or as a command executed in one of its containers
+type Client struct {
+	// HTTPClient sends warmup requests; the caller's context bounds each request
+	HTTPClient *http.Client
+
+	config    *rest.Config
+	clientset kubernetes.Interface
+}
+
+// NewClient returns a Client executing commands through the API server at config
+func NewClient(config *rest.Config) (*Client, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{HTTPClient: &http.Client{}, config: config, clientset: clientset}, nil
+}
+
+// HTTP sends a request to url and fails on any non-2xx response
+func (c *Client) HTTP(ctx context.Context, method, url, body string) error {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		out, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+		return fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, url, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// Exec runs command in a container of the pod and fails on a non-zero exit status
+func (c *Client) Exec(ctx context.Context, namespace, pod, container string, command []string) error {
+	req := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(c.config, http.MethodPost, req.URL())
+	if err != nil {
+		return err
+	}
+	var stdout, stderr bytes.Buffer
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, tail(stderr.Bytes()))
+	}
+	return nil
+}
+
+// tail returns the end of command output, which usually holds the error
+func tail(out []byte) []byte {
+	out = bytes.TrimSpace(out)
+	if len(out) > maxOutput {
+		out = out[len(out)-maxOutput:]
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package weightswap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrUnsupported is returned when the runtime does not implement dual-slot loading
+	ErrUnsupported = errors.New("runtime does not support dual-slot weight loading")
+
+	// ErrInsufficientMemory is returned when the standby slot does not fit on the GPU
+	ErrInsufficientMemory = errors.New("insufficient accelerator memory for a second weight slot")
+)
+
+const (
+	loadPath     = "/admin/slots/standby"
+	activatePath = "/admin/slots/activate"
+)
+
+// Client drives the dual-slot admin API of agent runtimes that can load model
+// weights into a second slot and switch to them atomically
+type Client struct {
+	// HTTPClient is used for admin requests; loading weights can take minutes
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client with a timeout suitable for loading weights
+func NewClient() *Client {
+	return &Client{HTTPClient: &http.Client{Timeout: 10 * time.Minute}}
+}
+
+type slotRequest struct {
+	Version string `json:"version"`
+}
+
+// Swap loads version into the standby slot of the runtime at baseURL and then
+// activates it. The active slot keeps serving until activation succeeds.
+func (c *Client) Swap(ctx context.Context, baseURL, version string) error {
+	if err := c.post(ctx, baseURL+loadPath, version); err != nil {
+		return fmt.Errorf("load standby slot: %w", err)
+	}
+	if err := c.post(ctx, baseURL+activatePath, version); err != nil {
+		return fmt.Errorf("activate standby slot: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) post(ctx context.Context, url, version string) error {
+	body, err := json.Marshal(slotRequest{Version: version})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented:
+		return ErrUnsupported
+	case resp.StatusCode == http.StatusInsufficientStorage || resp.StatusCode == http.StatusConflict:
+		return ErrInsufficientMemory
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return nil
+}